@@ -0,0 +1,354 @@
+// gerber-cli works with the Gerber files of a board, as written by the
+// gerber package or any other CAD tool. Arguments naming a directory
+// stand for the Gerber files in it, and layer types are inferred from
+// filenames (see gerber.LayerTypeOf).
+//
+// Usage:
+//
+//	gerber-cli render [-out board.png] [-dpi 300] [-side top] dir|files...
+//	gerber-cli stats dir|files...
+//	gerber-cli report [-json] dir|files...
+//	gerber-cli diff [-tol 0.001] before after
+//	gerber-cli zip [-fab oshpark] [-name board] [-out board.zip] dir|files...
+//
+// render writes a PNG (or an SVG, if -out ends in .svg) image of the
+// board, or (if -out ends in .stl) a 3D model of it. stats reports the
+// bounding box and statistics of each layer (see
+// gerber.Gerber.WriteStats), and report the board's size, copper
+// areas, drill counts, and smallest features, for comparison with the
+// capabilities of a fab (see gerber.Report). diff lists the layers of
+// two Gerber files or directories whose shapes differ by more than
+// -tol mm (see testutil.CompareLayers), and exits with status 1 if
+// there are any. zip repackages the layers with the filenames of a
+// fab's convention.
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gmlewis/go-gerber/gerber"
+	"github.com/gmlewis/go-gerber/gerber/render"
+	"github.com/gmlewis/go-gerber/gerber/testutil"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, `usage:
+  gerber-cli render [-out board.png] [-dpi 300] [-side top|bottom] dir|files...
+  gerber-cli stats dir|files...
+  gerber-cli report [-json] dir|files...
+  gerber-cli diff [-tol 0.001] before after
+  gerber-cli zip [-fab protel|oshpark|kicad] [-name board] [-out board.zip] dir|files...
+`)
+	os.Exit(2)
+}
+
+var (
+	// errUsage reports invalid arguments.
+	errUsage = errors.New("usage")
+	// errDiffer reports that diff found differences.
+	errDiffer = errors.New("the boards differ")
+)
+
+var conventions = map[string]gerber.FilenameConvention{
+	"protel":  gerber.ProtelNames,
+	"oshpark": gerber.OSHParkNames,
+	"kicad":   gerber.KiCadNames,
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() < 2 {
+		usage()
+	}
+
+	switch err := run(flag.Arg(0), flag.Args()[1:], os.Stdout); err {
+	case nil:
+	case errUsage:
+		usage()
+	case errDiffer:
+		os.Exit(1)
+	default:
+		log.Fatal(err)
+	}
+}
+
+// run runs the command with its arguments, writing its report to w.
+func run(cmd string, args []string, w io.Writer) error {
+	switch cmd {
+	case "render":
+		return renderCmd(args)
+	case "stats":
+		g, err := load(args...)
+		if err != nil {
+			return err
+		}
+		return g.WriteStats(w)
+	case "report":
+		return reportCmd(args, w)
+	case "diff":
+		return diffCmd(args, w)
+	case "zip":
+		return zipCmd(args, w)
+	}
+	return errUsage
+}
+
+// flags returns a flag set for a command, whose errors are reported as
+// errUsage.
+func flags(cmd string) *flag.FlagSet {
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	return fs
+}
+
+// gerberFiles expands the directories in args to the Gerber files
+// they contain, in filename order.
+func gerberFiles(args []string) ([]string, error) {
+	var files []string
+	for _, arg := range args {
+		fi, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !fi.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+		infos, err := ioutil.ReadDir(arg)
+		if err != nil {
+			return nil, err
+		}
+		var names []string
+		for _, info := range infos {
+			if _, _, ok := gerber.LayerTypeOf(info.Name()); ok && info.Mode().IsRegular() {
+				names = append(names, filepath.Join(arg, info.Name()))
+			}
+		}
+		if len(names) == 0 {
+			return nil, fmt.Errorf("%v: no Gerber files found", arg)
+		}
+		sort.Strings(names)
+		files = append(files, names...)
+	}
+	return files, nil
+}
+
+func load(args ...string) (*gerber.Gerber, error) {
+	if len(args) == 0 {
+		return nil, errUsage
+	}
+	files, err := gerberFiles(args)
+	if err != nil {
+		return nil, err
+	}
+	return gerber.ParseDesign(files...)
+}
+
+func renderCmd(args []string) error {
+	fs := flags("render")
+	out := fs.String("out", "board.png", "Output filename (.png, .svg, or .stl)")
+	dpi := fs.Int("dpi", 300, "Resolution of the PNG image")
+	side := fs.String("side", "top", "Side of the board to render (top or bottom)")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	var s gerber.Side
+	switch *side {
+	case "top":
+		s = gerber.SideTop
+	case "bottom":
+		s = gerber.SideBottom
+	default:
+		return fmt.Errorf("unknown side %q", *side)
+	}
+	g, err := load(fs.Args()...)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(filepath.Ext(*out)) {
+	case ".svg":
+		err = g.WriteSVGPreview(f, nil)
+	case ".stl":
+		err = g.WriteSTL(f, gerber.MeshOpts{})
+	default:
+		err = renderPNG(f, g, s, *dpi)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(*out)
+	}
+	return err
+}
+
+func renderPNG(w io.Writer, g *gerber.Gerber, side gerber.Side, dpi int) error {
+	c, err := render.FromSide(g, side)
+	if err != nil {
+		return err
+	}
+	img, err := c.RenderDPI(dpi)
+	if err != nil {
+		return err
+	}
+	return png.Encode(w, img)
+}
+
+func reportCmd(args []string, w io.Writer) error {
+	fs := flags("report")
+	asJSON := fs.Bool("json", false, "Write the report as JSON")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	g, err := load(fs.Args()...)
+	if err != nil {
+		return err
+	}
+	r := g.Report()
+	if !*asJSON {
+		return r.WriteText(w)
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+// diffCmd compares the layers of two Gerber files or directories,
+// matching layers by their base filenames (or, when comparing two
+// files, by position).
+func diffCmd(args []string, w io.Writer) error {
+	fs := flags("diff")
+	tol := fs.Float64("tol", 0.001, "Largest difference (in mm) between coordinates and sizes that are the same")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 2 {
+		return errUsage
+	}
+	ga, err := load(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	gb, err := load(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	name := func(l *gerber.Layer) string { return filepath.Base(l.Filename) }
+	if len(ga.Layers) == 1 && len(gb.Layers) == 1 {
+		name = func(*gerber.Layer) string { return filepath.Base(ga.Layers[0].Filename) }
+	}
+
+	after := map[string]*gerber.Layer{}
+	for _, l := range gb.Layers {
+		after[name(l)] = l
+	}
+	var changes []string
+	for _, la := range ga.Layers {
+		lb, ok := after[name(la)]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("%v: removed", name(la)))
+			continue
+		}
+		delete(after, name(la))
+		if err := testutil.CompareLayers(lb, la, *tol); err != nil {
+			changes = append(changes, fmt.Sprintf("%v: %v", name(la), err))
+		}
+	}
+	for _, lb := range gb.Layers {
+		if _, ok := after[name(lb)]; ok {
+			changes = append(changes, fmt.Sprintf("%v: added", name(lb)))
+		}
+	}
+	for _, c := range changes {
+		if _, err := fmt.Fprintln(w, c); err != nil {
+			return err
+		}
+	}
+	if len(changes) > 0 {
+		return errDiffer
+	}
+	return nil
+}
+
+func zipCmd(args []string, w io.Writer) error {
+	fs := flags("zip")
+	fab := fs.String("fab", "oshpark", "Filename convention of the fab (protel, oshpark, or kicad)")
+	name := fs.String("name", "", "Filename prefix of the layers in the zip (default that of the first file)")
+	out := fs.String("out", "", "Output zip filename (default <name>.zip)")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	fc, ok := conventions[*fab]
+	if !ok {
+		return fmt.Errorf("unknown fab %q", *fab)
+	}
+
+	g, err := load(fs.Args()...)
+	if err != nil {
+		return err
+	}
+	prefix := *name
+	if prefix == "" {
+		prefix = filepath.Base(g.FilenamePrefix)
+	}
+	if *out == "" {
+		*out = prefix + ".zip"
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	err = writeZip(f, w, g, fc, prefix)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(*out)
+	}
+	return err
+}
+
+// writeZip writes the original files of the layers of g to f, named
+// by fc, and reports each of them to w.
+func writeZip(f, w io.Writer, g *gerber.Gerber, fc gerber.FilenameConvention, prefix string) error {
+	zw := zip.NewWriter(f)
+	for _, layer := range g.Layers {
+		// The original files are copied as-is, so that nothing is lost
+		// by parsing and rewriting them.
+		buf, err := ioutil.ReadFile(layer.Filename)
+		if err != nil {
+			return err
+		}
+		filename := fc.Filename(prefix, layer)
+		zf, err := zw.Create(filename)
+		if err != nil {
+			return err
+		}
+		if _, err := zf.Write(buf); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "%v -> %v\n", layer.Filename, filename); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
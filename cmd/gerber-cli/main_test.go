@@ -0,0 +1,215 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gmlewis/go-gerber/gerber"
+)
+
+// writeBoard writes a small board, with its pad at (x, 5), to a new
+// directory within dir and returns the directory.
+func writeBoard(t *testing.T, dir, name string, x float64) string {
+	t.Helper()
+	out := filepath.Join(dir, name)
+	if err := os.Mkdir(out, 0755); err != nil {
+		t.Fatal(err)
+	}
+	g := gerber.New(filepath.Join(out, "board"))
+	g.TopCopper().Add(gerber.Circle(gerber.Pt{x, 5}, 1.5), gerber.Line(0, 0, 10, 0, gerber.CircleShape, 0.25))
+	g.Drill().Add(gerber.Circle(gerber.Pt{x, 5}, 0.8))
+	g.Outline().Add(gerber.Line(0, 0, 20, 0, gerber.CircleShape, 0.1), gerber.Line(20, 0, 20, 10, gerber.CircleShape, 0.1))
+	if err := g.WriteToDir(out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func tempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "gerber-cli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestRun_Stats(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	board := writeBoard(t, dir, "board", 5)
+
+	var out strings.Builder
+	if err := run("stats", []string{board}, &out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"3 layers", `"` + filepath.Join(board, "board.gtl") + `"`, "board.gtl: 1 flashes, 1 draws (10mm)"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("stats =\n%v\nwant %q", out.String(), want)
+		}
+	}
+}
+
+func TestRun_Report(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	board := writeBoard(t, dir, "board", 5)
+
+	var out strings.Builder
+	if err := run("report", []string{board}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "min drill 0.8mm, 1 holes") {
+		t.Errorf("report =\n%v\nwant the drill", out.String())
+	}
+
+	out.Reset()
+	if err := run("report", []string{"-json", board}, &out); err != nil {
+		t.Fatal(err)
+	}
+	var r gerber.Report
+	if err := json.Unmarshal([]byte(out.String()), &r); err != nil {
+		t.Fatalf("report -json = %v: %v", out.String(), err)
+	}
+	if r.Holes != 1 || r.CopperLayers != 1 {
+		t.Errorf("report -json = %+v, want 1 hole and 1 copper layer", r)
+	}
+}
+
+func TestRun_Diff(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	before := writeBoard(t, dir, "before", 5)
+	same := writeBoard(t, dir, "same", 5.0004)
+	moved := writeBoard(t, dir, "moved", 6)
+
+	tests := []struct {
+		name string
+		args []string
+		want []string // the layers that differ
+	}{
+		{name: "within tolerance", args: []string{before, same}},
+		{name: "moved pad", args: []string{before, moved}, want: []string{"board.drl", "board.gtl"}},
+		{name: "moved pad within larger tolerance", args: []string{"-tol", "1.5", before, moved}},
+		{name: "files", args: []string{filepath.Join(before, "board.gtl"), filepath.Join(moved, "board.gtl")}, want: []string{"board.gtl"}},
+		{name: "removed layer", args: []string{before, filepath.Join(same, "board.gtl")}, want: []string{"board.drl: removed", "board.gko: removed"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			err := run("diff", tt.args, &out)
+			if wantErr := len(tt.want) > 0; (err == errDiffer) != wantErr || err != nil && err != errDiffer {
+				t.Fatalf("diff = %v, want differences %v", err, wantErr)
+			}
+			var got []string // without the indented details
+			for _, line := range strings.Split(out.String(), "\n") {
+				if line != "" && !strings.HasPrefix(line, " ") {
+					got = append(got, line)
+				}
+			}
+			sort.Strings(got)
+			if len(got) != len(tt.want) {
+				t.Fatalf("diff =\n%v\nwant %v", out.String(), tt.want)
+			}
+			for i, want := range tt.want {
+				if !strings.HasPrefix(got[i], want) {
+					t.Errorf("diff line %v = %q, want %v...", i, got[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestRun_Render(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	board := writeBoard(t, dir, "board", 5)
+
+	out := filepath.Join(dir, "board.png")
+	if err := run("render", []string{"-out", out, "-dpi", "100", "-side", "bottom", board}, ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := png.Decode(f); err != nil {
+		t.Errorf("render wrote an invalid PNG: %v", err)
+	}
+
+	svg := filepath.Join(dir, "board.svg")
+	if err := run("render", []string{"-out", svg, board}, ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if buf, err := ioutil.ReadFile(svg); err != nil || !strings.Contains(string(buf), "<svg") {
+		t.Errorf("render wrote %q, %v, want an SVG", buf, err)
+	}
+
+	if err := run("render", []string{"-side", "left", board}, ioutil.Discard); err == nil {
+		t.Error("render -side left succeeded")
+	}
+
+	bad := filepath.Join(dir, "bad.png")
+	if err := run("render", []string{"-out", bad, "-dpi", "0", board}, ioutil.Discard); err == nil {
+		t.Error("render -dpi 0 succeeded")
+	}
+	if _, err := os.Stat(bad); !os.IsNotExist(err) {
+		t.Errorf("render -dpi 0 left %v behind (%v)", bad, err)
+	}
+}
+
+func TestRun_Zip(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	board := writeBoard(t, dir, "board", 5)
+
+	out := filepath.Join(dir, "fab.zip")
+	var log strings.Builder
+	if err := run("zip", []string{"-fab", "protel", "-name", "fab", "-out", out, board}, &log); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.OpenReader(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	var got []string
+	for _, f := range zr.File {
+		got = append(got, f.Name)
+	}
+	if want := "fab.drl fab.gko fab.gtl"; strings.Join(got, " ") != want {
+		t.Errorf("zip files = %v, want %v", got, want)
+	}
+	if n := strings.Count(log.String(), " -> "); n != 3 {
+		t.Errorf("zip reported %v files:\n%v", n, log.String())
+	}
+
+	if err := run("zip", []string{"-fab", "unknown", board}, ioutil.Discard); err == nil {
+		t.Error("zip -fab unknown succeeded")
+	}
+}
+
+func TestRun_Usage(t *testing.T) {
+	tests := []struct {
+		cmd  string
+		args []string
+	}{
+		{cmd: "unknown", args: []string{"board"}},
+		{cmd: "diff", args: []string{"board"}},
+		{cmd: "report", args: []string{"-bogus", "board"}},
+		{cmd: "stats"},
+	}
+	for _, tt := range tests {
+		if err := run(tt.cmd, tt.args, ioutil.Discard); err != errUsage {
+			t.Errorf("run(%v, %v) = %v, want %v", tt.cmd, tt.args, err, errUsage)
+		}
+	}
+}
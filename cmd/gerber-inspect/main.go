@@ -0,0 +1,93 @@
+// gerber-inspect inspects designs saved by the gerber package
+// (see gerber.Gerber.MarshalJSON).
+//
+// Usage:
+//
+//	gerber-inspect stats design.json
+//	gerber-inspect validate design.json
+//	gerber-inspect diff before.json after.json
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/gmlewis/go-gerber/gerber"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, `usage:
+  gerber-inspect stats design.json
+  gerber-inspect validate design.json
+  gerber-inspect diff before.json after.json
+`)
+	os.Exit(2)
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() < 2 {
+		usage()
+	}
+
+	switch cmd, args := flag.Arg(0), flag.Args()[1:]; cmd {
+	case "stats":
+		if err := load(args[0]).WriteStats(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+	case "validate":
+		if !validate(load(args[0])) {
+			os.Exit(1)
+		}
+	case "diff":
+		if len(args) != 2 {
+			usage()
+		}
+		if !diff(load(args[0]), load(args[1])) {
+			os.Exit(1)
+		}
+	default:
+		usage()
+	}
+}
+
+func load(filename string) *gerber.Gerber {
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil {
+		log.Fatal(err)
+	}
+	g := &gerber.Gerber{}
+	if err := json.Unmarshal(buf, g); err != nil {
+		log.Fatalf("%v: %v", filename, err)
+	}
+	return g
+}
+
+func validate(g *gerber.Gerber) bool {
+	issues := g.Validate()
+	for _, issue := range issues {
+		fmt.Println(issue)
+	}
+	return issues.Err() == nil
+}
+
+func diff(a, b *gerber.Gerber) bool {
+	sa, err := a.Snapshot()
+	if err != nil {
+		log.Fatal(err)
+	}
+	sb, err := b.Snapshot()
+	if err != nil {
+		log.Fatal(err)
+	}
+	changes := gerber.Diff(sa, sb)
+	for _, c := range changes {
+		fmt.Println(c)
+	}
+	return len(changes) == 0
+}
@@ -0,0 +1,81 @@
+package gerber
+
+import "fmt"
+
+// Anchors maps names to reference points of a group or footprint
+// (e.g. "pin1", "center", or "mounting-hole"), relative to its origin,
+// so that sub-designs can be placed by their features rather than by
+// their origins.
+type Anchors map[string]Pt
+
+// placement returns the position of the origin that moves anchor to at
+// after rotating counterclockwise by degrees about the origin.
+func placement(anchor, at Pt, degrees float64) Pt {
+	r := RotatePt(anchor, Pt{}, degrees)
+	return Pt{at[0] - r[0], at[1] - r[1]}
+}
+
+// PlaceAnchor returns a copy of the group rotated counterclockwise by
+// degrees about its origin and moved so that the named anchor lies
+// at at.
+func (g Group) PlaceAnchor(anchors Anchors, name string, at Pt, degrees float64) (Group, error) {
+	anchor, ok := anchors[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown anchor %q", name)
+	}
+	return g.Place(placement(anchor, at, degrees), degrees)
+}
+
+// Anchor returns the named point of the footprint relative to its
+// origin: one of its Anchors, or else "center" (the center of its
+// minimum bounding box, including its pads) or the center of the pad
+// with the given number (e.g. "1").
+func (f *Footprint) Anchor(name string) (Pt, bool) {
+	if pt, ok := f.Anchors[name]; ok {
+		return pt, true
+	}
+	if name == "center" {
+		mbb, ok := f.mbb()
+		return Pt{0.5 * (mbb.Min[0] + mbb.Max[0]), 0.5 * (mbb.Min[1] + mbb.Max[1])}, ok
+	}
+	for _, pad := range f.Pads {
+		if pad.Number == name {
+			return pad.Center, true
+		}
+	}
+	return Pt{}, false
+}
+
+// mbb returns the minimum bounding box of the footprint's primitives
+// and pad centers, and false if it has neither.
+func (f *Footprint) mbb() (MBB, bool) {
+	var mbb MBB
+	found := false
+	join := func(v MBB) {
+		if !found {
+			mbb, found = v, true
+			return
+		}
+		mbb.Join(&v)
+	}
+	for _, group := range f.Layers {
+		if len(group) > 0 {
+			join(group.MBB())
+		}
+	}
+	for _, pad := range f.Pads {
+		join(MBB{Min: pad.Center, Max: pad.Center})
+	}
+	return mbb, found
+}
+
+// PlaceAnchor adds the footprint to the design (see Place), rotated
+// counterclockwise by degrees about its origin and moved so that the
+// named anchor (see Anchor) lies at at.
+func (f *Footprint) PlaceAnchor(g *Gerber, name string, at Pt, degrees float64) error {
+	anchor, ok := f.Anchor(name)
+	if !ok {
+		return fmt.Errorf("footprint %q: unknown anchor %q", f.Name, name)
+	}
+	return f.Place(g, placement(anchor, at, degrees), degrees)
+}
@@ -0,0 +1,59 @@
+package gerber
+
+import "testing"
+
+func TestGroup_PlaceAnchor(t *testing.T) {
+	g := Group{Circle(Pt{2, 0}, 1)}
+	anchors := Anchors{"pin1": {2, 0}}
+	placed, err := g.PlaceAnchor(anchors, "pin1", Pt{10, 10}, 90)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := placed.MBB(), (MBB{Min: Pt{9.5, 9.5}, Max: Pt{10.5, 10.5}}); !mbbNear(got, want, 1e-9) {
+		t.Errorf("MBB = %v, want %v", got, want)
+	}
+	if _, err := g.PlaceAnchor(anchors, "missing", Pt{}, 0); err == nil {
+		t.Error("PlaceAnchor(missing): want error")
+	}
+}
+
+func TestFootprint_PlaceAnchor(t *testing.T) {
+	via := Via{Drill: 0.3, Pad: 0.6}.Padstack()
+	f := &Footprint{
+		Name: "header",
+		Layers: map[LayerType]Group{
+			TopSilkscreenLayer: {Line(-1, -1, 5, -1, CircleShape, 0.1)},
+		},
+		Pads: []PadstackRef{
+			{Padstack: via, Number: "1"},
+			{Padstack: via, Center: Pt{4, 0}, Number: "2"},
+		},
+		Anchors: Anchors{"mounting-hole": {2, 3}},
+	}
+	tests := []struct {
+		anchor  string
+		degrees float64
+		want    Pt // where pad 1 is placed
+	}{
+		{anchor: "1", want: Pt{10, 10}},
+		{anchor: "2", want: Pt{6, 10}},
+		{anchor: "2", degrees: 90, want: Pt{10, 6}},
+		{anchor: "center", want: Pt{8, 10.525}},
+		{anchor: "mounting-hole", want: Pt{8, 7}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.anchor, func(t *testing.T) {
+			g := New("test")
+			if err := f.PlaceAnchor(g, tt.anchor, Pt{10, 10}, tt.degrees); err != nil {
+				t.Fatal(err)
+			}
+			got := g.PadstackRefs()[0].Center
+			if !mbbNear(MBB{Min: got, Max: got}, MBB{Min: tt.want, Max: tt.want}, 1e-9) {
+				t.Errorf("pad 1 center = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if err := f.PlaceAnchor(New("test"), "missing", Pt{}, 0); err == nil {
+		t.Error("PlaceAnchor(missing): want error")
+	}
+}
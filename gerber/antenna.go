@@ -0,0 +1,160 @@
+package gerber
+
+import (
+	"errors"
+	"math"
+)
+
+// speedOfLight is in millimeters per second.
+const speedOfLight = 299792458e3
+
+// Substrate describes the dielectric between a trace and its ground plane.
+type Substrate struct {
+	// Er is the relative permittivity (dielectric constant).
+	Er float64
+	// Height is the dielectric thickness in millimeters.
+	Height float64
+}
+
+// FR4 is a standard 1.6mm FR-4 substrate.
+var FR4 = Substrate{Er: 4.4, Height: 1.6}
+
+// EffectivePermittivity returns the effective permittivity of a
+// microstrip of the given width (in mm) on the substrate.
+func (s Substrate) EffectivePermittivity(width float64) float64 {
+	return 0.5*(s.Er+1) + 0.5*(s.Er-1)/math.Sqrt(1+12*s.Height/width)
+}
+
+// MicrostripWidth returns the width (in mm) of a microstrip with
+// characteristic impedance z0 (in ohms) on the substrate, using the
+// Hammerstad synthesis equations.
+func (s Substrate) MicrostripWidth(z0 float64) float64 {
+	a := z0/60*math.Sqrt(0.5*(s.Er+1)) + (s.Er-1)/(s.Er+1)*(0.23+0.11/s.Er)
+	ratio := 8 * math.Exp(a) / (math.Exp(2*a) - 2)
+	if ratio > 2 {
+		b := 377 * math.Pi / (2 * z0 * math.Sqrt(s.Er))
+		ratio = 2 / math.Pi * (b - 1 - math.Log(2*b-1) + (s.Er-1)/(2*s.Er)*(math.Log(b-1)+0.39-0.61/s.Er))
+	}
+	return ratio * s.Height
+}
+
+// PatchOpts represents the options used by PatchAntenna.
+type PatchOpts struct {
+	// Impedance is the feed line impedance in ohms. Default is 50.
+	Impedance float64
+	// FeedLength is the length (in mm) of the feed line beyond the
+	// patch edge. Default is a quarter of the patch length.
+	FeedLength float64
+}
+
+// PatchAntenna returns a rectangular microstrip patch antenna for the
+// frequency freq (in Hz) on substrate s, with an inset microstrip feed
+// matched to opts.Impedance. The feed line ends at the group's origin
+// and the patch extends in +Y above it, centered on the Y axis.
+// The antenna requires a ground plane on the opposite copper layer.
+func PatchAntenna(freq float64, s Substrate, opts *PatchOpts) (Group, error) {
+	if freq <= 0 || s.Er <= 1 || s.Height <= 0 {
+		return nil, errors.New("invalid frequency or substrate")
+	}
+	var o PatchOpts
+	if opts != nil {
+		o = *opts
+	}
+	if o.Impedance <= 0 {
+		o.Impedance = 50
+	}
+
+	// Transmission line model (Balanis, "Antenna Theory").
+	w := speedOfLight / (2 * freq) * math.Sqrt(2/(s.Er+1))
+	eeff := s.EffectivePermittivity(w)
+	dl := 0.412 * s.Height * (eeff + 0.3) * (w/s.Height + 0.264) / ((eeff - 0.258) * (w/s.Height + 0.8))
+	l := speedOfLight/(2*freq*math.Sqrt(eeff)) - 2*dl
+
+	// Inset depth for the desired input impedance, given the
+	// approximate edge resistance of the patch.
+	rEdge := 90 * s.Er * s.Er / (s.Er - 1) * (l / w) * (l / w)
+	inset := 0.0
+	if o.Impedance < rEdge {
+		inset = l / math.Pi * math.Acos(math.Sqrt(o.Impedance/rEdge))
+	}
+	fw := s.MicrostripWidth(o.Impedance)
+	if o.FeedLength <= 0 {
+		o.FeedLength = 0.25 * l
+	}
+
+	// The inset notch leaves one feed width of clearance on each side of the feed.
+	y0, hw, notch := o.FeedLength, 0.5*w, 1.5*fw
+	patch := Polygon(Pt{0, 0}, true, []Pt{
+		{-hw, y0}, {-notch, y0}, {-notch, y0 + inset}, {notch, y0 + inset}, {notch, y0},
+		{hw, y0}, {hw, y0 + l}, {-hw, y0 + l},
+	}, 0)
+	// Rectangular line caps extend half the width beyond each end.
+	feed := Line(0, 0.5*fw, 0, y0+inset, RectShape, fw)
+	return Group{patch, feed}, nil
+}
+
+// IFAOpts represents the options used by InvertedFAntenna.
+// All dimensions are in millimeters.
+type IFAOpts struct {
+	// Height is the height of the antenna arm above the ground plane
+	// edge. Default is 4mm.
+	Height float64
+	// ShortSpacing is the distance between the shorting stub and the
+	// feed. Default is 2mm.
+	ShortSpacing float64
+	// TraceWidth is the width of the antenna traces. Default is 0.5mm.
+	TraceWidth float64
+	// PlateWidth, if positive, makes the arm a plate of this width
+	// (i.e. a planar inverted-F antenna or PIFA).
+	PlateWidth float64
+}
+
+// InvertedFAntenna returns a printed inverted-F antenna (IFA) for the
+// frequency freq (in Hz) on substrate s. The ground plane edge lies
+// along the X axis (below the antenna, not included in the group), the
+// feed is at the origin, and the shorting stub is at -ShortSpacing.
+// The arm extends in +X so that the path from the shorting stub to the
+// open end is a quarter of the effective wavelength.
+func InvertedFAntenna(freq float64, s Substrate, opts *IFAOpts) (Group, error) {
+	if freq <= 0 || s.Er < 1 {
+		return nil, errors.New("invalid frequency or substrate")
+	}
+	var o IFAOpts
+	if opts != nil {
+		o = *opts
+	}
+	if o.Height <= 0 {
+		o.Height = 4
+	}
+	if o.ShortSpacing <= 0 {
+		o.ShortSpacing = 2
+	}
+	if o.TraceWidth <= 0 {
+		o.TraceWidth = 0.5
+	}
+
+	// Without a ground plane below, the fields are split between
+	// the substrate and air.
+	eeff := 0.5 * (s.Er + 1)
+	quarter := speedOfLight / (4 * freq * math.Sqrt(eeff))
+	armEnd := quarter - o.Height - o.ShortSpacing
+	if o.PlateWidth > 0 {
+		armEnd -= o.PlateWidth
+	}
+	if armEnd <= 0 {
+		return nil, errors.New("antenna height and short spacing exceed a quarter wavelength")
+	}
+
+	h, x0 := o.Height, -o.ShortSpacing
+	group := Group{
+		Line(x0, 0, x0, h, CircleShape, o.TraceWidth), // shorting stub
+		Line(0, 0, 0, h, CircleShape, o.TraceWidth),   // feed
+	}
+	if o.PlateWidth > 0 {
+		group = append(group, Polygon(Pt{0, 0}, true, []Pt{
+			{x0, h}, {armEnd, h}, {armEnd, h + o.PlateWidth}, {x0, h + o.PlateWidth},
+		}, 0))
+		return group, nil
+	}
+	return append(group, Line(x0, h, armEnd, h, CircleShape, o.TraceWidth)), nil
+}
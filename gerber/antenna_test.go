@@ -0,0 +1,52 @@
+package gerber
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSubstrate_MicrostripWidth(t *testing.T) {
+	if got, want := FR4.MicrostripWidth(50), 3.06; math.Abs(got-want) > 0.02 {
+		t.Errorf("MicrostripWidth(50) = %v, want %v", got, want)
+	}
+}
+
+func TestPatchAntenna(t *testing.T) {
+	g, err := PatchAntenna(2.45e9, FR4, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	patch := g[0].MBB()
+	if got, want := patch.Max[0]-patch.Min[0], 37.24; math.Abs(got-want) > 0.05 {
+		t.Errorf("patch width = %v, want %v", got, want)
+	}
+	if got, want := patch.Max[1]-patch.Min[1], 28.8; math.Abs(got-want) > 0.2 {
+		t.Errorf("patch length = %v, want %v", got, want)
+	}
+	if mbb := g.MBB(); mbb.Min[1] != 0 {
+		t.Errorf("feed starts at y=%v, want 0", mbb.Min[1])
+	}
+}
+
+func TestInvertedFAntenna(t *testing.T) {
+	g, err := InvertedFAntenna(2.4e9, FR4, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The path from the shorting stub to the open end is a quarter wavelength.
+	arm := g[2].(*LineT)
+	got := 4 + arm.Length()
+	want := speedOfLight / (4 * 2.4e9 * math.Sqrt(0.5*(FR4.Er+1)))
+	if math.Abs(got-want) > 1e-9 {
+		t.Errorf("resonant length = %v, want %v", got, want)
+	}
+
+	placed, err := g.Place(Pt{10, 20}, 90)
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed := placed[1].(*LineT)
+	if feed.P1 != (Pt{10, 20}) {
+		t.Errorf("placed feed = %v, want it to start at (10,20)", feed.P1)
+	}
+}
@@ -0,0 +1,159 @@
+package gerber
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// DCodeRange represents an inclusive range of aperture D-codes.
+type DCodeRange struct {
+	From, To int
+}
+
+// ApertureNumbering controls how D-codes are assigned to the
+// apertures of each layer.
+type ApertureNumbering struct {
+	// DefaultCode is the D-code of the default aperture (used for regions).
+	// Zero means D11.
+	DefaultCode int
+	// Start is the first D-code assigned to the layer's apertures.
+	// Zero means D12.
+	Start int
+	// Reserved lists D-code ranges that are never assigned, for example
+	// to leave room for codes that CAM scripts expect to be fixed.
+	Reserved []DCodeRange
+	// Sorted assigns D-codes in order of aperture shape and size (and
+	// then definition) instead of the order in which the apertures were
+	// first used, so that the numbering does not depend upon the order
+	// in which primitives are added. See also WithDeterministicOutput.
+	Sorted bool
+}
+
+// WithApertureNumbering sets how D-codes are assigned to apertures.
+func WithApertureNumbering(n ApertureNumbering) Option {
+	return func(g *Gerber) {
+		g.numbering = n
+	}
+}
+
+// WithApertureTolerance merges apertures whose sizes differ by at most
+// tol millimeters (e.g. one unit of the output coordinate format) into
+// the first of them to be added to a layer, so that floating point
+// noise in a generator (such as 0.19999999mm and 0.2mm circles) does
+// not define near-identical apertures. Apertures of different shapes,
+// and those defined by aperture macros, are never merged. The number
+// of merged apertures is reported by Layer.Stats. A tolerance of zero
+// (the default) only shares identical apertures.
+func WithApertureTolerance(tol float64) Option {
+	return func(g *Gerber) {
+		g.apertureTolerance = tol
+	}
+}
+
+// similarAperture returns the index of the first aperture of the layer
+// that a may be merged into (see WithApertureTolerance), or -1 if there
+// is none.
+func (l *Layer) similarAperture(a *Aperture) int {
+	if l.g == nil || l.g.apertureTolerance <= 0 || a.Macro != nil {
+		return -1
+	}
+	tol := l.g.apertureTolerance
+	for i, b := range l.Apertures {
+		if b.Macro == nil && b.Shape == a.Shape && math.Abs(b.Size-a.Size) <= tol && math.Abs(b.height()-a.height()) <= tol {
+			return i
+		}
+	}
+	return -1
+}
+
+// minDCode is the smallest D-code that may be used for an aperture.
+const minDCode = 10
+
+func (n *ApertureNumbering) defaultCode() int {
+	if n.DefaultCode == 0 {
+		return 11
+	}
+	return n.DefaultCode
+}
+
+func (n *ApertureNumbering) reserved(code int) bool {
+	if code == n.defaultCode() {
+		return true
+	}
+	for _, r := range n.Reserved {
+		if code >= r.From && code <= r.To {
+			return true
+		}
+	}
+	return false
+}
+
+// apertureCodes returns the D-code of the default aperture and of each
+// of the layer's apertures (indexed like l.Apertures).
+func (l *Layer) apertureCodes() (defaultCode int, codes []int, err error) {
+	var n ApertureNumbering
+	if l.g != nil {
+		n = l.g.numbering
+		n.Sorted = n.Sorted || l.g.deterministic
+	}
+	defaultCode = n.defaultCode()
+	if defaultCode < minDCode {
+		return 0, nil, fmt.Errorf("invalid default aperture D-code %v (must be >= %v)", defaultCode, minDCode)
+	}
+
+	order := make([]int, len(l.Apertures))
+	for i := range order {
+		order[i] = i
+	}
+	if n.Sorted {
+		sort.SliceStable(order, func(i, j int) bool {
+			a, b := l.Apertures[order[i]], l.Apertures[order[j]]
+			if a.Shape != b.Shape {
+				return a.Shape < b.Shape
+			}
+			if a.Size != b.Size {
+				return a.Size < b.Size
+			}
+			if a.height() != b.height() {
+				return a.height() < b.height()
+			}
+			return a.ID() < b.ID() // e.g. macros
+		})
+	}
+
+	code := n.Start
+	if code == 0 {
+		code = 12
+	}
+	if code < minDCode {
+		return 0, nil, fmt.Errorf("invalid starting aperture D-code %v (must be >= %v)", code, minDCode)
+	}
+	codes = make([]int, len(l.Apertures))
+	for _, i := range order {
+		for n.reserved(code) {
+			code++
+		}
+		codes[i] = code
+		code++
+	}
+	return defaultCode, codes, nil
+}
+
+// DCode returns the D-code that will be used for the provided aperture
+// when the layer is written, or an error if the aperture is not used
+// by the layer. A nil aperture represents the default aperture.
+func (l *Layer) DCode(a *Aperture) (int, error) {
+	defaultCode, codes, err := l.apertureCodes()
+	if err != nil {
+		return 0, err
+	}
+	i, ok := l.apertureMap[a.ID()]
+	if !ok {
+		return 0, fmt.Errorf("aperture %v not used by layer %v", a.ID(), l.Filename)
+	}
+	if i < 0 {
+		return defaultCode, nil
+	}
+	return codes[i], nil
+}
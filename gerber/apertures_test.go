@@ -0,0 +1,149 @@
+package gerber
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestApertureNumbering(t *testing.T) {
+	tests := []struct {
+		name      string
+		numbering ApertureNumbering
+		want      []string
+	}{
+		{
+			name: "defaults",
+			want: []string{
+				"%ADD11C,0.00100*%",
+				"%ADD12C,0.50000*%",
+				"%ADD13C,0.20000*%",
+				"%ADD14R,0.30000X0.30000*%",
+				"G54D12*",
+				"G54D13*",
+				"G54D14*",
+				"G54D11*",
+			},
+		},
+		{
+			name:      "sorted",
+			numbering: ApertureNumbering{Sorted: true},
+			want: []string{
+				"%ADD11C,0.00100*%",
+				"%ADD12C,0.20000*%",
+				"%ADD13C,0.50000*%",
+				"%ADD14R,0.30000X0.30000*%",
+				"G54D13*",
+				"G54D12*",
+				"G54D14*",
+				"G54D11*",
+			},
+		},
+		{
+			name:      "reserved ranges",
+			numbering: ApertureNumbering{DefaultCode: 10, Start: 20, Reserved: []DCodeRange{{From: 21, To: 29}}},
+			want: []string{
+				"%ADD10C,0.00100*%",
+				"%ADD20C,0.50000*%",
+				"%ADD30C,0.20000*%",
+				"%ADD31R,0.30000X0.30000*%",
+				"G54D20*",
+				"G54D30*",
+				"G54D31*",
+				"G54D10*",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New("test", WithApertureNumbering(tt.numbering))
+			top := g.TopCopper()
+			top.Add(
+				Circle(Pt{0, 0}, 0.5),
+				Line(0, 0, 1, 1, CircleShape, 0.2),
+				Line(0, 0, 1, 1, RectShape, 0.3),
+				Polygon(Pt{0, 0}, true, []Pt{{0, 0}, {1, 0}, {1, 1}}, 0),
+			)
+			var buf bytes.Buffer
+			if err := top.WriteGerber(&buf); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, line := range strings.Split(buf.String(), "\n") {
+				if strings.HasPrefix(line, "%ADD") || strings.HasPrefix(line, "G54") {
+					got = append(got, line)
+				}
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got:\n%v\nwant:\n%v", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+
+			dc, err := top.DCode(&Aperture{Shape: RectShape, Size: 0.3})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := fmt.Sprintf("G54D%v*", dc); got[6] != want {
+				t.Errorf("DCode = %v, want %v", dc, got[6])
+			}
+		})
+	}
+}
+
+func TestApertureNumbering_Invalid(t *testing.T) {
+	g := New("test", WithApertureNumbering(ApertureNumbering{Start: 5}))
+	top := g.TopCopper()
+	top.Add(Circle(Pt{0, 0}, 0.5))
+	if err := top.WriteGerber(&bytes.Buffer{}); err == nil {
+		t.Errorf("WriteGerber = nil, want error")
+	}
+}
+
+func TestWithApertureTolerance(t *testing.T) {
+	write := func(opts ...Option) (*Layer, string) {
+		g := New("test", opts...)
+		top := g.TopCopper()
+		top.Add(
+			Circle(Pt{0, 0}, 0.2),
+			Circle(Pt{1, 0}, 0.19999999),
+			Line(0, 0, 1, 0, CircleShape, 0.20000001),
+			Pad(Pt{2, 0}, RectShape, 1, 0.5, 0),
+			Pad(Pt{3, 0}, RectShape, 1.0000001, 0.5, 0),
+			Pad(Pt{4, 0}, RectShape, 1, 0.5001, 0),
+			Circle(Pt{5, 0}, 0.3),
+		)
+		var buf bytes.Buffer
+		if err := top.WriteGerber(&buf); err != nil {
+			t.Fatal(err)
+		}
+		return top, buf.String()
+	}
+
+	top, got := write()
+	// Each output also defines the default aperture.
+	if n := strings.Count(got, "%ADD"); n != 8 {
+		t.Errorf("without a tolerance: %v apertures, want 8:\n%v", n, got)
+	}
+
+	top, got = write(WithApertureTolerance(1e-6))
+	if n := strings.Count(got, "%ADD"); n != 5 {
+		t.Errorf("%v apertures, want 5:\n%v", n, got)
+	}
+	if len(top.Apertures) != 4 {
+		t.Errorf("len(Apertures) = %v, want 4", len(top.Apertures))
+	}
+	if code, err := top.DCode(Circle(Pt{}, 0.19999999).Aperture()); err != nil || code != 12 {
+		t.Errorf("DCode(merged) = %v, %v, want 12", code, err)
+	}
+	s, err := top.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d12 := s.Apertures[1]; s.MergedApertures != 3 || d12.Code != 12 || d12.Flashes+d12.Draws != 3 {
+		t.Errorf("MergedApertures = %v, D12 = %+v, want 3 merged and 3 uses of D12", s.MergedApertures, d12)
+	}
+	if !strings.Contains(s.String(), ", 3 apertures merged") {
+		t.Errorf("String = %v", s)
+	}
+}
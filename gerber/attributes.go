@@ -0,0 +1,139 @@
+package gerber
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Attributes hold custom data (e.g. simulation IDs) attached to a
+// primitive by the tools built on this package. Like tags, attributes
+// are stored per layer and keyed by primitive identity. They are
+// inherited by the primitives that replace or are derived from a
+// primitive (see Layer.Replace and Gerber.DeriveOpenings), serialized
+// (see Gerber.MarshalJSON), and written as Gerber X2 object attributes
+// (%TO...*%), which Parse reads back.
+//
+// Attribute names contain only letters, digits, '_', '.', and '$', and
+// must not start with a digit; names starting with '.' are the standard
+// attributes of the Gerber specification (such as ".N", the net name).
+// Values may contain any character.
+type Attributes map[string]string
+
+// AddWithAttributes adds primitives to a layer (like Add) and sets the
+// attributes of each of them.
+func (l *Layer) AddWithAttributes(attrs Attributes, primitives ...Primitive) {
+	l.Add(primitives...)
+	for _, p := range primitives {
+		l.setAttributes(p, attrs)
+	}
+}
+
+// SetAttribute sets an attribute of a primitive in the layer.
+func (l *Layer) SetAttribute(p Primitive, name, value string) {
+	l.setAttributes(p, Attributes{name: value})
+}
+
+// Attributes returns the attributes of a primitive in the layer, or
+// nil if it has none. The result must not be modified.
+func (l *Layer) Attributes(p Primitive) Attributes {
+	return l.attrs[p]
+}
+
+// setAttributes merges attrs into the attributes of p.
+func (l *Layer) setAttributes(p Primitive, attrs Attributes) {
+	if len(attrs) == 0 {
+		return
+	}
+	if l.attrs == nil {
+		l.attrs = map[Primitive]Attributes{}
+	}
+	merged := Attributes{}
+	for k, v := range l.attrs[p] {
+		merged[k] = v
+	}
+	for k, v := range attrs {
+		merged[k] = v
+	}
+	l.attrs[p] = merged
+}
+
+// validAttributeName reports whether name is a valid X2 attribute name.
+func validAttributeName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r == '_' || r == '.' || r == '$' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z'):
+		case i > 0 && r >= '0' && r <= '9':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// writeAttributes writes the attributes as X2 object attributes,
+// sorted by name.
+func writeAttributes(w io.Writer, attrs Attributes) error {
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		if !validAttributeName(name) {
+			return fmt.Errorf("invalid attribute name %q", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "%%TO%v,%v*%%\n", name, escapeAttributeValue(name, attrs[name]))
+	}
+	return nil
+}
+
+// escapeAttributeValue escapes the value of the named attribute. The
+// values of standard (".") attributes, such as ".P" (e.g. "R1,1"), may
+// have several fields, which are escaped separately so that the commas
+// between them remain.
+func escapeAttributeValue(name, v string) string {
+	if !strings.HasPrefix(name, ".") {
+		return escapeAttribute(v)
+	}
+	fields := strings.Split(v, ",")
+	for i, f := range fields {
+		fields[i] = escapeAttribute(f)
+	}
+	return strings.Join(fields, ",")
+}
+
+// escapeAttribute escapes the characters of an attribute value that
+// are reserved by the Gerber format as \uXXXX.
+func escapeAttribute(v string) string {
+	var b strings.Builder
+	for _, r := range v {
+		if r == '%' || r == '*' || r == ',' || r == '\\' || r < ' ' {
+			fmt.Fprintf(&b, "\\u%04X", r)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// unescapeAttribute reverses escapeAttribute.
+func unescapeAttribute(v string) string {
+	var b strings.Builder
+	for i := 0; i < len(v); i++ {
+		if v[i] == '\\' && i+6 <= len(v) && v[i+1] == 'u' {
+			if r, err := strconv.ParseUint(v[i+2:i+6], 16, 32); err == nil {
+				b.WriteRune(rune(r))
+				i += 5
+				continue
+			}
+		}
+		b.WriteByte(v[i])
+	}
+	return b.String()
+}
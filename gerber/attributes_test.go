@@ -0,0 +1,112 @@
+package gerber
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAttributes(t *testing.T) {
+	g := New("test")
+	top := g.TopCopper()
+	g.TopSolderMask()
+	pad := Circle(Pt{1, 1}, 1)
+	top.AddWithAttributes(Attributes{"sim.id": "a,b*%", "kind": "pad", ".P": "R1,1*"}, pad)
+	top.SetOpenings(pad, Openings{Mask: true, MaskExpansion: 0.1})
+	top.SetAttribute(pad, "kind", "test point")
+	want := Attributes{"sim.id": "a,b*%", "kind": "test point", ".P": "R1,1*"}
+	if got := top.Attributes(pad); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Attributes = %v, want %v", got, want)
+	}
+
+	top.Replace(func(p Primitive) ([]Primitive, bool) {
+		moved, err := TransformPrimitive(p, Translate(1, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return []Primitive{moved}, true
+	})
+	moved := top.Primitives[0]
+	if got := top.Attributes(moved); !reflect.DeepEqual(got, want) {
+		t.Errorf("transformed Attributes = %v, want %v", got, want)
+	}
+
+	if err := g.DeriveOpenings(); err != nil {
+		t.Fatal(err)
+	}
+	mask := g.firstLayerOfType(TopSolderMaskLayer)
+	if len(mask.Primitives) != 1 {
+		t.Fatalf("mask has %v primitives, want 1", len(mask.Primitives))
+	}
+	if got := mask.Attributes(mask.Primitives[0]); !reflect.DeepEqual(got, want) {
+		t.Errorf("mask Attributes = %v, want %v", got, want)
+	}
+
+	data, err := json.Marshal(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ng Gerber
+	if err := json.Unmarshal(data, &ng); err != nil {
+		t.Fatal(err)
+	}
+	nt := ng.firstLayerOfType(TopCopperLayer)
+	if got := nt.Attributes(nt.Primitives[0]); !reflect.DeepEqual(got, want) {
+		t.Errorf("unmarshaled Attributes = %v, want %v", got, want)
+	}
+
+	top.Add(Circle(Pt{5, 5}, 1))
+	var buf bytes.Buffer
+	if err := top.WriteGerber(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); !strings.Contains(got, "%TO.P,R1,1\\u002A*%\n%TOkind,test point*%\n%TOsim.id,a\\u002Cb\\u002A\\u0025*%\n") || !strings.Contains(got, "%TD*%") {
+		t.Errorf("WriteGerber = %q, want escaped attributes", got)
+	}
+	parsed, err := Parse(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed.Primitives) != 2 {
+		t.Fatalf("parsed %v primitives, want 2", len(parsed.Primitives))
+	}
+	if got := parsed.Attributes(parsed.Primitives[0]); !reflect.DeepEqual(got, want) {
+		t.Errorf("parsed Attributes = %v, want %v", got, want)
+	}
+	if got := parsed.Attributes(parsed.Primitives[1]); got != nil {
+		t.Errorf("parsed Attributes = %v, want none", got)
+	}
+
+	top.SetAttribute(moved, "bad name", "x")
+	if err := top.WriteGerber(&bytes.Buffer{}); err == nil {
+		t.Error("WriteGerber with invalid attribute name = nil error, want error")
+	}
+}
+
+func TestParse_Attributes(t *testing.T) {
+	const file = `%FSLAX36Y36*%
+%MOMM*%
+%ADD10C,1*%
+%TO.N,GND*%
+%TO.P,R1,1*%
+D10*
+X0Y0D03*
+%TD.P*%
+X1000000Y0D03*
+%TD*%
+X2000000Y0D03*
+M02*
+`
+	layer, err := Parse(strings.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Attributes{{".N": "GND", ".P": "R1,1"}, {".N": "GND"}, nil}
+	for i, p := range layer.Primitives {
+		if got := layer.Attributes(p); !reflect.DeepEqual(got, want[i]) {
+			t.Errorf("Attributes(#%v) = %v, want %v", i, got, want[i])
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package gerber
+
+// Batch is a set of primitives built apart from any layer, so that the
+// primitives of a huge design may be generated by many goroutines at
+// once: each goroutine adds primitives to its own Batch (a Batch is not
+// safe for concurrent use), and the batches are then merged into the
+// layer (see Layer.Merge). The apertures and MBBs of the primitives are
+// computed as they are added to the batch.
+type Batch struct {
+	primitives []Primitive     // in the order added
+	apertures  []*Aperture     // in the order first used
+	ids        map[string]bool // the IDs of apertures
+	tags       map[Primitive][]string
+	attrs      map[Primitive]Attributes
+	openings   map[Primitive]Openings
+	mbb        *MBB // nil if the batch is empty
+}
+
+// NewBatch returns an empty batch.
+func NewBatch() *Batch {
+	return &Batch{ids: map[string]bool{}}
+}
+
+// Add adds primitives to the batch.
+func (b *Batch) Add(primitives ...Primitive) {
+	if b.ids == nil {
+		b.ids = map[string]bool{}
+	}
+	forEachAperture(primitives, func(a *Aperture) {
+		if id := a.ID(); !b.ids[id] {
+			b.ids[id] = true
+			b.apertures = append(b.apertures, a)
+		}
+	})
+	for _, p := range primitives {
+		v := p.MBB()
+		if b.mbb == nil {
+			b.mbb = &v
+			continue
+		}
+		b.mbb.Join(&v)
+	}
+	b.primitives = append(b.primitives, primitives...)
+}
+
+// Len returns the number of primitives in the batch.
+func (b *Batch) Len() int {
+	return len(b.primitives)
+}
+
+// AddTagged adds primitives to the batch (like Add) and tags them all
+// with the provided tags, which they keep in the layer.
+func (b *Batch) AddTagged(tags []string, primitives ...Primitive) {
+	b.Add(primitives...)
+	if len(tags) == 0 {
+		return
+	}
+	if b.tags == nil {
+		b.tags = map[Primitive][]string{}
+	}
+	for _, p := range primitives {
+		b.tags[p] = append(b.tags[p], tags...)
+	}
+}
+
+// Merge adds the primitives of the batches (in order, as if by Add or
+// AddTagged, with the attributes and openings of those of a batch of a
+// layer; see Layer.Batch) to the layer, reconciling the apertures of the batches
+// with those of the layer: each distinct aperture is defined once. The
+// layer's output is the same as if the primitives had been added
+// directly. Merge must not be called concurrently with other methods of
+// the layer, and the batches must not be changed while it runs.
+func (l *Layer) Merge(batches ...*Batch) {
+	for _, b := range batches {
+		if len(b.primitives) == 0 {
+			continue
+		}
+		for _, a := range b.apertures {
+			l.addAperture(a)
+		}
+		if l.mbb != nil || (l.g != nil && l.g.cachedMBB() != nil) {
+			l.joinMBB(*b.mbb)
+		}
+		indexed := l.indexCurrent()
+		if indexed {
+			for _, p := range b.primitives {
+				l.index.insert(p, 0)
+			}
+		}
+		l.Primitives = append(l.Primitives, b.primitives...)
+		if indexed {
+			l.indexed = l.Primitives
+		}
+		for _, p := range b.primitives {
+			l.Tag(p, b.tags[p]...)
+			l.setAttributes(p, b.attrs[p])
+			if o, ok := b.openings[p]; ok {
+				l.SetOpenings(p, o)
+			}
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package gerber
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+)
+
+func TestLayer_Merge(t *testing.T) {
+	const workers = 8
+	generate := func(add func(tags []string, p ...Primitive), worker int) {
+		for i := 0; i < 100; i++ {
+			x, y := float64(worker), float64(i)
+			add(nil, Circle(Pt{x, y}, 0.1*float64(1+i%5)))
+			add([]string{"trace"}, Line(x, y, x+0.5, y, CircleShape, 0.2))
+		}
+	}
+
+	want := New("test")
+	wantTop := want.TopCopper()
+	for w := 0; w < workers; w++ {
+		generate(wantTop.AddTagged, w)
+	}
+
+	g := New("test")
+	top := g.TopCopper()
+	top.Add(Circle(Pt{-1, -1}, 0.1))
+	top.MBB() // cached, and extended by Merge
+	batches := make([]*Batch, workers)
+	var wg sync.WaitGroup
+	for w := range batches {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			batches[w] = NewBatch()
+			generate(batches[w].AddTagged, w)
+		}(w)
+	}
+	wg.Wait()
+	top.Merge(batches...)
+
+	if got, want := len(top.Primitives), 1+len(wantTop.Primitives); got != want {
+		t.Fatalf("got %v primitives, want %v", got, want)
+	}
+	if got, want := len(top.Apertures), len(wantTop.Apertures); got != want {
+		t.Errorf("got %v apertures, want %v", got, want)
+	}
+	if got, want := top.MBB(), (MBB{Min: Pt{-1.05, -1.05}, Max: Pt{7.6, 99.25}}); !mbbNear(got, want, 1e-9) {
+		t.Errorf("MBB = %v, want %v", got, want)
+	}
+	if p := top.Primitives[2]; !top.HasTag(p, "trace") {
+		t.Errorf("%v is not tagged", p)
+	}
+
+	// Without the extra circle, the output matches that of Add.
+	top.Primitives = top.Primitives[1:]
+	var got, wantBuf bytes.Buffer
+	if err := top.WriteGerber(&got); err != nil {
+		t.Fatal(err)
+	}
+	if err := wantTop.WriteGerber(&wantBuf); err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != wantBuf.String() {
+		t.Error("merged layer differs from the layer built with Add")
+	}
+}
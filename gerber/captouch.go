@@ -0,0 +1,172 @@
+package gerber
+
+import "math"
+
+// TouchOpts represents the options used by the capacitive touch
+// generators. All dimensions are in millimeters.
+type TouchOpts struct {
+	// Gap is the clearance between electrodes, and between the
+	// electrodes and the ground surround. Default is 0.5mm.
+	Gap float64
+	// GroundWidth is the width of the hatched ground surround.
+	// Zero means no ground surround is generated.
+	GroundWidth float64
+	// HatchWidth and HatchPitch are the trace width and spacing of
+	// the ground hatching. Defaults are 0.2mm and 1mm.
+	HatchWidth float64
+	HatchPitch float64
+}
+
+func (o *TouchOpts) withDefaults() TouchOpts {
+	var v TouchOpts
+	if o != nil {
+		v = *o
+	}
+	if v.Gap <= 0 {
+		v.Gap = 0.5
+	}
+	if v.HatchWidth <= 0 {
+		v.HatchWidth = 0.2
+	}
+	if v.HatchPitch <= 0 {
+		v.HatchPitch = 1
+	}
+	return v
+}
+
+// TouchButton returns a round capacitive touch button electrode of the
+// given diameter and (if opts.GroundWidth > 0) its hatched ground ring.
+func TouchButton(center Pt, diameter float64, opts *TouchOpts) (electrode Primitive, ground []Primitive) {
+	o := opts.withDefaults()
+	electrode = Circle(center, diameter)
+	if o.GroundWidth > 0 {
+		r := 0.5*diameter + o.Gap
+		ground = hatchedRing(center, r, r+o.GroundWidth, o)
+	}
+	return electrode, ground
+}
+
+// TouchSlider returns the electrodes of a linear, interdigitated
+// capacitive touch slider with its lower left corner at ll, and (if
+// opts.GroundWidth > 0) its hatched ground surround. Adjacent
+// electrodes interleave with triangular teeth so that a finger always
+// covers at least two of them.
+func TouchSlider(ll Pt, length, height float64, segments int, opts *TouchOpts) (electrodes []Primitive, ground []Primitive) {
+	o := opts.withDefaults()
+	if segments < 1 {
+		return nil, nil
+	}
+	w := length / float64(segments)
+	const teeth = 3
+	// boundary returns the points of the boundary between segments k-1
+	// and k, shifted horizontally by dx.
+	boundary := func(k int, dx float64) []Pt {
+		x := ll[0] + float64(k)*w + dx
+		if k == 0 || k == segments {
+			return []Pt{{x, ll[1]}, {x, ll[1] + height}}
+		}
+		pts := make([]Pt, 0, 2*teeth+1)
+		for i := 0; i <= 2*teeth; i++ {
+			a := 0.5 * w
+			if i%2 == 1 {
+				a = -a
+			}
+			if i == 0 || i == 2*teeth {
+				a = 0
+			}
+			pts = append(pts, Pt{x + a, ll[1] + height*float64(i)/(2*teeth)})
+		}
+		return pts
+	}
+	for k := 0; k < segments; k++ {
+		left, right := boundary(k, 0.5*o.Gap), boundary(k+1, -0.5*o.Gap)
+		if k == 0 {
+			left = boundary(k, 0)
+		}
+		if k == segments-1 {
+			right = boundary(k+1, 0)
+		}
+		pts := append([]Pt{}, left...)
+		for i := len(right) - 1; i >= 0; i-- {
+			pts = append(pts, right[i])
+		}
+		electrodes = append(electrodes, Polygon(Pt{0, 0}, true, pts, 0))
+	}
+
+	if o.GroundWidth > 0 {
+		inner := MBB{Min: Pt{ll[0] - o.Gap, ll[1] - o.Gap}, Max: Pt{ll[0] + length + o.Gap, ll[1] + height + o.Gap}}
+		outer := MBB{
+			Min: Pt{inner.Min[0] - o.GroundWidth, inner.Min[1] - o.GroundWidth},
+			Max: Pt{inner.Max[0] + o.GroundWidth, inner.Max[1] + o.GroundWidth},
+		}
+		ground = hatchedFrame(inner, outer, o)
+	}
+	return electrodes, ground
+}
+
+// TouchWheel returns the three electrodes of a capacitive touch wheel
+// (an annulus divided into 120 degree sectors, starting at 90 degrees
+// and proceeding counterclockwise) and (if opts.GroundWidth > 0) its
+// hatched ground ring.
+func TouchWheel(center Pt, outerDiameter, innerDiameter float64, opts *TouchOpts) (electrodes []Primitive, ground []Primitive) {
+	o := opts.withDefaults()
+	ro, ri := 0.5*outerDiameter, 0.5*innerDiameter
+	const steps = 40 // points per arc
+	for k := 0; k < 3; k++ {
+		start, end := Radians(90+120*float64(k)), Radians(90+120*float64(k+1))
+		// Offset each sector edge by half the gap, perpendicular to the edge.
+		do, di := math.Asin(math.Min(1, 0.5*o.Gap/ro)), math.Asin(math.Min(1, 0.5*o.Gap/ri))
+		var pts []Pt
+		for i := 0; i <= steps; i++ {
+			angle := start + do + (end-start-2*do)*float64(i)/steps
+			pts = append(pts, Pt{center[0] + ro*math.Cos(angle), center[1] + ro*math.Sin(angle)})
+		}
+		for i := steps; i >= 0; i-- {
+			angle := start + di + (end-start-2*di)*float64(i)/steps
+			pts = append(pts, Pt{center[0] + ri*math.Cos(angle), center[1] + ri*math.Sin(angle)})
+		}
+		electrodes = append(electrodes, Polygon(Pt{0, 0}, true, pts, 0))
+	}
+	if o.GroundWidth > 0 {
+		r := ro + o.Gap
+		ground = hatchedRing(center, r, r+o.GroundWidth, o)
+	}
+	return electrodes, ground
+}
+
+// hatchedRing returns a hatched annulus between radii r1 and r2,
+// bordered by solid traces along both edges.
+func hatchedRing(center Pt, r1, r2 float64, o TouchOpts) []Primitive {
+	hw := 0.5 * o.HatchWidth
+	inside := func(pt Pt) bool {
+		d := Distance(center, pt)
+		return d >= r1+hw && d <= r2-hw
+	}
+	mbb := MBB{Min: Pt{center[0] - r2, center[1] - r2}, Max: Pt{center[0] + r2, center[1] + r2}}
+	result := hatch(mbb, inside, o.HatchWidth, o.HatchPitch)
+	result = append(result,
+		Arc(center, r1+hw, CircleShape, 1, 1, 0, 360, o.HatchWidth),
+		Arc(center, r2-hw, CircleShape, 1, 1, 0, 360, o.HatchWidth))
+	return result
+}
+
+// hatchedFrame returns a hatched rectangular frame between the inner
+// and outer rectangles, bordered by solid traces along both edges.
+func hatchedFrame(inner, outer MBB, o TouchOpts) []Primitive {
+	hw := 0.5 * o.HatchWidth
+	shrink := func(r MBB, d float64) MBB {
+		return MBB{Min: Pt{r.Min[0] + d, r.Min[1] + d}, Max: Pt{r.Max[0] - d, r.Max[1] - d}}
+	}
+	in, out := shrink(inner, -hw), shrink(outer, hw)
+	inside := func(pt Pt) bool {
+		return out.ContainsPoint(&pt) && !in.ContainsPoint(&pt)
+	}
+	result := hatch(outer, inside, o.HatchWidth, o.HatchPitch)
+	for _, r := range []MBB{in, out} {
+		pts := []Pt{r.Min, {r.Max[0], r.Min[1]}, r.Max, {r.Min[0], r.Max[1]}, r.Min}
+		for i := 1; i < len(pts); i++ {
+			result = append(result, Line(pts[i-1][0], pts[i-1][1], pts[i][0], pts[i][1], CircleShape, o.HatchWidth))
+		}
+	}
+	return result
+}
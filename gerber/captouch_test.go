@@ -0,0 +1,53 @@
+package gerber
+
+import "testing"
+
+func TestTouchGenerators(t *testing.T) {
+	opts := &TouchOpts{GroundWidth: 2}
+	button, buttonGround := TouchButton(Pt{0, 0}, 10, opts)
+	slider, sliderGround := TouchSlider(Pt{0, 0}, 40, 8, 5, opts)
+	wheel, wheelGround := TouchWheel(Pt{0, 0}, 30, 10, opts)
+
+	tests := []struct {
+		name       string
+		electrodes []Primitive
+		ground     []Primitive
+		want       int
+	}{
+		{name: "button", electrodes: []Primitive{button}, ground: buttonGround, want: 1},
+		{name: "slider", electrodes: slider, ground: sliderGround, want: 5},
+		{name: "wheel", electrodes: wheel, ground: wheelGround, want: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := len(tt.electrodes); got != tt.want {
+				t.Fatalf("len(electrodes) = %v, want %v", got, tt.want)
+			}
+			if len(tt.ground) == 0 {
+				t.Fatal("no ground generated")
+			}
+			for i, e := range tt.electrodes {
+				for j := i + 1; j < len(tt.electrodes); j++ {
+					if outlineOf(e).overlaps(outlineOf(tt.electrodes[j])) {
+						t.Errorf("electrodes #%v and #%v touch", i, j)
+					}
+				}
+				for _, g := range tt.ground {
+					if outlineOf(e).overlaps(outlineOf(g)) {
+						t.Errorf("electrode #%v touches ground %v", i, g)
+						break
+					}
+				}
+			}
+
+			// The hatched ground must form a single island.
+			g := New("test")
+			layer := g.TopCopper()
+			layer.Add(tt.ground...)
+			if got := len(layer.Islands(IslandOpts{})); got != 1 {
+				t.Errorf("ground islands = %v, want 1", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package gerber
+
+import (
+	"errors"
+	"math"
+)
+
+// CastellationTag is the tag of the holes and pads of the castellations
+// added by AddCastellations, which Gerber X2 output identifies as
+// castellated (see WithX2).
+const CastellationTag = "castellation"
+
+// CastellationOpts represents the options used by AddCastellations.
+// All dimensions are in millimeters.
+type CastellationOpts struct {
+	// Count is the number of castellations, Pitch apart along the edge.
+	Count int
+	Pitch float64
+	// Drill is the diameter of the plated holes, which are centered on
+	// the edge so that routing the board leaves half of each.
+	Drill float64
+	// Pad is the width (along the edge) of the copper pads, which are
+	// round around the holes and extend Length into the board.
+	Pad    float64
+	Length float64
+	// MaskExpansion grows the solder mask openings of the pads on
+	// each side (see Openings).
+	MaskExpansion float64
+}
+
+// AddCastellations places castellated (half-plated) holes, centered
+// along the straight board edge from p1 to p2, with the board on the
+// left (as for a counterclockwise outline): each is a plated hole in
+// the design's first drill layer and a pad (with solder mask openings
+// on the outer layers) on every copper layer, all tagged with
+// CastellationTag. The drill layer, and top and bottom copper layers if
+// the design has no copper, are added as necessary. It returns the
+// centers of the holes.
+func (g *Gerber) AddCastellations(p1, p2 Pt, opts CastellationOpts) ([]Pt, error) {
+	if opts.Count < 1 || opts.Drill <= 0 || opts.Pad <= opts.Drill || opts.Length < 0 || opts.MaskExpansion < 0 {
+		return nil, errors.New("invalid castellation count or size")
+	}
+	if opts.Count > 1 && opts.Pitch <= opts.Pad {
+		return nil, errors.New("castellation pads overlap")
+	}
+	length := Distance(p1, p2)
+	if float64(opts.Count-1)*opts.Pitch+opts.Pad > length+validationEps {
+		return nil, errors.New("castellations do not fit along the edge")
+	}
+
+	var copper []*Layer
+	for _, layer := range g.Layers {
+		if layer.Type.IsCopper() {
+			copper = append(copper, layer)
+		}
+	}
+	if len(copper) == 0 {
+		copper = []*Layer{g.firstLayerOfType(TopCopperLayer), g.firstLayerOfType(BottomCopperLayer)}
+	}
+	drill := g.firstLayerOfType(DrillLayer)
+
+	u := Pt{(p2[0] - p1[0]) / length, (p2[1] - p1[1]) / length}
+	n := Pt{-u[1], u[0]} // into the board
+	angle := math.Atan2(u[1], u[0]) * 180 / math.Pi
+	mid := Pt{0.5 * (p1[0] + p2[0]), 0.5 * (p1[1] + p2[1])}
+	tags := []string{CastellationTag}
+	var centers []Pt
+	for i := 0; i < opts.Count; i++ {
+		s := (float64(i) - 0.5*float64(opts.Count-1)) * opts.Pitch
+		c := Pt{mid[0] + s*u[0], mid[1] + s*u[1]}
+		centers = append(centers, c)
+		drill.AddTagged(tags, Circle(c, opts.Drill))
+		for _, layer := range copper {
+			pads := []Primitive{Circle(c, opts.Pad)}
+			if opts.Length > 0 {
+				h := 0.5 * opts.Length
+				pads = append(pads, Pad(Pt{c[0] + h*n[0], c[1] + h*n[1]}, RectShape, opts.Pad, opts.Length, angle))
+			}
+			if layer.Type == TopCopperLayer || layer.Type == BottomCopperLayer {
+				layer.AddWithOpenings(Openings{Mask: true, MaskExpansion: opts.MaskExpansion}, pads...)
+			} else {
+				layer.Add(pads...)
+			}
+			for _, p := range pads {
+				layer.Tag(p, CastellationTag)
+			}
+		}
+	}
+	return centers, nil
+}
+
+// MouseBiteHoles returns a row of holes of the given diameter, pitch
+// apart and centered along the segment from p1 to p2, for perforating
+// a breakaway tab (see AddMouseBites). Panelize perforates the tabs
+// between boards with such rows along both edges of each tab.
+func MouseBiteHoles(p1, p2 Pt, hole, pitch float64) []Primitive {
+	length := Distance(p1, p2)
+	n := int(math.Floor((length-hole)/pitch+validationEps)) + 1
+	if n < 1 {
+		n = 1
+	}
+	var u Pt
+	if length > 0 {
+		u = Pt{(p2[0] - p1[0]) / length, (p2[1] - p1[1]) / length}
+	}
+	mid := Pt{0.5 * (p1[0] + p2[0]), 0.5 * (p1[1] + p2[1])}
+	var result []Primitive
+	for i := 0; i < n; i++ {
+		s := (float64(i) - 0.5*float64(n-1)) * pitch
+		result = append(result, Circle(Pt{mid[0] + s*u[0], mid[1] + s*u[1]}, hole))
+	}
+	return result
+}
+
+// AddMouseBites adds a row of mouse bite holes (see MouseBiteHoles),
+// tagged with NonPlatedTag, to the design's first drill layer (adding
+// one if necessary) and returns them.
+func (g *Gerber) AddMouseBites(p1, p2 Pt, hole, pitch float64) []Primitive {
+	holes := MouseBiteHoles(p1, p2, hole, pitch)
+	g.firstLayerOfType(DrillLayer).AddTagged([]string{NonPlatedTag}, holes...)
+	return holes
+}
@@ -0,0 +1,80 @@
+package gerber
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestGerber_AddCastellations(t *testing.T) {
+	g := New("module", WithX2(true))
+	top, bottom := g.TopCopper(), g.BottomCopper()
+	// The bottom edge of the board, traversed counterclockwise.
+	centers, err := g.AddCastellations(Pt{0, 0}, Pt{10, 0}, CastellationOpts{Count: 3, Pitch: 2.54, Drill: 0.7, Pad: 1.5, Length: 1.2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Pt{{2.46, 0}, {5, 0}, {7.54, 0}}
+	for i, c := range centers {
+		if Distance(c, want[i]) > 1e-9 {
+			t.Errorf("centers[%v] = %v, want %v", i, c, want[i])
+		}
+	}
+
+	drill := g.layersOfType(DrillLayer)[0]
+	if len(drill.Primitives) != 3 || !drill.HasTag(drill.Primitives[0], CastellationTag) || drill.HasTag(drill.Primitives[0], NonPlatedTag) {
+		t.Errorf("drill layer = %v, want 3 plated castellated holes", drill.Primitives)
+	}
+	for _, layer := range []*Layer{top, bottom} {
+		if len(layer.Primitives) != 6 {
+			t.Fatalf("%v has %v primitives, want 6", layer.Type, len(layer.Primitives))
+		}
+		// The pads extend into the board (above the edge).
+		if got, want := layer.MBB(), (MBB{Min: Pt{1.71, -0.75}, Max: Pt{8.29, 1.2}}); !mbbNear(got, want, 1e-9) {
+			t.Errorf("%v MBB = %v, want %v", layer.Type, got, want)
+		}
+		for _, p := range layer.Primitives {
+			if o, ok := layer.Openings(p); !ok || !o.Mask || !layer.HasTag(p, CastellationTag) {
+				t.Errorf("%v pad %v has openings %v, %v", layer.Type, p, o, ok)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := drill.WriteGerber(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); !strings.Contains(got, "%TA.AperFunction,CastellatedDrill*%") {
+		t.Errorf("drill =\n%v\nwant CastellatedDrill", got)
+	}
+
+	for _, opts := range []CastellationOpts{
+		{Count: 0, Pitch: 2.54, Drill: 0.7, Pad: 1.5},
+		{Count: 2, Pitch: 1, Drill: 0.7, Pad: 1.5},
+		{Count: 5, Pitch: 2.54, Drill: 0.7, Pad: 1.5},
+	} {
+		if _, err := g.AddCastellations(Pt{0, 0}, Pt{10, 0}, opts); err == nil {
+			t.Errorf("AddCastellations(%+v) succeeded", opts)
+		}
+	}
+}
+
+func TestMouseBiteHoles(t *testing.T) {
+	holes := MouseBiteHoles(Pt{0, 0}, Pt{0, 3}, 0.5, 0.8)
+	if len(holes) != 4 {
+		t.Fatalf("got %v holes, want 4", len(holes))
+	}
+	for i, want := range []float64{0.3, 1.1, 1.9, 2.7} {
+		mbb := holes[i].MBB()
+		if c := 0.5 * (mbb.Min[1] + mbb.Max[1]); c < want-1e-9 || c > want+1e-9 {
+			t.Errorf("hole %v at Y=%v, want %v", i, c, want)
+		}
+	}
+
+	g := New("board")
+	g.AddMouseBites(Pt{0, 0}, Pt{3, 0}, 0.5, 0.8)
+	drill := g.layersOfType(DrillLayer)[0]
+	if len(drill.Primitives) != 4 || !drill.HasTag(drill.Primitives[3], NonPlatedTag) {
+		t.Errorf("drill layer = %v, want 4 non-plated holes", drill.Primitives)
+	}
+}
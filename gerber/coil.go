@@ -0,0 +1,307 @@
+package gerber
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// SpiralOpts represents the options used by ArchimedeanSpiral,
+// SquareSpiral, and MultiLayerCoil.
+// All dimensions are in millimeters and angles are in degrees.
+type SpiralOpts struct {
+	// Turns is the number of turns (rounded to a quarter turn for
+	// square spirals).
+	Turns float64
+	// InnerRadius is the distance from the center to the centerline of
+	// the start of the innermost turn (half the side of the innermost
+	// turn of a square spiral).
+	InnerRadius float64
+	// TraceWidth and Gap are the width of the trace and the spacing
+	// between adjacent turns.
+	TraceWidth float64
+	Gap        float64
+	// StartAngle is the direction of the start (inner end) of the
+	// spiral from its center.
+	StartAngle float64
+	// Clockwise winds the spiral clockwise outward from its start
+	// (rather than counterclockwise).
+	Clockwise bool
+	// Lead, if positive, extends the ends of the spiral (or coil) by
+	// straight terminals of this length: inward from the inner end and
+	// outward from the outer end.
+	Lead float64
+}
+
+// Spiral represents a planar spiral as generated by ArchimedeanSpiral
+// or SquareSpiral.
+type Spiral struct {
+	Traces Group
+	// Start and End are the inner and outer terminals of the spiral.
+	Start, End Pt
+	// Coil is the geometry of the spiral, for inductance estimation.
+	Coil SpiralCoil
+}
+
+// spiralTolerance is the maximum chord error (in millimeters) of the
+// line segments of an Archimedean spiral.
+const spiralTolerance = 0.01
+
+// ArchimedeanSpiral returns a spiral about center whose radius grows
+// by the pitch (TraceWidth+Gap) each turn, made of line segments.
+func ArchimedeanSpiral(center Pt, opts SpiralOpts) (*Spiral, error) {
+	return newSpiral(CircularCoil, center, opts)
+}
+
+// SquareSpiral returns a square spiral about center, with its sides
+// parallel to (and its ends on) the axes of its start direction.
+func SquareSpiral(center Pt, opts SpiralOpts) (*Spiral, error) {
+	return newSpiral(SquareCoil, center, opts)
+}
+
+func newSpiral(shape CoilShape, center Pt, opts SpiralOpts) (*Spiral, error) {
+	pts, coil, err := spiralPath(shape, opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Lead > 0 {
+		pts = append([]Pt{radialOffset(pts[0], -opts.Lead)}, append(pts, radialOffset(pts[len(pts)-1], opts.Lead))...)
+	}
+	f := spiralFrame{center: center, rotation: opts.StartAngle, mirror: opts.Clockwise}
+	s := &Spiral{Start: f.apply(pts[0]), End: f.apply(pts[len(pts)-1]), Coil: coil}
+	s.Traces = f.trace(pts, opts.TraceWidth)
+	return s, nil
+}
+
+// spiralPath returns the centerline of a spiral (from its inner end
+// to its outer end) about the origin, starting in the direction of the
+// positive X axis and winding counterclockwise, along with its
+// geometry.
+func spiralPath(shape CoilShape, o SpiralOpts) ([]Pt, SpiralCoil, error) {
+	if o.Turns <= 0 || o.InnerRadius <= 0 {
+		return nil, SpiralCoil{}, errors.New("invalid turns or inner radius")
+	}
+	if o.TraceWidth <= 0 || o.Gap < 0 {
+		return nil, SpiralCoil{}, errors.New("invalid trace width or gap")
+	}
+	if o.Lead >= o.InnerRadius {
+		return nil, SpiralCoil{}, fmt.Errorf("lead %v is not shorter than the inner radius", o.Lead)
+	}
+	a, pitch := o.InnerRadius, o.TraceWidth+o.Gap
+	switch shape {
+	case CircularCoil:
+		outer := a + pitch*o.Turns
+		step := 2 * math.Acos(1-math.Min(spiralTolerance/outer, 1))
+		n := int(math.Ceil(Radians(360*o.Turns) / step))
+		pts := make([]Pt, 0, n+1)
+		for i := 0; i <= n; i++ {
+			turns := o.Turns * float64(i) / float64(n)
+			pts = append(pts, PolarPt(a+pitch*turns, 360*turns))
+		}
+		return pts, SpiralCoil{Shape: CircularCoil, Turns: o.Turns, OuterDiameter: 2 * outer, InnerDiameter: 2 * a}, nil
+	case SquareCoil:
+		quarters := int(math.Round(4 * o.Turns))
+		if quarters < 1 {
+			return nil, SpiralCoil{}, errors.New("invalid turns or inner radius")
+		}
+		// Each quarter turn passes a corner and ends on the next axis.
+		h, outer := a, a
+		pts := []Pt{{a, 0}}
+		for i := 0; i < quarters; i++ {
+			switch i % 4 {
+			case 0:
+				pts = append(pts, Pt{h, h}, Pt{0, h})
+			case 1:
+				pts = append(pts, Pt{-h, h}, Pt{-h, 0})
+			case 2:
+				pts = append(pts, Pt{-h, -h}, Pt{0, -h})
+			case 3:
+				pts = append(pts, Pt{h + pitch, -h}, Pt{h + pitch, 0})
+				h += pitch
+			}
+			outer = h
+		}
+		return pts, SpiralCoil{Shape: SquareCoil, Turns: float64(quarters) / 4, OuterDiameter: 2 * outer, InnerDiameter: 2 * a}, nil
+	}
+	return nil, SpiralCoil{}, fmt.Errorf("unsupported spiral shape %v", shape)
+}
+
+// radialOffset returns pt moved by d away from the origin.
+func radialOffset(pt Pt, d float64) Pt {
+	r := math.Hypot(pt[0], pt[1])
+	return Pt{pt[0] * (r + d) / r, pt[1] * (r + d) / r}
+}
+
+// spiralFrame places a spiral path: mirrored about the X axis (if
+// mirror is set), rotated counterclockwise by rotation degrees, and
+// moved to center.
+type spiralFrame struct {
+	center   Pt
+	rotation float64
+	mirror   bool
+}
+
+func (f spiralFrame) apply(pt Pt) Pt {
+	if f.mirror {
+		pt[1] = -pt[1]
+	}
+	p := RotatePt(pt, Pt{}, f.rotation)
+	return Pt{f.center[0] + p[0], f.center[1] + p[1]}
+}
+
+// trace returns the lines through the placed points.
+func (f spiralFrame) trace(pts []Pt, width float64) Group {
+	t := NewTrace(f.apply(pts[0]), width)
+	for _, pt := range pts[1:] {
+		t.LineTo(f.apply(pt))
+	}
+	return t.Primitives()
+}
+
+// reflect returns the frame of the spiral mirrored about the line
+// through the frame's center in the direction of pt.
+func (f spiralFrame) reflect(pt Pt) spiralFrame {
+	beta := AngleTo(f.center, pt)
+	return spiralFrame{center: f.center, rotation: 2*beta - f.rotation, mirror: !f.mirror}
+}
+
+// MultiLayerCoilOpts represents the options used by MultiLayerCoil.
+// All dimensions are in millimeters.
+type MultiLayerCoilOpts struct {
+	SpiralOpts
+	// Shape is the shape of the spirals: SquareCoil or CircularCoil (an
+	// Archimedean spiral).
+	Shape CoilShape
+	// Layers is the number of copper layers (at least 2).
+	Layers int
+	// ViaDrill and ViaPad are the drill and pad diameters of the vias
+	// between the layers.
+	ViaDrill float64
+	ViaPad   float64
+}
+
+// CoilStack represents a coil spanning several copper layers, as
+// generated by MultiLayerCoil.
+type CoilStack struct {
+	// Layers are the traces of each copper layer, in order.
+	Layers []Group
+	// Vias connect consecutive layers, alternately at the inner and
+	// outer ends of their spirals.
+	Vias []Via
+	// Start and End are the terminals of the coil: the outer end of the
+	// first layer's spiral, and the free end of the last layer's.
+	Start, End Pt
+	// Coil is the geometry of the coil, for inductance estimation (its
+	// Coupling is left to the caller).
+	Coil SpiralCoil
+}
+
+// AddTo adds the coil to the copper layers (one per layer of the coil,
+// in order) and its vias to the drill layer.
+func (c *CoilStack) AddTo(drill *Layer, copper ...*Layer) error {
+	if len(copper) != len(c.Layers) {
+		return fmt.Errorf("coil has %v layers, not %v", len(c.Layers), len(copper))
+	}
+	for i, layer := range copper {
+		layer.Add(c.Layers[i]...)
+	}
+	for _, v := range c.Vias {
+		v.AddTo(drill, copper...)
+	}
+	return nil
+}
+
+// MultiLayerCoil returns a coil about center made of a spiral on each
+// of several copper layers, connected in series by vias so that the
+// current circulates the same way on every layer: the first layer's
+// spiral runs inward from Start to a via inside its innermost turn,
+// the second layer's (its mirror image) runs outward to a via outside
+// its outermost turn, and so on. Since each layer's spiral is the
+// mirror image of the previous layer's about their shared via, the via
+// positions depend on Turns; an error is returned if a via would touch
+// the traces of a layer it does not connect (as whole turns do with
+// more than two layers).
+func MultiLayerCoil(center Pt, opts MultiLayerCoilOpts) (*CoilStack, error) {
+	o := opts
+	if o.Layers < 2 {
+		return nil, fmt.Errorf("invalid number of layers %v", o.Layers)
+	}
+	if o.ViaDrill <= 0 || o.ViaPad <= o.ViaDrill {
+		return nil, errors.New("invalid via size")
+	}
+	pts, coil, err := spiralPath(o.Shape, o.SpiralOpts)
+	if err != nil {
+		return nil, err
+	}
+	coil.Layers = o.Layers
+	// The vias lie a gap inside the innermost turn and outside the
+	// outermost turn, on leads from the ends of the spirals.
+	offset := 0.5*(o.ViaPad+o.TraceWidth) + o.Gap
+	inner, outer := pts[0], pts[len(pts)-1]
+	innerVia, outerVia := radialOffset(inner, -offset), radialOffset(outer, offset)
+	if math.Hypot(innerVia[0], innerVia[1]) < 0.5*o.ViaPad {
+		return nil, fmt.Errorf("inner radius %v is too small for the vias", o.InnerRadius)
+	}
+
+	c := &CoilStack{Coil: coil}
+	// connects holds the indices of the layers connected by each via.
+	var connects [][2]int
+	f := spiralFrame{center: center, rotation: o.StartAngle, mirror: o.Clockwise}
+	for k := 0; k < o.Layers; k++ {
+		path := pts
+		atInner := k%2 == 1 || k+1 < o.Layers // via at the inner end
+		atOuter := k%2 == 0 && k > 0 || k%2 == 1 && k+1 < o.Layers
+		if atInner {
+			path = append([]Pt{innerVia}, path...)
+		}
+		if atOuter {
+			path = append(append([]Pt{}, path...), outerVia)
+		}
+		if k == 0 {
+			c.Start = f.apply(outer)
+			if o.Lead > 0 {
+				c.Start = f.apply(radialOffset(outer, o.Lead))
+				path = append(append([]Pt{}, path...), radialOffset(outer, o.Lead))
+			}
+		}
+		if k == o.Layers-1 {
+			if atOuter {
+				end := radialOffset(inner, -o.Lead)
+				path = append([]Pt{end}, path...)
+				c.End = f.apply(end)
+			} else {
+				end := radialOffset(outer, o.Lead)
+				path = append(append([]Pt{}, path...), end)
+				c.End = f.apply(end)
+			}
+		}
+		c.Layers = append(c.Layers, f.trace(path, o.TraceWidth))
+		if k+1 == o.Layers {
+			break
+		}
+		shared := innerVia
+		if k%2 == 1 {
+			shared = outerVia
+		}
+		at := f.apply(shared)
+		c.Vias = append(c.Vias, Via{Center: at, Drill: o.ViaDrill, Pad: o.ViaPad})
+		connects = append(connects, [2]int{k, k + 1})
+		f = f.reflect(at)
+	}
+
+	for i, v := range c.Vias {
+		pad := outlineOf(Circle(v.Center, o.ViaPad+2*o.Gap))
+		for k, traces := range c.Layers {
+			if k == connects[i][0] || k == connects[i][1] {
+				continue
+			}
+			for _, p := range traces {
+				if outlineOf(p).overlaps(pad) {
+					return nil, fmt.Errorf("the via at %v between layers %v and %v touches layer %v (try a different number of turns)",
+						fmtPt(v.Center), connects[i][0]+1, connects[i][1]+1, k+1)
+				}
+			}
+		}
+	}
+	return c, nil
+}
@@ -0,0 +1,105 @@
+package gerber
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestArchimedeanSpiral(t *testing.T) {
+	s, err := ArchimedeanSpiral(Pt{10, 10}, SpiralOpts{Turns: 3, InnerRadius: 2, TraceWidth: 0.2, Gap: 0.2, StartAngle: 90, Lead: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := s.Start, (Pt{10, 11}); Distance(got, want) > 1e-9 {
+		t.Errorf("Start = %v, want %v", got, want)
+	}
+	if got, want := s.End, (Pt{10, 14.2}); Distance(got, want) > 1e-9 {
+		t.Errorf("End = %v, want %v", got, want)
+	}
+	if got, want := s.Coil, (SpiralCoil{Shape: CircularCoil, Turns: 3, OuterDiameter: 6.4, InnerDiameter: 4}); math.Abs(got.OuterDiameter-want.OuterDiameter) > 1e-9 || got.InnerDiameter != want.InnerDiameter || got.Turns != want.Turns || got.Shape != want.Shape {
+		t.Errorf("Coil = %+v, want %+v", got, want)
+	}
+	// The centerline stays within the tolerance of the spiral.
+	for _, p := range s.Traces {
+		l := p.(*LineT)
+		mid := Midpoint(l.P1, l.P2)
+		r := Distance(Pt{10, 10}, mid)
+		if r < 0.99 || r > 4.21 {
+			t.Errorf("segment midpoint %v at radius %v", mid, r)
+		}
+	}
+}
+
+func TestSquareSpiral(t *testing.T) {
+	s, err := SquareSpiral(Pt{}, SpiralOpts{Turns: 1.5, InnerRadius: 1, TraceWidth: 0.25, Gap: 0.25})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []Pt
+	for _, p := range s.Traces {
+		got = append(got, p.(*LineT).P2)
+	}
+	want := []Pt{{1, 1}, {-1, 1}, {-1, -1}, {1.5, -1}, {1.5, 1.5}, {-1.5, 1.5}, {-1.5, 0}}
+	if len(got) != len(want) {
+		t.Fatalf("corners = %v, want %v", got, want)
+	}
+	for i := range want {
+		if Distance(got[i], want[i]) > 1e-9 {
+			t.Errorf("corner %v = %v, want %v", i, got[i], want[i])
+		}
+	}
+	if s.Start != (Pt{1, 0}) || s.End != (Pt{-1.5, 0}) || s.Coil.OuterDiameter != 3 {
+		t.Errorf("Start, End, Coil = %v, %v, %+v", s.Start, s.End, s.Coil)
+	}
+
+	if _, err := SquareSpiral(Pt{}, SpiralOpts{Turns: 1, InnerRadius: 1}); err == nil {
+		t.Error("SquareSpiral(no trace width) = nil, want error")
+	}
+}
+
+func TestMultiLayerCoil(t *testing.T) {
+	opts := MultiLayerCoilOpts{
+		SpiralOpts: SpiralOpts{Turns: 4.25, InnerRadius: 2, TraceWidth: 0.2, Gap: 0.2},
+		Shape:      SquareCoil,
+		Layers:     4,
+		ViaDrill:   0.3,
+		ViaPad:     0.6,
+	}
+	c, err := MultiLayerCoil(Pt{}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Layers) != 4 || len(c.Vias) != 3 {
+		t.Fatalf("layers, vias = %v, %v, want 4, 3", len(c.Layers), len(c.Vias))
+	}
+	// Inner vias are inside the innermost turn, the outer via outside
+	// the outermost one.
+	for i, want := range []float64{1.4, 2 + 4*0.4 + 0.6, 1.4} {
+		if got := math.Max(math.Abs(c.Vias[i].Center[0]), math.Abs(c.Vias[i].Center[1])); math.Abs(got-want) > 1e-9 {
+			t.Errorf("via %v at %v, want half-size %v", i, c.Vias[i].Center, want)
+		}
+	}
+	if c.Coil.Layers != 4 {
+		t.Errorf("Coil.Layers = %v, want 4", c.Coil.Layers)
+	}
+
+	g := New("coil")
+	copper := []*Layer{g.TopCopper(), g.LayerN(2), g.LayerN(3), g.BottomCopper()}
+	if err := c.AddTo(g.Drill(), copper...); err != nil {
+		t.Fatal(err)
+	}
+	conn, err := g.Connectivity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conn.Nets) != 1 {
+		t.Errorf("nets = %v, want the coil as one net", len(conn.Nets))
+	}
+
+	// Whole turns stack the inner vias of layers 1-2 and 3-4.
+	opts.Turns = 4
+	if _, err := MultiLayerCoil(Pt{}, opts); err == nil || !strings.Contains(err.Error(), "try a different number of turns") {
+		t.Errorf("MultiLayerCoil(4 turns) = %v, want via error", err)
+	}
+}
@@ -0,0 +1,385 @@
+package gerber
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// CheckGerber checks that a Gerber file (such as one written by this
+// package) follows the rules of the RS-274X grammar that fabs reject
+// files for, and returns an error naming the line of the first
+// violation. It checks that:
+//   - the coordinate format (FS) and units (MO) precede all operations,
+//     and coordinates fit in the format (leading zeros are allowed);
+//   - aperture macros (AM) are valid and are defined before the
+//     apertures that use them;
+//   - apertures are defined once, before they are selected, and an
+//     aperture is selected before the first draw (D01) or flash (D03);
+//   - regions (G36/G37) are not nested, do not contain flashes,
+//     aperture selections, or polarity changes, and their contours are
+//     closed;
+//   - circular interpolation (G02/G03) is in multi-quadrant mode (G75);
+//   - step and repeat blocks (%SR*%) are not nested and are closed;
+//   - the file ends with M02.
+//
+// See WithOutputValidation to check the files of a design as they are
+// written.
+func CheckGerber(r io.Reader) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	c := newComplianceChecker(nil)
+	if _, err := c.Write(data); err != nil {
+		return err
+	}
+	return c.Close()
+}
+
+// complianceChecker is an io.Writer that checks the Gerber commands
+// written to it (see CheckGerber), passing them through to w (if not
+// nil). Commands may span writes. The first violation is returned by
+// Write and recorded in err.
+type complianceChecker struct {
+	w    io.Writer
+	buf  []byte
+	line int
+	err  error
+
+	format     *CoordinateFormat
+	units      bool
+	apertures  map[int]bool
+	macros     map[string]bool
+	aperture   int
+	interp     int // 1 (linear), 2 (clockwise), or 3 (counterclockwise)
+	multiQuad  bool
+	inRegion   bool
+	stepRepeat bool
+	contour    bool
+	start, pos [2]int64
+	ended      bool
+}
+
+func newComplianceChecker(w io.Writer) *complianceChecker {
+	return &complianceChecker{w: w, line: 1, apertures: map[int]bool{}, macros: map[string]bool{}, interp: 1}
+}
+
+var errComplianceEnded = errors.New("commands after end of file (M02)")
+
+// Write checks the complete commands in p (and in earlier writes).
+func (c *complianceChecker) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	if c.w != nil {
+		if n, err := c.w.Write(p); err != nil {
+			return n, err
+		}
+	}
+	c.buf = append(c.buf, p...)
+	for c.err == nil {
+		data := bytes.TrimLeft(c.buf, " \t\r\n")
+		c.line += bytes.Count(c.buf[:len(c.buf)-len(data)], []byte("\n"))
+		c.buf = data
+		if len(data) == 0 {
+			break
+		}
+		if data[0] == '%' {
+			end := bytes.IndexByte(data[1:], '%')
+			if end < 0 {
+				break
+			}
+			if block := string(data[1 : end+1]); strings.HasPrefix(strings.TrimSpace(block), "AM") {
+				c.fail(c.macro(block))
+				c.line += bytes.Count(data[:end+2], []byte("\n"))
+				c.buf = data[end+2:]
+				continue
+			}
+			for _, cmd := range strings.Split(string(data[1:end+1]), "*") {
+				if cmd = strings.TrimSpace(cmd); cmd != "" && c.err == nil {
+					c.fail(c.extended(cmd))
+				}
+			}
+			c.line += bytes.Count(data[:end+2], []byte("\n"))
+			c.buf = data[end+2:]
+			continue
+		}
+		end := bytes.IndexByte(data, '*')
+		if end < 0 {
+			break
+		}
+		c.fail(c.word(strings.Join(strings.Fields(string(data[:end])), "")))
+		c.line += bytes.Count(data[:end+1], []byte("\n"))
+		c.buf = data[end+1:]
+	}
+	if c.err != nil {
+		return 0, c.err
+	}
+	return len(p), nil
+}
+
+// Close checks that the file is complete.
+func (c *complianceChecker) Close() error {
+	if c.err == nil && len(bytes.TrimSpace(c.buf)) > 0 {
+		c.fail(errors.New("unterminated command"))
+	}
+	if c.err == nil && !c.ended {
+		c.err = errors.New("missing end of file (M02)")
+	}
+	return c.err
+}
+
+func (c *complianceChecker) fail(err error) {
+	if err != nil && c.err == nil {
+		c.err = fmt.Errorf("line %v: %v", c.line, err)
+	}
+}
+
+func (c *complianceChecker) extended(cmd string) error {
+	if c.ended {
+		return errComplianceEnded
+	}
+	if c.inRegion && !strings.HasPrefix(cmd, "TO") && !strings.HasPrefix(cmd, "TD") {
+		return fmt.Errorf("%%%v*%% inside a region", cmd)
+	}
+	switch {
+	case strings.HasPrefix(cmd, "FS"):
+		var xi, xd, yi, yd int
+		if _, err := fmt.Sscanf(cmd, "FSLAX%1d%1dY%1d%1d", &xi, &xd, &yi, &yd); err != nil || xi != yi || xd != yd {
+			return fmt.Errorf("invalid format %q", cmd)
+		}
+		if xi < 1 || xi > maxIntegerDigits || xd < 1 || xd > 6 {
+			return fmt.Errorf("format %q must have 1 to %v integer and 1 to 6 decimal digits", cmd, maxIntegerDigits)
+		}
+		if c.format != nil {
+			return errors.New("duplicate format (FS)")
+		}
+		c.format = &CoordinateFormat{Integer: xi, Decimal: xd}
+	case cmd == "MOMM", cmd == "MOIN":
+		c.units = true
+	case strings.HasPrefix(cmd, "AD"):
+		if !strings.HasPrefix(cmd, "ADD") {
+			return fmt.Errorf("invalid aperture definition %q", cmd)
+		}
+		digits := strings.IndexFunc(cmd[3:], func(r rune) bool { return r < '0' || r > '9' })
+		if digits <= 0 {
+			return fmt.Errorf("invalid aperture definition %q", cmd)
+		}
+		code, _ := strconv.Atoi(cmd[3 : 3+digits])
+		template := strings.SplitN(cmd[3+digits:], ",", 2)[0]
+		if !c.macros[template] && template != "C" && template != "R" && template != "O" && template != "P" {
+			return fmt.Errorf("aperture D%v uses undefined macro %q", code, template)
+		}
+		if code < 10 {
+			return fmt.Errorf("aperture D%v: D-codes below 10 are reserved", code)
+		}
+		if c.apertures[code] {
+			return fmt.Errorf("aperture D%v defined twice", code)
+		}
+		c.apertures[code] = true
+	case cmd == "SR":
+		if !c.stepRepeat {
+			return errors.New("end of step and repeat (%SR*%) outside a block")
+		}
+		c.stepRepeat = false
+	case strings.HasPrefix(cmd, "SR"):
+		var x, y int
+		var i, j float64
+		if _, err := fmt.Sscanf(cmd, "SRX%dY%dI%gJ%g", &x, &y, &i, &j); err != nil || x < 1 || y < 1 || i < 0 || j < 0 {
+			return fmt.Errorf("invalid step and repeat %q", cmd)
+		}
+		if c.stepRepeat {
+			return errors.New("nested step and repeat block")
+		}
+		c.stepRepeat = true
+	case cmd == "LPD", cmd == "LPC", strings.HasPrefix(cmd, "T"):
+	default:
+		return fmt.Errorf("unknown command %%%v*%%", cmd)
+	}
+	return nil
+}
+
+// macro checks an aperture macro definition (the words of %AM...%).
+// Unlike ParseApertureMacro, it allows variable definitions.
+func (c *complianceChecker) macro(block string) error {
+	if c.ended {
+		return errComplianceEnded
+	}
+	if c.inRegion {
+		return errors.New("aperture macro inside a region")
+	}
+	words := strings.Split(block, "*")
+	name := strings.TrimSpace(words[0])[2:]
+	if !validAttributeName(name) {
+		return fmt.Errorf("invalid aperture macro name %q", name)
+	}
+	for _, w := range words[1:] {
+		if w = strings.TrimSpace(w); strings.HasPrefix(w, "$") {
+			continue
+		}
+		p, ok, err := parseMacroPrimitive(w)
+		if err != nil {
+			return fmt.Errorf("aperture macro %v: %v", name, err)
+		}
+		if ok {
+			if err := p.check(); err != nil {
+				return fmt.Errorf("aperture macro %v: %v", name, err)
+			}
+		}
+	}
+	c.macros[name] = true
+	return nil
+}
+
+func (c *complianceChecker) word(cmd string) error {
+	if c.ended {
+		return errComplianceEnded
+	}
+	switch {
+	case cmd == "", strings.HasPrefix(cmd, "G04"):
+		return nil
+	case cmd == "M02":
+		if c.inRegion {
+			return errors.New("end of file (M02) inside a region")
+		}
+		if c.stepRepeat {
+			return errors.New("end of file (M02) inside a step and repeat block")
+		}
+		c.ended = true
+		return nil
+	case cmd == "G36":
+		if c.inRegion {
+			return errors.New("nested region (G36)")
+		}
+		c.inRegion, c.contour = true, false
+		return nil
+	case cmd == "G37":
+		if !c.inRegion {
+			return errors.New("end of region (G37) outside a region")
+		}
+		if err := c.closeContour(); err != nil {
+			return err
+		}
+		c.inRegion = false
+		return nil
+	case cmd == "G74":
+		c.multiQuad = false
+		return nil
+	case cmd == "G75":
+		c.multiQuad = true
+		return nil
+	case strings.HasPrefix(cmd, "G54"):
+		if cmd = cmd[3:]; cmd == "" {
+			return nil
+		}
+	}
+	if len(cmd) >= 3 && cmd[0] == 'G' && cmd[1] == '0' && cmd[2] >= '1' && cmd[2] <= '3' {
+		c.interp = int(cmd[2] - '0')
+		if cmd = cmd[3:]; cmd == "" {
+			return nil
+		}
+	}
+	if cmd[0] == 'D' {
+		code, err := strconv.Atoi(cmd[1:])
+		if err != nil || code < 10 {
+			return fmt.Errorf("unknown command %q", cmd)
+		}
+		if c.inRegion {
+			return fmt.Errorf("aperture selection D%v inside a region", code)
+		}
+		if !c.apertures[code] {
+			return fmt.Errorf("aperture D%v selected before it is defined", code)
+		}
+		c.aperture = code
+		return nil
+	}
+	return c.operation(cmd)
+}
+
+// operation checks a coordinate data word with a D01, D02, or D03
+// operation.
+func (c *complianceChecker) operation(cmd string) error {
+	if c.format == nil || !c.units {
+		return fmt.Errorf("operation %q before the format (FS) and units (MO)", cmd)
+	}
+	pt, d := c.pos, 0
+	var offset bool
+	for rest := cmd; rest != ""; {
+		letter := rest[0]
+		end := 1
+		for end < len(rest) && (rest[end] == '-' || rest[end] == '+' || (rest[end] >= '0' && rest[end] <= '9')) {
+			end++
+		}
+		digits := rest[1:end]
+		rest = rest[end:]
+		v, err := strconv.ParseInt(digits, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid command %q", cmd)
+		}
+		switch letter {
+		case 'X', 'Y', 'I', 'J':
+			if max := int64(math.Pow(10, float64(c.format.Integer+c.format.Decimal))) - 1; v > max || v < -max {
+				return fmt.Errorf("coordinate %v%v exceeds the %v.%v format", string(letter), digits, c.format.Integer, c.format.Decimal)
+			}
+			switch letter {
+			case 'X':
+				pt[0] = v
+			case 'Y':
+				pt[1] = v
+			default:
+				offset = true
+			}
+		case 'D':
+			if d != 0 || rest != "" || v < 1 || v > 3 {
+				return fmt.Errorf("invalid command %q", cmd)
+			}
+			d = int(v)
+		default:
+			return fmt.Errorf("unknown command %q", cmd)
+		}
+	}
+	if d == 0 {
+		return fmt.Errorf("deprecated modal operation %q (missing D01, D02, or D03)", cmd)
+	}
+	if c.interp != 1 && d == 1 {
+		if !c.multiQuad {
+			return fmt.Errorf("circular interpolation %q in single quadrant mode (G74)", cmd)
+		}
+		if !offset {
+			return fmt.Errorf("circular interpolation %q without a center offset", cmd)
+		}
+	}
+	switch {
+	case d == 3 && c.inRegion:
+		return fmt.Errorf("flash %q inside a region", cmd)
+	case d == 2 && c.inRegion:
+		if err := c.closeContour(); err != nil {
+			return err
+		}
+		c.start = pt
+	case d == 1 && c.inRegion:
+		if !c.contour {
+			c.contour, c.start = true, c.pos
+		}
+	case d != 2 && c.aperture == 0:
+		return fmt.Errorf("operation %q before an aperture is selected", cmd)
+	}
+	c.pos = pt
+	return nil
+}
+
+// closeContour checks that the current contour (if any) of a region is
+// closed, and ends it.
+func (c *complianceChecker) closeContour() error {
+	if c.contour && c.pos != c.start {
+		return fmt.Errorf("region contour from X%vY%v is not closed", c.start[0], c.start[1])
+	}
+	c.contour = false
+	return nil
+}
@@ -0,0 +1,125 @@
+package gerber
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+
+	_ "github.com/gmlewis/go-fonts/fonts/freeserif"
+)
+
+func TestCheckGerber(t *testing.T) {
+	const header = "%FSLAX36Y36*%\n%MOMM*%\n%ADD10C,0.10000*%\n"
+	tests := []struct {
+		name    string
+		file    string
+		wantErr string
+	}{
+		{name: "valid", file: header + "G54D10*\nX0Y0D02*\nX1000000Y0D01*\nG36*\nX0Y0D02*\nX1000000Y0D01*\nX0Y1000000D01*\nX0Y0D01*\nG37*\nM02*\n"},
+		{name: "bare G54", file: "%FSLAX36Y36*%%MOMM*%G54*M02*"},
+		{name: "no format", file: "%MOMM*%\n%ADD10C,0.1*%\nD10*\nX0Y0D03*\nM02*\n", wantErr: "line 4: operation \"X0Y0D03\" before the format"},
+		{name: "undefined aperture", file: header + "G54D11*\nM02*\n", wantErr: "line 4: aperture D11 selected before it is defined"},
+		{name: "duplicate aperture", file: header + "%ADD10R,1X1*%\nM02*\n", wantErr: "aperture D10 defined twice"},
+		{name: "no aperture", file: header + "X0Y0D03*\nM02*\n", wantErr: "before an aperture is selected"},
+		{name: "coordinate range", file: "%FSLAX14Y14*%\n%MOMM*%\n%ADD10C,0.1*%\nD10*\nX123456Y0D03*\nM02*\n", wantErr: "coordinate X123456 exceeds the 1.4 format"},
+		{name: "nested region", file: header + "G36*\nG36*\n", wantErr: "nested region"},
+		{name: "flash in region", file: header + "D10*\nG36*\nX0Y0D03*\n", wantErr: "flash \"X0Y0D03\" inside a region"},
+		{name: "polarity in region", file: header + "G36*\n%LPC*%\n", wantErr: "%LPC*% inside a region"},
+		{name: "open contour", file: header + "G36*\nX0Y0D02*\nX1000000Y0D01*\nX0Y1000000D01*\nG37*\nM02*\n", wantErr: "line 8: region contour from X0Y0 is not closed"},
+		{name: "single quadrant", file: header + "D10*\nG74*\nG02*\nX0Y0I1000J0D01*\nM02*\n", wantErr: "single quadrant mode"},
+		{name: "no end", file: header, wantErr: "missing end of file"},
+		{name: "after end", file: header + "M02*\nD10*\n", wantErr: "commands after end of file"},
+		{name: "macro", file: header + "%AMBOX*\n21,1,$1,$1,0,0,0*\n%\n%ADD11BOX,1*%\nD11*\nX0Y0D03*\nM02*\n"},
+		{name: "undefined macro", file: header + "%ADD11BOX,1*%\nM02*\n", wantErr: "aperture D11 uses undefined macro \"BOX\""},
+		{name: "unknown", file: header + "%XY1*%\nM02*\n", wantErr: "unknown command %XY1*%"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckGerber(strings.NewReader(tt.file))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("CheckGerber = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("CheckGerber = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// randomPrimitive returns a random primitive of one of the package's
+// primitive types, within a 100mm square.
+func randomPrimitive(r *rand.Rand) Primitive {
+	pt := func() Pt { return Pt{100 * r.Float64(), 100 * r.Float64()} }
+	shapes := []Shape{CircleShape, RectShape}
+	size := 0.1 + r.Float64()
+	switch r.Intn(8) {
+	case 0:
+		return Circle(pt(), size)
+	case 1:
+		p1, p2 := pt(), pt()
+		return Line(p1[0], p1[1], p2[0], p2[1], shapes[r.Intn(2)], size)
+	case 2:
+		start := 360 * r.Float64()
+		return Arc(pt(), 1+10*r.Float64(), CircleShape, 1, 1, start, start+360*r.Float64(), 0.2)
+	case 3:
+		return Polygon(pt(), true, []Pt{{0, 0}, {size, 0}, {size, 2 * size}}, 0)
+	case 4:
+		c := pt()
+		start := Pt{c[0] + size, c[1]}
+		return Region(start, LineTo(Pt{c[0], c[1] + size}), ArcTo(start, c, r.Intn(2) == 0))
+	case 5:
+		p := pt()
+		return Text(p[0], p[1], 1, "Hi", "freeserif", 12, nil)
+	case 6:
+		return NetTie("a", pt(), "b", pt(), size, 0.5*size)
+	}
+	return Clear(Circle(pt(), size))
+}
+
+func TestWithOutputValidation_Random(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 50; i++ {
+		opts := []Option{WithOutputValidation(true), WithNativeArcs(i%2 == 0)}
+		if i%3 == 0 {
+			opts = append(opts, WithUnits(UnitsInch))
+		}
+		g := New("fuzz", opts...)
+		top := g.TopCopper()
+		for j := 0; j < 20; j++ {
+			top.Add(randomPrimitive(r))
+		}
+		var buf bytes.Buffer
+		if err := top.WriteGerber(&buf); err != nil {
+			t.Fatalf("#%v: WriteGerber: %v", i, err)
+		}
+		if err := CheckGerber(&buf); err != nil {
+			t.Fatalf("#%v: CheckGerber: %v", i, err)
+		}
+	}
+}
+
+// badPrimitive writes a flash inside a region.
+type badPrimitive struct{ CircleT }
+
+func (b *badPrimitive) WriteGerber(w io.Writer, apertureIndex int) error {
+	io.WriteString(w, "G36*\nX0Y0D03*\nG37*\n")
+	return nil
+}
+
+func TestWithOutputValidation(t *testing.T) {
+	g := New("board", WithOutputValidation(true))
+	top := g.TopCopper()
+	top.Add(Circle(Pt{1, 1}, 1), &badPrimitive{*Circle(Pt{2, 2}, 1)})
+	err := top.WriteGerber(&bytes.Buffer{})
+	if want := "layer board.gtl: primitive #1 (*gerber.badPrimitive): line 10: flash \"X0Y0D03\" inside a region"; err == nil || err.Error() != want {
+		t.Errorf("WriteGerber = %v, want %v", err, want)
+	}
+	if err := New("board").TopCopper().WriteGerber(&bytes.Buffer{}); err != nil {
+		t.Errorf("WriteGerber of an empty layer = %v, want nil", err)
+	}
+}
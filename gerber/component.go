@@ -0,0 +1,203 @@
+package gerber
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Component represents a part to be placed by an assembly house: a
+// placed footprint (see Footprint.Place) or a part added with
+// AddComponent. All dimensions are in millimeters.
+type Component struct {
+	// Designator is the part's reference designator (e.g. "R1").
+	Designator string `json:"designator"`
+	// Package names the part's footprint (e.g. "0603").
+	Package string `json:"package,omitempty"`
+	// Center is the centroid of the part and Rotation its rotation in
+	// degrees (counterclockwise, as seen from the top).
+	Center   Pt      `json:"center"`
+	Rotation float64 `json:"rotation,omitempty"`
+	Side     Side    `json:"side,omitempty"`
+	// Value (e.g. "10k") and MPN (the manufacturer's part number)
+	// identify the part to buy, for the bill of materials (see BOM).
+	Value string `json:"value,omitempty"`
+	MPN   string `json:"mpn,omitempty"`
+}
+
+// AddComponent adds a component to the design's pick-and-place data
+// (see WriteCentroid).
+func (g *Gerber) AddComponent(c *Component) {
+	g.components = append(g.components, c)
+}
+
+// Components returns the design's components, in the order they were
+// added.
+func (g *Gerber) Components() []*Component {
+	return g.components
+}
+
+// Component returns the design's component with the given designator,
+// or nil if there is none, so that its metadata (such as its Value)
+// can be set after its footprint is placed.
+func (g *Gerber) Component(designator string) *Component {
+	for _, c := range g.components {
+		if c.Designator == designator {
+			return c
+		}
+	}
+	return nil
+}
+
+// CentroidFilename returns the filename of the design's pick-and-place
+// file, which Write writes (after the layers) if the design has any
+// components.
+func (g *Gerber) CentroidFilename() string {
+	return g.FilenamePrefix + "-centroid.csv"
+}
+
+// WriteCentroid writes the design's pick-and-place (centroid) file as
+// CSV in the format accepted by JLCPCB and most other assembly houses:
+// the designator, position (after the design's origin and export
+// transform are applied), side ("Top" or "Bottom"), and rotation of
+// each component. It returns an error if two components have the same
+// designator.
+func (g *Gerber) WriteCentroid(w io.Writer) error {
+	seen := map[string]bool{}
+	for _, c := range g.components {
+		if c.Designator == "" {
+			return fmt.Errorf("component at %v has no designator", fmtPt(c.Center))
+		}
+		if seen[c.Designator] {
+			return fmt.Errorf("duplicate designator %v", c.Designator)
+		}
+		seen[c.Designator] = true
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"Designator", "Mid X", "Mid Y", "Layer", "Rotation"})
+	for _, c := range g.components {
+		center := g.exportPt(c.Center)
+		layer := "Top"
+		if c.Side == SideBottom {
+			layer = "Bottom"
+		}
+		cw.Write([]string{c.Designator, fmtFloat(center[0]) + "mm", fmtFloat(center[1]) + "mm", layer, fmtFloat(NormalizeAngle(c.Rotation))})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeCentroid writes the design's pick-and-place file (if it has any
+// components) to the io.WriteCloser returned by create.
+func (g *Gerber) writeCentroid(create func(filename string) (io.WriteCloser, error)) error {
+	if len(g.components) == 0 {
+		return nil
+	}
+	w, err := create(g.CentroidFilename())
+	if err != nil {
+		return err
+	}
+	if err := g.WriteCentroid(w); err != nil {
+		abort(w)
+		return err
+	}
+	return w.Close()
+}
+
+// BOMLine represents a line of a bill of materials: the components
+// with the same value, package, and part number.
+type BOMLine struct {
+	Designators []string `json:"designators"`
+	Quantity    int      `json:"quantity"`
+	Value       string   `json:"value,omitempty"`
+	Package     string   `json:"package,omitempty"`
+	MPN         string   `json:"mpn,omitempty"`
+}
+
+// BOM returns the design's bill of materials, with a line for each
+// distinct value, package, and part number of its components in the
+// order they first appear. The designators of each line are sorted
+// naturally (e.g. R2 before R10).
+func (g *Gerber) BOM() []*BOMLine {
+	var lines []*BOMLine
+	index := map[[3]string]*BOMLine{}
+	for _, c := range g.components {
+		key := [3]string{c.Value, c.Package, c.MPN}
+		line, ok := index[key]
+		if !ok {
+			line = &BOMLine{Value: c.Value, Package: c.Package, MPN: c.MPN}
+			index[key] = line
+			lines = append(lines, line)
+		}
+		line.Designators = append(line.Designators, c.Designator)
+		line.Quantity++
+	}
+	for _, line := range lines {
+		sort.SliceStable(line.Designators, func(i, j int) bool {
+			return designatorLess(line.Designators[i], line.Designators[j])
+		})
+	}
+	return lines
+}
+
+// designatorLess orders reference designators by their prefix and then
+// by their (trailing) number.
+func designatorLess(a, b string) bool {
+	split := func(s string) (string, int, bool) {
+		i := len(s)
+		for i > 0 && s[i-1] >= '0' && s[i-1] <= '9' {
+			i--
+		}
+		n, err := strconv.Atoi(s[i:])
+		return s[:i], n, err == nil
+	}
+	pa, na, aok := split(a)
+	pb, nb, bok := split(b)
+	if pa != pb || !aok || !bok || na == nb {
+		return a < b
+	}
+	return na < nb
+}
+
+// BOMFormat is the file format of a bill of materials.
+type BOMFormat int
+
+const (
+	// BOMCSV writes a header and a line per BOMLine, with its
+	// designators separated by commas.
+	BOMCSV BOMFormat = iota
+	// BOMJSON writes an array of BOMLines.
+	BOMJSON
+)
+
+// WriteBOM writes the design's bill of materials (see BOM) in the
+// given format.
+func (g *Gerber) WriteBOM(w io.Writer, format BOMFormat) error {
+	lines := g.BOM()
+	switch format {
+	case BOMCSV:
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"Designator", "Quantity", "Value", "Package", "MPN"})
+		for _, line := range lines {
+			cw.Write([]string{strings.Join(line.Designators, ","), strconv.Itoa(line.Quantity), line.Value, line.Package, line.MPN})
+		}
+		cw.Flush()
+		return cw.Error()
+	case BOMJSON:
+		if lines == nil {
+			lines = []*BOMLine{}
+		}
+		data, err := json.MarshalIndent(lines, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(data, '\n'))
+		return err
+	}
+	return fmt.Errorf("unknown BOM format %v", format)
+}
@@ -0,0 +1,121 @@
+package gerber
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGerber_WriteCentroid(t *testing.T) {
+	g := New("test", WithOrigin(Pt{-10, -10}))
+	chip, err := ChipFootprint("0603")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := chip.Named("R1").Place(g, Pt{10, 5}, 90); err != nil {
+		t.Fatal(err)
+	}
+	header, err := HeaderFootprint(1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := header.Named("J1").Place(g, Pt{0, 0}, -90); err != nil {
+		t.Fatal(err)
+	}
+	bottom, err := chip.Named("C1").Flipped()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bottom.Place(g, Pt{20, 0}, 0); err != nil {
+		t.Fatal(err)
+	}
+	g.AddComponent(&Component{Designator: "FID1", Center: Pt{1, 2}})
+
+	if got, want := g.Components()[0], (&Component{Designator: "R1", Package: "0603", Center: Pt{10, 5}, Rotation: 90}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Components[0] = %+v, want %+v", got, want)
+	}
+	var buf bytes.Buffer
+	if err := g.WriteCentroid(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := `Designator,Mid X,Mid Y,Layer,Rotation
+R1,20mm,15mm,Top,90
+J1,10mm,7.46mm,Top,270
+C1,30mm,10mm,Bottom,0
+FID1,11mm,12mm,Top,0
+`
+	if got := buf.String(); got != want {
+		t.Errorf("WriteCentroid =\n%v\nwant\n%v", got, want)
+	}
+
+	files := map[string]*memFile{}
+	err = g.Write(func(filename string) (io.WriteCloser, error) {
+		f := &memFile{}
+		files[filename] = f
+		return f, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := files[g.CentroidFilename()]; f == nil || f.String() != want || !f.closed {
+		t.Errorf("Write did not write %v", g.CentroidFilename())
+	}
+
+	g.AddComponent(&Component{Designator: "R1"})
+	if err := g.WriteCentroid(&buf); err == nil || !strings.Contains(err.Error(), "duplicate designator R1") {
+		t.Errorf("WriteCentroid = %v, want duplicate designator error", err)
+	}
+}
+
+func TestGerber_WriteBOM(t *testing.T) {
+	g := New("test")
+	chip, err := ChipFootprint("0603")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, name := range []string{"R10", "C1", "R2", "R1"} {
+		if err := chip.Named(name).Place(g, Pt{float64(i) * 5, 0}, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"R10", "R2"} {
+		g.Component(name).Value = "10k"
+	}
+	g.Component("C1").Value, g.Component("C1").MPN = "100n", "CL10B104KB8NNNC"
+	g.Component("R1").Value = "1k"
+	if g.Component("R3") != nil {
+		t.Error("Component(R3) != nil")
+	}
+
+	var buf bytes.Buffer
+	if err := g.WriteBOM(&buf, BOMCSV); err != nil {
+		t.Fatal(err)
+	}
+	want := `Designator,Quantity,Value,Package,MPN
+"R2,R10",2,10k,0603,
+C1,1,100n,0603,CL10B104KB8NNNC
+R1,1,1k,0603,
+`
+	if got := buf.String(); got != want {
+		t.Errorf("WriteBOM(CSV) =\n%v\nwant\n%v", got, want)
+	}
+
+	buf.Reset()
+	if err := g.WriteBOM(&buf, BOMJSON); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), `"designators": [
+      "R2",
+      "R10"
+    ],
+    "quantity": 2,
+    "value": "10k",
+    "package": "0603"`; !strings.Contains(got, want) {
+		t.Errorf("WriteBOM(JSON) =\n%v\nwant it to contain\n%v", got, want)
+	}
+	if err := g.WriteBOM(&buf, BOMFormat(-1)); err == nil {
+		t.Error("WriteBOM(unknown format) = nil, want error")
+	}
+}
@@ -0,0 +1,251 @@
+package gerber
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Node is a primitive of a design's net graph: copper on a copper
+// layer or a plated hole on a drill layer.
+type Node struct {
+	Layer     *Layer
+	Primitive Primitive
+}
+
+// Net is a set of electrically connected nodes: copper that touches or
+// overlaps on a layer, joined across layers by the plated holes (such
+// as vias) that touch it.
+type Net struct {
+	// Nodes are the net's nodes, in layer and then primitive order.
+	Nodes []Node
+	// Names are the sorted names of the nets tagged on its nodes (see
+	// NetTag), which normally number at most one.
+	Names []string
+	// ties are the net ties (see NetTie) among its nodes.
+	ties []*NetTieT
+}
+
+// Connectivity represents the net graph of a design, as returned by
+// Gerber.Connectivity. Plated holes connect all copper layers.
+type Connectivity struct {
+	// Nets are the design's nets, in the order of their first node.
+	Nets  []*Net
+	netOf map[Node]*Net
+}
+
+// Connectivity derives the design's pads (see DeriveOpenings) and
+// returns its net graph, built from its copper layers and the plated
+// holes of its drill layers (those not tagged with NonPlatedTag).
+func (g *Gerber) Connectivity() (*Connectivity, error) {
+	if err := g.DeriveOpenings(); err != nil {
+		return nil, err
+	}
+	var nodes []Node
+	index := map[Node]int{}
+	var copper []*Layer
+	for _, layer := range g.Layers {
+		switch {
+		case layer.Type.IsCopper():
+			copper = append(copper, layer)
+		case layer.Type != DrillLayer:
+			continue
+		}
+		for _, p := range layer.Primitives {
+			if layer.Type == DrillLayer && layer.HasTag(p, NonPlatedTag) {
+				continue
+			}
+			n := Node{Layer: layer, Primitive: p}
+			index[n] = len(nodes)
+			nodes = append(nodes, n)
+		}
+	}
+
+	// Union-find of connected nodes.
+	parent := make([]int, len(nodes))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	union := func(i, j int) {
+		parent[find(j)] = find(i)
+	}
+	for _, layer := range copper {
+		for _, island := range layer.Islands(IslandOpts{}) {
+			first := index[Node{Layer: layer, Primitive: island.Primitives[0]}]
+			for _, p := range island.Primitives[1:] {
+				union(first, index[Node{Layer: layer, Primitive: p}])
+			}
+		}
+	}
+	for i, hole := range nodes {
+		if hole.Layer.Type != DrillLayer {
+			continue
+		}
+		o, mbb := outlineOf(hole.Primitive), hole.Primitive.MBB()
+		for _, layer := range copper {
+			for _, p := range layer.Query(mbb) {
+				if outlineOf(p).overlaps(o) {
+					union(i, index[Node{Layer: layer, Primitive: p}])
+				}
+			}
+		}
+	}
+
+	c := &Connectivity{netOf: map[Node]*Net{}}
+	byRoot := map[int]*Net{}
+	for i, n := range nodes {
+		root := find(i)
+		net, ok := byRoot[root]
+		if !ok {
+			net = &Net{}
+			byRoot[root] = net
+			c.Nets = append(c.Nets, net)
+		}
+		net.Nodes = append(net.Nodes, n)
+		if tie, ok := n.Primitive.(*NetTieT); ok {
+			net.ties = append(net.ties, tie)
+		}
+		c.netOf[n] = net
+	}
+	for _, net := range c.Nets {
+		seen := map[string]bool{}
+		for _, n := range net.Nodes {
+			for _, name := range n.Layer.netsOf([]Primitive{n.Primitive}) {
+				if !seen[name] {
+					seen[name] = true
+					net.Names = append(net.Names, name)
+				}
+			}
+		}
+		sort.Strings(net.Names)
+	}
+	return c, nil
+}
+
+// NetOf returns the net of the primitive of the layer, or nil if it is
+// not a node of the graph.
+func (c *Connectivity) NetOf(layer *Layer, p Primitive) *Net {
+	return c.netOf[Node{Layer: layer, Primitive: p}]
+}
+
+// NetAt returns the net of the first primitive of the layer containing
+// pt (see PrimitiveContains), or nil if there is none.
+func (c *Connectivity) NetAt(layer *Layer, pt Pt) *Net {
+	for _, p := range layer.At(pt) {
+		if net := c.NetOf(layer, p); net != nil {
+			return net
+		}
+	}
+	return nil
+}
+
+// Connected reports whether the copper at pt1 on layer1 is connected
+// to the copper at pt2 on layer2 (e.g. two pads joined by traces and
+// vias). It returns false if either point has no copper.
+func (c *Connectivity) Connected(layer1 *Layer, pt1 Pt, layer2 *Layer, pt2 Pt) bool {
+	net := c.NetAt(layer1, pt1)
+	return net != nil && net == c.NetAt(layer2, pt2)
+}
+
+// Named returns the nets tagged with the named net, which normally
+// number one. More than one is an open: the net's copper is not
+// connected.
+func (c *Connectivity) Named(name string) []*Net {
+	var nets []*Net
+	for _, net := range c.Nets {
+		i := sort.SearchStrings(net.Names, name)
+		if i < len(net.Names) && net.Names[i] == name {
+			nets = append(nets, net)
+		}
+	}
+	return nets
+}
+
+// Issues returns the shorts of the graph (nets tagged with more than
+// one name, unless joined by net ties) and its opens (names tagged on
+// more than one net), with the first node of each offending net.
+func (c *Connectivity) Issues() Issues {
+	var issues Issues
+	for _, net := range c.Nets {
+		if groups := net.shorted(); len(groups) > 1 {
+			n := net.Nodes[0]
+			issues = append(issues, Issue{
+				Severity:  SeverityError,
+				Layer:     n.Layer,
+				Primitive: n.Primitive,
+				Message:   fmt.Sprintf("short between nets %v", strings.Join(groups, ", ")),
+			})
+		}
+	}
+	reported := map[string]bool{}
+	for _, net := range c.Nets {
+		for _, name := range net.Names {
+			if reported[name] {
+				continue
+			}
+			if parts := c.Named(name); len(parts) > 1 {
+				reported[name] = true
+				n := parts[1].Nodes[0]
+				issues = append(issues, Issue{
+					Severity:  SeverityError,
+					Layer:     n.Layer,
+					Primitive: n.Primitive,
+					Message:   fmt.Sprintf("net %v is split into %v unconnected parts", name, len(parts)),
+				})
+			}
+		}
+	}
+	return issues
+}
+
+// shorted returns the net's names as groups joined by its net ties
+// (each group's names separated by "+").
+func (n *Net) shorted() []string {
+	group := map[string]string{}
+	var find func(name string) string
+	find = func(name string) string {
+		if g, ok := group[name]; ok && g != name {
+			group[name] = find(g)
+			return group[name]
+		}
+		return name
+	}
+	for _, tie := range n.ties {
+		if a, b := find(tie.Nets[0]), find(tie.Nets[1]); a != b {
+			group[b] = a
+		}
+	}
+	members := map[string][]string{}
+	var roots []string
+	for _, name := range n.Names {
+		root := find(name)
+		if _, ok := members[root]; !ok {
+			roots = append(roots, root)
+		}
+		members[root] = append(members[root], name)
+	}
+	groups := make([]string, len(roots))
+	for i, root := range roots {
+		groups[i] = strings.Join(members[root], "+")
+	}
+	return groups
+}
+
+// CheckConnectivity derives the design's pads (see DeriveOpenings) and
+// checks its net graph (see Connectivity) for shorts and opens across
+// all copper layers. Unlike CheckShorts, it finds shorts made through
+// vias and nets whose copper is not fully connected.
+func (g *Gerber) CheckConnectivity() (Issues, error) {
+	c, err := g.Connectivity()
+	if err != nil {
+		return nil, err
+	}
+	return c.Issues(), nil
+}
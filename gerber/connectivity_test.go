@@ -0,0 +1,100 @@
+package gerber
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGerber_Connectivity(t *testing.T) {
+	g := New("test")
+	top, bottom, drill := g.TopCopper(), g.BottomCopper(), g.Drill()
+	// A top pad routed through a via to a bottom pad.
+	top.AddTagged([]string{NetTag("gnd")}, Circle(Pt{0, 0}, 1))
+	top.Add(Line(0, 0, 10, 0, CircleShape, 0.2))
+	Via{Center: Pt{10, 0}, Drill: 0.3, Pad: 0.6}.AddTo(drill, top, bottom)
+	bottom.Add(Line(10, 0, 10, 10, CircleShape, 0.2), Circle(Pt{10, 10}, 1))
+	// Pads on both sides of a mounting hole.
+	drill.AddTagged([]string{NonPlatedTag}, Circle(Pt{30, 0}, 1))
+	top.Add(Circle(Pt{30, 0}, 2))
+	bottom.Add(Circle(Pt{30, 0}, 2))
+
+	c, err := g.Connectivity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(c.Nets); got != 3 {
+		t.Errorf("nets = %v, want 3", got)
+	}
+	if !c.Connected(top, Pt{0, 0}, bottom, Pt{10, 10}) {
+		t.Error("Connected(top pad, bottom pad) = false, want true")
+	}
+	if c.Connected(top, Pt{30, 0}, bottom, Pt{30, 0}) {
+		t.Error("Connected(across mounting hole) = true, want false")
+	}
+	if c.Connected(top, Pt{0, 0}, bottom, Pt{20, 20}) {
+		t.Error("Connected(no copper) = true, want false")
+	}
+	net := c.NetAt(bottom, Pt{10, 10})
+	if want := []string{"gnd"}; net == nil || !reflect.DeepEqual(net.Names, want) || len(net.Nodes) != 7 {
+		t.Fatalf("NetAt(bottom pad) = %+v, want 7 nodes of net %v", net, want)
+	}
+	if got := c.NetOf(drill, net.Nodes[3].Primitive); got != nil {
+		t.Errorf("NetOf(wrong layer) = %v, want nil", got)
+	}
+	if issues := c.Issues(); len(issues) != 0 {
+		t.Errorf("Issues = %v, want none", issues)
+	}
+}
+
+func TestGerber_CheckConnectivity(t *testing.T) {
+	tests := []struct {
+		name string
+		add  func(top, bottom *Layer)
+		want []string
+	}{
+		{name: "connected"},
+		{
+			name: "short through a via",
+			add: func(top, bottom *Layer) {
+				bottom.AddTagged([]string{NetTag("vcc")}, Circle(Pt{10, 0}, 2))
+			},
+			want: []string{"error: test.gtl: short between nets gnd, vcc"},
+		},
+		{
+			name: "net tie",
+			add: func(top, bottom *Layer) {
+				bottom.Add(NetTie("gnd", Pt{10, 0}, "agnd", Pt{15, 0}, 0.6, 0.2))
+				bottom.AddTagged([]string{NetTag("agnd")}, Circle(Pt{15, 0}, 1))
+			},
+		},
+		{
+			name: "open",
+			add: func(top, bottom *Layer) {
+				bottom.AddTagged([]string{NetTag("gnd")}, Circle(Pt{20, 0}, 1))
+			},
+			want: []string{"error: test.gbl: net gnd is split into 2 unconnected parts"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New("test")
+			top, bottom := g.TopCopper(), g.BottomCopper()
+			top.AddTagged([]string{NetTag("gnd")}, Line(0, 0, 10, 0, CircleShape, 0.2))
+			Via{Center: Pt{10, 0}, Drill: 0.3, Pad: 0.6}.AddTo(g.Drill(), top, bottom)
+			if tt.add != nil {
+				tt.add(top, bottom)
+			}
+			issues, err := g.CheckConnectivity()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, i := range issues {
+				got = append(got, i.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CheckConnectivity = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package gerber
+
+import (
+	"fmt"
+	"math"
+)
+
+// Contour represents a chain of connected primitives of an outline layer.
+type Contour struct {
+	// Primitives are the primitives forming the contour, in order.
+	Primitives []Primitive
+	// Points are the vertices of the contour, in order, with arcs
+	// flattened into line segments. A closed contour ends where it starts.
+	Points []Pt
+	// Closed reports whether the contour ends where it starts.
+	Closed bool
+}
+
+// contourEdge represents the path drawn by one outline primitive.
+type contourEdge struct {
+	p      Primitive
+	pts    []Pt
+	closed bool
+}
+
+func contourEdges(l *Layer) []contourEdge {
+	var edges []contourEdge
+	for _, p := range l.Primitives {
+		switch v := p.(type) {
+		case *LineT:
+			if v.P1 != v.P2 {
+				edges = append(edges, contourEdge{p: p, pts: []Pt{v.P1, v.P2}})
+			}
+		case *ArcT:
+			edges = append(edges, contourEdge{p: p, pts: arcPoints(v)})
+		case *PolygonT:
+			// Regions are implicitly closed.
+			var pts []Pt
+			for _, pt := range v.Points {
+				pts = append(pts, Pt{pt[0] + v.Offset[0], pt[1] + v.Offset[1]})
+			}
+			if len(pts) > 0 && pts[0] != pts[len(pts)-1] {
+				pts = append(pts, pts[0])
+			}
+			edges = append(edges, contourEdge{p: p, pts: pts, closed: true})
+		}
+	}
+	return edges
+}
+
+// arcPoints returns the endpoints of the segments the arc is written as.
+func arcPoints(a *ArcT) []Pt {
+	segments := a.segments(0)
+	delta := (a.EndAngle - a.StartAngle) / float64(segments)
+	var pts []Pt
+	for i := 0; i <= segments; i++ {
+		angle := a.StartAngle + float64(i)*delta
+		pts = append(pts, Pt{a.Center[0] + a.XScale*math.Cos(angle)*a.Radius, a.Center[1] + a.YScale*math.Sin(angle)*a.Radius})
+	}
+	return pts
+}
+
+func reversed(pts []Pt) []Pt {
+	result := make([]Pt, len(pts))
+	for i, pt := range pts {
+		result[len(pts)-1-i] = pt
+	}
+	return result
+}
+
+// Contours chains the lines, arcs, and polygons of the layer (typically
+// an outline layer) into contours, joining endpoints that lie within
+// snap millimeters of each other. Other primitives are ignored.
+func (l *Layer) Contours(snap float64) []*Contour {
+	edges := contourEdges(l)
+	near := func(a, b Pt) bool { return Distance(a, b) <= snap }
+	used := make([]bool, len(edges))
+	var result []*Contour
+	for i, e := range edges {
+		if used[i] {
+			continue
+		}
+		used[i] = true
+		c := &Contour{Primitives: []Primitive{e.p}, Points: append([]Pt(nil), e.pts...)}
+		result = append(result, c)
+		if e.closed {
+			c.Closed = true
+			continue
+		}
+		for extended := true; extended && !near(c.Points[0], c.Points[len(c.Points)-1]); {
+			extended = false
+			for j, f := range edges {
+				if used[j] || f.closed {
+					continue
+				}
+				first, last := c.Points[0], c.Points[len(c.Points)-1]
+				switch {
+				case near(last, f.pts[0]):
+					c.Points = append(c.Points, f.pts[1:]...)
+					c.Primitives = append(c.Primitives, f.p)
+				case near(last, f.pts[len(f.pts)-1]):
+					c.Points = append(c.Points, reversed(f.pts)[1:]...)
+					c.Primitives = append(c.Primitives, f.p)
+				case near(first, f.pts[len(f.pts)-1]):
+					c.Points = append(append([]Pt(nil), f.pts[:len(f.pts)-1]...), c.Points...)
+					c.Primitives = append([]Primitive{f.p}, c.Primitives...)
+				case near(first, f.pts[0]):
+					c.Points = append(reversed(f.pts[1:]), c.Points...)
+					c.Primitives = append([]Primitive{f.p}, c.Primitives...)
+				default:
+					continue
+				}
+				used[j], extended = true, true
+				break
+			}
+		}
+		c.Closed = len(c.Points) > 2 && near(c.Points[0], c.Points[len(c.Points)-1])
+	}
+	return result
+}
+
+// CheckOutline checks that the design's outline layers form closed,
+// non-self-intersecting contours (see Layer.Contours), which most fabs
+// require, treating endpoints within snap millimeters of each other as
+// connected. Use HealOutline to close such gaps exactly.
+func (g *Gerber) CheckOutline(snap float64) Issues {
+	var issues Issues
+	add := func(layer *Layer, p Primitive, format string, args ...interface{}) {
+		issues = append(issues, Issue{Severity: SeverityError, Layer: layer, Primitive: p, Message: fmt.Sprintf(format, args...)})
+	}
+	found := false
+	for _, layer := range g.layersOfType(OutlineLayer) {
+		contours := layer.Contours(snap)
+		for _, c := range contours {
+			found = true
+			if !c.Closed {
+				first, last := c.Points[0], c.Points[len(c.Points)-1]
+				add(layer, c.Primitives[0], "outline contour is open: gap of %vmm between %v and %v",
+					fmtFloat(Distance(first, last)), fmtPt(last), fmtPt(first))
+			}
+		}
+		for i, c := range contours {
+			if pt, ok := selfIntersection(c); ok {
+				add(layer, c.Primitives[0], "outline contour crosses itself at %v", fmtPt(pt))
+			}
+			for _, d := range contours[i+1:] {
+				if pt, ok := contoursIntersection(c, d); ok {
+					add(layer, d.Primitives[0], "outline contours cross at %v", fmtPt(pt))
+				}
+			}
+		}
+	}
+	if !found {
+		add(nil, nil, "missing board outline")
+	}
+	return issues
+}
+
+// selfIntersection returns a point where two non-adjacent segments of
+// the contour touch or cross.
+func selfIntersection(c *Contour) (Pt, bool) {
+	// Skip repeated vertices, whose zero-length segments would make
+	// their neighbors appear adjacent.
+	pts := []Pt{c.Points[0]}
+	for _, pt := range c.Points[1:] {
+		if pt != pts[len(pts)-1] {
+			pts = append(pts, pt)
+		}
+	}
+	n := len(pts) - 1
+	for i := 0; i < n; i++ {
+		for j := i + 2; j < n; j++ {
+			if c.Closed && i == 0 && j == n-1 {
+				continue // the closing vertex is shared
+			}
+			a1, a2, b1, b2 := pts[i], pts[i+1], pts[j], pts[j+1]
+			if segmentsIntersect(a1, a2, b1, b2) {
+				return b1, true
+			}
+		}
+	}
+	return Pt{}, false
+}
+
+// contoursIntersection returns a point where segments of the two
+// contours touch or cross.
+func contoursIntersection(c, d *Contour) (Pt, bool) {
+	cm, dm := pointsMBB(c.Points), pointsMBB(d.Points)
+	if !cm.Intersects(&dm) {
+		return Pt{}, false
+	}
+	for i := 1; i < len(c.Points); i++ {
+		for j := 1; j < len(d.Points); j++ {
+			if segmentsIntersect(c.Points[i-1], c.Points[i], d.Points[j-1], d.Points[j]) {
+				return d.Points[j-1], true
+			}
+		}
+	}
+	return Pt{}, false
+}
+
+func pointsMBB(pts []Pt) MBB {
+	mbb := MBB{Min: pts[0], Max: pts[0]}
+	for _, pt := range pts[1:] {
+		v := MBB{Min: pt, Max: pt}
+		mbb.Join(&v)
+	}
+	return mbb
+}
+
+// HealOutline snaps together the endpoints of the lines of the design's
+// outline layers that lie within snap millimeters of each other (or of
+// an arc's endpoint, which is kept fixed), so that the contours they
+// form close exactly. It returns the number of lines that were moved.
+func (g *Gerber) HealOutline(snap float64) int {
+	var n int
+	for _, layer := range g.layersOfType(OutlineLayer) {
+		// Arc endpoints are fixed; the first endpoint of a line seen
+		// near no fixed point becomes one.
+		var fixed []Pt
+		for _, p := range layer.Primitives {
+			if a, ok := p.(*ArcT); ok {
+				pts := arcPoints(a)
+				fixed = append(fixed, pts[0], pts[len(pts)-1])
+			}
+		}
+		snapTo := func(pt Pt) Pt {
+			for _, f := range fixed {
+				if Distance(pt, f) <= snap {
+					return f
+				}
+			}
+			fixed = append(fixed, pt)
+			return pt
+		}
+		n += layer.Replace(func(p Primitive) ([]Primitive, bool) {
+			l, ok := p.(*LineT)
+			if !ok {
+				return nil, false
+			}
+			p1, p2 := snapTo(l.P1), snapTo(l.P2)
+			if p1 == l.P1 && p2 == l.P2 {
+				return nil, false
+			}
+			return []Primitive{Line(p1[0], p1[1], p2[0], p2[1], l.Shape, l.Thickness)}, true
+		})
+	}
+	return n
+}
@@ -0,0 +1,127 @@
+package gerber
+
+import "testing"
+
+func TestGerber_CheckOutline(t *testing.T) {
+	square := []Pt{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}}
+	tests := []struct {
+		name string
+		add  func(l *Layer)
+		snap float64
+		want []string
+	}{
+		{
+			name: "closed path",
+			add:  func(l *Layer) { l.Add(OutlinePath(square, 0.1)...) },
+		},
+		{
+			name: "closed polygon",
+			add:  func(l *Layer) { l.Add(Polygon(Pt{}, true, square[:4], 0)) },
+		},
+		{
+			name: "lines out of order and reversed",
+			add: func(l *Layer) {
+				l.Add(
+					Line(10, 10, 10, 0, CircleShape, 0.1),
+					Line(0, 0, 10, 0, CircleShape, 0.1),
+					Line(0, 0, 0, 10, CircleShape, 0.1),
+					Line(0, 10, 10, 10, CircleShape, 0.1),
+				)
+			},
+		},
+		{
+			name: "arc corner",
+			add: func(l *Layer) {
+				l.Add(
+					Line(0, 0, 9, 0, CircleShape, 0.1),
+					Arc(Pt{9, 1}, 1, CircleShape, 1, 1, -90, 0, 0.1),
+					Line(10, 1, 10, 10, CircleShape, 0.1),
+					Line(10, 10, 0, 10, CircleShape, 0.1),
+					Line(0, 10, 0, 0, CircleShape, 0.1),
+				)
+			},
+			snap: 1e-6,
+		},
+		{
+			name: "gap",
+			add: func(l *Layer) {
+				l.Add(
+					Line(0, 0, 10, 0, CircleShape, 0.1),
+					Line(10, 0, 10, 10, CircleShape, 0.1),
+					Line(10, 10, 0, 10, CircleShape, 0.1),
+				)
+			},
+			want: []string{"error: test.gko: outline contour is open: gap of 10mm between (0,10) and (0,0)"},
+		},
+		{
+			name: "gap within snap",
+			add: func(l *Layer) {
+				l.Add(
+					Line(0, 0, 10, 0, CircleShape, 0.1),
+					Line(10, 0, 10, 10, CircleShape, 0.1),
+					Line(10, 10, 0, 10, CircleShape, 0.1),
+					Line(0, 10, 0, 0.01, CircleShape, 0.1),
+				)
+			},
+			snap: 0.02,
+		},
+		{
+			name: "self-intersecting",
+			add: func(l *Layer) {
+				l.Add(OutlinePath([]Pt{{0, 0}, {10, 10}, {10, 0}, {0, 10}, {0, 0}}, 0.1)...)
+			},
+			want: []string{"error: test.gko: outline contour crosses itself at (10,0)"},
+		},
+		{
+			name: "crossing contours",
+			add: func(l *Layer) {
+				l.Add(OutlinePath(square, 0.1)...)
+				l.Add(Polygon(Pt{8, 8}, true, square[:4], 0))
+			},
+			want: []string{"error: test.gko: outline contours cross at (8,8)"},
+		},
+		{
+			name: "missing",
+			add:  func(l *Layer) { l.Add(Circle(Pt{5, 5}, 1)) },
+			want: []string{"error: missing board outline"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New("test")
+			tt.add(g.Outline())
+			issues := g.CheckOutline(tt.snap)
+			if len(issues) != len(tt.want) {
+				t.Fatalf("CheckOutline = %v, want %v", issues, tt.want)
+			}
+			for i, issue := range issues {
+				if got := issue.String(); got != tt.want[i] {
+					t.Errorf("issue[%v] = %q, want %q", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestGerber_HealOutline(t *testing.T) {
+	g := New("test")
+	g.Outline().Add(
+		Line(0, 0, 10, 0, CircleShape, 0.1),
+		Line(10.005, 0, 10, 10, CircleShape, 0.1),
+		Line(10, 10, 0, 10.003, CircleShape, 0.1),
+		Line(0, 10, 0, 0.002, CircleShape, 0.1),
+	)
+	if got := len(g.CheckOutline(0)); got == 0 {
+		t.Fatal("CheckOutline before healing found no issues")
+	}
+	if got, want := g.HealOutline(0.01), 2; got != want {
+		t.Errorf("HealOutline = %v, want %v", got, want)
+	}
+	if issues := g.CheckOutline(0); len(issues) != 0 {
+		t.Errorf("CheckOutline after healing = %v, want none", issues)
+	}
+	if got, want := g.HealOutline(0.01), 0; got != want {
+		t.Errorf("HealOutline again = %v, want %v", got, want)
+	}
+}
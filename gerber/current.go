@@ -0,0 +1,242 @@
+package gerber
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Stackup represents the copper of a design's layers (see
+// WithStackup), used by its electrical analyses. Fields that are zero
+// take their defaults.
+type Stackup struct {
+	// OuterCopper and InnerCopper are the copper weights (in oz/ft²) of
+	// the outer (top and bottom) and inner copper layers (default 1 and
+	// 0.5).
+	OuterCopper, InnerCopper float64
+	// Plating is the thickness (in mm) of the copper plating of holes
+	// (default 0.025).
+	Plating float64
+}
+
+// mmPerOz is the thickness (in mm) of one ounce per square foot of
+// copper.
+const mmPerOz = 0.0347
+
+func (s Stackup) withDefaults() Stackup {
+	if s.OuterCopper <= 0 {
+		s.OuterCopper = 1
+	}
+	if s.InnerCopper <= 0 {
+		s.InnerCopper = 0.5
+	}
+	if s.Plating <= 0 {
+		s.Plating = 0.025
+	}
+	return s
+}
+
+// Stackup returns the design's stackup (see WithStackup), with the
+// defaults of its zero fields.
+func (g *Gerber) Stackup() Stackup {
+	var s Stackup
+	if g.stackup != nil {
+		s = *g.stackup
+	}
+	return s.withDefaults()
+}
+
+// CopperWeight returns the copper weight (in oz/ft²) of copper layers
+// of type t.
+func (s Stackup) CopperWeight(t LayerType) float64 {
+	s = s.withDefaults()
+	if t == InnerCopperLayer {
+		return s.InnerCopper
+	}
+	return s.OuterCopper
+}
+
+// IPC2152Current returns the current (in amps) that a conductor with
+// the given cross section (in mm²) carries with a rise in temperature
+// of tempRise °C, approximating the baseline chart of IPC-2152 (which,
+// unlike IPC-2221, does not distinguish inner and outer layers) with
+// I = 0.0647·ΔT^0.4281·A^0.6732 (A in mil²). It is conservative for
+// boards with copper planes, which spread the heat.
+func IPC2152Current(area, tempRise float64) float64 {
+	mil2 := area / (0.0254 * 0.0254)
+	return 0.0647 * math.Pow(tempRise, 0.4281) * math.Pow(mil2, 0.6732)
+}
+
+// CurrentOpts represents the options used by CurrentCapacities.
+type CurrentOpts struct {
+	// Currents are the required currents (in amps) of nets, by name
+	// (see NetTag).
+	Currents map[string]float64
+	// TempRise is the allowed rise in temperature (in °C, default 10).
+	TempRise float64
+}
+
+// CurrentCapacity represents the estimated current capacity of a trace
+// or of a via array: the holes of a net that connect the same copper
+// on each layer, in parallel.
+type CurrentCapacity struct {
+	Net string
+	// Layer is the layer of Primitive: the trace, or the first hole of
+	// the via array.
+	Layer     *Layer
+	Primitive Primitive
+	// Vias is the number of holes of a via array (0 for a trace).
+	Vias int
+	// Capacity and Required are the estimated capacity (see
+	// IPC2152Current) and the net's required current, in amps.
+	Capacity, Required float64
+}
+
+// CurrentCapacities returns the current capacity of each trace (line
+// or arc) and via array of the nets (see Connectivity) with a required
+// current, in net order. Traces carry the copper weight of their layer
+// and holes their plating (see Stackup).
+func (g *Gerber) CurrentCapacities(opts CurrentOpts) ([]CurrentCapacity, error) {
+	c, err := g.Connectivity()
+	if err != nil {
+		return nil, err
+	}
+	tempRise := opts.TempRise
+	if tempRise <= 0 {
+		tempRise = 10
+	}
+	stackup := g.Stackup()
+
+	// The island of each copper primitive, identifying the copper that
+	// holes connect.
+	var copper []*Layer
+	island := map[Node]int{}
+	for _, layer := range g.Layers {
+		if !layer.Type.IsCopper() {
+			continue
+		}
+		copper = append(copper, layer)
+		for i, is := range layer.Islands(IslandOpts{}) {
+			for _, p := range is.Primitives {
+				island[Node{Layer: layer, Primitive: p}] = i
+			}
+		}
+	}
+	// The key of each hole is the islands that it connects, which only
+	// depends on the hole's center and size (to 1µm), so that repeated
+	// holes (such as those of several drill layers) are only compared
+	// with the nearby copper once.
+	keys := map[[4]int64]string{}
+	holeKey := func(hole Primitive) string {
+		mbb := hole.MBB()
+		um := func(v float64) int64 { return int64(math.Round(v * 1e3)) }
+		id := [4]int64{um(mbb.Min[0] + mbb.Max[0]), um(mbb.Min[1] + mbb.Max[1]), um(mbb.Max[0] - mbb.Min[0]), um(mbb.Max[1] - mbb.Min[1])}
+		if k, ok := keys[id]; ok {
+			return k
+		}
+		o := outlineOf(hole)
+		var key []string
+		for _, layer := range copper {
+			k := "-"
+			for _, p := range layer.Query(mbb) {
+				if outlineOf(p).overlaps(o) {
+					k = fmt.Sprint(island[Node{Layer: layer, Primitive: p}])
+					break
+				}
+			}
+			key = append(key, k)
+		}
+		keys[id] = strings.Join(key, ",")
+		return keys[id]
+	}
+
+	var caps []CurrentCapacity
+	for _, net := range c.Nets {
+		var name string
+		var required float64
+		for _, n := range net.Names {
+			if opts.Currents[n] > required {
+				name, required = n, opts.Currents[n]
+			}
+		}
+		if required <= 0 {
+			continue
+		}
+		arrays := map[string]int{}
+		for _, n := range net.Nodes {
+			if n.Layer.Type == DrillLayer {
+				var d float64
+				switch v := n.Primitive.(type) {
+				case *CircleT:
+					d = v.thickness
+				case *LineT:
+					d = v.Thickness
+				default:
+					continue
+				}
+				capacity := IPC2152Current(math.Pi*(d+stackup.Plating)*stackup.Plating, tempRise)
+				key := holeKey(n.Primitive)
+				if i, ok := arrays[key]; ok {
+					caps[i].Vias++
+					caps[i].Capacity += capacity
+					continue
+				}
+				arrays[key] = len(caps)
+				caps = append(caps, CurrentCapacity{Net: name, Layer: n.Layer, Primitive: n.Primitive, Vias: 1, Capacity: capacity, Required: required})
+				continue
+			}
+			width := primitiveWidth(n.Primitive)
+			if width <= 0 {
+				continue
+			}
+			area := width * stackup.CopperWeight(n.Layer.Type) * mmPerOz
+			caps = append(caps, CurrentCapacity{Net: name, Layer: n.Layer, Primitive: n.Primitive,
+				Capacity: IPC2152Current(area, tempRise), Required: required})
+		}
+	}
+	return caps, nil
+}
+
+// CheckCurrentCapacity returns an issue for each trace and via array
+// (see CurrentCapacities) that cannot carry its net's required current.
+func (g *Gerber) CheckCurrentCapacity(opts CurrentOpts) (Issues, error) {
+	caps, err := g.CurrentCapacities(opts)
+	if err != nil {
+		return nil, err
+	}
+	stackup := g.Stackup()
+	var issues Issues
+	for _, c := range caps {
+		if c.Capacity >= c.Required {
+			continue
+		}
+		mbb := c.Primitive.MBB()
+		at := Pt{0.5 * (mbb.Min[0] + mbb.Max[0]), 0.5 * (mbb.Min[1] + mbb.Max[1])}
+		what := fmt.Sprintf("trace of net %v (%vmm of %voz copper) at %v carries", c.Net,
+			fmtFloat(primitiveWidth(c.Primitive)), fmtFloat(stackup.CopperWeight(c.Layer.Type)), fmtPt(at))
+		if c.Vias > 0 {
+			what = fmt.Sprintf("%v vias of net %v from %v carry", c.Vias, c.Net, fmtPt(at))
+			if c.Vias == 1 {
+				what = fmt.Sprintf("via of net %v at %v carries", c.Net, fmtPt(at))
+			}
+		}
+		issues = append(issues, Issue{
+			Severity:  SeverityError,
+			Layer:     c.Layer,
+			Primitive: c.Primitive,
+			Message:   fmt.Sprintf("%v %.2fA < %.2fA", what, c.Capacity, c.Required),
+		})
+	}
+	return issues, nil
+}
+
+// primitiveWidth returns the width of a line or arc (0 otherwise).
+func primitiveWidth(p Primitive) float64 {
+	switch v := p.(type) {
+	case *LineT:
+		return v.Thickness
+	case *ArcT:
+		return v.Thickness
+	}
+	return 0
+}
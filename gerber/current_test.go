@@ -0,0 +1,94 @@
+package gerber
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestIPC2152Current(t *testing.T) {
+	// 10 mil of 1 oz copper (13.66 mil²) with a rise of 10°C.
+	if got := IPC2152Current(0.254*mmPerOz, 10); math.Abs(got-1.008) > 0.001 {
+		t.Errorf("IPC2152Current = %v, want 1.008", got)
+	}
+}
+
+func TestGerber_CheckCurrentCapacity(t *testing.T) {
+	g := New("test", WithStackup(Stackup{OuterCopper: 2}))
+	top, bottom, drill := g.TopCopper(), g.BottomCopper(), g.Drill()
+	top.AddTagged([]string{NetTag("pwr")}, Line(0, 0, 10, 0, CircleShape, 0.254), Line(10, 0, 20, 0, CircleShape, 1))
+	// Two vias in parallel, and a single via on another net.
+	for _, x := range []float64{19.5, 20} {
+		Via{Center: Pt{x, 0}, Drill: 0.3, Pad: 0.6}.AddTo(drill, top, bottom)
+	}
+	Via{Center: Pt{0, 5}, Drill: 0.3, Pad: 0.6}.AddTo(drill, top, bottom)
+	top.AddTagged([]string{NetTag("hv")}, Circle(Pt{0, 5}, 1))
+
+	opts := CurrentOpts{Currents: map[string]float64{"pwr": 1.8, "hv": 3}}
+	caps, err := g.CurrentCapacities(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	via := IPC2152Current(math.Pi*0.325*0.025, 10)
+	want := []struct {
+		net      string
+		vias     int
+		capacity float64
+	}{
+		{"pwr", 0, IPC2152Current(0.254*2*mmPerOz, 10)},
+		{"pwr", 0, IPC2152Current(2*mmPerOz, 10)},
+		{"pwr", 2, 2 * via},
+		{"hv", 1, via},
+	}
+	if len(caps) != len(want) {
+		t.Fatalf("CurrentCapacities = %+v, want %v", caps, len(want))
+	}
+	for i, c := range caps {
+		if c.Net != want[i].net || c.Vias != want[i].vias || math.Abs(c.Capacity-want[i].capacity) > 1e-9 {
+			t.Errorf("capacity[%v] = %v, %v vias, %v A, want %v, %v vias, %v A", i, c.Net, c.Vias, c.Capacity, want[i].net, want[i].vias, want[i].capacity)
+		}
+	}
+
+	issues, err := g.CheckCurrentCapacity(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, i := range issues {
+		got = append(got, i.String())
+	}
+	wantIssues := []string{
+		"error: test.gtl: trace of net pwr (0.254mm of 2oz copper) at (5,0) carries 1.61A < 1.80A",
+		"error: test.drl: via of net hv at (0,5) carries 2.06A < 3.00A",
+	}
+	if !reflect.DeepEqual(got, wantIssues) {
+		t.Errorf("CheckCurrentCapacity = %q, want %q", got, wantIssues)
+	}
+}
+
+func TestGerber_CurrentCapacities_ViaArray(t *testing.T) {
+	g := New("test")
+	top, bottom, drill := g.TopCopper(), g.BottomCopper(), g.Drill()
+	plane := []Pt{{0, 0}, {40, 0}, {40, 40}, {0, 40}}
+	top.AddTagged([]string{NetTag("pwr")}, Polygon(Pt{}, true, plane, 0))
+	bottom.AddTagged([]string{NetTag("pwr")}, Polygon(Pt{}, true, plane, 0))
+	for x := 0; x < 40; x++ {
+		for y := 0; y < 40; y++ {
+			Via{Center: Pt{float64(x) + 0.5, float64(y) + 0.5}, Drill: 0.3, Pad: 0.6}.AddTo(drill, top, bottom)
+		}
+	}
+
+	caps, err := g.CurrentCapacities(CurrentOpts{Currents: map[string]float64{"pwr": 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var vias []int
+	for _, c := range caps {
+		if c.Vias > 0 {
+			vias = append(vias, c.Vias)
+		}
+	}
+	if len(vias) != 1 || vias[0] != 1600 {
+		t.Errorf("CurrentCapacities via arrays = %v, want [1600]", vias)
+	}
+}
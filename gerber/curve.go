@@ -0,0 +1,245 @@
+package gerber
+
+import (
+	"fmt"
+	"io"
+	"math"
+)
+
+func init() {
+	registerPrimitive("curve", func() Primitive { return &CurveT{} })
+}
+
+// CurveKind is the kind of curve drawn through the points of a CurveT.
+type CurveKind int
+
+const (
+	// QuadraticCurve is a path of quadratic Bézier curves: a start point
+	// followed by a control point and an end point for each curve.
+	QuadraticCurve CurveKind = iota
+	// CubicCurve is a path of cubic Bézier curves: a start point followed
+	// by two control points and an end point for each curve.
+	CubicCurve
+	// CatmullRomCurve is a (uniform) Catmull-Rom spline passing through
+	// all of the points.
+	CatmullRomCurve
+)
+
+// defaultCurveTolerance is the chord error (in millimeters) of the
+// flattened curves when neither the curve nor the design sets one.
+const defaultCurveTolerance = 0.01
+
+// CurveT represents a smooth path drawn with a round aperture, and
+// satisfies the Primitive interface. The curve is adaptively flattened
+// into line segments when written, to within a chord error of its
+// Tolerance (or the design's, see WithArcTolerance).
+// All dimensions are in millimeters.
+type CurveT struct {
+	Kind      CurveKind `json:"kind"`
+	Points    []Pt      `json:"points"`
+	Thickness float64   `json:"thickness"`
+	// Tolerance is the maximum chord error allowed when the curve is
+	// flattened. If zero, the design's tolerance is used when writing.
+	Tolerance float64 `json:"tolerance,omitempty"`
+	mbb       *MBB    // cached minimum bounding box
+}
+
+// QuadraticBezier returns a path of quadratic Bézier curves (see
+// QuadraticCurve) of the given thickness.
+func QuadraticBezier(points []Pt, thickness float64) *CurveT {
+	return &CurveT{Kind: QuadraticCurve, Points: points, Thickness: thickness}
+}
+
+// CubicBezier returns a path of cubic Bézier curves (see CubicCurve)
+// of the given thickness.
+func CubicBezier(points []Pt, thickness float64) *CurveT {
+	return &CurveT{Kind: CubicCurve, Points: points, Thickness: thickness}
+}
+
+// CatmullRom returns a Catmull-Rom spline through the points, of the
+// given thickness.
+func CatmullRom(points []Pt, thickness float64) *CurveT {
+	return &CurveT{Kind: CatmullRomCurve, Points: points, Thickness: thickness}
+}
+
+// cubics returns the curve as a path of cubic Bézier curves (each of
+// four points, the first of which is the last of the previous one).
+func (c *CurveT) cubics() ([][4]Pt, error) {
+	pts := c.Points
+	lerp := func(a, b Pt, t float64) Pt { return Pt{a[0] + t*(b[0]-a[0]), a[1] + t*(b[1]-a[1])} }
+	var result [][4]Pt
+	switch c.Kind {
+	case QuadraticCurve:
+		if len(pts) < 3 || (len(pts)-1)%2 != 0 {
+			return nil, fmt.Errorf("quadratic curve of %v points", len(pts))
+		}
+		for i := 0; i+2 < len(pts); i += 2 {
+			p0, q, p1 := pts[i], pts[i+1], pts[i+2]
+			result = append(result, [4]Pt{p0, lerp(p0, q, 2.0/3), lerp(p1, q, 2.0/3), p1})
+		}
+	case CubicCurve:
+		if len(pts) < 4 || (len(pts)-1)%3 != 0 {
+			return nil, fmt.Errorf("cubic curve of %v points", len(pts))
+		}
+		for i := 0; i+3 < len(pts); i += 3 {
+			result = append(result, [4]Pt{pts[i], pts[i+1], pts[i+2], pts[i+3]})
+		}
+	case CatmullRomCurve:
+		if len(pts) < 2 {
+			return nil, fmt.Errorf("Catmull-Rom curve of %v points", len(pts))
+		}
+		// The end points are repeated to give the end spans tangents.
+		at := func(i int) Pt { return pts[min(max(i, 0), len(pts)-1)] }
+		for i := 0; i+1 < len(pts); i++ {
+			p0, p1, p2, p3 := at(i-1), at(i), at(i+1), at(i+2)
+			result = append(result, [4]Pt{
+				p1,
+				{p1[0] + (p2[0]-p0[0])/6, p1[1] + (p2[1]-p0[1])/6},
+				{p2[0] - (p3[0]-p1[0])/6, p2[1] - (p3[1]-p1[1])/6},
+				p2,
+			})
+		}
+	default:
+		return nil, fmt.Errorf("unknown curve kind %v", c.Kind)
+	}
+	return result, nil
+}
+
+// Flatten returns the points of the curve flattened into line
+// segments of at most tol millimeters of chord error (0 for the
+// curve's Tolerance or, if it is not set, 0.01mm).
+func (c *CurveT) Flatten(tol float64) ([]Pt, error) {
+	if tol <= 0 {
+		tol = c.Tolerance
+	}
+	if tol <= 0 {
+		tol = defaultCurveTolerance
+	}
+	cubics, err := c.cubics()
+	if err != nil {
+		return nil, err
+	}
+	result := []Pt{cubics[0][0]}
+	var flatten func(b [4]Pt, depth int)
+	flatten = func(b [4]Pt, depth int) {
+		// The curve lies within the hull of its control points, so it
+		// is flat enough if they are within tol of its chord.
+		d1, _ := segmentDistance(b[1], b[0], b[3])
+		d2, _ := segmentDistance(b[2], b[0], b[3])
+		if depth >= 16 || math.Max(d1, d2) <= tol {
+			result = append(result, b[3])
+			return
+		}
+		// Split the curve in half (de Casteljau).
+		mid := func(a, b Pt) Pt { return Pt{0.5 * (a[0] + b[0]), 0.5 * (a[1] + b[1])} }
+		p01, p12, p23 := mid(b[0], b[1]), mid(b[1], b[2]), mid(b[2], b[3])
+		p012, p123 := mid(p01, p12), mid(p12, p23)
+		m := mid(p012, p123)
+		flatten([4]Pt{b[0], p01, p012, m}, depth+1)
+		flatten([4]Pt{m, p123, p23, b[3]}, depth+1)
+	}
+	for _, b := range cubics {
+		flatten(b, 0)
+	}
+	return result, nil
+}
+
+// WriteGerber writes the primitive to the Gerber file.
+func (c *CurveT) WriteGerber(w io.Writer, apertureIndex int) error {
+	gw := toWriter(w)
+	tol := c.Tolerance
+	if tol <= 0 {
+		tol = gw.arcTolerance
+	}
+	pts, err := c.Flatten(tol)
+	if err != nil {
+		return err
+	}
+	gw.aperture(apertureIndex)
+	gw.move(pts[0][0], pts[0][1])
+	for _, pt := range pts[1:] {
+		gw.draw(pt[0], pt[1])
+	}
+	return nil
+}
+
+// Aperture returns the primitive's desired aperture.
+func (c *CurveT) Aperture() *Aperture {
+	return &Aperture{Shape: CircleShape, Size: c.Thickness}
+}
+
+// MBB returns the minimum bounding box in millimeters.
+func (c *CurveT) MBB() MBB {
+	if c.mbb != nil {
+		return *c.mbb
+	}
+	pts, err := c.Flatten(0)
+	if err != nil {
+		pts = c.Points
+	}
+	if len(pts) == 0 {
+		return MBB{}
+	}
+	c.mbb = &MBB{Min: pts[0], Max: pts[0]}
+	for _, pt := range pts[1:] {
+		c.mbb.Join(&MBB{Min: pt, Max: pt})
+	}
+	r := 0.5 * c.Thickness
+	c.mbb.Min[0] -= r
+	c.mbb.Min[1] -= r
+	c.mbb.Max[0] += r
+	c.mbb.Max[1] += r
+	return *c.mbb
+}
+
+func (c *CurveT) invalidateBounds() { c.mbb = nil }
+
+// lines returns the segments of the flattened curve.
+func (c *CurveT) lines() []*LineT {
+	pts, err := c.Flatten(0)
+	if err != nil {
+		return nil
+	}
+	var result []*LineT
+	for i := 1; i < len(pts); i++ {
+		result = append(result, Line(pts[i-1][0], pts[i-1][1], pts[i][0], pts[i][1], CircleShape, c.Thickness))
+	}
+	return result
+}
+
+// Transform returns a transformed copy of the curve. Béziers and
+// Catmull-Rom splines are transformed exactly by transforming their
+// points.
+func (c *CurveT) Transform(t Transform) Primitive {
+	pts := make([]Pt, 0, len(c.Points))
+	for _, pt := range c.Points {
+		pts = append(pts, t.Apply(pt))
+	}
+	s := t.scaleFactor()
+	return &CurveT{Kind: c.Kind, Points: pts, Thickness: s * c.Thickness, Tolerance: c.Tolerance}
+}
+
+// Expand returns a copy of the curve with its thickness grown by
+// 2*delta.
+func (c *CurveT) Expand(delta float64) Primitive {
+	return &CurveT{Kind: c.Kind, Points: c.Points, Thickness: expandSize(c.Thickness, delta), Tolerance: c.Tolerance}
+}
+
+// Contains reports whether pt lies within the stroked curve.
+func (c *CurveT) Contains(pt Pt) bool {
+	for _, l := range c.lines() {
+		if l.Contains(pt) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *CurveT) String() string {
+	kind := [...]string{"QuadraticBezier", "CubicBezier", "CatmullRom"}
+	name := fmt.Sprintf("Curve%v", int(c.Kind))
+	if int(c.Kind) >= 0 && int(c.Kind) < len(kind) {
+		name = kind[c.Kind]
+	}
+	return fmt.Sprintf("%v(%v points, %v)", name, len(c.Points), fmtFloat(c.Thickness))
+}
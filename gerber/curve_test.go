@@ -0,0 +1,121 @@
+package gerber
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestCurveT_Primitive(t *testing.T) {
+	var p Primitive = &CurveT{}
+	if p == nil {
+		// In actuality, this test won't compile if it isn't a Primitive.
+		t.Errorf("CurveT does not implement the Primitive interface")
+	}
+}
+
+func TestCurveT_Flatten(t *testing.T) {
+	p0, c1, c2, p1 := Pt{0, 0}, Pt{0, 10}, Pt{10, 10}, Pt{10, 0}
+	cubic := func(u float64) Pt {
+		a, b, c, d := (1-u)*(1-u)*(1-u), 3*u*(1-u)*(1-u), 3*u*u*(1-u), u*u*u
+		return Pt{a*p0[0] + b*c1[0] + c*c2[0] + d*p1[0], a*p0[1] + b*c1[1] + c*c2[1] + d*p1[1]}
+	}
+	curve := CubicBezier([]Pt{p0, c1, c2, p1}, 0.2)
+	var prev int
+	for _, tol := range []float64{0.1, 0.01, 0.001} {
+		pts, err := curve.Flatten(tol)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pts) <= prev {
+			t.Errorf("tol %v: got %v points, want more than %v", tol, len(pts), prev)
+		}
+		prev = len(pts)
+		if pts[0] != p0 || pts[len(pts)-1] != p1 {
+			t.Errorf("tol %v: curve runs from %v to %v", tol, pts[0], pts[len(pts)-1])
+		}
+		// Every point of the curve is within tol of the flattened path.
+		for u := 0.0; u <= 1; u += 0.01 {
+			pt, best := cubic(u), math.Inf(1)
+			for i := 1; i < len(pts); i++ {
+				d, _ := segmentDistance(pt, pts[i-1], pts[i])
+				best = math.Min(best, d)
+			}
+			if best > tol+1e-9 {
+				t.Fatalf("tol %v: curve at %v is %v from the flattened path", tol, u, best)
+			}
+		}
+	}
+
+	line, err := QuadraticBezier([]Pt{{0, 0}, {1, 1}, {2, 2}}, 0.2).Flatten(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(line) != 2 {
+		t.Errorf("straight quadratic flattened to %v", line)
+	}
+}
+
+func TestCatmullRom(t *testing.T) {
+	through := []Pt{{0, 0}, {3, 2}, {5, -1}, {9, 0}}
+	pts, err := CatmullRom(through, 0.2).Flatten(0.001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range through {
+		found := false
+		for _, pt := range pts {
+			if Distance(pt, want) < 1e-9 {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("spline does not pass through %v", want)
+		}
+	}
+}
+
+func TestCurveT_Errors(t *testing.T) {
+	for _, c := range []*CurveT{
+		QuadraticBezier([]Pt{{0, 0}, {1, 1}}, 0.2),
+		CubicBezier([]Pt{{0, 0}, {1, 1}, {2, 0}, {3, 0}, {4, 0}}, 0.2),
+		CatmullRom([]Pt{{0, 0}}, 0.2),
+		{Kind: 7, Points: []Pt{{0, 0}, {1, 1}}},
+	} {
+		var buf bytes.Buffer
+		if err := c.WriteGerber(&buf, 10); err == nil {
+			t.Errorf("%v: WriteGerber succeeded", c)
+		}
+	}
+}
+
+func TestCurveT_WriteGerber(t *testing.T) {
+	g := New("test", WithArcTolerance(0.05))
+	top := g.TopCopper()
+	curve := CubicBezier([]Pt{{0, 0}, {0, 10}, {10, 10}, {10, 0}}, 0.25)
+	top.Add(curve)
+	var buf bytes.Buffer
+	if err := top.WriteGerber(&buf); err != nil {
+		t.Fatal(err)
+	}
+	pts, _ := curve.Flatten(0.05)
+	got := buf.String()
+	if n := strings.Count(got, "D01*"); n != len(pts)-1 {
+		t.Errorf("got %v draws, want %v", n, len(pts)-1)
+	}
+	if want := "X5000000Y7500000D01*"; !strings.Contains(got, want) {
+		t.Errorf("missing %v in %v", want, got)
+	}
+
+	if got, want := curve.MBB(), (MBB{Min: Pt{-0.125, -0.125}, Max: Pt{10.125, 7.625}}); !mbbNear(got, want, 0.01) {
+		t.Errorf("MBB = %v, want %v", got, want)
+	}
+	if !curve.Contains(Pt{5, 7.5}) || curve.Contains(Pt{5, 5}) {
+		t.Error("Contains does not match the stroked curve")
+	}
+	xf := Rotate(90).Then(Translate(1, 2))
+	if tc := curve.Transform(xf); !tc.(Container).Contains(xf.Apply(Pt{5, 7.5})) {
+		t.Error("transformed curve does not contain the transformed point")
+	}
+}
@@ -0,0 +1,106 @@
+package gerber
+
+import (
+	"bytes"
+	"sort"
+)
+
+// WithDeterministicOutput enables or disables deterministic output, so
+// that generated Gerber files may be checked into version control and
+// diffed meaningfully: D-codes are assigned in order of the apertures'
+// shapes and sizes (as with the Sorted option of ApertureNumbering),
+// and consecutive primitives whose order does not change the image
+// (those that only draw dark objects, such as lines, circles, pads,
+// polygons, QR codes, and arrays of them) are written sorted by their
+// output, so that identical designs give byte-identical files even if
+// their primitives were added in a different order (e.g. by concurrent
+// or map-iterating code). Clear (see Clear) and other primitives keep
+// their position relative to the others. Combine it with WithGrid to
+// also absorb floating point noise in the coordinates.
+//
+// Apertures merged by WithApertureTolerance still depend upon which
+// aperture was added first.
+func WithDeterministicOutput(enabled bool) Option {
+	return func(g *Gerber) {
+		g.deterministic = enabled
+	}
+}
+
+// commutes reports whether p only draws dark objects, so that it may be
+// written before or after any other such primitive without changing the
+// image of the layer. (An inverted QR code is still dark: it draws its
+// light modules rather than clearing its dark ones.)
+func commutes(p Primitive) bool {
+	switch v := p.(type) {
+	case *ArcT, *CircleT, *CurveT, *FlashT, *HatchedRegionT, *LineT, *NetTieT, *PadT, *PolygonT, *QRCodeT, *RegionT:
+		return true
+	case *StepRepeatT:
+		for _, c := range v.Primitives {
+			if !commutes(c) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// outputOrder returns the indices of the layer's primitives in the
+// order they are written: their own order or, with
+// WithDeterministicOutput, with each run of commuting primitives sorted
+// by their output (as written by gw with the given D-codes), and then,
+// for drill layers, reordered by WithDrillOrder.
+func (l *Layer) outputOrder(gw *writer, defaultCode int, codes []int) []int {
+	order := make([]int, len(l.Primitives))
+	for i := range order {
+		order[i] = i
+	}
+	if l.g == nil {
+		return order
+	}
+	code := func(p Primitive) int {
+		if ai, ok := l.apertureMap[p.Aperture().ID()]; ok && ai >= 0 {
+			return codes[ai]
+		}
+		return defaultCode
+	}
+
+	var keys []string
+	if l.g.deterministic {
+		var buf bytes.Buffer
+		kw := *gw
+		kw.Writer = &buf
+		keys = make([]string, len(l.Primitives))
+		for i, p := range l.Primitives {
+			if !commutes(p) {
+				continue
+			}
+			buf.Reset()
+			kw.cached = false
+			writeAttributes(&kw, l.objectAttributes(p))
+			p.WriteGerber(&kw, code(p)) // errors are reported when writing
+			keys[i] = buf.String()
+		}
+	}
+	drills := l.Type == DrillLayer && l.g.drillOrder != DrillAsAdded
+	if keys == nil && !drills {
+		return order
+	}
+	for start := 0; start < len(order); {
+		end := start
+		for end < len(order) && commutes(l.Primitives[end]) {
+			end++
+		}
+		if end > start+1 {
+			run := order[start:end]
+			if keys != nil {
+				sort.SliceStable(run, func(i, j int) bool { return keys[run[i]] < keys[run[j]] })
+			}
+			if drills {
+				l.orderDrills(run, code)
+			}
+		}
+		start = end + 1
+	}
+	return order
+}
@@ -0,0 +1,73 @@
+package gerber
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWithDeterministicOutput(t *testing.T) {
+	primitives := []Primitive{
+		Circle(Pt{1, 1}, 0.5),
+		Line(0, 0, 5, 0, CircleShape, 0.2),
+		Pad(Pt{3, 3}, RectShape, 1, 2, 0),
+		Polygon(Pt{}, true, []Pt{{0, 0}, {1, 0}, {1, 1}}, 0),
+		Circle(Pt{2, 2}, 0.3),
+	}
+	write := func(order []int, opts ...Option) string {
+		g := New("test", opts...)
+		top := g.TopCopper()
+		for _, i := range order {
+			top.Add(primitives[i])
+		}
+		// The clear line erases the primitives before it, but not after.
+		top.Add(Clear(Line(0, 1, 5, 1, CircleShape, 0.1)))
+		top.Add(Circle(Pt{4, 4}, 0.5), Circle(Pt{0, 4}, 0.5))
+		var buf bytes.Buffer
+		if err := top.WriteGerber(&buf); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+
+	forward, reverse := []int{0, 1, 2, 3, 4}, []int{4, 3, 2, 1, 0}
+	if write(forward) == write(reverse) {
+		t.Fatal("output does not depend upon the order of the primitives")
+	}
+	got, want := write(reverse, WithDeterministicOutput(true)), write(forward, WithDeterministicOutput(true))
+	if got != want {
+		t.Errorf("deterministic output differs:\n%v\nwant:\n%v", got, want)
+	}
+
+	// The clear line stays between the primitives before and after it.
+	clear := strings.Index(got, "%LPC*%")
+	for _, after := range []string{"X4000000Y4000000D01*", "X000000Y4000000D01*"} {
+		if i := strings.Index(got, after); i < clear {
+			t.Errorf("%v is written at %v, before the clear line at %v", after, i, clear)
+		}
+	}
+	if i := strings.Index(got, "X1000000Y1000000D01*"); i < 0 || i > clear {
+		t.Errorf("circle is written at %v, want before the clear line at %v", i, clear)
+	}
+}
+
+func TestCommutes(t *testing.T) {
+	tests := []struct {
+		name string
+		p    Primitive
+		want bool
+	}{
+		{name: "circle", p: Circle(Pt{}, 1), want: true},
+		{name: "QR code", p: QRCode(0, 0, 10, "test"), want: true},
+		{name: "inverted QR code", p: &QRCodeT{Size: 10, Content: "test", Invert: true}, want: true},
+		{name: "dark array", p: StepRepeat(2, 2, 5, 5, Circle(Pt{}, 1), Line(0, 0, 1, 0, CircleShape, 0.1)), want: true},
+		{name: "array with a clear cell", p: StepRepeat(2, 2, 5, 5, Circle(Pt{}, 1), Clear(Circle(Pt{}, 0.5)))},
+		{name: "nested array", p: StepRepeat(2, 1, 5, 0, StepRepeat(1, 2, 0, 5, Clear(Circle(Pt{}, 0.5))))},
+		{name: "clear", p: Clear(Circle(Pt{}, 1))},
+	}
+	for _, tt := range tests {
+		if got := commutes(tt.p); got != tt.want {
+			t.Errorf("commutes(%v) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,367 @@
+// Package drc checks a gerber design against design rules (minimum
+// trace widths, clearances, annular rings, and drill sizes), returning
+// each violation with its location, so that generated designs can be
+// validated before they are sent to a fab.
+package drc
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/gmlewis/go-gerber/gerber"
+)
+
+// Rule names a design rule.
+type Rule string
+
+// The design rules checked by Check.
+const (
+	MinTraceRule        Rule = "min-trace"
+	ClearanceRule       Rule = "clearance"
+	AnnularRingRule     Rule = "annular-ring"
+	DrillClearanceRule  Rule = "drill-clearance"
+	SilkscreenOnPadRule Rule = "silkscreen-on-pad"
+	MinDrillRule        Rule = "min-drill"
+)
+
+// Rules represents the design rules checked by Check. All dimensions
+// are in millimeters. Rules that are zero (or false) are not checked.
+type Rules struct {
+	// MinTrace is the minimum width of copper lines and arcs.
+	MinTrace float64
+	// Clearance is the minimum clearance between unconnected copper
+	// (islands, see Layer.Islands) on a layer.
+	Clearance float64
+	// AnnularRing is the minimum copper ring around plated holes (see
+	// Gerber.CheckDrillRegistration).
+	AnnularRing float64
+	// DrillClearance is the minimum clearance between a hole and the
+	// copper it does not connect to: copper that does not touch a
+	// plated hole and any copper near a non-plated hole (see
+	// gerber.NonPlatedTag).
+	DrillClearance float64
+	// SilkscreenOnPad reports silkscreen that touches the solder mask
+	// openings of its side, where it would print on exposed copper.
+	SilkscreenOnPad bool
+	// MinDrill is the minimum diameter of holes and width of slots.
+	MinDrill float64
+}
+
+// FromFabLimits returns the rules that check the fab's minimum trace,
+// space, and drill sizes.
+func FromFabLimits(f gerber.FabLimits) Rules {
+	return Rules{MinTrace: f.MinTrace, Clearance: f.MinSpace, MinDrill: f.MinDrill}
+}
+
+// Violation represents one violation of a design rule.
+type Violation struct {
+	Rule Rule
+	// Layer is the layer of Primitive, the offending primitive, and
+	// Other the primitive it is too close to (for clearance rules).
+	Layer     *gerber.Layer
+	Primitive gerber.Primitive
+	Other     gerber.Primitive
+	// At is the (approximate) location of the violation, in mm.
+	At gerber.Pt
+	// Actual is the measured size or clearance and Limit the rule's
+	// minimum, in mm. Actual is not measured for annular rings.
+	Actual, Limit float64
+	// Message describes the violation.
+	Message string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%v: %v: %v at %v", v.Layer.Filename, v.Rule, v.Message, fmtPt(v.At))
+}
+
+// Check derives the design's pads and openings (see
+// Gerber.DeriveOpenings) and returns the violations of the rules,
+// grouped by rule (in the order of the Rules fields) and then in layer
+// order.
+func Check(g *gerber.Gerber, r Rules) ([]Violation, error) {
+	return CheckContext(context.Background(), g, r)
+}
+
+// CheckContext is like Check but stops early (returning ctx.Err()) if
+// ctx is canceled, for large designs whose clearances take long to
+// check.
+func CheckContext(ctx context.Context, g *gerber.Gerber, r Rules) ([]Violation, error) {
+	if err := g.DeriveOpenings(); err != nil {
+		return nil, err
+	}
+	c := &canceler{ctx: ctx}
+	var vs []Violation
+	add := func(v []Violation, err error) error {
+		vs = append(vs, v...)
+		return err
+	}
+	if r.MinTrace > 0 {
+		if err := add(checkMinTrace(c, g, r.MinTrace)); err != nil {
+			return nil, err
+		}
+	}
+	if r.Clearance > 0 {
+		if err := add(checkClearance(c, g, r.Clearance)); err != nil {
+			return nil, err
+		}
+	}
+	if r.AnnularRing > 0 {
+		issues, err := g.CheckDrillRegistration(r.AnnularRing)
+		if err != nil {
+			return nil, err
+		}
+		for _, i := range issues {
+			vs = append(vs, Violation{Rule: AnnularRingRule, Layer: i.Layer, Primitive: i.Primitive,
+				At: center(i.Primitive.MBB()), Limit: r.AnnularRing, Message: i.Message})
+		}
+	}
+	if r.DrillClearance > 0 {
+		if err := add(checkDrillClearance(c, g, r.DrillClearance)); err != nil {
+			return nil, err
+		}
+	}
+	if r.SilkscreenOnPad {
+		if err := add(checkSilkscreen(c, g)); err != nil {
+			return nil, err
+		}
+	}
+	if r.MinDrill > 0 {
+		if err := add(checkMinDrill(c, g, r.MinDrill)); err != nil {
+			return nil, err
+		}
+	}
+	return vs, nil
+}
+
+// ctxCheckInterval is the number of primitives checked between checks
+// for cancellation.
+const ctxCheckInterval = 1000
+
+// canceler checks for the cancellation of a check every
+// ctxCheckInterval primitives.
+type canceler struct {
+	ctx context.Context
+	n   int
+}
+
+// next counts a primitive, returning ctx.Err() if it is due to be
+// checked.
+func (c *canceler) next() error {
+	c.n++
+	if c.n%ctxCheckInterval != 0 {
+		return nil
+	}
+	return c.ctx.Err()
+}
+
+func checkMinTrace(c *canceler, g *gerber.Gerber, limit float64) ([]Violation, error) {
+	var vs []Violation
+	for _, layer := range g.Layers {
+		if !layer.Type.IsCopper() {
+			continue
+		}
+		for _, p := range layer.Primitives {
+			if err := c.next(); err != nil {
+				return nil, err
+			}
+			var width float64
+			switch v := p.(type) {
+			case *gerber.LineT:
+				width = v.Thickness
+			case *gerber.ArcT:
+				width = v.Thickness
+			default:
+				continue
+			}
+			if width < limit {
+				vs = append(vs, Violation{Rule: MinTraceRule, Layer: layer, Primitive: p, At: center(p.MBB()),
+					Actual: width, Limit: limit, Message: fmt.Sprintf("trace width %vmm < %vmm", fmtFloat(width), fmtFloat(limit))})
+			}
+		}
+	}
+	return vs, nil
+}
+
+// checkClearance reports the closest primitives of each pair of
+// islands of a copper layer that are too close.
+func checkClearance(c *canceler, g *gerber.Gerber, limit float64) ([]Violation, error) {
+	var vs []Violation
+	for _, layer := range g.Layers {
+		if !layer.Type.IsCopper() {
+			continue
+		}
+		islands := layer.Islands(gerber.IslandOpts{})
+		for i := range islands {
+			for j := i + 1; j < len(islands); j++ {
+				var closest *Violation
+				for _, a := range islands[i].Primitives {
+					if err := c.next(); err != nil {
+						return nil, err
+					}
+					for _, b := range islands[j].Primitives {
+						ma, mb := a.MBB(), b.MBB()
+						if gap(ma, mb) >= limit {
+							continue
+						}
+						if d := gerber.PrimitiveDistance(a, b); d < limit && (closest == nil || d < closest.Actual) {
+							closest = &Violation{Rule: ClearanceRule, Layer: layer, Primitive: a, Other: b,
+								At: between(ma, mb), Actual: d, Limit: limit}
+						}
+					}
+				}
+				if closest != nil {
+					closest.Message = fmt.Sprintf("copper clearance %vmm < %vmm", fmtFloat(closest.Actual), fmtFloat(limit))
+					vs = append(vs, *closest)
+				}
+			}
+		}
+	}
+	return vs, nil
+}
+
+func checkDrillClearance(c *canceler, g *gerber.Gerber, limit float64) ([]Violation, error) {
+	var copper []*gerber.Layer
+	for _, layer := range g.Layers {
+		if layer.Type.IsCopper() {
+			copper = append(copper, layer)
+		}
+	}
+	var vs []Violation
+	for _, drill := range g.Layers {
+		if drill.Type != gerber.DrillLayer {
+			continue
+		}
+		for _, hole := range drill.Primitives {
+			if err := c.next(); err != nil {
+				return nil, err
+			}
+			plated := !drill.HasTag(hole, gerber.NonPlatedTag)
+			mh := hole.MBB()
+			near := gerber.MBB{Min: gerber.Pt{mh.Min[0] - limit, mh.Min[1] - limit}, Max: gerber.Pt{mh.Max[0] + limit, mh.Max[1] + limit}}
+			for _, layer := range copper {
+				for _, p := range layer.Query(near) {
+					mp := p.MBB()
+					if gap(mh, mp) >= limit {
+						continue
+					}
+					d := gerber.PrimitiveDistance(hole, p)
+					if d >= limit || (plated && d == 0) {
+						continue
+					}
+					vs = append(vs, Violation{Rule: DrillClearanceRule, Layer: drill, Primitive: hole, Other: p,
+						At: between(mh, mp), Actual: d, Limit: limit,
+						Message: fmt.Sprintf("hole to copper clearance %vmm < %vmm on %v", fmtFloat(d), fmtFloat(limit), layer.Filename)})
+				}
+			}
+		}
+	}
+	return vs, nil
+}
+
+// silkscreenSides pairs the silkscreen layer of each side with its
+// solder mask layer.
+var silkscreenSides = map[gerber.LayerType]gerber.LayerType{
+	gerber.TopSilkscreenLayer:    gerber.TopSolderMaskLayer,
+	gerber.BottomSilkscreenLayer: gerber.BottomSolderMaskLayer,
+}
+
+func checkSilkscreen(c *canceler, g *gerber.Gerber) ([]Violation, error) {
+	var vs []Violation
+	for _, silk := range g.Layers {
+		maskType, ok := silkscreenSides[silk.Type]
+		if !ok {
+			continue
+		}
+		for _, mask := range g.Layers {
+			if mask.Type != maskType {
+				continue
+			}
+			for _, s := range silk.Primitives {
+				if err := c.next(); err != nil {
+					return nil, err
+				}
+				ms := s.MBB()
+				for _, m := range mask.Query(ms) {
+					if mm := m.MBB(); gerber.PrimitiveDistance(s, m) == 0 {
+						vs = append(vs, Violation{Rule: SilkscreenOnPadRule, Layer: silk, Primitive: s, Other: m,
+							At: between(ms, mm), Message: "silkscreen over a solder mask opening"})
+						break
+					}
+				}
+			}
+		}
+	}
+	return vs, nil
+}
+
+func checkMinDrill(c *canceler, g *gerber.Gerber, limit float64) ([]Violation, error) {
+	var vs []Violation
+	for _, drill := range g.Layers {
+		if drill.Type != gerber.DrillLayer {
+			continue
+		}
+		for _, p := range drill.Primitives {
+			if err := c.next(); err != nil {
+				return nil, err
+			}
+			var d float64
+			switch v := p.(type) {
+			case *gerber.CircleT:
+				mbb := v.MBB()
+				d = mbb.Max[0] - mbb.Min[0]
+			case *gerber.LineT:
+				d = v.Thickness
+			default:
+				continue
+			}
+			if d < limit {
+				vs = append(vs, Violation{Rule: MinDrillRule, Layer: drill, Primitive: p, At: center(p.MBB()),
+					Actual: d, Limit: limit, Message: fmt.Sprintf("drill %vmm < %vmm", fmtFloat(d), fmtFloat(limit))})
+			}
+		}
+	}
+	return vs, nil
+}
+
+// Count returns the number of violations of each rule.
+func Count(vs []Violation) map[Rule]int {
+	counts := map[Rule]int{}
+	for _, v := range vs {
+		counts[v.Rule]++
+	}
+	return counts
+}
+
+// center returns the center of mbb.
+func center(mbb gerber.MBB) gerber.Pt {
+	return gerber.Pt{0.5 * (mbb.Min[0] + mbb.Max[0]), 0.5 * (mbb.Min[1] + mbb.Max[1])}
+}
+
+// between returns a point between the MBBs: in the middle of their
+// overlap or of the gap between them, along each axis.
+func between(a, b gerber.MBB) gerber.Pt {
+	var pt gerber.Pt
+	for i := 0; i < 2; i++ {
+		lo, hi := math.Max(a.Min[i], b.Min[i]), math.Min(a.Max[i], b.Max[i])
+		pt[i] = 0.5 * (lo + hi)
+	}
+	return pt
+}
+
+// gap returns the distance between two MBBs (0 if they intersect).
+func gap(a, b gerber.MBB) float64 {
+	dx := math.Max(0, math.Max(a.Min[0]-b.Max[0], b.Min[0]-a.Max[0]))
+	dy := math.Max(0, math.Max(a.Min[1]-b.Max[1], b.Min[1]-a.Max[1]))
+	return math.Hypot(dx, dy)
+}
+
+// fmtFloat formats a value in mm, rounded to the nearest nanometer.
+func fmtFloat(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1e6)/1e6, 'f', -1, 64)
+}
+
+func fmtPt(pt gerber.Pt) string {
+	return fmt.Sprintf("(%v,%v)", fmtFloat(pt[0]), fmtFloat(pt[1]))
+}
@@ -0,0 +1,90 @@
+package drc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gmlewis/go-gerber/gerber"
+)
+
+func TestCheck(t *testing.T) {
+	g := gerber.New("test")
+	top := g.TopCopper()
+	// A thin trace 0.35mm from another.
+	top.Add(gerber.Line(0, 0, 10, 0, gerber.CircleShape, 0.1))
+	top.Add(gerber.Line(0, 0.5, 10, 0.5, gerber.CircleShape, 0.2))
+	// A small plated hole with a 0.1mm ring, under the silkscreen.
+	g.Drill().Add(gerber.Circle(gerber.Pt{5, 3}, 0.3))
+	top.AddWithOpenings(gerber.Openings{Mask: true}, gerber.Circle(gerber.Pt{5, 3}, 0.5))
+	g.TopSilkscreen().Add(gerber.Line(4, 3, 6, 3, gerber.CircleShape, 0.15))
+	// A mounting hole 0.3mm from copper.
+	g.Drill().AddTagged([]string{gerber.NonPlatedTag}, gerber.Circle(gerber.Pt{5, -3}, 1))
+	top.Add(gerber.Circle(gerber.Pt{5, -2}, 0.4))
+
+	vs, err := Check(g, Rules{
+		MinTrace:        0.15,
+		Clearance:       0.4,
+		AnnularRing:     0.15,
+		DrillClearance:  0.5,
+		SilkscreenOnPad: true,
+		MinDrill:        0.35,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		rule   Rule
+		s      string
+		actual float64
+	}{
+		{MinTraceRule, "test.gtl: min-trace: trace width 0.1mm < 0.15mm at (5,0)", 0.1},
+		{ClearanceRule, "test.gtl: clearance: copper clearance 0.35mm < 0.4mm at (5,0.225)", 0.35},
+		{AnnularRingRule, "test.drl: annular-ring: hole #0 at (5,3) (0.3mm) lacks a 0.15mm annular ring on test.gtl at (5,3)", 0},
+		{DrillClearanceRule, "test.drl: drill-clearance: hole to copper clearance 0.3mm < 0.5mm on test.gtl at (5,-2.35)", 0.3},
+		{SilkscreenOnPadRule, "test.gto: silkscreen-on-pad: silkscreen over a solder mask opening at (5,3)", 0},
+		{MinDrillRule, "test.drl: min-drill: drill 0.3mm < 0.35mm at (5,3)", 0.3},
+	}
+	if len(vs) != len(want) {
+		t.Fatalf("Check = %v, want %v violations", vs, len(want))
+	}
+	for i, v := range vs {
+		if v.Rule != want[i].rule || v.String() != want[i].s {
+			t.Errorf("violation[%v] = %v %q, want %v %q", i, v.Rule, v, want[i].rule, want[i].s)
+		}
+		if d := v.Actual - want[i].actual; d > 1e-9 || d < -1e-9 {
+			t.Errorf("violation[%v].Actual = %v, want %v", i, v.Actual, want[i].actual)
+		}
+	}
+	if got := Count(vs)[MinDrillRule]; got != 1 {
+		t.Errorf("Count[%v] = %v, want 1", MinDrillRule, got)
+	}
+
+	// Rules that are zero are not checked.
+	if vs, err := Check(g, Rules{MinTrace: 0.1}); err != nil || len(vs) != 0 {
+		t.Errorf("Check(MinTrace: 0.1) = %v, %v, want no violations", vs, err)
+	}
+}
+
+func TestCheckContext_Canceled(t *testing.T) {
+	g := gerber.New("test")
+	top := g.TopCopper()
+	for i := 0; i < 2000; i++ {
+		top.Add(gerber.Line(float64(i), 0, float64(i), 1, gerber.CircleShape, 0.05))
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := CheckContext(ctx, g, Rules{MinTrace: 0.1}); err != context.Canceled {
+		t.Errorf("CheckContext = %v, want %v", err, context.Canceled)
+	}
+	if vs, err := CheckContext(context.Background(), g, Rules{MinTrace: 0.1}); err != nil || len(vs) != 2000 {
+		t.Errorf("CheckContext = %v violations, %v, want 2000", len(vs), err)
+	}
+}
+
+func TestFromFabLimits(t *testing.T) {
+	got := FromFabLimits(gerber.OSHPark.Standard)
+	want := Rules{MinTrace: 0.1524, Clearance: 0.1524, MinDrill: 0.254}
+	if got != want {
+		t.Errorf("FromFabLimits = %+v, want %+v", got, want)
+	}
+}
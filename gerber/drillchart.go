@@ -0,0 +1,144 @@
+package gerber
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// DrillCount reports the number of holes of one finished size.
+type DrillCount struct {
+	// Diameter is the finished hole size in millimeters.
+	Diameter float64 `json:"diameter"`
+	// Plated is false for holes tagged with NonPlatedTag.
+	Plated bool `json:"plated"`
+	Count  int  `json:"count"`
+}
+
+func (d DrillCount) String() string {
+	kind := ""
+	if !d.Plated {
+		kind = " NPTH"
+	}
+	return fmt.Sprintf("%vmm%v x%v", fmtFloat(d.Diameter), kind, d.Count)
+}
+
+// DrillCounts returns the number of round holes of each finished size
+// in the design's drill layers, ordered by size (plated holes first).
+func (g *Gerber) DrillCounts() []DrillCount {
+	type key struct {
+		diameter float64
+		plated   bool
+	}
+	counts := map[key]int{}
+	for _, drill := range g.layersOfType(DrillLayer) {
+		for _, p := range drill.Primitives {
+			if hole, ok := p.(*CircleT); ok {
+				counts[key{diameter: hole.thickness, plated: !drill.HasTag(p, NonPlatedTag)}]++
+			}
+		}
+	}
+	var result []DrillCount
+	for k, n := range counts {
+		result = append(result, DrillCount{Diameter: k.diameter, Plated: k.plated, Count: n})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Diameter != result[j].Diameter {
+			return result[i].Diameter < result[j].Diameter
+		}
+		return result[i].Plated && !result[j].Plated
+	})
+	return result
+}
+
+const (
+	// drillChartPts is the size of the drill chart text.
+	drillChartPts = 6
+	// drillChartMargin is the distance (in mm) of the drill chart
+	// from the edges of the board and from other features.
+	drillChartMargin = 1
+)
+
+// drillChartLayers are the layers whose features the drill chart must
+// not overlap.
+var drillChartLayers = []LayerType{TopSilkscreenLayer, TopCopperLayer, TopSolderMaskLayer, DrillLayer}
+
+// drillChart returns the lines of the drill chart with its top left
+// corner at tl.
+func drillChart(counts []DrillCount, tl Pt, fontName string) (Group, error) {
+	lines := []string{"DRILLS"}
+	for _, c := range counts {
+		lines = append(lines, c.String())
+	}
+	var g Group
+	y := tl[1]
+	for _, line := range lines {
+		t := Text(tl[0], y, 1, line, fontName, drillChartPts, &TopLeft)
+		if err := t.Err(); err != nil {
+			return nil, err
+		}
+		// Leave a gap of half the line height between lines.
+		y -= 1.5 * drillChartPts * mmPerPt
+		g = append(g, t)
+	}
+	return g, nil
+}
+
+// addDrillChart adds the drill chart to the first corner of the board
+// outline (top left, top right, bottom left, then bottom right) where
+// it does not overlap the top side's features.
+func (g *Gerber) addDrillChart(fontName string) error {
+	counts := g.DrillCounts()
+	if len(counts) == 0 {
+		return nil
+	}
+	board := g.MBB()
+	if outlines := g.layersOfType(OutlineLayer); len(outlines) > 0 && !outlines[0].IsEmpty() {
+		board = outlines[0].MBB()
+	}
+	chart, err := drillChart(counts, Pt{}, fontName)
+	if err != nil {
+		return err
+	}
+	size := chart.MBB()
+	width, height := size.Max[0]-size.Min[0], size.Max[1]-size.Min[1]
+	left, right := board.Min[0]+drillChartMargin, board.Max[0]-drillChartMargin-width
+	top, bottom := board.Max[1]-drillChartMargin, board.Min[1]+drillChartMargin+height
+
+	var features []Primitive
+	for _, t := range drillChartLayers {
+		for _, layer := range g.layersOfType(t) {
+			features = append(features, layer.Primitives...)
+		}
+	}
+	inside := MBB{
+		Min: Pt{board.Min[0] - validationEps, board.Min[1] - validationEps},
+		Max: Pt{board.Max[0] + validationEps, board.Max[1] + validationEps},
+	}
+	for _, tl := range []Pt{{left, top}, {right, top}, {left, bottom}, {right, bottom}} {
+		area := MBB{
+			Min: Pt{tl[0] - drillChartMargin, tl[1] - height - drillChartMargin},
+			Max: Pt{tl[0] + width + drillChartMargin, tl[1] + drillChartMargin},
+		}
+		if !inside.Contains(&area) {
+			continue
+		}
+		free := true
+		for _, p := range features {
+			if mbb := p.MBB(); mbb.Intersects(&area) {
+				free = false
+				break
+			}
+		}
+		if !free {
+			continue
+		}
+		chart, err := drillChart(counts, tl, fontName)
+		if err != nil {
+			return err
+		}
+		g.firstLayerOfType(TopSilkscreenLayer).AddDerived(chart...)
+		return nil
+	}
+	return errors.New("no unused corner of the top silkscreen for the drill chart")
+}
@@ -0,0 +1,78 @@
+package gerber
+
+import (
+	"testing"
+
+	_ "github.com/gmlewis/go-fonts/fonts/freeserif"
+)
+
+// drillChartBoard returns a 50x30mm board with plated holes in its
+// center and a mounting hole in its top left corner.
+func drillChartBoard(opts ...Option) *Gerber {
+	g := New("test", opts...)
+	g.Outline().Add(OutlinePath(RoundedRect(MBB{Max: Pt{50, 30}}, 0), 0.1)...)
+	drill := g.Drill()
+	for i := 0; i < 4; i++ {
+		x := 20 + 2.54*float64(i)
+		drill.Add(Circle(Pt{x, 15}, 0.8))
+		g.TopCopper().Add(Circle(Pt{x, 15}, 1.6))
+	}
+	drill.Add(Circle(Pt{25, 12}, 0.4))
+	drill.AddTagged([]string{NonPlatedTag}, Circle(Pt{3, 27}, 3.2))
+	return g
+}
+
+func TestGerber_DrillCounts(t *testing.T) {
+	got := drillChartBoard().DrillCounts()
+	want := []DrillCount{
+		{Diameter: 0.4, Plated: true, Count: 1},
+		{Diameter: 0.8, Plated: true, Count: 4},
+		{Diameter: 3.2, Plated: false, Count: 1},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("DrillCounts = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("DrillCounts[%v] = %v, want %v", i, got[i], want[i])
+		}
+	}
+	if got, want := want[2].String(), "3.2mm NPTH x1"; got != want {
+		t.Errorf("String = %q, want %q", got, want)
+	}
+}
+
+func TestWithDrillChart(t *testing.T) {
+	g := drillChartBoard(WithDrillChart("freeserif"))
+	// Run the pipeline twice to check that the chart is not duplicated.
+	for i := 0; i < 2; i++ {
+		if err := g.DeriveOpenings(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	silk := g.layersOfType(TopSilkscreenLayer)
+	if len(silk) != 1 {
+		t.Fatalf("got %v top silkscreen layers, want 1", len(silk))
+	}
+	if got, want := len(silk[0].Primitives), 4; got != want {
+		t.Fatalf("got %v silkscreen primitives, want %v", got, want)
+	}
+	// The top left corner holds the mounting hole, so the chart
+	// belongs in the top right corner.
+	mbb := silk[0].MBB()
+	if mbb.Min[0] < 25 || mbb.Max[0] > 49.1 || mbb.Max[1] < 28.9 || mbb.Min[1] < 15 {
+		t.Errorf("chart MBB = %v, want the top right corner", mbb)
+	}
+
+	if err := New("test").DeriveOpenings(); err != nil {
+		t.Errorf("DeriveOpenings without the chart = %v, want nil", err)
+	}
+}
+
+func TestWithDrillChart_NoCorner(t *testing.T) {
+	g := drillChartBoard(WithDrillChart("freeserif"))
+	g.TopSilkscreen().Add(Polygon(Pt{}, true, RoundedRect(MBB{Max: Pt{50, 30}}, 0), 0))
+	if err := g.DeriveOpenings(); err == nil {
+		t.Error("DeriveOpenings with no free corner = nil error, want error")
+	}
+}
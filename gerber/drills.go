@@ -0,0 +1,222 @@
+package gerber
+
+import (
+	"math"
+	"path/filepath"
+	"sort"
+)
+
+// DrillOrder selects the order in which the holes of drill layers are
+// written (see WithDrillOrder).
+type DrillOrder int
+
+const (
+	// DrillAsAdded writes the holes of Gerber drill layers in the order
+	// they were added, and those of Excellon files by tool in that order.
+	DrillAsAdded DrillOrder = iota
+	// DrillByTool also groups the holes of Gerber drill layers by tool
+	// (aperture), so that each tool is only loaded once.
+	DrillByTool
+	// DrillNearestNeighbor groups the holes by tool and orders those of
+	// each tool by a nearest neighbor path, starting from the design's
+	// origin (see SetOrigin) and then from the last hole of the previous
+	// tool, to reduce the travel of the drilling machine (e.g. on large
+	// via-stitched designs).
+	DrillNearestNeighbor
+)
+
+// WithDrillOrder sets the order in which the holes of drill layers are
+// written to Gerber drill files and Excellon files (see WriteExcellon).
+// Only consecutive holes that may be drilled in any order are
+// reordered (see WithDeterministicOutput).
+func WithDrillOrder(order DrillOrder) Option {
+	return func(g *Gerber) {
+		g.drillOrder = order
+	}
+}
+
+// WithSplitDrills enables or disables writing the non-plated holes
+// (those tagged with NonPlatedTag) of each drill layer to a separate
+// file, named after the layer's file with "-NPTH" inserted before its
+// extension (e.g. "board-NPTH.drl"), as required by many fabs. The
+// layers' own files then only have the plated holes, and their X2 file
+// functions are "Plated" and "NonPlated". Drill layers without
+// non-plated holes are written as usual.
+func WithSplitDrills(enabled bool) Option {
+	return func(g *Gerber) {
+		g.splitDrills = enabled
+	}
+}
+
+// platedDrill and nonPlatedDrill are the kinds of the drill layers
+// written for each drill layer with WithSplitDrills.
+const (
+	platedDrill    = "PTH"
+	nonPlatedDrill = "NPTH"
+)
+
+// outputLayers returns the layers written to files: the design's
+// layers, with each drill layer that has non-plated holes replaced by
+// its plated and non-plated holes if split (see WithSplitDrills and
+// WithExcellonDrills).
+func (g *Gerber) outputLayers() []*Layer {
+	if !g.splitDrills && !g.excellonDrills {
+		return g.Layers
+	}
+	var layers []*Layer
+	for _, layer := range g.Layers {
+		if layer.Type != DrillLayer || len(layer.Select(NonPlatedTag)) == 0 {
+			layers = append(layers, layer)
+			continue
+		}
+		layers = append(layers, layer.drillView(platedDrill), layer.drillView(nonPlatedDrill))
+	}
+	return layers
+}
+
+// drillView returns a layer with the plated or non-plated holes of the
+// drill layer (sharing their tags and attributes), to be written in
+// place of it.
+func (l *Layer) drillView(kind string) *Layer {
+	view := &Layer{
+		Filename:    l.Filename,
+		Type:        l.Type,
+		N:           l.N,
+		Grid:        l.Grid,
+		Format:      l.Format,
+		apertureMap: map[string]int{"default": -1},
+		tags:        l.tags,
+		attrs:       l.attrs,
+		headerHooks: l.headerHooks,
+		footerHooks: l.footerHooks,
+		drillKind:   kind,
+		g:           l.g,
+	}
+	if kind == nonPlatedDrill {
+		ext := filepath.Ext(l.Filename)
+		view.Filename = l.Filename[:len(l.Filename)-len(ext)] + "-NPTH" + ext
+	}
+	for _, p := range l.Primitives {
+		if l.HasTag(p, NonPlatedTag) == (kind == nonPlatedDrill) {
+			view.Add(p)
+		}
+	}
+	return view
+}
+
+// orderDrills reorders a run of commuting primitives (by index into
+// the drill layer's primitives) as set by WithDrillOrder, given the
+// D-codes of their apertures.
+func (l *Layer) orderDrills(run []int, code func(p Primitive) int) {
+	if l.g.drillOrder == DrillAsAdded {
+		return
+	}
+	sort.SliceStable(run, func(i, j int) bool { return code(l.Primitives[run[i]]) < code(l.Primitives[run[j]]) })
+	if l.g.drillOrder != DrillNearestNeighbor {
+		return
+	}
+	at := l.g.origin
+	for start := 0; start < len(run); {
+		end := start + 1
+		for end < len(run) && code(l.Primitives[run[end]]) == code(l.Primitives[run[start]]) {
+			end++
+		}
+		tool := append([]int(nil), run[start:end]...)
+		starts, ends := make([]Pt, len(tool)), make([]Pt, len(tool))
+		for i, pi := range tool {
+			starts[i], ends[i] = drillEnds(l.Primitives[pi])
+		}
+		var path []int
+		path, at = nearestNeighborPath(at, starts, ends)
+		for i, k := range path {
+			run[start+i] = tool[k]
+		}
+		start = end
+	}
+}
+
+// drillEnds returns where the drilling of a hole primitive starts and
+// ends: the ends of a slot, or the center of any other hole.
+func drillEnds(p Primitive) (Pt, Pt) {
+	if l, ok := p.(*LineT); ok {
+		return l.P1, l.P2
+	}
+	mbb := p.MBB()
+	center := Pt{0.5 * (mbb.Min[0] + mbb.Max[0]), 0.5 * (mbb.Min[1] + mbb.Max[1])}
+	return center, center
+}
+
+// nearestNeighborPath returns the order in which to visit the holes
+// that start and end at the given points, starting from `from` and then
+// always going to the nearest unvisited start, and the end of the last
+// hole. The starts are bucketed in a grid of about one per cell, so
+// that each step only searches the cells around the current position.
+func nearestNeighborPath(from Pt, starts, ends []Pt) ([]int, Pt) {
+	if len(starts) == 0 {
+		return nil, from
+	}
+	mbb := MBB{Min: starts[0], Max: starts[0]}
+	for _, pt := range starts[1:] {
+		mbb.Join(&MBB{Min: pt, Max: pt})
+	}
+	w, h := mbb.Max[0]-mbb.Min[0], mbb.Max[1]-mbb.Min[1]
+	n := float64(len(starts))
+	cell := math.Max(math.Sqrt(w*h/n), (w+h)/n) // (w+h)/n for holes in a row
+	if cell < 1e-6 {
+		cell = 1
+	}
+	cellOf := func(pt Pt) (int, int) {
+		return int(math.Floor((pt[0] - mbb.Min[0]) / cell)), int(math.Floor((pt[1] - mbb.Min[1]) / cell))
+	}
+	nx, ny := cellOf(mbb.Max)
+	cells := map[[2]int][]int{}
+	for i, pt := range starts {
+		x, y := cellOf(pt)
+		cells[[2]int{x, y}] = append(cells[[2]int{x, y}], i)
+	}
+
+	path := make([]int, 0, len(starts))
+	take := func(key [2]int, k int) {
+		c := cells[key]
+		path = append(path, c[k])
+		from = ends[c[k]]
+		c[k] = c[len(c)-1]
+		cells[key] = c[:len(c)-1]
+	}
+	for len(path) < len(starts) {
+		bestKey, best, bestDist := [2]int{}, -1, 0.0
+		consider := func(key [2]int) {
+			for k, i := range cells[key] {
+				if d := Distance(from, starts[i]); best < 0 || d < bestDist || (d == bestDist && i < cells[bestKey][best]) {
+					bestKey, best, bestDist = key, k, d
+				}
+			}
+		}
+		cx, cy := cellOf(from)
+		if cx < 0 || cy < 0 || cx > nx || cy > ny {
+			for key := range cells {
+				consider(key)
+			}
+			take(bestKey, best)
+			continue
+		}
+		// The starts in ring r (the cells at a Chebyshev distance of r
+		// from the current one) are at least (r-1)*cell away.
+		for r := 0; r <= nx+ny && !(best >= 0 && bestDist <= float64(r-1)*cell); r++ {
+			if r == 0 {
+				consider([2]int{cx, cy})
+				continue
+			}
+			for x := cx - r; x <= cx+r; x++ {
+				consider([2]int{x, cy - r})
+				consider([2]int{x, cy + r})
+			}
+			for y := cy - r + 1; y < cy+r; y++ {
+				consider([2]int{cx - r, y})
+				consider([2]int{cx + r, y})
+			}
+		}
+		take(bestKey, best)
+	}
+	return path, from
+}
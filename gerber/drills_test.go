@@ -0,0 +1,142 @@
+package gerber
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestWithSplitDrills(t *testing.T) {
+	write := func(opts ...Option) map[string]*memFile {
+		g := New("board", append(opts, WithX2(true), WithManifest(true), WithParallelWrites(2))...)
+		g.TopCopper().Add(Circle(Pt{1, 1}, 1))
+		drill := g.Drill()
+		drill.Add(Circle(Pt{1, 1}, 0.4))
+		g.AddSlot(Pt{5, 1}, Pt{5, 3}, 1, 0, false)
+		drill.AddTagged([]string{NonPlatedTag}, Circle(Pt{8, 8}, 3.2))
+		files := map[string]*memFile{}
+		err := g.Write(func(filename string) (io.WriteCloser, error) {
+			f := &memFile{}
+			files[filename] = f
+			return f, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return files
+	}
+
+	files := write()
+	if _, ok := files["board-NPTH.drl"]; ok {
+		t.Error("non-plated holes were split without WithSplitDrills")
+	}
+	if got := files["board.drl"].String(); !strings.Contains(got, "C,3.20000*%") {
+		t.Errorf("drill file lacks the non-plated hole:\n%v", got)
+	}
+
+	files = write(WithSplitDrills(true))
+	pth, npth := files["board.drl"].String(), files["board-NPTH.drl"].String()
+	if !strings.Contains(pth, "%TF.FileFunction,Plated,1,1,PTH*%") || !strings.Contains(pth, "C,0.40000*%") ||
+		strings.Contains(pth, "C,3.20000*%") || strings.Contains(pth, "C,1.00000*%") {
+		t.Errorf("plated drill file =\n%v", pth)
+	}
+	if !strings.Contains(npth, "%TF.FileFunction,NonPlated,1,1,NPTH*%") || !strings.Contains(npth, "C,3.20000*%") ||
+		!strings.Contains(npth, "C,1.00000*%") || strings.Contains(npth, "C,0.40000*%") {
+		t.Errorf("non-plated drill file =\n%v", npth)
+	}
+	if got := files["board-README.txt"].String(); !strings.Contains(got, "board-NPTH.drl") || !strings.Contains(got, "Non-plated drill holes") {
+		t.Errorf("README =\n%v", got)
+	}
+}
+
+func TestWithDrillOrder(t *testing.T) {
+	write := func(order DrillOrder) string {
+		g := New("test", WithDrillOrder(order))
+		drill := g.Drill()
+		drill.Add(
+			Circle(Pt{9, 0}, 0.3),
+			Circle(Pt{1, 0}, 0.5),
+			Circle(Pt{2, 0}, 0.3),
+			Circle(Pt{5, 0}, 0.3),
+			Circle(Pt{0, 0}, 0.5),
+		)
+		var buf bytes.Buffer
+		if err := drill.WriteGerber(&buf); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+	order := func(out string) string {
+		var xs []string
+		for _, line := range strings.Split(out, "\n") {
+			if strings.HasSuffix(line, "D02*") {
+				xs = append(xs, line[1:strings.Index(line, "Y")])
+			}
+		}
+		return strings.Join(xs, " ")
+	}
+	tests := []struct {
+		order DrillOrder
+		want  string
+	}{
+		{DrillAsAdded, "9000000 1000000 2000000 5000000 000000"},
+		{DrillByTool, "9000000 2000000 5000000 1000000 000000"},
+		{DrillNearestNeighbor, "2000000 5000000 9000000 1000000 000000"},
+	}
+	for _, tt := range tests {
+		if got := order(write(tt.order)); got != tt.want {
+			t.Errorf("order %v: got holes at X=%v, want %v", tt.order, got, tt.want)
+		}
+	}
+}
+
+func TestWriteExcellon_NearestNeighbor(t *testing.T) {
+	g := New("board", WithDrillOrder(DrillNearestNeighbor))
+	g.Drill().Add(Circle(Pt{9, 0}, 0.3), Circle(Pt{2, 0}, 0.3), Slot(Pt{6, 0}, Pt{4, 0}, 0.3), Circle(Pt{3, 0}, 0.3))
+	var buf bytes.Buffer
+	if err := g.WriteExcellon(&buf, true); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "T1\nX2.0Y0.0\nX3.0Y0.0\nX6.0Y0.0G85X4.0Y0.0\nX9.0Y0.0\nT0\n"; !strings.Contains(got, want) {
+		t.Errorf("WriteExcellon =\n%v\nwant holes:\n%v", got, want)
+	}
+}
+
+func TestNearestNeighborPath(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	var pts []Pt
+	for i := 0; i < 500; i++ {
+		pts = append(pts, Pt{100 * r.Float64(), 50 * r.Float64()})
+	}
+	// A row of holes uses a grid for degenerate bounds as well.
+	for i := 0; i < 50; i++ {
+		pts = append(pts, Pt{float64(i), 60})
+	}
+	path, _ := nearestNeighborPath(Pt{}, pts, pts)
+
+	// Compare with a brute force search.
+	visited := make([]bool, len(pts))
+	at := Pt{}
+	for n, i := range path {
+		if visited[i] {
+			t.Fatalf("hole %v is visited twice", i)
+		}
+		best := math.Inf(1)
+		for j, pt := range pts {
+			if !visited[j] {
+				best = math.Min(best, Distance(at, pt))
+			}
+		}
+		if d := Distance(at, pts[i]); d > best+1e-9 {
+			t.Fatalf("step %v goes %v to hole %v, want %v to the nearest", n, d, i, best)
+		}
+		visited[i] = true
+		at = pts[i]
+	}
+	if len(path) != len(pts) {
+		t.Errorf("got a path of %v holes, want %v", len(path), len(pts))
+	}
+}
@@ -0,0 +1,142 @@
+package gerber
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// String returns a one-line summary of the design.
+func (g *Gerber) String() string {
+	var n int
+	for _, layer := range g.Layers {
+		n += len(layer.Primitives)
+	}
+	return fmt.Sprintf("Gerber %q: %v layers, %v primitives, MBB %v", g.FilenamePrefix, len(g.Layers), n, fmtMBB(g.MBB()))
+}
+
+// Dump writes a human-readable description of the design (including
+// every layer, its aperture table, and its primitives) to w.
+func (g *Gerber) Dump(w io.Writer) error {
+	if _, err := fmt.Fprintln(w, g); err != nil {
+		return err
+	}
+	for _, layer := range g.Layers {
+		if err := layer.Dump(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// String returns a one-line summary of the layer.
+func (l *Layer) String() string {
+	mbb := "empty"
+	if !l.IsEmpty() {
+		mbb = fmtMBB(l.MBB())
+	}
+	return fmt.Sprintf("%v layer %q: %v primitives, %v apertures, MBB %v", l.Type, l.Filename, len(l.Primitives), len(l.Apertures), mbb)
+}
+
+// Dump writes a human-readable description of the layer (including
+// its aperture table, primitive counts by type, and primitives) to w.
+func (l *Layer) Dump(w io.Writer) error {
+	ew := &errWriter{w: w}
+	fmt.Fprintln(ew, l)
+
+	if defaultCode, codes, err := l.apertureCodes(); err != nil {
+		fmt.Fprintf(ew, "  apertures: %v\n", err)
+	} else {
+		fmt.Fprintf(ew, "  D%v: default\n", defaultCode)
+		for _, i := range sortedIndices(codes) {
+			fmt.Fprintf(ew, "  D%v: %v\n", codes[i], l.Apertures[i])
+		}
+	}
+
+	counts := map[string]int{}
+	for _, p := range l.Primitives {
+		counts[primitiveTypeName(p)]++
+	}
+	var names []string
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(ew, "  %v: %v\n", name, counts[name])
+	}
+
+	for i, p := range l.Primitives {
+		if ew.err != nil {
+			break
+		}
+		fmt.Fprintf(ew, "  #%v: %v\n", i, p)
+	}
+	return ew.err
+}
+
+// primitiveTypeName returns the registered name of the primitive's
+// type (e.g. "circle") or its Go type if it is not registered.
+func primitiveTypeName(p Primitive) string {
+	if name, ok := primitiveNames[reflect.TypeOf(p)]; ok {
+		return name
+	}
+	return fmt.Sprintf("%T", p)
+}
+
+func (a *Aperture) String() string {
+	if a.Macro != nil {
+		return fmt.Sprintf("Aperture(%v%v)", a.Macro.Name, fmtParams(a.Params))
+	}
+	if h := a.height(); h != a.Size {
+		return fmt.Sprintf("Aperture(%v, %vx%v)", a.Shape, fmtFloat(a.Size), fmtFloat(h))
+	}
+	return fmt.Sprintf("Aperture(%v, %v)", a.Shape, fmtFloat(a.Size))
+}
+
+func (a *ArcT) String() string {
+	return fmt.Sprintf("Arc(%v, r=%v, %v..%v deg, %v, %v)", fmtPt(a.Center), fmtFloat(a.Radius),
+		fmtFloat(a.StartAngle*180/math.Pi), fmtFloat(a.EndAngle*180/math.Pi), a.Shape, fmtFloat(a.Thickness))
+}
+
+func (c *CircleT) String() string {
+	return fmt.Sprintf("Circle(%v, %v)", fmtPt(c.pt), fmtFloat(c.thickness))
+}
+
+func (l *LineT) String() string {
+	return fmt.Sprintf("Line(%v-%v, %v, %v)", fmtPt(l.P1), fmtPt(l.P2), l.Shape, fmtFloat(l.Thickness))
+}
+
+func (p *PolygonT) String() string {
+	return fmt.Sprintf("Polygon(%v, %v points)", fmtPt(p.Offset), len(p.Points))
+}
+
+func (m *MaskWindowT) String() string {
+	if m.Purpose != "" {
+		return fmt.Sprintf("MaskWindow(%q, %v points)", m.Purpose, len(m.Region))
+	}
+	return fmt.Sprintf("MaskWindow(%v points)", len(m.Region))
+}
+
+func (t *TextT) String() string {
+	if err := t.Err(); err != nil {
+		return fmt.Sprintf("Text(%q, %v, %v, error: %v)", t.message, t.fontName, fmtPt(Pt{t.x, t.y}), err)
+	}
+	return fmt.Sprintf("Text(%q, %v, %v)", t.message, t.fontName, fmtPt(Pt{t.x, t.y}))
+}
+
+// fmtFloat formats a value in mm, rounded to the nearest nanometer.
+func fmtFloat(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1e6)/1e6, 'f', -1, 64)
+}
+
+func fmtPt(pt Pt) string {
+	return fmt.Sprintf("(%v,%v)", fmtFloat(pt[0]), fmtFloat(pt[1]))
+}
+
+func fmtMBB(mbb MBB) string {
+	return fmt.Sprintf("%v-%v", fmtPt(mbb.Min), fmtPt(mbb.Max))
+}
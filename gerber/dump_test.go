@@ -0,0 +1,46 @@
+package gerber
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestGerber_Dump(t *testing.T) {
+	g := New("test")
+	top := g.TopCopper()
+	top.Add(
+		Circle(Pt{1, 1}, 0.5),
+		Line(0, 0, 10, 0.1, RectShape, 0.2),
+		Polygon(Pt{0, 0}, true, []Pt{{0, 0}, {1, 0}, {1, 1}}, 0),
+	)
+	g.TopSilkscreen()
+
+	var buf bytes.Buffer
+	if err := g.Dump(&buf); err != nil {
+		t.Fatalf("Dump: %v", err)
+	}
+	want := `Gerber "test": 2 layers, 3 primitives, MBB (-0.1,-0.1)-(10.1,1.25)
+TopCopper layer "test.gtl": 3 primitives, 2 apertures, MBB (-0.1,-0.1)-(10.1,1.25)
+  D11: default
+  D12: Aperture(C, 0.5)
+  D13: Aperture(R, 0.2)
+  circle: 1
+  line: 1
+  polygon: 1
+  #0: Circle((1,1), 0.5)
+  #1: Line((0,0)-(10,0.1), R, 0.2)
+  #2: Polygon((0,0), 3 points)
+TopSilkscreen layer "test.gto": 0 primitives, 0 apertures, MBB empty
+  D11: default
+`
+	if got := buf.String(); got != want {
+		t.Errorf("Dump =\n%v\nwant:\n%v", got, want)
+	}
+
+	// Write errors are returned, whichever line fails.
+	for _, n := range []int{10, 80, 150} {
+		if err := g.Dump(&failingWriter{n: n}); err != errDiskFull {
+			t.Errorf("Dump to a writer failing after %v bytes = %v, want %v", n, err, errDiskFull)
+		}
+	}
+}
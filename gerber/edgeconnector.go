@@ -0,0 +1,96 @@
+package gerber
+
+import (
+	"errors"
+	"fmt"
+)
+
+// edgeNotePts is the size of the fab drawing callouts of an
+// EdgeConnector.
+const edgeNotePts = 10
+
+// EdgeConnector represents a card-edge connector: a row of gold-plated
+// fingers running up from the bottom edge of the board, on both
+// sides. All dimensions are in millimeters.
+type EdgeConnector struct {
+	// At is the center of the bottom of the first (leftmost) finger,
+	// which lies on the board edge.
+	At Pt
+	// Count is the number of fingers, Pitch their center-to-center
+	// spacing, and Width and Length their size.
+	Count                int
+	Pitch, Width, Length float64
+	// BevelAngle (in degrees, e.g. 30 or 45) and BevelDepth specify
+	// the chamfer of the connector edge. A zero BevelAngle means the
+	// edge is not beveled.
+	BevelAngle, BevelDepth float64
+	// GoldThickness, if positive, is the minimum thickness of the hard
+	// gold plating in micrometers (e.g. 0.76 for 30 microinches).
+	GoldThickness float64
+}
+
+func (e EdgeConnector) validate() error {
+	if e.Count < 1 || e.Width <= 0 || e.Length <= 0 || (e.Count > 1 && e.Pitch <= e.Width) {
+		return errors.New("invalid finger count or size")
+	}
+	if e.BevelAngle < 0 || e.BevelAngle >= 90 || (e.BevelAngle > 0 && e.BevelDepth <= 0) {
+		return errors.New("invalid bevel")
+	}
+	return nil
+}
+
+// Fingers returns the filled rectangles of the fingers.
+func (e EdgeConnector) Fingers() Group {
+	hw := 0.5 * e.Width
+	pts := []Pt{{-hw, 0}, {hw, 0}, {hw, e.Length}, {-hw, e.Length}}
+	var g Group
+	for i := 0; i < e.Count; i++ {
+		g = append(g, Polygon(Pt{e.At[0] + float64(i)*e.Pitch, e.At[1]}, true, pts, 0))
+	}
+	return g
+}
+
+// Notes returns the standard fab drawing callouts of the connector:
+// its size, its bevel, and its selective gold plating.
+func (e EdgeConnector) Notes() []string {
+	notes := []string{fmt.Sprintf("EDGE CONNECTOR: %v FINGERS AT %vMM PITCH, %vMM X %vMM",
+		e.Count, fmtFloat(e.Pitch), fmtFloat(e.Width), fmtFloat(e.Length))}
+	if e.BevelAngle > 0 {
+		notes = append(notes, fmt.Sprintf("BEVEL CONNECTOR EDGE %v DEG X %vMM DEEP, BOTH SIDES",
+			fmtFloat(e.BevelAngle), fmtFloat(e.BevelDepth)))
+	}
+	plating := "SELECTIVE HARD GOLD PLATING ON FINGERS PER ASTM B488"
+	if e.GoldThickness > 0 {
+		plating += fmt.Sprintf(", %vUM MIN", fmtFloat(e.GoldThickness))
+	}
+	notes = append(notes, plating, "NO SOLDER MASK OR SILKSCREEN ON FINGERS")
+	return notes
+}
+
+// Callouts returns the connector's notes (see Notes) as lines of text
+// in the provided font, with the top left of the first line at tl.
+func (e EdgeConnector) Callouts(tl Pt, fontName string) Group {
+	var g Group
+	for i, note := range e.Notes() {
+		y := tl[1] - 1.5*float64(i)*edgeNotePts*mmPerPt
+		g = append(g, Text(tl[0], y, 1, note, fontName, edgeNotePts, &TopLeft))
+	}
+	return g
+}
+
+// AddTo adds the fingers, with solder mask openings, to the design's
+// top and bottom copper layers (which are added if necessary), and, if
+// fab is not nil, adds the connector's callouts (see Callouts) to the
+// fab drawing layer with their top left at notes.
+func (e EdgeConnector) AddTo(g *Gerber, fab *Layer, notes Pt, fontName string) error {
+	if err := e.validate(); err != nil {
+		return err
+	}
+	for _, t := range []LayerType{TopCopperLayer, BottomCopperLayer} {
+		g.firstLayerOfType(t).AddWithOpenings(Openings{Mask: true}, e.Fingers()...)
+	}
+	if fab != nil {
+		fab.Add(e.Callouts(notes, fontName)...)
+	}
+	return nil
+}
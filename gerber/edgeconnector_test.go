@@ -0,0 +1,68 @@
+package gerber
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEdgeConnector_Notes(t *testing.T) {
+	tests := []struct {
+		name string
+		e    EdgeConnector
+		want []string
+	}{
+		{
+			name: "no bevel",
+			e:    EdgeConnector{Count: 10, Pitch: 2.54, Width: 1.5, Length: 6},
+			want: []string{
+				"EDGE CONNECTOR: 10 FINGERS AT 2.54MM PITCH, 1.5MM X 6MM",
+				"SELECTIVE HARD GOLD PLATING ON FINGERS PER ASTM B488",
+				"NO SOLDER MASK OR SILKSCREEN ON FINGERS",
+			},
+		},
+		{
+			name: "bevel and gold thickness",
+			e:    EdgeConnector{Count: 2, Pitch: 1, Width: 0.7, Length: 4, BevelAngle: 30, BevelDepth: 0.5, GoldThickness: 0.76},
+			want: []string{
+				"EDGE CONNECTOR: 2 FINGERS AT 1MM PITCH, 0.7MM X 4MM",
+				"BEVEL CONNECTOR EDGE 30 DEG X 0.5MM DEEP, BOTH SIDES",
+				"SELECTIVE HARD GOLD PLATING ON FINGERS PER ASTM B488, 0.76UM MIN",
+				"NO SOLDER MASK OR SILKSCREEN ON FINGERS",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.e.Notes(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Notes = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEdgeConnector_AddTo(t *testing.T) {
+	e := EdgeConnector{At: Pt{5, 0}, Count: 3, Pitch: 2, Width: 1, Length: 4, BevelAngle: 45, BevelDepth: 0.5}
+	g := New("test")
+	fab := New("fab").TopSilkscreen()
+	if err := e.AddTo(g, fab, Pt{0, 20}, "freeserif"); err != nil {
+		t.Fatalf("AddTo: %v", err)
+	}
+	for _, typ := range []LayerType{TopCopperLayer, BottomCopperLayer} {
+		layer := g.firstLayerOfType(typ)
+		if got, want := layer.MBB(), (MBB{Min: Pt{4.5, 0}, Max: Pt{9.5, 4}}); !mbbNear(got, want, 1e-9) {
+			t.Errorf("%v MBB = %v, want %v", typ, got, want)
+		}
+		if _, ok := layer.Openings(layer.Primitives[0]); !ok {
+			t.Errorf("%v fingers have no openings", typ)
+		}
+	}
+	if got, want := len(fab.Primitives), len(e.Notes()); got != want {
+		t.Errorf("fab drawing has %v callouts, want %v", got, want)
+	}
+
+	bad := e
+	bad.BevelDepth = 0
+	if err := bad.AddTo(New("test"), nil, Pt{}, ""); err == nil {
+		t.Errorf("AddTo with a zero bevel depth = nil, want error")
+	}
+}
@@ -0,0 +1,138 @@
+package gerber
+
+// Remove removes all primitives for which filter returns true
+// and returns the number of primitives removed.
+// Apertures (and tags and attributes) no longer used by the layer are
+// removed as well.
+func (l *Layer) Remove(filter func(p Primitive) bool) int {
+	return l.Replace(func(p Primitive) ([]Primitive, bool) {
+		return nil, filter(p)
+	})
+}
+
+// Replace calls fn for each primitive in the layer. When fn returns
+// ok == true, the primitive is replaced by the returned replacements
+// (which may be empty, to remove the primitive, or contain several
+// primitives), which inherit its tags, attributes, and declared
+// openings.
+// Replace returns the number of primitives replaced.
+func (l *Layer) Replace(fn func(p Primitive) (replacements []Primitive, ok bool)) int {
+	var n int
+	result := make([]Primitive, 0, len(l.Primitives))
+	tags := map[Primitive][]string{}
+	attrs := map[Primitive]Attributes{}
+	openings := map[Primitive]Openings{}
+	derived := map[Primitive]bool{}
+	for _, p := range l.Primitives {
+		replacements, ok := fn(p)
+		if !ok {
+			replacements = []Primitive{p}
+			if l.derived[p] {
+				derived[p] = true
+			}
+		} else {
+			n++
+		}
+		result = append(result, replacements...)
+		// Replacements inherit the tags, attributes, and openings of the
+		// replaced primitive.
+		t, hasTags := l.tags[p]
+		a, hasAttrs := l.attrs[p]
+		o, hasOpenings := l.openings[p]
+		for _, r := range replacements {
+			if hasTags {
+				tags[r] = append(tags[r], t...)
+			}
+			if hasAttrs {
+				attrs[r] = a
+			}
+			if hasOpenings {
+				openings[r] = o
+			}
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	l.setPrimitives(result)
+	l.tags, l.attrs, l.openings, l.derived = tags, attrs, openings, derived
+	return n
+}
+
+// setPrimitives replaces all primitives in the layer, rebuilding
+// its apertures and invalidating its cached MBB.
+func (l *Layer) setPrimitives(primitives []Primitive) {
+	l.Primitives = nil
+	l.Apertures = nil
+	l.apertureMap = map[string]int{"default": -1}
+	l.mergedApertures = 0
+	l.invalidateMBB()
+	l.Add(primitives...)
+}
+
+// InvalidateBounds discards the cached MBBs of the layer's primitives,
+// of the layer, and of its design, and the layer's spatial index (see
+// Query). Add and the editing methods (such as Remove) keep these up
+// to date, so InvalidateBounds is only needed after primitives of the
+// layer are modified in place (e.g. by moving the end of a LineT).
+func (l *Layer) InvalidateBounds() {
+	for _, p := range l.Primitives {
+		invalidateBounds(p)
+	}
+	l.invalidateMBB()
+}
+
+// InvalidateBounds calls InvalidateBounds for each layer of the design.
+func (g *Gerber) InvalidateBounds() {
+	for _, layer := range g.Layers {
+		layer.InvalidateBounds()
+	}
+	g.mu.Lock()
+	g.mbb = nil
+	g.mu.Unlock()
+}
+
+// boundsCacher is implemented by primitives that cache their MBBs.
+type boundsCacher interface {
+	invalidateBounds()
+}
+
+// invalidateBounds discards the cached MBB of p and of its children.
+func invalidateBounds(p Primitive) {
+	if c, ok := p.(compound); ok {
+		for _, child := range c.children() {
+			invalidateBounds(child)
+		}
+	}
+	if b, ok := p.(boundsCacher); ok {
+		b.invalidateBounds()
+	}
+}
+
+// invalidateMBB clears the cached MBB and spatial index of the layer
+// and the cached MBB of its design.
+func (l *Layer) invalidateMBB() {
+	l.mbb = nil
+	l.index, l.indexed = nil, nil
+	if l.g != nil {
+		l.g.mu.Lock()
+		l.g.mbb = nil
+		l.g.mu.Unlock()
+	}
+}
+
+// RemoveLayer removes the layer from the design and reports
+// whether it was found.
+func (g *Gerber) RemoveLayer(layer *Layer) bool {
+	for i, l := range g.Layers {
+		if l != layer {
+			continue
+		}
+		g.Layers = append(g.Layers[:i], g.Layers[i+1:]...)
+		g.mu.Lock()
+		g.mbb = nil
+		g.mu.Unlock()
+		return true
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package gerber
+
+import (
+	"testing"
+)
+
+func TestLayer_Remove(t *testing.T) {
+	g := New("test")
+	top := g.TopCopper()
+	top.Add(Circle(Pt{0, 0}, 1), Line(0, 0, 10, 0, RectShape, 0.2), Circle(Pt{1, 1}, 1))
+	top.MBB() // populate the cache
+	g.MBB()
+
+	isLine := func(p Primitive) bool { _, ok := p.(*LineT); return ok }
+	if got := top.Remove(isLine); got != 1 {
+		t.Errorf("Remove = %v, want 1", got)
+	}
+	if got := len(top.Primitives); got != 2 {
+		t.Errorf("len(Primitives) = %v, want 2", got)
+	}
+	if got := len(top.Apertures); got != 1 {
+		t.Errorf("len(Apertures) = %v, want 1", got)
+	}
+	if got, want := g.MBB(), (MBB{Min: Pt{-0.5, -0.5}, Max: Pt{1.5, 1.5}}); got != want {
+		t.Errorf("MBB = %v, want %v", got, want)
+	}
+}
+
+func TestLayer_Replace(t *testing.T) {
+	g := New("test")
+	top := g.TopCopper()
+	top.Add(Circle(Pt{0, 0}, 1), Circle(Pt{5, 0}, 1))
+
+	got := top.Replace(func(p Primitive) ([]Primitive, bool) {
+		c, ok := p.(*CircleT)
+		if !ok || c.pt[0] != 5 {
+			return nil, false
+		}
+		return []Primitive{Circle(c.pt, 2), Line(0, 0, 5, 0, CircleShape, 0.1)}, true
+	})
+	if got != 1 {
+		t.Errorf("Replace = %v, want 1", got)
+	}
+	if got := len(top.Primitives); got != 3 {
+		t.Errorf("len(Primitives) = %v, want 3", got)
+	}
+	if got := len(top.Apertures); got != 3 {
+		t.Errorf("len(Apertures) = %v, want 3", got)
+	}
+}
+
+func TestGerber_RemoveLayer(t *testing.T) {
+	g := New("test")
+	top := g.TopCopper()
+	bottom := g.BottomCopper()
+	if !g.RemoveLayer(top) {
+		t.Errorf("RemoveLayer(top) = false, want true")
+	}
+	if g.RemoveLayer(top) {
+		t.Errorf("second RemoveLayer(top) = true, want false")
+	}
+	if len(g.Layers) != 1 || g.Layers[0] != bottom {
+		t.Errorf("Layers = %v, want [bottom]", g.Layers)
+	}
+}
+
+func TestLayer_AddExtendsMBB(t *testing.T) {
+	const eps = 1e-9
+	g := New("test")
+	top := g.TopCopper()
+	if got := top.MBB(); got != (MBB{}) {
+		t.Fatalf("empty MBB = %v", got)
+	}
+	top.Add(Circle(Pt{10, 10}, 2))
+	if got, want := top.MBB(), (MBB{Min: Pt{9, 9}, Max: Pt{11, 11}}); !mbbNear(got, want, eps) {
+		t.Errorf("MBB after the first Add = %v, want %v", got, want)
+	}
+	g.MBB()
+	top.Add(Line(0, 0, 5, 0, CircleShape, 1))
+	g.BottomCopper().Add(Circle(Pt{20, 0}, 2))
+	if got, want := top.MBB(), (MBB{Min: Pt{-0.5, -0.5}, Max: Pt{11, 11}}); !mbbNear(got, want, eps) {
+		t.Errorf("layer MBB = %v, want %v", got, want)
+	}
+	if got, want := g.MBB(), (MBB{Min: Pt{-0.5, -1}, Max: Pt{21, 11}}); !mbbNear(got, want, eps) {
+		t.Errorf("design MBB = %v, want %v", got, want)
+	}
+}
+
+func TestLayer_InvalidateBounds(t *testing.T) {
+	const eps = 1e-9
+	g := New("test")
+	top := g.TopCopper()
+	line := Line(0, 0, 5, 0, CircleShape, 1)
+	cleared := Line(0, 0, 1, 0, CircleShape, 1)
+	region := Region(Pt{0, 0}, LineTo(Pt{1, 0}), LineTo(Pt{1, 1}))
+	array := StepRepeat(2, 1, 3, 0, Circle(Pt{0, 0}, 1))
+	inner := Line(0, 0, 0, 1, CircleShape, 1)
+	array.Primitives = append(array.Primitives, inner)
+	top.Add(line, Clear(cleared), region, array)
+	top.MBB()
+	g.MBB()
+	if got := len(top.Query(MBB{Min: Pt{7, 0}, Max: Pt{7, 0}})); got != 0 {
+		t.Fatalf("Query = %v primitives, want 0", got)
+	}
+
+	line.P2 = Pt{10, 0}
+	cleared.P2 = Pt{0, -10}
+	region.Segments[1].To = Pt{1, 20}
+	inner.P2 = Pt{-4, 1}
+	if got := top.MBB(); got.Max[0] != 5.5 {
+		t.Fatalf("MBB before InvalidateBounds = %v, want the cached bounds", got)
+	}
+	g.InvalidateBounds()
+	want := MBB{Min: Pt{-4.5, -10.5}, Max: Pt{10.5, 20}}
+	if got := top.MBB(); !mbbNear(got, want, eps) {
+		t.Errorf("layer MBB = %v, want %v", got, want)
+	}
+	if got := g.MBB(); !mbbNear(got, want, eps) {
+		t.Errorf("design MBB = %v, want %v", got, want)
+	}
+	if got := top.Query(MBB{Min: Pt{7, 0}, Max: Pt{7, 0}}); len(got) != 1 || got[0] != line {
+		t.Errorf("Query = %v, want the moved line", got)
+	}
+}
@@ -0,0 +1,221 @@
+package gerber
+
+import (
+	"fmt"
+	"math"
+)
+
+// JobStats represents the metrics of a design that drive its
+// fabrication price. All dimensions are in millimeters.
+type JobStats struct {
+	// Width and Height are the size of the board outline (or of the
+	// whole design if it has no outline).
+	Width, Height float64
+	// Area is the area (in mm²) of the board's bounding box, which is
+	// what fabs typically charge for.
+	Area float64
+	// CopperLayers is the number of non-empty copper layers.
+	CopperLayers int
+	// MinTrace is the narrowest line or arc on a copper layer
+	// (0 if there are none).
+	MinTrace float64
+	// MinSpace is the smallest clearance between unconnected copper
+	// on the same layer (0 if there is no more than one island per
+	// layer).
+	MinSpace float64
+	// MinDrill is the smallest hole or slot width (0 if there are none).
+	MinDrill float64
+	// Holes is the number of holes and slots, and DrillSizes the
+	// number of distinct tool sizes they use.
+	Holes      int
+	DrillSizes int
+	// Primitives is the total number of primitives in the design.
+	Primitives int
+}
+
+// Estimate analyzes the design and returns its price-driving metrics.
+// MinSpace is measured between islands (see Layer.Islands), so copper
+// of the same net that is not connected on a layer also counts.
+func (g *Gerber) Estimate() *JobStats {
+	s := &JobStats{}
+	board := g.boardMBB()
+	s.Width, s.Height = board.Max[0]-board.Min[0], board.Max[1]-board.Min[1]
+	s.Area = s.Width * s.Height
+
+	sizes := map[float64]bool{}
+	for _, layer := range g.Layers {
+		s.Primitives += len(layer.Primitives)
+		switch {
+		case layer.Type == DrillLayer:
+			for _, p := range layer.Primitives {
+				var d float64
+				switch v := p.(type) {
+				case *CircleT:
+					d = v.thickness
+				case *LineT:
+					d = v.Thickness
+				default:
+					continue
+				}
+				s.Holes++
+				sizes[d] = true
+				s.MinDrill = minPositive(s.MinDrill, d)
+			}
+		case layer.Type.IsCopper() && !layer.IsEmpty():
+			s.CopperLayers++
+			for _, p := range layer.Primitives {
+				switch v := p.(type) {
+				case *LineT:
+					s.MinTrace = minPositive(s.MinTrace, v.Thickness)
+				case *ArcT:
+					s.MinTrace = minPositive(s.MinTrace, v.Thickness)
+				}
+			}
+			if space := layer.minSpace(); space > 0 {
+				s.MinSpace = minPositive(s.MinSpace, space)
+			}
+		}
+	}
+	s.DrillSizes = len(sizes)
+	return s
+}
+
+// minSpace returns the smallest clearance between the islands of
+// the layer, or 0 if it has fewer than two islands.
+func (l *Layer) minSpace() float64 {
+	islands := l.Islands(IslandOpts{})
+	type part struct {
+		outline outline
+		mbb     MBB
+	}
+	parts := make([][]part, len(islands))
+	for i, island := range islands {
+		for _, p := range island.Primitives {
+			parts[i] = append(parts[i], part{outline: outlineOf(p), mbb: p.MBB()})
+		}
+	}
+	best := math.Inf(1)
+	for i := range parts {
+		for j := i + 1; j < len(parts); j++ {
+			for _, a := range parts[i] {
+				for _, b := range parts[j] {
+					if mbbGap(a.mbb, b.mbb) >= best {
+						continue
+					}
+					best = math.Min(best, a.outline.distance(b.outline))
+				}
+			}
+		}
+	}
+	if math.IsInf(best, 1) {
+		return 0
+	}
+	return best
+}
+
+// mbbGap returns the distance between two MBBs (0 if they intersect).
+func mbbGap(a, b MBB) float64 {
+	dx := math.Max(0, math.Max(a.Min[0]-b.Max[0], b.Min[0]-a.Max[0]))
+	dy := math.Max(0, math.Max(a.Min[1]-b.Max[1], b.Min[1]-a.Max[1]))
+	return math.Hypot(dx, dy)
+}
+
+func minPositive(current, v float64) float64 {
+	if current == 0 || v < current {
+		return v
+	}
+	return current
+}
+
+func (s *JobStats) String() string {
+	return fmt.Sprintf("%vx%vmm (%vmm²), %v copper layers, min trace %vmm, min space %vmm, min drill %vmm, %v holes (%v sizes)",
+		fmtFloat(s.Width), fmtFloat(s.Height), fmtFloat(s.Area), s.CopperLayers,
+		fmtFloat(s.MinTrace), fmtFloat(s.MinSpace), fmtFloat(s.MinDrill), s.Holes, s.DrillSizes)
+}
+
+// CostTier represents the rough price tier of a design at a fab.
+type CostTier int
+
+const (
+	// TierStandard is the fab's standard (cheapest) service.
+	TierStandard CostTier = iota
+	// TierAdvanced requires the fab's more expensive advanced service.
+	TierAdvanced
+	// TierUnsupported exceeds the fab's capabilities.
+	TierUnsupported
+)
+
+func (t CostTier) String() string {
+	switch t {
+	case TierStandard:
+		return "standard"
+	case TierAdvanced:
+		return "advanced"
+	}
+	return "unsupported"
+}
+
+// FabLimits represents the limits of a fab service. All dimensions
+// are in millimeters.
+type FabLimits struct {
+	MinTrace, MinSpace, MinDrill float64
+	MaxCopperLayers              int
+}
+
+// check returns the reasons (if any) the design exceeds the limits.
+func (f FabLimits) check(s *JobStats) []string {
+	var reasons []string
+	if s.CopperLayers > f.MaxCopperLayers {
+		reasons = append(reasons, fmt.Sprintf("%v copper layers > %v", s.CopperLayers, f.MaxCopperLayers))
+	}
+	if s.MinTrace > 0 && s.MinTrace < f.MinTrace {
+		reasons = append(reasons, fmt.Sprintf("min trace %vmm < %vmm", fmtFloat(s.MinTrace), fmtFloat(f.MinTrace)))
+	}
+	if s.MinSpace > 0 && s.MinSpace < f.MinSpace {
+		reasons = append(reasons, fmt.Sprintf("min space %vmm < %vmm", fmtFloat(s.MinSpace), fmtFloat(f.MinSpace)))
+	}
+	if s.MinDrill > 0 && s.MinDrill < f.MinDrill {
+		reasons = append(reasons, fmt.Sprintf("min drill %vmm < %vmm", fmtFloat(s.MinDrill), fmtFloat(f.MinDrill)))
+	}
+	return reasons
+}
+
+// Fab represents the (approximate) capabilities of a PCB fab.
+type Fab struct {
+	Name     string
+	Standard FabLimits
+	Advanced FabLimits
+}
+
+// Rough capabilities of common fabs. These change over time;
+// consult the fab before relying on them.
+var (
+	OSHPark = Fab{
+		Name:     "OSH Park",
+		Standard: FabLimits{MinTrace: 0.1524, MinSpace: 0.1524, MinDrill: 0.254, MaxCopperLayers: 2},
+		Advanced: FabLimits{MinTrace: 0.127, MinSpace: 0.127, MinDrill: 0.254, MaxCopperLayers: 6},
+	}
+	JLCPCB = Fab{
+		Name:     "JLCPCB",
+		Standard: FabLimits{MinTrace: 0.127, MinSpace: 0.127, MinDrill: 0.3, MaxCopperLayers: 2},
+		Advanced: FabLimits{MinTrace: 0.09, MinSpace: 0.09, MinDrill: 0.15, MaxCopperLayers: 20},
+	}
+	PCBWay = Fab{
+		Name:     "PCBWay",
+		Standard: FabLimits{MinTrace: 0.127, MinSpace: 0.127, MinDrill: 0.3, MaxCopperLayers: 2},
+		Advanced: FabLimits{MinTrace: 0.075, MinSpace: 0.075, MinDrill: 0.15, MaxCopperLayers: 14},
+	}
+)
+
+// Tier returns the cost tier of the design at the fab, along with
+// the reasons it does not fit the fab's standard service.
+func (s *JobStats) Tier(fab Fab) (CostTier, []string) {
+	reasons := fab.Standard.check(s)
+	if len(reasons) == 0 {
+		return TierStandard, nil
+	}
+	if advanced := fab.Advanced.check(s); len(advanced) > 0 {
+		return TierUnsupported, advanced
+	}
+	return TierAdvanced, reasons
+}
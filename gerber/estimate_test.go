@@ -0,0 +1,55 @@
+package gerber
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGerber_Estimate(t *testing.T) {
+	const eps = 1e-9
+	g := New("test")
+	g.Outline().Add(OutlinePath(RoundedRect(MBB{Max: Pt{20, 10}}, 0), 0)...)
+	top := g.TopCopper()
+	top.Add(
+		Line(1, 1, 9, 1, CircleShape, 0.2),
+		Line(1, 1.3, 9, 1.3, CircleShape, 0.2), // 0.1mm clearance
+		Circle(Pt{15, 5}, 2),
+	)
+	g.BottomCopper().Add(Line(1, 5, 19, 5, CircleShape, 0.5))
+	g.Drill().Add(Circle(Pt{15, 5}, 0.3), Circle(Pt{5, 8}, 0.3), Circle(Pt{6, 8}, 0.2))
+
+	s := g.Estimate()
+	near := func(name string, got, want float64) {
+		if math.Abs(got-want) > eps {
+			t.Errorf("%v = %v, want %v", name, got, want)
+		}
+	}
+	near("Area", s.Area, 200)
+	near("MinTrace", s.MinTrace, 0.2)
+	near("MinSpace", s.MinSpace, 0.1)
+	near("MinDrill", s.MinDrill, 0.2)
+	if s.CopperLayers != 2 || s.Holes != 3 || s.DrillSizes != 2 || s.Primitives != 11 {
+		t.Errorf("Estimate = %v, want 2 copper layers, 3 holes (2 sizes), 11 primitives", s)
+	}
+
+	tests := []struct {
+		name string
+		fab  Fab
+		want CostTier
+	}{
+		{name: "OSH Park", fab: OSHPark, want: TierUnsupported},
+		{name: "JLCPCB", fab: JLCPCB, want: TierAdvanced},
+		{name: "PCBWay", fab: PCBWay, want: TierAdvanced},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, reasons := s.Tier(tt.fab)
+			if got != tt.want {
+				t.Errorf("Tier = %v, want %v", got, tt.want)
+			}
+			if len(reasons) == 0 {
+				t.Errorf("Tier reasons = %v, want non-empty", reasons)
+			}
+		})
+	}
+}
@@ -0,0 +1,437 @@
+package gerber
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WithExcellonDrills enables or disables writing the drill layers as
+// Excellon drill files (see Layer.WriteExcellon), as most fabs expect,
+// rather than as Gerber files, under the same filenames. As Excellon
+// files cannot mix plated and non-plated holes, the non-plated holes
+// of each drill layer are then written to a separate file as with
+// WithSplitDrills.
+func WithExcellonDrills(enabled bool) Option {
+	return func(g *Gerber) {
+		g.excellonDrills = enabled
+	}
+}
+
+// writeOutput writes an output layer (see outputLayers) to its file:
+// as an Excellon drill file for drill layers with WithExcellonDrills,
+// and as a Gerber file otherwise.
+func (l *Layer) writeOutput(ctx context.Context, w io.Writer) error {
+	if l.excellonOutput() {
+		return l.WriteExcellonContext(ctx, w)
+	}
+	return l.WriteGerberContext(ctx, w)
+}
+
+// excellonOutput reports whether the layer is written as an Excellon
+// drill file (see WithExcellonDrills).
+func (l *Layer) excellonOutput() bool {
+	return l.Type == DrillLayer && l.g != nil && l.g.excellonDrills
+}
+
+// WriteExcellon writes the plated (or non-plated, those tagged with
+// NonPlatedTag) holes and slots of the design's drill layers as an
+// Excellon drill file (see Layer.WriteExcellon).
+func (g *Gerber) WriteExcellon(w io.Writer, plated bool) error {
+	kind := nonPlatedDrill
+	if plated {
+		kind = platedDrill
+	}
+	return g.writeExcellon(context.Background(), w, kind, g.layersOfType(DrillLayer), func(l *Layer, p Primitive) bool {
+		return l.HasTag(p, NonPlatedTag) != plated
+	})
+}
+
+// WriteExcellon writes the holes and slots of the drill layer as an
+// Excellon drill file, with metric decimal coordinates (the design's
+// output coordinates, see SetExportTransform). Tools are numbered from
+// the smallest, and slots are routed with G85; the holes of each tool
+// are in the order they were added or, with
+// WithDrillOrder(DrillNearestNeighbor), by a nearest neighbor path. The
+// file is for non-plated holes (see ParseExcellon) if all of the
+// layer's holes are tagged with NonPlatedTag. It returns an error if
+// the layer has primitives other than circles and round lines.
+func (l *Layer) WriteExcellon(w io.Writer) error {
+	return l.WriteExcellonContext(context.Background(), w)
+}
+
+// WriteExcellonContext is like WriteExcellon but returns ctx.Err() if
+// ctx is canceled before the holes are written.
+func (l *Layer) WriteExcellonContext(ctx context.Context, w io.Writer) error {
+	kind := l.drillKind
+	if kind == "" {
+		kind = platedDrill
+		if len(l.Primitives) > 0 && len(l.Select(NonPlatedTag)) == len(l.Primitives) {
+			kind = nonPlatedDrill
+		}
+	}
+	g := l.g
+	if g == nil {
+		g = New("")
+	}
+	if err := g.writeExcellon(ctx, w, kind, []*Layer{l}, func(*Layer, Primitive) bool { return true }); err != nil {
+		return err
+	}
+	l.layerProgress(ctx).add(len(l.Primitives))
+	return nil
+}
+
+// writeExcellon writes the holes of the drill layers selected by
+// include as an Excellon drill file for holes of the given kind
+// (platedDrill or nonPlatedDrill).
+func (g *Gerber) writeExcellon(ctx context.Context, w io.Writer, kind string, drills []*Layer, include func(l *Layer, p Primitive) bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	type hit struct {
+		p1, p2 Pt
+		slot   bool
+	}
+	hits := map[float64][]hit{}
+	for _, drill := range drills {
+		for _, p := range drill.Primitives {
+			if !include(drill, p) {
+				continue
+			}
+			switch v := p.(type) {
+			case *CircleT:
+				hits[v.thickness] = append(hits[v.thickness], hit{p1: v.pt})
+			case *LineT:
+				if v.Shape != CircleShape {
+					return fmt.Errorf("%v: slot from %v to %v is not round", drill.Filename, fmtPt(v.P1), fmtPt(v.P2))
+				}
+				hits[v.Thickness] = append(hits[v.Thickness], hit{p1: v.P1, p2: v.P2, slot: v.P1 != v.P2})
+			default:
+				return fmt.Errorf("%v: %T cannot be drilled", drill.Filename, p)
+			}
+		}
+	}
+	var sizes []float64
+	for d := range hits {
+		sizes = append(sizes, d)
+	}
+	sort.Float64s(sizes)
+	if g.drillOrder == DrillNearestNeighbor {
+		at := g.origin
+		for _, d := range sizes {
+			tool := hits[d]
+			starts, ends := make([]Pt, len(tool)), make([]Pt, len(tool))
+			for i, h := range tool {
+				starts[i], ends[i] = h.p1, h.p1
+				if h.slot {
+					ends[i] = h.p2
+				}
+			}
+			var path []int
+			path, at = nearestNeighborPath(at, starts, ends)
+			hits[d] = make([]hit, 0, len(tool))
+			for _, i := range path {
+				hits[d] = append(hits[d], tool[i])
+			}
+		}
+	}
+
+	xy := func(pt Pt) string {
+		pt = g.exportPt(pt)
+		return fmt.Sprintf("X%vY%v", excellonNum(pt[0]), excellonNum(pt[1]))
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "M48\n; %v %v drill file\n; FORMAT={-:-/ absolute / metric / decimal}\nFMAT,2\nMETRIC\n", g.FilenamePrefix, kind)
+	for i, d := range sizes {
+		fmt.Fprintf(&buf, "T%vC%v\n", i+1, gcodeNum(d))
+	}
+	buf.WriteString("%\nG90\nG05\n")
+	for i, d := range sizes {
+		fmt.Fprintf(&buf, "T%v\n", i+1)
+		for _, h := range hits[d] {
+			if h.slot {
+				fmt.Fprintf(&buf, "%vG85%v\n", xy(h.p1), xy(h.p2))
+			} else {
+				fmt.Fprintf(&buf, "%v\n", xy(h.p1))
+			}
+		}
+	}
+	buf.WriteString("T0\nM30\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// excellonNum formats a decimal coordinate, which always has a decimal
+// point so that it is not read as an integer in the file's format.
+func excellonNum(v float64) string {
+	s := gcodeNum(v)
+	if !strings.Contains(s, ".") {
+		s += ".0"
+	}
+	return s
+}
+
+// isExcellon reports whether data is an Excellon drill file: one whose
+// first command (after any comments) starts its header with M48.
+func isExcellon(data []byte) bool {
+	for len(data) > 0 {
+		var line []byte
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			line, data = data[:i], data[i+1:]
+		} else {
+			line, data = data, nil
+		}
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 || line[0] == ';' {
+			continue
+		}
+		return bytes.Equal(line, []byte("M48"))
+	}
+	return false
+}
+
+// excellonParser holds the state while parsing an Excellon file.
+type excellonParser struct {
+	layer *Layer
+	// scale converts the file's units to mm.
+	scale float64
+	// intDigits and decDigits are the digits of coordinates without
+	// a decimal point, and leadingZeros whether such coordinates keep
+	// their leading zeros (LZ) rather than their trailing ones (TZ).
+	intDigits, decDigits int
+	leadingZeros         bool
+	tools                map[int]float64
+	tool                 float64
+	pos                  Pt
+	nonPlated            bool
+}
+
+// ParseExcellon reads an Excellon drill file, such as one written by
+// this package (see Layer.WriteExcellon) or by other CAM tools, into a
+// new drill layer that does not belong to a design. Hits become circles
+// of their tool's diameter and G85 slots become slots (see Slot), in
+// millimeters. Metric and inch units, decimal coordinates, and
+// coordinates with leading (LZ) or trailing (TZ) zeros in the units'
+// default or declared format (e.g. "METRIC,TZ,000.000") are supported;
+// routing (G00-G03) and repeat commands are not. The holes are tagged
+// with NonPlatedTag if the file's comments say it is for non-plated
+// holes (e.g. "; board NPTH drill file" or ";TYPE=NON_PLATED").
+func ParseExcellon(r io.Reader) (*Layer, error) {
+	p := &excellonParser{
+		layer:        &Layer{Type: DrillLayer, apertureMap: map[string]int{"default": -1}},
+		scale:        1,
+		intDigits:    3,
+		decDigits:    3,
+		leadingZeros: true,
+		tools:        map[int]float64{},
+		tool:         -1,
+	}
+	s := bufio.NewScanner(r)
+	header := false
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		var err error
+		switch {
+		case line == "":
+		case line[0] == ';':
+			if c := strings.ToUpper(line); strings.Contains(c, "NPTH") || strings.Contains(c, "NON_PLATED") {
+				p.nonPlated = true
+			}
+		case line == "M48":
+			header = true
+		case header && (line == "%" || line == "M95"):
+			header = false
+		case header:
+			err = p.headerCommand(line)
+		default:
+			err = p.command(line)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %v: %v", n, err)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if p.nonPlated {
+		for _, prim := range p.layer.Primitives {
+			p.layer.Tag(prim, NonPlatedTag)
+		}
+	}
+	return p.layer, nil
+}
+
+// headerCommand parses a command of the header: units, formats, and
+// tool definitions. Other commands (such as FMAT, VER, or ICI) are
+// ignored.
+func (p *excellonParser) headerCommand(line string) error {
+	fields := strings.Split(line, ",")
+	switch fields[0] {
+	case "METRIC", "INCH":
+		p.setUnits(fields[0] == "METRIC")
+		for _, f := range fields[1:] {
+			switch {
+			case f == "LZ":
+				p.leadingZeros = true
+			case f == "TZ":
+				p.leadingZeros = false
+			case strings.Contains(f, "."):
+				i := strings.Index(f, ".")
+				p.intDigits, p.decDigits = i, len(f)-i-1
+			}
+		}
+		return nil
+	}
+	if line[0] != 'T' {
+		return nil
+	}
+	n, rest := leadingInt(line[1:])
+	if n < 0 {
+		return fmt.Errorf("invalid tool %q", line)
+	}
+	i := strings.IndexByte(rest, 'C')
+	if i < 0 {
+		return nil // a tool without a diameter is never used
+	}
+	v, _ := leadingNumber(rest[i+1:])
+	d, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return fmt.Errorf("invalid tool diameter %q", line)
+	}
+	p.tools[n] = p.scale * d
+	return nil
+}
+
+// setUnits sets the units (and their default coordinate format).
+func (p *excellonParser) setUnits(metric bool) {
+	if metric {
+		p.scale, p.intDigits, p.decDigits = 1, 3, 3
+	} else {
+		p.scale, p.intDigits, p.decDigits = 25.4, 2, 4
+	}
+}
+
+// command parses a command of the body: tool changes, hits, and slots.
+func (p *excellonParser) command(line string) error {
+	switch line {
+	case "G90", "G05", "G81", "M30", "M00", "M70", "T0":
+		return nil
+	case "M71":
+		p.scale = 1
+		return nil
+	case "M72":
+		p.scale = 25.4
+		return nil
+	}
+	switch line[0] {
+	case 'T':
+		n, rest := leadingInt(line[1:])
+		d, ok := p.tools[n]
+		if n < 0 || rest != "" || !ok {
+			return fmt.Errorf("undefined tool %q", line)
+		}
+		p.tool = d
+		return nil
+	case 'X', 'Y':
+		if p.tool < 0 {
+			return fmt.Errorf("hit %q before selecting a tool", line)
+		}
+		var p2 string
+		if i := strings.Index(line, "G85"); i >= 0 {
+			line, p2 = line[:i], line[i+3:]
+		}
+		start, err := p.coordinates(line)
+		if err != nil {
+			return err
+		}
+		if p2 == "" {
+			p.layer.Add(Circle(start, p.tool))
+			return nil
+		}
+		end, err := p.coordinates(p2)
+		if err != nil {
+			return err
+		}
+		p.layer.Add(Slot(start, end, p.tool))
+		return nil
+	}
+	return fmt.Errorf("unsupported command %q", line)
+}
+
+// coordinates parses the X and Y coordinates of a hit (each optional,
+// keeping that of the previous hit), and moves to them.
+func (p *excellonParser) coordinates(s string) (Pt, error) {
+	for s != "" {
+		axis := strings.IndexByte("XY", s[0])
+		if axis < 0 {
+			return Pt{}, fmt.Errorf("invalid coordinates %q", s)
+		}
+		var v string
+		v, s = leadingNumber(s[1:])
+		mm, err := p.coordinate(v)
+		if err != nil {
+			return Pt{}, err
+		}
+		p.pos[axis] = mm
+	}
+	return p.pos, nil
+}
+
+// coordinate converts a coordinate of the file to mm.
+func (p *excellonParser) coordinate(v string) (float64, error) {
+	if strings.Contains(v, ".") || v == "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid coordinate %q", v)
+		}
+		return p.scale * f, nil
+	}
+	sign := 1.0
+	digits := v
+	if digits[0] == '-' || digits[0] == '+' {
+		if digits[0] == '-' {
+			sign = -1
+		}
+		digits = digits[1:]
+	}
+	if p.leadingZeros {
+		for len(digits) < p.intDigits+p.decDigits {
+			digits += "0"
+		}
+	}
+	n, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid coordinate %q", v)
+	}
+	return p.scale * sign * float64(n) / math.Pow(10, float64(p.decDigits)), nil
+}
+
+// leadingInt returns the decimal integer at the start of s (-1 if
+// none) and the rest of s.
+func leadingInt(s string) (int, string) {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	n, err := strconv.Atoi(s[:i])
+	if err != nil {
+		return -1, s
+	}
+	return n, s[i:]
+}
+
+// leadingNumber returns the (possibly signed and decimal) number at
+// the start of s and the rest of s.
+func leadingNumber(s string) (string, string) {
+	i := 0
+	for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.' || (i == 0 && (s[i] == '-' || s[i] == '+'))) {
+		i++
+	}
+	return s[:i], s[i:]
+}
@@ -0,0 +1,181 @@
+package gerber
+
+import (
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWithExcellonDrills(t *testing.T) {
+	g := New("board", WithExcellonDrills(true))
+	g.TopCopper().Add(Circle(Pt{1, 2}, 1.6))
+	drill := g.Drill()
+	drill.Add(Circle(Pt{1, 2}, 0.8), Slot(Pt{1, 1}, Pt{3, 1}, 0.8))
+	drill.AddTagged([]string{NonPlatedTag}, Circle(Pt{4, 4}, 3))
+
+	files := map[string]*memFile{}
+	err := g.Write(func(filename string) (io.WriteCloser, error) {
+		f := &memFile{}
+		files[filename] = f
+		return f, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := files["board.gtl"].String(); !strings.HasPrefix(got, "%FS") {
+		t.Errorf("copper layer is not a Gerber file:\n%v", got)
+	}
+	tests := []struct {
+		filename string
+		want     string
+	}{
+		{filename: "board.drl", want: "M48\n; board PTH drill file\n; FORMAT={-:-/ absolute / metric / decimal}\nFMAT,2\nMETRIC\nT1C0.8\n%\nG90\nG05\nT1\nX1.0Y2.0\nX1.0Y1.0G85X3.0Y1.0\nT0\nM30\n"},
+		{filename: "board-NPTH.drl", want: "M48\n; board NPTH drill file\n; FORMAT={-:-/ absolute / metric / decimal}\nFMAT,2\nMETRIC\nT1C3\n%\nG90\nG05\nT1\nX4.0Y4.0\nT0\nM30\n"},
+	}
+	for _, tt := range tests {
+		f, ok := files[tt.filename]
+		if !ok {
+			t.Errorf("Write did not write %v: %v", tt.filename, files)
+			continue
+		}
+		if got := f.String(); got != tt.want {
+			t.Errorf("%v =\n%v\nwant:\n%v", tt.filename, got, tt.want)
+		}
+	}
+
+	// The files read back into the holes that were written.
+	plated, err := Parse(strings.NewReader(files["board.drl"].String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, p := range plated.Primitives {
+		got = append(got, holeString(p))
+	}
+	if want := "1,2 0.8; 1,1-3,1 0.8"; strings.Join(got, "; ") != want {
+		t.Errorf("parsed plated holes = %v, want %v", got, want)
+	}
+	nonPlated, err := Parse(strings.NewReader(files["board-NPTH.drl"].String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := nonPlated.Select(NonPlatedTag); len(got) != 1 || len(nonPlated.Primitives) != 1 {
+		t.Errorf("parsed non-plated holes = %v, tagged %v, want 1 tagged hole", nonPlated.Primitives, got)
+	}
+}
+
+func TestParseExcellon(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		// want is a hole per line: "x,y d" or "x1,y1-x2,y2 d".
+		want      []string
+		nonPlated bool
+	}{
+		{
+			name:      "inch with trailing zeros",
+			data:      "M48\n;TYPE=NON_PLATED\nINCH,TZ\nT01C0.0394\n%\nG05\nT01\nX10000Y-5000\nY2500\nX0G85X20000\nM30\n",
+			want:      []string{"25.4,-12.7 1.00076", "25.4,6.35 1.00076", "0,6.35-50.8,6.35 1.00076"},
+			nonPlated: true,
+		},
+		{
+			name: "metric with leading zeros",
+			data: "; generated elsewhere\nM48\nVER,1\nMETRIC,LZ,000.000\nT1F00S00C0.5\nT2C1.0\n%\nT2\nX0015Y-0025\nT1\nX+01Y001\nM30\n",
+			want: []string{"1.5,-2.5 1", "10,1 0.5"},
+		},
+		{
+			name: "decimal body units",
+			data: "M48\nMETRIC\nT1C0.3\n%\nT1\nX1.5Y2.\nM72\nX1.\nM30\n",
+			want: []string{"1.5,2 0.3", "25.4,2 0.3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			layer, err := ParseExcellon(strings.NewReader(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if layer.Type != DrillLayer {
+				t.Errorf("type = %v, want %v", layer.Type, DrillLayer)
+			}
+			var got []string
+			for _, p := range layer.Primitives {
+				got = append(got, holeString(p))
+				if layer.HasTag(p, NonPlatedTag) != tt.nonPlated {
+					t.Errorf("%v tagged non-plated = %v, want %v", holeString(p), !tt.nonPlated, tt.nonPlated)
+				}
+			}
+			if strings.Join(got, "; ") != strings.Join(tt.want, "; ") {
+				t.Errorf("holes = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// holeString formats a parsed hole for TestParseExcellon.
+func holeString(p Primitive) string {
+	r := func(v float64) float64 { return math.Round(v*1e5) / 1e5 }
+	switch v := p.(type) {
+	case *CircleT:
+		return fmtFloat(r(v.pt[0])) + "," + fmtFloat(r(v.pt[1])) + " " + fmtFloat(r(v.thickness))
+	case *LineT:
+		return fmtFloat(r(v.P1[0])) + "," + fmtFloat(r(v.P1[1])) + "-" + fmtFloat(r(v.P2[0])) + "," + fmtFloat(r(v.P2[1])) + " " + fmtFloat(r(v.Thickness))
+	}
+	return "?"
+}
+
+func TestParseExcellon_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{name: "hit without a tool", data: "M48\nMETRIC\nT1C1\n%\nX1Y1\n", want: "line 5: hit"},
+		{name: "undefined tool", data: "M48\nMETRIC\nT1C1\n%\nT2\n", want: "line 5: undefined tool"},
+		{name: "routing", data: "M48\nMETRIC\nT1C1\n%\nT1\nG00X1Y1\n", want: "line 6: unsupported command"},
+		{name: "invalid coordinate", data: "M48\nMETRIC\nT1C1\n%\nT1\nX1.2.3\n", want: "line 6: invalid coordinate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseExcellon(strings.NewReader(tt.data)); err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+				t.Errorf("ParseExcellon = %v, want %v...", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDesign_Excellon(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-gerber")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	g := New(filepath.Join(dir, "board"), WithExcellonDrills(true))
+	g.TopCopper().Add(Circle(Pt{1, 1}, 1))
+	drill := g.Drill()
+	drill.Add(Circle(Pt{1, 1}, 0.4))
+	drill.AddTagged([]string{NonPlatedTag}, Circle(Pt{4, 4}, 3))
+	if err := g.WriteToDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ParseDesign(filepath.Join(dir, "board.gtl"), filepath.Join(dir, "board.drl"), filepath.Join(dir, "board-NPTH.drl"))
+	if err != nil {
+		t.Fatalf("ParseDesign: %v", err)
+	}
+	drills := got.layersOfType(DrillLayer)
+	if len(drills) != 2 {
+		t.Fatalf("ParseDesign = %v drill layers, want 2", len(drills))
+	}
+	if n := len(drills[0].Select(NonPlatedTag)); n != 0 || len(drills[0].Primitives) != 1 {
+		t.Errorf("plated drill layer = %v holes, %v non-plated", len(drills[0].Primitives), n)
+	}
+	if n := len(drills[1].Select(NonPlatedTag)); n != 1 || len(drills[1].Primitives) != 1 {
+		t.Errorf("non-plated drill layer = %v holes, %v non-plated", len(drills[1].Primitives), n)
+	}
+}
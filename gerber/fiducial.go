@@ -0,0 +1,59 @@
+package gerber
+
+// FiducialSize is the diameter (in mm) of the copper dots of the
+// fiducial marks added by AddFiducial. Their solder mask openings are
+// twice as large, and their copper keepouts three times.
+const FiducialSize = 1.0
+
+// toolingHoleClearance is the distance (in mm) from the tooling holes
+// added by AddToolingHole to the copper kept clear around them.
+const toolingHoleClearance = 0.5
+
+// ToolingHoleTag is the tag of the holes added by AddToolingHole.
+const ToolingHoleTag = "tooling-hole"
+
+// AddFiducial adds a fiducial mark (for the optical alignment of
+// assembly machines) at (x,y): a copper dot of diameter FiducialSize
+// tagged with FiducialTag, with a solder mask opening (see Openings)
+// and a keepout of its copper layer (see AddKeepout) around it, so that
+// pours and other generators leave it clear. The mark is added to the
+// top copper layer (which is added if necessary) and, if the design has
+// one, to the bottom copper layer.
+func (g *Gerber) AddFiducial(x, y float64) {
+	pt, d := Pt{x, y}, FiducialSize
+	layers := []*Layer{g.firstLayerOfType(TopCopperLayer)}
+	if bottom := g.layersOfType(BottomCopperLayer); len(bottom) > 0 {
+		layers = append(layers, bottom[0])
+	}
+	for _, layer := range layers {
+		dot := Circle(pt, d)
+		layer.AddWithOpenings(Openings{Mask: true, MaskExpansion: 0.5 * d}, dot)
+		layer.Tag(dot, FiducialTag)
+		g.AddKeepout(Keepout{Region: circlePolygon(pt, 1.5*d), Layers: []LayerType{layer.Type}})
+	}
+}
+
+// AddToolingHole adds a non-plated tooling hole of diameter d at (x,y)
+// (for the pins of fixtures and panels) to the design's first drill
+// layer (adding one if necessary), tagged with NonPlatedTag and
+// ToolingHoleTag, with a keepout of the copper layers around it (see
+// AddKeepout). It returns the hole.
+func (g *Gerber) AddToolingHole(x, y, d float64) *CircleT {
+	hole := Circle(Pt{x, y}, d)
+	g.firstLayerOfType(DrillLayer).AddTagged([]string{NonPlatedTag, ToolingHoleTag}, hole)
+	g.AddKeepout(Keepout{
+		Region: circlePolygon(Pt{x, y}, 0.5*d+toolingHoleClearance),
+		Layers: []LayerType{TopCopperLayer, InnerCopperLayer, BottomCopperLayer},
+	})
+	return hole
+}
+
+// circlePolygon returns the polygon of hullCircleSegments vertices
+// approximating the circle of radius r centered on center.
+func circlePolygon(center Pt, r float64) []Pt {
+	var pts []Pt
+	for i := 0; i < hullCircleSegments; i++ {
+		pts = append(pts, PolarFrom(center, r, 360*float64(i)/hullCircleSegments))
+	}
+	return pts
+}
@@ -0,0 +1,61 @@
+package gerber
+
+import "testing"
+
+func TestGerber_AddFiducial(t *testing.T) {
+	g := New("board")
+	top, bottom := g.TopCopper(), g.BottomCopper()
+	g.AddFiducial(5, 5)
+	if err := g.DeriveOpenings(); err != nil {
+		t.Fatal(err)
+	}
+	for _, layer := range []*Layer{top, bottom} {
+		if len(layer.Primitives) != 1 || !layer.HasTag(layer.Primitives[0], FiducialTag) {
+			t.Fatalf("%v = %v, want a fiducial", layer.Type, layer.Primitives)
+		}
+		if got, want := layer.MBB(), (MBB{Min: Pt{4.5, 4.5}, Max: Pt{5.5, 5.5}}); !mbbNear(got, want, 1e-12) {
+			t.Errorf("%v MBB = %v, want %v", layer.Type, got, want)
+		}
+		if k := g.Keepouts(layer.Type); len(k) != 1 || len(k[0].Region) != hullCircleSegments {
+			t.Errorf("%v keepouts = %v, want one", layer.Type, k)
+		}
+	}
+	for _, mt := range []LayerType{TopSolderMaskLayer, BottomSolderMaskLayer} {
+		masks := g.layersOfType(mt)
+		if len(masks) != 1 {
+			t.Fatalf("design has %v %v layers, want 1", len(masks), mt)
+		}
+		if got, want := masks[0].MBB(), (MBB{Min: Pt{4, 4}, Max: Pt{6, 6}}); !mbbNear(got, want, 1e-12) {
+			t.Errorf("%v MBB = %v, want %v", mt, got, want)
+		}
+	}
+
+	// Pours leave the fiducial clear.
+	pour := top.Pour(RoundedRect(MBB{Max: Pt{10, 10}}, 0), nil)
+	if len(pour) != 1 || PrimitiveContains(pour[0], Pt{5, 6.2}) || !PrimitiveContains(pour[0], Pt{5, 6.6}) {
+		t.Errorf("pour = %v, want a clearance around the fiducial", pour)
+	}
+}
+
+func TestGerber_AddToolingHole(t *testing.T) {
+	g := New("board")
+	hole := g.AddToolingHole(3, 3, 3.2)
+	drill := g.layersOfType(DrillLayer)[0]
+	if len(drill.Primitives) != 1 || drill.Primitives[0] != hole {
+		t.Fatalf("drill layer = %v, want the tooling hole", drill.Primitives)
+	}
+	if !drill.HasTag(hole, NonPlatedTag) || !drill.HasTag(hole, ToolingHoleTag) {
+		t.Error("tooling hole is not tagged")
+	}
+	if got := g.DrillCounts(); len(got) != 1 || got[0].Plated {
+		t.Errorf("DrillCounts = %v, want one non-plated hole", got)
+	}
+	for _, lt := range []LayerType{TopCopperLayer, InnerCopperLayer, BottomCopperLayer} {
+		if k := g.Keepouts(lt); len(k) != 1 {
+			t.Errorf("%v keepouts = %v, want one", lt, k)
+		}
+	}
+	if k := g.Keepouts(TopSilkscreenLayer); len(k) != 0 {
+		t.Errorf("silkscreen keepouts = %v, want none", k)
+	}
+}
@@ -0,0 +1,69 @@
+package gerber
+
+import "fmt"
+
+// Fix is an automatic correction offered by a check for an Issue (such
+// as enlarging a pad), which ApplyFixes applies to the design.
+type Fix struct {
+	// Description describes the correction.
+	Description string
+	apply       func() error
+}
+
+// ApplyFixes applies the fixes offered by the issues to the design, in
+// order, and returns the changes made to its primitives (see Diff) and
+// the issues without a fix. Fixes of problems already corrected (e.g.
+// by an earlier fix) change nothing. All primitives must be
+// serializable (see RegisterPrimitive). Checking the design again
+// confirms the corrections.
+func (g *Gerber) ApplyFixes(issues Issues) ([]Change, Issues, error) {
+	before, err := g.Snapshot()
+	if err != nil {
+		return nil, nil, err
+	}
+	var unfixed Issues
+	for _, i := range issues {
+		if i.Fix == nil {
+			unfixed = append(unfixed, i)
+			continue
+		}
+		if err := i.Fix.apply(); err != nil {
+			return nil, nil, fmt.Errorf("%v: %v", i, err)
+		}
+	}
+	after, err := g.Snapshot()
+	if err != nil {
+		return nil, nil, err
+	}
+	return Diff(before, after), unfixed, nil
+}
+
+// annularRingFix returns the fix of a plated hole that lacks an
+// annular ring of minRing on the copper layer: a pad of the minimum
+// size centered on the hole replaces a smaller round pad there, or is
+// added.
+func annularRingFix(layer *Layer, hole *CircleT, minRing float64) *Fix {
+	d := hole.thickness + 2*minRing
+	return &Fix{
+		Description: fmt.Sprintf("enlarge the pad of the hole at %v on %v to %vmm", fmtPt(hole.pt), layer.Filename, fmtFloat(d)),
+		apply: func() error {
+			if ringCovered(layer, hole.pt, 0.5*d-validationEps) {
+				return nil
+			}
+			pad := Circle(hole.pt, d)
+			replaced := false
+			layer.Replace(func(p Primitive) ([]Primitive, bool) {
+				c, ok := p.(*CircleT)
+				if replaced || !ok || layer.IsDerived(p) || c.thickness >= d || Distance(c.pt, hole.pt) > validationEps {
+					return nil, false
+				}
+				replaced = true
+				return []Primitive{pad}, true
+			})
+			if !replaced {
+				layer.Add(pad)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,222 @@
+package gerber
+
+import (
+	"context"
+
+	"github.com/gmlewis/go-gerber/gerber/internal/polyclip"
+)
+
+// AddBendArea adds a bend area (the closed polygon region) to the
+// design's bend area layer, which is added if necessary.
+// Pours that touch a bend area are hatched by default (see Pour).
+func (g *Gerber) AddBendArea(region []Pt) *PolygonT {
+	p := Polygon(Pt{0, 0}, true, region, 0)
+	g.firstLayerOfType(BendAreaLayer).Add(p)
+	return p
+}
+
+// PourOpts represents the options used by Pour.
+// All dimensions are in millimeters.
+type PourOpts struct {
+	// Hatched generates a hatched pour instead of a solid one.
+	Hatched bool
+	// HatchWidth and HatchPitch are the trace width and spacing of a
+	// hatched pour. Defaults are 0.2mm and 1mm.
+	HatchWidth float64
+	HatchPitch float64
+	// Obstacles are primitives (e.g. the traces and pads of other
+	// nets) that the pour clears by Clearance.
+	Obstacles []Primitive
+	// Connections are pads (e.g. of the pour's own net) that the pour
+	// connects to with thermal reliefs: each pad is cleared by
+	// ThermalGap (Clearance if 0) and joined to the pour by
+	// SpokeCount (default 4) spokes of SpokeWidth (default 0.3mm),
+	// rotated by SpokeAngle degrees.
+	Connections []Primitive
+	Clearance   float64
+	ThermalGap  float64
+	SpokeWidth  float64
+	SpokeCount  int
+	SpokeAngle  float64
+}
+
+// Pour fills the closed polygon region of the layer with copper and
+// returns the primitives added. The pour is hatched if opts.Hatched is
+// set or if (on a copper layer) the region touches a bend area of the
+// design, since solid copper cracks when flexed.
+//
+// Pours avoid the keepouts of the layer (see AddKeepout): keepouts
+// within a solid pour are cut out of it, while a solid pour that
+// partially overlaps a keepout is hatched instead.
+//
+// The voids of a pour around its obstacles and connections are holes
+// in its regions, which are followed by its thermal relief spokes (see
+// PourOpts), so the pour is appended to the layer like any other
+// primitives.
+func (l *Layer) Pour(region []Pt, opts *PourOpts) []Primitive {
+	result, _ := l.PourContext(context.Background(), region, opts)
+	return result
+}
+
+// PourContext is like Pour but stops early (returning ctx.Err(), and
+// adding nothing to the layer) if ctx is canceled while the pour is
+// being computed.
+func (l *Layer) PourContext(ctx context.Context, region []Pt, opts *PourOpts) ([]Primitive, error) {
+	var o PourOpts
+	if opts != nil {
+		o = *opts
+	}
+	if o.HatchWidth <= 0 {
+		o.HatchWidth = 0.2
+	}
+	if o.HatchPitch <= 0 {
+		o.HatchPitch = 1
+	}
+	if !o.Hatched && l.Type.IsCopper() && l.g != nil {
+		for _, bend := range l.g.layersOfType(BendAreaLayer) {
+			for _, p := range bend.Primitives {
+				if outlineOf(p).overlaps(outline{polys: [][]Pt{region}}) {
+					o.Hatched = true
+				}
+			}
+		}
+	}
+
+	var holes [][]Pt
+	for _, k := range l.keepouts() {
+		if !polygonsOverlap(region, k.Region) {
+			continue
+		}
+		if !polygonWithin(k.Region, region) {
+			o.Hatched = true
+			break
+		}
+		holes = append(holes, k.Region)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	voids := pourVoids(o)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var result []Primitive
+	if !o.Hatched {
+		result = pourRegions(region, append(holes, voids...))
+	} else {
+		// Hatch lines are clipped to avoid the keepouts and voids.
+		for _, p := range HatchedPolygon(region, o.HatchWidth, o.HatchPitch) {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			result = append(result, l.clipToKeepouts(p, voids)...)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	result = append(result, thermalSpokes(o)...)
+	l.Add(result...)
+	return result, nil
+}
+
+// pourRegions returns the regions filling the closed polygon region
+// less the holes (closed polygons), each with the holes within it cut
+// out (see cutHoles).
+func pourRegions(region []Pt, holes [][]Pt) []Primitive {
+	shapes := make([]polyclip.Shape, len(holes))
+	for i, h := range holes {
+		shapes[i] = polyclip.Shape{h}
+	}
+	var outers, inners [][]Pt
+	for _, c := range polyclip.Difference(polyclip.Shape{region}, shapes...) {
+		if polyclip.SignedArea(c) > 0 {
+			outers = append(outers, c)
+		} else {
+			inners = append(inners, c)
+		}
+	}
+	// Each hole belongs to the smallest contour around it.
+	within := make([][][]Pt, len(outers))
+	for _, h := range inners {
+		best := -1
+		for i, c := range outers {
+			if pointInPolygon(h[0], c) && (best < 0 || polyclip.SignedArea(c) < polyclip.SignedArea(outers[best])) {
+				best = i
+			}
+		}
+		if best >= 0 {
+			within[best] = append(within[best], h)
+		}
+	}
+	var result []Primitive
+	for i, c := range outers {
+		pts := cutHoles(c, within[i])
+		segments := make([]Segment, 0, len(pts)-1)
+		for _, pt := range pts[1:] {
+			segments = append(segments, LineTo(pt))
+		}
+		result = append(result, Region(pts[0], segments...))
+	}
+	return result
+}
+
+// clipToKeepouts returns the parts of the line p that (including its
+// width) avoid the layer's keepouts and the voids (closed polygons).
+func (l *Layer) clipToKeepouts(p Primitive, voids [][]Pt) []Primitive {
+	line, ok := p.(*LineT)
+	keepouts := l.keepouts()
+	if !ok || len(keepouts)+len(voids) == 0 {
+		return []Primitive{p}
+	}
+	var grown [][]Pt
+	for _, k := range keepouts {
+		grown = append(grown, polyclip.OffsetPolygon(k.Region, 0.5*line.Thickness))
+	}
+	for _, v := range voids {
+		grown = append(grown, polyclip.OffsetPolygon(v, 0.5*line.Thickness))
+	}
+	outside := func(pt Pt) bool {
+		for _, poly := range grown {
+			if pointInPolygon(pt, poly) {
+				return false
+			}
+		}
+		return true
+	}
+	var result []Primitive
+	for _, run := range clipRuns(line.P1, line.P2, outside) {
+		result = append(result, Line(run[0][0], run[0][1], run[1][0], run[1][1], line.Shape, line.Thickness))
+	}
+	return result
+}
+
+// polygonWithin reports whether polygon a lies entirely within polygon b.
+func polygonWithin(a, b []Pt) bool {
+	for _, pt := range a {
+		if !pointInPolygon(pt, b) {
+			return false
+		}
+	}
+	for i, j := 0, len(a)-1; i < len(a); j, i = i, i+1 {
+		for k, m := 0, len(b)-1; k < len(b); m, k = k, k+1 {
+			if segmentsIntersect(a[j], a[i], b[m], b[k]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// HatchedPolygon returns a hatched fill of the closed polygon region:
+// horizontal and vertical traces of the given width and pitch, bordered
+// by a trace along the region's edge. See HatchedRegion for a single
+// primitive with other angles and patterns.
+func HatchedPolygon(region []Pt, width, pitch float64) []Primitive {
+	var result []Primitive
+	for _, l := range HatchedRegion(region, width, pitch, 0, true).Lines() {
+		result = append(result, l)
+	}
+	return result
+}
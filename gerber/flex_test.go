@@ -0,0 +1,125 @@
+package gerber
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLayer_Pour(t *testing.T) {
+	square := func(x, y, size float64) []Pt {
+		return []Pt{{x, y}, {x + size, y}, {x + size, y + size}, {x, y + size}}
+	}
+
+	tests := []struct {
+		name        string
+		region      []Pt
+		opts        *PourOpts
+		wantHatched bool
+	}{
+		{name: "solid", region: square(0, 0, 5)},
+		{name: "hatched", region: square(0, 0, 5), opts: &PourOpts{Hatched: true}, wantHatched: true},
+		{name: "hatched in bend area", region: square(18, -3, 5), wantHatched: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New("test")
+			g.AddBendArea(square(20, -10, 10))
+			top := g.TopCopper()
+			result := top.Pour(tt.region, tt.opts)
+			if got := len(result) > 1; got != tt.wantHatched {
+				t.Errorf("hatched = %v, want %v", got, tt.wantHatched)
+			}
+			// A hatched pour must remain a single island within the region.
+			if got := len(top.Islands(IslandOpts{})); got != 1 {
+				t.Errorf("islands = %v, want 1", got)
+			}
+			mbb := top.MBB()
+			want := MBB{Min: tt.region[0], Max: tt.region[2]}
+			if !contains(&want, &mbb, 1e-9) {
+				t.Errorf("pour MBB = %v, want within %v", mbb, want)
+			}
+		})
+	}
+
+	g := New("test", WithX2(true))
+	if got, want := g.BendArea().fileFunction(), "Other,Bend-Area"; got != want {
+		t.Errorf("fileFunction = %q, want %q", got, want)
+	}
+}
+
+func TestLayer_Pour_Thermals(t *testing.T) {
+	square := []Pt{{0, 0}, {20, 0}, {20, 20}, {0, 20}}
+	top := New("test").TopCopper()
+	trace := Line(2, 2, 18, 2, CircleShape, 0.25)
+	pad := Circle(Pt{10, 10}, 2)
+	top.Add(trace, pad)
+
+	result := top.Pour(square, &PourOpts{
+		Obstacles:   []Primitive{trace},
+		Connections: []Primitive{pad},
+		Clearance:   0.5,
+	})
+	var regions, spokes int
+	for _, p := range result {
+		switch v := p.(type) {
+		case *RegionT:
+			regions++
+			// The voids are holes in the pour.
+			for _, pt := range []Pt{{10, 2}, {10, 2.6}, {10, 11.4}, {8.6, 10}} {
+				if v.Contains(pt) {
+					t.Errorf("pour covers the void at %v", pt)
+				}
+			}
+			for _, pt := range []Pt{{10, 2.7}, {10, 11.6}, {8.4, 10}, {1, 1}} {
+				if !v.Contains(pt) {
+					t.Errorf("pour misses %v", pt)
+				}
+			}
+		case *LineT:
+			spokes++
+			if got, want := Distance(v.P1, v.P2), 1+0.5+0.3; math.Abs(got-want) > 1e-9 {
+				t.Errorf("spoke length = %v, want %v", got, want)
+			}
+		default:
+			t.Errorf("Pour returned %T, want only regions and spokes", p)
+		}
+	}
+	if regions != 1 || spokes != 4 {
+		t.Errorf("Pour = %v regions and %v spokes, want 1 and 4", regions, spokes)
+	}
+	// The pour follows the layer's existing primitives.
+	if len(top.Primitives) != len(result)+2 {
+		t.Fatalf("layer has %v primitives, want %v", len(top.Primitives), len(result)+2)
+	}
+	if got := top.Primitives[:2]; got[0] != trace || got[1] != pad {
+		t.Errorf("first primitives = %v, want the trace and pad", got)
+	}
+}
+
+func TestGerber_DeriveOpenings_Coverlay(t *testing.T) {
+	g := New("test")
+	top, bottom := g.TopCopper(), g.BottomCopper()
+	coverlay := g.TopCoverlay()
+	tp, bp := Pad(Pt{5, 5}, RectShape, 1, 2, 0), Circle(Pt{5, 5}, 1)
+	top.AddWithOpenings(Openings{Mask: true, MaskExpansion: 0.1}, tp)
+	bottom.AddWithOpenings(Openings{Mask: true}, bp)
+	if err := g.DeriveOpenings(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The top side is covered by coverlay, and the bottom by solder mask.
+	if got := len(coverlay.Primitives); got != 1 || !coverlay.IsDerived(coverlay.Primitives[0]) {
+		t.Fatalf("coverlay = %v, want the derived opening of the pad", coverlay.Primitives)
+	}
+	mbb := coverlay.Primitives[0].MBB()
+	if want := (MBB{Min: Pt{4.4, 3.9}, Max: Pt{5.6, 6.1}}); !contains(&want, &mbb, 1e-9) || !contains(&mbb, &want, 1e-9) {
+		t.Errorf("coverlay opening MBB = %v, want %v", mbb, want)
+	}
+	if got := g.layersOfType(TopSolderMaskLayer); len(got) != 0 {
+		t.Errorf("top solder mask layers = %v, want none", got)
+	}
+	if got := g.layersOfType(BottomSolderMaskLayer); len(got) != 1 || len(got[0].Primitives) != 1 {
+		t.Errorf("bottom solder mask layers = %v, want one with the opening of the pad", got)
+	}
+}
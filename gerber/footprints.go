@@ -0,0 +1,182 @@
+package gerber
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// PartTagPrefix is the prefix of tags that name the part (e.g. a
+// reference designator such as "R1") a primitive belongs to. The
+// primitives and pads placed by Footprint.Place are tagged
+// automatically, so that a placed part can be found with Select.
+const PartTagPrefix = "part:"
+
+// PartTag returns the tag naming the given part.
+func PartTag(part string) string {
+	return PartTagPrefix + part
+}
+
+// silkscreenWidth is the line width of the library footprints' outlines.
+const silkscreenWidth = 0.12
+
+// Named returns a copy of the footprint with the given name, such as a
+// reference designator, so that the pads and primitives placed from
+// the copy belong to that part. Its Package is the footprint's name
+// unless already set.
+func (f *Footprint) Named(name string) *Footprint {
+	named := *f
+	named.Name = name
+	if named.Package == "" {
+		named.Package = f.Name
+	}
+	return &named
+}
+
+// Flipped returns a copy of the footprint for the other side of the
+// board (and Side), as seen from the top: its primitives, pads, and anchors are
+// mirrored about the Y axis and moved to the layers of the other side,
+// and the top and bottom pads of its padstacks are swapped. It returns
+// an error if any of its primitives does not implement Transformer.
+func (f *Footprint) Flipped() (*Footprint, error) {
+	flipped := &Footprint{Name: f.Name, Package: f.Package, Side: SideBottom, Layers: map[LayerType]Group{}, PinInPaste: f.PinInPaste}
+	if f.Side == SideBottom {
+		flipped.Side = SideTop
+	}
+	for t, group := range f.Layers {
+		mirrored, err := group.Transform(MirrorY())
+		if err != nil {
+			return nil, fmt.Errorf("footprint %q: %v", f.Name, err)
+		}
+		if opposite, ok := t.opposite(); ok {
+			t = opposite
+		}
+		flipped.Layers[t] = append(flipped.Layers[t], mirrored...)
+	}
+	padstacks := map[*Padstack]*Padstack{}
+	for _, pad := range f.Pads {
+		ps, ok := padstacks[pad.Padstack]
+		if !ok {
+			c := *pad.Padstack
+			c.Top, c.Bottom = c.Bottom, c.Top
+			c.TentTop, c.TentBottom = c.TentBottom, c.TentTop
+			ps = &c
+			padstacks[pad.Padstack] = ps
+		}
+		// Pads are symmetric, so mirroring only negates their rotation.
+		pad.Padstack, pad.Center, pad.Rotation = ps, Pt{-pad.Center[0], pad.Center[1]}, NormalizeAngle(-pad.Rotation)
+		flipped.Pads = append(flipped.Pads, pad)
+	}
+	if len(f.Anchors) > 0 {
+		flipped.Anchors = Anchors{}
+		for name, pt := range f.Anchors {
+			flipped.Anchors[name] = Pt{-pt[0], pt[1]}
+		}
+	}
+	return flipped, nil
+}
+
+// chipLands holds the pad width, pad height, pad center spacing, and
+// body width and height of the chip footprints, in mm (IPC-7351
+// nominal density).
+var chipLands = map[string][5]float64{
+	"0402": {0.59, 0.64, 0.97, 1.0, 0.5},
+	"0603": {0.8, 0.95, 1.65, 1.6, 0.8},
+	"0805": {1.025, 1.4, 1.825, 2.0, 1.25},
+	"1206": {1.125, 1.75, 2.925, 3.2, 1.6},
+}
+
+// ChipFootprint returns the footprint of a two terminal chip resistor
+// or capacitor of the given imperial size code ("0402", "0603",
+// "0805", or "1206"), centered on its origin with pads "1" (on the
+// left) and "2" along the X axis, and a silkscreen outline between
+// them when they are far enough apart.
+func ChipFootprint(size string) (*Footprint, error) {
+	lands, ok := chipLands[size]
+	if !ok {
+		return nil, fmt.Errorf("unknown chip size %q", size)
+	}
+	w, h, spacing, bodyH := lands[0], lands[1], lands[2], lands[4]
+	ps := &Padstack{Name: "smd-" + size, Top: RectPad(w, h), Paste: true}
+	f := &Footprint{Name: size, Layers: map[LayerType]Group{}, Pads: []PadstackRef{
+		{Padstack: ps, Center: Pt{-0.5 * spacing, 0}, Number: "1"},
+		{Padstack: ps, Center: Pt{0.5 * spacing, 0}, Number: "2"},
+	}}
+	// The outline keeps clear of the pads (and of their solder mask
+	// openings) by 0.2mm.
+	if x := 0.5*(spacing-w) - 0.2; x > 0 {
+		y := 0.5*bodyH + 0.1
+		f.Layers[TopSilkscreenLayer] = Group{
+			Line(-x, y, x, y, CircleShape, silkscreenWidth),
+			Line(-x, -y, x, -y, CircleShape, silkscreenWidth),
+		}
+	}
+	return f, nil
+}
+
+// SOICFootprint returns the footprint of a small outline IC with the
+// given (even) number of pins on a 1.27mm pitch and a 3.9mm wide body,
+// centered on its origin. Pin 1 is at the top left and the pins are
+// numbered counterclockwise. Its silkscreen outline marks pin 1 with a
+// dot.
+func SOICFootprint(pins int) (*Footprint, error) {
+	if pins < 4 || pins%2 != 0 {
+		return nil, fmt.Errorf("invalid SOIC pin count %v", pins)
+	}
+	const pitch, rowX, padW, padH, bodyW = 1.27, 2.475, 1.95, 0.6, 3.9
+	n := pins / 2
+	bodyL := float64(n-1)*pitch + 1.09
+	ps := &Padstack{Name: "smd-soic", Top: RectPad(padW, padH), Paste: true}
+	f := &Footprint{Name: "SOIC-" + strconv.Itoa(pins)}
+	top := 0.5 * float64(n-1) * pitch
+	for i := 0; i < n; i++ {
+		y := top - float64(i)*pitch
+		f.Pads = append(f.Pads, PadstackRef{Padstack: ps, Center: Pt{-rowX, y}, Number: strconv.Itoa(i + 1)})
+	}
+	for i := 0; i < n; i++ {
+		y := -top + float64(i)*pitch
+		f.Pads = append(f.Pads, PadstackRef{Padstack: ps, Center: Pt{rowX, y}, Number: strconv.Itoa(n + i + 1)})
+	}
+	x, y := 0.5*bodyW, 0.5*bodyL
+	f.Layers = map[LayerType]Group{TopSilkscreenLayer: {
+		Line(-x, y, x, y, CircleShape, silkscreenWidth),
+		Line(-x, -y, x, -y, CircleShape, silkscreenWidth),
+		Circle(Pt{-rowX - 0.5*padW - 0.4, top}, 0.5),
+	}}
+	return f, nil
+}
+
+// HeaderFootprint returns the footprint of a pin header with the given
+// number of rows and columns on a 2.54mm pitch, with pin 1 (which has a
+// square pad) at its origin. Pins are numbered along each row, and the
+// rows extend downward. Its silkscreen outline surrounds the pins.
+func HeaderFootprint(rows, cols int) (*Footprint, error) {
+	if rows < 1 || cols < 1 {
+		return nil, fmt.Errorf("invalid %vx%v header", rows, cols)
+	}
+	const pitch, drill, pad = 2.54, 1.0, 1.7
+	round := &Padstack{Name: "header", Drill: drill, Top: RoundPad(pad), Bottom: RoundPad(pad)}
+	square := &Padstack{Name: "header-pin1", Drill: drill, Top: RectPad(pad, pad), Bottom: RectPad(pad, pad)}
+	f := &Footprint{Name: fmt.Sprintf("Header-%vx%v", rows, cols)}
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			ps := round
+			if r == 0 && c == 0 {
+				ps = square
+			}
+			center := Pt{float64(c) * pitch, -float64(r) * pitch}
+			f.Pads = append(f.Pads, PadstackRef{Padstack: ps, Center: center, Number: strconv.Itoa(r*cols + c + 1)})
+		}
+	}
+	const margin = 1.33
+	outline := []Pt{
+		{-margin, margin}, {float64(cols-1)*pitch + margin, margin},
+		{float64(cols-1)*pitch + margin, -float64(rows-1)*pitch - margin}, {-margin, -float64(rows-1)*pitch - margin},
+	}
+	var silk Group
+	for i, p1 := range outline {
+		p2 := outline[(i+1)%len(outline)]
+		silk = append(silk, Line(p1[0], p1[1], p2[0], p2[1], CircleShape, silkscreenWidth))
+	}
+	f.Layers = map[LayerType]Group{TopSilkscreenLayer: silk}
+	return f, nil
+}
@@ -0,0 +1,133 @@
+package gerber
+
+import (
+	"testing"
+)
+
+// placedParts returns the number of primitives of each layer of g
+// tagged with the part.
+func placedParts(g *Gerber, part string) map[LayerType]int {
+	result := map[LayerType]int{}
+	for _, layer := range g.Layers {
+		if n := len(layer.Select(PartTag(part))); n > 0 {
+			result[layer.Type] += n
+		}
+	}
+	return result
+}
+
+func TestChipFootprint(t *testing.T) {
+	f, err := ChipFootprint("0603")
+	if err != nil {
+		t.Fatalf("ChipFootprint: %v", err)
+	}
+	g := New("test")
+	if err := f.Named("R1").Place(g, Pt{10, 10}, 90); err != nil {
+		t.Fatalf("Place: %v", err)
+	}
+	if err := g.DeriveOpenings(); err != nil {
+		t.Fatalf("DeriveOpenings: %v", err)
+	}
+	want := map[LayerType]int{TopCopperLayer: 2, TopSilkscreenLayer: 2}
+	got := placedParts(g, "R1")
+	for lt, n := range want {
+		if got[lt] != n {
+			t.Errorf("%v has %v primitives of R1, want %v", lt, got[lt], n)
+		}
+	}
+	for _, lt := range []LayerType{TopSolderMaskLayer, TopSolderPasteLayer} {
+		if n := len(g.firstLayerOfType(lt).Primitives); n != 2 {
+			t.Errorf("%v has %v openings, want 2", lt, n)
+		}
+	}
+	pad1 := g.firstLayerOfType(TopCopperLayer).Select(PartTag("R1"))[0]
+	if want := (MBB{Min: Pt{9.525, 8.775}, Max: Pt{10.475, 9.575}}); !mbbNear(pad1.MBB(), want, 1e-9) {
+		t.Errorf("pad 1 MBB = %v, want %v", pad1.MBB(), want)
+	}
+
+	if _, err := ChipFootprint("0201"); err == nil {
+		t.Error("ChipFootprint(0201) = nil error, want error")
+	}
+}
+
+func TestSOICFootprint(t *testing.T) {
+	f, err := SOICFootprint(8)
+	if err != nil {
+		t.Fatalf("SOICFootprint: %v", err)
+	}
+	tests := []struct {
+		pad  string
+		want Pt
+	}{
+		{pad: "1", want: Pt{-2.475, 1.905}},
+		{pad: "4", want: Pt{-2.475, -1.905}},
+		{pad: "5", want: Pt{2.475, -1.905}},
+		{pad: "8", want: Pt{2.475, 1.905}},
+	}
+	for _, tt := range tests {
+		if got, ok := f.Anchor(tt.pad); !ok || Distance(got, tt.want) > 1e-9 {
+			t.Errorf("pad %v = %v, want %v", tt.pad, got, tt.want)
+		}
+	}
+	if f.Name != "SOIC-8" || len(f.Pads) != 8 {
+		t.Errorf("SOICFootprint = %v with %v pads, want SOIC-8 with 8", f.Name, len(f.Pads))
+	}
+	if _, err := SOICFootprint(7); err == nil {
+		t.Error("SOICFootprint(7) = nil error, want error")
+	}
+}
+
+func TestHeaderFootprint(t *testing.T) {
+	f, err := HeaderFootprint(2, 3)
+	if err != nil {
+		t.Fatalf("HeaderFootprint: %v", err)
+	}
+	g := New("test")
+	if err := f.Place(g, Pt{5, 5}, 0); err != nil {
+		t.Fatalf("Place: %v", err)
+	}
+	if err := g.DeriveOpenings(); err != nil {
+		t.Fatalf("DeriveOpenings: %v", err)
+	}
+	got := placedParts(g, "Header-2x3")
+	if got[DrillLayer] != 6 || got[TopCopperLayer] != 6 || got[BottomCopperLayer] != 6 || got[TopSilkscreenLayer] != 4 {
+		t.Errorf("placed parts = %v, want 6 holes and 6 pads on each side in a 4 line outline", got)
+	}
+	if pt, _ := f.Anchor("4"); pt != (Pt{0, -2.54}) {
+		t.Errorf("pad 4 = %v, want (0,-2.54)", pt)
+	}
+	if f.Pads[0].Padstack.Top.Shape != RectShape || f.Pads[1].Padstack.Top.Shape != CircleShape {
+		t.Errorf("pad shapes = %v and %v, want a square pin 1", f.Pads[0].Padstack.Top.Shape, f.Pads[1].Padstack.Top.Shape)
+	}
+}
+
+func TestFootprint_Flipped(t *testing.T) {
+	f, err := SOICFootprint(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flipped, err := f.Named("U1").Flipped()
+	if err != nil {
+		t.Fatalf("Flipped: %v", err)
+	}
+	if pt, _ := flipped.Anchor("1"); Distance(pt, Pt{2.475, 1.905}) > 1e-9 {
+		t.Errorf("flipped pad 1 = %v, want (2.475,1.905)", pt)
+	}
+	g := New("test")
+	if err := flipped.Place(g, Pt{10, 10}, 0); err != nil {
+		t.Fatalf("Place: %v", err)
+	}
+	if err := g.DeriveOpenings(); err != nil {
+		t.Fatalf("DeriveOpenings: %v", err)
+	}
+	got := placedParts(g, "U1")
+	if got[BottomCopperLayer] != 8 || got[BottomSilkscreenLayer] != 3 || got[TopCopperLayer] != 0 || got[TopSilkscreenLayer] != 0 {
+		t.Errorf("placed parts = %v, want 8 pads and a 3 primitive outline on the bottom", got)
+	}
+	if n := len(g.firstLayerOfType(BottomSolderPasteLayer).Primitives); n != 8 {
+		t.Errorf("bottom paste has %v openings, want 8", n)
+	}
+	if f.Pads[0].Padstack.Top.Width == 0 || f.Pads[0].Center[0] > 0 {
+		t.Errorf("Flipped modified the original footprint")
+	}
+}
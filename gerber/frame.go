@@ -0,0 +1,90 @@
+package gerber
+
+import (
+	"fmt"
+	"math"
+)
+
+// Frame is a coordinate reference frame of a design (an origin, a
+// rotation, and optionally mirroring) that generators can draw into
+// using their own local coordinates, so that boards composed from
+// several generators do not have to thread offsets through every call.
+// Frames nest: a sub-frame is positioned within its parent frame.
+type Frame struct {
+	g *Gerber
+	t Transform // maps local coordinates to design coordinates
+}
+
+// Frame returns the design's own (root) coordinate frame.
+func (g *Gerber) Frame() *Frame {
+	return &Frame{g: g, t: Identity()}
+}
+
+// Sub returns a frame nested within f whose origin lies at origin
+// (in the coordinates of f) and whose axes are rotated counterclockwise
+// by degrees.
+func (f *Frame) Sub(origin Pt, degrees float64) *Frame {
+	return &Frame{g: f.g, t: Rotate(degrees).Then(Translate(origin[0], origin[1])).Then(f.t)}
+}
+
+// Mirror returns a frame with the same origin as f whose X axis is
+// reversed, mirroring everything drawn into it about its Y axis (e.g.
+// for artwork viewed from the bottom of the board).
+func (f *Frame) Mirror() *Frame {
+	return &Frame{g: f.g, t: MirrorY().Then(f.t)}
+}
+
+// Transform returns the transformation from the frame's coordinates to
+// the design's coordinates.
+func (f *Frame) Transform() Transform {
+	return f.t
+}
+
+// IsMirrored reports whether the frame is mirrored with respect to the
+// design (by an odd number of Mirror calls).
+func (f *Frame) IsMirrored() bool {
+	return f.t.det() < 0
+}
+
+// Apply returns the design coordinates of a point of the frame.
+func (f *Frame) Apply(pt Pt) Pt {
+	return f.t.Apply(pt)
+}
+
+// Angle returns the design angle (in degrees, counterclockwise) of a
+// direction of the frame at the given angle.
+func (f *Frame) Angle(degrees float64) float64 {
+	s, c := math.Sincos(Radians(degrees))
+	return Degrees(math.Atan2(f.t.D*c+f.t.E*s, f.t.A*c+f.t.B*s))
+}
+
+// Add adds copies of the primitives, transformed from the frame's
+// coordinates to the design's, to the layer. It returns an error (and
+// adds nothing) if any of them does not implement Transformer.
+func (f *Frame) Add(layer *Layer, primitives ...Primitive) error {
+	placed, err := Group(primitives).Transform(f.t)
+	if err != nil {
+		return err
+	}
+	layer.Add(placed...)
+	return nil
+}
+
+// PlacePadstack adds an instance of the padstack to the design at
+// center (in the frame's coordinates), rotated counterclockwise by
+// rotation degrees relative to the frame. Since pads are symmetric, a
+// mirrored frame places them by rotation alone.
+func (f *Frame) PlacePadstack(ps *Padstack, center Pt, rotation float64) *PadstackRef {
+	return f.g.PlacePadstack(ps, f.Apply(center), NormalizeAngle(f.Angle(rotation)))
+}
+
+// PlaceFootprint adds the footprint to the design at at (in the
+// frame's coordinates), rotated counterclockwise by degrees relative
+// to the frame (see Footprint.Place). Footprints cannot be placed in a
+// mirrored frame, since their layers would have to change sides.
+func (f *Frame) PlaceFootprint(fp *Footprint, at Pt, degrees float64) error {
+	if f.IsMirrored() {
+		return fmt.Errorf("footprint %q: cannot place in a mirrored frame", fp.Name)
+	}
+	return fp.Place(f.g, f.Apply(at), NormalizeAngle(f.Angle(degrees)))
+}
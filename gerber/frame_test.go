@@ -0,0 +1,69 @@
+package gerber
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFrame(t *testing.T) {
+	g := New("test")
+	top := g.TopCopper()
+	// A generator draws a line along its own X axis and a pad at its end.
+	generator := func(f *Frame) {
+		if err := f.Add(top, Line(0, 0, 10, 0, CircleShape, 0.2)); err != nil {
+			t.Fatal(err)
+		}
+		f.PlacePadstack(&Padstack{Top: RectPad(2, 1)}, Pt{10, 0}, 0)
+	}
+
+	board := g.Frame().Sub(Pt{20, 10}, 90)
+	generator(board.Sub(Pt{5, 0}, 0))
+	generator(board.Mirror())
+
+	tests := []struct {
+		name         string
+		got, want    MBB
+		center, wPad Pt
+	}{
+		{
+			name: "nested",
+			got:  top.Primitives[0].MBB(), want: MBB{Min: Pt{19.9, 14.9}, Max: Pt{20.1, 25.1}},
+			center: g.PadstackRefs()[0].Center, wPad: Pt{20, 25},
+		},
+		{
+			name: "mirrored",
+			got:  top.Primitives[1].MBB(), want: MBB{Min: Pt{19.9, -0.1}, Max: Pt{20.1, 10.1}},
+			center: g.PadstackRefs()[1].Center, wPad: Pt{20, 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !mbbNear(tt.got, tt.want, 1e-9) {
+				t.Errorf("MBB = %v, want %v", tt.got, tt.want)
+			}
+			if Distance(tt.center, tt.wPad) > 1e-9 {
+				t.Errorf("pad center = %v, want %v", tt.center, tt.wPad)
+			}
+		})
+	}
+	if got := g.PadstackRefs()[0].Rotation; math.Abs(got-90) > 1e-9 {
+		t.Errorf("rotation = %v, want 90", got)
+	}
+	if got := g.PadstackRefs()[1].Rotation; math.Abs(got-270) > 1e-9 {
+		t.Errorf("mirrored rotation = %v, want 270", got)
+	}
+
+	if board.IsMirrored() || !board.Mirror().IsMirrored() || board.Mirror().Mirror().IsMirrored() {
+		t.Error("IsMirrored is wrong")
+	}
+	fp := &Footprint{Name: "fp", Layers: map[LayerType]Group{TopSilkscreenLayer: {Circle(Pt{1, 0}, 0.5)}}}
+	if err := board.PlaceFootprint(fp, Pt{0, 0}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := g.firstLayerOfType(TopSilkscreenLayer).MBB(), (MBB{Min: Pt{19.75, 10.75}, Max: Pt{20.25, 11.25}}); !mbbNear(got, want, 1e-9) {
+		t.Errorf("footprint MBB = %v, want %v", got, want)
+	}
+	if err := board.Mirror().PlaceFootprint(fp, Pt{0, 0}, 0); err == nil {
+		t.Error("PlaceFootprint in a mirrored frame = nil error, want error")
+	}
+}
@@ -0,0 +1,314 @@
+package gerber
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/gmlewis/go-gerber/gerber/internal/polyclip"
+)
+
+// GCodeOpts represents the options of the G-code written by
+// WriteIsolationGCode and WriteDrillGCode, for milling prototype boards
+// on a desktop CNC machine. All dimensions are in millimeters and feed
+// rates in mm/min. Depths are positive, measured down from the surface
+// of the board (Z=0).
+type GCodeOpts struct {
+	// ToolDiameter is the cutting diameter of the isolation mill.
+	ToolDiameter float64
+	// Passes is the number of isolation passes around the copper (1 if
+	// zero), each further from the copper than the last. Overlap is the
+	// fraction (0 to 1) of the tool diameter by which passes overlap.
+	Passes  int
+	Overlap float64
+	// CutDepth is the depth of the isolation cuts, DrillDepth that of
+	// the holes, and PeckDepth that of each peck of a hole (0 drills
+	// each hole in one plunge).
+	CutDepth   float64
+	DrillDepth float64
+	PeckDepth  float64
+	// SafeZ is the height of rapid moves.
+	SafeZ float64
+	// Feed is the feed rate of cuts and PlungeFeed that of plunges (Feed
+	// if zero).
+	Feed       float64
+	PlungeFeed float64
+	// SpindleSpeed is the spindle speed in RPM (0 leaves the spindle to
+	// the operator).
+	SpindleSpeed float64
+}
+
+// gcodeTolerance is the maximum distance (in mm) by which isolation
+// paths approximating arcs stray outward from the exact offset.
+const gcodeTolerance = 0.005
+
+// WriteIsolationGCode derives the design's pads (see DeriveOpenings)
+// and writes G-code that routes around the copper of the given side,
+// so that only the design's copper remains. The tool's center follows
+// the outline of the copper grown by the tool's radius (merging copper
+// closer than the tool's diameter). The bottom side is mirrored left
+// to right, as it is milled after the board is flipped over.
+func (g *Gerber) WriteIsolationGCode(w io.Writer, side Side, opts GCodeOpts) error {
+	if !(opts.ToolDiameter > 0 && opts.CutDepth > 0 && opts.SafeZ > 0 && opts.Feed > 0) || opts.Overlap < 0 || opts.Overlap >= 1 {
+		return errors.New("invalid isolation tool, depth, height, feed, or overlap")
+	}
+	if err := g.DeriveOpenings(); err != nil {
+		return err
+	}
+	t := TopCopperLayer
+	if side == SideBottom {
+		t = BottomCopperLayer
+	}
+	layers := g.layersOfType(t)
+	if len(layers) == 0 {
+		return fmt.Errorf("design has no %v copper layer", side)
+	}
+	layer := layers[0]
+	passes := opts.Passes
+	if passes < 1 {
+		passes = 1
+	}
+
+	var buf bytes.Buffer
+	gc := &gcodeWriter{Buffer: &buf, g: g, mirror: side == SideBottom, opts: opts}
+	gc.header(fmt.Sprintf("isolation routing of %v with a %vmm tool", layer.Filename, fmtFloat(opts.ToolDiameter)))
+	step := opts.ToolDiameter * (1 - opts.Overlap)
+	for i := 0; i < passes; i++ {
+		for _, path := range isolationPaths(layer.Primitives, 0.5*opts.ToolDiameter+float64(i)*step) {
+			gc.cut(path)
+		}
+	}
+	gc.footer()
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// WriteDrillGCode derives the design's pads (see DeriveOpenings) and
+// writes G-code that drills the round holes of the design's drill layers from the given side (mirrored left to right for
+// the bottom side), grouped by size from the smallest, pausing (M0) for
+// each change of drill. It returns an error if the design has slots.
+func (g *Gerber) WriteDrillGCode(w io.Writer, side Side, opts GCodeOpts) error {
+	if !(opts.DrillDepth > 0 && opts.SafeZ > 0 && opts.Feed > 0) || opts.PeckDepth < 0 {
+		return errors.New("invalid drill depth, peck depth, height, or feed")
+	}
+	if err := g.DeriveOpenings(); err != nil {
+		return err
+	}
+	var count int
+	holes := map[float64][]Pt{}
+	for _, drill := range g.layersOfType(DrillLayer) {
+		for _, p := range drill.Primitives {
+			switch v := p.(type) {
+			case *CircleT:
+				holes[v.thickness] = append(holes[v.thickness], v.pt)
+				count++
+			case *LineT:
+				return fmt.Errorf("%v: slot from %v to %v cannot be drilled", drill.Filename, fmtPt(v.P1), fmtPt(v.P2))
+			}
+		}
+	}
+	var sizes []float64
+	for d := range holes {
+		sizes = append(sizes, d)
+	}
+	sort.Float64s(sizes)
+
+	var buf bytes.Buffer
+	gc := &gcodeWriter{Buffer: &buf, g: g, mirror: side == SideBottom, opts: opts}
+	gc.header(fmt.Sprintf("drilling of %v holes with %v drills from the %v", count, len(sizes), side))
+	for _, d := range sizes {
+		gc.toolChange(fmt.Sprintf("%vmm drill", fmtFloat(d)))
+		for _, pt := range holes[d] {
+			gc.drill(pt)
+		}
+	}
+	gc.footer()
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// gcodeWriter writes G-code with the design's export coordinates.
+type gcodeWriter struct {
+	*bytes.Buffer
+	g      *Gerber
+	mirror bool
+	opts   GCodeOpts
+}
+
+// gcodeNum formats a G-code value to 0.1 micron (without a negative
+// zero).
+func gcodeNum(v float64) string {
+	v = math.Round(v*1e4) / 1e4
+	if v == 0 {
+		v = 0
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func (gc *gcodeWriter) xy(pt Pt) string {
+	pt = gc.g.exportPt(pt)
+	if gc.mirror {
+		pt[0] = -pt[0]
+	}
+	return fmt.Sprintf("X%vY%v", gcodeNum(pt[0]), gcodeNum(pt[1]))
+}
+
+func (gc *gcodeWriter) plungeFeed() float64 {
+	if gc.opts.PlungeFeed > 0 {
+		return gc.opts.PlungeFeed
+	}
+	return gc.opts.Feed
+}
+
+func (gc *gcodeWriter) header(comment string) {
+	fmt.Fprintf(gc, "(%v: %v)\n", gc.g.FilenamePrefix, comment)
+	// Millimeters, absolute coordinates, and feed rates per minute.
+	fmt.Fprintf(gc, "G21\nG90\nG94\nG0 Z%v\n", gcodeNum(gc.opts.SafeZ))
+	if gc.opts.SpindleSpeed > 0 {
+		fmt.Fprintf(gc, "M3 S%v\n", gcodeNum(gc.opts.SpindleSpeed))
+	}
+}
+
+func (gc *gcodeWriter) toolChange(tool string) {
+	fmt.Fprintf(gc, "(%v)\n", tool)
+	if gc.opts.SpindleSpeed > 0 {
+		gc.WriteString("M5\n")
+	}
+	gc.WriteString("M0\n")
+	if gc.opts.SpindleSpeed > 0 {
+		fmt.Fprintf(gc, "M3 S%v\n", gcodeNum(gc.opts.SpindleSpeed))
+	}
+}
+
+func (gc *gcodeWriter) footer() {
+	if gc.opts.SpindleSpeed > 0 {
+		gc.WriteString("M5\n")
+	}
+	gc.WriteString("M2\n")
+}
+
+// cut mills along the path at the cut depth.
+func (gc *gcodeWriter) cut(path []Pt) {
+	fmt.Fprintf(gc, "G0 %v\n", gc.xy(path[0]))
+	fmt.Fprintf(gc, "G1 Z%v F%v\n", gcodeNum(-gc.opts.CutDepth), gcodeNum(gc.plungeFeed()))
+	for i, pt := range path[1:] {
+		if i == 0 {
+			fmt.Fprintf(gc, "G1 %v F%v\n", gc.xy(pt), gcodeNum(gc.opts.Feed))
+			continue
+		}
+		fmt.Fprintf(gc, "G1 %v\n", gc.xy(pt))
+	}
+	fmt.Fprintf(gc, "G0 Z%v\n", gcodeNum(gc.opts.SafeZ))
+}
+
+// drill drills a hole, in pecks of the peck depth, retracting to the
+// safe height after each.
+func (gc *gcodeWriter) drill(pt Pt) {
+	fmt.Fprintf(gc, "G0 %v\n", gc.xy(pt))
+	peck := gc.opts.PeckDepth
+	if peck <= 0 {
+		peck = gc.opts.DrillDepth
+	}
+	for z := 0.0; z < gc.opts.DrillDepth-validationEps; {
+		z = math.Min(z+peck, gc.opts.DrillDepth)
+		fmt.Fprintf(gc, "G1 Z%v F%v\n", gcodeNum(-z), gcodeNum(gc.plungeFeed()))
+		fmt.Fprintf(gc, "G0 Z%v\n", gcodeNum(gc.opts.SafeZ))
+	}
+}
+
+// isolationPaths returns the outline of the copper grown by d: the
+// parts of the outlines of each primitive grown by d that do not lie
+// within d of any other copper.
+func isolationPaths(copper []Primitive, d float64) [][]Pt {
+	type item struct {
+		outline outline
+		mbb     MBB
+	}
+	items := make([]item, len(copper))
+	for i, p := range copper {
+		items[i] = item{outline: outlineOf(p), mbb: p.MBB()}
+	}
+	outside := func(pt Pt) bool {
+		o := outline{strokes: []stroke{{p1: pt, p2: pt}}}
+		for _, it := range items {
+			if mbbGap(it.mbb, MBB{Min: pt, Max: pt}) < d-validationEps && it.outline.distance(o) < d-validationEps {
+				return false
+			}
+		}
+		return true
+	}
+
+	var paths [][]Pt
+	for _, it := range items {
+		for _, poly := range grownOutline(it.outline, d) {
+			var polyPaths [][]Pt
+			for i := 1; i < len(poly); i++ {
+				for _, run := range clipRuns(poly[i-1], poly[i], outside) {
+					if n := len(polyPaths); n > 0 && Distance(polyPaths[n-1][len(polyPaths[n-1])-1], run[0]) < validationEps {
+						polyPaths[n-1] = append(polyPaths[n-1], run[1])
+						continue
+					}
+					polyPaths = append(polyPaths, []Pt{run[0], run[1]})
+				}
+			}
+			// Join the path that closes the outline to the one that starts it.
+			if n := len(polyPaths); n > 1 && Distance(polyPaths[n-1][len(polyPaths[n-1])-1], polyPaths[0][0]) < validationEps {
+				polyPaths[0] = append(polyPaths[n-1], polyPaths[0][1:]...)
+				polyPaths = polyPaths[:n-1]
+			}
+			paths = append(paths, polyPaths...)
+		}
+	}
+	return joinPaths(paths)
+}
+
+// joinPaths joins the paths that end where others start, such as the
+// arcs of the outlines of overlapping pads (which meet within the
+// tolerance of their approximation).
+func joinPaths(paths [][]Pt) [][]Pt {
+	const eps = 4 * gcodeTolerance
+	for i := 0; i < len(paths); i++ {
+		for j := 0; j < len(paths); j++ {
+			if j == i || Distance(paths[i][len(paths[i])-1], paths[j][0]) >= eps {
+				continue
+			}
+			paths[i] = append(paths[i], paths[j]...)
+			paths = append(paths[:j], paths[j+1:]...)
+			if j < i {
+				i--
+			}
+			j = -1
+		}
+	}
+	return paths
+}
+
+// grownOutline returns the closed counterclockwise polygons (each
+// ending with its first point) bounding the outline grown by d. Arcs
+// are approximated by edges tangent to them (within gcodeTolerance),
+// so that no edge comes closer than d to the outline.
+func grownOutline(o outline, d float64) [][]Pt {
+	var polys [][]Pt
+	for _, s := range o.strokes {
+		p2 := s.p2
+		if Distance(s.p1, s.p2) < validationEps {
+			p2 = s.p1
+		}
+		poly := polyclip.Capsule(s.p1, p2, s.r+d, gcodeTolerance)
+		polys = append(polys, append(poly, poly[0]))
+	}
+	for _, poly := range o.polys {
+		grown := polyclip.OffsetPolygon(poly, d)
+		if polyclip.SignedArea(grown) < 0 {
+			grown = reversed(grown)
+		}
+		if len(grown) >= 3 {
+			polys = append(polys, append(grown, grown[0]))
+		}
+	}
+	return polys
+}
@@ -0,0 +1,159 @@
+package gerber
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// gcodeMoves returns the XY points of the G1 moves of the G-code and
+// the number of plunges of each depth.
+func gcodeMoves(t *testing.T, s string) ([]Pt, map[string]int) {
+	t.Helper()
+	var pts []Pt
+	plunges := map[string]int{}
+	for _, line := range strings.Split(s, "\n") {
+		if !strings.HasPrefix(line, "G1 ") {
+			continue
+		}
+		if strings.HasPrefix(line, "G1 Z") {
+			plunges[strings.Fields(line)[1]]++
+			continue
+		}
+		var pt Pt
+		if _, err := fmt.Sscanf(strings.Fields(line)[1], "X%gY%g", &pt[0], &pt[1]); err != nil {
+			t.Fatalf("move %q: %v", line, err)
+		}
+		pts = append(pts, pt)
+	}
+	return pts, plunges
+}
+
+func TestGerber_WriteIsolationGCode(t *testing.T) {
+	opts := GCodeOpts{ToolDiameter: 0.2, CutDepth: 0.1, SafeZ: 2, Feed: 100, PlungeFeed: 50, SpindleSpeed: 10000}
+	tests := []struct {
+		name    string
+		side    Side
+		opts    GCodeOpts
+		centers []Pt
+		paths   int
+	}{
+		{"pad", SideTop, opts, []Pt{{5, 5}}, 1},
+		{"merged pads", SideTop, opts, []Pt{{5, 5}, {6, 5}}, 1},
+		{"separate pads", SideTop, opts, []Pt{{5, 5}, {10, 5}}, 2},
+		{"bottom", SideBottom, opts, []Pt{{-5, 5}}, 1},
+		{"two passes", SideTop, GCodeOpts{ToolDiameter: 0.2, Passes: 2, Overlap: 0.5, CutDepth: 0.1, SafeZ: 2, Feed: 100}, []Pt{{5, 5}}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New("test")
+			copper := g.TopCopper()
+			if tt.side == SideBottom {
+				copper = g.BottomCopper()
+			}
+			for _, c := range tt.centers {
+				// Pads are mirrored back on the bottom side.
+				if tt.side == SideBottom {
+					c[0] = -c[0]
+				}
+				copper.Add(Circle(c, 2))
+			}
+			var buf bytes.Buffer
+			if err := g.WriteIsolationGCode(&buf, tt.side, tt.opts); err != nil {
+				t.Fatal(err)
+			}
+			got := buf.String()
+			if !strings.HasPrefix(got, "(test: isolation routing of ") || !strings.HasSuffix(got, "M2\n") {
+				t.Errorf("G-code =\n%v\nwant a header and M2 ending", got)
+			}
+			pts, plunges := gcodeMoves(t, got)
+			if plunges["Z-0.1"] != tt.paths {
+				t.Errorf("plunges = %v, want %v paths", plunges, tt.paths)
+			}
+			// The tool's center stays a tool's radius (and up to the
+			// further passes) from the copper, within the tolerance.
+			maxD := 1.1
+			if tt.opts.Passes > 1 {
+				maxD = 1.2
+			}
+			for _, pt := range pts {
+				d := 1e9
+				for _, c := range tt.centers {
+					if dc := Distance(pt, c); dc < d {
+						d = dc
+					}
+				}
+				if d < 1.1-1e-4 || d > maxD+gcodeTolerance+1e-4 {
+					t.Fatalf("move to %v is %v from the pads, want %v to %v", pt, d, 1.1, maxD)
+				}
+			}
+		})
+	}
+}
+
+func TestGerber_WriteDrillGCode(t *testing.T) {
+	g := New("test")
+	drill := g.Drill()
+	drill.Add(Circle(Pt{1, 1}, 0.8), Circle(Pt{2, 1}, 0.4), Circle(Pt{3, 1}, 0.8))
+
+	var buf bytes.Buffer
+	opts := GCodeOpts{DrillDepth: 1.6, PeckDepth: 0.7, SafeZ: 2, Feed: 60}
+	if err := g.WriteDrillGCode(&buf, SideBottom, opts); err != nil {
+		t.Fatal(err)
+	}
+	want := `(test: drilling of 3 holes with 2 drills from the bottom)
+G21
+G90
+G94
+G0 Z2
+(0.4mm drill)
+M0
+G0 X-2Y1
+G1 Z-0.7 F60
+G0 Z2
+G1 Z-1.4 F60
+G0 Z2
+G1 Z-1.6 F60
+G0 Z2
+(0.8mm drill)
+M0
+G0 X-1Y1
+G1 Z-0.7 F60
+G0 Z2
+G1 Z-1.4 F60
+G0 Z2
+G1 Z-1.6 F60
+G0 Z2
+G0 X-3Y1
+G1 Z-0.7 F60
+G0 Z2
+G1 Z-1.4 F60
+G0 Z2
+G1 Z-1.6 F60
+G0 Z2
+M2
+`
+	if got := buf.String(); got != want {
+		t.Errorf("WriteDrillGCode =\n%v\nwant\n%v", got, want)
+	}
+
+	drill.Add(Line(1, 3, 2, 3, CircleShape, 0.8))
+	if err := g.WriteDrillGCode(&buf, SideTop, opts); err == nil || !strings.Contains(err.Error(), "cannot be drilled") {
+		t.Errorf("WriteDrillGCode(slot) = %v, want a slot error", err)
+	}
+}
+
+func TestGerber_WriteGCode_Errors(t *testing.T) {
+	g := New("test")
+	var buf bytes.Buffer
+	if err := g.WriteIsolationGCode(&buf, SideTop, GCodeOpts{ToolDiameter: 0.2}); err == nil {
+		t.Error("WriteIsolationGCode(no depth) = nil, want error")
+	}
+	if err := g.WriteIsolationGCode(&buf, SideTop, GCodeOpts{ToolDiameter: 0.2, CutDepth: 0.1, SafeZ: 2, Feed: 100}); err == nil || !strings.Contains(err.Error(), "no top copper") {
+		t.Errorf("WriteIsolationGCode(no copper) = %v, want a missing layer error", err)
+	}
+	if err := g.WriteDrillGCode(&buf, SideTop, GCodeOpts{SafeZ: 2, Feed: 100}); err == nil {
+		t.Error("WriteDrillGCode(no depth) = nil, want error")
+	}
+}
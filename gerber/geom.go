@@ -0,0 +1,420 @@
+package gerber
+
+import "math"
+
+// pointInPolygon reports whether pt lies inside the closed polygon pts
+// using the even-odd rule.
+func pointInPolygon(pt Pt, pts []Pt) bool {
+	inside := false
+	for i, j := 0, len(pts)-1; i < len(pts); j, i = i, i+1 {
+		pi, pj := pts[i], pts[j]
+		if (pi[1] > pt[1]) != (pj[1] > pt[1]) &&
+			pt[0] < (pj[0]-pi[0])*(pt[1]-pi[1])/(pj[1]-pi[1])+pi[0] {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// segmentDistance returns the distance from pt to the segment p1-p2
+// and the (clamped) fractional position of the closest point along the segment.
+func segmentDistance(pt, p1, p2 Pt) (dist, t float64) {
+	dx, dy := p2[0]-p1[0], p2[1]-p1[1]
+	if l2 := dx*dx + dy*dy; l2 > 0 {
+		t = ((pt[0]-p1[0])*dx + (pt[1]-p1[1])*dy) / l2
+		t = math.Max(0, math.Min(1, t))
+	}
+	cx, cy := p1[0]+t*dx, p1[1]+t*dy
+	return math.Hypot(pt[0]-cx, pt[1]-cy), t
+}
+
+// scaleFactor returns the (uniform) linear scale factor of the transform.
+func (t Transform) scaleFactor() float64 {
+	return math.Sqrt(math.Abs(t.det()))
+}
+
+func (t Transform) det() float64 {
+	return t.A*t.E - t.B*t.D
+}
+
+// rotation returns the rotation angle (in radians) of the transform.
+// For mirroring transforms (det < 0), this is the rotation applied
+// after mirroring about the X axis.
+func (t Transform) rotation() float64 {
+	return math.Atan2(t.D, t.A)
+}
+
+// arcExtents returns the minimum bounding box of the elliptical arc
+// about center with radii rx and ry from angle start to end (in
+// radians, start <= end), which includes the points where the arc
+// crosses the axes through its center as well as its end points.
+func arcExtents(center Pt, rx, ry, start, end float64) MBB {
+	at := func(angle float64) MBB {
+		pt := Pt{center[0] + rx*math.Cos(angle), center[1] + ry*math.Sin(angle)}
+		return MBB{Min: pt, Max: pt}
+	}
+	mbb := at(start)
+	v := at(end)
+	mbb.Join(&v)
+	if end-start >= 2*math.Pi {
+		start, end = 0, 2*math.Pi
+	}
+	for k := math.Ceil(start / (0.5 * math.Pi)); k*0.5*math.Pi <= end; k++ {
+		v := at(k * 0.5 * math.Pi)
+		mbb.Join(&v)
+	}
+	return mbb
+}
+
+// grow returns mbb grown by d on each side.
+func grow(mbb MBB, d float64) MBB {
+	return MBB{Min: Pt{mbb.Min[0] - d, mbb.Min[1] - d}, Max: Pt{mbb.Max[0] + d, mbb.Max[1] + d}}
+}
+
+// expandSize returns size grown by delta on each side, limited to zero.
+func expandSize(size, delta float64) float64 {
+	return math.Max(0, size+2*delta)
+}
+
+// stroke is a segment stroked with a round pen of radius r
+// (a circle when p1 == p2).
+type stroke struct {
+	p1, p2 Pt
+	r      float64
+}
+
+// outline approximates the copper of a primitive by strokes and
+// filled polygons, for overlap (connectivity) testing.
+type outline struct {
+	strokes []stroke
+	polys   [][]Pt
+}
+
+// outlineOf returns the outline of p. Rectangular line caps are
+// approximated by round caps. Primitives of unknown types are
+// approximated by their MBB.
+func outlineOf(p Primitive) outline {
+	switch v := p.(type) {
+	case *CircleT:
+		return outline{strokes: []stroke{{p1: v.pt, p2: v.pt, r: 0.5 * v.thickness}}}
+	case *LineT:
+		return outline{strokes: []stroke{{p1: v.P1, p2: v.P2, r: 0.5 * v.Thickness}}}
+	case *ArcT:
+		var o outline
+		segments := v.segments(0)
+		delta := (v.EndAngle - v.StartAngle) / float64(segments)
+		pt := func(angle float64) Pt {
+			return Pt{v.Center[0] + v.XScale*math.Cos(angle)*v.Radius, v.Center[1] + v.YScale*math.Sin(angle)*v.Radius}
+		}
+		for i := 0; i < segments; i++ {
+			angle := v.StartAngle + float64(i)*delta
+			o.strokes = append(o.strokes, stroke{p1: pt(angle), p2: pt(angle + delta), r: 0.5 * v.Thickness})
+		}
+		return o
+	case *PolygonT:
+		pts := make([]Pt, 0, len(v.Points))
+		for _, pt := range v.Points {
+			pts = append(pts, Pt{pt[0] + v.Offset[0], pt[1] + v.Offset[1]})
+		}
+		return outline{polys: [][]Pt{pts}}
+	case *RegionT:
+		return outline{polys: [][]Pt{v.Points(0)}}
+	case *PadT:
+		// A pad is its core rectangle stroked by its corner radius.
+		r := v.radius()
+		a, b := 0.5*v.Width-r, 0.5*v.Height-r
+		var core []Pt
+		for _, c := range []Pt{{-a, -b}, {a, -b}, {a, b}, {-a, b}} {
+			pt := RotatePt(c, Pt{}, v.Rotation)
+			core = append(core, Pt{v.Center[0] + pt[0], v.Center[1] + pt[1]})
+		}
+		var o outline
+		if a > 0 && b > 0 {
+			o.polys = [][]Pt{core}
+		}
+		if r > 0 {
+			for i, pt := range core {
+				o.strokes = append(o.strokes, stroke{p1: pt, p2: core[(i+1)%len(core)], r: r})
+			}
+		}
+		return o
+	case *NetTieT:
+		return outline{strokes: []stroke{
+			{p1: v.P1, p2: v.P1, r: 0.5 * v.Pad},
+			{p1: v.P2, p2: v.P2, r: 0.5 * v.Pad},
+			{p1: v.P1, p2: v.P2, r: 0.5 * v.Width},
+		}}
+	case *CurveT:
+		var o outline
+		for _, l := range v.lines() {
+			o.strokes = append(o.strokes, stroke{p1: l.P1, p2: l.P2, r: 0.5 * l.Thickness})
+		}
+		return o
+	case *HatchedRegionT:
+		var o outline
+		for _, l := range v.Lines() {
+			o.strokes = append(o.strokes, stroke{p1: l.P1, p2: l.P2, r: 0.5 * l.Thickness})
+		}
+		return o
+	case *TextT:
+		var o outline
+		if err := v.renderText(); err == nil {
+			for _, poly := range v.Render.Polygons {
+				if poly.Dark {
+					o.polys = append(o.polys, poly.Pts)
+				}
+			}
+		}
+		return o
+	}
+	mbb := p.MBB()
+	return outline{polys: [][]Pt{{mbb.Min, {mbb.Max[0], mbb.Min[1]}, mbb.Max, {mbb.Min[0], mbb.Max[1]}}}}
+}
+
+// overlaps reports whether the outlines touch or overlap.
+func (o outline) overlaps(u outline) bool {
+	for _, s := range o.strokes {
+		for _, t := range u.strokes {
+			if segmentsDistance(s.p1, s.p2, t.p1, t.p2) <= s.r+t.r {
+				return true
+			}
+		}
+		for _, poly := range u.polys {
+			if strokeTouchesPolygon(s, poly) {
+				return true
+			}
+		}
+	}
+	for _, poly := range o.polys {
+		for _, t := range u.strokes {
+			if strokeTouchesPolygon(t, poly) {
+				return true
+			}
+		}
+		for _, other := range u.polys {
+			if polygonsOverlap(poly, other) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// distance returns the minimum clearance (in mm) between the
+// outlines, or 0 if they touch or overlap.
+func (o outline) distance(u outline) float64 {
+	if o.overlaps(u) {
+		return 0
+	}
+	d := math.Inf(1)
+	for _, s := range o.strokes {
+		for _, t := range u.strokes {
+			d = math.Min(d, segmentsDistance(s.p1, s.p2, t.p1, t.p2)-s.r-t.r)
+		}
+		for _, poly := range u.polys {
+			d = math.Min(d, strokePolygonDistance(s, poly))
+		}
+	}
+	for _, poly := range o.polys {
+		for _, t := range u.strokes {
+			d = math.Min(d, strokePolygonDistance(t, poly))
+		}
+		for _, other := range u.polys {
+			for i, j := 0, len(poly)-1; i < len(poly); j, i = i, i+1 {
+				for k, l := 0, len(other)-1; k < len(other); l, k = k, k+1 {
+					d = math.Min(d, segmentsDistance(poly[j], poly[i], other[l], other[k]))
+				}
+			}
+		}
+	}
+	return d
+}
+
+// strokePolygonDistance returns the distance from the stroke to the
+// edges of poly, which it is assumed not to overlap.
+func strokePolygonDistance(s stroke, poly []Pt) float64 {
+	d := math.Inf(1)
+	for i, j := 0, len(poly)-1; i < len(poly); j, i = i, i+1 {
+		d = math.Min(d, segmentsDistance(s.p1, s.p2, poly[j], poly[i])-s.r)
+	}
+	return d
+}
+
+func strokeTouchesPolygon(s stroke, poly []Pt) bool {
+	if len(poly) == 0 {
+		return false
+	}
+	if pointInPolygon(s.p1, poly) {
+		return true
+	}
+	for i, j := 0, len(poly)-1; i < len(poly); j, i = i, i+1 {
+		if segmentsDistance(s.p1, s.p2, poly[j], poly[i]) <= s.r {
+			return true
+		}
+	}
+	return false
+}
+
+func polygonsOverlap(a, b []Pt) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return false
+	}
+	if pointInPolygon(a[0], b) || pointInPolygon(b[0], a) {
+		return true
+	}
+	for i, j := 0, len(a)-1; i < len(a); j, i = i, i+1 {
+		for k, l := 0, len(b)-1; k < len(b); l, k = k, k+1 {
+			if segmentsIntersect(a[j], a[i], b[l], b[k]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// segmentsDistance returns the minimum distance between segments a1-a2 and b1-b2.
+func segmentsDistance(a1, a2, b1, b2 Pt) float64 {
+	if segmentsIntersect(a1, a2, b1, b2) {
+		return 0
+	}
+	d1, _ := segmentDistance(a1, b1, b2)
+	d2, _ := segmentDistance(a2, b1, b2)
+	d3, _ := segmentDistance(b1, a1, a2)
+	d4, _ := segmentDistance(b2, a1, a2)
+	return math.Min(math.Min(d1, d2), math.Min(d3, d4))
+}
+
+// segmentsIntersect reports whether segments a1-a2 and b1-b2 cross or touch.
+func segmentsIntersect(a1, a2, b1, b2 Pt) bool {
+	cross := func(o, p, q Pt) float64 {
+		return (p[0]-o[0])*(q[1]-o[1]) - (p[1]-o[1])*(q[0]-o[0])
+	}
+	d1, d2 := cross(b1, b2, a1), cross(b1, b2, a2)
+	d3, d4 := cross(a1, a2, b1), cross(a1, a2, b2)
+	if ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0)) {
+		return true
+	}
+	// Collinear or touching cases.
+	onSegment := func(p, q, r Pt) bool {
+		return math.Min(p[0], q[0]) <= r[0] && r[0] <= math.Max(p[0], q[0]) &&
+			math.Min(p[1], q[1]) <= r[1] && r[1] <= math.Max(p[1], q[1])
+	}
+	return (d1 == 0 && onSegment(b1, b2, a1)) || (d2 == 0 && onSegment(b1, b2, a2)) ||
+		(d3 == 0 && onSegment(a1, a2, b1)) || (d4 == 0 && onSegment(a1, a2, b2))
+}
+
+// primitiveArea returns the approximate area (in mm²) covered by p.
+// Primitives of unknown types are approximated by their MBB.
+func primitiveArea(p Primitive) float64 {
+	switch v := p.(type) {
+	case *CircleT:
+		r := 0.5 * v.thickness
+		return math.Pi * r * r
+	case *LineT:
+		length := math.Hypot(v.P2[0]-v.P1[0], v.P2[1]-v.P1[1])
+		if v.Shape == RectShape {
+			return (length + v.Thickness) * v.Thickness
+		}
+		r := 0.5 * v.Thickness
+		return length*v.Thickness + math.Pi*r*r
+	case *ArcT:
+		var area float64
+		for _, s := range outlineOf(v).strokes {
+			area += math.Hypot(s.p2[0]-s.p1[0], s.p2[1]-s.p1[1]) * v.Thickness
+		}
+		return area
+	case *PolygonT:
+		return PolygonArea(v.Points)
+	case *CurveT:
+		r := 0.5 * v.Thickness
+		area := math.Pi * r * r
+		for _, l := range v.lines() {
+			area += Distance(l.P1, l.P2) * v.Thickness
+		}
+		return area
+	case *HatchedRegionT:
+		var area float64
+		for _, l := range v.Lines() {
+			area += primitiveArea(l)
+		}
+		return area
+	case *TextT:
+		var area float64
+		if err := v.renderText(); err == nil {
+			for _, poly := range v.Render.Polygons {
+				if poly.Dark {
+					area += PolygonArea(poly.Pts)
+				} else {
+					area -= PolygonArea(poly.Pts)
+				}
+			}
+		}
+		return area
+	}
+	mbb := p.MBB()
+	return mbb.Area()
+}
+
+// hatch returns horizontal and vertical hatch lines of the given width
+// and pitch across mbb, clipped to the region for which inside returns true.
+func hatch(mbb MBB, inside func(pt Pt) bool, width, pitch float64) []Primitive {
+	var result []Primitive
+	for y := mbb.Min[1] + 0.5*pitch; y < mbb.Max[1]; y += pitch {
+		for _, run := range clipRuns(Pt{mbb.Min[0], y}, Pt{mbb.Max[0], y}, inside) {
+			result = append(result, Line(run[0][0], run[0][1], run[1][0], run[1][1], CircleShape, width))
+		}
+	}
+	for x := mbb.Min[0] + 0.5*pitch; x < mbb.Max[0]; x += pitch {
+		for _, run := range clipRuns(Pt{x, mbb.Min[1]}, Pt{x, mbb.Max[1]}, inside) {
+			result = append(result, Line(run[0][0], run[0][1], run[1][0], run[1][1], CircleShape, width))
+		}
+	}
+	return result
+}
+
+// clipRuns returns the runs of the segment p1-p2 that lie inside the
+// region, with endpoints refined by bisection.
+func clipRuns(p1, p2 Pt, inside func(pt Pt) bool) [][2]Pt {
+	const step = 0.05 // mm
+	length := Distance(p1, p2)
+	n := int(math.Ceil(length/step)) + 1
+	at := func(t float64) Pt {
+		return Pt{p1[0] + t*(p2[0]-p1[0]), p1[1] + t*(p2[1]-p1[1])}
+	}
+	// edge finds the transition between t0 (with state s0) and t1.
+	edge := func(t0, t1 float64, s0 bool) float64 {
+		for i := 0; i < 30; i++ {
+			tm := 0.5 * (t0 + t1)
+			if inside(at(tm)) == s0 {
+				t0 = tm
+			} else {
+				t1 = tm
+			}
+		}
+		if s0 {
+			return t0
+		}
+		return t1
+	}
+
+	var runs [][2]Pt
+	var start float64
+	prevT, prevIn := 0.0, inside(p1)
+	for i := 1; i <= n; i++ {
+		t := float64(i) / float64(n)
+		in := inside(at(t))
+		if in != prevIn {
+			e := edge(prevT, t, prevIn)
+			if in {
+				start = e
+			} else {
+				runs = append(runs, [2]Pt{at(start), at(e)})
+			}
+		}
+		prevT, prevIn = t, in
+	}
+	if prevIn {
+		runs = append(runs, [2]Pt{at(start), p2})
+	}
+	return runs
+}
@@ -0,0 +1,148 @@
+// Package geometry performs boolean operations (union, intersection,
+// and difference) and offsetting on filled shapes, and converts
+// between shapes and gerber primitives, so that (for example) a
+// keep-out can be subtracted from a pour, overlapping traces can be
+// merged into a single region, or an outline can be inset.
+// All dimensions are in millimeters.
+package geometry
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/gmlewis/go-gerber/gerber"
+	"github.com/gmlewis/go-gerber/gerber/internal/polyclip"
+)
+
+// Pt is a point, as in package gerber.
+type Pt = gerber.Pt
+
+// Shape is a filled area bounded by closed contours, with even-odd
+// fill semantics: a contour nested within an odd number of others is
+// a hole. The shapes returned by this package have non-crossing
+// contours, wound counterclockwise around the filled area (so that
+// holes are wound clockwise).
+type Shape [][]Pt
+
+// arcTolerance is the maximum error (in mm) of the polygons that
+// approximate round line caps and circles.
+const arcTolerance = 0.001
+
+// Union returns the area covered by any of the shapes.
+func Union(shapes ...Shape) Shape {
+	return Shape(polyclip.Union(clips(shapes)...))
+}
+
+// Intersection returns the area covered by all of the shapes.
+func Intersection(shapes ...Shape) Shape {
+	return Shape(polyclip.Intersection(clips(shapes)...))
+}
+
+// Difference returns the area of s that is not covered by any of the
+// others.
+func Difference(s Shape, others ...Shape) Shape {
+	return Shape(polyclip.Difference(polyclip.Shape(s), clips(others)...))
+}
+
+// Offset returns the shape grown by delta (shrunk, if negative), with
+// rounded corners where it grows: the points within delta of s, or the
+// points of s farther than -delta from its boundary.
+func Offset(s Shape, delta float64) Shape {
+	return Shape(polyclip.Offset(polyclip.Shape(s), delta))
+}
+
+func clips(shapes []Shape) []polyclip.Shape {
+	result := make([]polyclip.Shape, len(shapes))
+	for i, s := range shapes {
+		result[i] = polyclip.Shape(s)
+	}
+	return result
+}
+
+// Contains reports whether pt lies within the shape.
+func (s Shape) Contains(pt Pt) bool {
+	return polyclip.Shape(s).Contains(pt)
+}
+
+// Area returns the area of the shape.
+func (s Shape) Area() float64 {
+	return polyclip.Shape(s).Area()
+}
+
+// MBB returns the minimum bounding box of the shape.
+func (s Shape) MBB() gerber.MBB {
+	return polyclip.Shape(s).Bounds()
+}
+
+// Primitives returns the shape as filled regions (see
+// gerber.EvenOddRegions), with its holes as clear regions.
+func (s Shape) Primitives() gerber.Group {
+	return gerber.EvenOddRegions(Pt{}, s...)
+}
+
+// FromPrimitives returns the area covered by the primitives (lines,
+// arcs, circles, pads, polygons, regions, and clear primitives, which
+// erase what the primitives before them cover), as drawn on a layer.
+// Round caps and circles are approximated by circumscribed polygons.
+func FromPrimitives(primitives ...gerber.Primitive) (Shape, error) {
+	var dark []Shape
+	for _, p := range primitives {
+		if c, ok := p.(*gerber.ClearT); ok {
+			s, err := FromPrimitives(c.Primitive)
+			if err != nil {
+				return nil, err
+			}
+			dark = []Shape{Difference(Union(dark...), s)}
+			continue
+		}
+		s, err := fromPrimitive(p)
+		if err != nil {
+			return nil, err
+		}
+		dark = append(dark, s)
+	}
+	return Union(dark...), nil
+}
+
+func fromPrimitive(p gerber.Primitive) (Shape, error) {
+	switch v := p.(type) {
+	case *gerber.PolygonT:
+		c := make([]Pt, 0, len(v.Points))
+		for _, pt := range v.Points {
+			c = append(c, Pt{pt[0] + v.Offset[0], pt[1] + v.Offset[1]})
+		}
+		return Shape{c}, nil
+	case *gerber.RegionT:
+		return Shape{v.Points(0)}, nil
+	case *gerber.LineT:
+		if v.Shape == gerber.RectShape {
+			return Shape{gerber.ConvexHull(v)}, nil
+		}
+		return Shape{polyclip.Capsule(v.P1, v.P2, 0.5*v.Thickness, arcTolerance)}, nil
+	case *gerber.ArcT:
+		var strokes []Shape
+		pts := arcPoints(v)
+		for i := 1; i < len(pts); i++ {
+			strokes = append(strokes, Shape{polyclip.Capsule(pts[i-1], pts[i], 0.5*v.Thickness, arcTolerance)})
+		}
+		return Union(strokes...), nil
+	case *gerber.CircleT, *gerber.PadT:
+		return Shape{gerber.ConvexHull(v)}, nil
+	}
+	return nil, fmt.Errorf("unsupported primitive %T", p)
+}
+
+// arcPoints returns the centerline of an arc, divided into segments of
+// roughly 0.1mm.
+func arcPoints(a *gerber.ArcT) []Pt {
+	n := int(math.Ceil((a.EndAngle - a.StartAngle) * a.Radius * math.Max(a.XScale, a.YScale) / 0.1))
+	if n < 1 {
+		n = 1
+	}
+	pts := make([]Pt, 0, n+1)
+	for i := 0; i <= n; i++ {
+		angle := a.StartAngle + (a.EndAngle-a.StartAngle)*float64(i)/float64(n)
+		pts = append(pts, Pt{a.Center[0] + a.XScale*a.Radius*math.Cos(angle), a.Center[1] + a.YScale*a.Radius*math.Sin(angle)})
+	}
+	return pts
+}
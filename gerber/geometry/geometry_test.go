@@ -0,0 +1,106 @@
+package geometry
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gmlewis/go-gerber/gerber"
+	"github.com/gmlewis/go-gerber/gerber/internal/polyclip"
+)
+
+func square(x, y, size float64) Shape {
+	return Shape{{{x, y}, {x + size, y}, {x + size, y + size}, {x, y + size}}}
+}
+
+func TestBooleans(t *testing.T) {
+	a, b := square(0, 0, 2), square(1, 1, 2)
+	tests := []struct {
+		name     string
+		got      Shape
+		area     float64
+		contours int
+	}{
+		{"union", Union(a, b), 7, 1},
+		{"intersection", Intersection(a, b), 1, 1},
+		{"difference", Difference(a, b), 3, 1},
+		{"hole", Difference(square(0, 0, 4), square(1, 1, 2)), 12, 2},
+		{"disjoint union", Union(a, square(5, 5, 1)), 5, 2},
+		{"disjoint intersection", Intersection(a, square(5, 5, 1)), 0, 0},
+		{"shared edge", Union(a, square(2, 0, 2)), 8, 1},
+		{"identical", Union(a, a), 4, 1},
+		{"identical difference", Difference(a, a), 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.got.Area(); math.Abs(got-tt.area) > 1e-9 {
+				t.Errorf("Area = %v, want %v", got, tt.area)
+			}
+			if got := len(tt.got); got != tt.contours {
+				t.Errorf("contours = %v (%v), want %v", got, tt.got, tt.contours)
+			}
+		})
+	}
+
+	// The union of the overlapping squares has 8 vertices, wound
+	// counterclockwise.
+	u := Union(a, b)
+	if len(u[0]) != 8 || polyclip.SignedArea(u[0]) <= 0 {
+		t.Errorf("Union = %v, want 8 counterclockwise vertices", u)
+	}
+	if h := Difference(square(0, 0, 4), square(1, 1, 2)); polyclip.SignedArea(h[0])*polyclip.SignedArea(h[1]) >= 0 {
+		t.Errorf("Difference = %v, want a hole wound opposite its outline", h)
+	}
+}
+
+func TestOffset(t *testing.T) {
+	s := square(0, 0, 4)
+	if got, want := Offset(s, -1).Area(), 4.0; math.Abs(got-want) > 1e-9 {
+		t.Errorf("Offset(-1) area = %v, want %v", got, want)
+	}
+	// Grown by 1 with round corners: 16 + 4*4 + π.
+	if got, want := Offset(s, 1).Area(), 32+math.Pi; math.Abs(got-want) > 0.01 {
+		t.Errorf("Offset(1) area = %v, want %v", got, want)
+	}
+	if got := Offset(s, -2.5); len(got) != 0 {
+		t.Errorf("Offset(-2.5) = %v, want empty", got)
+	}
+}
+
+func TestFromPrimitives(t *testing.T) {
+	// Two overlapping traces merge into a single region.
+	s, err := FromPrimitives(
+		gerber.Line(0, 0, 10, 0, gerber.CircleShape, 1),
+		gerber.Line(5, -5, 5, 5, gerber.CircleShape, 1),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s) != 1 {
+		t.Fatalf("FromPrimitives = %v, want 1 contour", s)
+	}
+	if got, want := s.Area(), 2*(10+math.Pi/4)-1; math.Abs(got-want) > 0.01 {
+		t.Errorf("Area = %v, want %v", got, want)
+	}
+	if !s.Contains(Pt{5, 4.9}) || s.Contains(Pt{4, 4}) {
+		t.Error("Contains: wrong")
+	}
+	if got := s.Primitives(); len(got) != 1 {
+		t.Errorf("Primitives = %v, want 1 region", got)
+	}
+
+	// Clear primitives erase what is beneath them.
+	s, err = FromPrimitives(
+		gerber.Polygon(gerber.Pt{}, true, square(0, 0, 4)[0], 0),
+		gerber.Clear(gerber.Circle(gerber.Pt{2, 2}, 1)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := s.Area(), 16-math.Pi/4; len(s) != 2 || math.Abs(got-want) > 0.01 {
+		t.Errorf("FromPrimitives = %v contours of area %v, want 2, %v", len(s), got, want)
+	}
+
+	if _, err := FromPrimitives(&gerber.TextT{}); err == nil {
+		t.Error("FromPrimitives(text) = nil, want error")
+	}
+}
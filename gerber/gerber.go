@@ -3,10 +3,20 @@ package gerber
 
 import (
 	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"sync"
 )
 
+// ctxCheckInterval is the number of primitives processed between
+// checks for cancellation in context-aware operations.
+const ctxCheckInterval = 1000
+
 // Gerber represents the layers needed to build a PCB.
 type Gerber struct {
 	// FilenamePrefix is the filename prefix for the Gerber design files.
@@ -16,48 +26,348 @@ type Gerber struct {
 
 	mu  sync.Mutex // protects mbb against multiple requests
 	mbb *MBB       // cached minimum bounding box
+
+	units               Units
+	format              *CoordinateFormat // nil means the default for units
+	origin              Pt
+	exportXform         *Transform
+	defaultApertureSize float64
+	x2                  bool
+	array               bool // a panel of boards (see Panelize)
+	naming              FilenameConvention
+	numbering           ApertureNumbering
+	apertureTolerance   float64
+	arcTolerance        float64
+	nativeArcs          bool
+	validateOutput      bool
+	keepouts            []Keepout
+	padstackRefs        []*PadstackRef
+	components          []*Component
+	silkscreenMargin    float64
+	drillChartFont      string
+	autoFormat          bool
+	grid                float64
+	manifest            bool
+	revision            string
+	revisionFont        string
+	revisionAt          Pt
+	testPads            *TestPadOpts
+	stackup             *Stackup // nil means the default stackup
+	parallelWrites      int
+	outputFormat        OutputFormat
+	autoPaste           bool
+	pasteShrink         float64
+	pipeline            []Pass // nil means DefaultPipeline
+	deterministic       bool
+	drillOrder          DrillOrder
+	splitDrills         bool
+	excellonDrills      bool
+	maskPolicy          *MaskPolicy // nil means no automatic mask openings
+	progress            ProgressFunc
+	gerbvProject        bool
+	// written holds the checksums of the files last written by
+	// WriteChanged, by path.
+	written map[string][sha256.Size]byte
 }
 
 // New returns a new Gerber design.
 // filenamePrefix is the base filename for all gerber files (e.g. "bifilar-coil").
-func New(filenamePrefix string) *Gerber {
-	return &Gerber{
-		FilenamePrefix: filenamePrefix,
+// opts may be used to configure the output (units, precision, etc.).
+func New(filenamePrefix string, opts ...Option) *Gerber {
+	g := &Gerber{
+		FilenamePrefix:      filenamePrefix,
+		defaultApertureSize: defaultApertureSize,
+		naming:              ProtelNames,
+	}
+	for _, opt := range opts {
+		opt(g)
 	}
+	return g
 }
 
 // WriteGerber writes all the Gerber layers to their respective files
 // then zips them all together into a ZIP file with the same prefix
 // for sending to PCB manufacturers.
 func (g *Gerber) WriteGerber() error {
+	return g.WriteGerberContext(context.Background())
+}
+
+// WriteGerberContext is like WriteGerber but stops early (returning
+// ctx.Err()) if ctx is canceled.
+func (g *Gerber) WriteGerberContext(ctx context.Context) error {
 	zf, err := os.Create(g.FilenamePrefix + ".zip")
 	if err != nil {
 		return err
 	}
-	zw := zip.NewWriter(zf)
-	for _, layer := range g.Layers {
-		f, err := zw.Create(layer.Filename)
-		if err != nil {
+	if err := g.WriteZipContext(ctx, zf); err != nil {
+		zf.Close()
+		return err
+	}
+	if err := zf.Close(); err != nil {
+		return err
+	}
+	return g.WriteContext(ctx, func(filename string) (io.WriteCloser, error) {
+		return os.Create(filename)
+	})
+}
+
+// Write writes each layer to the io.WriteCloser returned by create
+// for the layer's filename. This allows the layers to be written
+// anywhere (memory, cloud storage, tests, etc.), followed by the
+// pick-and-place file if the design has components (see
+// WriteCentroid), and the gerbv project and manifests of the set if
+// enabled (see WithGerbvProject and WithManifest).
+// Declared solder mask and paste openings are derived first
+// (see DeriveOpenings) into copies of the layers, leaving the design's
+// own layers unchanged. With WithOutputFormat(IPC2581Format), the
+// design's IPC-2581 file (see WriteIPC2581) and pick-and-place file
+// are written instead.
+func (g *Gerber) Write(create func(filename string) (io.WriteCloser, error)) error {
+	return g.WriteContext(context.Background(), create)
+}
+
+// WriteContext is like Write but stops early (returning ctx.Err())
+// if ctx is canceled.
+func (g *Gerber) WriteContext(ctx context.Context, create func(filename string) (io.WriteCloser, error)) error {
+	if g.outputFormat == IPC2581Format {
+		return g.writeIPC2581(ctx, create)
+	}
+	restore, err := g.deriveOutput()
+	if err != nil {
+		return err
+	}
+	defer restore()
+	var m *Manifest
+	if g.manifest {
+		m = g.newManifest()
+	}
+	layers := g.outputLayers()
+	ctx = withProgress(ctx, g.progress, layers)
+	if g.parallelWrites > 1 {
+		if err := g.writeParallel(ctx, create, layers, m); err != nil {
 			return err
 		}
-		if err := layer.WriteGerber(f); err != nil {
-			return err
+	} else {
+		for _, layer := range layers {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			w, err := create(layer.Filename)
+			if err != nil {
+				return err
+			}
+			if m != nil {
+				w = m.add(layer, w)
+			}
+			if err := layer.writeOutput(ctx, w); err != nil {
+				abort(w)
+				return err
+			}
+			if err := w.Close(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := g.writeCentroid(create); err != nil {
+		return err
+	}
+	if err := g.writeGerbvProject(create); err != nil {
+		return err
+	}
+	if m != nil {
+		return m.write(g, create)
+	}
+	return nil
+}
+
+// writeParallel writes the output layers like WriteContext, serializing up to
+// g.parallelWrites of them to memory concurrently (see
+// WithParallelWrites) while their files are written in order.
+func (g *Gerber) writeParallel(ctx context.Context, create func(filename string) (io.WriteCloser, error), layers []*Layer, m *Manifest) error {
+	// Compute the bounding boxes cached by the primitives, which the
+	// serializations of the layers share.
+	for _, layer := range layers {
+		for _, p := range layer.Primitives {
+			p.MBB()
 		}
-		w, err := os.Create(layer.Filename)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	type output struct {
+		buf bytes.Buffer
+		err error
+	}
+	outputs := make([]chan *output, len(layers))
+	started := 0
+	start := func() {
+		layer, done := layers[started], make(chan *output, 1)
+		outputs[started] = done
+		started++
+		go func() {
+			out := &output{}
+			out.err = layer.writeOutput(ctx, &out.buf)
+			done <- out
+		}()
+	}
+	written := 0
+	defer func() {
+		// Wait for the layers being serialized, which must not outlive
+		// the call.
+		cancel()
+		for _, done := range outputs[written:started] {
+			<-done
+		}
+	}()
+
+	for started < len(layers) && started < g.parallelWrites {
+		start()
+	}
+	for written < len(layers) {
+		layer, out := layers[written], <-outputs[written]
+		written++
+		if started < len(layers) {
+			start()
+		}
+		if out.err != nil {
+			return out.err
+		}
+		w, err := create(layer.Filename)
 		if err != nil {
 			return err
 		}
-		if err := layer.WriteGerber(w); err != nil {
+		if m != nil {
+			w = m.add(layer, w)
+		}
+		if _, err := w.Write(out.buf.Bytes()); err != nil {
+			abort(w)
 			return err
 		}
 		if err := w.Close(); err != nil {
 			return err
 		}
 	}
+	return nil
+}
+
+// WriteToDir writes all the Gerber layers to files in dir, which is
+// created if necessary. Each file is named using the base name of the
+// layer's Filename.
+func (g *Gerber) WriteToDir(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return g.Write(func(filename string) (io.WriteCloser, error) {
+		return os.Create(filepath.Join(dir, filepath.Base(filename)))
+	})
+}
+
+// WriteChanged is like WriteToDir but only writes the files whose
+// contents have changed: those that differ from what WriteChanged last
+// wrote to them or, the first time, from the existing files in dir.
+// Every file of the set is written (and compared) alike: the layers,
+// the pick-and-place file, and the gerbv project and manifests if
+// enabled. Unchanged files are not touched, so that iterating on a
+// generator for a large design only rewrites (and only makes viewers
+// reload) the affected files, and a file whose output fails is left as
+// it was. It returns the paths of the written files.
+func (g *Gerber) WriteChanged(dir string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	if g.written == nil {
+		g.written = map[string][sha256.Size]byte{}
+	}
+	var written []string
+	err := g.Write(func(filename string) (io.WriteCloser, error) {
+		return &changedFile{path: filepath.Join(dir, filepath.Base(filename)), sums: g.written, written: &written}, nil
+	})
+	return written, err
+}
+
+// aborter is implemented by the files returned by the create functions
+// of Write (such as those of WriteChanged) that must not keep what was
+// written to them when writing fails.
+type aborter interface {
+	abort()
+}
+
+// abort closes a file whose writing failed, discarding its contents if
+// it is an aborter.
+func abort(w io.WriteCloser) {
+	if a, ok := w.(aborter); ok {
+		a.abort()
+		return
+	}
+	w.Close()
+}
+
+// changedFile buffers a file written by WriteChanged, and writes it to
+// path when it is closed if its contents have changed.
+type changedFile struct {
+	bytes.Buffer
+	path string
+	// sums holds the checksums of the files last written, by path,
+	// and written the paths of the files written by this call.
+	sums    map[string][sha256.Size]byte
+	written *[]string
+}
+
+// abort discards the file, leaving the file at path untouched.
+func (f *changedFile) abort() {
+	f.Reset()
+}
+
+func (f *changedFile) Close() error {
+	sum := sha256.Sum256(f.Bytes())
+	last, ok := f.sums[f.path]
+	if !ok {
+		if data, err := ioutil.ReadFile(f.path); err == nil && bytes.Equal(data, f.Bytes()) {
+			f.sums[f.path] = sum
+			return nil
+		}
+	} else if last == sum {
+		if _, err := os.Stat(f.path); err == nil {
+			return nil
+		}
+	}
+	if err := ioutil.WriteFile(f.path, f.Bytes(), 0644); err != nil {
+		return err
+	}
+	f.sums[f.path] = sum
+	*f.written = append(*f.written, f.path)
+	return nil
+}
+
+// WriteZip writes all the Gerber layers to w as a ZIP archive.
+func (g *Gerber) WriteZip(w io.Writer) error {
+	return g.WriteZipContext(context.Background(), w)
+}
+
+// WriteZipContext is like WriteZip but stops early (returning ctx.Err())
+// if ctx is canceled.
+func (g *Gerber) WriteZipContext(ctx context.Context, w io.Writer) error {
+	zw := zip.NewWriter(w)
+	err := g.WriteContext(ctx, func(filename string) (io.WriteCloser, error) {
+		f, err := zw.Create(filename)
+		if err != nil {
+			return nil, err
+		}
+		return nopCloser{f}, nil
+	})
+	if err != nil {
+		return err
+	}
 	return zw.Close()
 }
 
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
 // MBB returns the minimum bounding box of the design in millimeters.
+// Empty layers are ignored. A design with no primitives returns an empty MBB.
 func (g *Gerber) MBB() MBB {
 	g.mu.Lock()
 	defer g.mu.Unlock() // Only calculate MBB once.
@@ -68,6 +378,9 @@ func (g *Gerber) MBB() MBB {
 	var finalMu sync.Mutex
 	var wg sync.WaitGroup
 	for _, p := range g.Layers {
+		if p.IsEmpty() {
+			continue
+		}
 		wg.Add(1)
 		go func(p *Layer) {
 			v := p.MBB()
@@ -82,5 +395,45 @@ func (g *Gerber) MBB() MBB {
 		}(p)
 	}
 	wg.Wait()
+	if g.mbb == nil {
+		return MBB{}
+	}
 	return *g.mbb
 }
+
+// cachedMBB returns the cached MBB of the design, or nil if there is
+// none.
+func (g *Gerber) cachedMBB() *MBB {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.mbb
+}
+
+// SetOrigin sets the design coordinate that maps to (0,0) in the
+// output Gerber files. This allows a design to be built in a convenient
+// local coordinate system (e.g. centered on a coil) and then shifted into
+// fab coordinates at write time.
+func (g *Gerber) SetOrigin(origin Pt) {
+	g.origin = origin
+}
+
+// SetOriginLowerLeft sets the origin to the lower-left corner of the
+// design's current MBB so that all output coordinates are positive.
+func (g *Gerber) SetOriginLowerLeft() {
+	g.origin = g.MBB().Min
+}
+
+// SetExportTransform sets a transformation that is applied to all
+// coordinates (after shifting by the origin) at write time, for example
+// to rotate a design into fab coordinates.
+//
+// Only coordinates are transformed; aperture sizes and shapes are not,
+// so the transformation should be rigid (translation, mirroring, and
+// rotation by multiples of 90 degrees when rectangular apertures are used).
+func (g *Gerber) SetExportTransform(t Transform) {
+	if t.IsIdentity() {
+		g.exportXform = nil
+		return
+	}
+	g.exportXform = &t
+}
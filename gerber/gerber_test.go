@@ -0,0 +1,842 @@
+package gerber
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGerber_MBB(t *testing.T) {
+	tests := []struct {
+		name string
+		g    func() *Gerber
+		want MBB
+	}{
+		{
+			name: "no layers",
+			g:    func() *Gerber { return New("test") },
+			want: MBB{},
+		},
+		{
+			name: "empty layers are ignored",
+			g: func() *Gerber {
+				g := New("test")
+				g.TopCopper().Add(Circle(Pt{10, 10}, 2))
+				g.TopSilkscreen()
+				return g
+			},
+			want: MBB{Min: Pt{9, 9}, Max: Pt{11, 11}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.g().MBB(); got != tt.want {
+				t.Errorf("MBB = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew_Options(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want []string
+	}{
+		{
+			name: "defaults",
+			want: []string{
+				"%FSLAX36Y36*%",
+				"%MOMM*%",
+				"%LPD*%",
+				"%ADD11C,0.00100*%",
+				"%ADD12C,1.00000*%",
+				"G54D12*",
+				"X10000000Y20000000D02*",
+				"X10000000Y20000000D01*",
+				"M02*",
+			},
+		},
+		{
+			name: "inches",
+			opts: []Option{WithUnits(UnitsInch)},
+			want: []string{
+				"%FSLAX26Y26*%",
+				"%MOIN*%",
+				"%LPD*%",
+				"%ADD11C,0.000039*%",
+				"%ADD12C,0.039370*%",
+				"G54D12*",
+				"X393701Y787402D02*",
+				"X393701Y787402D01*",
+				"M02*",
+			},
+		},
+		{
+			name: "Inches option",
+			opts: []Option{Inches},
+			want: []string{
+				"%FSLAX26Y26*%",
+				"%MOIN*%",
+				"%LPD*%",
+				"%ADD11C,0.000039*%",
+				"%ADD12C,0.039370*%",
+				"G54D12*",
+				"X393701Y787402D02*",
+				"X393701Y787402D01*",
+				"M02*",
+			},
+		},
+		{
+			name: "precision, origin, and default aperture",
+			opts: []Option{WithCoordinateFormat(4, 4), WithOrigin(Pt{5, 5}), WithDefaultApertureSize(0.01)},
+			want: []string{
+				"%FSLAX44Y44*%",
+				"%MOMM*%",
+				"%LPD*%",
+				"%ADD11C,0.01000*%",
+				"%ADD12C,1.00000*%",
+				"G54D12*",
+				"X050000Y150000D02*",
+				"X050000Y150000D01*",
+				"M02*",
+			},
+		},
+		{
+			name: "auto format",
+			opts: []Option{WithCoordinateFormat(1, 6), WithAutoFormat(true)},
+			want: []string{
+				"%FSLAX26Y26*%",
+				"%MOMM*%",
+				"%LPD*%",
+				"%ADD11C,0.00100*%",
+				"%ADD12C,1.00000*%",
+				"G54D12*",
+				"X10000000Y20000000D02*",
+				"X10000000Y20000000D01*",
+				"M02*",
+			},
+		},
+		{
+			name: "X2 attributes",
+			opts: []Option{WithX2(true)},
+			want: []string{
+				"%FSLAX36Y36*%",
+				"%MOMM*%",
+				"%TF.GenerationSoftware,gmlewis,go-gerber*%",
+				"%TF.Part,Single*%",
+				"%TF.FileFunction,Copper,L1,Top*%",
+				"%TF.FilePolarity,Positive*%",
+				"%LPD*%",
+				"%ADD11C,0.00100*%",
+				"%TA.AperFunction,Conductor*%",
+				"%ADD12C,1.00000*%",
+				"%TD.AperFunction*%",
+				"G54D12*",
+				"X10000000Y20000000D02*",
+				"X10000000Y20000000D01*",
+				"M02*",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New("test", tt.opts...)
+			top := g.TopCopper()
+			top.Add(Circle(Pt{10, 20}, 1))
+			var buf bytes.Buffer
+			if err := top.WriteGerber(&buf); err != nil {
+				t.Fatalf("WriteGerber: %v", err)
+			}
+			got := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WriteGerber =\n%v\nwant:\n%v", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestLayer_WriteGerber_NoDefaultAperture(t *testing.T) {
+	g := New("test", WithDefaultApertureSize(0))
+	top := g.TopCopper()
+	top.Add(Polygon(Pt{}, true, []Pt{{0, 0}, {1, 0}, {1, 1}}, 0), Circle(Pt{2, 2}, 1))
+	var buf bytes.Buffer
+	if err := top.WriteGerber(&buf); err != nil {
+		t.Fatalf("WriteGerber: %v", err)
+	}
+	want := []string{
+		"%FSLAX36Y36*%",
+		"%MOMM*%",
+		"%LPD*%",
+		"%ADD12C,1.00000*%",
+		"G36*",
+		"X000000Y000000D02*",
+		"X1000000Y000000D01*",
+		"X1000000Y1000000D01*",
+		"X000000Y000000D01*",
+		"G37*",
+		"G54D12*",
+		"X2000000Y2000000D02*",
+		"X2000000Y2000000D01*",
+		"M02*",
+	}
+	got := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("WriteGerber =\n%v\nwant:\n%v", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	s, err := top.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(s.Apertures), 1; got != want {
+		t.Errorf("Stats apertures = %v, want %v", got, want)
+	}
+}
+
+func TestLayer_WriteGerber_CoordinateRange(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		x       float64
+		wantErr bool
+	}{
+		{name: "in range", x: 999},
+		{name: "default format", x: 1500, wantErr: true},
+		{name: "auto format", opts: []Option{WithAutoFormat(true)}, x: 1500},
+		{name: "beyond the maximum integer digits", opts: []Option{WithAutoFormat(true), WithUnits(UnitsInch)}, x: Inch(2e6), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New("test", tt.opts...)
+			top := g.TopCopper()
+			top.Add(Line(0, 0, tt.x, 0, CircleShape, 1))
+			err := top.WriteGerber(ioutil.Discard)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("WriteGerber = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLayer_WriteGerber_Grid(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []Option
+		layerGrid float64
+		x, y      float64
+		want      string
+	}{
+		{name: "no grid", x: 1.2345678, y: 0.1 + 0.2, want: "X1234568Y300000D02*"},
+		{name: "design grid", opts: []Option{WithGrid(0.01)}, x: 1.23456, y: -0.005, want: "X1230000Y-10000D02*"},
+		{name: "layer grid overrides design grid", opts: []Option{WithGrid(0.01)}, layerGrid: 0.1, x: 1.23456, y: 0.15, want: "X1200000Y200000D02*"},
+		{name: "grid finer than resolution", opts: []Option{WithCoordinateFormat(3, 3), WithGrid(1e-6)}, x: 1.2345, y: 0, want: "X001235Y000000D02*"},
+		{name: "float noise", opts: []Option{WithGrid(0.001)}, x: 0.1 + 0.2, y: 0.3, want: "X300000Y300000D02*"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New("test", tt.opts...)
+			top := g.TopCopper()
+			top.Grid = tt.layerGrid
+			top.Add(Circle(Pt{tt.x, tt.y}, 1))
+			var buf bytes.Buffer
+			if err := top.WriteGerber(&buf); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); !strings.Contains(got, tt.want+"\n") {
+				t.Errorf("WriteGerber =\n%v\nwant line %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLayer_WriteGerber_Format(t *testing.T) {
+	g := New("test", WithCoordinateFormat(4, 6))
+	top := g.TopCopper()
+	top.Format = &CoordinateFormat{Integer: 2, Decimal: 4}
+	top.Add(Circle(Pt{10, 20.12346}, 1))
+	bottom := g.BottomCopper()
+	bottom.Add(Circle(Pt{10, 20.12346}, 1))
+
+	tests := []struct {
+		layer *Layer
+		want  []string
+	}{
+		{layer: top, want: []string{"%FSLAX24Y24*%", "X100000Y201235D02*"}},
+		{layer: bottom, want: []string{"%FSLAX46Y46*%", "X10000000Y20123460D02*"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.layer.Filename, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.layer.WriteGerber(&buf); err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if got := buf.String(); !strings.Contains(got, want+"\n") {
+					t.Errorf("WriteGerber =\n%v\nwant line %v", got, want)
+				}
+			}
+		})
+	}
+
+	top.Format = &CoordinateFormat{Integer: 1, Decimal: 4}
+	if err := top.WriteGerber(ioutil.Discard); err == nil {
+		t.Errorf("WriteGerber = nil, want coordinate range error")
+	}
+}
+
+func TestWithFilenameConvention(t *testing.T) {
+	fc := FilenameFunc(func(prefix string, layer *Layer) string {
+		return prefix + "-" + layer.Type.String() + ".gbr"
+	})
+	g := New("board", WithFilenameConvention(fc))
+	if got, want := g.TopCopper().Filename, "board-TopCopper.gbr"; got != want {
+		t.Errorf("Filename = %q, want %q", got, want)
+	}
+	if got, want := New("board").LayerN(3).Filename, "board.gl3"; got != want {
+		t.Errorf("Filename = %q, want %q", got, want)
+	}
+}
+
+func TestFilenameConventions(t *testing.T) {
+	tests := []struct {
+		name string
+		fc   FilenameConvention
+		want []string
+	}{
+		{"Protel", ProtelNames, []string{"board.gtl", "board.gl2", "board.gko", "board.drl"}},
+		{"OSH Park", OSHParkNames, []string{"board.gtl", "board.g2l", "board.gko", "board.xln"}},
+		{"KiCad", KiCadNames, []string{"board-F_Cu.gbr", "board-In1_Cu.gbr", "board-Edge_Cuts.gbr", "board.drl"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New("board", WithFilenameConvention(tt.fc))
+			for i, layer := range []*Layer{g.TopCopper(), g.LayerN(2), g.Outline(), g.Drill()} {
+				if layer.Filename != tt.want[i] {
+					t.Errorf("Filename = %q, want %q", layer.Filename, tt.want[i])
+				}
+				typ, n, prefix, _, ok := layerTypeOf(layer.Filename)
+				if !ok || typ != layer.Type || n != layer.N || prefix != "board" {
+					t.Errorf("layerTypeOf(%q) = %v, %v, %q, %v, want %v, %v, %q, true", layer.Filename, typ, n, prefix, ok, layer.Type, layer.N, "board")
+				}
+			}
+		})
+	}
+}
+
+func TestPlaneMechanicalKeepoutLayers(t *testing.T) {
+	g := New("board", WithX2(true))
+	g.TopCopper()
+	plane := g.PlaneN(2)
+	g.LayerN(3)
+	g.BottomCopper()
+	for i, layer := range []*Layer{plane, g.Mechanical(2), g.Keepout()} {
+		want := []string{"board.gp2", "board.gm2", "board.gkp"}[i]
+		if layer.Filename != want {
+			t.Errorf("Filename = %q, want %q", layer.Filename, want)
+		}
+		typ, n, prefix, _, ok := layerTypeOf(layer.Filename)
+		if !ok || typ != layer.Type || n != layer.N || prefix != "board" {
+			t.Errorf("layerTypeOf(%q) = %v, %v, %q, %v, want %v, %v, %q, true", layer.Filename, typ, n, prefix, ok, layer.Type, layer.N, "board")
+		}
+	}
+	if typ, _, _, _, _ := layerTypeOf("board.gm1"); typ != OutlineLayer {
+		t.Errorf("layerTypeOf(board.gm1) = %v, want %v", typ, OutlineLayer)
+	}
+	if typ, n, ok := LayerTypeOf("out/board.gp2"); !ok || typ != InnerPlaneLayer || n != 2 {
+		t.Errorf("LayerTypeOf(out/board.gp2) = %v, %v, %v, want %v, 2, true", typ, n, ok, InnerPlaneLayer)
+	}
+	if _, _, ok := LayerTypeOf("README.md"); ok {
+		t.Error("LayerTypeOf(README.md) = true, want false")
+	}
+
+	plane.Add(Circle(Pt{5, 5}, 1))
+	var buf bytes.Buffer
+	if err := plane.WriteGerber(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"%TF.FileFunction,Copper,L2,Inr,Plane*%\n", "%TF.FilePolarity,Negative*%\n"} {
+		if got := buf.String(); !strings.Contains(got, want) {
+			t.Errorf("plane =\n%v\nwant %q", got, want)
+		}
+	}
+	if got, want := g.numCopperLayers(), 4; got != want {
+		t.Errorf("numCopperLayers = %v, want %v", got, want)
+	}
+}
+
+func TestLayerTypes(t *testing.T) {
+	for typ, info := range layerTypes {
+		if got, err := typ.MarshalText(); err != nil || string(got) != info.name {
+			t.Errorf("%v.MarshalText = %q, %v, want %q", typ, got, err, info.name)
+		}
+		if info.ipcSide == "TOP" || info.ipcSide == "BOTTOM" {
+			opposite, ok := typ.opposite()
+			if back, _ := opposite.opposite(); !ok || back != typ || layerTypes[opposite].ipcSide == info.ipcSide {
+				t.Errorf("%v.opposite = %v, %v, want a type on the other side", typ, opposite, ok)
+			}
+		} else if opposite, ok := typ.opposite(); ok {
+			t.Errorf("%v.opposite = %v, want none", typ, opposite)
+		}
+	}
+
+	stacked := StackedLayerTypes()
+	if got, want := stacked[0], BottomSilkscreenLayer; got != want {
+		t.Errorf("StackedLayerTypes()[0] = %v, want %v", got, want)
+	}
+	if got, want := stacked[len(stacked)-1], DrillLayer; got != want {
+		t.Errorf("StackedLayerTypes() ends with %v, want %v", got, want)
+	}
+	for _, typ := range stacked {
+		if typ == MechanicalLayer || typ == KeepoutLayer {
+			t.Errorf("StackedLayerTypes() includes %v", typ)
+		}
+	}
+
+	if got, want := layerFunction(&Layer{Type: InnerPlaneLayer, N: 3}), "Inner plane, negative (layer 3)"; got != want {
+		t.Errorf("layerFunction = %q, want %q", got, want)
+	}
+	if got, want := DefaultSVGStyle(LayerType(99)), otherSVGStyle; got != want {
+		t.Errorf("DefaultSVGStyle(99) = %v, want %v", got, want)
+	}
+}
+
+type memFile struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (m *memFile) Close() error {
+	m.closed = true
+	return nil
+}
+
+func TestGerber_Write(t *testing.T) {
+	g := New("board")
+	g.TopCopper().Add(Circle(Pt{1, 1}, 1))
+	g.Outline().Add(Line(0, 0, 2, 0, CircleShape, 0.1))
+
+	files := map[string]*memFile{}
+	err := g.Write(func(filename string) (io.WriteCloser, error) {
+		f := &memFile{}
+		files[filename] = f
+		return f, nil
+	})
+	if err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	for _, name := range []string{"board.gtl", "board.gko"} {
+		f, ok := files[name]
+		if !ok {
+			t.Fatalf("missing file %q", name)
+		}
+		if !f.closed {
+			t.Errorf("file %q was not closed", name)
+		}
+		if !strings.HasSuffix(f.String(), "M02*\n") {
+			t.Errorf("file %q is incomplete:\n%v", name, f.String())
+		}
+	}
+}
+
+func TestGerber_Write_Parallel(t *testing.T) {
+	write := func(opts ...Option) ([]string, map[string]string, error) {
+		g := New("board", append(opts, WithX2(true), WithManifest(true), WithAutoFormat(true))...)
+		for i := 0; i < 5; i++ {
+			layer := g.LayerN(i + 2)
+			for j := 0; j < 100; j++ {
+				x := float64(i*100 + j)
+				layer.Add(Line(x, -x, x+1, -x, CircleShape, 0.1+0.01*float64(j%3)), Circle(Pt{x, x}, 0.5))
+			}
+		}
+		g.TopCopper().Add(Circle(Pt{1, 1}, 1))
+		g.Outline().Add(Line(0, 0, 2, 0, CircleShape, 0.1))
+		var order []string
+		files := map[string]string{}
+		err := g.Write(func(filename string) (io.WriteCloser, error) {
+			order = append(order, filename)
+			return &memFileFunc{close: func(data string) { files[filename] = data }}, nil
+		})
+		return order, files, err
+	}
+	wantOrder, want, err := write()
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotOrder, got, err := write(WithParallelWrites(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(gotOrder, wantOrder) {
+		t.Errorf("parallel Write created %v, want %v", gotOrder, wantOrder)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Error("parallel Write output differs from sequential output")
+	}
+}
+
+func TestGerber_Write_ParallelError(t *testing.T) {
+	g := New("board", WithParallelWrites(2))
+	g.TopCopper().Add(Circle(Pt{1, 1}, 1))
+	g.BottomCopper().Add(Circle(Pt{1e9, 1}, 1))
+	g.Outline().Add(Line(0, 0, 2, 0, CircleShape, 0.1))
+	var order []string
+	err := g.Write(func(filename string) (io.WriteCloser, error) {
+		order = append(order, filename)
+		return &memFile{}, nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "does not fit") {
+		t.Errorf("Write = %v, want coordinate error", err)
+	}
+	if want := []string{"board.gtl"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("Write created %v, want %v", order, want)
+	}
+}
+
+// memFileFunc is a file that passes its contents to close when closed.
+type memFileFunc struct {
+	bytes.Buffer
+	close func(data string)
+}
+
+func (m *memFileFunc) Close() error {
+	m.close(m.String())
+	return nil
+}
+
+func TestGerber_WriteToDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-gerber")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	g := New("board")
+	g.TopCopper().Add(Circle(Pt{1, 1}, 1))
+	out := filepath.Join(dir, "out")
+	if err := g.WriteToDir(out); err != nil {
+		t.Fatalf("WriteToDir: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(out, "board.gtl")); err != nil {
+		t.Errorf("board.gtl: %v", err)
+	}
+}
+
+func TestGerber_WriteChanged(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-gerber")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	g := New("board", WithManifest(true))
+	g.TopCopper().Add(Circle(Pt{1, 1}, 1))
+	g.Drill().Add(Circle(Pt{1, 1}, 0.5))
+	if err := g.WriteToDir(dir); err != nil {
+		t.Fatalf("WriteToDir: %v", err)
+	}
+	path := func(name string) string { return filepath.Join(dir, name) }
+	tests := []struct {
+		name string
+		edit func()
+		want []string
+	}{
+		{name: "unchanged since WriteToDir", edit: func() {}},
+		{
+			name: "changed copper",
+			edit: func() { g.firstLayerOfType(TopCopperLayer).Add(Circle(Pt{2, 2}, 1)) },
+			want: []string{path("board.gtl"), path("board-manifest.json"), path("board-README.txt")},
+		},
+		{name: "unchanged", edit: func() {}},
+		{
+			name: "deleted file",
+			// The restored file is as listed by the manifests.
+			edit: func() { os.Remove(path("board.drl")) },
+			want: []string{path("board.drl")},
+		},
+	}
+	for _, tt := range tests {
+		tt.edit()
+		got, err := g.WriteChanged(dir)
+		if err != nil {
+			t.Fatalf("%v: WriteChanged: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%v: WriteChanged = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	data, err := ioutil.ReadFile(path("board.gtl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "X2000000Y2000000") {
+		t.Errorf("board.gtl = %q, want the added circle", data)
+	}
+}
+
+func TestGerber_WriteChanged_Error(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-gerber")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	g := New("board")
+	top := g.TopCopper()
+	top.Add(Circle(Pt{1, 1}, 1))
+	if _, err := g.WriteChanged(dir); err != nil {
+		t.Fatalf("WriteChanged: %v", err)
+	}
+	path := filepath.Join(dir, "board.gtl")
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A failed write leaves the last good file in place.
+	top.Add(Circle(Pt{2, 2}, 1))
+	bad := Line(0, 0, 1, 1, CircleShape, 0.3) // its aperture is not in the layer
+	top.Primitives = append(top.Primitives, bad)
+	if written, err := g.WriteChanged(dir); err == nil || len(written) != 0 {
+		t.Fatalf("WriteChanged = %v, %v, want an error and no files written", written, err)
+	}
+	if got, err := ioutil.ReadFile(path); err != nil || !bytes.Equal(got, want) {
+		t.Errorf("board.gtl = %q, %v, want it untouched:\n%s", got, err, want)
+	}
+
+	// The failed write is not mistaken for the file's contents.
+	top.Primitives = top.Primitives[:len(top.Primitives)-1]
+	if written, err := g.WriteChanged(dir); err != nil || !reflect.DeepEqual(written, []string{path}) {
+		t.Errorf("WriteChanged = %v, %v, want %v", written, err, path)
+	}
+}
+
+func TestGerber_WriteChanged_Centroid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-gerber")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	g := New("board")
+	g.TopCopper().Add(Circle(Pt{1, 1}, 1))
+	g.AddComponent(&Component{Designator: "R1", Center: Pt{1, 1}})
+	centroid := filepath.Join(dir, g.CentroidFilename())
+	if written, err := g.WriteChanged(dir); err != nil || len(written) != 2 || written[1] != centroid {
+		t.Fatalf("WriteChanged = %v, %v, want the layer and %v", written, err, centroid)
+	}
+	g.AddComponent(&Component{Designator: "R2", Center: Pt{2, 1}})
+	if written, err := g.WriteChanged(dir); err != nil || !reflect.DeepEqual(written, []string{centroid}) {
+		t.Errorf("WriteChanged = %v, %v, want only the changed %v", written, err, centroid)
+	}
+}
+
+func TestGerber_WriteZip(t *testing.T) {
+	g := New("board")
+	g.TopCopper().Add(Circle(Pt{1, 1}, 1))
+	g.Drill().Add(Circle(Pt{1, 1}, 0.5))
+
+	var buf bytes.Buffer
+	if err := g.WriteZip(&buf); err != nil {
+		t.Fatalf("WriteZip: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("zip.NewReader: %v", err)
+	}
+	var got []string
+	for _, f := range zr.File {
+		got = append(got, f.Name)
+	}
+	if want := []string{"board.gtl", "board.drl"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("zip files = %v, want %v", got, want)
+	}
+}
+
+func TestGerber_WriteContext_Canceled(t *testing.T) {
+	g := New("board")
+	g.TopCopper().Add(Circle(Pt{1, 1}, 1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := g.WriteContext(ctx, func(filename string) (io.WriteCloser, error) {
+		t.Fatalf("unexpected call to create(%q)", filename)
+		return nil, nil
+	})
+	if err != context.Canceled {
+		t.Errorf("WriteContext = %v, want %v", err, context.Canceled)
+	}
+
+	if _, err := g.ValidateContext(ctx); err != context.Canceled {
+		t.Errorf("ValidateContext = %v, want %v", err, context.Canceled)
+	}
+
+	var buf bytes.Buffer
+	if err := g.Layers[0].WriteGerber(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseContext(ctx, &buf); err != context.Canceled {
+		t.Errorf("ParseContext = %v, want %v", err, context.Canceled)
+	}
+
+	top := g.Layers[0]
+	n := len(top.Primitives)
+	square := []Pt{{0, 0}, {5, 0}, {5, 5}, {0, 5}}
+	for _, hatched := range []bool{false, true} {
+		if _, err := top.PourContext(ctx, square, &PourOpts{Hatched: hatched}); err != context.Canceled {
+			t.Errorf("PourContext(hatched %v) = %v, want %v", hatched, err, context.Canceled)
+		}
+	}
+	if len(top.Primitives) != n {
+		t.Errorf("canceled pours added %v primitives", len(top.Primitives)-n)
+	}
+
+	p := Panelizer{NX: 2, NY: 2, Separation: VScore, OutlineWidth: 0.1}
+	if _, err := p.PanelizeContext(ctx, panelBoard(), "panel"); err != context.Canceled {
+		t.Errorf("PanelizeContext = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestWithProgress(t *testing.T) {
+	type call struct{ done, total int }
+	for _, n := range []int{1, 3} {
+		var calls []call
+		g := New("board", WithProgress(func(done, total int) { calls = append(calls, call{done, total}) }), WithParallelWrites(n))
+		top := g.TopCopper()
+		for i := 0; i < 2500; i++ {
+			top.Add(Circle(Pt{float64(i % 100), float64(i / 100)}, 0.5))
+		}
+		g.Drill().Add(Circle(Pt{1, 1}, 0.4))
+		g.Outline().Add(Line(0, 0, 10, 0, CircleShape, 0.1))
+		if err := g.Write(func(filename string) (io.WriteCloser, error) { return &memFile{}, nil }); err != nil {
+			t.Fatal(err)
+		}
+		if len(calls) < 5 || calls[0] != (call{0, 2502}) || calls[len(calls)-1] != (call{2502, 2502}) {
+			t.Fatalf("parallel writes %v: got progress %v", n, calls)
+		}
+		for i := 1; i < len(calls); i++ {
+			if calls[i].done < calls[i-1].done || calls[i].total != 2502 {
+				t.Errorf("parallel writes %v: progress %v after %v", n, calls[i], calls[i-1])
+			}
+		}
+
+		// A layer reports its own progress.
+		calls = nil
+		if err := top.WriteGerber(ioutil.Discard); err != nil {
+			t.Fatal(err)
+		}
+		if want := []call{{0, 2500}, {1000, 2500}, {2000, 2500}, {2500, 2500}}; !reflect.DeepEqual(calls, want) {
+			t.Errorf("layer progress = %v, want %v", calls, want)
+		}
+	}
+
+	// The callback may cancel the write.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	g := New("board", WithProgress(func(done, total int) {
+		if done >= 1000 {
+			cancel()
+		}
+	}))
+	top := g.TopCopper()
+	for i := 0; i < 5000; i++ {
+		top.Add(Circle(Pt{float64(i % 100), float64(i / 100)}, 0.5))
+	}
+	err := g.WriteContext(ctx, func(filename string) (io.WriteCloser, error) { return &memFile{}, nil })
+	if err != context.Canceled {
+		t.Errorf("WriteContext = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestLayer_WriteGerber_CoordinateError(t *testing.T) {
+	g := New("test")
+	top := g.TopCopper()
+	top.Add(Circle(Pt{1, 1}, 1), Circle(Pt{1, 1000}, 1), Circle(Pt{2, math.Inf(1)}, 1))
+	err := top.WriteGerber(ioutil.Discard)
+	var ce *CoordinateError
+	if !errors.As(err, &ce) || ce.Layer != top || ce.Index != 1 || ce.Primitive != top.Primitives[1] || ce.Value != 1000 {
+		t.Fatalf("WriteGerber = %#v, want a coordinate error for primitive #1", err)
+	}
+	if got, want := err.Error(), "layer test.gtl: primitive #1 (circle): coordinate 1000mm does not fit in the 3.6 coordinate format (see WithAutoFormat)"; got != want {
+		t.Errorf("WriteGerber = %q, want %q", got, want)
+	}
+
+	top.Primitives = append(top.Primitives[:1], top.Primitives[2])
+	if err := top.WriteGerber(ioutil.Discard); !errors.As(err, &ce) || ce.Index != 1 || !strings.Contains(err.Error(), "invalid coordinate +Inf") {
+		t.Errorf("WriteGerber = %v, want an invalid coordinate error for primitive #1", err)
+	}
+
+	// Streamed primitives are reported too.
+	s, err := g.BottomCopper().NewStreamWriter(ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Write(Circle(Pt{1, 1}, 1), Circle(Pt{math.NaN(), 1}, 1)); !errors.As(err, &ce) || ce.Index != 1 {
+		t.Errorf("StreamWriter.Write = %v, want a coordinate error for primitive #1", err)
+	}
+}
+
+func TestAppendCoord(t *testing.T) {
+	for c, want := range map[int64]string{0: "000000", 5: "000005", -5: "-00005", 1234567: "1234567", -123456: "-123456"} {
+		if got := string(appendCoord(nil, c)); got != fmt.Sprintf("%06d", c) || got != want {
+			t.Errorf("appendCoord(%v) = %q, want %q", c, got, want)
+		}
+	}
+}
+
+// failingWriter fails once more than n bytes have been written.
+type failingWriter struct {
+	n int
+}
+
+var errDiskFull = errors.New("disk full")
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if len(p) > w.n {
+		n := w.n
+		w.n = 0
+		return n, errDiskFull
+	}
+	w.n -= len(p)
+	return len(p), nil
+}
+
+func (w *failingWriter) Close() error { return nil }
+
+func TestLayer_WriteGerber_Errors(t *testing.T) {
+	g := New("test")
+	top := g.TopCopper()
+	for i := 0; i < 10000; i++ {
+		top.Add(Circle(Pt{float64(i % 100), float64(i / 100)}, 1))
+	}
+	for _, n := range []int{0, 100, writeBufferSize + 100} {
+		if err := top.WriteGerber(&failingWriter{n: n}); err == nil || !strings.Contains(err.Error(), "test.gtl: disk full") {
+			t.Errorf("WriteGerber(fails after %v bytes) = %v, want disk full", n, err)
+		}
+	}
+	err := g.Write(func(filename string) (io.WriteCloser, error) {
+		return &failingWriter{n: 1000}, nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Errorf("Write = %v, want disk full", err)
+	}
+
+	bottom := g.BottomCopper()
+	bottom.Primitives = append(bottom.Primitives, Line(0, 0, 1, 1, CircleShape, 0.3))
+	if err := bottom.WriteGerber(ioutil.Discard); err == nil || !strings.Contains(err.Error(), "primitive #0 (line) uses an aperture that is not in the layer") {
+		t.Errorf("WriteGerber(unknown aperture) = %v, want error", err)
+	}
+}
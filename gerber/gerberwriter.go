@@ -0,0 +1,107 @@
+package gerber
+
+import "io"
+
+// GerberWriter writes the operations of a third-party primitive (see
+// RegisterPrimitive) in the coordinate format, units, origin, output
+// grid, and export transform of the layer being written, so that custom
+// primitives (such as QR codes or fractal fills) serialize exactly like
+// the built-in ones. All coordinates and sizes are in millimeters.
+//
+// A typical WriteGerber method selects its aperture and then draws:
+//
+//	func (q *QRCode) WriteGerber(w io.Writer, apertureIndex int) error {
+//		gw := gerber.NewGerberWriter(w)
+//		gw.SelectAperture(apertureIndex)
+//		for _, pt := range q.modules() {
+//			gw.Flash(pt)
+//		}
+//		return nil
+//	}
+//
+// GerberWriter is an io.Writer, so it may also be passed to the
+// WriteGerber methods of other primitives (e.g. to compose built-in
+// primitives) and used to write raw Gerber statements.
+type GerberWriter struct {
+	w *writer
+}
+
+// NewGerberWriter returns a GerberWriter for the io.Writer passed to a
+// primitive's WriteGerber method. Any other io.Writer gets the default
+// (millimeter) settings.
+func NewGerberWriter(w io.Writer) *GerberWriter {
+	return &GerberWriter{w: toWriter(w)}
+}
+
+// Write writes p unchanged, such as a raw Gerber statement.
+func (gw *GerberWriter) Write(p []byte) (int, error) {
+	return gw.w.Write(p)
+}
+
+// SelectAperture selects the aperture with the provided index (the one
+// passed to WriteGerber) for the operations that follow.
+func (gw *GerberWriter) SelectAperture(apertureIndex int) {
+	gw.w.aperture(apertureIndex)
+}
+
+// Move moves the current point to pt without drawing (D02).
+func (gw *GerberWriter) Move(pt Pt) {
+	gw.w.move(pt[0], pt[1])
+}
+
+// Draw draws a straight line from the current point to pt (D01).
+func (gw *GerberWriter) Draw(pt Pt) {
+	gw.w.draw(pt[0], pt[1])
+}
+
+// Flash flashes the current aperture at pt (D03).
+func (gw *GerberWriter) Flash(pt Pt) {
+	gw.w.flash(pt[0], pt[1])
+}
+
+// Arc draws a circular arc about center, clockwise or counterclockwise,
+// from the current point from to the point to (or a full circle if they
+// are the same). Unless native arcs are enabled (see WithNativeArcs),
+// the arc is flattened into line segments (see WithArcTolerance).
+func (gw *GerberWriter) Arc(from, to, center Pt, clockwise bool) {
+	if gw.w.nativeArcs {
+		gw.w.arc(from, to, center, clockwise)
+		return
+	}
+	r := Region(from, ArcTo(to, center, clockwise))
+	for _, pt := range r.Points(gw.w.arcTolerance)[1:] {
+		gw.w.draw(pt[0], pt[1])
+	}
+}
+
+// BeginRegion starts a region (G36), whose contours are the moves and
+// draws that follow, each closed with a draw back to its start point.
+// Regions use the default aperture, so primitives that write them
+// should return nil from their Aperture method.
+func (gw *GerberWriter) BeginRegion() {
+	io.WriteString(gw.w, "G36*\n")
+}
+
+// EndRegion ends a region started with BeginRegion (G37).
+func (gw *GerberWriter) EndRegion() {
+	io.WriteString(gw.w, "G37*\n")
+}
+
+// Polarity sets the polarity of the objects that follow to dark (LPD) or
+// clear (LPC). Within a primitive wrapped by Clear, the polarity is
+// inverted. Primitives that write clear objects should restore the dark
+// polarity before returning.
+func (gw *GerberWriter) Polarity(dark bool) {
+	gw.w.polarity(dark)
+}
+
+// XY returns the formatted coordinates of pt (e.g. "X001000Y-02500"),
+// after the origin and export transform are applied.
+func (gw *GerberWriter) XY(pt Pt) string {
+	return gw.w.xy(pt[0], pt[1])
+}
+
+// Size returns the formatted size (e.g. of an aperture) in output units.
+func (gw *GerberWriter) Size(v float64) string {
+	return gw.w.size(v)
+}
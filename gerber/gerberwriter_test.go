@@ -0,0 +1,97 @@
+package gerber
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+// testDisc and testHalfDisc are third-party versions of a circle and a
+// half-disc region, written with a GerberWriter.
+type testDisc struct {
+	Center Pt
+}
+
+func (d *testDisc) WriteGerber(w io.Writer, apertureIndex int) error {
+	gw := NewGerberWriter(w)
+	gw.SelectAperture(apertureIndex)
+	gw.Move(d.Center)
+	gw.Draw(d.Center)
+	return nil
+}
+func (d *testDisc) Aperture() *Aperture { return &Aperture{Shape: CircleShape, Size: 0.5} }
+func (d *testDisc) MBB() MBB            { return Circle(d.Center, 0.5).MBB() }
+
+type testHalfDisc struct {
+	From, To, Center Pt
+}
+
+func (d *testHalfDisc) WriteGerber(w io.Writer, apertureIndex int) error {
+	gw := NewGerberWriter(w)
+	gw.SelectAperture(apertureIndex)
+	gw.BeginRegion()
+	gw.Move(d.From)
+	gw.Arc(d.From, d.To, d.Center, false)
+	gw.Draw(d.From)
+	gw.EndRegion()
+	return nil
+}
+func (d *testHalfDisc) Aperture() *Aperture { return nil }
+func (d *testHalfDisc) MBB() MBB            { return d.region().MBB() }
+func (d *testHalfDisc) region() *RegionT    { return Region(d.From, ArcTo(d.To, d.Center, false)) }
+
+func TestGerberWriter(t *testing.T) {
+	half := &testHalfDisc{From: Pt{5, 0}, To: Pt{-5, 0}, Center: Pt{0, 0}}
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{name: "defaults"},
+		{name: "inch with origin", opts: []Option{WithUnits(UnitsInch), WithOrigin(Pt{-10, -10})}},
+		{name: "native arcs", opts: []Option{WithNativeArcs(true)}},
+		{name: "mirrored", opts: []Option{WithExportTransform(Scale(-1, 1))}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			write := func(primitives ...Primitive) string {
+				g := New("test", tt.opts...)
+				top := g.TopCopper()
+				top.Add(primitives...)
+				var buf bytes.Buffer
+				if err := top.WriteGerber(&buf); err != nil {
+					t.Fatalf("WriteGerber: %v", err)
+				}
+				return buf.String()
+			}
+			got := write(&testDisc{Center: Pt{1.25, -2.5}}, half)
+			want := write(Circle(Pt{1.25, -2.5}, 0.5), half.region())
+			if got != want {
+				t.Errorf("custom primitives =\n%v\nwant\n%v", got, want)
+			}
+		})
+	}
+}
+
+func TestGerberWriter_Helpers(t *testing.T) {
+	var buf bytes.Buffer
+	gw := NewGerberWriter(&buf)
+	if got, want := gw.XY(Pt{1, -2.5}), "X1000000Y-2500000"; got != want {
+		t.Errorf("XY = %q, want %q", got, want)
+	}
+	if got, want := gw.Size(0.25), "0.25000"; got != want {
+		t.Errorf("Size = %q, want %q", got, want)
+	}
+	gw.Flash(Pt{1, 2})
+	gw.Polarity(false)
+	// Built-in primitives written to a GerberWriter use its settings.
+	if err := Clear(Circle(Pt{0, 0}, 1)).WriteGerber(gw, 10); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"X1000000Y2000000D03*\n", "%LPC*%\nG54D10*\n", "%LPD*%\n"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output missing %q:\n%v", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package gerber
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// WithGerbvProject enables or disables the writing of a gerbv project
+// file (see GerbvProjectFilename and WriteGerbvProject) alongside the
+// layers, so that the whole set may be opened at once in gerbv for
+// inspection while iterating on a generator.
+func WithGerbvProject(enabled bool) Option {
+	return func(g *Gerber) {
+		g.gerbvProject = enabled
+	}
+}
+
+// GerbvProjectFilename returns the filename of the design's gerbv
+// project file (e.g. "board.gvp").
+func (g *Gerber) GerbvProjectFilename() string {
+	return g.FilenamePrefix + ".gvp"
+}
+
+// gerbvColor returns the color of layers of type t in gerbv projects.
+func gerbvColor(t LayerType) [3]uint8 {
+	if info, ok := layerTypes[t]; ok {
+		return info.gerbv
+	}
+	return otherGerbvColor
+}
+
+// WriteGerbvProject writes a gerbv project file to w that opens the
+// design's layer files (see Write), in the directory of the project
+// file, stacked as seen from the top of the board: the drill and
+// outline layers on top, then the top side, inner, and bottom side
+// layers. Each layer type has its own color (copper is red on top,
+// blue on the bottom, and yellow inside), and the solder mask and
+// paste layers are initially hidden.
+func (g *Gerber) WriteGerbvProject(w io.Writer) error {
+	layers := append([]*Layer(nil), g.outputLayers()...)
+	order := func(t LayerType) int {
+		if n := layerTypes[t].stack; n > 0 {
+			return n
+		}
+		return layerTypes[OutlineLayer].stack
+	}
+	// gerbv draws layer 0 on top.
+	sort.SliceStable(layers, func(i, j int) bool { return order(layers[i].Type) > order(layers[j].Type) })
+
+	quote := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	ew := &errWriter{w: w}
+	fmt.Fprintf(ew, "(gerbv-file-version! \"2.0A\")\n")
+	for i := len(layers) - 1; i >= 0; i-- {
+		layer := layers[i]
+		c := gerbvColor(layer.Type)
+		fmt.Fprintf(ew, "(define-layer! %v (cons 'filename \"%v\")(cons 'visible %v)(cons 'color #(%v %v %v)))\n",
+			i, quote.Replace(filepath.Base(layer.Filename)), gerbvBool(!layerTypes[layer.Type].gerbvHidden),
+			257*int(c[0]), 257*int(c[1]), 257*int(c[2]))
+	}
+	fmt.Fprintf(ew, "(define-layer! -1 (cons 'filename \".\")(cons 'visible #f)(cons 'color #(0 0 0)))\n")
+	fmt.Fprintf(ew, "(set-render-type! 0)\n")
+	return ew.err
+}
+
+// gerbvBool returns the Scheme boolean v.
+func gerbvBool(v bool) string {
+	if v {
+		return "#t"
+	}
+	return "#f"
+}
+
+// writeGerbvProject writes the design's gerbv project file, if enabled.
+func (g *Gerber) writeGerbvProject(create func(filename string) (io.WriteCloser, error)) error {
+	if !g.gerbvProject {
+		return nil
+	}
+	w, err := create(g.GerbvProjectFilename())
+	if err != nil {
+		return err
+	}
+	if err := g.WriteGerbvProject(w); err != nil {
+		abort(w)
+		return err
+	}
+	return w.Close()
+}
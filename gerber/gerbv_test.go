@@ -0,0 +1,78 @@
+package gerber
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWithGerbvProject(t *testing.T) {
+	g := New("out/board", WithGerbvProject(true), WithSplitDrills(true))
+	g.TopCopper().AddPad(PadOpts{}, Circle(Pt{1, 1}, 1))
+	g.BottomCopper().Add(Circle(Pt{1, 1}, 1))
+	drill := g.Drill()
+	drill.Add(Circle(Pt{1, 1}, 0.4))
+	drill.AddTagged([]string{NonPlatedTag}, Circle(Pt{4, 4}, 3))
+	g.Outline().Add(Line(0, 0, 10, 0, CircleShape, 0.1))
+
+	files := map[string]*memFile{}
+	err := g.Write(func(filename string) (io.WriteCloser, error) {
+		f := &memFile{}
+		files[filename] = f
+		return f, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, ok := files["out/board.gvp"]
+	if !ok {
+		t.Fatalf("Write did not write the gerbv project: %v", files)
+	}
+	want := `(gerbv-file-version! "2.0A")
+(define-layer! 5 (cons 'filename "board.gbl")(cons 'visible #t)(cons 'color #(19789 32639 50372)))
+(define-layer! 4 (cons 'filename "board.gtl")(cons 'visible #t)(cons 'color #(51400 13364 13364)))
+(define-layer! 3 (cons 'filename "board.gts")(cons 'visible #f)(cons 'color #(5140 41120 20560)))
+(define-layer! 2 (cons 'filename "board.gko")(cons 'visible #t)(cons 'color #(65535 65535 0)))
+(define-layer! 1 (cons 'filename "board-NPTH.drl")(cons 'visible #t)(cons 'color #(23130 51400 51400)))
+(define-layer! 0 (cons 'filename "board.drl")(cons 'visible #t)(cons 'color #(23130 51400 51400)))
+(define-layer! -1 (cons 'filename ".")(cons 'visible #f)(cons 'color #(0 0 0)))
+(set-render-type! 0)
+`
+	if got := f.String(); got != want {
+		t.Errorf("gerbv project =\n%v\nwant:\n%v", got, want)
+	}
+	for _, layer := range g.outputLayers() {
+		if _, ok := files[layer.Filename]; !ok {
+			t.Errorf("the project lists %v, which was not written", layer.Filename)
+		}
+	}
+}
+
+func TestWriteChanged_GerbvProject(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gerbv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	g := New("board", WithGerbvProject(true))
+	g.TopCopper().Add(Circle(Pt{1, 1}, 1))
+	path := filepath.Join(dir, "board.gvp")
+	written, err := g.WriteChanged(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(written, " "); !strings.HasSuffix(got, path) {
+		t.Errorf("WriteChanged wrote %v, want the layer and %v", got, path)
+	}
+	if written, err = g.WriteChanged(dir); err != nil || len(written) != 0 {
+		t.Errorf("unchanged WriteChanged wrote %v, %v", written, err)
+	}
+	os.Remove(path)
+	if written, err = g.WriteChanged(dir); err != nil || len(written) != 1 || written[0] != path {
+		t.Errorf("WriteChanged = %v, %v, want the missing project", written, err)
+	}
+}
@@ -0,0 +1,55 @@
+package gerber
+
+// Group is a collection of primitives that are placed together,
+// such as the output of a generator (e.g. PatchAntenna).
+type Group []Primitive
+
+// MBB returns the minimum bounding box of the group in millimeters.
+func (g Group) MBB() MBB {
+	var mbb MBB
+	for i, p := range g {
+		v := p.MBB()
+		if i == 0 {
+			mbb = v
+			continue
+		}
+		mbb.Join(&v)
+	}
+	return mbb
+}
+
+// Transform returns a transformed copy of the group, or an error if
+// any of its primitives does not implement Transformer.
+func (g Group) Transform(t Transform) (Group, error) {
+	result := make(Group, 0, len(g))
+	for _, p := range g {
+		tp, err := TransformPrimitive(p, t)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, tp)
+	}
+	return result, nil
+}
+
+// Place returns a copy of the group rotated counterclockwise by
+// degrees about its origin and then moved to at.
+func (g Group) Place(at Pt, degrees float64) (Group, error) {
+	return g.Transform(Rotate(degrees).Then(Translate(at[0], at[1])))
+}
+
+// AddMirrored adds copies of the primitives to the layer (like Add),
+// mirrored about the X axis (y = 0) of the design, so that the bottom
+// side features of a design symmetric about its X axis may be built
+// with the same top side coordinates. Text is mirrored too, so that it
+// reads correctly on the bottom of the fabricated board. It returns an
+// error (adding nothing) if any primitive does not implement
+// Transformer. See also Footprint.Flipped.
+func (l *Layer) AddMirrored(primitives ...Primitive) error {
+	mirrored, err := Group(primitives).Transform(MirrorX())
+	if err != nil {
+		return err
+	}
+	l.Add(mirrored...)
+	return nil
+}
@@ -0,0 +1,154 @@
+package gerber
+
+import (
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/gmlewis/go-gerber/gerber/internal/polyclip"
+)
+
+func init() {
+	registerPrimitive("hatchedRegion", func() Primitive { return &HatchedRegionT{} })
+}
+
+// HatchedRegionT represents a hatched fill of a closed contour: parallel
+// traces Pitch apart at Angle degrees (crossed with perpendicular
+// traces if Cross is set), bordered by a trace along the contour. It
+// satisfies the Primitive interface. Hatched copper stays flexible and
+// lighter than a solid pour, as needed for flexible PCBs and shields.
+// All traces are drawn within the contour.
+// All dimensions are in millimeters.
+type HatchedRegionT struct {
+	Contour []Pt `json:"contour"`
+	// Width is the width of the traces and Pitch their spacing.
+	Width float64 `json:"width"`
+	Pitch float64 `json:"pitch"`
+	Angle float64 `json:"angle,omitempty"`
+	Cross bool    `json:"cross,omitempty"`
+
+	// lines caches the traces of the hatch with the parameters in key.
+	lines []*LineT
+	key   string
+}
+
+// HatchedRegion returns a hatched fill of the closed contour with traces
+// of the given width and pitch at angle degrees, crossed if cross is
+// set.
+func HatchedRegion(contour []Pt, width, pitch, angle float64, cross bool) *HatchedRegionT {
+	return &HatchedRegionT{Contour: contour, Width: width, Pitch: pitch, Angle: angle, Cross: cross}
+}
+
+// Lines returns the traces of the hatch, followed by those of its
+// border.
+func (h *HatchedRegionT) Lines() []*LineT {
+	key := fmt.Sprint(h.Contour, h.Width, h.Pitch, h.Angle, h.Cross)
+	if h.lines != nil && h.key == key {
+		return h.lines
+	}
+	h.lines, h.key = nil, key
+	if len(h.Contour) < 3 || !(h.Width > 0) || !(h.Pitch > 0) {
+		return nil
+	}
+
+	// Keep the hatching (and border) within the contour, and hatch it
+	// in a frame rotated by -Angle.
+	inner := polyclip.OffsetPolygon(h.Contour, -0.5*h.Width)
+	sin, cos := math.Sincos(h.Angle * math.Pi / 180)
+	frame := make([]Pt, 0, len(inner))
+	var mbb MBB
+	for i, pt := range inner {
+		pt = Pt{pt[0]*cos + pt[1]*sin, pt[1]*cos - pt[0]*sin}
+		frame = append(frame, pt)
+		v := MBB{Min: pt, Max: pt}
+		if i == 0 {
+			mbb = v
+			continue
+		}
+		mbb.Join(&v)
+	}
+	inside := func(pt Pt) bool { return pointInPolygon(pt, frame) }
+	add := func(p1, p2 Pt) {
+		p1 = Pt{p1[0]*cos - p1[1]*sin, p1[0]*sin + p1[1]*cos}
+		p2 = Pt{p2[0]*cos - p2[1]*sin, p2[0]*sin + p2[1]*cos}
+		h.lines = append(h.lines, Line(p1[0], p1[1], p2[0], p2[1], CircleShape, h.Width))
+	}
+	for y := mbb.Min[1] + 0.5*h.Pitch; y < mbb.Max[1]; y += h.Pitch {
+		for _, run := range clipRuns(Pt{mbb.Min[0], y}, Pt{mbb.Max[0], y}, inside) {
+			add(run[0], run[1])
+		}
+	}
+	if h.Cross {
+		for x := mbb.Min[0] + 0.5*h.Pitch; x < mbb.Max[0]; x += h.Pitch {
+			for _, run := range clipRuns(Pt{x, mbb.Min[1]}, Pt{x, mbb.Max[1]}, inside) {
+				add(run[0], run[1])
+			}
+		}
+	}
+	for i := range inner {
+		p1, p2 := inner[i], inner[(i+1)%len(inner)]
+		h.lines = append(h.lines, Line(p1[0], p1[1], p2[0], p2[1], CircleShape, h.Width))
+	}
+	return h.lines
+}
+
+// WriteGerber writes the primitive to the Gerber file.
+func (h *HatchedRegionT) WriteGerber(w io.Writer, apertureIndex int) error {
+	if len(h.Contour) < 3 || !(h.Width > 0) || !(h.Pitch > 0) {
+		return fmt.Errorf("hatched region of %v points, width %v and pitch %v", len(h.Contour), fmtFloat(h.Width), fmtFloat(h.Pitch))
+	}
+	gw := toWriter(w)
+	gw.aperture(apertureIndex)
+	for _, l := range h.Lines() {
+		gw.move(l.P1[0], l.P1[1])
+		gw.draw(l.P2[0], l.P2[1])
+	}
+	return nil
+}
+
+// Aperture returns the round aperture of the traces.
+func (h *HatchedRegionT) Aperture() *Aperture {
+	return &Aperture{Shape: CircleShape, Size: h.Width}
+}
+
+// MBB returns the minimum bounding box in millimeters.
+func (h *HatchedRegionT) MBB() MBB {
+	var mbb MBB
+	for i, pt := range h.Contour {
+		v := MBB{Min: pt, Max: pt}
+		if i == 0 {
+			mbb = v
+			continue
+		}
+		mbb.Join(&v)
+	}
+	return mbb
+}
+
+// Contains reports whether pt lies within a trace of the hatch.
+func (h *HatchedRegionT) Contains(pt Pt) bool {
+	for _, l := range h.Lines() {
+		if l.Contains(pt) {
+			return true
+		}
+	}
+	return false
+}
+
+// Transform returns a transformed copy of the hatched region.
+func (h *HatchedRegionT) Transform(t Transform) Primitive {
+	contour := make([]Pt, 0, len(h.Contour))
+	for _, pt := range h.Contour {
+		contour = append(contour, t.Apply(pt))
+	}
+	s := t.scaleFactor()
+	angle := h.Angle + Degrees(t.rotation())
+	if t.det() < 0 {
+		angle = Degrees(t.rotation()) - h.Angle
+	}
+	return HatchedRegion(contour, s*h.Width, s*h.Pitch, NormalizeAngle(angle), h.Cross)
+}
+
+func (h *HatchedRegionT) String() string {
+	return fmt.Sprintf("HatchedRegion(%v points, %v, pitch=%v, %v deg)", len(h.Contour), fmtFloat(h.Width), fmtFloat(h.Pitch), fmtFloat(h.Angle))
+}
@@ -0,0 +1,116 @@
+package gerber
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestHatchedRegionT_Primitive(t *testing.T) {
+	var p Primitive = &HatchedRegionT{}
+	if p == nil {
+		// In actuality, this test won't compile if it isn't a Primitive.
+		t.Errorf("HatchedRegionT does not implement the Primitive interface")
+	}
+}
+
+func TestHatchedRegion_Lines(t *testing.T) {
+	square := []Pt{{0, 0}, {10, 0}, {10, 10}, {0, 10}}
+	tests := []struct {
+		name      string
+		angle     float64
+		cross     bool
+		wantLines int
+	}{
+		{name: "horizontal", wantLines: 10 + 4},
+		{name: "vertical", angle: 90, wantLines: 10 + 4},
+		{name: "cross", cross: true, wantLines: 20 + 4},
+		{name: "diagonal", angle: 45, wantLines: 14 + 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := HatchedRegion(square, 0.2, 1, tt.angle, tt.cross)
+			lines := h.Lines()
+			if len(lines) != tt.wantLines {
+				t.Fatalf("got %v lines, want %v", len(lines), tt.wantLines)
+			}
+			for _, l := range lines[:len(lines)-4] {
+				if !tt.cross {
+					angle := math.Atan2(l.P2[1]-l.P1[1], l.P2[0]-l.P1[0]) * 180 / math.Pi
+					if d := math.Mod(angle-tt.angle+360, 180); d > 1e-6 && d < 180-1e-6 {
+						t.Errorf("line %v is at %v degrees, want %v", l, angle, tt.angle)
+					}
+				}
+				for _, pt := range []Pt{l.P1, l.P2} {
+					if pt[0] < 0.1-1e-6 || pt[0] > 9.9+1e-6 || pt[1] < 0.1-1e-6 || pt[1] > 9.9+1e-6 {
+						t.Errorf("line %v leaves the contour", l)
+					}
+				}
+			}
+			if !h.Contains(Pt{0.1, 5}) || h.Contains(Pt{-0.1, 5}) {
+				t.Error("Contains does not match the border")
+			}
+			if got, want := h.MBB(), (MBB{Max: Pt{10, 10}}); !mbbNear(got, want, 1e-9) {
+				t.Errorf("MBB = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestHatchedRegion_WriteGerber(t *testing.T) {
+	g := New("test")
+	top := g.TopCopper()
+	top.Add(HatchedRegion([]Pt{{0, 0}, {4, 0}, {4, 4}, {0, 4}}, 0.2, 1, 30, true))
+	var buf bytes.Buffer
+	if err := top.WriteGerber(&buf); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	if strings.Count(got, "C,0.20000*%") != 1 {
+		t.Errorf("apertures of %v", got)
+	}
+	n := len(top.Primitives[0].(*HatchedRegionT).Lines())
+	if d := strings.Count(got, "D01*"); d != n {
+		t.Errorf("got %v draws, want %v", d, n)
+	}
+
+	if err := HatchedRegion(nil, 0.2, 1, 0, false).WriteGerber(&buf, 10); err == nil {
+		t.Error("WriteGerber of an empty region succeeded")
+	}
+}
+
+func TestHatchedRegion_Transform(t *testing.T) {
+	h := HatchedRegion([]Pt{{0, 0}, {6, 0}, {6, 4}, {0, 4}}, 0.2, 1, 30, false)
+	for _, xf := range []Transform{Rotate(30).Then(Translate(5, -3)), MirrorX(), Scale(2, 2)} {
+		th := h.Transform(xf).(*HatchedRegionT)
+		l, tl := h.Lines()[0], th.Lines()[0]
+		p1, p2 := xf.Apply(l.P1), xf.Apply(l.P2)
+		want := Degrees(math.Atan2(p2[1]-p1[1], p2[0]-p1[0]))
+		got := Degrees(math.Atan2(tl.P2[1]-tl.P1[1], tl.P2[0]-tl.P1[0]))
+		if d := math.Mod(got-want+360, 180); d > 1e-6 && d < 180-1e-6 {
+			t.Errorf("%v: hatch at %v degrees, want %v", xf, got, want)
+		}
+		if got, want := th.Width, xf.scaleFactor()*h.Width; math.Abs(got-want) > 1e-9 {
+			t.Errorf("%v: width = %v, want %v", xf, got, want)
+		}
+	}
+}
+
+func TestHatchedRegion_JSON(t *testing.T) {
+	g := New("test")
+	g.TopCopper().Add(HatchedRegion([]Pt{{0, 0}, {4, 0}, {4, 4}}, 0.2, 0.8, 45, true))
+	data, err := json.Marshal(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := &Gerber{}
+	if err := json.Unmarshal(data, got); err != nil {
+		t.Fatal(err)
+	}
+	h, ok := got.Layers[0].Primitives[0].(*HatchedRegionT)
+	if !ok || len(h.Contour) != 3 || h.Pitch != 0.8 || h.Angle != 45 || !h.Cross {
+		t.Fatalf("got primitive %#v", got.Layers[0].Primitives[0])
+	}
+}
@@ -0,0 +1,31 @@
+package gerber
+
+import "io"
+
+// WriteHook writes extra Gerber statements (e.g. custom attributes or
+// vendor-specific commands) to the output of a layer.
+type WriteHook func(w io.Writer, layer *Layer) error
+
+// OnHeader registers a hook that is run when the layer is written,
+// after the format, units, and file attribute statements but before
+// any apertures or primitives. Hooks are run in registration order.
+func (l *Layer) OnHeader(hook WriteHook) {
+	l.headerHooks = append(l.headerHooks, hook)
+}
+
+// OnFooter registers a hook that is run when the layer is written,
+// after all primitives but before the end-of-file (M02) statement.
+// Hooks are run in registration order.
+func (l *Layer) OnFooter(hook WriteHook) {
+	l.footerHooks = append(l.footerHooks, hook)
+}
+
+// runHooks runs the provided hooks, stopping at the first error.
+func (l *Layer) runHooks(w io.Writer, hooks []WriteHook) error {
+	for _, hook := range hooks {
+		if err := hook(w, l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
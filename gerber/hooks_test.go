@@ -0,0 +1,43 @@
+package gerber
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLayer_WriteHooks(t *testing.T) {
+	g := New("test")
+	top := g.TopCopper()
+	top.Add(Circle(Pt{0, 0}, 1))
+	top.OnHeader(func(w io.Writer, layer *Layer) error {
+		_, err := fmt.Fprintf(w, "G04 %v*\n", layer.Type)
+		return err
+	})
+	top.OnFooter(func(w io.Writer, layer *Layer) error {
+		_, err := io.WriteString(w, "G04 end*\n")
+		return err
+	})
+
+	var buf bytes.Buffer
+	if err := top.WriteGerber(&buf); err != nil {
+		t.Fatalf("WriteGerber: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if got, want := lines[2], "G04 TopCopper*"; got != want {
+		t.Errorf("header hook line = %q, want %q", got, want)
+	}
+	if got, want := lines[len(lines)-2], "G04 end*"; got != want {
+		t.Errorf("footer hook line = %q, want %q", got, want)
+	}
+
+	top.OnFooter(func(w io.Writer, layer *Layer) error {
+		return errors.New("boom")
+	})
+	if err := top.WriteGerber(&buf); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("WriteGerber error = %v, want boom", err)
+	}
+}
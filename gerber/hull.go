@@ -0,0 +1,169 @@
+package gerber
+
+import (
+	"math"
+	"sort"
+)
+
+// hullCircleSegments is the number of sides of the polygon that
+// approximates (circumscribes) round line caps and circles in hulls.
+const hullCircleSegments = 32
+
+// ConvexHull returns the convex hull, in counterclockwise order, of
+// the primitives (including their line widths). Round caps and circles
+// are approximated by circumscribed polygons, so the hull never cuts
+// into a primitive. Panelizers and renderers use it for tight fits.
+func ConvexHull(primitives ...Primitive) []Pt {
+	var pts []Pt
+	for _, p := range primitives {
+		pts = append(pts, hullPoints(p)...)
+	}
+	if len(pts) < 3 {
+		return pts
+	}
+	return convexHull(pts)
+}
+
+// ConvexHull returns the convex hull of the group (see ConvexHull).
+func (g Group) ConvexHull() []Pt {
+	return ConvexHull(g...)
+}
+
+// ConvexHull returns the convex hull of the layer (see ConvexHull).
+func (l *Layer) ConvexHull() []Pt {
+	return ConvexHull(l.Primitives...)
+}
+
+// hullPoints returns points whose convex hull contains p.
+func hullPoints(p Primitive) []Pt {
+	if c, ok := p.(*ClearT); ok {
+		p = c.Primitive
+	}
+	if line, ok := p.(*LineT); ok && line.Shape == RectShape {
+		h := 0.5 * line.Thickness
+		var pts []Pt
+		for _, c := range []Pt{line.P1, line.P2} {
+			pts = append(pts, Pt{c[0] - h, c[1] - h}, Pt{c[0] + h, c[1] - h}, Pt{c[0] + h, c[1] + h}, Pt{c[0] - h, c[1] + h})
+		}
+		return pts
+	}
+	o := outlineOf(p)
+	var pts []Pt
+	for _, poly := range o.polys {
+		pts = append(pts, poly...)
+	}
+	r := 1 / math.Cos(math.Pi/hullCircleSegments)
+	for _, s := range o.strokes {
+		for _, c := range []Pt{s.p1, s.p2} {
+			if s.r <= 0 {
+				pts = append(pts, c)
+				continue
+			}
+			for i := 0; i < hullCircleSegments; i++ {
+				pts = append(pts, PolarFrom(c, r*s.r, 360*float64(i)/hullCircleSegments))
+			}
+		}
+	}
+	return pts
+}
+
+// OrientedBox represents a rectangle of the given size, rotated
+// counterclockwise by Degrees about its center.
+type OrientedBox struct {
+	Center        Pt
+	Width, Height float64
+	Degrees       float64
+}
+
+// Area returns the area of the box.
+func (b OrientedBox) Area() float64 {
+	return b.Width * b.Height
+}
+
+// Corners returns the corners of the box in counterclockwise order.
+func (b OrientedBox) Corners() []Pt {
+	hw, hh := 0.5*b.Width, 0.5*b.Height
+	var pts []Pt
+	for _, pt := range []Pt{{-hw, -hh}, {hw, -hh}, {hw, hh}, {-hw, hh}} {
+		pt = RotatePt(pt, Pt{}, b.Degrees)
+		pts = append(pts, Pt{pt[0] + b.Center[0], pt[1] + b.Center[1]})
+	}
+	return pts
+}
+
+// OrientedMBB returns the minimum-area (possibly rotated) bounding box
+// of the primitives (see ConvexHull), with Degrees in [0, 90). Rotating
+// the primitives by -Degrees about the box's center makes the box their
+// (axis-aligned) minimum bounding box, e.g. to pack rotated boards.
+func OrientedMBB(primitives ...Primitive) OrientedBox {
+	hull := ConvexHull(primitives...)
+	if len(hull) == 0 {
+		return OrientedBox{}
+	}
+	var best OrientedBox
+	for i := range hull {
+		p1, p2 := hull[i], hull[(i+1)%len(hull)]
+		angle := math.Mod(math.Atan2(p2[1]-p1[1], p2[0]-p1[0])*180/math.Pi+360, 90)
+		if i > 0 && p1 == p2 {
+			continue
+		}
+		var mbb MBB
+		for j, pt := range hull {
+			v := RotatePt(pt, Pt{}, -angle)
+			if j == 0 {
+				mbb = MBB{Min: v, Max: v}
+				continue
+			}
+			mbb.Join(&MBB{Min: v, Max: v})
+		}
+		box := OrientedBox{
+			Center:  RotatePt(Pt{0.5 * (mbb.Min[0] + mbb.Max[0]), 0.5 * (mbb.Min[1] + mbb.Max[1])}, Pt{}, angle),
+			Width:   mbb.Max[0] - mbb.Min[0],
+			Height:  mbb.Max[1] - mbb.Min[1],
+			Degrees: angle,
+		}
+		if i == 0 || box.Area() < best.Area()-1e-12 {
+			best = box
+		}
+	}
+	return best
+}
+
+// OrientedMBB returns the oriented minimum bounding box of the group
+// (see OrientedMBB).
+func (g Group) OrientedMBB() OrientedBox {
+	return OrientedMBB(g...)
+}
+
+// OrientedMBB returns the oriented minimum bounding box of the layer
+// (see OrientedMBB).
+func (l *Layer) OrientedMBB() OrientedBox {
+	return OrientedMBB(l.Primitives...)
+}
+
+// convexHull returns the convex hull of pts in counterclockwise order
+// (Andrew's monotone chain).
+func convexHull(pts []Pt) []Pt {
+	pts = append([]Pt(nil), pts...)
+	sort.Slice(pts, func(i, j int) bool {
+		return pts[i][0] < pts[j][0] || (pts[i][0] == pts[j][0] && pts[i][1] < pts[j][1])
+	})
+	cross := func(o, a, b Pt) float64 {
+		return (a[0]-o[0])*(b[1]-o[1]) - (a[1]-o[1])*(b[0]-o[0])
+	}
+	var hull []Pt
+	for pass := 0; pass < 2; pass++ {
+		start := len(hull)
+		for _, pt := range pts {
+			for len(hull) >= start+2 && cross(hull[len(hull)-2], hull[len(hull)-1], pt) <= 0 {
+				hull = hull[:len(hull)-1]
+			}
+			hull = append(hull, pt)
+		}
+		hull = hull[:len(hull)-1]
+		for i, j := 0, len(pts)-1; i < j; i, j = i+1, j-1 {
+			pts[i], pts[j] = pts[j], pts[i]
+		}
+	}
+	return hull
+}
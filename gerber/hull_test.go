@@ -0,0 +1,54 @@
+package gerber
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gmlewis/go-gerber/gerber/internal/polyclip"
+)
+
+func TestConvexHull(t *testing.T) {
+	g := Group{
+		Polygon(Pt{}, true, []Pt{{0, 0}, {4, 0}, {4, 2}, {0, 2}}, 0),
+		Polygon(Pt{}, true, []Pt{{1, 1}, {2, 1}, {2, 1.5}}, 0), // inside
+		Line(4, 2, 6, 2, RectShape, 1),
+	}
+	got := g.ConvexHull()
+	want := []Pt{{0, 0}, {4, 0}, {6.5, 1.5}, {6.5, 2.5}, {3.5, 2.5}, {0, 2}}
+	if len(got) != len(want) {
+		t.Fatalf("ConvexHull = %v, want %v", got, want)
+	}
+	for i := range got {
+		if Distance(got[i], want[i]) > 1e-9 {
+			t.Errorf("ConvexHull[%v] = %v, want %v", i, got[i], want[i])
+		}
+	}
+	if area := polyclip.SignedArea(got); area <= 0 {
+		t.Errorf("ConvexHull is not counterclockwise (area %v)", area)
+	}
+
+	// Circles are circumscribed.
+	for _, pt := range ConvexHull(Circle(Pt{1, 1}, 2)) {
+		if d := Distance(pt, Pt{1, 1}); d < 1-1e-9 {
+			t.Errorf("circle hull point %v is inside the circle (%v)", pt, d)
+		}
+	}
+}
+
+func TestOrientedMBB(t *testing.T) {
+	// A 10x2 rectangle rotated by 30 degrees about (5, 5).
+	box := OrientedBox{Center: Pt{5, 5}, Width: 10, Height: 2, Degrees: 30}
+	layer := New("test").TopCopper()
+	layer.Add(Polygon(Pt{}, true, box.Corners(), 0))
+
+	got := layer.OrientedMBB()
+	if Distance(got.Center, box.Center) > 1e-9 || math.Abs(got.Area()-box.Area()) > 1e-9 || math.Abs(got.Degrees-30) > 1e-9 {
+		t.Errorf("OrientedMBB = %+v, want %+v", got, box)
+	}
+	if mbb := layer.MBB(); got.Area() >= (mbb.Max[0]-mbb.Min[0])*(mbb.Max[1]-mbb.Min[1]) {
+		t.Errorf("OrientedMBB area %v is not smaller than the MBB's", got.Area())
+	}
+	if got := OrientedMBB(); got != (OrientedBox{}) {
+		t.Errorf("OrientedMBB() = %+v, want empty", got)
+	}
+}
@@ -0,0 +1,207 @@
+package gerber
+
+import (
+	"errors"
+	"image"
+
+	"github.com/gmlewis/go-gerber/gerber/internal/polyclip"
+)
+
+// DitherMode selects how ImageRegions converts the gray levels of an
+// image to dark and light pixels.
+type DitherMode int
+
+const (
+	// ThresholdDither makes the pixels darker than the threshold dark.
+	ThresholdDither DitherMode = iota
+	// FloydSteinbergDither diffuses the error of thresholding each pixel
+	// to its neighbors, so that gray areas become patterns of dots.
+	FloydSteinbergDither
+)
+
+// ImageOpts represents the options used by ImageRegions.
+type ImageOpts struct {
+	// Width is the width (in millimeters) of the artwork; its height
+	// follows from the aspect ratio of the image.
+	Width float64
+	// Threshold is the luminance (from 0 for black to 1 for white)
+	// below which pixels are dark. Zero uses 0.5.
+	Threshold float64
+	Dither    DitherMode
+	// Invert draws the light pixels rather than the dark ones.
+	Invert bool
+}
+
+// ImageRegions converts an image (such as a logo) to regions whose
+// lower left corner is at origin, for artwork on a copper or
+// silkscreen layer. Transparent pixels are light. Adjacent dark pixels
+// are merged into a single polygon per connected area, with its holes
+// cut out by keyhole bridges, so that the number of primitives does not
+// grow with the resolution of the image.
+func ImageRegions(img image.Image, origin Pt, opts ImageOpts) ([]Primitive, error) {
+	b := img.Bounds()
+	if b.Empty() {
+		return nil, errors.New("image is empty")
+	}
+	if !(opts.Width > 0) {
+		return nil, errors.New("image width must be positive")
+	}
+	threshold := opts.Threshold
+	if threshold == 0 {
+		threshold = 0.5
+	}
+	w, h := b.Dx(), b.Dy()
+
+	// The pixels are indexed by column and row from the bottom.
+	lum := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			r, g, bl, a := img.At(b.Min.X+x, b.Max.Y-1-y).RGBA()
+			// Composite the (premultiplied) color over white.
+			lum[y*w+x] = (0.299*float64(r)+0.587*float64(g)+0.114*float64(bl))/0xffff + 1 - float64(a)/0xffff
+		}
+	}
+	dark := make([]bool, w*h)
+	for y := h - 1; y >= 0; y-- { // from the top of the image
+		for x := 0; x < w; x++ {
+			v := lum[y*w+x]
+			dark[y*w+x] = (v < threshold) != opts.Invert
+			if opts.Dither != FloydSteinbergDither {
+				continue
+			}
+			e := v
+			if v >= threshold {
+				e = v - 1
+			}
+			spread := func(dx, dy int, f float64) {
+				if x+dx >= 0 && x+dx < w && y-dy >= 0 {
+					lum[(y-dy)*w+x+dx] += e * f
+				}
+			}
+			spread(1, 0, 7.0/16)
+			spread(-1, 1, 3.0/16)
+			spread(0, 1, 5.0/16)
+			spread(1, 1, 1.0/16)
+		}
+	}
+
+	s := opts.Width / float64(w)
+	var outers, holes [][]Pt
+	for _, loop := range traceCells(dark, w, h) {
+		pts := make([]Pt, 0, len(loop))
+		for _, v := range loop {
+			pts = append(pts, Pt{origin[0] + s*float64(v[0]), origin[1] + s*float64(v[1])})
+		}
+		if polyclip.SignedArea(pts) > 0 {
+			outers = append(outers, pts)
+		} else {
+			holes = append(holes, pts)
+		}
+	}
+
+	// Each hole belongs to the smallest outer contour around it, tested
+	// at a point of the light cell to the right of its first edge.
+	cut := make([][][]Pt, len(outers))
+	for _, hole := range holes {
+		p1, p2 := hole[0], hole[1]
+		d := Pt{(p2[0] - p1[0]) / Distance(p1, p2), (p2[1] - p1[1]) / Distance(p1, p2)}
+		pt := Pt{0.5*(p1[0]+p2[0]) + 0.5*s*d[1], 0.5*(p1[1]+p2[1]) - 0.5*s*d[0]}
+		best, bestArea := -1, 0.0
+		for i, outer := range outers {
+			if area := polyclip.SignedArea(outer); pointInPolygon(pt, outer) && (best < 0 || area < bestArea) {
+				best, bestArea = i, area
+			}
+		}
+		if best >= 0 {
+			cut[best] = append(cut[best], hole)
+		}
+	}
+	var result []Primitive
+	for i, outer := range outers {
+		result = append(result, Polygon(Pt{}, true, cutHoles(outer, cut[i]), 0))
+	}
+	return result, nil
+}
+
+// traceCells returns the boundaries (as grid vertices) of the
+// connected areas of set cells of a w by h grid (indexed by column and
+// row), counterclockwise around the areas and clockwise around their
+// holes. Cells touching only at a corner are not connected.
+func traceCells(set []bool, w, h int) [][][2]int {
+	at := func(x, y int) bool {
+		return x >= 0 && x < w && y >= 0 && y < h && set[y*w+x]
+	}
+	// Each boundary edge runs with its set cell on the left.
+	type edge struct{ from, to [2]int }
+	out := map[[2]int][]int{}
+	var edges []edge
+	add := func(x1, y1, x2, y2 int) {
+		out[[2]int{x1, y1}] = append(out[[2]int{x1, y1}], len(edges))
+		edges = append(edges, edge{[2]int{x1, y1}, [2]int{x2, y2}})
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if !at(x, y) {
+				continue
+			}
+			if !at(x, y-1) {
+				add(x, y, x+1, y)
+			}
+			if !at(x+1, y) {
+				add(x+1, y, x+1, y+1)
+			}
+			if !at(x, y+1) {
+				add(x+1, y+1, x, y+1)
+			}
+			if !at(x-1, y) {
+				add(x, y+1, x, y)
+			}
+		}
+	}
+
+	used := make([]bool, len(edges))
+	var loops [][][2]int
+	for start := range edges {
+		if used[start] {
+			continue
+		}
+		var loop [][2]int
+		for e := start; !used[e]; {
+			used[e] = true
+			loop = append(loop, edges[e].from)
+			// At a corner shared by two diagonal cells, turning left
+			// keeps to the cell being traced.
+			next := -1
+			for _, f := range out[edges[e].to] {
+				if used[f] && f != start {
+					continue
+				}
+				d1 := [2]int{edges[e].to[0] - edges[e].from[0], edges[e].to[1] - edges[e].from[1]}
+				d2 := [2]int{edges[f].to[0] - edges[f].from[0], edges[f].to[1] - edges[f].from[1]}
+				if next < 0 || d1[0]*d2[1]-d1[1]*d2[0] > 0 {
+					next = f
+				}
+			}
+			if next < 0 {
+				break
+			}
+			e = next
+		}
+		loops = append(loops, simplifyGrid(loop))
+	}
+	return loops
+}
+
+// simplifyGrid removes the vertices of a closed grid polygon that lie
+// between collinear edges.
+func simplifyGrid(loop [][2]int) [][2]int {
+	n := len(loop)
+	var result [][2]int
+	for i, v := range loop {
+		prev, next := loop[(i+n-1)%n], loop[(i+1)%n]
+		if (v[0]-prev[0])*(next[1]-v[1])-(v[1]-prev[1])*(next[0]-v[0]) != 0 {
+			result = append(result, v)
+		}
+	}
+	return result
+}
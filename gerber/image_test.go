@@ -0,0 +1,136 @@
+package gerber
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"strings"
+	"testing"
+)
+
+// pixelImage returns a black and white image of the rows (from the
+// top), in which '#' is black.
+func pixelImage(rows ...string) image.Image {
+	img := image.NewGray(image.Rect(0, 0, len(rows[0]), len(rows)))
+	for y, row := range rows {
+		for x, c := range row {
+			if c == '#' {
+				img.SetGray(x, y, color.Gray{})
+			} else {
+				img.SetGray(x, y, color.Gray{Y: 255})
+			}
+		}
+	}
+	return img
+}
+
+func TestImageRegions(t *testing.T) {
+	tests := []struct {
+		name      string
+		img       image.Image
+		opts      ImageOpts
+		wantPolys int
+		wantArea  float64
+	}{
+		{
+			name:      "ring",
+			img:       pixelImage("###", "#.#", "###"),
+			opts:      ImageOpts{Width: 3},
+			wantPolys: 1,
+			wantArea:  8,
+		},
+		{
+			name:      "diagonal",
+			img:       pixelImage("#..", ".#.", "..#"),
+			opts:      ImageOpts{Width: 6},
+			wantPolys: 3,
+			wantArea:  12,
+		},
+		{
+			name:      "inverted",
+			img:       pixelImage("###", "#.#", "###"),
+			opts:      ImageOpts{Width: 3, Invert: true},
+			wantPolys: 1,
+			wantArea:  1,
+		},
+		{
+			name:      "island",
+			img:       pixelImage("#####", "#...#", "#.#.#", "#...#", "#####"),
+			opts:      ImageOpts{Width: 5},
+			wantPolys: 2,
+			wantArea:  17,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ImageRegions(tt.img, Pt{10, 20}, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != tt.wantPolys {
+				t.Fatalf("got %v polygons, want %v", len(got), tt.wantPolys)
+			}
+			var area float64
+			for _, p := range got {
+				area += math.Abs(PolygonArea(p.(*PolygonT).Points))
+				if mbb := p.MBB(); mbb.Min[0] < 10-1e-9 || mbb.Min[1] < 20-1e-9 || mbb.Max[0] > 10+tt.opts.Width+1e-9 {
+					t.Errorf("polygon %v is outside of the image", p)
+				}
+			}
+			if math.Abs(area-tt.wantArea) > 1e-9 {
+				t.Errorf("area = %v, want %v", area, tt.wantArea)
+			}
+		})
+	}
+}
+
+func TestImageRegions_Orientation(t *testing.T) {
+	// The black pixel at the top left of the image is drawn at the top
+	// left of the artwork.
+	got, err := ImageRegions(pixelImage("#.", ".."), Pt{}, ImageOpts{Width: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || !mbbNear(got[0].MBB(), MBB{Min: Pt{0, 1}, Max: Pt{1, 2}}, 1e-9) {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestImageRegions_Dither(t *testing.T) {
+	gray := image.NewGray(image.Rect(0, 0, 20, 20))
+	for i := range gray.Pix {
+		gray.Pix[i] = 128
+	}
+	for _, mode := range []DitherMode{ThresholdDither, FloydSteinbergDither} {
+		got, err := ImageRegions(gray, Pt{}, ImageOpts{Width: 20, Dither: mode})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var area float64
+		for _, p := range got {
+			area += math.Abs(PolygonArea(p.(*PolygonT).Points))
+		}
+		want := 0.0 // 50% gray is just light enough
+		if mode == FloydSteinbergDither {
+			want = 200 // about half of the pixels
+		}
+		if math.Abs(area-want) > 20 {
+			t.Errorf("mode %v: dark area = %v, want about %v", mode, area, want)
+		}
+	}
+}
+
+func TestImageRegions_Errors(t *testing.T) {
+	for _, tt := range []struct {
+		img     image.Image
+		width   float64
+		wantErr string
+	}{
+		{img: image.NewGray(image.Rect(0, 0, 0, 0)), width: 1, wantErr: "empty"},
+		{img: pixelImage("#"), wantErr: "width"},
+	} {
+		if _, err := ImageRegions(tt.img, Pt{}, ImageOpts{Width: tt.width}); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("got error %v, want %q", err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package gerber
+
+import "math"
+
+// CoilShape represents the shape of a planar spiral coil.
+type CoilShape int
+
+const (
+	// SquareCoil is a square spiral.
+	SquareCoil CoilShape = iota
+	// HexagonalCoil is a hexagonal spiral.
+	HexagonalCoil
+	// OctagonalCoil is an octagonal spiral.
+	OctagonalCoil
+	// CircularCoil is a circular spiral (e.g. one made of arcs).
+	CircularCoil
+)
+
+// mu0 is the permeability of free space in H/m.
+const mu0 = 4e-7 * math.Pi
+
+// coilCoefficients are the modified Wheeler (k1, k2) and current sheet
+// (c1..c4) coefficients from Mohan et al., "Simple Accurate Expressions
+// for Planar Spiral Inductances", IEEE JSSC, 1999. Circular coils use
+// the Wheeler coefficients of an octagon.
+var coilCoefficients = map[CoilShape]struct{ k1, k2, c1, c2, c3, c4 float64 }{
+	SquareCoil:    {2.34, 2.75, 1.27, 2.07, 0.18, 0.13},
+	HexagonalCoil: {2.33, 3.82, 1.09, 2.23, 0.00, 0.17},
+	OctagonalCoil: {2.25, 3.55, 1.07, 2.29, 0.00, 0.19},
+	CircularCoil:  {2.25, 3.55, 1.00, 2.46, 0.00, 0.20},
+}
+
+// SpiralCoil describes the geometry of a planar spiral coil for
+// inductance estimation. All dimensions are in millimeters.
+type SpiralCoil struct {
+	Shape CoilShape
+	// Turns is the number of turns on each layer.
+	Turns float64
+	// OuterDiameter and InnerDiameter are measured to the
+	// centerlines of the outermost and innermost turns.
+	OuterDiameter float64
+	InnerDiameter float64
+	// Layers is the number of identical, series-connected layers
+	// (0 is treated as 1).
+	Layers int
+	// Coupling is the mutual coupling coefficient (0 to 1)
+	// between each pair of layers.
+	Coupling float64
+}
+
+// SpiralFromGeometry measures a spiral coil with the given shape and
+// number of turns per layer from the centerlines of its traces (lines
+// and arcs) about center. As in Mohan et al., the diameters of square
+// and polygonal coils are measured across the flats (so a square coil's
+// outer diameter is its outer side length): each line contributes its
+// distance from center, and is ignored unless the foot of the
+// perpendicular from center falls within it (as for the leads in and
+// out of the coil). Arcs contribute their distances from center. Other
+// primitives are ignored.
+func SpiralFromGeometry(shape CoilShape, center Pt, turns float64, primitives ...Primitive) SpiralCoil {
+	rMin, rMax := math.Inf(1), 0.0
+	update := func(r float64) {
+		rMin, rMax = math.Min(rMin, r), math.Max(rMax, r)
+	}
+	flat := func(p1, p2 Pt) {
+		dx, dy := p2[0]-p1[0], p2[1]-p1[1]
+		l2 := dx*dx + dy*dy
+		if l2 == 0 {
+			return
+		}
+		if t := ((center[0]-p1[0])*dx + (center[1]-p1[1])*dy) / l2; t > 0 && t < 1 {
+			update(Distance(center, Pt{p1[0] + t*dx, p1[1] + t*dy}))
+		}
+	}
+	for _, p := range primitives {
+		switch v := p.(type) {
+		case *LineT:
+			flat(v.P1, v.P2)
+		case *ArcT:
+			for _, s := range outlineOf(v).strokes {
+				update(Distance(center, s.p1))
+				update(Distance(center, s.p2))
+			}
+		}
+	}
+	if rMax == 0 {
+		rMin = 0
+	}
+	return SpiralCoil{Shape: shape, Turns: turns, OuterDiameter: 2 * rMax, InnerDiameter: 2 * rMin}
+}
+
+// WheelerInductance returns the approximate inductance of the coil in
+// henries using the modified Wheeler formula.
+func (c SpiralCoil) WheelerInductance() float64 {
+	k := coilCoefficients[c.Shape]
+	davg, rho := c.fill()
+	l := k.k1 * mu0 * c.Turns * c.Turns * davg / (1 + k.k2*rho)
+	return c.stack(l)
+}
+
+// CurrentSheetInductance returns the approximate inductance of the coil
+// in henries using the current sheet approximation.
+func (c SpiralCoil) CurrentSheetInductance() float64 {
+	k := coilCoefficients[c.Shape]
+	davg, rho := c.fill()
+	if rho <= 0 {
+		return 0
+	}
+	l := 0.5 * mu0 * c.Turns * c.Turns * davg * k.c1 * (math.Log(k.c2/rho) + k.c3*rho + k.c4*rho*rho)
+	return c.stack(l)
+}
+
+// fill returns the average diameter (in meters) and fill ratio of the coil.
+func (c SpiralCoil) fill() (davg, rho float64) {
+	dout, din := c.OuterDiameter*1e-3, c.InnerDiameter*1e-3
+	if dout+din == 0 {
+		return 0, 0
+	}
+	return 0.5 * (dout + din), (dout - din) / (dout + din)
+}
+
+// stack returns the total inductance of the series-connected layers,
+// each with self inductance l.
+func (c SpiralCoil) stack(l float64) float64 {
+	n := float64(c.Layers)
+	if n < 1 {
+		n = 1
+	}
+	return n*l + n*(n-1)*c.Coupling*l
+}
@@ -0,0 +1,65 @@
+package gerber
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSpiralCoil_Inductance(t *testing.T) {
+	coil := SpiralCoil{Shape: SquareCoil, Turns: 5, OuterDiameter: 10, InnerDiameter: 5}
+	// davg = 7.5mm, rho = 1/3.
+	wantWheeler := 2.34 * mu0 * 25 * 7.5e-3 / (1 + 2.75/3)
+	wantSheet := 0.5 * mu0 * 25 * 7.5e-3 * 1.27 * (math.Log(2.07*3) + 0.18/3 + 0.13/9)
+
+	tests := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{name: "Wheeler", got: coil.WheelerInductance(), want: wantWheeler},
+		{name: "current sheet", got: coil.CurrentSheetInductance(), want: wantSheet},
+		{
+			name: "two coupled layers",
+			got:  SpiralCoil{Shape: SquareCoil, Turns: 5, OuterDiameter: 10, InnerDiameter: 5, Layers: 2, Coupling: 0.5}.WheelerInductance(),
+			want: 3 * wantWheeler,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if math.Abs(tt.got-tt.want) > 1e-15 {
+				t.Errorf("got %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpiralFromGeometry(t *testing.T) {
+	got := SpiralFromGeometry(CircularCoil, Pt{0, 0}, 1,
+		Arc(Pt{0, 0}, 5, CircleShape, 1, 1, 0, 180, 0.2),
+		Arc(Pt{0, 0}, 10, CircleShape, 1, 1, 180, 360, 0.2),
+		Circle(Pt{100, 100}, 1),
+	)
+	if math.Abs(got.OuterDiameter-20) > 1e-9 || math.Abs(got.InnerDiameter-10) > 1e-9 {
+		t.Errorf("SpiralFromGeometry = %+v, want diameters 20 and 10", got)
+	}
+}
+
+func TestSpiralFromGeometry_Square(t *testing.T) {
+	// Two turns at a 1mm pitch, from a 10mm to a 6mm square, with a
+	// lead toward the center.
+	pts := []Pt{{-5, -5}, {5, -5}, {5, 5}, {-4, 5}, {-4, -4}, {4, -4}, {4, 4}, {-3, 4}, {-3, -3}, {-1, -1}}
+	var lines []Primitive
+	for i := 1; i < len(pts); i++ {
+		lines = append(lines, Line(pts[i-1][0], pts[i-1][1], pts[i][0], pts[i][1], CircleShape, 0.2))
+	}
+	got := SpiralFromGeometry(SquareCoil, Pt{0, 0}, 2, lines...)
+	if math.Abs(got.OuterDiameter-10) > 1e-9 || math.Abs(got.InnerDiameter-6) > 1e-9 {
+		t.Errorf("SpiralFromGeometry = %+v, want diameters 10 and 6", got)
+	}
+	// Mohan's modified Wheeler value: davg = 8mm, rho = 1/4.
+	want := 2.34 * mu0 * 4 * 8e-3 / (1 + 2.75/4)
+	if l := got.WheelerInductance(); math.Abs(l-want) > 1e-15 {
+		t.Errorf("WheelerInductance = %v, want %v", l, want)
+	}
+}
@@ -0,0 +1,110 @@
+package polyclip
+
+import "math"
+
+// Join is the way the offset edges of a path meet at its corners.
+type Join int
+
+const (
+	// MiterJoin extends the offset edges to meet (beveling only where
+	// the path folds back on itself).
+	MiterJoin Join = iota
+	// BevelJoin miters the corners that turn by no more than 120
+	// degrees and bevels the others.
+	BevelJoin
+	// RoundJoin rounds the corners on the outside of each turn and
+	// miters the others.
+	RoundJoin
+)
+
+// Corner is a corner of an offset path: the point In where the offset
+// of the edge before Vertex ends and the point Out where the offset of
+// the edge after it starts. They are the same point when mitered; a
+// Round corner is an arc about Vertex from In to Out.
+type Corner struct {
+	Vertex, In, Out Pt
+	Round           bool
+}
+
+// Corners returns the corners of the path (which must not repeat
+// points) offset by d to its left (or, if d is negative, to its
+// right). The ends of an open path are offset square to its first and
+// last edges. The arcs of Round corners run clockwise if d is
+// positive.
+func Corners(pts []Pt, d float64, closed bool, join Join) []Corner {
+	n := len(pts)
+	normal := func(i int) Pt { // the left normal of the edge from pts[i]
+		p, q := pts[i], pts[(i+1)%n]
+		l := math.Hypot(q[0]-p[0], q[1]-p[1])
+		return Pt{-(q[1] - p[1]) / l, (q[0] - p[0]) / l}
+	}
+	at := func(v, n Pt, s float64) Pt { return Pt{v[0] + s*n[0], v[1] + s*n[1]} }
+
+	corners := make([]Corner, 0, n)
+	for i, v := range pts {
+		var n1, n2 Pt
+		switch {
+		case !closed && i == 0:
+			n1 = normal(0)
+			n2 = n1
+		case !closed && i == n-1:
+			n1 = normal(n - 2)
+			n2 = n1
+		default:
+			n1, n2 = normal((i+n-1)%n), normal(i)
+		}
+		// c is 1 plus the cosine of the turn: 2 cos² of half the turn.
+		c := 1 + dot(n1, n2)
+		prev, next := pts[(i+n-1)%n], pts[(i+1)%n]
+		turn := cross(Pt{v[0] - prev[0], v[1] - prev[1]}, Pt{next[0] - v[0], next[1] - v[1]})
+		switch {
+		case join == RoundJoin && (closed || i > 0 && i < n-1) && math.Abs(turn) > 1e-12 && turn*d < 0:
+			corners = append(corners, Corner{Vertex: v, In: at(v, n1, d), Out: at(v, n2, d), Round: true})
+			continue
+		case join == BevelJoin && c < 0.5:
+			corners = append(corners, Corner{Vertex: v, In: at(v, n1, d), Out: at(v, n2, d)})
+			continue
+		case c < 1e-6: // the edges fold back on themselves
+			corners = append(corners, Corner{Vertex: v, In: at(v, n1, d), Out: at(v, n1, d)})
+			continue
+		}
+		miter := at(v, Pt{n1[0] + n2[0], n1[1] + n2[1]}, d/c)
+		corners = append(corners, Corner{Vertex: v, In: miter, Out: miter})
+	}
+	return corners
+}
+
+// OffsetPolygon returns the closed polygon (without repeated points)
+// with its edges moved outward by delta (inward, if negative) and
+// mitered at its corners.
+func OffsetPolygon(pts []Pt, delta float64) []Pt {
+	pts = Clean(pts)
+	if len(pts) < 3 || delta == 0 {
+		return pts
+	}
+	// Outward is to the right of a counterclockwise polygon.
+	d := delta
+	if SignedArea(pts) > 0 {
+		d = -delta
+	}
+	return points(Corners(pts, d, true, MiterJoin))
+}
+
+// OffsetPath returns the path (which must not repeat points) offset by
+// d to its left (or, if d is negative, to its right), with mitered
+// corners (beveled where the path turns by more than 120 degrees).
+func OffsetPath(pts []Pt, d float64, closed bool) []Pt {
+	return points(Corners(pts, d, closed, BevelJoin))
+}
+
+// points returns the polygon through the corners.
+func points(corners []Corner) []Pt {
+	result := make([]Pt, 0, len(corners))
+	for _, k := range corners {
+		result = append(result, k.In)
+		if k.Out != k.In {
+			result = append(result, k.Out)
+		}
+	}
+	return result
+}
@@ -0,0 +1,422 @@
+// Package polyclip performs boolean operations (union, intersection,
+// and difference) on filled shapes and offsets polygons and paths. It
+// is the geometry core shared by package gerber and package geometry
+// (which cannot be imported by package gerber).
+// All dimensions are in millimeters.
+package polyclip
+
+import (
+	"math"
+	"sort"
+
+	"github.com/gmlewis/go3d/float64/vec2"
+)
+
+// Pt is a point, as in package gerber.
+type Pt = vec2.T
+
+// Shape is a filled area bounded by closed contours, with even-odd
+// fill semantics: a contour nested within an odd number of others is
+// a hole. The shapes returned by this package have non-crossing
+// contours, wound counterclockwise around the filled area (so that
+// holes are wound clockwise).
+type Shape [][]Pt
+
+// snap is the grid (in mm) to which the vertices of the results are
+// rounded, so that coincident points computed differently compare
+// equal.
+const snap = 1e-9
+
+// sideEps is the distance (in mm) either side of an edge at which its
+// neighborhood is sampled to decide whether it bounds the result.
+const sideEps = 1e-6
+
+// arcTolerance is the maximum error (in mm) of the polygons that
+// approximate round corners for Offset.
+const arcTolerance = 0.001
+
+// Union returns the area covered by any of the shapes.
+func Union(shapes ...Shape) Shape {
+	return combine(shapes, func(in []bool) bool {
+		for _, b := range in {
+			if b {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Intersection returns the area covered by all of the shapes.
+func Intersection(shapes ...Shape) Shape {
+	if len(shapes) == 0 {
+		return nil
+	}
+	return combine(shapes, func(in []bool) bool {
+		for _, b := range in {
+			if !b {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// Difference returns the area of s that is not covered by any of the
+// others.
+func Difference(s Shape, others ...Shape) Shape {
+	return combine(append([]Shape{s}, others...), func(in []bool) bool {
+		if !in[0] {
+			return false
+		}
+		for _, b := range in[1:] {
+			if b {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// Offset returns the shape grown by delta (shrunk, if negative), with
+// rounded corners where it grows: the points within delta of s, or the
+// points of s farther than -delta from its boundary.
+func Offset(s Shape, delta float64) Shape {
+	if delta == 0 {
+		return Union(s)
+	}
+	r := math.Abs(delta)
+	var strokes []Shape
+	for _, c := range s {
+		c = Clean(c)
+		for i, p := range c {
+			q := c[(i+1)%len(c)]
+			strokes = append(strokes, Shape{Capsule(p, q, r, arcTolerance)})
+		}
+	}
+	if delta > 0 {
+		return Union(append([]Shape{s}, strokes...)...)
+	}
+	return Difference(s, strokes...)
+}
+
+// Contains reports whether pt lies within the shape.
+func (s Shape) Contains(pt Pt) bool {
+	var in bool
+	for _, c := range s {
+		for i, j := 0, len(c)-1; i < len(c); j, i = i, i+1 {
+			a, b := c[i], c[j]
+			if (a[1] > pt[1]) != (b[1] > pt[1]) && pt[0] < (b[0]-a[0])*(pt[1]-a[1])/(b[1]-a[1])+a[0] {
+				in = !in
+			}
+		}
+	}
+	return in
+}
+
+// Area returns the area of the shape.
+func (s Shape) Area() float64 {
+	var area float64
+	for _, c := range Union(s) {
+		area += SignedArea(c)
+	}
+	return area
+}
+
+// Bounds returns the minimum bounding box of the shape.
+func (s Shape) Bounds() vec2.Rect {
+	var mbb vec2.Rect
+	first := true
+	for _, c := range s {
+		for _, p := range c {
+			if first {
+				mbb, first = vec2.Rect{Min: p, Max: p}, false
+				continue
+			}
+			mbb.Min = Pt{math.Min(mbb.Min[0], p[0]), math.Min(mbb.Min[1], p[1])}
+			mbb.Max = Pt{math.Max(mbb.Max[0], p[0]), math.Max(mbb.Max[1], p[1])}
+		}
+	}
+	return mbb
+}
+
+// Capsule returns the polygon (circumscribing its round caps, within
+// tolerance of them) of the segment from p to q stroked with a round
+// pen of radius r: a circumscribed circle if p == q.
+func Capsule(p, q Pt, r, tolerance float64) []Pt {
+	if p == q {
+		pts := arc(nil, p, r, 0, 2*math.Pi, tolerance)
+		return pts[:len(pts)-1]
+	}
+	a := math.Atan2(q[1]-p[1], q[0]-p[0])
+	// The cap about q runs from the right of the segment to its left,
+	// then the cap about p from its left back to its right.
+	pts := arc(nil, q, r, a-0.5*math.Pi, math.Pi, tolerance)
+	return arc(pts, p, r, a+0.5*math.Pi, math.Pi, tolerance)
+}
+
+// arc appends to pts the counterclockwise arc of radius r about center
+// from angle a0 (in radians) through sweep, approximated by edges
+// tangent to the arc (within tolerance of it).
+func arc(pts []Pt, center Pt, r, a0, sweep, tolerance float64) []Pt {
+	at := func(radius, angle float64) Pt {
+		s, c := math.Sincos(angle)
+		return Pt{center[0] + radius*c, center[1] + radius*s}
+	}
+	n := int(math.Ceil(sweep / (2 * math.Acos(r/(r+tolerance)))))
+	if n < 4 {
+		n = 4
+	}
+	h := 0.5 * sweep / float64(n)
+	pts = append(pts, at(r, a0))
+	for i := 0; i < n; i++ {
+		pts = append(pts, at(r/math.Cos(h), a0+float64(2*i+1)*h))
+	}
+	return append(pts, at(r, a0+sweep))
+}
+
+// edge is a directed edge of a contour.
+type edge struct {
+	p, q Pt
+}
+
+// combine returns the boundary of the area for which inside (given
+// whether a point lies within each of the shapes) is true.
+func combine(shapes []Shape, inside func(in []bool) bool) Shape {
+	var edges []edge
+	mbbs := make([]vec2.Rect, len(shapes))
+	for i, s := range shapes {
+		mbbs[i] = s.Bounds()
+		for _, c := range s {
+			c = Clean(c)
+			if len(c) < 3 {
+				continue
+			}
+			for j, p := range c {
+				edges = append(edges, edge{p, c[(j+1)%len(c)]})
+			}
+		}
+	}
+
+	in := make([]bool, len(shapes))
+	filled := func(pt Pt) bool {
+		for i, s := range shapes {
+			m := mbbs[i]
+			in[i] = pt[0] >= m.Min[0] && pt[0] <= m.Max[0] && pt[1] >= m.Min[1] && pt[1] <= m.Max[1] && s.Contains(pt)
+		}
+		return inside(in)
+	}
+
+	// Keep each piece of an edge (once) that has the result on one side
+	// only, directed so that the result is on its left.
+	seen := map[[2]key]bool{}
+	var kept []edge
+	for _, e := range splitEdges(edges) {
+		kp, kq := keyOf(e.p), keyOf(e.q)
+		k := [2]key{kp, kq}
+		if kq.less(kp) {
+			k = [2]key{kq, kp}
+		}
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		d := Pt{e.q[0] - e.p[0], e.q[1] - e.p[1]}
+		l := math.Hypot(d[0], d[1])
+		mid := Pt{0.5 * (e.p[0] + e.q[0]), 0.5 * (e.p[1] + e.q[1])}
+		n := Pt{-d[1] / l * sideEps, d[0] / l * sideEps}
+		left, right := filled(Pt{mid[0] + n[0], mid[1] + n[1]}), filled(Pt{mid[0] - n[0], mid[1] - n[1]})
+		switch {
+		case left && !right:
+			kept = append(kept, e)
+		case right && !left:
+			kept = append(kept, edge{e.q, e.p})
+		}
+	}
+	return chain(kept)
+}
+
+// key is a point rounded to the snap grid.
+type key [2]int64
+
+func keyOf(p Pt) key {
+	return key{int64(math.Round(p[0] / snap)), int64(math.Round(p[1] / snap))}
+}
+
+func (k key) less(o key) bool {
+	return k[0] < o[0] || k[0] == o[0] && k[1] < o[1]
+}
+
+func (k key) pt() Pt {
+	return Pt{float64(k[0]) * snap, float64(k[1]) * snap}
+}
+
+// splitEdges returns the edges split at every point where they cross
+// or touch another edge, with their endpoints snapped to the grid.
+func splitEdges(edges []edge) []edge {
+	cuts := make([][]float64, len(edges))
+	order := make([]int, len(edges))
+	for i := range order {
+		order[i] = i
+	}
+	minX := func(e edge) float64 { return math.Min(e.p[0], e.q[0]) }
+	sort.Slice(order, func(a, b int) bool { return minX(edges[order[a]]) < minX(edges[order[b]]) })
+	for a, i := range order {
+		ei := edges[i]
+		maxX := math.Max(ei.p[0], ei.q[0])
+		for _, j := range order[a+1:] {
+			ej := edges[j]
+			if minX(ej) > maxX {
+				break
+			}
+			if math.Max(ei.p[1], ei.q[1]) < math.Min(ej.p[1], ej.q[1]) || math.Max(ej.p[1], ej.q[1]) < math.Min(ei.p[1], ei.q[1]) {
+				continue
+			}
+			ti, tj := intersections(ei, ej)
+			cuts[i] = append(cuts[i], ti...)
+			cuts[j] = append(cuts[j], tj...)
+		}
+	}
+
+	var result []edge
+	for i, e := range edges {
+		ts := append([]float64{0, 1}, cuts[i]...)
+		sort.Float64s(ts)
+		prev := keyOf(e.p)
+		for _, t := range ts[1:] {
+			k := keyOf(Pt{e.p[0] + t*(e.q[0]-e.p[0]), e.p[1] + t*(e.q[1]-e.p[1])})
+			if t == 1 {
+				k = keyOf(e.q)
+			}
+			if k == prev {
+				continue
+			}
+			result = append(result, edge{prev.pt(), k.pt()})
+			prev = k
+		}
+	}
+	return result
+}
+
+// intersections returns the parameters (along each edge, from 0 to 1)
+// of the points where two edges cross or touch, including the ends of
+// the overlap of collinear edges.
+func intersections(a, b edge) (ta, tb []float64) {
+	r := Pt{a.q[0] - a.p[0], a.q[1] - a.p[1]}
+	s := Pt{b.q[0] - b.p[0], b.q[1] - b.p[1]}
+	qp := Pt{b.p[0] - a.p[0], b.p[1] - a.p[1]}
+	d := cross(r, s)
+	rr, ss := dot(r, r), dot(s, s)
+	if math.Abs(d) > 1e-12*math.Sqrt(rr*ss) {
+		t, u := cross(qp, s)/d, cross(qp, r)/d
+		const eps = 1e-12
+		if t >= -eps && t <= 1+eps && u >= -eps && u <= 1+eps {
+			return []float64{clamp(t)}, []float64{clamp(u)}
+		}
+		return nil, nil
+	}
+	// Parallel: only collinear edges meet.
+	if math.Abs(cross(qp, r)) > snap*math.Sqrt(rr) {
+		return nil, nil
+	}
+	for _, pt := range []Pt{b.p, b.q} {
+		if t := dot(Pt{pt[0] - a.p[0], pt[1] - a.p[1]}, r) / rr; t > 0 && t < 1 {
+			ta = append(ta, t)
+		}
+	}
+	for _, pt := range []Pt{a.p, a.q} {
+		if u := dot(Pt{pt[0] - b.p[0], pt[1] - b.p[1]}, s) / ss; u > 0 && u < 1 {
+			tb = append(tb, u)
+		}
+	}
+	return ta, tb
+}
+
+func clamp(t float64) float64 {
+	return math.Max(0, math.Min(1, t))
+}
+
+func cross(a, b Pt) float64 { return a[0]*b[1] - a[1]*b[0] }
+func dot(a, b Pt) float64   { return a[0]*b[0] + a[1]*b[1] }
+
+// chain joins directed edges end to end into closed contours, without
+// their collinear vertices.
+func chain(edges []edge) Shape {
+	out := map[key][]int{}
+	for i, e := range edges {
+		k := keyOf(e.p)
+		out[k] = append(out[k], i)
+	}
+	used := make([]bool, len(edges))
+	var result Shape
+	for i := range edges {
+		if used[i] {
+			continue
+		}
+		used[i] = true
+		start := keyOf(edges[i].p)
+		c := []Pt{edges[i].p}
+		for cur := keyOf(edges[i].q); cur != start; {
+			next := -1
+			for _, j := range out[cur] {
+				if !used[j] {
+					next = j
+					break
+				}
+			}
+			if next < 0 {
+				c = nil // an open chain (from numerical error)
+				break
+			}
+			used[next] = true
+			c = append(c, edges[next].p)
+			cur = keyOf(edges[next].q)
+		}
+		if c = simplify(c); len(c) >= 3 {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// simplify returns the closed contour without its collinear vertices.
+func simplify(c []Pt) []Pt {
+	for changed := true; changed && len(c) >= 3; {
+		changed = false
+		for i := 0; i < len(c) && len(c) >= 3; i++ {
+			prev, next := c[(i+len(c)-1)%len(c)], c[(i+1)%len(c)]
+			u, v := Pt{c[i][0] - prev[0], c[i][1] - prev[1]}, Pt{next[0] - c[i][0], next[1] - c[i][1]}
+			if math.Abs(cross(u, v)) <= snap*math.Max(math.Hypot(u[0], u[1]), math.Hypot(v[0], v[1])) && dot(u, v) > 0 {
+				c = append(c[:i:i], c[i+1:]...)
+				changed = true
+			}
+		}
+	}
+	return c
+}
+
+// Clean returns the contour without repeated points (including a
+// closing point).
+func Clean(c []Pt) []Pt {
+	var result []Pt
+	for _, p := range c {
+		if len(result) > 0 && p == result[len(result)-1] {
+			continue
+		}
+		result = append(result, p)
+	}
+	for len(result) > 1 && result[len(result)-1] == result[0] {
+		result = result[:len(result)-1]
+	}
+	return result
+}
+
+// SignedArea returns the (positive if counterclockwise) area of a
+// closed contour.
+func SignedArea(c []Pt) float64 {
+	var a float64
+	for i, j := 0, len(c)-1; i < len(c); j, i = i, i+1 {
+		a += c[j][0]*c[i][1] - c[i][0]*c[j][1]
+	}
+	return 0.5 * a
+}
@@ -0,0 +1,386 @@
+package gerber
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/gmlewis/go-gerber/gerber/internal/polyclip"
+)
+
+// OutputFormat is the file format of a design written by Write (and so
+// by WriteZip, WriteToDir, and WriteGerber).
+type OutputFormat int
+
+const (
+	// GerberFormat writes a Gerber file for each layer (the default).
+	GerberFormat OutputFormat = iota
+	// IPC2581Format writes the whole design as a single IPC-2581
+	// (revision B) XML file (see WriteIPC2581).
+	IPC2581Format
+)
+
+// WithOutputFormat sets the file format written by Write, for fabs and
+// assembly houses that prefer IPC-2581 to Gerber.
+func WithOutputFormat(f OutputFormat) Option {
+	return func(g *Gerber) {
+		g.outputFormat = f
+	}
+}
+
+// IPC2581Filename returns the filename of the design's IPC-2581 file.
+func (g *Gerber) IPC2581Filename() string {
+	return g.FilenamePrefix + ".xml"
+}
+
+// writeIPC2581 writes the design's IPC-2581 file, followed by its
+// pick-and-place file if it has any components, to the
+// io.WriteClosers returned by create.
+func (g *Gerber) writeIPC2581(ctx context.Context, create func(filename string) (io.WriteCloser, error)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	w, err := create(g.IPC2581Filename())
+	if err != nil {
+		return err
+	}
+	if err := g.WriteIPC2581(w); err != nil {
+		abort(w)
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return g.writeCentroid(create)
+}
+
+// WriteIPC2581 writes the design as an IPC-2581 (revision B) XML file
+// for fabrication: a layer for each of the design's layers, with their
+// features (in output coordinates, after the design's origin and
+// export transform are applied), the holes of its drill layers, and the
+// board profile traced from its outline layer (see Layer.Contours).
+// Declared solder mask and paste openings are derived first (see
+// DeriveOpenings) into copies of the layers, as by Write. Each layer is serialized as Gerber and read back
+// (see Parse), so that every primitive (including third-party ones) is
+// exported as it would be plotted.
+func (g *Gerber) WriteIPC2581(w io.Writer) error {
+	restore, err := g.deriveOutput()
+	if err != nil {
+		return err
+	}
+	defer restore()
+	// Arcs are written (and so exported) as arcs rather than flattened.
+	defer func(nativeArcs bool) { g.nativeArcs = nativeArcs }(g.nativeArcs)
+	g.nativeArcs = true
+	x := &ipcWriter{entries: map[string]string{}}
+	names := map[string]int{}
+	var refs []string
+	var layers, features bytes.Buffer
+	for _, layer := range g.Layers {
+		name := ipcLayerName(layer)
+		if names[name]++; names[name] > 1 {
+			name = fmt.Sprintf("%v_%v", name, names[name])
+		}
+		refs = append(refs, name)
+		var buf bytes.Buffer
+		if err := layer.WriteGerber(&buf); err != nil {
+			return err
+		}
+		parsed, err := Parse(&buf)
+		if err != nil {
+			return fmt.Errorf("layer %v: %v", layer.Filename, err)
+		}
+		function, side := ipcLayerFunction(layer.Type)
+		polarity := "POSITIVE"
+		if layer.Type.IsNegative() {
+			polarity = "NEGATIVE"
+		}
+		fmt.Fprintf(&layers, "      <Layer name=%q layerFunction=%q side=%q polarity=%q/>\n", name, function, side, polarity)
+		if parsed.IsEmpty() {
+			continue
+		}
+		fmt.Fprintf(&features, "        <LayerFeature layerRef=%q>\n", name)
+		if layer.Type == DrillLayer {
+			x.holes(&features, layer, parsed.Primitives)
+		} else {
+			x.sets(&features, parsed.Primitives)
+		}
+		features.WriteString("        </LayerFeature>\n")
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.WriteString("<IPC-2581 revision=\"B\" xmlns=\"http://webstds.ipc.org/2581\">\n")
+	buf.WriteString("  <Content roleRef=\"Owner\">\n    <FunctionMode mode=\"FABRICATION\"/>\n    <StepRef name=\"board\"/>\n")
+	for _, name := range refs {
+		fmt.Fprintf(&buf, "    <LayerRef name=%q/>\n", name)
+	}
+	buf.WriteString("    <DictionaryStandard units=\"MILLIMETER\">\n")
+	ids := make([]string, 0, len(x.entries))
+	for id := range x.entries {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		fmt.Fprintf(&buf, "      <EntryStandard id=%q>%v</EntryStandard>\n", id, x.entries[id])
+	}
+	buf.WriteString("    </DictionaryStandard>\n  </Content>\n")
+	fmt.Fprintf(&buf, "  <LogisticHeader>\n    <Role id=\"Owner\" roleFunction=\"SENDER\"/>\n"+
+		"    <Enterprise id=\"go-gerber\" code=\"NONE\"/>\n"+
+		"    <Person name=\"go-gerber\" enterpriseRef=\"go-gerber\" roleRef=\"Owner\"/>\n  </LogisticHeader>\n")
+	revision := g.revision
+	if revision == "" {
+		revision = "1"
+	}
+	fmt.Fprintf(&buf, "  <HistoryRecord number=\"1\" origination=%q software=\"gmlewis/go-gerber\" lastChange=%q>\n"+
+		"    <FileRevision fileRevisionId=%v comment=\"\">\n"+
+		"      <SoftwarePackage name=\"go-gerber\" vendor=\"gmlewis\"><Certification certificationStatus=\"SELFTEST\"/></SoftwarePackage>\n"+
+		"    </FileRevision>\n  </HistoryRecord>\n", ipcTimestamp, ipcTimestamp, ipcAttr(revision))
+	fmt.Fprintf(&buf, "  <Ecad name=%v>\n    <CadHeader units=\"MILLIMETER\"/>\n    <CadData>\n", ipcAttr(g.FilenamePrefix))
+	buf.Write(layers.Bytes())
+	buf.WriteString("      <Step name=\"board\">\n        <Datum x=\"0\" y=\"0\"/>\n")
+	buf.WriteString(g.ipcProfile())
+	buf.Write(features.Bytes())
+	buf.WriteString("      </Step>\n    </CadData>\n  </Ecad>\n</IPC-2581>\n")
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// ipcTimestamp is the origination and last change time written to
+// IPC-2581 files, so that their output is reproducible.
+const ipcTimestamp = "2000-01-01T00:00:00"
+
+// ipcLayerName returns the IPC-2581 name of a layer (e.g. "TopCopper"
+// or "InnerCopper2").
+func ipcLayerName(l *Layer) string {
+	if l.N > 0 {
+		return fmt.Sprintf("%v%v", l.Type, l.N)
+	}
+	return l.Type.String()
+}
+
+// ipcLayerFunction returns the IPC-2581 function and side of a layer type.
+func ipcLayerFunction(t LayerType) (string, string) {
+	if info, ok := layerTypes[t]; ok {
+		return info.ipcFunction, info.ipcSide
+	}
+	return "DOCUMENTATION", "ALL"
+}
+
+// ipcWriter collects the standard primitives (pad shapes) of the
+// features of an IPC-2581 file.
+type ipcWriter struct {
+	// entries maps the id of each standard primitive to its element.
+	entries map[string]string
+}
+
+// sets writes the parsed primitives of a layer as sets of features,
+// starting a new set wherever the polarity changes.
+func (x *ipcWriter) sets(w *bytes.Buffer, primitives []Primitive) {
+	open := ""
+	for _, p := range primitives {
+		polarity := "POSITIVE"
+		if c, ok := p.(*ClearT); ok {
+			p, polarity = c.Primitive, "NEGATIVE"
+		}
+		if polarity != open {
+			if open != "" {
+				w.WriteString("          </Set>\n")
+			}
+			fmt.Fprintf(w, "          <Set polarity=%q>\n", polarity)
+			open = polarity
+		}
+		x.feature(w, p)
+	}
+	if open != "" {
+		w.WriteString("          </Set>\n")
+	}
+}
+
+// feature writes the Features element of a primitive returned by Parse.
+func (x *ipcWriter) feature(w *bytes.Buffer, p Primitive) {
+	const indent = "            "
+	switch v := p.(type) {
+	case *CircleT:
+		x.flash(w, v.pt, fmt.Sprintf("CIRCLE_%v", ipcFloat(v.thickness)), fmt.Sprintf("<Circle diameter=\"%v\"/>", ipcFloat(v.thickness)))
+	case *LineT:
+		end := "ROUND"
+		if v.Shape == RectShape {
+			if v.P1 == v.P2 {
+				s := ipcFloat(v.Thickness)
+				x.flash(w, v.P1, fmt.Sprintf("RECT_%vx%v", s, s), fmt.Sprintf("<RectCenter width=\"%v\" height=\"%v\"/>", s, s))
+				return
+			}
+			end = "SQUARE"
+		}
+		fmt.Fprintf(w, "%v<Features><Line startX=\"%v\" startY=\"%v\" endX=\"%v\" endY=\"%v\"><LineDesc lineEnd=%q lineWidth=\"%v\"/></Line></Features>\n",
+			indent, ipcFloat(v.P1[0]), ipcFloat(v.P1[1]), ipcFloat(v.P2[0]), ipcFloat(v.P2[1]), end, ipcFloat(v.Thickness))
+	case *ArcT:
+		p1 := PolarFrom(v.Center, v.Radius, Degrees(v.StartAngle))
+		p2 := PolarFrom(v.Center, v.Radius, Degrees(v.EndAngle))
+		fmt.Fprintf(w, "%v<Features><Arc startX=\"%v\" startY=\"%v\" endX=\"%v\" endY=\"%v\" centerX=\"%v\" centerY=\"%v\" clockwise=\"false\"><LineDesc lineEnd=\"ROUND\" lineWidth=\"%v\"/></Arc></Features>\n",
+			indent, ipcFloat(p1[0]), ipcFloat(p1[1]), ipcFloat(p2[0]), ipcFloat(p2[1]), ipcFloat(v.Center[0]), ipcFloat(v.Center[1]), ipcFloat(v.Thickness))
+	case *PolygonT:
+		if width, height, ok := centeredRect(v.Points); ok {
+			sw, sh := ipcFloat(width), ipcFloat(height)
+			x.flash(w, v.Offset, fmt.Sprintf("RECT_%vx%v", sw, sh), fmt.Sprintf("<RectCenter width=\"%v\" height=\"%v\"/>", sw, sh))
+			return
+		}
+		fmt.Fprintf(w, "%v<Features><Contour>%v</Contour></Features>\n", indent, ipcPolygon("Polygon", v.Offset, v.Points))
+	case *PadT:
+		// Obrounds (the only pads returned by Parse).
+		id := fmt.Sprintf("OVAL_%vx%v", ipcFloat(v.Width), ipcFloat(v.Height))
+		x.flash(w, v.Center, id, fmt.Sprintf("<Oval width=\"%v\" height=\"%v\"/>", ipcFloat(v.Width), ipcFloat(v.Height)))
+	case *FlashT:
+		image, _ := v.Macro.Image(v.Params...)
+		for _, p := range image {
+			if _, ok := p.(*ClearT); ok {
+				continue // as in SVG previews, clear parts are not drawn
+			}
+			if p, err := TransformPrimitive(p, Translate(v.Center[0], v.Center[1])); err == nil {
+				x.feature(w, p)
+			}
+		}
+	}
+}
+
+// centeredRect reports whether pts is an axis aligned rectangle
+// centered on the origin (as Parse returns flashes of rectangle
+// apertures), and returns its size.
+func centeredRect(pts []Pt) (float64, float64, bool) {
+	if len(pts) != 4 {
+		return 0, 0, false
+	}
+	hw, hh := math.Abs(pts[0][0]), math.Abs(pts[0][1])
+	for _, pt := range pts {
+		if math.Abs(pt[0]) != hw || math.Abs(pt[1]) != hh {
+			return 0, 0, false
+		}
+	}
+	return 2 * hw, 2 * hh, hw > 0 && hh > 0 && pts[0] != pts[1] && pts[0] != pts[2] && pts[0] != pts[3]
+}
+
+// flash writes a feature placing the standard primitive id (defined by
+// element) at pt.
+func (x *ipcWriter) flash(w *bytes.Buffer, pt Pt, id, element string) {
+	x.entries[id] = element
+	fmt.Fprintf(w, "            <Features><Location x=\"%v\" y=\"%v\"/><StandardPrimitiveRef id=%q/></Features>\n", ipcFloat(pt[0]), ipcFloat(pt[1]), id)
+}
+
+// holes writes the parsed primitives of a drill layer: its round holes
+// (plated unless tagged with NonPlatedTag, and vias if tagged with
+// ViaTag), and any other primitives (such as slots) as features.
+func (x *ipcWriter) holes(w *bytes.Buffer, layer *Layer, primitives []Primitive) {
+	// The tags of the holes, by their output position and diameter.
+	status := map[string]string{}
+	for _, p := range layer.Primitives {
+		c, ok := p.(*CircleT)
+		if !ok || status[ipcHoleKey(layer.g.exportPt(c.pt), c.thickness)] != "" {
+			continue
+		}
+		s := "PLATED"
+		switch {
+		case layer.HasTag(p, NonPlatedTag):
+			s = "NONPLATED"
+		case layer.HasTag(p, ViaTag):
+			s = "VIA"
+		}
+		status[ipcHoleKey(layer.g.exportPt(c.pt), c.thickness)] = s
+	}
+	w.WriteString("          <Set>\n")
+	var others []Primitive
+	for i, p := range primitives {
+		c, ok := p.(*CircleT)
+		if !ok {
+			others = append(others, p)
+			continue
+		}
+		s := status[ipcHoleKey(c.pt, c.thickness)]
+		if s == "" {
+			s = "PLATED"
+		}
+		fmt.Fprintf(w, "            <Hole name=\"H%v\" diameter=\"%v\" platingStatus=%q plusTol=\"0\" minusTol=\"0\" x=\"%v\" y=\"%v\"/>\n",
+			i+1, ipcFloat(c.thickness), s, ipcFloat(c.pt[0]), ipcFloat(c.pt[1]))
+	}
+	for _, p := range others {
+		x.feature(w, p)
+	}
+	w.WriteString("          </Set>\n")
+}
+
+func ipcHoleKey(pt Pt, d float64) string {
+	return fmt.Sprintf("%v,%v,%v", ipcFloat(pt[0]), ipcFloat(pt[1]), ipcFloat(d))
+}
+
+// ipcProfileSnap is the distance (in millimeters) within which the
+// endpoints of the outline primitives are joined into the profile.
+const ipcProfileSnap = 0.01
+
+// ipcProfile returns the Profile element of the board: the largest
+// closed contour of its outline layers, with the others as cutouts.
+func (g *Gerber) ipcProfile() string {
+	var contours [][]Pt
+	for _, layer := range g.layersOfType(OutlineLayer) {
+		for _, c := range layer.Contours(ipcProfileSnap) {
+			if !c.Closed {
+				continue
+			}
+			pts := make([]Pt, 0, len(c.Points))
+			for _, pt := range c.Points {
+				pts = append(pts, g.exportPt(pt))
+			}
+			contours = append(contours, pts)
+		}
+	}
+	if len(contours) == 0 {
+		return ""
+	}
+	sort.SliceStable(contours, func(i, j int) bool {
+		return math.Abs(polyclip.SignedArea(contours[i])) > math.Abs(polyclip.SignedArea(contours[j]))
+	})
+	var b strings.Builder
+	b.WriteString("        <Profile>")
+	b.WriteString(ipcPolygon("Polygon", Pt{}, contours[0]))
+	for _, c := range contours[1:] {
+		b.WriteString(ipcPolygon("Cutout", Pt{}, c))
+	}
+	b.WriteString("</Profile>\n")
+	return b.String()
+}
+
+// ipcPolygon returns the closed polygon (offset by offset) as an
+// element with the given name.
+func ipcPolygon(name string, offset Pt, pts []Pt) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<%v>", name)
+	for i := 0; i <= len(pts); i++ {
+		pt := pts[i%len(pts)]
+		if i > 0 && pt == pts[i-1] {
+			continue
+		}
+		tag := "PolyStepSegment"
+		if i == 0 {
+			tag = "PolyBegin"
+		}
+		fmt.Fprintf(&b, "<%v x=\"%v\" y=\"%v\"/>", tag, ipcFloat(pt[0]+offset[0]), ipcFloat(pt[1]+offset[1]))
+	}
+	fmt.Fprintf(&b, "</%v>", name)
+	return b.String()
+}
+
+// ipcFloat formats v (in mm) to the nearest nanometer.
+func ipcFloat(v float64) string {
+	return fmtFloat(math.Round(v*1e6) / 1e6)
+}
+
+// ipcAttr returns s quoted as an XML attribute value.
+func ipcAttr(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return "\"" + b.String() + "\""
+}
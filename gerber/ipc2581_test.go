@@ -0,0 +1,80 @@
+package gerber
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestWriteIPC2581(t *testing.T) {
+	g := New("board", WithOrigin(Pt{-10, -10}), WithRevision("B"))
+	top := g.TopCopper()
+	top.Add(
+		Circle(Pt{1, 1}, 1.5),
+		Line(0, 0, 5, 0, CircleShape, 0.25),
+		Pad(Pt{3, 3}, RectShape, 1, 0.5, 0),
+		Arc(Pt{0, 0}, 2, CircleShape, 1, 1, 0, 90, 0.2),
+		Polygon(Pt{}, true, []Pt{{6, 0}, {8, 0}, {8, 2}}, 0),
+		Clear(Circle(Pt{7.5, 0.5}, 0.3)),
+	)
+	drill := g.Drill()
+	drill.Add(Circle(Pt{1, 1}, 0.8), Circle(Pt{4, 4}, 3))
+	drill.Tag(drill.Primitives[1], NonPlatedTag)
+	g.Outline().Add(OutlinePath([]Pt{{-1, -1}, {9, -1}, {9, 5}, {-1, 5}, {-1, -1}}, 0.1)...)
+
+	var buf bytes.Buffer
+	if err := g.WriteIPC2581(&buf); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	d := xml.NewDecoder(strings.NewReader(got))
+	for {
+		if _, err := d.Token(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("invalid XML: %v\n%v", err, got)
+		}
+	}
+
+	for _, want := range []string{
+		`<Layer name="TopCopper" layerFunction="SIGNAL" side="TOP" polarity="POSITIVE"/>`,
+		`<Layer name="Drill" layerFunction="DRILL" side="ALL" polarity="POSITIVE"/>`,
+		`<EntryStandard id="CIRCLE_1.5"><Circle diameter="1.5"/></EntryStandard>`,
+		`<EntryStandard id="RECT_1x0.5"`,
+		`<Location x="11" y="11"/><StandardPrimitiveRef id="CIRCLE_1.5"/>`,
+		`<Line startX="10" startY="10" endX="15" endY="10"><LineDesc lineEnd="ROUND" lineWidth="0.25"/></Line>`,
+		`<Arc startX="12" startY="10" endX="10" endY="12" centerX="10" centerY="10" clockwise="false">`,
+		`<Contour><Polygon><PolyBegin x="16" y="10"/><PolyStepSegment x="18" y="10"/><PolyStepSegment x="18" y="12"/><PolyStepSegment x="16" y="10"/></Polygon></Contour>`,
+		`<Set polarity="NEGATIVE">`,
+		`diameter="0.8" platingStatus="PLATED" plusTol="0" minusTol="0" x="11" y="11"/>`,
+		`diameter="3" platingStatus="NONPLATED" plusTol="0" minusTol="0" x="14" y="14"/>`,
+		`<Profile><Polygon><PolyBegin x="9" y="9"/>`,
+		`fileRevisionId="B"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %v:\n%v", want, got)
+		}
+	}
+}
+
+func TestWithOutputFormat(t *testing.T) {
+	g := New("board", WithOutputFormat(IPC2581Format))
+	g.TopCopper().Add(Circle(Pt{1, 1}, 1))
+	g.AddComponent(&Component{Designator: "R1", Center: Pt{1, 1}})
+	files := map[string]*bytes.Buffer{}
+	err := g.Write(func(filename string) (io.WriteCloser, error) {
+		files[filename] = &bytes.Buffer{}
+		return nopCloser{files[filename]}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files["board.xml"] == nil || files[g.CentroidFilename()] == nil {
+		t.Fatalf("wrote files %v, want board.xml and the centroid file", files)
+	}
+	if !strings.Contains(files["board.xml"].String(), "<IPC-2581 revision=\"B\"") {
+		t.Errorf("board.xml = %v", files["board.xml"])
+	}
+}
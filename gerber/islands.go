@@ -0,0 +1,110 @@
+package gerber
+
+// Island is a set of connected (touching or overlapping) primitives
+// in a layer, such as part of a copper pour.
+type Island struct {
+	// Primitives are the island's primitives, in layer order.
+	Primitives []Primitive
+	// Area is the approximate area (in mm²) of the island,
+	// ignoring any overlap between its primitives.
+	Area float64
+	// Connected reports whether the island contains an anchor
+	// primitive (see IslandOpts).
+	Connected bool
+}
+
+// IslandOpts represents the options used to find and remove islands.
+type IslandOpts struct {
+	// Anchor reports whether a primitive connects its island to the
+	// rest of the circuit (e.g. a pad or trace tagged with the pour's
+	// net). If nil, only the island with the largest area is connected.
+	Anchor func(p Primitive) bool
+	// MinArea, if positive, limits RemoveIslands to unconnected
+	// islands with an area (in mm²) smaller than MinArea.
+	MinArea float64
+}
+
+// Islands returns the islands of the layer in the order of their first
+// primitive. Unconnected islands (Connected == false) are orphan copper
+// that may be flagged or removed (see RemoveIslands).
+func (l *Layer) Islands(opts IslandOpts) []*Island {
+	n := len(l.Primitives)
+	outlines := make([]outline, n)
+	mbbs := make([]MBB, n)
+	for i, p := range l.Primitives {
+		outlines[i] = outlineOf(p)
+		mbbs[i] = p.MBB()
+	}
+
+	// Union-find of touching primitives.
+	parent := make([]int, n)
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	index := l.spatial()
+	for i := 0; i < n; i++ {
+		for _, j := range index.query(mbbs[i]) {
+			if j <= i || find(i) == find(j) {
+				continue
+			}
+			if outlines[i].overlaps(outlines[j]) {
+				parent[find(j)] = find(i)
+			}
+		}
+	}
+
+	var islands []*Island
+	byRoot := map[int]*Island{}
+	for i, p := range l.Primitives {
+		root := find(i)
+		island, ok := byRoot[root]
+		if !ok {
+			island = &Island{}
+			byRoot[root] = island
+			islands = append(islands, island)
+		}
+		island.Primitives = append(island.Primitives, p)
+		island.Area += primitiveArea(p)
+		if opts.Anchor != nil && opts.Anchor(p) {
+			island.Connected = true
+		}
+	}
+
+	if opts.Anchor == nil && len(islands) > 0 {
+		largest := islands[0]
+		for _, island := range islands[1:] {
+			if island.Area > largest.Area {
+				largest = island
+			}
+		}
+		largest.Connected = true
+	}
+	return islands
+}
+
+// RemoveIslands removes the unconnected islands of the layer (limited
+// to those smaller than opts.MinArea, if positive) and returns them.
+func (l *Layer) RemoveIslands(opts IslandOpts) []*Island {
+	var removed []*Island
+	remove := map[Primitive]bool{}
+	for _, island := range l.Islands(opts) {
+		if island.Connected || (opts.MinArea > 0 && island.Area >= opts.MinArea) {
+			continue
+		}
+		removed = append(removed, island)
+		for _, p := range island.Primitives {
+			remove[p] = true
+		}
+	}
+	if len(removed) > 0 {
+		l.Remove(func(p Primitive) bool { return remove[p] })
+	}
+	return removed
+}
@@ -0,0 +1,62 @@
+package gerber
+
+import "testing"
+
+func TestLayer_Islands(t *testing.T) {
+	square := func(x, y, size float64) *PolygonT {
+		return Polygon(Pt{x, y}, true, []Pt{{0, 0}, {size, 0}, {size, size}, {0, size}}, 0)
+	}
+
+	tests := []struct {
+		name        string
+		opts        IslandOpts
+		wantIslands int
+		wantRemoved int
+		wantLeft    int
+	}{
+		{
+			name:        "largest island is connected",
+			wantIslands: 4,
+			wantRemoved: 3,
+			wantLeft:    2,
+		},
+		{
+			name:        "anchored islands are connected",
+			opts:        IslandOpts{Anchor: func(p Primitive) bool { _, ok := p.(*CircleT); return ok }},
+			wantIslands: 4,
+			wantRemoved: 3,
+			wantLeft:    1,
+		},
+		{
+			name:        "only small islands are removed",
+			opts:        IslandOpts{MinArea: 2},
+			wantIslands: 4,
+			wantRemoved: 1,
+			wantLeft:    4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New("test")
+			top := g.TopCopper()
+			top.Add(
+				square(0, 0, 10),                    // pour
+				Line(5, 5, 20, 5, CircleShape, 0.5), // trace touching the pour
+				Circle(Pt{30, 30}, 1),               // isolated pad
+				square(20, 20, 2),                   // orphan island
+				square(-2, 4, 1.9),                  // sliver not quite touching the pour
+			)
+			if got := len(top.Islands(tt.opts)); got != tt.wantIslands {
+				t.Errorf("len(Islands) = %v, want %v", got, tt.wantIslands)
+			}
+			removed := top.RemoveIslands(tt.opts)
+			if got := len(removed); got != tt.wantRemoved {
+				t.Errorf("len(RemoveIslands) = %v, want %v", got, tt.wantRemoved)
+			}
+			if got := len(top.Primitives); got != tt.wantLeft {
+				t.Errorf("len(Primitives) = %v, want %v", got, tt.wantLeft)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package gerber
+
+import "github.com/gmlewis/go-gerber/gerber/internal/polyclip"
+
+// Keepout is a region that generators (such as Pour, AddThermalVias,
+// AddThieving, and AddStitchingVias) automatically avoid.
+type Keepout struct {
+	// Region is the closed polygon of the keepout.
+	Region []Pt `json:"region"`
+	// Layers restricts the keepout to layers of these types.
+	// If empty, the keepout applies to all layers.
+	Layers []LayerType `json:"layers,omitempty"`
+}
+
+// AppliesTo reports whether the keepout applies to layers of type t.
+func (k Keepout) AppliesTo(t LayerType) bool {
+	if len(k.Layers) == 0 {
+		return true
+	}
+	for _, lt := range k.Layers {
+		if lt == t {
+			return true
+		}
+	}
+	return false
+}
+
+// AddKeepout adds a keepout to the design.
+func (g *Gerber) AddKeepout(k Keepout) {
+	g.keepouts = append(g.keepouts, k)
+}
+
+// Keepouts returns the keepouts of the design that apply to layers of
+// type t, including (for copper layers) the polygons and regions of
+// its keep-out layers (see Gerber.Keepout).
+func (g *Gerber) Keepouts(t LayerType) []Keepout {
+	var result []Keepout
+	for _, k := range g.keepouts {
+		if k.AppliesTo(t) {
+			result = append(result, k)
+		}
+	}
+	if !t.IsCopper() {
+		return result
+	}
+	for _, layer := range g.layersOfType(KeepoutLayer) {
+		for _, p := range layer.Primitives {
+			for _, poly := range outlineOf(p).polys {
+				result = append(result, Keepout{Region: poly, Layers: []LayerType{t}})
+			}
+		}
+	}
+	return result
+}
+
+// keepouts returns the keepouts that apply to the layer.
+func (l *Layer) keepouts() []Keepout {
+	if l.g == nil {
+		return nil
+	}
+	return l.g.Keepouts(l.Type)
+}
+
+// inKeepout reports whether p touches any keepout of the layer.
+func (l *Layer) inKeepout(p Primitive) bool {
+	o := outlineOf(p)
+	for _, k := range l.keepouts() {
+		if o.overlaps(outline{polys: [][]Pt{k.Region}}) {
+			return true
+		}
+	}
+	return false
+}
+
+// cutHoles returns region with each of the holes (which must lie
+// within it) cut out using zero-width "keyhole" bridges, so that the
+// result can be written as a single Gerber region.
+func cutHoles(region []Pt, holes [][]Pt) []Pt {
+	result := append([]Pt{}, region...)
+	ccw := polyclip.SignedArea(result) > 0
+	for _, hole := range holes {
+		h := append([]Pt{}, hole...)
+		if (polyclip.SignedArea(h) > 0) == ccw { // holes must wind the opposite way
+			for i, j := 0, len(h)-1; i < j; i, j = i+1, j-1 {
+				h[i], h[j] = h[j], h[i]
+			}
+		}
+		// Bridge between the closest pair of vertices.
+		bi, bj, best := 0, 0, -1.0
+		for i, p := range result {
+			for j, q := range h {
+				if d := Distance(p, q); best < 0 || d < best {
+					bi, bj, best = i, j, d
+				}
+			}
+		}
+		cut := make([]Pt, 0, len(result)+len(h)+2)
+		cut = append(cut, result[:bi+1]...)
+		for k := 0; k <= len(h); k++ {
+			cut = append(cut, h[(bj+k)%len(h)])
+		}
+		cut = append(cut, result[bi:]...)
+		result = cut
+	}
+	return result
+}
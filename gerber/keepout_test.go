@@ -0,0 +1,81 @@
+package gerber
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKeepouts(t *testing.T) {
+	square := func(x, y, size float64) []Pt {
+		return []Pt{{x, y}, {x + size, y}, {x + size, y + size}, {x, y + size}}
+	}
+	inside := Keepout{Region: square(4, 4, 2)}
+	crossing := Keepout{Region: square(8, 4, 4)}
+	bottomOnly := Keepout{Region: square(0, 0, 10), Layers: []LayerType{BottomCopperLayer}}
+
+	tests := []struct {
+		name        string
+		keepouts    []Keepout
+		opts        *PourOpts
+		wantHatched bool
+	}{
+		{name: "no keepouts"},
+		{name: "keepout within solid pour", keepouts: []Keepout{inside}},
+		{name: "keepout crossing solid pour", keepouts: []Keepout{crossing}, wantHatched: true},
+		{name: "keepout within hatched pour", keepouts: []Keepout{inside}, opts: &PourOpts{Hatched: true}, wantHatched: true},
+		{name: "keepout on other layer", keepouts: []Keepout{bottomOnly}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New("test")
+			for _, k := range tt.keepouts {
+				g.AddKeepout(k)
+			}
+			top := g.TopCopper()
+			result := top.Pour(square(0, 0, 10), tt.opts)
+			if got := len(result) > 1; got != tt.wantHatched {
+				t.Errorf("hatched = %v, want %v", got, tt.wantHatched)
+			}
+			for _, k := range g.Keepouts(TopCopperLayer) {
+				// Test points well within each keepout.
+				c := Midpoint(k.Region[0], k.Region[2])
+				for _, p := range result {
+					if PrimitiveContains(p, c) {
+						t.Errorf("%v covers keepout center %v", p, c)
+					}
+				}
+			}
+		})
+	}
+
+	g := New("test")
+	g.AddKeepout(Keepout{Region: square(1.5, 1.5, 1)})
+	top := g.TopCopper()
+	epad := Polygon(Pt{0, 0}, true, square(0, 0, 4), 0)
+	top.Add(epad)
+	vias, err := top.AddThermalVias(epad, ThermalViaOpts{Pitch: 1.2, Drill: 0.3, Pad: 0.6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(vias), 8; got != want {
+		t.Errorf("thermal vias = %v, want %v (center via in keepout)", got, want)
+	}
+}
+
+func TestKeepouts_KeepoutLayer(t *testing.T) {
+	g := New("test")
+	g.AddKeepout(Keepout{Region: []Pt{{0, 0}, {1, 0}, {1, 1}}})
+	g.Keepout().Add(Polygon(Pt{10, 0}, true, []Pt{{0, 0}, {2, 0}, {2, 2}, {0, 2}}, 0), Line(0, 5, 10, 5, CircleShape, 0.2))
+
+	got := g.Keepouts(InnerCopperLayer)
+	if len(got) != 2 {
+		t.Fatalf("Keepouts = %v, want 2", got)
+	}
+	if want := []Pt{{10, 0}, {12, 0}, {12, 2}, {10, 2}}; !reflect.DeepEqual(got[1].Region, want) {
+		t.Errorf("Keepouts[1].Region = %v, want %v", got[1].Region, want)
+	}
+	if got := g.Keepouts(TopSilkscreenLayer); len(got) != 1 {
+		t.Errorf("Keepouts(silkscreen) = %v, want only the design's keepout", got)
+	}
+}
@@ -0,0 +1,377 @@
+package gerber
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// kicadLayers maps the KiCad layers of footprint graphics to layer
+// types. Graphics on other layers (such as F.Fab and F.CrtYd) are not
+// imported.
+var kicadLayers = map[string]LayerType{
+	"F.Cu":         TopCopperLayer,
+	"B.Cu":         BottomCopperLayer,
+	"F.SilkS":      TopSilkscreenLayer,
+	"B.SilkS":      BottomSilkscreenLayer,
+	"F.Silkscreen": TopSilkscreenLayer,
+	"B.Silkscreen": BottomSilkscreenLayer,
+	"F.Mask":       TopSolderMaskLayer,
+	"B.Mask":       BottomSolderMaskLayer,
+	"F.Paste":      TopSolderPasteLayer,
+	"B.Paste":      BottomSolderPasteLayer,
+	"Edge.Cuts":    OutlineLayer,
+}
+
+// ParseKiCadFootprint reads a KiCad footprint (.kicad_mod) file, in
+// the format of KiCad 5 ("module") or later ("footprint"), and returns
+// it as a Footprint named after it (see Named to place it as a part).
+// KiCad's Y axis points down, so Y coordinates are negated.
+//
+// Pads become padstack instances (see PadstackRef) with their numbers
+// and rotations; their mask and paste openings follow the pad's KiCad
+// layers, and non-plated holes are marked NonPlated. Padstacks only
+// have round, oblong, and rectangular pads, so rounded rectangle,
+// trapezoid, and custom pads are imported as rectangles (or, for
+// custom pads, as their anchor pad), and oval drills as round holes of
+// their smaller size. Lines, rectangles, circles, arcs, and polygons
+// on the copper, silkscreen, mask, paste, and Edge.Cuts layers are
+// added to the footprint's layers; text and 3D models are ignored.
+func ParseKiCadFootprint(r io.Reader) (*Footprint, error) {
+	root, err := parseSExpr(r)
+	if err != nil {
+		return nil, err
+	}
+	if root.name() != "footprint" && root.name() != "module" {
+		return nil, fmt.Errorf("not a KiCad footprint: %q", root.name())
+	}
+	f := &Footprint{Name: root.str(1), Layers: map[LayerType]Group{}}
+	padstacks := map[Padstack]*Padstack{}
+	for _, e := range root.list[1:] {
+		switch e.name() {
+		case "pad":
+			ref, err := kicadPad(e)
+			if err != nil {
+				return nil, fmt.Errorf("footprint %q: pad %q: %v", f.Name, e.str(1), err)
+			}
+			if ps, ok := padstacks[*ref.Padstack]; ok {
+				ref.Padstack = ps
+			} else {
+				padstacks[*ref.Padstack] = ref.Padstack
+			}
+			f.Pads = append(f.Pads, ref)
+		case "fp_line", "fp_rect", "fp_circle", "fp_arc", "fp_poly":
+			t, ok := kicadLayers[e.child("layer").str(1)]
+			if !ok {
+				continue
+			}
+			p, err := kicadGraphic(e)
+			if err != nil {
+				return nil, fmt.Errorf("footprint %q: %v: %v", f.Name, e.name(), err)
+			}
+			f.Layers[t] = append(f.Layers[t], p...)
+		}
+	}
+	return f, nil
+}
+
+// kicadPad returns the padstack instance of a pad, with a new padstack.
+func kicadPad(e *sexpr) (PadstackRef, error) {
+	kind, shape := e.str(2), e.str(3)
+	at := e.child("at")
+	size := e.child("size")
+	if at == nil || size == nil {
+		return PadstackRef{}, errors.New("missing position or size")
+	}
+	w, h := size.num(1), size.num(2)
+	var pad PadShape
+	switch shape {
+	case "circle":
+		pad = RoundPad(w)
+	case "oval":
+		pad = PadShape{Shape: CircleShape, Width: w, Height: h}
+	case "rect", "roundrect", "trapezoid", "custom":
+		pad = RectPad(w, h)
+		if shape == "custom" {
+			if opts := e.child("options"); opts != nil && opts.child("anchor").str(1) == "circle" {
+				pad = RoundPad(w)
+			}
+		}
+	default:
+		return PadstackRef{}, fmt.Errorf("unknown pad shape %q", shape)
+	}
+
+	layers := map[string]bool{}
+	if l := e.child("layers"); l != nil {
+		for i := 1; i < len(l.list); i++ {
+			layers[l.str(i)] = true
+		}
+	}
+	on := func(side string) bool { return layers[side] || layers["*"+side[1:]] || layers["F&B"+side[1:]] }
+	ps := &Padstack{TentTop: !on("F.Mask"), TentBottom: !on("B.Mask")}
+	switch kind {
+	case "thru_hole", "np_thru_hole":
+		drill := e.child("drill")
+		if drill == nil {
+			return PadstackRef{}, errors.New("missing drill")
+		}
+		if drill.str(1) == "oval" {
+			ps.Drill = drill.num(2)
+			if d := drill.num(3); d > 0 && d < ps.Drill {
+				ps.Drill = d
+			}
+		} else {
+			ps.Drill = drill.num(1)
+		}
+		ps.NonPlated = kind == "np_thru_hole"
+		if !ps.NonPlated || w > ps.Drill {
+			if on("F.Cu") {
+				ps.Top = pad
+			}
+			if on("B.Cu") {
+				ps.Bottom = pad
+			}
+			if layers["*.Cu"] {
+				ps.Inner = pad
+			}
+		}
+	case "smd", "connect":
+		if on("F.Cu") {
+			ps.Top = pad
+		} else if on("B.Cu") {
+			ps.Bottom = pad
+		}
+		ps.Paste = on("F.Paste") || on("B.Paste")
+	default:
+		return PadstackRef{}, fmt.Errorf("unknown pad type %q", kind)
+	}
+	if m := e.child("solder_mask_margin"); m != nil {
+		ps.MaskExpansion = m.num(1)
+	}
+	if m := e.child("solder_paste_margin"); m != nil {
+		ps.PasteOverprint = m.num(1)
+	}
+	return PadstackRef{Padstack: ps, Center: at.pt(1), Rotation: at.num(3), Number: e.str(1)}, nil
+}
+
+// kicadGraphic returns the primitives of a graphic item.
+func kicadGraphic(e *sexpr) ([]Primitive, error) {
+	width := e.child("width").num(1)
+	if stroke := e.child("stroke"); stroke != nil {
+		width = stroke.child("width").num(1)
+	}
+	filled := false
+	if fill := e.child("fill"); fill != nil {
+		filled = fill.str(1) == "solid" || fill.str(1) == "yes"
+	} else if e.name() == "fp_poly" {
+		filled = true // KiCad 5 polygons are filled
+	}
+	start, end := e.child("start").pt(1), e.child("end").pt(1)
+	switch e.name() {
+	case "fp_line":
+		return []Primitive{Line(start[0], start[1], end[0], end[1], CircleShape, width)}, nil
+	case "fp_rect":
+		pts := []Pt{start, {end[0], start[1]}, end, {start[0], end[1]}}
+		return kicadPolygon(pts, filled, width), nil
+	case "fp_circle":
+		center := e.child("center").pt(1)
+		r := Distance(center, end)
+		if filled {
+			return []Primitive{Circle(center, 2*r+width)}, nil
+		}
+		return []Primitive{Arc(center, r, CircleShape, 1, 1, 0, 360, width)}, nil
+	case "fp_arc":
+		if mid := e.child("mid"); mid != nil {
+			return kicadArc(start, mid.pt(1), end, width)
+		}
+		// KiCad 5 arcs are a center (start), a starting point (end),
+		// and an angle, which is clockwise as seen with Y pointing up.
+		r := Distance(start, end)
+		a := math.Atan2(end[1]-start[1], end[0]-start[0]) * 180 / math.Pi
+		sweep := e.child("angle").num(1)
+		return []Primitive{Arc(start, r, CircleShape, 1, 1, a-sweep, a, width)}, nil
+	case "fp_poly":
+		var pts []Pt
+		if list := e.child("pts"); list != nil {
+			for _, xy := range list.list[1:] {
+				if xy.name() == "xy" {
+					pts = append(pts, xy.pt(1))
+				}
+			}
+		}
+		if len(pts) < 3 {
+			return nil, fmt.Errorf("polygon of %v points", len(pts))
+		}
+		return kicadPolygon(pts, filled, width), nil
+	}
+	return nil, nil
+}
+
+// kicadPolygon returns a filled polygon (with a border of the given
+// width, if any) or the outline of one.
+func kicadPolygon(pts []Pt, filled bool, width float64) []Primitive {
+	var result []Primitive
+	if filled {
+		result = append(result, Polygon(Pt{}, true, pts, 0))
+		if width <= 0 {
+			return result
+		}
+	}
+	for i, p1 := range pts {
+		p2 := pts[(i+1)%len(pts)]
+		result = append(result, Line(p1[0], p1[1], p2[0], p2[1], CircleShape, width))
+	}
+	return result
+}
+
+// kicadArc returns the arc from start through mid to end.
+func kicadArc(start, mid, end Pt, width float64) ([]Primitive, error) {
+	// The center is the intersection of the perpendicular bisectors.
+	bx, by := mid[0]-start[0], mid[1]-start[1]
+	cx, cy := end[0]-start[0], end[1]-start[1]
+	d := 2 * (bx*cy - by*cx)
+	if math.Abs(d) < 1e-12 {
+		return []Primitive{Line(start[0], start[1], end[0], end[1], CircleShape, width)}, nil
+	}
+	ux := (cy*(bx*bx+by*by) - by*(cx*cx+cy*cy)) / d
+	uy := (bx*(cx*cx+cy*cy) - cx*(bx*bx+by*by)) / d
+	center := Pt{start[0] + ux, start[1] + uy}
+	angle := func(pt Pt) float64 { return math.Atan2(pt[1]-center[1], pt[0]-center[0]) * 180 / math.Pi }
+	a0, am, a1 := angle(start), angle(mid), angle(end)
+	ccw := func(from, to float64) float64 { return math.Mod(math.Mod(to-from, 360)+360, 360) }
+	if ccw(a0, am) > ccw(a0, a1) { // clockwise from start to end
+		a0, a1 = a1, a0
+	}
+	return []Primitive{Arc(center, Distance(center, start), CircleShape, 1, 1, a0, a0+ccw(a0, a1), width)}, nil
+}
+
+// sexpr is a node of an S-expression: an atom or a list.
+type sexpr struct {
+	atom string
+	list []*sexpr
+}
+
+// name returns the first atom of a list, which names it, or "".
+func (s *sexpr) name() string {
+	if s == nil || len(s.list) == 0 {
+		return ""
+	}
+	return s.list[0].atom
+}
+
+// child returns the first sublist with the given name, or nil.
+func (s *sexpr) child(name string) *sexpr {
+	if s == nil {
+		return nil
+	}
+	for _, c := range s.list[1:] {
+		if c.name() == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// str returns the i'th atom of a list, or "".
+func (s *sexpr) str(i int) string {
+	if s == nil || i >= len(s.list) {
+		return ""
+	}
+	return s.list[i].atom
+}
+
+// num returns the i'th atom of a list as a number, or 0.
+func (s *sexpr) num(i int) float64 {
+	v, _ := strconv.ParseFloat(s.str(i), 64)
+	return v
+}
+
+// pt returns the point at the i'th atom of a list, with its Y
+// coordinate negated.
+func (s *sexpr) pt(i int) Pt {
+	return Pt{s.num(i), -s.num(i + 1)}
+}
+
+// parseSExpr parses the first S-expression read from r.
+func parseSExpr(r io.Reader) (*sexpr, error) {
+	br := bufio.NewReader(r)
+	line := 1
+	var stack []*sexpr
+	var root *sexpr
+	var atom strings.Builder
+	flush := func() {
+		if atom.Len() > 0 && len(stack) > 0 {
+			top := stack[len(stack)-1]
+			top.list = append(top.list, &sexpr{atom: atom.String()})
+		}
+		atom.Reset()
+	}
+	for root == nil {
+		c, err := br.ReadByte()
+		if err == io.EOF {
+			if len(stack) > 0 {
+				return nil, fmt.Errorf("line %v: unexpected end of file", line)
+			}
+			return nil, errors.New("no S-expression found")
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch c {
+		case '(':
+			flush()
+			s := &sexpr{list: []*sexpr{}}
+			if len(stack) > 0 {
+				top := stack[len(stack)-1]
+				top.list = append(top.list, s)
+			}
+			stack = append(stack, s)
+		case ')':
+			flush()
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("line %v: unbalanced parenthesis", line)
+			}
+			if len(stack) == 1 {
+				root = stack[0]
+			}
+			stack = stack[:len(stack)-1]
+		case '"':
+			for {
+				c, err := br.ReadByte()
+				if err != nil {
+					return nil, fmt.Errorf("line %v: unterminated string", line)
+				}
+				if c == '"' {
+					break
+				}
+				if c == '\\' {
+					if c, err = br.ReadByte(); err != nil {
+						return nil, fmt.Errorf("line %v: unterminated string", line)
+					}
+				}
+				if c == '\n' {
+					line++
+				}
+				atom.WriteByte(c)
+			}
+			// Keep empty strings (such as the number of an unnumbered pad).
+			if atom.Len() == 0 && len(stack) > 0 {
+				top := stack[len(stack)-1]
+				top.list = append(top.list, &sexpr{})
+			}
+			flush()
+		case ' ', '\t', '\r', '\n':
+			if c == '\n' {
+				line++
+			}
+			flush()
+		default:
+			atom.WriteByte(c)
+		}
+	}
+	return root, nil
+}
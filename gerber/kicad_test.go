@@ -0,0 +1,123 @@
+package gerber
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+const testKiCadFootprint = `(footprint "TEST_FP" (version 20211014) (generator pcbnew)
+  (layer "F.Cu")
+  (descr "A test footprint")
+  (fp_text reference "REF**" (at 0 -3) (layer "F.SilkS")
+    (effects (font (size 1 1) (thickness 0.15))))
+  (fp_line (start -2 -1.5) (end 2 -1.5) (layer "F.SilkS") (width 0.12))
+  (fp_circle (center 0 0) (end 1 0) (layer "F.SilkS") (stroke (width 0.1) (type solid)) (fill none))
+  (fp_arc (start 1 0) (mid 0 -1) (end -1 0) (layer "F.SilkS") (stroke (width 0.1) (type solid)))
+  (fp_rect (start -3 -2) (end 3 2) (layer "F.CrtYd") (width 0.05))
+  (pad "1" smd roundrect (at -1.5 0.5 90) (size 1 0.6) (layers "F.Cu" "F.Paste" "F.Mask") (roundrect_rratio 0.25))
+  (pad "2" thru_hole circle (at 1.5 0.5) (size 1.6 1.6) (drill 0.8) (layers *.Cu *.Mask))
+  (pad "3" thru_hole circle (at 1.5 2.5) (size 1.6 1.6) (drill 0.8) (layers *.Cu *.Mask))
+  (pad "" np_thru_hole circle (at 0 -1) (size 1 1) (drill 1) (layers *.Cu *.Mask))
+)
+`
+
+func TestParseKiCadFootprint(t *testing.T) {
+	f, err := ParseKiCadFootprint(strings.NewReader(testKiCadFootprint))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Name != "TEST_FP" {
+		t.Errorf("Name = %q, want TEST_FP", f.Name)
+	}
+	if len(f.Layers) != 1 || len(f.Layers[TopSilkscreenLayer]) != 3 {
+		t.Fatalf("Layers = %v, want 3 silkscreen primitives", f.Layers)
+	}
+	line := f.Layers[TopSilkscreenLayer][0].(*LineT)
+	if line.P1 != (Pt{-2, 1.5}) || line.Thickness != 0.12 {
+		t.Errorf("line = %v, want one from (-2,1.5) of width 0.12", line)
+	}
+	// The arc through (0,-1) in KiCad's coordinates is the upper half
+	// of the circle.
+	arc := f.Layers[TopSilkscreenLayer][2].(*ArcT)
+	if math.Abs(arc.Radius-1) > 1e-9 || math.Abs(arc.StartAngle) > 1e-9 || math.Abs(arc.EndAngle-math.Pi) > 1e-9 {
+		t.Errorf("arc = %v, want the upper half of the unit circle", arc)
+	}
+
+	if len(f.Pads) != 4 {
+		t.Fatalf("got %v pads, want 4", len(f.Pads))
+	}
+	smd, th, npth := f.Pads[0], f.Pads[1], f.Pads[3]
+	if smd.Number != "1" || smd.Center != (Pt{-1.5, -0.5}) || smd.Rotation != 90 {
+		t.Errorf("pad 1 = %+v", smd)
+	}
+	if ps := smd.Padstack; ps.Drill != 0 || ps.Top != RectPad(1, 0.6) || ps.Bottom.Width != 0 || !ps.Paste || ps.TentTop {
+		t.Errorf("pad 1 padstack = %+v", ps)
+	}
+	if ps := th.Padstack; ps.Drill != 0.8 || ps.NonPlated || ps.Top != RoundPad(1.6) || ps.Inner != RoundPad(1.6) || ps.Bottom != RoundPad(1.6) || ps.TentTop || ps.TentBottom {
+		t.Errorf("pad 2 padstack = %+v", ps)
+	}
+	if f.Pads[2].Padstack != th.Padstack {
+		t.Error("identical pads do not share their padstack")
+	}
+	if ps := npth.Padstack; ps.Drill != 1 || !ps.NonPlated || ps.Top.Width != 0 {
+		t.Errorf("non-plated padstack = %+v", ps)
+	}
+
+	g := New("test")
+	if err := f.Named("U1").Place(g, Pt{10, 10}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.DeriveOpenings(); err != nil {
+		t.Fatal(err)
+	}
+	drill := g.firstLayerOfType(DrillLayer)
+	var nonPlated int
+	for _, p := range drill.Primitives {
+		if drill.HasTag(p, NonPlatedTag) {
+			nonPlated++
+		}
+	}
+	if len(drill.Primitives) != 3 || nonPlated != 1 {
+		t.Errorf("got %v holes (%v non-plated), want 3 (1 non-plated)", len(drill.Primitives), nonPlated)
+	}
+	if c := g.Components(); len(c) != 1 || c[0].Designator != "U1" || c[0].Package != "TEST_FP" {
+		t.Errorf("Components = %v, want U1", c)
+	}
+}
+
+func TestParseKiCadFootprint_Module(t *testing.T) {
+	f, err := ParseKiCadFootprint(strings.NewReader(`(module R_0603 (layer F.Cu) (tedit 5B301BBD)
+  (fp_arc (start 0 0) (end 1 0) (angle 90) (layer F.SilkS) (width 0.12))
+  (fp_poly (pts (xy 0 0) (xy 1 0) (xy 1 1)) (layer F.Cu) (width 0))
+  (pad 1 smd rect (at -0.75 0) (size 0.8 0.95) (layers F.Cu F.Paste F.Mask))
+)`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Name != "R_0603" || len(f.Pads) != 1 || f.Pads[0].Number != "1" {
+		t.Fatalf("got footprint %+v", f)
+	}
+	// A clockwise (on screen) arc from 0 degrees ends at -90 degrees
+	// once Y is flipped.
+	arc := f.Layers[TopSilkscreenLayer][0].(*ArcT)
+	if math.Abs(arc.StartAngle+math.Pi/2) > 1e-9 || math.Abs(arc.EndAngle) > 1e-9 {
+		t.Errorf("arc from %v to %v, want -pi/2 to 0", arc.StartAngle, arc.EndAngle)
+	}
+	if _, ok := f.Layers[TopCopperLayer][0].(*PolygonT); !ok {
+		t.Errorf("got copper %v, want a polygon", f.Layers[TopCopperLayer])
+	}
+}
+
+func TestParseKiCadFootprint_Errors(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"(footprint \"x\"",
+		"(kicad_pcb (version 4))",
+		"(footprint \"x\" (pad \"1\" smd rect (layers F.Cu)))",
+	} {
+		if _, err := ParseKiCadFootprint(strings.NewReader(s)); err == nil {
+			t.Errorf("ParseKiCadFootprint(%q) succeeded", s)
+		}
+	}
+}
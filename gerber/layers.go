@@ -1,15 +1,262 @@
 package gerber
 
 import (
+	"bufio"
+	"context"
 	"fmt"
 	"io"
-	"log"
+	"sort"
 )
 
+// LayerType represents the function of a layer in the design.
+type LayerType int
+
+// Layer types created by the corresponding Gerber methods
+// (e.g. TopCopper creates a TopCopperLayer).
+const (
+	TopCopperLayer LayerType = iota
+	TopSolderMaskLayer
+	TopSilkscreenLayer
+	BottomCopperLayer
+	BottomSolderMaskLayer
+	BottomSilkscreenLayer
+	InnerCopperLayer
+	DrillLayer
+	OutlineLayer
+	TopSolderPasteLayer
+	BottomSolderPasteLayer
+	TopCoverlayLayer
+	BottomCoverlayLayer
+	TopStiffenerLayer
+	BottomStiffenerLayer
+	BendAreaLayer
+	InnerPlaneLayer
+	MechanicalLayer
+	KeepoutLayer
+)
+
+// layerTypeInfo describes a layer type for each of the formats that
+// the package reads and writes.
+type layerTypeInfo struct {
+	// name is the name of the type (see String).
+	name string
+	// numbered types (whose extension, fileFunction, and description
+	// are formats of the layer's N) are those of the numbered inner
+	// copper, plane, and mechanical layers.
+	numbered bool
+	// extension is the Protel filename extension (see ProtelNames),
+	// fileFunction the X2 .FileFunction attribute, and description the
+	// function of the file in the design's manifest.
+	extension, fileFunction, description string
+	// ipcFunction and ipcSide are the IPC-2581 layer function and side.
+	// Types on the TOP or BOTTOM side have an opposite type with the
+	// same function on the other side (see Footprint.Flipped).
+	ipcFunction, ipcSide string
+	// svg is the default SVG style (see DefaultSVGStyle) and gerbv the
+	// color (as 8-bit RGB) in gerbv projects, where gerbvHidden types
+	// are listed but not initially shown, since gerbv draws their
+	// openings as positive areas that would hide the copper.
+	svg         SVGStyle
+	gerbv       [3]uint8
+	gerbvHidden bool
+	// stack is the position of the type in previews of the whole board
+	// (see StackedLayerTypes), from 1 at the bottom of the board, or 0
+	// if previews do not stack it.
+	stack int
+}
+
+// The default styles of the layer types without one of their own.
+var (
+	otherSVGStyle   = SVGStyle{Color: "#8080ff", Opacity: 0.5}
+	otherGerbvColor = [3]uint8{0x80, 0x80, 0xff}
+)
+
+// layerTypes describes each layer type.
+var layerTypes = map[LayerType]layerTypeInfo{
+	TopCopperLayer: {
+		name: "TopCopper", extension: "gtl", fileFunction: "Copper,L1,Top", description: "Top copper",
+		ipcFunction: "SIGNAL", ipcSide: "TOP",
+		svg: SVGStyle{Color: "#b87333", Opacity: 1}, gerbv: [3]uint8{0xc8, 0x34, 0x34}, stack: 11,
+	},
+	TopSolderMaskLayer: {
+		name: "TopSolderMask", extension: "gts", fileFunction: "Soldermask,Top", description: "Top solder mask",
+		ipcFunction: "SOLDERMASK", ipcSide: "TOP",
+		svg: SVGStyle{Color: "#006400", Opacity: 0.6, Negative: true}, gerbv: [3]uint8{0x14, 0xa0, 0x50}, gerbvHidden: true, stack: 12,
+	},
+	TopSilkscreenLayer: {
+		name: "TopSilkscreen", extension: "gto", fileFunction: "Legend,Top", description: "Top silkscreen",
+		ipcFunction: "SILKSCREEN", ipcSide: "TOP",
+		svg: SVGStyle{Color: "white", Opacity: 1}, gerbv: [3]uint8{0xf0, 0xf0, 0xf0}, stack: 14,
+	},
+	BottomCopperLayer: {
+		// The file function numbers the layer after the design's
+		// other copper layers (see fileFunction).
+		name: "BottomCopper", extension: "gbl", description: "Bottom copper",
+		ipcFunction: "SIGNAL", ipcSide: "BOTTOM",
+		svg: SVGStyle{Color: "#b87333", Opacity: 1}, gerbv: [3]uint8{0x4d, 0x7f, 0xc4}, stack: 4,
+	},
+	BottomSolderMaskLayer: {
+		name: "BottomSolderMask", extension: "gbs", fileFunction: "Soldermask,Bot", description: "Bottom solder mask",
+		ipcFunction: "SOLDERMASK", ipcSide: "BOTTOM",
+		svg: SVGStyle{Color: "#006400", Opacity: 0.6, Negative: true}, gerbv: [3]uint8{0x14, 0xa0, 0x50}, gerbvHidden: true, stack: 3,
+	},
+	BottomSilkscreenLayer: {
+		name: "BottomSilkscreen", extension: "gbo", fileFunction: "Legend,Bot", description: "Bottom silkscreen",
+		ipcFunction: "SILKSCREEN", ipcSide: "BOTTOM",
+		svg: SVGStyle{Color: "white", Opacity: 1}, gerbv: [3]uint8{0xe8, 0xb2, 0xa7}, stack: 1,
+	},
+	InnerCopperLayer: {
+		name: "InnerCopper", numbered: true, extension: "gl%v", fileFunction: "Copper,L%v,Inr", description: "Inner copper (layer %v)",
+		ipcFunction: "SIGNAL", ipcSide: "INTERNAL",
+		svg: SVGStyle{Color: "#b87333", Opacity: 1}, gerbv: [3]uint8{0xc2, 0xc2, 0x00}, stack: 7,
+	},
+	DrillLayer: {
+		// The file function and description depend upon the kind of
+		// holes of the layer (see WithSplitDrills).
+		name: "Drill", extension: "drl", description: "Drill holes",
+		ipcFunction: "DRILL", ipcSide: "ALL",
+		svg: SVGStyle{Color: "black", Opacity: 1}, gerbv: [3]uint8{0x5a, 0xc8, 0xc8}, stack: 16,
+	},
+	OutlineLayer: {
+		name: "Outline", extension: "gko", fileFunction: "Profile,NP", description: "Board outline",
+		ipcFunction: "BOARD_OUTLINE", ipcSide: "ALL",
+		svg: SVGStyle{Color: "yellow", Opacity: 1}, gerbv: [3]uint8{0xff, 0xff, 0x00}, stack: 15,
+	},
+	TopSolderPasteLayer: {
+		name: "TopSolderPaste", extension: "gtp", fileFunction: "Paste,Top", description: "Top solder paste",
+		ipcFunction: "SOLDERPASTE", ipcSide: "TOP",
+		svg: SVGStyle{Color: "#a0a0a0", Opacity: 0.8}, gerbv: [3]uint8{0x80, 0x80, 0x80}, gerbvHidden: true, stack: 13,
+	},
+	BottomSolderPasteLayer: {
+		name: "BottomSolderPaste", extension: "gbp", fileFunction: "Paste,Bot", description: "Bottom solder paste",
+		ipcFunction: "SOLDERPASTE", ipcSide: "BOTTOM",
+		svg: SVGStyle{Color: "#a0a0a0", Opacity: 0.8}, gerbv: [3]uint8{0x80, 0x80, 0x80}, gerbvHidden: true, stack: 2,
+	},
+	TopCoverlayLayer: {
+		name: "TopCoverlay", extension: "gtcl", fileFunction: "Other,Coverlay-Top", description: "Top coverlay",
+		ipcFunction: "COVERLAY", ipcSide: "TOP",
+		svg: otherSVGStyle, gerbv: otherGerbvColor, stack: 10,
+	},
+	BottomCoverlayLayer: {
+		name: "BottomCoverlay", extension: "gbcl", fileFunction: "Other,Coverlay-Bot", description: "Bottom coverlay",
+		ipcFunction: "COVERLAY", ipcSide: "BOTTOM",
+		svg: otherSVGStyle, gerbv: otherGerbvColor, stack: 5,
+	},
+	TopStiffenerLayer: {
+		name: "TopStiffener", extension: "gtst", fileFunction: "Other,Stiffener-Top", description: "Top stiffener",
+		ipcFunction: "STIFFENER", ipcSide: "TOP",
+		svg: otherSVGStyle, gerbv: otherGerbvColor, stack: 9,
+	},
+	BottomStiffenerLayer: {
+		name: "BottomStiffener", extension: "gbst", fileFunction: "Other,Stiffener-Bot", description: "Bottom stiffener",
+		ipcFunction: "STIFFENER", ipcSide: "BOTTOM",
+		svg: otherSVGStyle, gerbv: otherGerbvColor, stack: 6,
+	},
+	BendAreaLayer: {
+		name: "BendArea", extension: "gbnd", fileFunction: "Other,Bend-Area", description: "Flex bend areas",
+		ipcFunction: "DOCUMENTATION", ipcSide: "ALL",
+		svg: otherSVGStyle, gerbv: otherGerbvColor, stack: 8,
+	},
+	InnerPlaneLayer: {
+		name: "InnerPlane", numbered: true, extension: "gp%v", fileFunction: "Copper,L%v,Inr,Plane", description: "Inner plane, negative (layer %v)",
+		ipcFunction: "PLANE", ipcSide: "INTERNAL",
+		svg: SVGStyle{Color: "#b87333", Opacity: 1, Negative: true}, gerbv: [3]uint8{0xc2, 0x7f, 0x00}, stack: 7,
+	},
+	MechanicalLayer: {
+		name: "Mechanical", numbered: true, extension: "gm%v", fileFunction: "Other,Mechanical-%v", description: "Mechanical %v",
+		ipcFunction: "DOCUMENTATION", ipcSide: "ALL",
+		svg: otherSVGStyle, gerbv: otherGerbvColor,
+	},
+	KeepoutLayer: {
+		name: "Keepout", extension: "gkp", fileFunction: "Other,Keep-out", description: "Keep-out areas",
+		ipcFunction: "DOCUMENTATION", ipcSide: "ALL",
+		svg: otherSVGStyle, gerbv: otherGerbvColor,
+	},
+}
+
+// typeInfo returns the description of the layer's type, with the
+// layer's N substituted if the type is numbered, and whether the type
+// is known.
+func (l *Layer) typeInfo() (layerTypeInfo, bool) {
+	info, ok := layerTypes[l.Type]
+	if info.numbered {
+		info.extension = fmt.Sprintf(info.extension, l.N)
+		info.fileFunction = fmt.Sprintf(info.fileFunction, l.N)
+		info.description = fmt.Sprintf(info.description, l.N)
+	}
+	return info, ok
+}
+
+func (t LayerType) String() string {
+	if info, ok := layerTypes[t]; ok {
+		return info.name
+	}
+	return fmt.Sprintf("LayerType(%d)", int(t))
+}
+
+// opposite returns the layer type with the same function as t on the
+// other side of the board, if t is on the top or bottom side.
+func (t LayerType) opposite() (LayerType, bool) {
+	side := map[string]string{"TOP": "BOTTOM", "BOTTOM": "TOP"}[layerTypes[t].ipcSide]
+	if side == "" {
+		return t, false
+	}
+	for ot, info := range layerTypes {
+		if info.ipcFunction == layerTypes[t].ipcFunction && info.ipcSide == side {
+			return ot, true
+		}
+	}
+	return t, false
+}
+
+// StackedLayerTypes returns the layer types that previews of the whole
+// board (such as WriteSVGPreview) stack, in order from the bottom of the
+// board to the top.
+func StackedLayerTypes() []LayerType {
+	var result []LayerType
+	for t, info := range layerTypes {
+		if info.stack > 0 {
+			result = append(result, t)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		si, sj := layerTypes[result[i]].stack, layerTypes[result[j]].stack
+		return si < sj || si == sj && result[i] < result[j]
+	})
+	return result
+}
+
+// IsCopper reports whether the layer type is a copper layer.
+// Plane layers (see IsNegative) are not, since their primitives are
+// clearances rather than copper.
+func (t LayerType) IsCopper() bool {
+	return t == TopCopperLayer || t == BottomCopperLayer || t == InnerCopperLayer
+}
+
+// IsNegative reports whether the primitives of layers of type t mark
+// the absence of material: the clearances (anti-pads and splits) of an
+// InnerPlaneLayer, which is otherwise solid copper.
+func (t LayerType) IsNegative() bool {
+	return t == InnerPlaneLayer
+}
+
 // Layer represents a printed circuit board layer.
 type Layer struct {
 	// Filename is the filename of the Gerber layer.
 	Filename string
+	// Type is the function of the layer.
+	Type LayerType
+	// N is the copper layer number of an InnerCopperLayer or an
+	// InnerPlaneLayer (see LayerN and PlaneN), or the number of a
+	// MechanicalLayer.
+	N int
+	// Grid, if positive, overrides the design's output grid (see
+	// WithGrid) for this layer.
+	Grid float64
+	// Format, if not nil, overrides the design's coordinate format
+	// (see WithCoordinateFormat) for this layer, e.g. to match the
+	// precision expected by an older CAM package.
+	Format *CoordinateFormat
 	// Primitives represents the collection of primitives.
 	Primitives []Primitive
 	// Apertures represents the apertures used in the layer.
@@ -17,50 +264,284 @@ type Layer struct {
 
 	// apertureMap maps an aperture to its index in the Apertures slice.
 	apertureMap map[string]int
+	// mergedApertures is the number of apertures merged into similar
+	// ones (see WithApertureTolerance).
+	mergedApertures int
+	// tags holds the tags of each tagged primitive (see Tag).
+	tags map[Primitive][]string
+	// attrs holds the attributes of each primitive (see Attributes).
+	attrs map[Primitive]Attributes
+	// openings holds the mask and paste openings declared for copper
+	// primitives, and derived marks primitives generated from them or
+	// from padstacks (see Gerber.DeriveOpenings).
+	openings map[Primitive]Openings
+	derived  map[Primitive]bool
+	// headerHooks and footerHooks are run by WriteGerber (see OnHeader).
+	headerHooks []WriteHook
+	footerHooks []WriteHook
+	// drillKind is the kind of holes ("PTH" or "NPTH") of a layer
+	// written in place of a drill layer (see WithSplitDrills).
+	drillKind string
 	// g is the root Gerber object.
 	g   *Gerber
 	mbb *MBB // cached minimum bounding box
+	// index is the cached spatial index of the primitives (see Query),
+	// and indexed is the Primitives slice that it was built from.
+	index   *spatialIndex
+	indexed []Primitive
 }
 
 // Add adds primitives to a layer.
-// It generates new apertures as necessary.
+// It generates new apertures as necessary, and extends the cached MBBs
+// of the layer and its design. Add is not safe for concurrent use with
+// other methods of the layer; goroutines generating primitives in
+// parallel may build a Batch each and then Merge them.
 func (l *Layer) Add(primitives ...Primitive) {
+	l.addApertures(primitives)
+	l.extendMBB(primitives)
+	indexed := l.indexCurrent()
+	if indexed {
+		for _, p := range primitives {
+			l.index.insert(p, 0)
+		}
+	}
+	l.Primitives = append(l.Primitives, primitives...)
+	if indexed {
+		l.indexed = l.Primitives
+	}
+}
+
+// compound is implemented by primitives made of other primitives that
+// are written with their own apertures (such as StepRepeatT).
+type compound interface {
+	children() []Primitive
+}
+
+// addApertures adds the apertures used by the primitives (and by the
+// children of compound primitives) to the layer.
+func (l *Layer) addApertures(primitives []Primitive) {
+	forEachAperture(primitives, l.addAperture)
+}
+
+// forEachAperture calls f with the aperture of each of the primitives
+// (and of the children of compound primitives) that has one.
+func forEachAperture(primitives []Primitive, f func(a *Aperture)) {
 	for _, p := range primitives {
-		a := p.Aperture()
-		if a == nil {
-			continue // use the default layer
+		if c, ok := p.(compound); ok {
+			forEachAperture(c.children(), f)
 		}
-		id := a.ID()
-		if _, ok := l.apertureMap[id]; ok {
-			continue
+		if a := p.Aperture(); a != nil { // otherwise use the default
+			f(a)
 		}
-		l.apertureMap[id] = len(l.Apertures)
-		l.Apertures = append(l.Apertures, a)
 	}
-	l.Primitives = append(l.Primitives, primitives...)
+}
+
+// addAperture adds an aperture to the layer unless it (or, see
+// WithApertureTolerance, a similar one) is already there.
+func (l *Layer) addAperture(a *Aperture) {
+	id := a.ID()
+	if _, ok := l.apertureMap[id]; ok {
+		return
+	}
+	if i := l.similarAperture(a); i >= 0 {
+		l.apertureMap[id] = i
+		l.mergedApertures++
+		return
+	}
+	l.apertureMap[id] = len(l.Apertures)
+	l.Apertures = append(l.Apertures, a)
+}
+
+// dcodes returns the function that maps the apertures of the layer to
+// their D-codes, given the D-codes returned by apertureCodes.
+func (l *Layer) dcodes(defaultCode int, codes []int) func(a *Aperture) int {
+	return func(a *Aperture) int {
+		if ai, ok := l.apertureMap[a.ID()]; ok && ai >= 0 {
+			return codes[ai]
+		}
+		return defaultCode
+	}
 }
 
 // WriteGerber writes a layer to its corresponding Gerber layer file.
 func (l *Layer) WriteGerber(w io.Writer) error {
-	io.WriteString(w, "%FSLAX36Y36*%\n")
-	io.WriteString(w, "%MOMM*%\n")
-	io.WriteString(w, "%LPD*%\n")
+	return l.WriteGerberContext(context.Background(), w)
+}
+
+// writeBufferSize is the size of the buffer of the output of layers.
+const writeBufferSize = 64 << 10
+
+// WriteGerberContext is like WriteGerber but stops early (returning
+// ctx.Err()) if ctx is canceled while the primitives are being written.
+// The output is buffered, so w need not be. It returns the first error
+// writing to w, or an error if a primitive uses an aperture that is
+// not in the layer (because it was not added with Add).
+func (l *Layer) WriteGerberContext(ctx context.Context, w io.Writer) error {
+	bw := bufio.NewWriterSize(w, writeBufferSize)
+	ew := &errWriter{w: bw}
+	if err := l.writeGerber(ctx, ew); err != nil {
+		if ew.err != nil {
+			return fmt.Errorf("layer %v: %v", l.Filename, ew.err)
+		}
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("layer %v: %v", l.Filename, err)
+	}
+	return nil
+}
+
+func (l *Layer) writeGerber(ctx context.Context, ew *errWriter) error {
+	gw := l.newWriter(ew)
+	var checker *complianceChecker
+	if l.g != nil && l.g.validateOutput {
+		checker = newComplianceChecker(gw.Writer)
+		gw.Writer = checker
+	}
+	defaultCode, codes, err := l.apertureCodes()
+	if err != nil {
+		return fmt.Errorf("layer %v: %v", l.Filename, err)
+	}
+	if err := l.writeHeader(gw, defaultCode); err != nil {
+		return fmt.Errorf("layer %v: %v", l.Filename, err)
+	}
+	if err := l.writeMacros(gw); err != nil {
+		return fmt.Errorf("layer %v: %v", l.Filename, err)
+	}
+	l.writeApertures(gw, codes)
+	gw.dcode = l.dcodes(defaultCode, codes)
+	if checker != nil && checker.err != nil {
+		return fmt.Errorf("layer %v: header: %v", l.Filename, checker.err)
+	}
+
+	pr, reported := l.layerProgress(ctx), 0
+	for n, i := range l.outputOrder(gw, defaultCode, codes) {
+		p := l.Primitives[i]
+		if n%ctxCheckInterval == 0 {
+			pr.add(n - reported)
+			reported = n
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if ew.err != nil {
+				return ew.err
+			}
+		}
+		ai, ok := l.apertureMap[p.Aperture().ID()]
+		if ok {
+			ok = l.hasApertures(p)
+		}
+		if !ok {
+			return fmt.Errorf("layer %v: primitive #%v (%v) uses an aperture that is not in the layer (see Layer.Add)", l.Filename, i, primitiveTypeName(p))
+		}
+		code := defaultCode
+		if ai >= 0 {
+			code = codes[ai]
+		}
+		attrs := l.objectAttributes(p)
+		if err := writeAttributes(gw, attrs); err != nil {
+			return fmt.Errorf("layer %v: primitive #%v: %v", l.Filename, i, err)
+		}
+		if err := p.WriteGerber(gw, code); err != nil {
+			return fmt.Errorf("layer %v: %v", l.Filename, err)
+		}
+		if len(attrs) > 0 {
+			io.WriteString(gw, "%TD*%\n")
+		}
+		if gw.err != nil {
+			return gw.layerErr(l, i, p)
+		}
+		if checker != nil && checker.err != nil {
+			return fmt.Errorf("layer %v: primitive #%v (%v): %v", l.Filename, i, primitiveTypeName(p), checker.err)
+		}
+	}
+	pr.add(len(l.Primitives) - reported)
+
+	if gw.err != nil {
+		return gw.layerErr(l, 0, nil)
+	}
+
+	if err := l.runHooks(gw, l.footerHooks); err != nil {
+		return fmt.Errorf("layer %v: %v", l.Filename, err)
+	}
+	io.WriteString(gw, "M02*\n")
+	if checker != nil {
+		if err := checker.Close(); err != nil {
+			return fmt.Errorf("layer %v: %v", l.Filename, err)
+		}
+	}
+	return ew.err
+}
 
-	io.WriteString(w, "%ADD11C,0.00100*%\n")
-	for i, a := range l.Apertures {
-		a.WriteGerber(w, 12+i)
+// hasApertures reports whether the apertures of the children of a
+// compound primitive (if any) are all in the layer.
+func (l *Layer) hasApertures(p Primitive) bool {
+	c, ok := p.(compound)
+	if !ok {
+		return true
+	}
+	for _, child := range c.children() {
+		if _, ok := l.apertureMap[child.Aperture().ID()]; !ok || !l.hasApertures(child) {
+			return false
+		}
 	}
+	return true
+}
 
-	for _, p := range l.Primitives {
-		ai := l.apertureMap[p.Aperture().ID()]
-		p.WriteGerber(w, 12+ai)
+// writeHeader writes the header of the layer's file, up to and
+// including the definition of the default aperture.
+func (l *Layer) writeHeader(gw *writer, defaultCode int) error {
+	gw.header()
+	if l.g != nil && l.g.x2 {
+		l.writeX2(gw)
+	}
+	if l.g != nil && l.g.revision != "" {
+		l.writeProjectID(gw)
 	}
+	if err := l.runHooks(gw, l.headerHooks); err != nil {
+		return err
+	}
+	io.WriteString(gw, "%LPD*%\n")
 
-	io.WriteString(w, "M02*\n")
+	defaultSize := defaultApertureSize
+	if l.g != nil {
+		defaultSize = l.g.defaultApertureSize
+	}
+	if defaultSize > 0 {
+		fmt.Fprintf(gw, "%%ADD%vC,%v*%%\n", defaultCode, gw.size(defaultSize))
+	} else {
+		gw.omitted = defaultCode
+	}
 	return nil
 }
 
+// newWriter returns a writer for the layer's design that uses the
+// layer's coordinate format and output grid.
+func (l *Layer) newWriter(w io.Writer) *writer {
+	gw := newWriter(w, l.g)
+	if l.Format != nil {
+		f := *l.Format
+		if l.g != nil && l.g.autoFormat {
+			f = l.g.fittedFormat(f)
+		}
+		gw.setFormat(f)
+	}
+	gw.setGrid(l.grid())
+	return gw
+}
+
+// grid returns the output grid (in mm) of the layer, or 0 for none.
+func (l *Layer) grid() float64 {
+	if l.Grid > 0 || l.g == nil {
+		return l.Grid
+	}
+	return l.g.grid
+}
+
 // MBB returns the minimum bounding box of the layer in millimeters.
+// An empty layer returns an empty MBB; use IsEmpty to distinguish
+// this case from a layer whose primitives are all at the origin.
+// The result is cached (see InvalidateBounds).
 func (l *Layer) MBB() MBB {
 	if l.mbb != nil {
 		return *l.mbb
@@ -74,18 +555,66 @@ func (l *Layer) MBB() MBB {
 		l.mbb.Join(&v)
 	}
 	if l.mbb == nil { // no primitives
-		log.Printf("No primivites on layer %v", l.Filename)
 		l.mbb = &MBB{}
 	}
 
 	return *l.mbb
 }
 
-func (g *Gerber) makeLayer(extension string) *Layer {
+// extendMBB extends the cached MBBs (if any) of the layer and its
+// design by those of the primitives, which are about to be added.
+func (l *Layer) extendMBB(primitives []Primitive) {
+	if len(primitives) == 0 || (l.mbb == nil && (l.g == nil || l.g.cachedMBB() == nil)) {
+		return
+	}
+	var mbb MBB
+	for i, p := range primitives {
+		v := p.MBB()
+		if i == 0 {
+			mbb = v
+			continue
+		}
+		mbb.Join(&v)
+	}
+	l.joinMBB(mbb)
+}
+
+// joinMBB extends the cached MBBs of the layer and its design (if any)
+// by mbb, the MBB of primitives about to be added.
+func (l *Layer) joinMBB(mbb MBB) {
+	if l.mbb != nil {
+		if len(l.Primitives) == 0 { // the empty MBB of an empty layer
+			l.mbb = &mbb
+		} else {
+			l.mbb.Join(&mbb)
+		}
+	}
+	if l.g != nil {
+		l.g.mu.Lock()
+		if l.g.mbb != nil {
+			l.g.mbb.Join(&mbb)
+		}
+		l.g.mu.Unlock()
+	}
+}
+
+// IsEmpty reports whether the layer has no primitives.
+func (l *Layer) IsEmpty() bool {
+	return len(l.Primitives) == 0
+}
+
+func (g *Gerber) makeLayer(t LayerType, n int) *Layer {
 	layer := &Layer{
-		Filename:    g.FilenamePrefix + "." + extension,
+		Type:        t,
+		N:           n,
 		apertureMap: map[string]int{"default": -1},
+		g:           g,
+	}
+	naming := g.naming
+	if naming == nil {
+		naming = ProtelNames
 	}
+	layer.Filename = naming.Filename(g.FilenamePrefix, layer)
 	g.Layers = append(g.Layers, layer)
 	return layer
 }
@@ -93,53 +622,133 @@ func (g *Gerber) makeLayer(extension string) *Layer {
 // TopCopper adds a top copper layer to the design
 // and returns the layer.
 func (g *Gerber) TopCopper() *Layer {
-	return g.makeLayer("gtl")
+	return g.makeLayer(TopCopperLayer, 0)
 }
 
 // TopSolderMask adds a top solder mask layer to the design
 // and returns the layer.
 func (g *Gerber) TopSolderMask() *Layer {
-	return g.makeLayer("gts")
+	return g.makeLayer(TopSolderMaskLayer, 0)
 }
 
 // TopSilkscreen adds a top silkscreen layer to the design
 // and returns the layer.
 func (g *Gerber) TopSilkscreen() *Layer {
-	return g.makeLayer("gto")
+	return g.makeLayer(TopSilkscreenLayer, 0)
 }
 
 // BottomCopper adds a bottom copper layer to the design
 // and returns the layer.
 func (g *Gerber) BottomCopper() *Layer {
-	return g.makeLayer("gbl")
+	return g.makeLayer(BottomCopperLayer, 0)
 }
 
 // BottomSolderMask adds a bottom solder mask layer to the design
 // and returns the layer.
 func (g *Gerber) BottomSolderMask() *Layer {
-	return g.makeLayer("gbs")
+	return g.makeLayer(BottomSolderMaskLayer, 0)
 }
 
 // BottomSilkscreen adds a bottom silkscreen layer to the design
 // and returns the layer.
 func (g *Gerber) BottomSilkscreen() *Layer {
-	return g.makeLayer("gbo")
+	return g.makeLayer(BottomSilkscreenLayer, 0)
 }
 
 // LayerN adds a layer-n copper layer to a multi-layer design
 // and returns the layer.
 func (g *Gerber) LayerN(n int) *Layer {
-	return g.makeLayer(fmt.Sprintf("gl%v", n))
+	return g.makeLayer(InnerCopperLayer, n)
+}
+
+// PlaneN adds a layer-n negative plane layer (such as a ground or
+// power plane) to a multi-layer design and returns the layer.
+// Its primitives are the clearances of the plane (see IsNegative).
+func (g *Gerber) PlaneN(n int) *Layer {
+	return g.makeLayer(InnerPlaneLayer, n)
 }
 
 // Drill adds a drill layer to the design
 // and returns the layer.
 func (g *Gerber) Drill() *Layer {
-	return g.makeLayer("drl")
+	return g.makeLayer(DrillLayer, 0)
 }
 
 // Outline adds an outline layer to the design
 // and returns the layer.
 func (g *Gerber) Outline() *Layer {
-	return g.makeLayer("gko")
+	return g.makeLayer(OutlineLayer, 0)
+}
+
+// TopSolderPaste adds a top solder paste layer to the design
+// and returns the layer.
+func (g *Gerber) TopSolderPaste() *Layer {
+	return g.makeLayer(TopSolderPasteLayer, 0)
+}
+
+// BottomSolderPaste adds a bottom solder paste layer to the design
+// and returns the layer.
+func (g *Gerber) BottomSolderPaste() *Layer {
+	return g.makeLayer(BottomSolderPasteLayer, 0)
+}
+
+// Flexible PCBs use coverlay (a laminated film with openings) instead
+// of solder mask, stiffeners to support areas carrying components,
+// and bend areas that must not carry solid copper or stiffeners.
+// DeriveOpenings cuts the mask openings of a side with a coverlay
+// layer in the coverlay.
+
+// TopCoverlay adds a top coverlay layer (the openings in the film)
+// to the design and returns the layer.
+func (g *Gerber) TopCoverlay() *Layer {
+	return g.makeLayer(TopCoverlayLayer, 0)
+}
+
+// BottomCoverlay adds a bottom coverlay layer (the openings in the
+// film) to the design and returns the layer.
+func (g *Gerber) BottomCoverlay() *Layer {
+	return g.makeLayer(BottomCoverlayLayer, 0)
+}
+
+// TopStiffener adds a top stiffener outline layer to the design
+// and returns the layer.
+func (g *Gerber) TopStiffener() *Layer {
+	return g.makeLayer(TopStiffenerLayer, 0)
+}
+
+// BottomStiffener adds a bottom stiffener outline layer to the design
+// and returns the layer.
+func (g *Gerber) BottomStiffener() *Layer {
+	return g.makeLayer(BottomStiffenerLayer, 0)
+}
+
+// BendArea adds a bend area annotation layer to the design and returns
+// the layer. Bend areas are drawn as filled polygons (see AddBendArea).
+func (g *Gerber) BendArea() *Layer {
+	return g.makeLayer(BendAreaLayer, 0)
+}
+
+// Mechanical adds mechanical layer n (for dimensions, fabrication
+// drawings, and the like) to the design and returns the layer.
+// As CAM tools (and ParseDesign) commonly read "prefix.gm1" as the board
+// outline, mechanical layers are usually numbered from 2.
+func (g *Gerber) Mechanical(n int) *Layer {
+	return g.makeLayer(MechanicalLayer, n)
+}
+
+// Keepout adds a keep-out layer to the design and returns the layer.
+// Its polygons and regions are keepouts of the design's copper layers
+// (see Keepouts).
+func (g *Gerber) Keepout() *Layer {
+	return g.makeLayer(KeepoutLayer, 0)
+}
+
+// sortedIndices returns the indices of codes in increasing order of code.
+func sortedIndices(codes []int) []int {
+	indices := make([]int, len(codes))
+	for i := range indices {
+		indices[i] = i
+	}
+	sort.Slice(indices, func(i, j int) bool { return codes[indices[i]] < codes[indices[j]] })
+	return indices
 }
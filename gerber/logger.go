@@ -0,0 +1,25 @@
+package gerber
+
+// Logger is the interface used by this package to report non-fatal
+// conditions (such as a font substitution) that do not warrant an error.
+// It is satisfied by *log.Logger.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// nopLogger discards all messages.
+type nopLogger struct{}
+
+func (nopLogger) Printf(format string, v ...interface{}) {}
+
+// logger is the package-wide Logger. By default, nothing is logged.
+var logger Logger = nopLogger{}
+
+// SetLogger sets the Logger used by this package.
+// Passing nil disables logging (the default).
+func SetLogger(l Logger) {
+	if l == nil {
+		l = nopLogger{}
+	}
+	logger = l
+}
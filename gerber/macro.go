@@ -0,0 +1,706 @@
+package gerber
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	registerPrimitive("flash", func() Primitive { return &FlashT{} })
+}
+
+// The codes of the aperture macro primitives (see MacroPrimitive).
+const (
+	MacroCircleCode     = 1
+	MacroOutlineCode    = 4
+	MacroPolygonCode    = 5
+	MacroMoireCode      = 6
+	MacroThermalCode    = 7
+	MacroVectorLineCode = 20
+	MacroCenterLineCode = 21
+)
+
+// MacroPrimitive represents one primitive of an aperture macro: its
+// code and its modifiers, in the order defined by the Gerber
+// specification. Each modifier is an arithmetic expression (using +, -,
+// x for multiplication, /, and parentheses) of decimal numbers and the
+// macro's parameters $1, $2, and so on; parameters not provided by an
+// aperture are 0. Lengths are in millimeters and rotations are in
+// degrees counterclockwise about the macro's origin.
+type MacroPrimitive struct {
+	Code      int      `json:"code"`
+	Modifiers []string `json:"modifiers"`
+}
+
+// MacroCircle returns a circle of the given diameter centered on (x,y).
+func MacroCircle(diameter, x, y string) MacroPrimitive {
+	return MacroPrimitive{Code: MacroCircleCode, Modifiers: []string{"1", diameter, x, y, "0"}}
+}
+
+// MacroVectorLine returns a line of the given width (with square ends)
+// from (x1,y1) to (x2,y2).
+func MacroVectorLine(width, x1, y1, x2, y2 string) MacroPrimitive {
+	return MacroPrimitive{Code: MacroVectorLineCode, Modifiers: []string{"1", width, x1, y1, x2, y2, "0"}}
+}
+
+// MacroCenterLine returns a width by height rectangle centered on (x,y).
+func MacroCenterLine(width, height, x, y string) MacroPrimitive {
+	return MacroPrimitive{Code: MacroCenterLineCode, Modifiers: []string{"1", width, height, x, y, "0"}}
+}
+
+// MacroOutline returns the polygon whose vertices have the coordinates
+// x0, y0, x1, y1, and so on. The outline is closed automatically.
+func MacroOutline(coords ...string) MacroPrimitive {
+	mods := []string{"1", strconv.Itoa(len(coords) / 2)}
+	mods = append(mods, coords...)
+	if len(coords) >= 2 {
+		mods = append(mods, coords[0], coords[1])
+	}
+	return MacroPrimitive{Code: MacroOutlineCode, Modifiers: append(mods, "0")}
+}
+
+// MacroPolygon returns a regular polygon with the given number of
+// vertices (3 to 12) centered on (x,y), whose circumscribed circle has
+// the given diameter. Its first vertex lies on the positive X axis.
+func MacroPolygon(vertices, x, y, diameter string) MacroPrimitive {
+	return MacroPrimitive{Code: MacroPolygonCode, Modifiers: []string{"1", vertices, x, y, diameter, "0"}}
+}
+
+// MacroMoire returns a moiré (a target of up to rings concentric rings
+// of the given thickness and gap, with outer diameter outer, and a
+// crosshair of the given thickness and length) centered on (x,y), as
+// used for fiducials and alignment marks.
+func MacroMoire(x, y, outer, thickness, gap, rings, crossThickness, crossLength string) MacroPrimitive {
+	return MacroPrimitive{Code: MacroMoireCode, Modifiers: []string{x, y, outer, thickness, gap, rings, crossThickness, crossLength, "0"}}
+}
+
+// MacroThermal returns a thermal relief (a ring with outer and inner
+// diameters divided into four parts by gaps of the given width along
+// its axes) centered on (x,y).
+func MacroThermal(x, y, outer, inner, gap string) MacroPrimitive {
+	return MacroPrimitive{Code: MacroThermalCode, Modifiers: []string{x, y, outer, inner, gap, "0"}}
+}
+
+// Rotated returns a copy of the primitive rotated by degrees (an
+// expression) about the macro's origin.
+func (p MacroPrimitive) Rotated(degrees string) MacroPrimitive {
+	mods := append([]string{}, p.Modifiers...)
+	if _, _, rotation, err := p.layout(); err == nil {
+		if rotation < 0 {
+			mods = append(mods, degrees)
+		} else {
+			mods[rotation] = degrees
+		}
+	}
+	return MacroPrimitive{Code: p.Code, Modifiers: mods}
+}
+
+// Clear returns a copy of the primitive with its exposure off, so that
+// it erases the parts of the macro's earlier primitives that it covers.
+// Moirés and thermals cannot be cleared and are returned unchanged.
+func (p MacroPrimitive) Clear() MacroPrimitive {
+	mods := append([]string{}, p.Modifiers...)
+	if p.Code != MacroMoireCode && p.Code != MacroThermalCode && len(mods) > 0 {
+		mods[0] = "0"
+	}
+	return MacroPrimitive{Code: p.Code, Modifiers: mods}
+}
+
+// layout returns the indexes of the primitive's length modifiers and
+// of those that are X coordinates, and the index of its rotation
+// modifier (-1 if it is omitted).
+func (p MacroPrimitive) layout() (lengths, xs []int, rotation int, err error) {
+	n := len(p.Modifiers)
+	counts := map[int]int{MacroVectorLineCode: 7, MacroCenterLineCode: 6, MacroPolygonCode: 6, MacroMoireCode: 9, MacroThermalCode: 6}
+	if want, ok := counts[p.Code]; ok && n != want {
+		return nil, nil, 0, fmt.Errorf("macro primitive %v has %v modifiers, want %v", p.Code, n, want)
+	}
+	switch p.Code {
+	case MacroCircleCode:
+		if n != 4 && n != 5 {
+			return nil, nil, 0, fmt.Errorf("macro circle has %v modifiers, want 4 or 5", n)
+		}
+		if rotation = -1; n == 5 {
+			rotation = 4
+		}
+		return []int{1, 2, 3}, []int{2}, rotation, nil
+	case MacroVectorLineCode:
+		return []int{1, 2, 3, 4, 5}, []int{2, 4}, 6, nil
+	case MacroCenterLineCode:
+		return []int{1, 2, 3, 4}, []int{3}, 5, nil
+	case MacroOutlineCode:
+		if n < 11 || n%2 == 0 {
+			return nil, nil, 0, fmt.Errorf("macro outline has %v modifiers, want an odd number of at least 11", n)
+		}
+		for i := 2; i < n-1; i++ {
+			lengths = append(lengths, i)
+			if i%2 == 0 {
+				xs = append(xs, i)
+			}
+		}
+		return lengths, xs, n - 1, nil
+	case MacroPolygonCode:
+		return []int{2, 3, 4}, []int{2}, 5, nil
+	case MacroMoireCode:
+		return []int{0, 1, 2, 3, 4, 6, 7}, []int{0}, 8, nil
+	case MacroThermalCode:
+		return []int{0, 1, 2, 3, 4}, []int{0}, 5, nil
+	}
+	return nil, nil, 0, fmt.Errorf("unknown macro primitive code %v", p.Code)
+}
+
+// check verifies the primitive's modifiers.
+func (p MacroPrimitive) check() error {
+	if _, _, _, err := p.layout(); err != nil {
+		return err
+	}
+	for _, m := range p.Modifiers {
+		if _, err := evalMacroExpr(m, nil); err != nil {
+			return fmt.Errorf("macro primitive %v: %v", p.Code, err)
+		}
+	}
+	return nil
+}
+
+// ApertureMacro represents a named, parameterized aperture template
+// (%AM*%) made of macro primitives, for pad shapes (such as rounded
+// rectangles, chamfered pads, and custom fiducials) that the standard
+// apertures cannot represent. Its apertures (see Aperture) are written
+// as native macro apertures, and are flashed with Flash.
+type ApertureMacro struct {
+	Name       string           `json:"name"`
+	Primitives []MacroPrimitive `json:"primitives"`
+}
+
+// NewApertureMacro returns an aperture macro, or an error if its name
+// is not a valid Gerber name or one of its primitives is invalid.
+func NewApertureMacro(name string, primitives ...MacroPrimitive) (*ApertureMacro, error) {
+	m := &ApertureMacro{Name: name, Primitives: primitives}
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// check verifies the macro's name and primitives.
+func (m *ApertureMacro) check() error {
+	if !validAttributeName(m.Name) || (len(m.Name) == 1 && strings.Contains("CROP", m.Name)) {
+		return fmt.Errorf("invalid aperture macro name %q", m.Name)
+	}
+	if len(m.Primitives) == 0 {
+		return fmt.Errorf("aperture macro %v has no primitives", m.Name)
+	}
+	for _, p := range m.Primitives {
+		if err := p.check(); err != nil {
+			return fmt.Errorf("aperture macro %v: %v", m.Name, err)
+		}
+	}
+	return nil
+}
+
+// Aperture returns the aperture instantiating the macro with the
+// parameters ($1, $2, and so on).
+func (m *ApertureMacro) Aperture(params ...float64) *Aperture {
+	return &Aperture{Macro: m, Params: params}
+}
+
+// ParseApertureMacro parses the aperture macro definition def (a %AM*%
+// extended command, with or without its percent signs). Comments are
+// skipped; variable definitions ($n=...) are not supported. Lengths are
+// in the units of the file the definition came from.
+func ParseApertureMacro(def string) (*ApertureMacro, error) {
+	words := strings.Split(strings.Trim(strings.TrimSpace(def), "%"), "*")
+	name := strings.TrimSpace(words[0])
+	if !strings.HasPrefix(name, "AM") {
+		return nil, fmt.Errorf("invalid aperture macro %q", def)
+	}
+	m := &ApertureMacro{Name: name[2:]}
+	for _, w := range words[1:] {
+		p, ok, err := parseMacroPrimitive(w)
+		if err != nil {
+			return nil, fmt.Errorf("aperture macro %v: %v", m.Name, err)
+		}
+		if ok {
+			m.Primitives = append(m.Primitives, p)
+		}
+	}
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// parseMacroPrimitive parses one word of a macro definition. It returns
+// false for empty words and comments.
+func parseMacroPrimitive(w string) (MacroPrimitive, bool, error) {
+	if w = strings.TrimSpace(w); w == "" || w == "0" || strings.HasPrefix(w, "0 ") {
+		return MacroPrimitive{}, false, nil
+	}
+	w = strings.Join(strings.Fields(w), "")
+	if strings.HasPrefix(w, "$") {
+		return MacroPrimitive{}, false, fmt.Errorf("variable definition %q is not supported", w)
+	}
+	fields := strings.Split(w, ",")
+	code, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return MacroPrimitive{}, false, fmt.Errorf("invalid macro primitive %q", w)
+	}
+	return MacroPrimitive{Code: code, Modifiers: fields[1:]}, true, nil
+}
+
+// writeGerber writes the macro definition in the writer's units.
+func (m *ApertureMacro) writeGerber(gw *writer) error {
+	if err := m.check(); err != nil {
+		return err
+	}
+	if gw.units == UnitsInch {
+		m = m.transformed(m.Name, 1/mmPerInch, false, 0)
+	}
+	fmt.Fprintf(gw, "%%AM%v*\n", m.Name)
+	for _, p := range m.Primitives {
+		fmt.Fprintf(gw, "%v,%v*\n", p.Code, strings.Join(p.Modifiers, ","))
+	}
+	io.WriteString(gw, "%\n")
+	return nil
+}
+
+// writeMacros writes the definitions of the aperture macros used by the
+// layer's apertures, which must precede the apertures.
+func (l *Layer) writeMacros(gw *writer) error {
+	defined := map[string]*ApertureMacro{}
+	for _, a := range l.Apertures {
+		m := a.Macro
+		if m == nil {
+			continue
+		}
+		if d, ok := defined[m.Name]; ok {
+			if !reflect.DeepEqual(d.Primitives, m.Primitives) {
+				return fmt.Errorf("two different aperture macros are named %v", m.Name)
+			}
+			continue
+		}
+		defined[m.Name] = m
+		if err := m.writeGerber(gw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// transformed returns a copy of the macro (with the given name) whose
+// image is scaled by scale, mirrored about the Y axis if mirror is
+// set, and then rotated counterclockwise by degrees.
+func (m *ApertureMacro) transformed(name string, scale float64, mirror bool, degrees float64) *ApertureMacro {
+	result := &ApertureMacro{Name: name}
+	for _, p := range m.Primitives {
+		lengths, xs, rotation, err := p.layout()
+		if err != nil {
+			result.Primitives = append(result.Primitives, p)
+			continue
+		}
+		mods := append([]string{}, p.Modifiers...)
+		if rotation < 0 && (mirror || degrees != 0) {
+			rotation = len(mods)
+			mods = append(mods, "0")
+		}
+		if scale != 1 {
+			for _, i := range lengths {
+				mods[i] = scaleExpr(mods[i], scale)
+			}
+		}
+		if mirror {
+			for _, i := range append(xs, rotation) {
+				mods[i] = negateExpr(mods[i])
+			}
+		}
+		if degrees != 0 {
+			mods[rotation] = addExpr(mods[rotation], degrees)
+		}
+		result.Primitives = append(result.Primitives, MacroPrimitive{Code: p.Code, Modifiers: mods})
+	}
+	return result
+}
+
+// scaleExpr returns the expression e multiplied by scale (or, for
+// conversions from millimeters to inches, divided by its inverse).
+func scaleExpr(e string, scale float64) string {
+	if v, err := strconv.ParseFloat(e, 64); err == nil {
+		return fmtFloat(v * scale)
+	}
+	if inv := 1 / scale; math.Abs(inv-math.Round(inv*1e6)/1e6) < 1e-9 && scale < 1 {
+		return fmt.Sprintf("(%v)/%v", e, fmtFloat(inv))
+	}
+	return fmt.Sprintf("(%v)x%v", e, fmtFloat(scale))
+}
+
+// negateExpr returns the negated expression e.
+func negateExpr(e string) string {
+	if v, err := strconv.ParseFloat(e, 64); err == nil {
+		return fmtFloat(-v)
+	}
+	return fmt.Sprintf("-(%v)", e)
+}
+
+// addExpr returns the expression e plus v.
+func addExpr(e string, v float64) string {
+	if w, err := strconv.ParseFloat(e, 64); err == nil {
+		return fmtFloat(w + v)
+	}
+	return fmt.Sprintf("(%v)+%v", e, fmtFloat(v))
+}
+
+// Image returns the primitives (circles and polygons, in the macro's
+// units) that make up the macro's image for the parameters, in order.
+// Primitives whose exposure is off are wrapped in ClearT.
+func (m *ApertureMacro) Image(params ...float64) ([]Primitive, error) {
+	var result []Primitive
+	for _, p := range m.Primitives {
+		v := make([]float64, len(p.Modifiers))
+		for i, e := range p.Modifiers {
+			var err error
+			if v[i], err = evalMacroExpr(e, params); err != nil {
+				return nil, fmt.Errorf("aperture macro %v: %v", m.Name, err)
+			}
+		}
+		prims, err := p.image(v)
+		if err != nil {
+			return nil, fmt.Errorf("aperture macro %v: %v", m.Name, err)
+		}
+		result = append(result, prims...)
+	}
+	return result, nil
+}
+
+// macroArcSegments is the number of segments of each quarter circle of
+// the polygons approximating thermals.
+const macroArcSegments = 16
+
+// image returns the primitives of the macro primitive with the
+// evaluated modifiers v.
+func (p MacroPrimitive) image(v []float64) ([]Primitive, error) {
+	if _, _, _, err := p.layout(); err != nil {
+		return nil, err
+	}
+	rotation := 0.0
+	if p.Code != MacroCircleCode || len(v) == 5 {
+		rotation = v[len(v)-1]
+	}
+	polygon := func(pts ...Pt) Primitive {
+		for i, pt := range pts {
+			pts[i] = RotatePt(pt, Pt{}, rotation)
+		}
+		return Polygon(Pt{}, true, pts, 0)
+	}
+	rect := func(c Pt, w, h float64) Primitive {
+		hw, hh := 0.5*w, 0.5*h
+		return polygon(Pt{c[0] - hw, c[1] - hh}, Pt{c[0] + hw, c[1] - hh}, Pt{c[0] + hw, c[1] + hh}, Pt{c[0] - hw, c[1] + hh})
+	}
+	var prims []Primitive
+	switch p.Code {
+	case MacroCircleCode:
+		prims = []Primitive{Circle(RotatePt(Pt{v[2], v[3]}, Pt{}, rotation), v[1])}
+	case MacroVectorLineCode:
+		p1, p2 := Pt{v[2], v[3]}, Pt{v[4], v[5]}
+		length := Distance(p1, p2)
+		if length == 0 {
+			return nil, nil
+		}
+		n := Pt{-0.5 * v[1] * (p2[1] - p1[1]) / length, 0.5 * v[1] * (p2[0] - p1[0]) / length}
+		prims = []Primitive{polygon(Pt{p1[0] - n[0], p1[1] - n[1]}, Pt{p2[0] - n[0], p2[1] - n[1]}, Pt{p2[0] + n[0], p2[1] + n[1]}, Pt{p1[0] + n[0], p1[1] + n[1]})}
+	case MacroCenterLineCode:
+		prims = []Primitive{rect(Pt{v[3], v[4]}, v[1], v[2])}
+	case MacroOutlineCode:
+		var pts []Pt
+		for i := 2; i+1 < len(v)-1; i += 2 {
+			pts = append(pts, Pt{v[i], v[i+1]})
+		}
+		if int(v[1]) != len(pts)-1 {
+			return nil, fmt.Errorf("macro outline of %v points has %v vertices", len(pts), v[1])
+		}
+		prims = []Primitive{polygon(pts[:len(pts)-1]...)}
+	case MacroPolygonCode:
+		n := int(v[1])
+		if n < 3 || n > 12 {
+			return nil, fmt.Errorf("macro polygon has %v vertices, want 3 to 12", v[1])
+		}
+		var pts []Pt
+		for i := 0; i < n; i++ {
+			pts = append(pts, PolarFrom(Pt{v[2], v[3]}, 0.5*v[4], 360*float64(i)/float64(n)))
+		}
+		prims = []Primitive{polygon(pts...)}
+	case MacroMoireCode:
+		c := RotatePt(Pt{v[0], v[1]}, Pt{}, rotation)
+		for i, d := 0, v[2]; i < int(v[5]) && d > 0; i, d = i+1, d-2*(v[3]+v[4]) {
+			prims = append(prims, Circle(c, d))
+			if inner := d - 2*v[3]; inner > 0 {
+				prims = append(prims, Clear(Circle(c, inner)))
+			}
+		}
+		prims = append(prims, rect(Pt{v[0], v[1]}, v[7], v[6]), rect(Pt{v[0], v[1]}, v[6], v[7]))
+		return prims, nil
+	case MacroThermalCode:
+		c, outer, inner, h := Pt{v[0], v[1]}, 0.5*v[2], 0.5*v[3], 0.5*v[4]
+		if h >= outer {
+			return nil, nil
+		}
+		for q := 0; q < 4; q++ {
+			// The part of the ring in the first quadrant, outside the
+			// gaps, rotated into quadrant q.
+			var pts []Pt
+			a0 := math.Asin(h / outer)
+			for i := 0; i <= macroArcSegments; i++ {
+				pts = append(pts, PolarFrom(Pt{}, outer, Degrees(a0+(math.Pi/2-2*a0)*float64(i)/macroArcSegments)))
+			}
+			if h < inner {
+				a1 := math.Asin(h / inner)
+				for i := macroArcSegments; i >= 0; i-- {
+					pts = append(pts, PolarFrom(Pt{}, inner, Degrees(a1+(math.Pi/2-2*a1)*float64(i)/macroArcSegments)))
+				}
+			} else {
+				pts = append(pts, Pt{h, h})
+			}
+			for i, pt := range pts {
+				pt = RotatePt(pt, Pt{}, 90*float64(q))
+				pts[i] = Pt{c[0] + pt[0], c[1] + pt[1]}
+			}
+			prims = append(prims, polygon(pts...))
+		}
+		return prims, nil
+	}
+	if v[0] == 0 {
+		for i, prim := range prims {
+			prims[i] = Clear(prim)
+		}
+	}
+	return prims, nil
+}
+
+// evalMacroExpr evaluates the arithmetic expression of a macro modifier
+// with the parameters ($1 is params[0]).
+func evalMacroExpr(e string, params []float64) (float64, error) {
+	p := &macroExprParser{s: strings.Join(strings.Fields(e), ""), params: params}
+	v, err := p.expr()
+	if err == nil && p.i < len(p.s) {
+		err = fmt.Errorf("unexpected %q", p.s[p.i:])
+	}
+	if err != nil {
+		return 0, fmt.Errorf("invalid expression %q: %v", e, err)
+	}
+	return v, nil
+}
+
+// macroExprParser is a recursive descent parser of macro expressions.
+type macroExprParser struct {
+	s      string
+	i      int
+	params []float64
+}
+
+func (p *macroExprParser) expr() (float64, error) {
+	v, err := p.term()
+	for err == nil && p.i < len(p.s) && (p.s[p.i] == '+' || p.s[p.i] == '-') {
+		op := p.s[p.i]
+		p.i++
+		var w float64
+		if w, err = p.term(); op == '+' {
+			v += w
+		} else {
+			v -= w
+		}
+	}
+	return v, err
+}
+
+func (p *macroExprParser) term() (float64, error) {
+	v, err := p.factor()
+	for err == nil && p.i < len(p.s) && (p.s[p.i] == 'x' || p.s[p.i] == 'X' || p.s[p.i] == '/') {
+		op := p.s[p.i]
+		p.i++
+		var w float64
+		if w, err = p.factor(); op == '/' {
+			v /= w
+		} else {
+			v *= w
+		}
+	}
+	return v, err
+}
+
+func (p *macroExprParser) factor() (float64, error) {
+	if p.i >= len(p.s) {
+		return 0, errors.New("unexpected end")
+	}
+	switch c := p.s[p.i]; c {
+	case '+', '-':
+		p.i++
+		v, err := p.factor()
+		if c == '-' {
+			v = -v
+		}
+		return v, err
+	case '(':
+		p.i++
+		v, err := p.expr()
+		if err != nil {
+			return 0, err
+		}
+		if p.i >= len(p.s) || p.s[p.i] != ')' {
+			return 0, errors.New("missing )")
+		}
+		p.i++
+		return v, nil
+	}
+	start := p.i
+	if p.s[p.i] == '$' {
+		p.i++
+	}
+	digits := p.i
+	for p.i < len(p.s) && (p.s[p.i] == '.' || (p.s[p.i] >= '0' && p.s[p.i] <= '9')) {
+		p.i++
+	}
+	if start < digits {
+		n, err := strconv.Atoi(p.s[digits:p.i])
+		if err != nil || n < 1 {
+			return 0, fmt.Errorf("invalid parameter %q", p.s[start:p.i])
+		}
+		if n > len(p.params) {
+			return 0, nil
+		}
+		return p.params[n-1], nil
+	}
+	v, err := strconv.ParseFloat(p.s[start:p.i], 64)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected %q", p.s[start:])
+	}
+	return v, nil
+}
+
+// FlashT represents a flash of an aperture macro (instantiated with
+// the parameters) at Center, and satisfies the Primitive interface.
+type FlashT struct {
+	Center Pt
+	Macro  *ApertureMacro
+	Params []float64
+	mbb    *MBB // cached minimum bounding box
+}
+
+// Flash returns a flash of the aperture macro with the parameters.
+func Flash(center Pt, macro *ApertureMacro, params ...float64) *FlashT {
+	return &FlashT{Center: center, Macro: macro, Params: params}
+}
+
+// WriteGerber writes the primitive to the Gerber file.
+func (f *FlashT) WriteGerber(w io.Writer, apertureIndex int) error {
+	gw := toWriter(w)
+	gw.aperture(apertureIndex)
+	gw.flash(f.Center[0], f.Center[1])
+	return nil
+}
+
+// Aperture returns the primitive's macro aperture.
+func (f *FlashT) Aperture() *Aperture {
+	return f.Macro.Aperture(f.Params...)
+}
+
+// MBB returns the minimum bounding box of the exposed parts of the
+// macro's image in millimeters.
+func (f *FlashT) MBB() MBB {
+	if f.mbb != nil {
+		return *f.mbb
+	}
+	mbb := MBB{Min: f.Center, Max: f.Center}
+	image, _ := f.Macro.Image(f.Params...)
+	first := true
+	for _, p := range image {
+		if _, ok := p.(*ClearT); ok {
+			continue
+		}
+		v := p.MBB()
+		v = MBB{Min: Pt{f.Center[0] + v.Min[0], f.Center[1] + v.Min[1]}, Max: Pt{f.Center[0] + v.Max[0], f.Center[1] + v.Max[1]}}
+		if first {
+			mbb, first = v, false
+		} else {
+			mbb.Join(&v)
+		}
+	}
+	f.mbb = &mbb
+	return mbb
+}
+
+func (f *FlashT) invalidateBounds() { f.mbb = nil }
+
+// Transform returns a transformed copy of the flash. Rotation,
+// mirroring, and scaling are applied by a copy of the macro (whose name
+// records the transformation).
+func (f *FlashT) Transform(t Transform) Primitive {
+	center := t.Apply(f.Center)
+	mirror := t.det() < 0
+	degrees := Degrees(t.rotation())
+	if mirror {
+		degrees += 180
+	}
+	if degrees = NormalizeAngle(degrees); math.Abs(degrees-360) < validationEps || math.Abs(degrees) < validationEps {
+		degrees = 0
+	}
+	scale := t.scaleFactor()
+	if math.Abs(scale-1) < validationEps {
+		scale = 1
+	}
+	if !mirror && degrees == 0 && scale == 1 {
+		return Flash(center, f.Macro, f.Params...)
+	}
+	name := f.Macro.Name + "_"
+	if mirror {
+		name += "M"
+	}
+	if degrees != 0 {
+		name += "R" + fmtFloat(degrees)
+	}
+	if scale != 1 {
+		name += "S" + fmtFloat(scale)
+	}
+	return Flash(center, f.Macro.transformed(name, scale, mirror, degrees), f.Params...)
+}
+
+// Contains reports whether pt lies within the exposed parts of the
+// macro's image.
+func (f *FlashT) Contains(pt Pt) bool {
+	image, err := f.Macro.Image(f.Params...)
+	if err != nil {
+		return false
+	}
+	local := Pt{pt[0] - f.Center[0], pt[1] - f.Center[1]}
+	for i := len(image) - 1; i >= 0; i-- {
+		if c, ok := image[i].(*ClearT); ok {
+			if PrimitiveContains(c.Primitive, local) {
+				return false
+			}
+		} else if PrimitiveContains(image[i], local) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *FlashT) String() string {
+	return fmt.Sprintf("Flash(%v%v, %v)", f.Macro.Name, fmtParams(f.Params), fmtPt(f.Center))
+}
+
+// fmtParams returns the parameters of a macro aperture as they are
+// written in its definition (%ADD*%): "" or a comma followed by the
+// parameters separated by X.
+func fmtParams(params []float64) string {
+	if len(params) == 0 {
+		return ""
+	}
+	s := make([]string, len(params))
+	for i, v := range params {
+		s[i] = fmtFloat(v)
+	}
+	return "," + strings.Join(s, "X")
+}
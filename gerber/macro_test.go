@@ -0,0 +1,237 @@
+package gerber
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+)
+
+// roundRect returns a macro of a $1 by $2 rectangle with corners of
+// radius $3.
+func roundRect(t *testing.T) *ApertureMacro {
+	t.Helper()
+	m, err := NewApertureMacro("RRECT",
+		MacroCenterLine("$1", "$2-$3x2", "0", "0"),
+		MacroCenterLine("$1-$3x2", "$2", "0", "0"),
+		MacroCircle("$3x2", "$1/2-$3", "$2/2-$3"),
+		MacroCircle("$3x2", "-$1/2+$3", "$2/2-$3"),
+		MacroCircle("$3x2", "-$1/2+$3", "-$2/2+$3"),
+		MacroCircle("$3x2", "$1/2-$3", "-$2/2+$3"),
+	)
+	if err != nil {
+		t.Fatalf("NewApertureMacro: %v", err)
+	}
+	return m
+}
+
+func TestEvalMacroExpr(t *testing.T) {
+	tests := []struct {
+		e       string
+		want    float64
+		wantErr bool
+	}{
+		{e: "1.5", want: 1.5},
+		{e: "$1+$2x3", want: 7},
+		{e: "($1+$2)X3", want: 9},
+		{e: "-$1/2", want: -0.5},
+		{e: "$3", want: 0},
+		{e: "1 - 2 - 3", want: -4},
+		{e: "(1", wantErr: true},
+		{e: "$0", wantErr: true},
+		{e: "1,2", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := evalMacroExpr(tt.e, []float64{1, 2})
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("evalMacroExpr(%q) = %v, %v, want %v (error %v)", tt.e, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestNewApertureMacro_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		macro string
+		prims []MacroPrimitive
+	}{
+		{name: "standard name", macro: "R", prims: []MacroPrimitive{MacroCircle("1", "0", "0")}},
+		{name: "invalid name", macro: "1BOX", prims: []MacroPrimitive{MacroCircle("1", "0", "0")}},
+		{name: "no primitives", macro: "BOX"},
+		{name: "invalid expression", macro: "BOX", prims: []MacroPrimitive{MacroCircle("$1x", "0", "0")}},
+		{name: "short outline", macro: "BOX", prims: []MacroPrimitive{MacroOutline("0", "0", "1", "1")}},
+		{name: "unknown code", macro: "BOX", prims: []MacroPrimitive{{Code: 3, Modifiers: []string{"1"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewApertureMacro(tt.macro, tt.prims...); err == nil {
+				t.Errorf("NewApertureMacro = nil, want error")
+			}
+		})
+	}
+}
+
+func TestFlashT_Geometry(t *testing.T) {
+	f := Flash(Pt{10, 10}, roundRect(t), 4, 2, 0.5)
+	if want := (MBB{Min: Pt{8, 9}, Max: Pt{12, 11}}); !mbbNear(f.MBB(), want, 1e-9) {
+		t.Errorf("MBB = %v, want %v", f.MBB(), want)
+	}
+	for _, tt := range []struct {
+		pt   Pt
+		want bool
+	}{
+		{pt: Pt{10, 10}, want: true},
+		{pt: Pt{11.9, 10}, want: true},
+		{pt: Pt{11.6, 10.6}, want: true},
+		{pt: Pt{11.95, 10.95}, want: false},
+		{pt: Pt{12.1, 10}, want: false},
+	} {
+		if got := f.Contains(tt.pt); got != tt.want {
+			t.Errorf("Contains(%v) = %v, want %v", tt.pt, got, tt.want)
+		}
+	}
+
+	ring, err := NewApertureMacro("RING", MacroCircle("2", "0", "0"), MacroCircle("1", "0", "0").Clear())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := Flash(Pt{}, ring); f.Contains(Pt{}) || !f.Contains(Pt{0.75, 0}) {
+		t.Errorf("ring Contains = %v at its center and %v on its ring, want false and true", f.Contains(Pt{}), f.Contains(Pt{0.75, 0}))
+	}
+}
+
+func TestFlashT_Transform(t *testing.T) {
+	f := Flash(Pt{10, 10}, roundRect(t), 4, 2, 0.5)
+	tests := []struct {
+		name     string
+		t        Transform
+		wantName string
+		want     MBB
+	}{
+		{name: "translate", t: Translate(1, 2), wantName: "RRECT", want: MBB{Min: Pt{9, 11}, Max: Pt{13, 13}}},
+		{name: "rotate", t: Rotate(90), wantName: "RRECT_R90", want: MBB{Min: Pt{-11, 8}, Max: Pt{-9, 12}}},
+		{name: "mirror", t: MirrorY(), wantName: "RRECT_M", want: MBB{Min: Pt{-12, 9}, Max: Pt{-8, 11}}},
+		{name: "scale", t: Scale(2, 2), wantName: "RRECT_S2", want: MBB{Min: Pt{16, 18}, Max: Pt{24, 22}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := f.Transform(tt.t).(*FlashT)
+			if got.Macro.Name != tt.wantName {
+				t.Errorf("macro = %v, want %v", got.Macro.Name, tt.wantName)
+			}
+			if !mbbNear(got.MBB(), tt.want, 1e-9) {
+				t.Errorf("MBB = %v, want %v", got.MBB(), tt.want)
+			}
+		})
+	}
+}
+
+func TestFlashT_WriteGerber(t *testing.T) {
+	tests := []struct {
+		name  string
+		units Units
+		want  []string // in order
+	}{
+		{
+			name: "millimeters",
+			want: []string{"%AMRRECT*\n21,1,$1,$2-$3x2,0,0,0*\n", "RRECT,4X2X0.5*%\n", "X10000000Y10000000D03*\n"},
+		},
+		{
+			name:  "inches",
+			units: UnitsInch,
+			want:  []string{"%AMRRECT*\n21,1,($1)/25.4,($2-$3x2)/25.4,0,0,0*\n", "RRECT,4X2X0.5*%\n", "D03*\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New("test", WithUnits(tt.units), WithOutputValidation(true))
+			m := roundRect(t)
+			g.TopCopper().Add(Flash(Pt{10, 10}, m, 4, 2, 0.5), Flash(Pt{20, 10}, m, 4, 2, 0.5))
+			files := map[string]*memFile{}
+			err := g.Write(func(filename string) (io.WriteCloser, error) {
+				f := &memFile{}
+				files[filename] = f
+				return f, nil
+			})
+			if err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			out := files["test.gtl"].String()
+			if strings.Count(out, "%AMRRECT*") != 1 || strings.Count(out, "RRECT,4X2X0.5*%") != 1 {
+				t.Errorf("test.gtl = %q, want one macro and one aperture definition", out)
+			}
+			rest := out
+			for _, want := range tt.want {
+				i := strings.Index(rest, want)
+				if i < 0 {
+					t.Fatalf("test.gtl = %q, want %q after the previous statements", out, want)
+				}
+				rest = rest[i+len(want):]
+			}
+
+			layer, err := Parse(strings.NewReader(out))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if len(layer.Primitives) != 2 {
+				t.Fatalf("Parse = %v primitives, want 2", len(layer.Primitives))
+			}
+			got, ok := layer.Primitives[0].(*FlashT)
+			if !ok {
+				t.Fatalf("Parse = %T, want *FlashT", layer.Primitives[0])
+			}
+			if want := (MBB{Min: Pt{8, 9}, Max: Pt{12, 11}}); !mbbNear(got.MBB(), want, 1e-3) {
+				t.Errorf("Parse MBB = %v, want %v", got.MBB(), want)
+			}
+		})
+	}
+}
+
+func TestLayer_WriteGerber_MacroConflict(t *testing.T) {
+	a, err := NewApertureMacro("PAD", MacroCircle("1", "0", "0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewApertureMacro("PAD", MacroCircle("2", "0", "0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	layer := New("test").TopCopper()
+	layer.Add(Flash(Pt{}, a), Flash(Pt{1, 1}, b, 1))
+	var buf bytes.Buffer
+	if err := layer.WriteGerber(&buf); err == nil || !strings.Contains(err.Error(), "named PAD") {
+		t.Errorf("WriteGerber = %v, want a macro name conflict", err)
+	}
+}
+
+func TestParseApertureMacro(t *testing.T) {
+	m, err := ParseApertureMacro("%AMTHERM*\n0 A thermal pad*\n7,0,0,$1,$1x0.6,0.2,45*\n5,1,6,0,0,$1,0*%")
+	if err != nil {
+		t.Fatalf("ParseApertureMacro: %v", err)
+	}
+	if m.Name != "THERM" || len(m.Primitives) != 2 || m.Primitives[0].Code != MacroThermalCode || m.Primitives[0].Modifiers[3] != "$1x0.6" {
+		t.Errorf("ParseApertureMacro = %+v, want a thermal and a hexagon", m)
+	}
+	image, err := m.Image(1)
+	if err != nil {
+		t.Fatalf("Image: %v", err)
+	}
+	if len(image) != 5 {
+		t.Errorf("Image = %v primitives, want 5", len(image))
+	}
+}
+
+func TestFlashT_JSON(t *testing.T) {
+	want := New("test")
+	want.TopCopper().Add(Flash(Pt{10, 10}, roundRect(t), 4, 2, 0.5))
+	data, err := json.Marshal(want)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	got := &Gerber{}
+	if err := json.Unmarshal(data, got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	compareOutputs(t, got, want)
+}
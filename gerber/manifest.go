@@ -0,0 +1,167 @@
+package gerber
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"text/tabwriter"
+)
+
+// Manifest describes a written Gerber set: each of its files, with the
+// file's function, units, and checksum, as requested by many fabs and
+// for automated checks of the set. See WithManifest.
+type Manifest struct {
+	// Name is the design's FilenamePrefix.
+	Name string `json:"name"`
+	// Generator identifies the software that wrote the set.
+	Generator string `json:"generator"`
+	// CopperLayers is the number of copper layers in the design.
+	CopperLayers int `json:"copperLayers"`
+	// Revision is the design's revision (see WithRevision), if any.
+	Revision string `json:"revision,omitempty"`
+	// Files describes the layer files, in layer order.
+	Files []*ManifestFile `json:"files"`
+}
+
+// ManifestFile describes one file of a Gerber set.
+type ManifestFile struct {
+	Filename string `json:"filename"`
+	// Layer is the type of the layer (e.g. "TopCopper") and Function
+	// its description (e.g. "Top copper").
+	Layer    string `json:"layer"`
+	Function string `json:"function"`
+	// FileFunction is the layer's Gerber X2 .FileFunction attribute
+	// value (e.g. "Copper,L1,Top"), if any.
+	FileFunction string `json:"fileFunction,omitempty"`
+	// Units is "MM" or "IN", and Format the coordinate format (e.g.
+	// "3.6") with which the file was written, or "decimal" for Excellon
+	// drill files (see WithExcellonDrills).
+	Units  string `json:"units"`
+	Format string `json:"format"`
+	// Size is the size of the file in bytes and SHA256 its hex-encoded
+	// SHA-256 checksum.
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// ManifestFilenames returns the filenames of the JSON and text
+// manifests written with the design's layers (see WithManifest).
+func (g *Gerber) ManifestFilenames() (jsonFile, textFile string) {
+	return g.FilenamePrefix + "-manifest.json", g.FilenamePrefix + "-README.txt"
+}
+
+// newManifest returns the (empty) manifest of the design.
+func (g *Gerber) newManifest() *Manifest {
+	return &Manifest{Name: g.FilenamePrefix, Generator: "gmlewis/go-gerber", CopperLayers: g.numCopperLayers(), Revision: g.revision}
+}
+
+// add adds the layer to the manifest and returns a writer that records
+// the size and checksum of the layer's file, written to w, when it is
+// closed.
+func (m *Manifest) add(l *Layer, w io.WriteCloser) io.WriteCloser {
+	f := l.newWriter(ioutil.Discard).format
+	mf := &ManifestFile{
+		Filename:     l.Filename,
+		Layer:        l.Type.String(),
+		Function:     layerFunction(l),
+		FileFunction: l.fileFunction(),
+		Units:        l.g.units.String(),
+		Format:       fmt.Sprintf("%v.%v", f.Integer, f.Decimal),
+	}
+	if l.excellonOutput() {
+		mf.FileFunction, mf.Units, mf.Format = "", UnitsMM.String(), "decimal"
+	}
+	m.Files = append(m.Files, mf)
+	return &manifestWriter{WriteCloser: w, file: mf, h: sha256.New()}
+}
+
+// manifestWriter records the size and checksum of a file being written.
+type manifestWriter struct {
+	io.WriteCloser
+	file *ManifestFile
+	h    hash.Hash
+}
+
+func (w *manifestWriter) abort() {
+	abort(w.WriteCloser)
+}
+
+func (w *manifestWriter) Write(p []byte) (int, error) {
+	n, err := w.WriteCloser.Write(p)
+	w.h.Write(p[:n])
+	w.file.Size += int64(n)
+	return n, err
+}
+
+func (w *manifestWriter) Close() error {
+	w.file.SHA256 = hex.EncodeToString(w.h.Sum(nil))
+	return w.WriteCloser.Close()
+}
+
+// write writes the JSON and text manifests to the io.WriteClosers
+// returned by create.
+func (m *Manifest) write(g *Gerber, create func(filename string) (io.WriteCloser, error)) error {
+	jsonFile, textFile := g.ManifestFilenames()
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	for _, out := range []struct {
+		filename string
+		write    func(w io.Writer) error
+	}{
+		{jsonFile, func(w io.Writer) error {
+			_, err := w.Write(append(data, '\n'))
+			return err
+		}},
+		{textFile, m.WriteText},
+	} {
+		w, err := create(out.filename)
+		if err != nil {
+			return err
+		}
+		if err := out.write(w); err != nil {
+			abort(w)
+			return err
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteText writes the manifest as a human-readable table.
+func (m *Manifest) WriteText(w io.Writer) error {
+	name := m.Name
+	if m.Revision != "" {
+		name += " revision " + m.Revision
+	}
+	fmt.Fprintf(w, "Gerber files for %v (%v copper layers), written by %v.\n\n", name, m.CopperLayers, m.Generator)
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "File\tFunction\tUnits\tFormat\tBytes\tSHA-256")
+	for _, f := range m.Files {
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\t%v\n", f.Filename, f.Function, f.Units, f.Format, f.Size, f.SHA256)
+	}
+	return tw.Flush()
+}
+
+// layerFunction returns the description of the layer's function.
+func layerFunction(l *Layer) string {
+	if l.Type == DrillLayer {
+		switch l.drillKind {
+		case platedDrill:
+			return "Plated drill holes"
+		case nonPlatedDrill:
+			return "Non-plated drill holes"
+		}
+	}
+	if info, ok := l.typeInfo(); ok {
+		return info.description
+	}
+	return l.Type.String()
+}
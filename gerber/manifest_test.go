@@ -0,0 +1,65 @@
+package gerber
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestWithManifest(t *testing.T) {
+	g := New("board", WithManifest(true), WithUnits(UnitsInch))
+	g.TopCopper().Add(Circle(Pt{5, 5}, 2))
+	g.LayerN(2).Add(Circle(Pt{5, 5}, 2))
+	g.BottomCopper().Add(Circle(Pt{5, 5}, 2))
+	g.Drill().Add(Circle(Pt{5, 5}, 1))
+	files := map[string]*memFile{}
+	var order []string
+	err := g.Write(func(filename string) (io.WriteCloser, error) {
+		f := &memFile{}
+		files[filename] = f
+		order = append(order, filename)
+		return f, nil
+	})
+	if err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	jsonFile, textFile := g.ManifestFilenames()
+	if n := len(order); n != 6 || order[n-2] != jsonFile || order[n-1] != textFile {
+		t.Fatalf("Write created %v, want the 4 layers followed by %v and %v", order, jsonFile, textFile)
+	}
+	if !files[jsonFile].closed || !files[textFile].closed {
+		t.Errorf("Write did not close the manifests")
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(files[jsonFile].Bytes(), &m); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if m.Name != "board" || m.CopperLayers != 3 || len(m.Files) != 4 {
+		t.Fatalf("manifest = %+v, want 4 files of board with 3 copper layers", m)
+	}
+	wantFunctions := []string{"Top copper", "Inner copper (layer 2)", "Bottom copper", "Drill holes"}
+	for i, f := range m.Files {
+		data := files[f.Filename].Bytes()
+		sum := sha256.Sum256(data)
+		if f.Size != int64(len(data)) || f.SHA256 != hex.EncodeToString(sum[:]) {
+			t.Errorf("%v: size %v, checksum %v, want %v and %x", f.Filename, f.Size, f.SHA256, len(data), sum)
+		}
+		if f.Function != wantFunctions[i] || f.Units != "IN" || f.Format != "2.6" {
+			t.Errorf("%v: function %q in %v with format %v, want %q in IN with format 2.6", f.Filename, f.Function, f.Units, f.Format, wantFunctions[i])
+		}
+	}
+	if m.Files[1].FileFunction != "Copper,L2,Inr" {
+		t.Errorf("inner copper file function = %q, want Copper,L2,Inr", m.Files[1].FileFunction)
+	}
+
+	text := files[textFile].String()
+	for _, want := range []string{"Gerber files for board (3 copper layers)", "board.gtl", "Inner copper (layer 2)", m.Files[3].SHA256} {
+		if !strings.Contains(text, want) {
+			t.Errorf("%v = %q, want %q", textFile, text, want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package gerber
+
+// The generators below draw marks for documentation and photoplot
+// verification layers (e.g. a fab drawing or an assembly layer).
+// All dimensions are in millimeters.
+
+// Crosshair returns a crosshair of the given overall size centered on
+// center, drawn with lines of the given width.
+func Crosshair(center Pt, size, width float64) Group {
+	h := 0.5 * size
+	return Group{
+		Line(center[0]-h, center[1], center[0]+h, center[1], CircleShape, width),
+		Line(center[0], center[1]-h, center[0], center[1]+h, CircleShape, width),
+	}
+}
+
+// AlignmentTarget returns a photoplot alignment (registration) target:
+// a circle of diameter d with a crosshair extending beyond it by half
+// its radius on each side.
+func AlignmentTarget(center Pt, d, width float64) Group {
+	g := Group{Arc(center, 0.5*d, CircleShape, 1, 1, 0, 360, width)}
+	return append(g, Crosshair(center, 1.5*d, width)...)
+}
+
+// OriginMarker returns a marker of the XY origin (0,0): a crosshair of
+// the given size with arrowheads on the positive X and Y axes.
+func OriginMarker(size, width float64) Group {
+	h, a := 0.5*size, 0.15*size
+	return append(Crosshair(Pt{}, size, width),
+		Line(h, 0, h-a, a, CircleShape, width),
+		Line(h, 0, h-a, -a, CircleShape, width),
+		Line(0, h, a, h-a, CircleShape, width),
+		Line(0, h, -a, h-a, CircleShape, width),
+	)
+}
+
+// ScaleBar returns a horizontal scale bar of the given length starting
+// at ll, with the given number of divisions marked by ticks of the
+// given height (the first and last ticks are twice as tall), so that
+// the scale of a plot can be verified.
+func ScaleBar(ll Pt, length, height float64, divisions int, width float64) Group {
+	if divisions < 1 {
+		divisions = 1
+	}
+	g := Group{Line(ll[0], ll[1], ll[0]+length, ll[1], CircleShape, width)}
+	for i := 0; i <= divisions; i++ {
+		x, h := ll[0]+length*float64(i)/float64(divisions), height
+		if i == 0 || i == divisions {
+			h *= 2
+		}
+		g = append(g, Line(x, ll[1], x, ll[1]+h, CircleShape, width))
+	}
+	return g
+}
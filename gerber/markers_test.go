@@ -0,0 +1,27 @@
+package gerber
+
+import "testing"
+
+func TestMarkers(t *testing.T) {
+	tests := []struct {
+		name  string
+		group Group
+		want  MBB
+		n     int
+	}{
+		{name: "crosshair", group: Crosshair(Pt{1, 1}, 2, 0.1), want: MBB{Min: Pt{-0.05, -0.05}, Max: Pt{2.05, 2.05}}, n: 2},
+		{name: "alignment target", group: AlignmentTarget(Pt{}, 2, 0.1), want: MBB{Min: Pt{-1.55, -1.55}, Max: Pt{1.55, 1.55}}, n: 3},
+		{name: "origin marker", group: OriginMarker(10, 0), want: MBB{Min: Pt{-5, -5}, Max: Pt{5, 5}}, n: 6},
+		{name: "scale bar", group: ScaleBar(Pt{0, 0}, 10, 1, 10, 0), want: MBB{Min: Pt{0, 0}, Max: Pt{10, 2}}, n: 12},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := len(tt.group); got != tt.n {
+				t.Errorf("len = %v, want %v", got, tt.n)
+			}
+			if got := tt.group.MBB(); !mbbNear(got, tt.want, 1e-6) {
+				t.Errorf("MBB = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package gerber
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/gmlewis/go-gerber/gerber/internal/polyclip"
+)
+
+func init() {
+	registerPrimitive("maskWindow", func() Primitive { return &MaskWindowT{} })
+}
+
+// MaskWindowT is a solder mask opening (window) that exposes a region
+// of copper independent of any pad, e.g. for edge-wettable shield
+// contacts, bare copper that carries current, or ENIG touch targets.
+// It belongs on a solder mask layer (see Layer.AddMaskWindow) and
+// satisfies the Primitive interface.
+type MaskWindowT struct {
+	// Region is the closed polygon of the window.
+	Region []Pt `json:"region"`
+	// Purpose optionally describes why the copper is exposed. It is
+	// written as a comment so that it is visible to the fab.
+	Purpose string `json:"purpose,omitempty"`
+}
+
+// MaskWindow returns a mask window primitive.
+// All dimensions are in millimeters.
+func MaskWindow(region []Pt, purpose string) *MaskWindowT {
+	return &MaskWindowT{Region: region, Purpose: purpose}
+}
+
+// WriteGerber writes the primitive to the Gerber file.
+func (m *MaskWindowT) WriteGerber(w io.Writer, apertureIndex int) error {
+	if len(m.Region) == 0 {
+		return nil
+	}
+	if m.Purpose != "" {
+		fmt.Fprintf(w, "G04 Mask window: %v*\n", m.Purpose)
+	}
+	return Polygon(Pt{}, true, m.Region, 0).WriteGerber(toWriter(w), apertureIndex)
+}
+
+// Aperture returns nil for MaskWindowT because it uses the default aperture.
+func (m *MaskWindowT) Aperture() *Aperture {
+	return nil
+}
+
+// MBB returns the minimum bounding box in millimeters.
+func (m *MaskWindowT) MBB() MBB {
+	if len(m.Region) == 0 {
+		return MBB{}
+	}
+	return Polygon(Pt{}, true, m.Region, 0).MBB()
+}
+
+// Transform returns a transformed copy of the window.
+func (m *MaskWindowT) Transform(t Transform) Primitive {
+	pts := make([]Pt, 0, len(m.Region))
+	for _, pt := range m.Region {
+		pts = append(pts, t.Apply(pt))
+	}
+	return MaskWindow(pts, m.Purpose)
+}
+
+// Expand returns a copy of the window whose edges are moved outward
+// by delta (or inward, if delta is negative).
+func (m *MaskWindowT) Expand(delta float64) Primitive {
+	return MaskWindow(polyclip.OffsetPolygon(m.Region, delta), m.Purpose)
+}
+
+// Contains reports whether pt lies within the window.
+func (m *MaskWindowT) Contains(pt Pt) bool {
+	return pointInPolygon(pt, m.Region)
+}
+
+// AddMaskWindow adds a mask window exposing region of this top or
+// bottom copper layer to the solder mask layer on the same side,
+// which is added to the design if necessary. Region is grown by
+// expansion to allow for mask registration.
+func (l *Layer) AddMaskWindow(region []Pt, expansion float64, purpose string) (*MaskWindowT, error) {
+	if l.g == nil {
+		return nil, errors.New("layer does not belong to a design")
+	}
+	var maskType LayerType
+	switch l.Type {
+	case TopCopperLayer:
+		maskType = TopSolderMaskLayer
+	case BottomCopperLayer:
+		maskType = BottomSolderMaskLayer
+	default:
+		return nil, fmt.Errorf("mask windows require a top or bottom copper layer, not %v", l.Type)
+	}
+	m := MaskWindow(region, purpose)
+	if expansion != 0 {
+		m = m.Expand(expansion).(*MaskWindowT)
+	}
+	l.g.firstLayerOfType(maskType).Add(m)
+	return m, nil
+}
@@ -0,0 +1,65 @@
+package gerber
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestLayer_AddMaskWindow(t *testing.T) {
+	g := New("test")
+	region := RoundedRect(MBB{Min: Pt{0, 0}, Max: Pt{4, 2}}, 0)
+	m, err := g.TopCopper().AddMaskWindow(region, 0.1, "ENIG touch target")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mask := g.layersOfType(TopSolderMaskLayer)
+	if len(mask) != 1 || len(mask[0].Primitives) != 1 || mask[0].Primitives[0] != m {
+		t.Fatalf("top mask = %v, want the mask window", mask)
+	}
+	if got, want := m.MBB(), (MBB{Min: Pt{-0.1, -0.1}, Max: Pt{4.1, 2.1}}); !mbbNear(got, want, 1e-9) {
+		t.Errorf("MBB = %v, want %v", got, want)
+	}
+	if !m.Contains(Pt{2, 1}) || m.Contains(Pt{5, 1}) {
+		t.Errorf("Contains is wrong for %v", m)
+	}
+
+	var buf bytes.Buffer
+	if err := mask[0].WriteGerber(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); !strings.Contains(got, "G04 Mask window: ENIG touch target*\n") || !strings.Contains(got, "G36*") {
+		t.Errorf("WriteGerber = %v, want a commented region", got)
+	}
+
+	if _, err := g.LayerN(2).AddMaskWindow(region, 0, ""); err == nil {
+		t.Error("AddMaskWindow on inner layer: want error")
+	}
+
+	data, err := json.Marshal(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Gerber
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if mw, ok := got.Layers[1].Primitives[0].(*MaskWindowT); !ok || mw.Purpose != m.Purpose || len(mw.Region) != len(m.Region) {
+		t.Errorf("unmarshaled = %v, want %v", got.Layers[1].Primitives[0], m)
+	}
+}
+
+func TestValidate_maskWindow(t *testing.T) {
+	g := New("test")
+	g.TopCopper().Add(MaskWindow(RoundedRect(MBB{Max: Pt{1, 1}}, 0), ""))
+	var found bool
+	for _, issue := range g.Validate() {
+		if strings.Contains(issue.Message, "mask window") {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Validate: want mask window warning")
+	}
+}
@@ -0,0 +1,86 @@
+package gerber
+
+import (
+	"math"
+
+	"github.com/gmlewis/go-gerber/gerber/internal/polyclip"
+)
+
+// PathLength returns the length of the open path through pts.
+func PathLength(pts []Pt) float64 {
+	var length float64
+	for i := 1; i < len(pts); i++ {
+		length += Distance(pts[i-1], pts[i])
+	}
+	return length
+}
+
+// Perimeter returns the perimeter of the closed polygon pts.
+func Perimeter(pts []Pt) float64 {
+	if len(pts) < 2 {
+		return 0
+	}
+	return PathLength(pts) + Distance(pts[len(pts)-1], pts[0])
+}
+
+// PolygonArea returns the (unsigned) area enclosed by the closed
+// polygon pts, which must not be self-intersecting.
+func PolygonArea(pts []Pt) float64 {
+	return math.Abs(polyclip.SignedArea(pts))
+}
+
+// Length returns the length of the line's centerline in millimeters.
+func (l *LineT) Length() float64 {
+	return Distance(l.P1, l.P2)
+}
+
+// Length returns the length of the arc's centerline in millimeters.
+// Elliptical arcs (XScale != YScale) are integrated numerically.
+func (a *ArcT) Length() float64 {
+	delta := math.Abs(a.EndAngle - a.StartAngle)
+	if a.XScale == a.YScale {
+		return delta * a.Radius * a.XScale
+	}
+	// Integrate the arc's speed using Simpson's rule.
+	const n = 1000 // must be even
+	speed := func(angle float64) float64 {
+		s, c := math.Sincos(angle)
+		return a.Radius * math.Hypot(a.XScale*s, a.YScale*c)
+	}
+	h := delta / n
+	sum := speed(a.StartAngle) + speed(a.StartAngle+delta)
+	for i := 1; i < n; i++ {
+		w := 2.0
+		if i%2 == 1 {
+			w = 4
+		}
+		sum += w * speed(a.StartAngle+float64(i)*h)
+	}
+	return sum * h / 3
+}
+
+// Perimeter returns the perimeter of the polygon in millimeters.
+func (p *PolygonT) Perimeter() float64 {
+	return Perimeter(p.Points)
+}
+
+// Area returns the area of the polygon in square millimeters.
+func (p *PolygonT) Area() float64 {
+	return PolygonArea(p.Points)
+}
+
+// TraceLength returns the total centerline length (in millimeters) of
+// the lines and arcs among primitives (e.g. the turns of a coil), which
+// is useful for estimating resistance. Other primitives are ignored.
+func TraceLength(primitives ...Primitive) float64 {
+	var length float64
+	for _, p := range primitives {
+		switch v := p.(type) {
+		case *LineT:
+			length += v.Length()
+		case *ArcT:
+			length += v.Length()
+		}
+	}
+	return length
+}
@@ -0,0 +1,39 @@
+package gerber
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMeasure(t *testing.T) {
+	const eps = 1e-6
+	square := []Pt{{0, 0}, {2, 0}, {2, 2}, {0, 2}}
+
+	tests := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{name: "PathLength", got: PathLength(square), want: 6},
+		{name: "Perimeter", got: Perimeter(square), want: 8},
+		{name: "PolygonArea", got: PolygonArea(square), want: 4},
+		{name: "PolygonArea clockwise", got: PolygonArea([]Pt{{0, 0}, {0, 2}, {2, 2}, {2, 0}}), want: 4},
+		{name: "LineT.Length", got: Line(0, 0, 3, 4, CircleShape, 0.1).Length(), want: 5},
+		{name: "ArcT.Length circle", got: Arc(Pt{0, 0}, 2, CircleShape, 1, 1, 0, 360, 0.1).Length(), want: 4 * math.Pi},
+		{name: "ArcT.Length ellipse", got: Arc(Pt{0, 0}, 1, CircleShape, 2, 1, 0, 360, 0.1).Length(), want: 9.688448220},
+		{name: "PolygonT.Area", got: Polygon(Pt{5, 5}, true, square, 0).Area(), want: 4},
+		{
+			name: "TraceLength",
+			got:  TraceLength(Line(0, 0, 1, 0, CircleShape, 0.1), Arc(Pt{0, 0}, 1, CircleShape, 1, 1, 0, 90, 0.1), Circle(Pt{0, 0}, 1)),
+			want: 1 + 0.5*math.Pi,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if math.Abs(tt.got-tt.want) > eps {
+				t.Errorf("got %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package gerber
+
+import "fmt"
+
+// Batch returns a batch (see Merge) of transformed copies of the
+// primitives of the layer (such as a layer of a separately generated
+// sub-design, or a third-party layer read by Parse), with their tags,
+// attributes, and declared openings. Primitives derived by the layer's
+// design (see DeriveOpenings) are not copied, since they are derived
+// again by the design the batch is merged into. It returns an error if
+// any primitive does not implement Transformer.
+func (l *Layer) Batch(t Transform) (*Batch, error) {
+	b := NewBatch()
+	for _, p := range l.Primitives {
+		if l.IsDerived(p) {
+			continue
+		}
+		tp, err := TransformPrimitive(p, t)
+		if err != nil {
+			return nil, fmt.Errorf("layer %v: %v", l.Filename, err)
+		}
+		b.AddTagged(l.Tags(p), tp)
+		if attrs := l.Attributes(p); len(attrs) > 0 {
+			if b.attrs == nil {
+				b.attrs = map[Primitive]Attributes{}
+			}
+			b.attrs[tp] = attrs
+		}
+		if o, ok := l.Openings(p); ok {
+			if b.openings == nil {
+				b.openings = map[Primitive]Openings{}
+			}
+			b.openings[tp] = o
+		}
+	}
+	return b, nil
+}
+
+// MergeDesign composites the design other (e.g. a separately generated
+// sub-design, or one read by ParseDesign), moved by offset, into the
+// design: a batch of each of its layers (see Layer.Batch) is merged
+// into the design's first layer of the same type (and number, for
+// inner and mechanical layers), which is added if necessary, and its
+// padstack instances, components, and keepouts are moved and added
+// too. The design keeps its own output settings. It returns an error
+// (adding nothing) if any primitive of other does not implement
+// Transformer.
+func (g *Gerber) MergeDesign(other *Gerber, offset Pt) error {
+	t := Translate(offset[0], offset[1])
+	// Check that every primitive can be moved before changing g.
+	for _, layer := range other.Layers {
+		for _, p := range layer.Primitives {
+			if _, ok := p.(Transformer); !ok && !layer.IsDerived(p) {
+				return fmt.Errorf("layer %v: primitive %T does not support transforms", layer.Filename, p)
+			}
+		}
+	}
+	for _, layer := range other.Layers {
+		var target *Layer
+		for _, l := range g.layersOfType(layer.Type) {
+			if l.N == layer.N {
+				target = l
+				break
+			}
+		}
+		if target == nil {
+			target = g.makeLayer(layer.Type, layer.N)
+		}
+		b, err := layer.Batch(t)
+		if err != nil {
+			return err
+		}
+		target.Merge(b)
+	}
+
+	move := func(pt Pt) Pt { return Pt{pt[0] + offset[0], pt[1] + offset[1]} }
+	for _, ref := range other.padstackRefs {
+		r := *ref
+		r.Center = move(ref.Center)
+		g.padstackRefs = append(g.padstackRefs, &r)
+	}
+	for _, c := range other.components {
+		mc := *c
+		mc.Center = move(c.Center)
+		g.AddComponent(&mc)
+	}
+	for _, k := range other.keepouts {
+		region := make([]Pt, 0, len(k.Region))
+		for _, pt := range k.Region {
+			region = append(region, move(pt))
+		}
+		g.AddKeepout(Keepout{Region: region, Layers: k.Layers})
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package gerber
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// opaquePrimitive hides the methods of its primitive other than those
+// of Primitive.
+type opaquePrimitive struct{ Primitive }
+
+func TestLayer_Batch(t *testing.T) {
+	g := New("test")
+	top := g.TopCopper()
+	top.Add(Circle(Pt{0, 0}, 1), Line(0, 0, 5, 0, CircleShape, 0.2))
+
+	sub := New("sub")
+	st := sub.TopCopper()
+	pad := Circle(Pt{1, 0}, 1)
+	st.AddWithOpenings(Openings{Mask: true, MaskExpansion: 0.1}, pad)
+	st.Tag(pad, NetTag("gnd"))
+	st.SetAttribute(pad, "id", "7")
+	st.Add(Pad(Pt{3, 0}, RectShape, 1, 2, 0))
+
+	b, err := st.Batch(Rotate(90).Then(Translate(10, 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	top.Merge(b)
+	if len(top.Primitives) != 4 {
+		t.Fatalf("got %v primitives, want 4", len(top.Primitives))
+	}
+	merged := top.Primitives[2]
+	if got, want := merged.MBB(), (MBB{Min: Pt{9.5, 0.5}, Max: Pt{10.5, 1.5}}); !mbbNear(got, want, 1e-9) {
+		t.Errorf("merged pad MBB = %v, want %v", got, want)
+	}
+	if !top.HasTag(merged, NetTag("gnd")) || top.Attributes(merged)["id"] != "7" {
+		t.Errorf("merged pad tags = %v, attributes = %v", top.Tags(merged), top.Attributes(merged))
+	}
+	if o, ok := top.Openings(merged); !ok || o.MaskExpansion != 0.1 {
+		t.Errorf("merged pad openings = %v, %v", o, ok)
+	}
+	// The 1mm circle aperture is shared with the layer's own circle.
+	if len(top.Apertures) != 3 {
+		t.Errorf("got %v apertures, want 3: %v", len(top.Apertures), top.Apertures)
+	}
+
+	// A parsed layer may be merged too.
+	var buf bytes.Buffer
+	if err := st.WriteGerber(&buf); err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := Parse(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, err = parsed.Batch(Translate(0, 20)); err != nil {
+		t.Fatal(err)
+	}
+	top.Merge(b)
+	if got, want := top.MBB().Max[1], 21.0; got != want {
+		t.Errorf("MBB max Y = %v, want %v", got, want)
+	}
+}
+
+func TestGerber_MergeDesign(t *testing.T) {
+	g := New("board")
+	g.TopCopper().Add(Circle(Pt{0, 0}, 1))
+	g.Outline().Add(OutlinePath(RoundedRect(MBB{Max: Pt{30, 20}}, 0), 0.1)...)
+
+	sub := New("sub")
+	sub.TopCopper().AddPad(PadOpts{}, Circle(Pt{1, 1}, 1))
+	sub.LayerN(2).Add(Line(0, 0, 2, 0, CircleShape, 0.2))
+	sub.PlacePadstack(&Padstack{Drill: 0.8, Top: RoundPad(1.6), Bottom: RoundPad(1.6)}, Pt{2, 2}, 0).Number = "1"
+	sub.AddComponent(&Component{Designator: "U1", Center: Pt{1, 2}})
+	sub.AddKeepout(Keepout{Region: RoundedRect(MBB{Max: Pt{1, 1}}, 0)})
+	if err := sub.DeriveOpenings(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := g.MergeDesign(sub, Pt{10, 5}); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.DeriveOpenings(); err != nil {
+		t.Fatal(err)
+	}
+	top := g.firstLayerOfType(TopCopperLayer)
+	// The board's circle, the merged pad, and the derived padstack pad.
+	if len(top.Primitives) != 3 || len(g.layersOfType(TopCopperLayer)) != 1 {
+		t.Fatalf("top copper = %v, want 3 primitives", top.Primitives)
+	}
+	if got, want := top.Primitives[1].MBB(), (MBB{Min: Pt{10.5, 5.5}, Max: Pt{11.5, 6.5}}); !mbbNear(got, want, 1e-9) {
+		t.Errorf("merged pad MBB = %v, want %v", got, want)
+	}
+	inner := g.layersOfType(InnerCopperLayer)
+	if len(inner) != 1 || inner[0].N != 2 || len(inner[0].Primitives) != 1 {
+		t.Errorf("inner layers = %v, want layer 2 with 1 primitive", inner)
+	}
+	// The mask openings are derived again (once) by the design.
+	if got := len(g.firstLayerOfType(TopSolderMaskLayer).Primitives); got != 2 {
+		t.Errorf("top mask = %v primitives, want 2", got)
+	}
+	drill := g.firstLayerOfType(DrillLayer)
+	if len(drill.Primitives) != 1 || drill.Primitives[0].MBB().Min != (Pt{11.6, 6.6}) {
+		t.Errorf("drill = %v, want the padstack's hole at (12,7)", drill.Primitives)
+	}
+	if c := g.Component("U1"); c == nil || c.Center != (Pt{11, 7}) {
+		t.Errorf("component U1 = %v, want it at (11,7)", c)
+	}
+	if k := g.Keepouts(TopCopperLayer); len(k) != 1 || k[0].Region[0] != (Pt{10, 5}) {
+		t.Errorf("keepouts = %v", k)
+	}
+
+	var buf bytes.Buffer
+	if err := g.WriteZip(&buf); err != nil {
+		t.Fatal(err)
+	}
+	bad := New("bad")
+	bad.TopCopper().Add(opaquePrimitive{Circle(Pt{}, 1)})
+	if err := g.MergeDesign(bad, Pt{}); err == nil || !strings.Contains(err.Error(), "transforms") {
+		t.Errorf("MergeDesign of an untransformable primitive = %v, want error", err)
+	}
+}
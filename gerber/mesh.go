@@ -0,0 +1,265 @@
+package gerber
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+
+	"github.com/gmlewis/go-gerber/gerber/internal/polyclip"
+)
+
+// MeshOpts represents the options used by WriteSTL. All dimensions are
+// in millimeters. Fields that are zero take their defaults.
+type MeshOpts struct {
+	// BoardThickness is the thickness of the board (default 1.6).
+	BoardThickness float64
+	// CopperThickness is the thickness of the outer copper (default
+	// that of the design's Stackup).
+	CopperThickness float64
+	// Snap is the distance within which the ends of the primitives of
+	// the outline are joined (see Layer.Contours).
+	Snap float64
+}
+
+// defaultBoardThickness is the default thickness (in mm) of boards.
+const defaultBoardThickness = 1.6
+
+// meshTriangle is a triangle of a mesh, whose vertices are
+// counterclockwise as seen from outside the solid.
+type meshTriangle [3][3]float64
+
+// WriteSTL writes a (binary) STL model of the board for checking its
+// fit in an enclosure: the board outline (or, if the design has no
+// outline, its bounding box) extruded to the board's thickness, with
+// its cutouts and the holes of its drill layers, and the copper of the
+// top and bottom copper layers extruded above and below it. Each copper
+// primitive is extruded separately, so overlapping copper makes
+// overlapping solids; clear primitives and plane layers are ignored,
+// and copper is not cut by holes. Z=0 is the bottom of the board, and
+// X and Y are the design's output coordinates (see SetExportTransform).
+func (g *Gerber) WriteSTL(w io.Writer, opts MeshOpts) error {
+	tris, err := g.mesh(opts)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	header := make([]byte, 80)
+	copy(header, "go-gerber "+g.FilenamePrefix)
+	buf.Write(header)
+	binary.Write(&buf, binary.LittleEndian, uint32(len(tris)))
+	for _, t := range tris {
+		var v [12]float32
+		n := t.normal()
+		for i := 0; i < 3; i++ {
+			v[i] = float32(n[i])
+			for j := 0; j < 3; j++ {
+				v[3+3*j+i] = float32(t[j][i])
+			}
+		}
+		binary.Write(&buf, binary.LittleEndian, v)
+		binary.Write(&buf, binary.LittleEndian, uint16(0))
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// normal returns the unit normal of the triangle.
+func (t meshTriangle) normal() [3]float64 {
+	var u, v [3]float64
+	for i := 0; i < 3; i++ {
+		u[i], v[i] = t[1][i]-t[0][i], t[2][i]-t[0][i]
+	}
+	n := [3]float64{u[1]*v[2] - u[2]*v[1], u[2]*v[0] - u[0]*v[2], u[0]*v[1] - u[1]*v[0]}
+	if l := math.Sqrt(n[0]*n[0] + n[1]*n[1] + n[2]*n[2]); l > 0 {
+		n[0], n[1], n[2] = n[0]/l, n[1]/l, n[2]/l
+	}
+	return n
+}
+
+// mesh returns the triangles of the model written by WriteSTL.
+func (g *Gerber) mesh(opts MeshOpts) ([]meshTriangle, error) {
+	if opts.BoardThickness < 0 || opts.CopperThickness < 0 || opts.Snap < 0 {
+		return nil, errors.New("invalid mesh thickness or snap")
+	}
+	thickness, copper := opts.BoardThickness, opts.CopperThickness
+	if thickness == 0 {
+		thickness = defaultBoardThickness
+	}
+	if copper == 0 {
+		copper = g.Stackup().OuterCopper * mmPerOz
+	}
+
+	board, holes, err := g.meshBoard(opts.Snap)
+	if err != nil {
+		return nil, err
+	}
+	for _, drill := range g.layersOfType(DrillLayer) {
+		for _, p := range drill.Primitives {
+			for _, hole := range grownOutline(outlineOf(p), 0) {
+				hole = hole[:len(hole)-1]
+				if pointInPolygon(hole[0], board) {
+					holes = append(holes, hole)
+				}
+			}
+		}
+	}
+
+	var tris []meshTriangle
+	ccw := func(pts []Pt) []Pt {
+		result := make([]Pt, len(pts))
+		for i, pt := range pts {
+			result[i] = g.exportPt(pt)
+		}
+		if polyclip.SignedArea(result) < 0 {
+			result = reversed(result)
+		}
+		return result
+	}
+	board = ccw(board)
+	for i, hole := range holes {
+		holes[i] = reversed(ccw(hole))
+	}
+	tris = extrude(tris, board, holes, 0, thickness)
+
+	for _, layer := range g.Layers {
+		z0, z1 := thickness, thickness+copper
+		switch layer.Type {
+		case TopCopperLayer:
+		case BottomCopperLayer:
+			z0, z1 = -copper, 0
+		default:
+			continue
+		}
+		for _, p := range layer.Primitives {
+			if _, ok := p.(*ClearT); ok {
+				continue
+			}
+			for _, poly := range grownOutline(outlineOf(p), 0) {
+				if poly = poly[:len(poly)-1]; len(poly) >= 3 {
+					tris = extrude(tris, ccw(poly), nil, z0, z1)
+				}
+			}
+		}
+	}
+	return tris, nil
+}
+
+// meshBoard returns the polygon of the board and of its cutouts: the
+// largest closed contour of the design's outline layers and those
+// within it, or the design's bounding box if it has no outline.
+func (g *Gerber) meshBoard(snap float64) ([]Pt, [][]Pt, error) {
+	var contours [][]Pt
+	outlines := g.layersOfType(OutlineLayer)
+	for _, layer := range outlines {
+		for _, c := range layer.Contours(snap) {
+			if c.Closed && len(c.Points) > 3 {
+				contours = append(contours, c.Points[:len(c.Points)-1])
+			}
+		}
+	}
+	if len(contours) == 0 {
+		for _, layer := range outlines {
+			if !layer.IsEmpty() {
+				return nil, nil, errors.New("design's outline is not closed")
+			}
+		}
+		mbb := g.MBB()
+		if !(mbb.Max[0] > mbb.Min[0] && mbb.Max[1] > mbb.Min[1]) {
+			return nil, nil, errors.New("design is empty")
+		}
+		return []Pt{mbb.Min, {mbb.Max[0], mbb.Min[1]}, mbb.Max, {mbb.Min[0], mbb.Max[1]}}, nil, nil
+	}
+
+	board := 0
+	for i, c := range contours {
+		if math.Abs(polyclip.SignedArea(c)) > math.Abs(polyclip.SignedArea(contours[board])) {
+			board = i
+		}
+	}
+	var cutouts [][]Pt
+	for i, c := range contours {
+		if i != board && pointInPolygon(c[0], contours[board]) {
+			cutouts = append(cutouts, c)
+		}
+	}
+	return contours[board], cutouts, nil
+}
+
+// extrude appends to tris the triangles of the solid between z0 and z1
+// of the counterclockwise polygon with the (clockwise) holes.
+func extrude(tris []meshTriangle, poly []Pt, holes [][]Pt, z0, z1 float64) []meshTriangle {
+	at := func(pt Pt, z float64) [3]float64 { return [3]float64{pt[0], pt[1], z} }
+	for _, t := range triangulate(cutHoles(poly, holes)) {
+		tris = append(tris,
+			meshTriangle{at(t[0], z1), at(t[1], z1), at(t[2], z1)},
+			meshTriangle{at(t[0], z0), at(t[2], z0), at(t[1], z0)})
+	}
+	for _, ring := range append([][]Pt{poly}, holes...) {
+		for i, a := range ring {
+			b := ring[(i+1)%len(ring)]
+			if a == b {
+				continue
+			}
+			tris = append(tris,
+				meshTriangle{at(a, z0), at(b, z0), at(b, z1)},
+				meshTriangle{at(a, z0), at(b, z1), at(a, z1)})
+		}
+	}
+	return tris
+}
+
+// triangulate splits the counterclockwise polygon (which may have
+// zero-width bridges to holes, see cutHoles) into counterclockwise
+// triangles by ear clipping.
+func triangulate(pts []Pt) [][3]Pt {
+	n := len(pts)
+	if n < 3 {
+		return nil
+	}
+	next, prev := make([]int, n), make([]int, n)
+	for i := range pts {
+		next[i], prev[i] = (i+1)%n, (i+n-1)%n
+	}
+	cross := func(a, b, c Pt) float64 {
+		return (b[0]-a[0])*(c[1]-a[1]) - (b[1]-a[1])*(c[0]-a[0])
+	}
+	isEar := func(i int) bool {
+		a, b, c := pts[prev[i]], pts[i], pts[next[i]]
+		if cross(a, b, c) <= 0 {
+			return false
+		}
+		for j := next[next[i]]; j != prev[i]; j = next[j] {
+			p := pts[j]
+			if p == a || p == b || p == c {
+				continue
+			}
+			if cross(a, b, p) >= 0 && cross(b, c, p) >= 0 && cross(c, a, p) >= 0 {
+				return false
+			}
+		}
+		return true
+	}
+
+	var result [][3]Pt
+	i := 0
+	for stuck := 0; n > 3; {
+		if !isEar(i) && stuck <= n {
+			i, stuck = next[i], stuck+1
+			continue
+		}
+		// When no ears remain, the polygon is degenerate (e.g. it
+		// crosses itself); clip its vertices anyway.
+		if a, b, c := pts[prev[i]], pts[i], pts[next[i]]; cross(a, b, c) > 0 {
+			result = append(result, [3]Pt{a, b, c})
+		}
+		next[prev[i]], prev[next[i]] = next[i], prev[i]
+		i, n, stuck = prev[i], n-1, 0
+	}
+	if a, b, c := pts[prev[i]], pts[i], pts[next[i]]; cross(a, b, c) > 0 {
+		result = append(result, [3]Pt{a, b, c})
+	}
+	return result
+}
@@ -0,0 +1,78 @@
+package gerber
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/gmlewis/go-gerber/gerber/internal/polyclip"
+)
+
+// stlVolume returns the number of triangles of a binary STL model, and
+// the volume they enclose.
+func stlVolume(t *testing.T, data []byte) (int, float64) {
+	t.Helper()
+	n := int(binary.LittleEndian.Uint32(data[80:]))
+	if len(data) != 84+50*n {
+		t.Fatalf("STL is %v bytes, want %v for %v triangles", len(data), 84+50*n, n)
+	}
+	var volume float64
+	for i := 0; i < n; i++ {
+		var v [12]float32
+		binary.Read(bytes.NewReader(data[84+50*i:]), binary.LittleEndian, &v)
+		a, b, c := v[3:6], v[6:9], v[9:12]
+		volume += float64(a[0]*(b[1]*c[2]-b[2]*c[1])-a[1]*(b[0]*c[2]-b[2]*c[0])+a[2]*(b[0]*c[1]-b[1]*c[0])) / 6
+	}
+	return n, volume
+}
+
+func TestGerber_WriteSTL(t *testing.T) {
+	g := New("board")
+	g.Outline().Add(OutlinePath(RoundedRect(MBB{Max: Pt{20, 10}}, 0), 0.1)...)
+	g.Outline().Add(OutlinePath(RoundedRect(MBB{Min: Pt{12, 2}, Max: Pt{18, 4}}, 0), 0.1)...)
+	g.Drill().Add(Circle(Pt{3, 3}, 1), Circle(Pt{3, 7}, 1))
+
+	var buf bytes.Buffer
+	if err := g.WriteSTL(&buf, MeshOpts{BoardThickness: 1}); err != nil {
+		t.Fatal(err)
+	}
+	_, volume := stlVolume(t, buf.Bytes())
+	want := 200 - 12 - 2*math.Pi*0.25
+	if math.Abs(volume-want) > 0.01 {
+		t.Errorf("board volume = %v, want %v", volume, want)
+	}
+
+	g.TopCopper().Add(Pad(Pt{8, 5}, RectShape, 2, 1, 0))
+	g.BottomCopper().Add(Pad(Pt{8, 5}, RectShape, 2, 1, 0), Clear(Circle(Pt{8, 5}, 0.5)))
+	buf.Reset()
+	if err := g.WriteSTL(&buf, MeshOpts{BoardThickness: 1, CopperThickness: 0.1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, v := stlVolume(t, buf.Bytes()); math.Abs(v-(want+2*0.2)) > 0.01 {
+		t.Errorf("volume with copper = %v, want %v", v, want+2*0.2)
+	}
+
+	open := New("open")
+	open.Outline().Add(Line(0, 0, 10, 0, CircleShape, 0.1))
+	if err := open.WriteSTL(&buf, MeshOpts{}); err == nil {
+		t.Error("WriteSTL of an open outline succeeded")
+	}
+}
+
+func TestTriangulate(t *testing.T) {
+	// An L-shaped polygon with a square hole.
+	poly := []Pt{{0, 0}, {10, 0}, {10, 4}, {4, 4}, {4, 10}, {0, 10}}
+	hole := []Pt{{1, 1}, {1, 3}, {3, 3}, {3, 1}}
+	var area float64
+	for _, tri := range triangulate(cutHoles(poly, [][]Pt{hole})) {
+		a := polyclip.SignedArea(tri[:])
+		if a <= 0 {
+			t.Errorf("triangle %v is not counterclockwise", tri)
+		}
+		area += a
+	}
+	if want := 64.0 - 4; math.Abs(area-want) > 1e-9 {
+		t.Errorf("area = %v, want %v", area, want)
+	}
+}
@@ -0,0 +1,139 @@
+package gerber
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FilenameConvention names the files of a design's layers.
+type FilenameConvention interface {
+	// Filename returns the filename for the layer of a design
+	// with the given filename prefix.
+	Filename(prefix string, layer *Layer) string
+}
+
+// FilenameFunc is an adapter that allows an ordinary function
+// to be used as a FilenameConvention.
+type FilenameFunc func(prefix string, layer *Layer) string
+
+// Filename calls f(prefix, layer).
+func (f FilenameFunc) Filename(prefix string, layer *Layer) string {
+	return f(prefix, layer)
+}
+
+// ProtelNames is the default FilenameConvention, which names layers
+// using the traditional Protel extensions (e.g. "prefix.gtl").
+var ProtelNames FilenameConvention = FilenameFunc(func(prefix string, layer *Layer) string {
+	return prefix + "." + protelExtension(layer)
+})
+
+// OSHParkNames is a FilenameConvention with the Protel extensions
+// expected by OSH Park: inner copper layers are named by their layer
+// number (e.g. "prefix.g2l") and the drill file is "prefix.xln".
+var OSHParkNames FilenameConvention = FilenameFunc(func(prefix string, layer *Layer) string {
+	switch layer.Type {
+	case InnerCopperLayer:
+		return fmt.Sprintf("%v.g%vl", prefix, layer.N)
+	case DrillLayer:
+		return prefix + ".xln"
+	}
+	return ProtelNames.Filename(prefix, layer)
+})
+
+// KiCadNames is a FilenameConvention with the names of the files
+// plotted by KiCad (e.g. "prefix-F_Cu.gbr" and "prefix-In1_Cu.gbr" for
+// layer 2), as expected by fabs with KiCad specific upload checks.
+// The drill file is "prefix.drl".
+var KiCadNames FilenameConvention = FilenameFunc(func(prefix string, layer *Layer) string {
+	if layer.Type == DrillLayer {
+		return prefix + ".drl"
+	}
+	return prefix + "-" + kicadLayerName(layer) + ".gbr"
+})
+
+func kicadLayerName(layer *Layer) string {
+	switch layer.Type {
+	case TopCopperLayer:
+		return "F_Cu"
+	case TopSolderMaskLayer:
+		return "F_Mask"
+	case TopSilkscreenLayer:
+		return "F_Silkscreen"
+	case BottomCopperLayer:
+		return "B_Cu"
+	case BottomSolderMaskLayer:
+		return "B_Mask"
+	case BottomSilkscreenLayer:
+		return "B_Silkscreen"
+	case InnerCopperLayer, InnerPlaneLayer:
+		return fmt.Sprintf("In%v_Cu", layer.N-1)
+	case MechanicalLayer:
+		return fmt.Sprintf("User_%v", layer.N)
+	case OutlineLayer:
+		return "Edge_Cuts"
+	case TopSolderPasteLayer:
+		return "F_Paste"
+	case BottomSolderPasteLayer:
+		return "B_Paste"
+	}
+	// KiCad has no dedicated flex or keep-out layers.
+	return layer.Type.String()
+}
+
+// filenameConventions are the built-in conventions that ParseDesign
+// recognizes.
+var filenameConventions = []FilenameConvention{ProtelNames, OSHParkNames, KiCadNames}
+
+// LayerTypeOf returns the layer type (and layer number, see Layer.N)
+// that ParseDesign infers from a filename, or false if the filename
+// does not match any of the built-in conventions (see ProtelNames,
+// OSHParkNames, and KiCadNames).
+func LayerTypeOf(filename string) (LayerType, int, bool) {
+	t, n, _, _, ok := layerTypeOf(filename)
+	return t, n, ok
+}
+
+// maxCopperLayers is the highest copper layer number that ParseDesign
+// recognizes in filenames.
+const maxCopperLayers = 32
+
+// layerTypeOf returns the layer type (and copper layer number) of a
+// filename in one of the built-in conventions, along with its filename
+// prefix and the convention. The longest matching name wins (for example, "board-F_Cu.gbr"
+// is a KiCad top copper layer of the design "board").
+func layerTypeOf(filename string) (t LayerType, n int, prefix string, fc FilenameConvention, ok bool) {
+	lower := strings.ToLower(filename)
+	var best int
+	try := func(lt LayerType, ln int) {
+		for _, c := range filenameConventions {
+			name := strings.ToLower(c.Filename("", &Layer{Type: lt, N: ln}))
+			if len(name) > best && len(lower) > len(name) && strings.HasSuffix(lower, name) {
+				t, n, prefix, fc, ok, best = lt, ln, filename[:len(filename)-len(name)], c, true, len(name)
+			}
+		}
+	}
+	for lt := range layerTypes {
+		if lt != InnerCopperLayer && lt != InnerPlaneLayer && lt != MechanicalLayer {
+			try(lt, 0)
+		}
+	}
+	for ln := 1; ln <= maxCopperLayers; ln++ {
+		try(InnerCopperLayer, ln)
+		try(InnerPlaneLayer, ln)
+		if ln > 1 { // "prefix.gm1" is the outline (see below)
+			try(MechanicalLayer, ln)
+		}
+	}
+	// Some CAM tools name the board outline "prefix.gm1".
+	if !ok && strings.HasSuffix(lower, ".gm1") && len(lower) > 4 {
+		return OutlineLayer, 0, filename[:len(filename)-4], ProtelNames, true
+	}
+	return t, n, prefix, fc, ok
+}
+
+func protelExtension(layer *Layer) string {
+	if info, ok := layer.typeInfo(); ok {
+		return info.extension
+	}
+	return "gbr"
+}
@@ -0,0 +1,197 @@
+package gerber
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+func init() {
+	registerPrimitive("netTie", func() Primitive { return &NetTieT{} })
+}
+
+// NetTagPrefix is the prefix of tags that name the net of a copper
+// primitive (e.g. "net:gnd"), which CheckShorts uses to find shorts.
+// The pads of padstack instances with a Net are tagged automatically.
+const NetTagPrefix = "net:"
+
+// NetTag returns the tag naming the given net.
+func NetTag(net string) string {
+	return NetTagPrefix + net
+}
+
+// NetTieT represents a net tie: copper deliberately joining two nets
+// (e.g. for a star ground or the sense connections of a current shunt)
+// with a round pad at each end and a neck of a defined width between
+// them. It satisfies the Primitive interface. CheckShorts does not
+// report the nets joined by a net tie as shorted.
+// All dimensions are in millimeters.
+type NetTieT struct {
+	// Nets are the names of the nets joined at P1 and P2.
+	Nets [2]string `json:"nets"`
+	P1   Pt        `json:"p1"`
+	P2   Pt        `json:"p2"`
+	// Pad is the diameter of the pads and Width the width of the neck.
+	Pad   float64 `json:"pad"`
+	Width float64 `json:"width"`
+}
+
+// NetTie returns a net tie joining net1 (at p1) and net2 (at p2).
+func NetTie(net1 string, p1 Pt, net2 string, p2 Pt, pad, width float64) *NetTieT {
+	return &NetTieT{Nets: [2]string{net1, net2}, P1: p1, P2: p2, Pad: pad, Width: width}
+}
+
+// WriteGerber writes the primitive to the Gerber file as regions, so
+// that its exact geometry does not depend on the available apertures.
+func (n *NetTieT) WriteGerber(w io.Writer, apertureIndex int) error {
+	gw := toWriter(w)
+	for _, c := range []Pt{n.P1, n.P2} {
+		start := Pt{c[0] + 0.5*n.Pad, c[1]}
+		if err := Region(start, ArcTo(start, c, false)).WriteGerber(gw, apertureIndex); err != nil {
+			return err
+		}
+	}
+	if neck := n.neck(); neck != nil {
+		return Polygon(Pt{}, true, neck, 0).WriteGerber(gw, apertureIndex)
+	}
+	return nil
+}
+
+// neck returns the rectangle of the neck between the centers of the
+// pads, or nil if they coincide.
+func (n *NetTieT) neck() []Pt {
+	length := Distance(n.P1, n.P2)
+	if length == 0 {
+		return nil
+	}
+	h := 0.5 * n.Width
+	dx, dy := -h*(n.P2[1]-n.P1[1])/length, h*(n.P2[0]-n.P1[0])/length
+	return []Pt{
+		{n.P1[0] - dx, n.P1[1] - dy}, {n.P2[0] - dx, n.P2[1] - dy},
+		{n.P2[0] + dx, n.P2[1] + dy}, {n.P1[0] + dx, n.P1[1] + dy},
+	}
+}
+
+// Aperture returns nil for NetTieT because it uses the default aperture.
+func (n *NetTieT) Aperture() *Aperture {
+	return nil
+}
+
+// MBB returns the minimum bounding box in millimeters.
+func (n *NetTieT) MBB() MBB {
+	r := 0.5 * math.Max(n.Pad, n.Width)
+	return MBB{
+		Min: Pt{math.Min(n.P1[0], n.P2[0]) - r, math.Min(n.P1[1], n.P2[1]) - r},
+		Max: Pt{math.Max(n.P1[0], n.P2[0]) + r, math.Max(n.P1[1], n.P2[1]) + r},
+	}
+}
+
+// Transform returns a transformed copy of the net tie.
+func (n *NetTieT) Transform(t Transform) Primitive {
+	s := t.scaleFactor()
+	return NetTie(n.Nets[0], t.Apply(n.P1), n.Nets[1], t.Apply(n.P2), s*n.Pad, s*n.Width)
+}
+
+// Expand returns a copy of the net tie grown by delta on each side.
+func (n *NetTieT) Expand(delta float64) Primitive {
+	return NetTie(n.Nets[0], n.P1, n.Nets[1], n.P2, expandSize(n.Pad, delta), expandSize(n.Width, delta))
+}
+
+// Contains reports whether pt lies within the pads or the neck.
+func (n *NetTieT) Contains(pt Pt) bool {
+	r := 0.5 * n.Pad
+	if Distance(pt, n.P1) <= r || Distance(pt, n.P2) <= r {
+		return true
+	}
+	neck := n.neck()
+	return neck != nil && pointInPolygon(pt, neck)
+}
+
+// CheckShorts derives the design's pads (see DeriveOpenings) and
+// checks each copper layer for shorts: connected (touching or
+// overlapping) copper containing primitives tagged with different nets
+// (see NetTag). Net ties (see NetTie) are exempt, but it is an error
+// for a net tie to touch copper of a net other than the two it joins.
+func (g *Gerber) CheckShorts() (Issues, error) {
+	if err := g.DeriveOpenings(); err != nil {
+		return nil, err
+	}
+	var issues Issues
+	for _, layer := range g.Layers {
+		if !layer.Type.IsCopper() {
+			continue
+		}
+		// Find the islands of the layer without its net ties.
+		var ties []*NetTieT
+		copper := &Layer{}
+		for _, p := range layer.Primitives {
+			if tie, ok := p.(*NetTieT); ok {
+				ties = append(ties, tie)
+				continue
+			}
+			copper.Primitives = append(copper.Primitives, p)
+		}
+		islands := copper.Islands(IslandOpts{})
+		islandNets := make([][]string, len(islands))
+		for i, island := range islands {
+			islandNets[i] = layer.netsOf(island.Primitives)
+			if len(islandNets[i]) > 1 {
+				issues = append(issues, Issue{
+					Severity:  SeverityError,
+					Layer:     layer,
+					Primitive: island.Primitives[0],
+					Message:   fmt.Sprintf("short between nets %v", strings.Join(islandNets[i], ", ")),
+				})
+			}
+		}
+		for _, tie := range ties {
+			o := outlineOf(tie)
+			mbb := tie.MBB()
+			for i, island := range islands {
+				if !islandTouches(island, o, mbb) {
+					continue
+				}
+				for _, net := range islandNets[i] {
+					if net != tie.Nets[0] && net != tie.Nets[1] {
+						issues = append(issues, Issue{
+							Severity:  SeverityError,
+							Layer:     layer,
+							Primitive: tie,
+							Message:   fmt.Sprintf("net tie of %v and %v at %v touches net %v", tie.Nets[0], tie.Nets[1], fmtPt(tie.P1), net),
+						})
+					}
+				}
+			}
+		}
+	}
+	return issues, nil
+}
+
+// netsOf returns the sorted names of the nets tagged on the primitives.
+func (l *Layer) netsOf(primitives []Primitive) []string {
+	seen := map[string]bool{}
+	var nets []string
+	for _, p := range primitives {
+		for _, tag := range l.Tags(p) {
+			if net := strings.TrimPrefix(tag, NetTagPrefix); net != tag && !seen[net] {
+				seen[net] = true
+				nets = append(nets, net)
+			}
+		}
+	}
+	sort.Strings(nets)
+	return nets
+}
+
+// islandTouches reports whether any primitive of the island touches
+// the outline o (with minimum bounding box mbb).
+func islandTouches(island *Island, o outline, mbb MBB) bool {
+	for _, p := range island.Primitives {
+		if pm := p.MBB(); pm.Intersects(&mbb) && outlineOf(p).overlaps(o) {
+			return true
+		}
+	}
+	return false
+}
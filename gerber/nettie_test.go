@@ -0,0 +1,120 @@
+package gerber
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestNetTieT_Primitive(t *testing.T) {
+	var p Primitive = &NetTieT{}
+	if p == nil {
+		// In actuality, this test won't compile if it isn't a Primitive.
+		t.Errorf("NetTieT does not implement the Primitive interface")
+	}
+}
+
+func TestGerber_CheckShorts(t *testing.T) {
+	tests := []struct {
+		name    string
+		join    func(top *Layer)
+		wantErr string
+	}{
+		{name: "separate"},
+		{
+			name: "net tie",
+			join: func(top *Layer) { top.Add(NetTie("gnd", Pt{0, 0}, "agnd", Pt{5, 0}, 0.5, 0.2)) },
+		},
+		{
+			name:    "trace",
+			join:    func(top *Layer) { top.Add(Line(0, 0, 5, 0, CircleShape, 0.2)) },
+			wantErr: "short between nets agnd, gnd",
+		},
+		{
+			name:    "net tie touching another net",
+			join:    func(top *Layer) { top.Add(NetTie("gnd", Pt{0, 0}, "agnd", Pt{0, 5}, 0.5, 0.2)) },
+			wantErr: "net tie of gnd and agnd at (0,0) touches net vcc",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New("test")
+			top := g.TopCopper()
+			pad := &Padstack{Top: RoundPad(1)}
+			for _, p := range []struct {
+				net string
+				at  Pt
+			}{{"gnd", Pt{0, 0}}, {"agnd", Pt{5, 0}}, {"vcc", Pt{0, 5}}} {
+				g.PlacePadstack(pad, p.at, 0).Net = p.net
+			}
+			// A trace on the "agnd" net.
+			top.AddTagged([]string{NetTag("agnd")}, Line(5, 0, 10, 0, CircleShape, 0.2))
+			if tt.join != nil {
+				tt.join(top)
+			}
+			issues, err := g.CheckShorts()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr == "" {
+				if len(issues) != 0 {
+					t.Errorf("CheckShorts = %v, want no issues", issues)
+				}
+				return
+			}
+			if len(issues) != 1 || !strings.Contains(issues[0].Message, tt.wantErr) {
+				t.Errorf("CheckShorts = %v, want %q", issues, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNetTieT_Geometry(t *testing.T) {
+	tie := NetTie("a", Pt{1, 1}, "b", Pt{4, 1}, 1, 0.2)
+	if want := (MBB{Min: Pt{0.5, 0.5}, Max: Pt{4.5, 1.5}}); !mbbNear(tie.MBB(), want, 1e-9) {
+		t.Errorf("MBB = %v, want %v", tie.MBB(), want)
+	}
+	for _, tt := range []struct {
+		pt   Pt
+		want bool
+	}{{Pt{1.4, 1.3}, true}, {Pt{2.5, 1.05}, true}, {Pt{2.5, 1.2}, false}} {
+		if got := tie.Contains(tt.pt); got != tt.want {
+			t.Errorf("Contains(%v) = %v, want %v", tt.pt, got, tt.want)
+		}
+	}
+
+	moved := tie.Transform(Rotate(90)).(*NetTieT)
+	if want := (MBB{Min: Pt{-1.5, 0.5}, Max: Pt{-0.5, 4.5}}); !mbbNear(moved.MBB(), want, 1e-9) {
+		t.Errorf("rotated MBB = %v, want %v", moved.MBB(), want)
+	}
+
+	g := New("test")
+	g.TopCopper().Add(tie)
+	var buf bytes.Buffer
+	if err := g.Layers[0].WriteGerber(&buf); err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := Parse(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(parsed.Primitives); got != 3 {
+		t.Errorf("parsed %v primitives, want 3 regions", got)
+	}
+	if !mbbNear(parsed.MBB(), tie.MBB(), 0.01) {
+		t.Errorf("parsed MBB = %v, want %v", parsed.MBB(), tie.MBB())
+	}
+
+	data, err := json.Marshal(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ng Gerber
+	if err := json.Unmarshal(data, &ng); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := ng.Layers[0].Primitives[0].(*NetTieT); !ok || *got != *tie {
+		t.Errorf("unmarshaled %v, want %v", ng.Layers[0].Primitives[0], tie)
+	}
+}
@@ -0,0 +1,66 @@
+package gerber
+
+import "github.com/gmlewis/go-gerber/gerber/internal/polyclip"
+
+// OffsetPrimitive returns the contour of p grown by delta millimeters on each
+// side (shrunk, if delta is negative), such as a solder mask opening
+// for a copper pad or a clearance zone around a drill hole. Unlike
+// ExpandPrimitive, which joins the offset edges of polygons with
+// miters, the corners that the offset turns around (the convex corners
+// when growing, and the reflex corners when shrinking) become circular
+// arcs about the original corners, so that the result is exactly the
+// set of points within delta of p. Polygons and regions become regions
+// (whose arcs are flattened unless WithNativeArcs is set); other
+// primitives are expanded (see ExpandPrimitive), which is exact for
+// their round and rectangular shapes.
+//
+// When shrinking, delta must be small compared to the polygon's edges
+// (see the geometry package for general offsets).
+func OffsetPrimitive(p Primitive, delta float64) (Primitive, error) {
+	switch v := p.(type) {
+	case *PolygonT:
+		pts := make([]Pt, 0, len(v.Points))
+		for _, pt := range v.Points {
+			pts = append(pts, Pt{pt[0] + v.Offset[0], pt[1] + v.Offset[1]})
+		}
+		return offsetContour(pts, delta), nil
+	case *RegionT:
+		return offsetContour(v.Points(0), delta), nil
+	case *ClearT:
+		o, err := OffsetPrimitive(v.Primitive, delta)
+		if err != nil {
+			return nil, err
+		}
+		return Clear(o), nil
+	}
+	return ExpandPrimitive(p, delta)
+}
+
+// offsetContour returns the region of the closed polygon pts offset by
+// delta, with arcs at the corners that the offset turns around.
+func offsetContour(pts []Pt, delta float64) Primitive {
+	clean := polyclip.Clean(pts)
+	if len(clean) < 3 || delta == 0 {
+		return Polygon(Pt{}, true, clean, 0)
+	}
+	// Outward is to the right of a counterclockwise polygon.
+	d := delta
+	if polyclip.SignedArea(clean) > 0 {
+		d = -delta
+	}
+
+	var start Pt
+	var segments []Segment
+	for i, k := range polyclip.Corners(clean, d, true, polyclip.RoundJoin) {
+		if i == 0 {
+			start = k.In
+		} else {
+			segments = append(segments, LineTo(k.In))
+		}
+		if k.Round {
+			segments = append(segments, ArcTo(k.Out, k.Vertex, d > 0))
+		}
+	}
+	// The region closes back to the start.
+	return Region(start, segments...)
+}
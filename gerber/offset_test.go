@@ -0,0 +1,77 @@
+package gerber
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gmlewis/go-gerber/gerber/internal/polyclip"
+)
+
+func TestOffsetPrimitive(t *testing.T) {
+	square := []Pt{{0, 0}, {4, 0}, {4, 4}, {0, 4}}
+	// An L shape (wound clockwise) with a reflex corner at (2,2).
+	ell := []Pt{{0, 0}, {0, 4}, {2, 4}, {2, 2}, {4, 2}, {4, 0}}
+	tests := []struct {
+		name    string
+		p       Primitive
+		delta   float64
+		area    float64
+		arcs    int
+		inside  []Pt
+		outside []Pt
+	}{
+		{name: "grow square", p: Polygon(Pt{1, 1}, true, square, 0), delta: 1, area: 32 + math.Pi, arcs: 4,
+			inside: []Pt{{0.5, 0.5}, {2, 5.9}}, outside: []Pt{{0.2, 0.2}, {2, 6.1}}},
+		{name: "shrink square", p: Polygon(Pt{}, true, square, 0), delta: -1, area: 4,
+			inside: []Pt{{1.1, 1.1}}, outside: []Pt{{0.9, 2}}},
+		{name: "grow ell", p: Region(ell[0], LineTo(ell[1]), LineTo(ell[2]), LineTo(ell[3]), LineTo(ell[4]), LineTo(ell[5])), delta: 0.5,
+			area: 12 + 0.5*16 + 5*0.25*math.Pi/4 - 0.25, arcs: 5, inside: []Pt{{2.4, 2.4}}, outside: []Pt{{2.6, 2.6}}},
+		{name: "shrink ell", p: Polygon(Pt{}, true, ell, 0), delta: -0.5, area: 12 - 0.5*16 + 5*0.25 - 0.25*math.Pi/4, arcs: 1,
+			inside: []Pt{{1.5, 1.5}}, outside: []Pt{{2.3, 2.3}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := OffsetPrimitive(tt.p, tt.delta)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r, ok := got.(*RegionT)
+			if !ok {
+				t.Fatalf("OffsetPrimitive = %T, want *RegionT", got)
+			}
+			var arcs int
+			for _, s := range r.Segments {
+				if s.Arc {
+					arcs++
+				}
+			}
+			if arcs != tt.arcs {
+				t.Errorf("arcs = %v, want %v", arcs, tt.arcs)
+			}
+			if area := math.Abs(polyclip.SignedArea(r.Points(0.0001))); math.Abs(area-tt.area) > 0.001 {
+				t.Errorf("area = %v, want %v", area, tt.area)
+			}
+			for _, pt := range tt.inside {
+				if !r.Contains(pt) {
+					t.Errorf("Contains(%v) = false, want true", pt)
+				}
+			}
+			for _, pt := range tt.outside {
+				if r.Contains(pt) {
+					t.Errorf("Contains(%v) = true, want false", pt)
+				}
+			}
+		})
+	}
+
+	got, err := OffsetPrimitive(Circle(Pt{1, 1}, 2), 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mbb := got.MBB(); !mbbNear(mbb, MBB{Min: Pt{-0.5, -0.5}, Max: Pt{2.5, 2.5}}, 1e-9) {
+		t.Errorf("OffsetPrimitive(circle) MBB = %v", mbb)
+	}
+	if _, err := OffsetPrimitive(&TextT{}, 1); err == nil {
+		t.Error("OffsetPrimitive(text) = nil, want error")
+	}
+}
@@ -0,0 +1,340 @@
+package gerber
+
+import (
+	"fmt"
+	"math"
+)
+
+// Openings declares the solder mask and solder paste openings to
+// generate for a copper primitive, so that pads do not have to be
+// duplicated by hand across the copper, mask, and paste layers.
+// Expansions (in millimeters) grow (or, if negative, shrink) the
+// copper geometry on each side. See Gerber.DeriveOpenings.
+type Openings struct {
+	Mask           bool    `json:"mask,omitempty"`
+	MaskExpansion  float64 `json:"maskExpansion,omitempty"`
+	Paste          bool    `json:"paste,omitempty"`
+	PasteExpansion float64 `json:"pasteExpansion,omitempty"`
+}
+
+// MaskPolicy represents the solder mask openings that DeriveOpenings
+// adds for the pads without declared openings (see WithMaskPolicy):
+// the flashed primitives (circles, pads, and flashes) of the top and
+// bottom copper layers, other than those derived from padstacks (whose
+// openings follow the padstacks' tenting). Traces and other drawn
+// copper stay covered.
+type MaskPolicy struct {
+	// Expansion grows the openings beyond the pads or, if negative,
+	// shrinks them (for solder mask defined pads), in millimeters.
+	Expansion float64 `json:"expansion,omitempty"`
+	// TentVias covers the pads of vias (those tagged with ViaTag or
+	// StitchingViaTag) with solder mask instead of opening them.
+	TentVias bool `json:"tentVias,omitempty"`
+}
+
+// policyOpenings returns the openings that the design's mask policy
+// gives a primitive of the top or bottom copper layer l, and whether
+// it gives any.
+func (g *Gerber) policyOpenings(l *Layer, p Primitive) (Openings, bool) {
+	if g.maskPolicy == nil || l.IsDerived(p) {
+		return Openings{}, false
+	}
+	switch p.(type) {
+	case *CircleT, *PadT, *FlashT:
+	default:
+		return Openings{}, false
+	}
+	if g.maskPolicy.TentVias && (l.HasTag(p, ViaTag) || l.HasTag(p, StitchingViaTag)) {
+		return Openings{}, false
+	}
+	return Openings{Mask: true, MaskExpansion: g.maskPolicy.Expansion}, true
+}
+
+// AddWithOpenings adds primitives to a layer (like Add) and declares
+// the openings to generate for each of them.
+func (l *Layer) AddWithOpenings(o Openings, primitives ...Primitive) {
+	l.Add(primitives...)
+	for _, p := range primitives {
+		l.SetOpenings(p, o)
+	}
+}
+
+// SetOpenings declares the openings to generate for a primitive
+// in the layer, replacing any previous declaration.
+func (l *Layer) SetOpenings(p Primitive, o Openings) {
+	if l.openings == nil {
+		l.openings = map[Primitive]Openings{}
+	}
+	l.openings[p] = o
+}
+
+// Openings returns the openings declared for a primitive in the layer.
+func (l *Layer) Openings(p Primitive) (Openings, bool) {
+	o, ok := l.openings[p]
+	return o, ok
+}
+
+// IsDerived reports whether the primitive was generated by
+// Gerber.DeriveOpenings.
+func (l *Layer) IsDerived(p Primitive) bool {
+	return l.derived[p]
+}
+
+// AddDerived adds primitives to a layer (like Add) and marks them as
+// derived, so that they are removed the next time DeriveOpenings runs.
+// Export passes (see Pass) use it to remain repeatable.
+func (l *Layer) AddDerived(primitives ...Primitive) {
+	l.Add(primitives...)
+	if l.derived == nil {
+		l.derived = map[Primitive]bool{}
+	}
+	for _, p := range primitives {
+		l.derived[p] = true
+	}
+}
+
+// DeriveOpenings removes all previously derived primitives and then
+// runs the design's export pipeline (see Pipeline), which by default
+// (re)generates the primitives of the design's padstack instances (see
+// PlacePadstack) and the solder mask and paste primitives declared with
+// Openings (or, see WithMaskPolicy, by the design's mask policy) on the
+// top and bottom copper layers, adding the needed layers to the design
+// if necessary, and then clips the silkscreen and adds the drill chart
+// if enabled (see WithSilkscreenClipping and WithDrillChart). On a side
+// of a flex design with a coverlay layer (see TopCoverlay), the mask
+// openings are cut in the coverlay instead of a solder mask layer.
+// DeriveOpenings may be called any number of times. When the design is
+// written (e.g. by WriteGerber), the openings are derived into copies
+// of its layers, leaving the design itself unchanged.
+func (g *Gerber) DeriveOpenings() error {
+	for _, layer := range g.Layers {
+		if len(layer.derived) > 0 {
+			layer.Remove(layer.IsDerived)
+			layer.derived = nil
+		}
+	}
+	for _, pass := range g.Pipeline() {
+		if err := pass.Run(g); err != nil {
+			return fmt.Errorf("%v pass: %v", pass.Name, err)
+		}
+	}
+	return nil
+}
+
+// deriveOutput derives the design's openings (see DeriveOpenings) into
+// copies of its layers, which replace the design's layers until restore
+// is called, so that the export pipeline runs on (and adds its layers
+// to) the output of a write only.
+func (g *Gerber) deriveOutput() (restore func(), err error) {
+	layers := g.Layers
+	g.mu.Lock()
+	mbb := g.mbb
+	g.mbb = nil // the copies extend (and may replace) the cached MBB
+	g.mu.Unlock()
+	restore = func() {
+		g.Layers = layers
+		g.mu.Lock()
+		g.mbb = mbb
+		g.mu.Unlock()
+	}
+	g.Layers = make([]*Layer, len(layers))
+	for i, layer := range layers {
+		g.Layers[i] = layer.outputCopy()
+	}
+	if err := g.DeriveOpenings(); err != nil {
+		restore()
+		return nil, err
+	}
+	return restore, nil
+}
+
+// outputCopy returns a copy of the layer that shares its primitives
+// and apertures, but whose primitives, tags, attributes, openings, and
+// cached MBB may be changed without changing the layer.
+func (l *Layer) outputCopy() *Layer {
+	c := *l
+	c.Primitives = append([]Primitive(nil), l.Primitives...)
+	c.Apertures = append([]*Aperture(nil), l.Apertures...)
+	c.apertureMap = make(map[string]int, len(l.apertureMap))
+	for k, v := range l.apertureMap {
+		c.apertureMap[k] = v
+	}
+	if l.tags != nil {
+		c.tags = make(map[Primitive][]string, len(l.tags))
+		for p, tags := range l.tags {
+			c.tags[p] = append([]string(nil), tags...)
+		}
+	}
+	if l.attrs != nil {
+		c.attrs = make(map[Primitive]Attributes, len(l.attrs))
+		for p, attrs := range l.attrs {
+			c.attrs[p] = attrs
+		}
+	}
+	if l.openings != nil {
+		c.openings = make(map[Primitive]Openings, len(l.openings))
+		for p, o := range l.openings {
+			c.openings[p] = o
+		}
+	}
+	if l.derived != nil {
+		c.derived = make(map[Primitive]bool, len(l.derived))
+		for p, d := range l.derived {
+			c.derived[p] = d
+		}
+	}
+	c.headerHooks = append([]WriteHook(nil), l.headerHooks...)
+	c.footerHooks = append([]WriteHook(nil), l.footerHooks...)
+	if l.mbb != nil {
+		mbb := *l.mbb
+		c.mbb = &mbb
+	}
+	c.index, c.indexed = nil, nil
+	return &c
+}
+
+// deriveOpenings adds the solder mask and paste primitives declared
+// with Openings (or given by the mask policy) on the top and bottom
+// copper layers. On a side of a flex design with a coverlay layer, the
+// mask openings are cut in the coverlay instead.
+func (g *Gerber) deriveOpenings() error {
+	sides := []struct{ copper, mask, coverlay, paste LayerType }{
+		{TopCopperLayer, TopSolderMaskLayer, TopCoverlayLayer, TopSolderPasteLayer},
+		{BottomCopperLayer, BottomSolderMaskLayer, BottomCoverlayLayer, BottomSolderPasteLayer},
+	}
+	var holes []Primitive
+	if g.autoPaste {
+		for _, layer := range g.layersOfType(DrillLayer) {
+			holes = append(holes, layer.Primitives...)
+		}
+	}
+	for _, side := range sides {
+		mask := side.mask
+		if len(g.layersOfType(side.coverlay)) > 0 {
+			mask = side.coverlay
+		}
+		for _, copper := range g.layersOfType(side.copper) {
+			for _, p := range copper.Primitives {
+				o, ok := copper.openings[p]
+				if !ok {
+					if o, ok = g.policyOpenings(copper, p); !ok {
+						continue
+					}
+				}
+				if o.Mask {
+					if err := g.derive(mask, copper, p, o.MaskExpansion); err != nil {
+						return fmt.Errorf("layer %v: %v", copper.Filename, err)
+					}
+				}
+				if !o.Paste && g.autoPaste && o.Mask && copper.isSMDPad(p, holes) {
+					o.Paste = true
+					mbb := p.MBB()
+					o.PasteExpansion = -0.5 * g.pasteShrink * math.Min(mbb.Max[0]-mbb.Min[0], mbb.Max[1]-mbb.Min[1])
+				}
+				if o.Paste {
+					if err := g.derive(side.paste, copper, p, o.PasteExpansion); err != nil {
+						return fmt.Errorf("layer %v: %v", copper.Filename, err)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// isSMDPad reports whether p is a surface mount pad of the layer for
+// WithAutoPaste: a flashed pad that is not a via, fiducial, test pad, or
+// castellation and has no hole (of holes) at its center.
+func (l *Layer) isSMDPad(p Primitive, holes []Primitive) bool {
+	switch p.(type) {
+	case *CircleT, *PadT, *FlashT:
+	default:
+		return false
+	}
+	for _, tag := range []string{ViaTag, FiducialTag, TestPadTag, CastellationTag} {
+		if l.HasTag(p, tag) {
+			return false
+		}
+	}
+	mbb := p.MBB()
+	center := Pt{0.5 * (mbb.Min[0] + mbb.Max[0]), 0.5 * (mbb.Min[1] + mbb.Max[1])}
+	for _, h := range holes {
+		if hm := h.MBB(); hm.ContainsPoint(&center) {
+			return false
+		}
+	}
+	return true
+}
+
+// derive adds the expanded copy of p (a primitive of the layer from)
+// to the (first) layer of type t, with the attributes of p.
+func (g *Gerber) derive(t LayerType, from *Layer, p Primitive, delta float64) error {
+	ep, err := ExpandPrimitive(p, delta)
+	if err != nil {
+		return err
+	}
+	layer := g.firstLayerOfType(t)
+	layer.AddDerived(ep)
+	layer.setAttributes(ep, from.Attributes(p))
+	return nil
+}
+
+// layersOfType returns the layers of the design with the given type.
+func (g *Gerber) layersOfType(t LayerType) []*Layer {
+	var layers []*Layer
+	for _, layer := range g.Layers {
+		if layer.Type == t {
+			layers = append(layers, layer)
+		}
+	}
+	return layers
+}
+
+// PadDefinition determines whether the copper or the solder mask
+// opening defines the exposed area of a pad.
+type PadDefinition int
+
+const (
+	// CopperDefined (non-solder-mask-defined) pads have a mask opening
+	// larger than the copper.
+	CopperDefined PadDefinition = iota
+	// MaskDefined (solder-mask-defined) pads have a mask opening smaller
+	// than the copper, as commonly needed for BGAs and RF pads.
+	MaskDefined
+)
+
+// DefaultMaskMargin is the mask margin (in millimeters) used by AddPad
+// when PadOpts.MaskMargin is zero.
+const DefaultMaskMargin = 0.05
+
+// PadOpts represents the options used by AddPad.
+type PadOpts struct {
+	Definition PadDefinition
+	// MaskMargin is the (positive) distance between the copper edge and
+	// the mask opening edge: the mask clearance of a CopperDefined pad or
+	// the mask overlap of a MaskDefined pad. Zero uses DefaultMaskMargin.
+	MaskMargin float64
+	// Paste generates a solder paste opening, grown by PasteExpansion.
+	Paste          bool
+	PasteExpansion float64
+}
+
+// AddPad adds copper pads to a layer and declares their solder mask
+// (and, optionally, paste) openings according to opts, so that the pads
+// are generated consistently across the copper, mask, and paste layers.
+func (l *Layer) AddPad(opts PadOpts, pads ...Primitive) {
+	margin := opts.MaskMargin
+	if margin == 0 {
+		margin = DefaultMaskMargin
+	}
+	margin = math.Abs(margin)
+	if opts.Definition == MaskDefined {
+		margin = -margin
+	}
+	l.AddWithOpenings(Openings{
+		Mask:           true,
+		MaskExpansion:  margin,
+		Paste:          opts.Paste,
+		PasteExpansion: opts.PasteExpansion,
+	}, pads...)
+}
@@ -0,0 +1,244 @@
+package gerber
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGerber_DeriveOpenings(t *testing.T) {
+	g := New("test")
+	top := g.TopCopper()
+	mask := g.TopSolderMask()
+	pad := Circle(Pt{1, 1}, 1)
+	rect := Polygon(Pt{5, 5}, true, []Pt{{0, 0}, {2, 0}, {2, 1}, {0, 1}}, 0)
+	top.AddWithOpenings(Openings{Mask: true, MaskExpansion: 0.05, Paste: true, PasteExpansion: -0.1}, pad, rect)
+	top.Add(Line(0, 0, 5, 5, CircleShape, 0.2)) // no openings
+
+	for i := 0; i < 2; i++ { // deriving is idempotent
+		if err := g.DeriveOpenings(); err != nil {
+			t.Fatalf("DeriveOpenings: %v", err)
+		}
+	}
+	if got, want := len(g.Layers), 3; got != want {
+		t.Fatalf("len(Layers) = %v, want %v (paste layer added)", got, want)
+	}
+	paste := g.Layers[2]
+	if paste.Type != TopSolderPasteLayer || paste.Filename != "test.gtp" {
+		t.Errorf("paste layer = %v %q, want TopSolderPaste test.gtp", paste.Type, paste.Filename)
+	}
+
+	tests := []struct {
+		name  string
+		layer *Layer
+		want  []MBB
+	}{
+		{
+			name:  "mask",
+			layer: mask,
+			want: []MBB{
+				{Min: Pt{0.45, 0.45}, Max: Pt{1.55, 1.55}},
+				{Min: Pt{4.95, 4.95}, Max: Pt{7.05, 6.05}},
+			},
+		},
+		{
+			name:  "paste",
+			layer: paste,
+			want: []MBB{
+				{Min: Pt{0.6, 0.6}, Max: Pt{1.4, 1.4}},
+				{Min: Pt{5.1, 5.1}, Max: Pt{6.9, 5.9}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := len(tt.layer.Primitives); got != len(tt.want) {
+				t.Fatalf("len(Primitives) = %v, want %v", got, len(tt.want))
+			}
+			for i, p := range tt.layer.Primitives {
+				if !tt.layer.IsDerived(p) {
+					t.Errorf("primitive #%v is not marked as derived", i)
+				}
+				if got := p.MBB(); !mbbNear(got, tt.want[i], 1e-9) {
+					t.Errorf("primitive #%v MBB = %v, want %v", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+
+	// A JSON round trip must not duplicate derived primitives.
+	data, err := json.Marshal(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ng := &Gerber{}
+	if err := json.Unmarshal(data, ng); err != nil {
+		t.Fatal(err)
+	}
+	if err := ng.DeriveOpenings(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := layerOutputs(t, ng), layerOutputs(t, g); !reflect.DeepEqual(got, want) {
+		t.Errorf("outputs after JSON round trip differ")
+	}
+}
+
+func mbbNear(a, b MBB, eps float64) bool {
+	for i := 0; i < 2; i++ {
+		if d := a.Min[i] - b.Min[i]; d > eps || d < -eps {
+			return false
+		}
+		if d := a.Max[i] - b.Max[i]; d > eps || d < -eps {
+			return false
+		}
+	}
+	return true
+}
+
+func TestLayer_AddPad(t *testing.T) {
+	tests := []struct {
+		name string
+		opts PadOpts
+		want float64 // mask opening diameter
+	}{
+		{name: "copper defined, default margin", opts: PadOpts{}, want: 1.1},
+		{name: "copper defined", opts: PadOpts{MaskMargin: 0.1}, want: 1.2},
+		{name: "mask defined", opts: PadOpts{Definition: MaskDefined, MaskMargin: 0.1}, want: 0.8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New("test")
+			g.TopCopper().AddPad(tt.opts, Circle(Pt{0, 0}, 1))
+			if err := g.DeriveOpenings(); err != nil {
+				t.Fatal(err)
+			}
+			mask := g.layersOfType(TopSolderMaskLayer)
+			if len(mask) != 1 || len(mask[0].Primitives) != 1 {
+				t.Fatalf("mask layers = %v, want 1 layer with 1 primitive", mask)
+			}
+			if got := mask[0].Primitives[0].Aperture().Size; math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("mask opening = %v, want %v", got, tt.want)
+			}
+			if got := len(g.layersOfType(TopSolderPasteLayer)); got != 0 {
+				t.Errorf("paste layers = %v, want 0", got)
+			}
+		})
+	}
+}
+
+func TestGerber_Write_LeavesDesignUnchanged(t *testing.T) {
+	g := New("test")
+	top := g.TopCopper()
+	mask := g.TopSolderMask()
+	top.AddWithOpenings(Openings{Mask: true, Paste: true}, Circle(Pt{1, 1}, 1))
+	mbb := g.MBB()
+
+	files := map[string]*memFile{}
+	err := g.Write(func(filename string) (io.WriteCloser, error) {
+		f := &memFile{}
+		files[filename] = f
+		return f, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := files["test.gtp"]; !ok {
+		t.Errorf("Write did not write the derived paste layer: %v", files)
+	}
+	if !strings.Contains(files["test.gts"].String(), "X1000000Y1000000D01*") {
+		t.Errorf("Write did not write the derived mask opening:\n%v", files["test.gts"])
+	}
+	if got := len(g.Layers); got != 2 {
+		t.Errorf("len(Layers) = %v after Write, want 2", got)
+	}
+	if got := len(mask.Primitives); got != 0 {
+		t.Errorf("mask has %v primitives after Write, want 0", got)
+	}
+	if got := g.MBB(); got != mbb {
+		t.Errorf("MBB = %v after Write, want %v", got, mbb)
+	}
+}
+
+func TestGerber_DeriveOpenings_AutoPaste(t *testing.T) {
+	g := New("test", WithAutoPaste(0.2))
+	top, bottom := g.TopCopper(), g.BottomCopper()
+	top.AddPad(PadOpts{}, Pad(Pt{0, 0}, RectShape, 2, 1, 0), Circle(Pt{5, 0}, 1))
+	top.AddPad(PadOpts{Paste: true, PasteExpansion: 0.1}, Circle(Pt{10, 0}, 1))
+	top.Add(Circle(Pt{15, 0}, 1)) // tented
+	top.AddPad(PadOpts{}, Line(20, 0, 25, 0, CircleShape, 1))
+	// A through-hole pad and an untented via.
+	g.PlacePadstack(&Padstack{Drill: 1, Top: RoundPad(2), Bottom: RoundPad(2)}, Pt{30, 0}, 0)
+	g.Via(35, 0, 0.3, 0.6, ViaUntented)
+	bottom.AddPad(PadOpts{}, Circle(Pt{40, 0}, 1))
+	if err := g.DeriveOpenings(); err != nil {
+		t.Fatal(err)
+	}
+
+	paste := g.firstLayerOfType(TopSolderPasteLayer)
+	want := []MBB{
+		{Min: Pt{-0.9, -0.4}, Max: Pt{0.9, 0.4}},
+		{Min: Pt{4.6, -0.4}, Max: Pt{5.4, 0.4}},
+		{Min: Pt{9.4, -0.6}, Max: Pt{10.6, 0.6}},
+	}
+	if len(paste.Primitives) != len(want) {
+		t.Fatalf("top paste = %v primitives, want %v", len(paste.Primitives), len(want))
+	}
+	for i, p := range paste.Primitives {
+		if got := p.MBB(); !mbbNear(got, want[i], 1e-9) {
+			t.Errorf("top paste #%v = %v, want %v", i, got, want[i])
+		}
+	}
+	if got := len(g.firstLayerOfType(BottomSolderPasteLayer).Primitives); got != 1 {
+		t.Errorf("bottom paste = %v primitives, want 1", got)
+	}
+}
+
+func TestWithMaskPolicy(t *testing.T) {
+	for _, tentVias := range []bool{false, true} {
+		g := New("test", WithMaskPolicy(MaskPolicy{Expansion: 0.1, TentVias: tentVias}))
+		top := g.TopCopper()
+		bottom := g.BottomCopper()
+		top.Add(Pad(Pt{0, 0}, RectShape, 2, 1, 0), Circle(Pt{5, 0}, 1))
+		top.Add(Line(0, 5, 5, 5, CircleShape, 0.2)) // a trace
+		covered := Circle(Pt{10, 0}, 1)
+		top.AddWithOpenings(Openings{}, covered)
+		top.AddWithOpenings(Openings{Mask: true}, Circle(Pt{15, 0}, 1)) // declared
+		g.Via(20, 0, 0.3, 0.6, ViaTented)
+		// Tented padstack pads follow their padstack.
+		g.PlacePadstack(&Padstack{Drill: 1, Top: RoundPad(2), Bottom: RoundPad(2), TentTop: true, TentBottom: true}, Pt{30, 0}, 0)
+		for i := 0; i < 2; i++ { // deriving is idempotent
+			if err := g.DeriveOpenings(); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		want := []MBB{
+			{Min: Pt{-1.1, -0.6}, Max: Pt{1.1, 0.6}},
+			{Min: Pt{4.4, -0.6}, Max: Pt{5.6, 0.6}},
+			{Min: Pt{14.5, -0.5}, Max: Pt{15.5, 0.5}},
+		}
+		if !tentVias {
+			want = append(want, MBB{Min: Pt{19.6, -0.4}, Max: Pt{20.4, 0.4}})
+		}
+		mask := g.firstLayerOfType(TopSolderMaskLayer)
+		if len(mask.Primitives) != len(want) {
+			t.Fatalf("TentVias=%v: top mask = %v primitives, want %v", tentVias, len(mask.Primitives), len(want))
+		}
+		for i, p := range mask.Primitives {
+			if got := p.MBB(); !mbbNear(got, want[i], 1e-9) {
+				t.Errorf("TentVias=%v: top mask #%v = %v, want %v", tentVias, i, got, want[i])
+			}
+		}
+		wantBottom := 0
+		if !tentVias {
+			wantBottom = 1
+		}
+		if got := len(g.firstLayerOfType(BottomSolderMaskLayer).Primitives); got != wantBottom || len(bottom.Primitives) != 2 {
+			t.Errorf("TentVias=%v: bottom mask = %v primitives, want %v", tentVias, got, wantBottom)
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package gerber
+
+// Option configures a Gerber design. See New.
+type Option func(*Gerber)
+
+// Units represents the units used when writing Gerber files.
+// Designs are always constructed in millimeters; Units only
+// affects the output.
+type Units int
+
+const (
+	// UnitsMM writes Gerber files in millimeters (%MOMM*%).
+	UnitsMM Units = iota
+	// UnitsInch writes Gerber files in inches (%MOIN*%).
+	UnitsInch
+)
+
+// String returns the Gerber mode code for the units ("MM" or "IN").
+func (u Units) String() string {
+	if u == UnitsInch {
+		return "IN"
+	}
+	return "MM"
+}
+
+// CoordinateFormat represents the number of integer and decimal
+// digits in the Gerber format specification (e.g. %FSLAX36Y36*%).
+type CoordinateFormat struct {
+	Integer int
+	Decimal int
+}
+
+var (
+	// DefaultFormatMM is the coordinate format used for millimeters.
+	DefaultFormatMM = CoordinateFormat{Integer: 3, Decimal: 6}
+	// DefaultFormatInch is the coordinate format used for inches.
+	DefaultFormatInch = CoordinateFormat{Integer: 2, Decimal: 6}
+)
+
+const defaultApertureSize = 0.001 // mm
+
+// WithUnits sets the units of the output Gerber files.
+func WithUnits(units Units) Option {
+	return func(g *Gerber) {
+		g.units = units
+	}
+}
+
+var (
+	// Inches is shorthand for WithUnits(UnitsInch): coordinates,
+	// aperture sizes, and the format specification (see
+	// DefaultFormatInch) are written in inches, e.g.
+	//
+	//	g := gerber.New(prefix, gerber.Inches)
+	Inches Option = WithUnits(UnitsInch)
+	// Millimeters is shorthand for WithUnits(UnitsMM), the default.
+	Millimeters Option = WithUnits(UnitsMM)
+)
+
+// WithCoordinateFormat sets the coordinate format (precision) of the
+// output Gerber files. If not set, DefaultFormatMM or DefaultFormatInch
+// is used depending upon the units.
+func WithCoordinateFormat(integer, decimal int) Option {
+	return func(g *Gerber) {
+		g.format = &CoordinateFormat{Integer: integer, Decimal: decimal}
+	}
+}
+
+// WithOrigin sets the design coordinate that maps to (0,0) in the
+// output Gerber files.
+func WithOrigin(origin Pt) Option {
+	return func(g *Gerber) {
+		g.origin = origin
+	}
+}
+
+// WithExportTransform sets the transformation applied to all
+// coordinates at write time. See Gerber.SetExportTransform.
+func WithExportTransform(t Transform) Option {
+	return func(g *Gerber) {
+		g.SetExportTransform(t)
+	}
+}
+
+// WithDefaultApertureSize sets the diameter (in millimeters) of the
+// default aperture (D11) selected by regions such as polygons and text.
+// Since regions do not actually draw with it, a size of zero omits the
+// default aperture (and its selection) entirely, for fabs that reject
+// sub-resolution apertures. Third-party primitives that draw with the
+// default aperture (i.e. whose Aperture method returns nil) require a
+// positive size.
+func WithDefaultApertureSize(size float64) Option {
+	return func(g *Gerber) {
+		g.defaultApertureSize = size
+	}
+}
+
+// WithArcTolerance sets the maximum chord error (in millimeters) allowed
+// when arcs are flattened into line segments, trading file size for
+// fidelity. If not set (or zero), arcs are divided into segments of
+// roughly 0.1mm. The tolerance also applies to curves (see CurveT),
+// which otherwise default to 0.01mm. An arc's or curve's own Tolerance
+// takes precedence.
+func WithArcTolerance(tol float64) Option {
+	return func(g *Gerber) {
+		g.arcTolerance = tol
+	}
+}
+
+// WithNativeArcs enables or disables writing circular arcs (both ArcT
+// primitives with equal X and Y scales and the arc segments of
+// regions, see Region) as G02/G03 circular interpolation, rather than
+// flattening them into line segments. Export transforms must then have
+// a uniform scale.
+func WithNativeArcs(enabled bool) Option {
+	return func(g *Gerber) {
+		g.nativeArcs = enabled
+	}
+}
+
+// WithOutputValidation enables or disables checking the command stream
+// of each layer as it is written (see CheckGerber), so that writing
+// fails with the offending primitive rather than producing a file that
+// a fab rejects. This is mostly useful when developing primitives (see
+// RegisterPrimitive) and write hooks.
+func WithOutputValidation(enabled bool) Option {
+	return func(g *Gerber) {
+		g.validateOutput = enabled
+	}
+}
+
+// WithX2 enables or disables the writing of Gerber X2 attributes: the
+// file attributes (e.g. %TF.FileFunction*%) in the header of each
+// layer, the functions of the apertures of copper, drill, and outline
+// layers (e.g. %TA.AperFunction,ViaPad*%, see AperFunctionAttribute),
+// and the net (.N) and component (.C) object attributes of copper and
+// drill primitives tagged with NetTag and PartTag.
+func WithX2(enabled bool) Option {
+	return func(g *Gerber) {
+		g.x2 = enabled
+	}
+}
+
+// WithSilkscreenClipping enables an export pass (see DeriveOpenings)
+// that clips the silkscreen layers against the solder mask openings on
+// the same side, grown by margin millimeters, so that silkscreen never
+// prints on exposed copper. A margin of zero (the default) disables
+// clipping.
+func WithSilkscreenClipping(margin float64) Option {
+	return func(g *Gerber) {
+		g.silkscreenMargin = margin
+	}
+}
+
+// WithDrillChart enables an export pass (see DrillChartPass) that
+// prints a reduced drill chart (the number of holes of each finished
+// size) in the given font in an unused corner of the top silkscreen,
+// for prototype boards ordered without a fab drawing. An empty font
+// name (the default) disables the chart.
+func WithDrillChart(fontName string) Option {
+	return func(g *Gerber) {
+		g.drillChartFont = fontName
+	}
+}
+
+// WithAutoFormat enables or disables the automatic addition of integer
+// digits to the coordinate format (see WithCoordinateFormat) when the
+// design (e.g. a large panel) has coordinates that would not otherwise
+// fit. Without it, writing such a design returns an error.
+func WithAutoFormat(enabled bool) Option {
+	return func(g *Gerber) {
+		g.autoFormat = enabled
+	}
+}
+
+// WithGrid snaps all output coordinates to multiples of grid
+// millimeters (e.g. 0.001), rounding half away from zero, so that
+// floating point noise in a generator cannot change the output between
+// runs or platforms. The grid is rounded to a whole number of output
+// coordinate units. A layer's Grid overrides this setting. A grid of
+// zero (the default) disables snapping.
+func WithGrid(grid float64) Option {
+	return func(g *Gerber) {
+		g.grid = grid
+	}
+}
+
+// WithManifest enables or disables the writing of a manifest of the
+// Gerber set, listing each file with its function, units, coordinate
+// format, size, and SHA-256 checksum, alongside the layers (see
+// ManifestFilenames): as JSON for automation and as a text table for
+// people (and fabs) reading the set.
+func WithManifest(enabled bool) Option {
+	return func(g *Gerber) {
+		g.manifest = enabled
+	}
+}
+
+// WithRevision sets the revision (e.g. "B" or "1.2") of the design, so
+// that every file of a release is consistently marked: each layer is
+// written with a .ProjectId attribute naming the design and revision,
+// the manifest records it, and WithRevisionStamp prints it on the
+// silkscreen. An empty revision (the default) marks nothing.
+func WithRevision(rev string) Option {
+	return func(g *Gerber) {
+		g.revision = rev
+	}
+}
+
+// WithRevisionStamp enables an export pass (see RevisionPass) that
+// prints the design's revision (see RevisionText) in the given font,
+// centered on at, on the top silkscreen and, mirrored, on the bottom
+// silkscreen if the design has one. An empty font name (the default)
+// disables the stamp.
+func WithRevisionStamp(fontName string, at Pt) Option {
+	return func(g *Gerber) {
+		g.revisionFont = fontName
+		g.revisionAt = at
+	}
+}
+
+// WithTestPads enables an export pass (see TestPadPass) that adds a
+// bottom side test pad for each of opts.Nets, on the grid of a
+// bed-of-nails test fixture, so that generated boards can be tested
+// (see WriteFixtureDrillMap). Nil opts (the default) disable the pass.
+func WithTestPads(opts *TestPadOpts) Option {
+	return func(g *Gerber) {
+		g.testPads = opts
+	}
+}
+
+// WithStackup sets the stackup of the design (see Stackup), used by
+// its electrical analyses such as CurrentCapacities.
+func WithStackup(s Stackup) Option {
+	return func(g *Gerber) {
+		g.stackup = &s
+	}
+}
+
+// WithAutoPaste makes DeriveOpenings add solder paste openings (to the
+// TopSolderPaste and BottomSolderPaste layers, for ordering a stencil)
+// for the surface mount pads of the outer copper layers that have
+// solder mask openings but no declared paste openings (see Openings):
+// their flashed pads without holes, other than vias, fiducials, and
+// test pads. Each aperture is shrunk by the fraction shrink (e.g. 0.1)
+// of the smaller dimension of its pad, reducing the amount of paste;
+// 0 keeps the pads' size.
+func WithAutoPaste(shrink float64) Option {
+	return func(g *Gerber) {
+		g.autoPaste = true
+		g.pasteShrink = shrink
+	}
+}
+
+// WithMaskPolicy makes DeriveOpenings add solder mask openings for the
+// pads of the outer copper layers that have no declared openings (see
+// Openings), as set by the policy, so that mask layers need not be
+// drawn by hand. Declared openings override the policy: a pad declared
+// with SetOpenings(p, Openings{}) stays covered.
+func WithMaskPolicy(policy MaskPolicy) Option {
+	return func(g *Gerber) {
+		g.maskPolicy = &policy
+	}
+}
+
+// WithParallelWrites sets the number of layers that Gerber.Write (and
+// WriteGerber, etc.) serializes concurrently, for large designs. With
+// n > 1, each layer is serialized to memory and then written to its
+// file in order; its write hooks (see OnHeader) may be run concurrently
+// with those of other layers. The default (0 or 1) writes one layer at
+// a time, directly to its file.
+func WithParallelWrites(n int) Option {
+	return func(g *Gerber) {
+		g.parallelWrites = n
+	}
+}
+
+// WithFilenameConvention sets the convention used to name layer files.
+func WithFilenameConvention(fc FilenameConvention) Option {
+	return func(g *Gerber) {
+		g.naming = fc
+	}
+}
+
+// Format returns the coordinate format used to write the design.
+func (g *Gerber) Format() CoordinateFormat {
+	if g.format != nil {
+		return *g.format
+	}
+	if g.units == UnitsInch {
+		return DefaultFormatInch
+	}
+	return DefaultFormatMM
+}
+
+// Units returns the units used to write the design.
+func (g *Gerber) Units() Units {
+	return g.units
+}
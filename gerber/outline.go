@@ -0,0 +1,196 @@
+package gerber
+
+import "math"
+
+// filletSegments is the number of segments used for each rounded corner.
+const filletSegments = 16
+
+// RoundCorners returns the closed polygon pts with each corner rounded
+// to the given radius (in mm). Radii too large for a corner's edges are
+// reduced to fit.
+func RoundCorners(pts []Pt, radius float64) []Pt {
+	n := len(pts)
+	if n < 3 || radius <= 0 {
+		return pts
+	}
+	var result []Pt
+	for i, p := range pts {
+		a, b := pts[(i+n-1)%n], pts[(i+1)%n]
+		la, lb := Distance(p, a), Distance(p, b)
+		if la == 0 || lb == 0 {
+			result = append(result, p)
+			continue
+		}
+		u := Pt{(a[0] - p[0]) / la, (a[1] - p[1]) / la}
+		v := Pt{(b[0] - p[0]) / lb, (b[1] - p[1]) / lb}
+		half := 0.5 * math.Acos(math.Max(-1, math.Min(1, u[0]*v[0]+u[1]*v[1])))
+		if half < 1e-9 || math.Pi/2-half < 1e-9 { // no corner to round
+			result = append(result, p)
+			continue
+		}
+		// Distance from the corner to the tangent points.
+		d := radius / math.Tan(half)
+		if limit := 0.5 * math.Min(la, lb); d > limit {
+			d = limit
+		}
+		r := d * math.Tan(half)
+		bisector := Pt{u[0] + v[0], u[1] + v[1]}
+		bl := math.Hypot(bisector[0], bisector[1])
+		center := Pt{p[0] + bisector[0]/bl*r/math.Sin(half), p[1] + bisector[1]/bl*r/math.Sin(half)}
+		start, end := Pt{p[0] + u[0]*d, p[1] + u[1]*d}, Pt{p[0] + v[0]*d, p[1] + v[1]*d}
+		a0, a1 := math.Atan2(start[1]-center[1], start[0]-center[0]), math.Atan2(end[1]-center[1], end[0]-center[0])
+		sweep := a1 - a0
+		for sweep > math.Pi {
+			sweep -= 2 * math.Pi
+		}
+		for sweep < -math.Pi {
+			sweep += 2 * math.Pi
+		}
+		for k := 0; k <= filletSegments; k++ {
+			angle := a0 + sweep*float64(k)/filletSegments
+			result = append(result, Pt{center[0] + r*math.Cos(angle), center[1] + r*math.Sin(angle)})
+		}
+	}
+	return result
+}
+
+// RoundedRect returns the closed polygon of the rectangle mbb with its
+// corners rounded to the given radius.
+func RoundedRect(mbb MBB, radius float64) []Pt {
+	return RoundCorners([]Pt{mbb.Min, {mbb.Max[0], mbb.Min[1]}, mbb.Max, {mbb.Min[0], mbb.Max[1]}}, radius)
+}
+
+// OutlinePath returns the lines (of the given width) tracing the
+// closed polygon pts, as used on an outline layer.
+func OutlinePath(pts []Pt, width float64) []Primitive {
+	var result []Primitive
+	for i := range pts {
+		p1, p2 := pts[i], pts[(i+1)%len(pts)]
+		result = append(result, Line(p1[0], p1[1], p2[0], p2[1], CircleShape, width))
+	}
+	return result
+}
+
+// RoundedRectOutline returns the lines and arcs (of the given width)
+// tracing the rectangle mbb with its corners rounded to the given
+// radius (reduced to fit the rectangle), for an outline layer: board
+// outlines and interior cutouts. Unlike tracing RoundedRect with
+// OutlinePath, the corners are arcs (see WithNativeArcs).
+func RoundedRectOutline(mbb MBB, radius, width float64) []Primitive {
+	r := math.Max(0, math.Min(radius, 0.5*math.Min(mbb.Max[0]-mbb.Min[0], mbb.Max[1]-mbb.Min[1])))
+	x0, y0, x1, y1 := mbb.Min[0]+r, mbb.Min[1]+r, mbb.Max[0]-r, mbb.Max[1]-r
+	var result []Primitive
+	line := func(p1, p2 Pt) {
+		if p1 != p2 {
+			result = append(result, Line(p1[0], p1[1], p2[0], p2[1], CircleShape, width))
+		}
+	}
+	corner := func(c Pt, start float64) {
+		if r > 0 {
+			result = append(result, Arc(c, r, CircleShape, 1, 1, start, start+90, width))
+		}
+	}
+	line(Pt{x0, mbb.Min[1]}, Pt{x1, mbb.Min[1]})
+	corner(Pt{x1, y0}, 270)
+	line(Pt{mbb.Max[0], y0}, Pt{mbb.Max[0], y1})
+	corner(Pt{x1, y1}, 0)
+	line(Pt{x1, mbb.Max[1]}, Pt{x0, mbb.Max[1]})
+	corner(Pt{x0, y1}, 90)
+	line(Pt{mbb.Min[0], y1}, Pt{mbb.Min[0], y0})
+	corner(Pt{x0, y0}, 180)
+	return result
+}
+
+// CircularOutline returns the full circle arc (drawn with the given
+// width) of a round board or cutout of radius r centered on center.
+func CircularOutline(center Pt, r, width float64) []Primitive {
+	return []Primitive{Arc(center, r, CircleShape, 1, 1, 0, 360, width)}
+}
+
+// SlotOutline returns the lines and arcs (of the given width) tracing
+// a routed slot of width slotWidth between the centers p1 and p2 (a
+// round cutout if they coincide), for an outline layer.
+func SlotOutline(p1, p2 Pt, slotWidth, width float64) []Primitive {
+	r := 0.5 * slotWidth
+	if p1 == p2 {
+		return CircularOutline(p1, r, width)
+	}
+	a := math.Atan2(p2[1]-p1[1], p2[0]-p1[0]) * 180 / math.Pi
+	n := PolarFrom(Pt{}, r, a+90)
+	return []Primitive{
+		Line(p1[0]-n[0], p1[1]-n[1], p2[0]-n[0], p2[1]-n[1], CircleShape, width),
+		Arc(p2, r, CircleShape, 1, 1, a-90, a+90, width),
+		Line(p2[0]+n[0], p2[1]+n[1], p1[0]+n[0], p1[1]+n[1], CircleShape, width),
+		Arc(p1, r, CircleShape, 1, 1, a+90, a+270, width),
+	}
+}
+
+// FormFactor represents a standard board outline with its mounting
+// holes. All dimensions are in millimeters, relative to the lower left
+// corner of the board.
+type FormFactor struct {
+	Name          string
+	Width, Height float64
+	CornerRadius  float64
+	// Holes are the centers of the mounting holes.
+	Holes        []Pt
+	HoleDiameter float64
+}
+
+// Standard form factors.
+var (
+	// ArduinoShield is an Arduino Uno shield (2.7" x 2.1").
+	ArduinoShield = FormFactor{
+		Name:         "Arduino shield",
+		Width:        68.58,
+		Height:       53.34,
+		Holes:        []Pt{{13.97, 2.54}, {15.24, 50.8}, {66.04, 7.62}, {66.04, 35.56}},
+		HoleDiameter: 3.2,
+	}
+	// RaspberryPiHAT is a Raspberry Pi HAT (65mm x 56.5mm).
+	RaspberryPiHAT = FormFactor{
+		Name:         "Raspberry Pi HAT",
+		Width:        65,
+		Height:       56.5,
+		CornerRadius: 3,
+		Holes:        []Pt{{3.5, 3.5}, {61.5, 3.5}, {3.5, 52.5}, {61.5, 52.5}},
+		HoleDiameter: 2.75,
+	}
+	// Feather is an Adafruit Feather (2.0" x 0.9").
+	Feather = FormFactor{
+		Name:         "Feather",
+		Width:        50.8,
+		Height:       22.86,
+		CornerRadius: 2.54,
+		Holes:        []Pt{{2.54, 2.54}, {48.26, 2.54}, {2.54, 20.32}, {48.26, 20.32}},
+		HoleDiameter: 2.5,
+	}
+	// BusinessCard is a standard US business card (3.5" x 2").
+	BusinessCard = FormFactor{
+		Name:         "Business card",
+		Width:        88.9,
+		Height:       50.8,
+		CornerRadius: 3,
+	}
+)
+
+// Outline returns the closed polygon of the board outline with its
+// lower left corner at ll.
+func (f FormFactor) Outline(ll Pt) []Pt {
+	return RoundedRect(MBB{Min: ll, Max: Pt{ll[0] + f.Width, ll[1] + f.Height}}, f.CornerRadius)
+}
+
+// AddTo adds the board outline (drawn with lines and arcs of the given
+// width, see RoundedRectOutline) and mounting holes (tagged with
+// NonPlatedTag), with the board's lower left corner at ll, to the
+// design's outline and drill layers, which are added if necessary.
+func (f FormFactor) AddTo(g *Gerber, ll Pt, width float64) {
+	g.firstLayerOfType(OutlineLayer).Add(RoundedRectOutline(MBB{Min: ll, Max: Pt{ll[0] + f.Width, ll[1] + f.Height}}, f.CornerRadius, width)...)
+	if len(f.Holes) == 0 {
+		return
+	}
+	drill := g.firstLayerOfType(DrillLayer)
+	for _, h := range f.Holes {
+		drill.AddTagged([]string{NonPlatedTag}, Circle(Pt{ll[0] + h[0], ll[1] + h[1]}, f.HoleDiameter))
+	}
+}
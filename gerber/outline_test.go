@@ -0,0 +1,94 @@
+package gerber
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRoundCorners(t *testing.T) {
+	const eps = 1e-9
+	pts := RoundedRect(MBB{Min: Pt{0, 0}, Max: Pt{10, 5}}, 1)
+	if got, want := len(pts), 4*(filletSegments+1); got != want {
+		t.Fatalf("len(pts) = %v, want %v", got, want)
+	}
+	// Every point of the first (lower left) fillet is 1mm from (1,1).
+	for _, pt := range pts[:filletSegments+1] {
+		if d := Distance(pt, Pt{1, 1}); math.Abs(d-1) > eps {
+			t.Errorf("fillet point %v is %v from the corner center, want 1", pt, d)
+		}
+	}
+	// The area is that of the rectangle less the corners (approximately).
+	want := 50 - (4 - math.Pi)
+	if got := PolygonArea(pts); math.Abs(got-want) > 0.01 {
+		t.Errorf("area = %v, want %v", got, want)
+	}
+	// A radius larger than the edges allow is reduced.
+	mbb := polygonMBB(RoundedRect(MBB{Min: Pt{0, 0}, Max: Pt{2, 2}}, 5))
+	if mbb.Max[0]-mbb.Min[0] > 2+eps {
+		t.Errorf("oversized fillet MBB = %v, want within (0,0)-(2,2)", mbb)
+	}
+}
+
+func TestFormFactor_AddTo(t *testing.T) {
+	g := New("test")
+	RaspberryPiHAT.AddTo(g, Pt{10, 10}, 0.1)
+	if got, want := len(g.Layers), 2; got != want {
+		t.Fatalf("len(Layers) = %v, want %v", got, want)
+	}
+	outline := g.Layers[0].MBB()
+	if got, want := outline.Max[0]-outline.Min[0], 65.1; math.Abs(got-want) > 1e-9 {
+		t.Errorf("outline width = %v, want %v", got, want)
+	}
+	drill := g.Layers[1]
+	if got, want := len(drill.Primitives), 4; got != want {
+		t.Errorf("holes = %v, want %v", got, want)
+	}
+	if got, want := drill.MBB().Min, (Pt{13.5 - 1.375, 13.5 - 1.375}); math.Abs(got[0]-want[0]) > 1e-9 || math.Abs(got[1]-want[1]) > 1e-9 {
+		t.Errorf("drill MBB.Min = %v, want %v", got, want)
+	}
+}
+
+func TestOutlineBuilders(t *testing.T) {
+	tests := []struct {
+		name       string
+		primitives []Primitive
+		wantArcs   int
+		want       MBB
+	}{
+		{"rounded rect", RoundedRectOutline(MBB{Min: Pt{0, 0}, Max: Pt{10, 5}}, 1, 0.1), 4, MBB{Min: Pt{-0.05, -0.05}, Max: Pt{10.05, 5.05}}},
+		{"sharp rect", RoundedRectOutline(MBB{Min: Pt{0, 0}, Max: Pt{10, 5}}, 0, 0.1), 0, MBB{Min: Pt{-0.05, -0.05}, Max: Pt{10.05, 5.05}}},
+		{"obround", RoundedRectOutline(MBB{Min: Pt{0, 0}, Max: Pt{10, 4}}, 5, 0.1), 4, MBB{Min: Pt{-0.05, -0.05}, Max: Pt{10.05, 4.05}}},
+		{"circle", CircularOutline(Pt{5, 5}, 5, 0.1), 1, MBB{Min: Pt{-0.05, -0.05}, Max: Pt{10.05, 10.05}}},
+		{"slot", SlotOutline(Pt{1, 1}, Pt{1, 4}, 2, 0.1), 2, MBB{Min: Pt{-0.05, -0.05}, Max: Pt{2.05, 5.05}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New("test").Outline()
+			l.Add(tt.primitives...)
+			var arcs int
+			for _, p := range tt.primitives {
+				if _, ok := p.(*ArcT); ok {
+					arcs++
+				}
+			}
+			if arcs != tt.wantArcs {
+				t.Errorf("arcs = %v, want %v", arcs, tt.wantArcs)
+			}
+			if contours := l.Contours(1e-6); len(contours) != 1 || !contours[0].Closed {
+				t.Errorf("Contours = %+v, want one closed contour", contours)
+			}
+			if got := l.MBB(); !mbbNear(got, tt.want, 1e-9) {
+				t.Errorf("MBB = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func polygonMBB(pts []Pt) MBB {
+	mbb := MBB{Min: pts[0], Max: pts[0]}
+	for _, pt := range pts[1:] {
+		v := MBB{Min: pt, Max: pt}
+		mbb.Join(&v)
+	}
+	return mbb
+}
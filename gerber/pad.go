@@ -0,0 +1,160 @@
+package gerber
+
+import (
+	"fmt"
+	"io"
+	"math"
+)
+
+func init() {
+	registerPrimitive("pad", func() Primitive { return &PadT{} })
+}
+
+// The aperture macros generated for pads that the standard apertures
+// cannot represent. Their parameters are the width ($1) and height ($2)
+// of the pad, then the corner radius ($3) of a rounded rectangle, then
+// the rotation in degrees.
+var (
+	rotatedRectMacro = &ApertureMacro{Name: "RotRect", Primitives: []MacroPrimitive{
+		MacroCenterLine("$1", "$2", "0", "0").Rotated("$3"),
+	}}
+	rotatedObroundMacro = &ApertureMacro{Name: "RotObround", Primitives: []MacroPrimitive{
+		MacroCenterLine("$1-$2", "$2", "0", "0").Rotated("$3"),
+		MacroCircle("$2", "($1-$2)/2", "0").Rotated("$3"),
+		MacroCircle("$2", "-($1-$2)/2", "0").Rotated("$3"),
+	}}
+	roundRectMacro = &ApertureMacro{Name: "RoundRect", Primitives: []MacroPrimitive{
+		MacroCenterLine("$1", "$2-$3x2", "0", "0").Rotated("$4"),
+		MacroCenterLine("$1-$3x2", "$2", "0", "0").Rotated("$4"),
+		MacroCircle("$3x2", "$1/2-$3", "$2/2-$3").Rotated("$4"),
+		MacroCircle("$3x2", "-$1/2+$3", "$2/2-$3").Rotated("$4"),
+		MacroCircle("$3x2", "-$1/2+$3", "-$2/2+$3").Rotated("$4"),
+		MacroCircle("$3x2", "$1/2-$3", "-$2/2+$3").Rotated("$4"),
+	}}
+)
+
+// PadT represents a surface mount pad: a Width by Height rectangle,
+// obround, or rounded rectangle centered on Center and rotated
+// counterclockwise by Rotation degrees. It satisfies the Primitive
+// interface. Axis aligned rectangles and obrounds are flashed with
+// standard apertures; other pads (e.g. of footprints placed at 45°) are
+// flashed with generated aperture macros rather than drawn as regions.
+type PadT struct {
+	Center Pt
+	// Shape is RectShape or ObroundShape (CircleShape is the same as
+	// ObroundShape).
+	Shape         Shape
+	Width, Height float64
+	// Radius is the corner radius of a rounded rectangle (RectShape).
+	// A radius of half the smaller side makes an obround.
+	Radius   float64
+	Rotation float64
+}
+
+// Pad returns a rectangle or obround pad.
+func Pad(center Pt, shape Shape, width, height, rotation float64) *PadT {
+	return &PadT{Center: center, Shape: shape, Width: width, Height: height, Rotation: rotation}
+}
+
+// RoundRectPad returns a rectangular pad with corners of radius r.
+func RoundRectPad(center Pt, width, height, r, rotation float64) *PadT {
+	return &PadT{Center: center, Shape: RectShape, Width: width, Height: height, Radius: r, Rotation: rotation}
+}
+
+// radius returns the pad's effective corner radius.
+func (p *PadT) radius() float64 {
+	max := 0.5 * math.Min(p.Width, p.Height)
+	if p.Shape != RectShape {
+		return max
+	}
+	return math.Max(0, math.Min(p.Radius, max))
+}
+
+// WriteGerber writes the primitive to the Gerber file.
+func (p *PadT) WriteGerber(w io.Writer, apertureIndex int) error {
+	if !(p.Width > 0 && p.Height > 0) {
+		return fmt.Errorf("pad of size %vx%v", fmtFloat(p.Width), fmtFloat(p.Height))
+	}
+	gw := toWriter(w)
+	gw.aperture(apertureIndex)
+	gw.flash(p.Center[0], p.Center[1])
+	return nil
+}
+
+// Aperture returns the aperture flashed by the pad.
+func (p *PadT) Aperture() *Aperture {
+	w, h, r := p.Width, p.Height, p.radius()
+	rotation := NormalizeAngle(p.Rotation)
+	if q := math.Round(rotation / 90); math.Abs(rotation-90*q) < validationEps {
+		// Quarter turns of symmetric shapes need no macro.
+		if int(q)%2 == 1 {
+			w, h = h, w
+		}
+		rotation = 0
+	}
+	obround := r > 0 && r >= 0.5*math.Min(w, h)-validationEps
+	switch {
+	case obround && rotation == 0 && w == h:
+		return &Aperture{Shape: CircleShape, Size: w}
+	case obround && rotation == 0:
+		return &Aperture{Shape: ObroundShape, Size: w, Height: h}
+	case obround:
+		if h > w {
+			w, h, rotation = h, w, NormalizeAngle(rotation+90)
+		}
+		return rotatedObroundMacro.Aperture(w, h, rotation)
+	case r > 0:
+		return roundRectMacro.Aperture(w, h, r, rotation)
+	case rotation == 0:
+		return &Aperture{Shape: RectShape, Size: w, Height: h}
+	}
+	return rotatedRectMacro.Aperture(w, h, rotation)
+}
+
+// MBB returns the minimum bounding box in millimeters.
+func (p *PadT) MBB() MBB {
+	r := p.radius()
+	a, b := 0.5*p.Width-r, 0.5*p.Height-r
+	s, c := math.Sincos(Radians(p.Rotation))
+	dx := math.Abs(a*c) + math.Abs(b*s) + r
+	dy := math.Abs(a*s) + math.Abs(b*c) + r
+	return MBB{Min: Pt{p.Center[0] - dx, p.Center[1] - dy}, Max: Pt{p.Center[0] + dx, p.Center[1] + dy}}
+}
+
+// Contains reports whether pt lies within the pad.
+func (p *PadT) Contains(pt Pt) bool {
+	local := RotatePt(Pt{pt[0] - p.Center[0], pt[1] - p.Center[1]}, Pt{}, -p.Rotation)
+	r := p.radius()
+	dx := math.Max(0, math.Abs(local[0])-(0.5*p.Width-r))
+	dy := math.Max(0, math.Abs(local[1])-(0.5*p.Height-r))
+	return dx*dx+dy*dy <= r*r+validationEps*validationEps
+}
+
+// Transform returns a transformed copy of the pad.
+func (p *PadT) Transform(t Transform) Primitive {
+	s := t.scaleFactor()
+	rotation := p.Rotation + Degrees(t.rotation())
+	if t.det() < 0 {
+		// The pad is symmetric about its axes, so mirroring it about
+		// the X axis only negates its rotation.
+		rotation = Degrees(t.rotation()) - p.Rotation
+	}
+	return &PadT{Center: t.Apply(p.Center), Shape: p.Shape, Width: s * p.Width, Height: s * p.Height,
+		Radius: s * p.Radius, Rotation: NormalizeAngle(rotation)}
+}
+
+// Expand returns a copy of the pad grown by delta on each side. The
+// corners of rectangles stay sharp.
+func (p *PadT) Expand(delta float64) Primitive {
+	r := p.Radius
+	if r > 0 {
+		r = math.Max(0, r+delta)
+	}
+	return &PadT{Center: p.Center, Shape: p.Shape, Width: expandSize(p.Width, delta), Height: expandSize(p.Height, delta),
+		Radius: r, Rotation: p.Rotation}
+}
+
+func (p *PadT) String() string {
+	return fmt.Sprintf("Pad(%v, %v, %vx%v, r=%v, %v deg)", fmtPt(p.Center), p.Shape,
+		fmtFloat(p.Width), fmtFloat(p.Height), fmtFloat(p.radius()), fmtFloat(p.Rotation))
+}
@@ -0,0 +1,143 @@
+package gerber
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestPadT_Aperture(t *testing.T) {
+	tests := []struct {
+		name string
+		pad  *PadT
+		want string // ID
+	}{
+		{name: "rect", pad: Pad(Pt{}, RectShape, 2, 1, 0), want: "R2000000.00000x1000000.00000"},
+		{name: "quarter turn rect", pad: Pad(Pt{}, RectShape, 2, 1, -90), want: "R1000000.00000x2000000.00000"},
+		{name: "square", pad: Pad(Pt{}, RectShape, 1, 1, 180), want: "R1000000.00000"},
+		{name: "obround", pad: Pad(Pt{}, ObroundShape, 2, 1, 0), want: "O2000000.00000x1000000.00000"},
+		{name: "circle", pad: Pad(Pt{}, CircleShape, 1, 1, 30), want: "MRotObround,1X1X30"},
+		{name: "rotated rect", pad: Pad(Pt{}, RectShape, 2, 1, 45), want: "MRotRect,2X1X45"},
+		{name: "rotated obround", pad: Pad(Pt{}, ObroundShape, 1, 2, 45), want: "MRotObround,2X1X135"},
+		{name: "round rect", pad: RoundRectPad(Pt{}, 2, 1, 0.25, 0), want: "MRoundRect,2X1X0.25X0"},
+		{name: "fully rounded rect", pad: RoundRectPad(Pt{}, 2, 1, 0.5, 90), want: "O1000000.00000x2000000.00000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.pad.Aperture().ID(); got != tt.want {
+				t.Errorf("Aperture = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPadT_Geometry(t *testing.T) {
+	pad := Pad(Pt{10, 10}, RectShape, 2, 1, 45)
+	d := 1.5 / math.Sqrt2
+	if want := (MBB{Min: Pt{10 - d, 10 - d}, Max: Pt{10 + d, 10 + d}}); !mbbNear(pad.MBB(), want, 1e-9) {
+		t.Errorf("MBB = %v, want %v", pad.MBB(), want)
+	}
+	round := RoundRectPad(Pt{}, 4, 2, 0.5, 90)
+	if want := (MBB{Min: Pt{-1, -2}, Max: Pt{1, 2}}); !mbbNear(round.MBB(), want, 1e-9) {
+		t.Errorf("round rect MBB = %v, want %v", round.MBB(), want)
+	}
+	tests := []struct {
+		p    *PadT
+		pt   Pt
+		want bool
+	}{
+		{p: pad, pt: Pt{10.7, 10.7}, want: true},
+		{p: pad, pt: Pt{10.7, 9.3}, want: false},
+		{p: round, pt: Pt{0, 1.9}, want: true},
+		{p: round, pt: Pt{0.95, 1.95}, want: false},
+		{p: round, pt: Pt{0.8, 1.8}, want: true},
+		{p: Pad(Pt{}, ObroundShape, 4, 2, 0), pt: Pt{1.9, 0.9}, want: false},
+	}
+	for _, tt := range tests {
+		if got := tt.p.Contains(tt.pt); got != tt.want {
+			t.Errorf("%v.Contains(%v) = %v, want %v", tt.p, tt.pt, got, tt.want)
+		}
+	}
+
+	moved := pad.Transform(RotateAbout(Pt{10, 10}, 45).Then(MirrorX())).(*PadT)
+	if moved.Rotation != 270 || moved.Center != (Pt{10, -10}) {
+		t.Errorf("Transform = %v, want a pad at (10,-10) rotated by 270 degrees", moved)
+	}
+	if got := pad.Expand(0.1).(*PadT); got.Width != 2.2 || got.Height != 1.2 || got.Radius != 0 {
+		t.Errorf("Expand = %v, want a sharp 2.2x1.2 rectangle", got)
+	}
+}
+
+func TestPadT_WriteGerber(t *testing.T) {
+	tests := []struct {
+		name  string
+		units Units
+		want  []string
+	}{
+		{
+			name: "millimeters",
+			want: []string{"%AMRotRect*\n21,1,$1,$2,0,0,$3*\n%\n", "RotRect,2X1X45*%\n", "O,2.00000X1.00000*%\n", "R,1.00000X2.00000*%\n"},
+		},
+		{
+			name:  "inches",
+			units: UnitsInch,
+			want:  []string{"%AMRotRect*\n21,1,($1)/25.4,($2)/25.4,0,0,$3*\n%\n", "RotRect,2X1X45*%\n", "O,0.078740X0.039370*%\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New("test", WithUnits(tt.units), WithOutputValidation(true))
+			g.TopCopper().Add(
+				Pad(Pt{5, 5}, RectShape, 2, 1, 45),
+				Pad(Pt{10, 5}, RectShape, 2, 1, 135),
+				Pad(Pt{15, 5}, ObroundShape, 2, 1, 0),
+				Pad(Pt{20, 5}, RectShape, 2, 1, 90),
+			)
+			files := map[string]*memFile{}
+			err := g.Write(func(filename string) (io.WriteCloser, error) {
+				f := &memFile{}
+				files[filename] = f
+				return f, nil
+			})
+			if err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			out := files["test.gtl"].String()
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("test.gtl = %q, want %q", out, want)
+				}
+			}
+			if n := strings.Count(out, "%AMRotRect*"); n != 1 {
+				t.Errorf("test.gtl defines RotRect %v times, want once", n)
+			}
+
+			layer, err := Parse(strings.NewReader(out))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if len(layer.Primitives) != 4 {
+				t.Fatalf("Parse = %v primitives, want 4", len(layer.Primitives))
+			}
+			if want := g.firstLayerOfType(TopCopperLayer).MBB(); !mbbNear(layer.MBB(), want, 1e-3) {
+				t.Errorf("Parse MBB = %v, want %v", layer.MBB(), want)
+			}
+		})
+	}
+}
+
+func TestPadT_JSON(t *testing.T) {
+	want := New("test")
+	want.TopCopper().Add(RoundRectPad(Pt{1, 2}, 2, 1, 0.2, 30))
+	data, err := json.Marshal(want)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	got := &Gerber{}
+	if err := json.Unmarshal(data, got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	compareOutputs(t, got, want)
+}
@@ -0,0 +1,145 @@
+package gerber
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// PadMapEntry describes one pad of a design, for bed-of-nails fixture
+// design and pin-mapping code generation. All dimensions are in
+// millimeters.
+type PadMapEntry struct {
+	Part   string `json:"part,omitempty"`
+	Number string `json:"number,omitempty"`
+	Net    string `json:"net,omitempty"`
+	// Shape is "round", "oblong", or "rect", and Width and Height the
+	// size of the pad before rotation.
+	Shape  string  `json:"shape"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+	// X and Y are the pad's center, after the design's origin and
+	// export transform are applied (i.e. as written).
+	X        float64 `json:"x"`
+	Y        float64 `json:"y"`
+	Rotation float64 `json:"rotation,omitempty"`
+	Drill    float64 `json:"drill,omitempty"`
+	// Layer is "top" or "bottom" for surface mount pads, or "through"
+	// for drilled pads.
+	Layer string `json:"layer"`
+}
+
+// PadMap returns an entry for each padstack instance of the design
+// (see PlacePadstack) that has a pad, sorted by part and then by pad
+// number (numerically, where possible).
+func (g *Gerber) PadMap() []*PadMapEntry {
+	var result []*PadMapEntry
+	for _, ref := range g.padstackRefs {
+		ps := ref.Padstack
+		pad, layer := ps.Top, "top"
+		if pad.Width <= 0 {
+			pad, layer = ps.Bottom, "bottom"
+		}
+		if ps.Drill > 0 {
+			layer = "through"
+		}
+		if pad.Width <= 0 {
+			continue
+		}
+		shape := "round"
+		switch {
+		case pad.Shape == RectShape:
+			shape = "rect"
+		case pad.Width != pad.height():
+			shape = "oblong"
+		}
+		center := g.exportPt(ref.Center)
+		result = append(result, &PadMapEntry{
+			Part:     ref.Part,
+			Number:   ref.Number,
+			Net:      ref.Net,
+			Shape:    shape,
+			Width:    pad.Width,
+			Height:   pad.height(),
+			X:        center[0],
+			Y:        center[1],
+			Rotation: ref.Rotation,
+			Drill:    ps.Drill,
+			Layer:    layer,
+		})
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.Part != b.Part {
+			return a.Part < b.Part
+		}
+		return padNumberLess(a.Number, b.Number)
+	})
+	return result
+}
+
+// padNumberLess orders numeric pad numbers numerically, before any
+// other pad numbers, which are ordered alphabetically.
+func padNumberLess(a, b string) bool {
+	na, aErr := strconv.Atoi(a)
+	nb, bErr := strconv.Atoi(b)
+	switch {
+	case aErr == nil && bErr == nil:
+		return na < nb
+	case aErr == nil:
+		return true
+	case bErr == nil:
+		return false
+	}
+	return a < b
+}
+
+// exportPt returns pt as written, after the design's origin and export
+// transform are applied.
+func (g *Gerber) exportPt(pt Pt) Pt {
+	pt = Pt{pt[0] - g.origin[0], pt[1] - g.origin[1]}
+	if g.exportXform != nil {
+		pt = g.exportXform.Apply(pt)
+	}
+	return pt
+}
+
+// NumberPads numbers the design's unnumbered pads "1", "2", ... in the
+// order they were placed, separately for each part, skipping numbers
+// already used within the part.
+func (g *Gerber) NumberPads() {
+	used := map[string]map[string]bool{}
+	for _, ref := range g.padstackRefs {
+		if used[ref.Part] == nil {
+			used[ref.Part] = map[string]bool{}
+		}
+		used[ref.Part][ref.Number] = true
+	}
+	next := map[string]int{}
+	for _, ref := range g.padstackRefs {
+		if ref.Number != "" {
+			continue
+		}
+		for {
+			next[ref.Part]++
+			if n := strconv.Itoa(next[ref.Part]); !used[ref.Part][n] {
+				ref.Number = n
+				used[ref.Part][n] = true
+				break
+			}
+		}
+	}
+}
+
+// WritePadMap writes the design's pad map (see PadMap) as CSV.
+func (g *Gerber) WritePadMap(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"part", "number", "net", "shape", "width", "height", "x", "y", "rotation", "drill", "layer"})
+	for _, e := range g.PadMap() {
+		cw.Write([]string{e.Part, e.Number, e.Net, e.Shape, fmtFloat(e.Width), fmtFloat(e.Height),
+			fmtFloat(e.X), fmtFloat(e.Y), fmtFloat(e.Rotation), fmtFloat(e.Drill), e.Layer})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,90 @@
+package gerber
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestGerber_PadMap(t *testing.T) {
+	smd := &Padstack{Name: "smd", Top: RectPad(1, 0.5)}
+	tht := &Padstack{Name: "tht", Drill: 0.8, Top: RoundPad(1.6), Bottom: RoundPad(1.6)}
+	oblong := &Padstack{Name: "oblong", Bottom: PadShape{Width: 2, Height: 1}}
+
+	w := NewWorkspace()
+	w.AddFootprint(&Footprint{
+		Name: "header",
+		Pads: []PadstackRef{
+			{Padstack: tht, Center: Pt{0, 2.54}, Number: "2", Net: "VCC"},
+			{Padstack: tht, Center: Pt{0, 0}, Number: "1", Net: "GND"},
+		},
+	})
+	g := w.New("test", WithOrigin(Pt{10, 10}))
+	if err := w.PlaceFootprint(g, "header", Pt{20, 20}, 0); err != nil {
+		t.Fatal(err)
+	}
+	r1 := g.PlacePadstack(smd, Pt{15, 15}, 90)
+	r1.Part, r1.Net = "R1", "VCC"
+	r2 := g.PlacePadstack(smd, Pt{16, 15}, 90)
+	r2.Part = "R1"
+	testPad := g.PlacePadstack(oblong, Pt{12, 12}, 0)
+	testPad.Part, testPad.Number = "TP", "A"
+	g.NumberPads()
+
+	var buf bytes.Buffer
+	if err := g.WritePadMap(&buf); err != nil {
+		t.Fatalf("WritePadMap: %v", err)
+	}
+	want := `part,number,net,shape,width,height,x,y,rotation,drill,layer
+R1,1,VCC,rect,1,0.5,5,5,90,0,top
+R1,2,,rect,1,0.5,6,5,90,0,top
+TP,A,,oblong,2,1,2,2,0,0,bottom
+header,1,GND,round,1.6,1.6,10,10,0,0.8,through
+header,2,VCC,round,1.6,1.6,10,12.54,0,0.8,through
+`
+	if got := buf.String(); got != want {
+		t.Errorf("WritePadMap =\n%v\nwant:\n%v", got, want)
+	}
+
+	b, err := json.Marshal(g.PadMap()[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), `{"part":"R1","number":"1","net":"VCC","shape":"rect","width":1,"height":0.5,"x":5,"y":5,"rotation":90,"layer":"top"}`; got != want {
+		t.Errorf("json = %v, want %v", got, want)
+	}
+
+	// Pad numbers and nets survive serialization.
+	b, err = json.Marshal(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ng Gerber
+	if err := json.Unmarshal(b, &ng); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(ng.PadMap()), 5; got != want {
+		t.Fatalf("PadMap after round trip has %v entries, want %v", got, want)
+	}
+	if got := ng.PadMap()[4]; got.Part != "header" || got.Number != "2" || got.Net != "VCC" {
+		t.Errorf("PadMap()[4] after round trip = %+v", got)
+	}
+}
+
+func TestPadNumberLess(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"2", "10", true},
+		{"10", "2", false},
+		{"9", "A1", true},
+		{"A1", "9", false},
+		{"A1", "B1", true},
+	}
+	for _, tt := range tests {
+		if got := padNumberLess(tt.a, tt.b); got != tt.want {
+			t.Errorf("padNumberLess(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package gerber
+
+import (
+	"fmt"
+	"math"
+)
+
+// DefaultMaxAspectRatio is the board thickness to drill diameter ratio
+// that most fabs support for plated holes at standard pricing.
+const DefaultMaxAspectRatio = 10
+
+// PadShape represents the shape of a pad in a padstack, centered on
+// the padstack's position. All dimensions are in millimeters.
+type PadShape struct {
+	// Shape is CircleShape (for round or oblong pads) or RectShape.
+	Shape Shape `json:"shape,omitempty"`
+	// Width is the size of the pad (0 for no pad) and Height its size
+	// in Y (0 for the same as Width).
+	Width  float64 `json:"width,omitempty"`
+	Height float64 `json:"height,omitempty"`
+}
+
+// RoundPad returns a round pad of diameter d.
+func RoundPad(d float64) PadShape {
+	return PadShape{Shape: CircleShape, Width: d}
+}
+
+// RectPad returns a rectangular pad.
+func RectPad(width, height float64) PadShape {
+	return PadShape{Shape: RectShape, Width: width, Height: height}
+}
+
+func (s PadShape) height() float64 {
+	if s.Height == 0 {
+		return s.Width
+	}
+	return s.Height
+}
+
+// primitive returns the pad at center rotated by rotation degrees,
+// or nil if there is no pad.
+func (s PadShape) primitive(center Pt, rotation float64) Primitive {
+	w, h := s.Width, s.height()
+	if w <= 0 || h <= 0 {
+		return nil
+	}
+	if s.Shape == RectShape {
+		hw, hh := 0.5*w, 0.5*h
+		var pts []Pt
+		for _, pt := range []Pt{{-hw, -hh}, {hw, -hh}, {hw, hh}, {-hw, hh}} {
+			pts = append(pts, RotatePt(pt, Pt{}, rotation))
+		}
+		return Polygon(center, true, pts, 0)
+	}
+	if w == h {
+		return Circle(center, w)
+	}
+	// Oblong pads are lines with round caps along the long axis.
+	thickness, half, angle := h, 0.5*(w-h), rotation
+	if h > w {
+		thickness, half, angle = w, 0.5*(h-w), rotation+90
+	}
+	p1, p2 := PolarFrom(center, -half, angle), PolarFrom(center, half, angle)
+	return Line(p1[0], p1[1], p2[0], p2[1], CircleShape, thickness)
+}
+
+// Padstack defines the drill, per-layer pads, and solder mask of a
+// via or through-hole (or, with no drill, surface mount) pad. Placing
+// padstacks with PlacePadstack (rather than adding pads to each layer
+// by hand) keeps every instance consistent: changes to a Padstack
+// apply to all of its instances when the design is next written.
+// All dimensions are in millimeters.
+type Padstack struct {
+	// Name identifies the padstack. Its pads and holes are tagged
+	// with "padstack:" followed by the name.
+	Name string `json:"name,omitempty"`
+	// Drill is the diameter of the hole (0 for a surface mount pad),
+	// which is tagged with NonPlatedTag if NonPlated is set.
+	Drill     float64 `json:"drill,omitempty"`
+	NonPlated bool    `json:"nonPlated,omitempty"`
+	// Top, Inner, and Bottom are the pads on the top, inner, and
+	// bottom copper layers.
+	Top    PadShape `json:"top"`
+	Inner  PadShape `json:"inner"`
+	Bottom PadShape `json:"bottom"`
+	// MaskExpansion grows the solder mask openings of the top and
+	// bottom pads.
+	MaskExpansion float64 `json:"maskExpansion,omitempty"`
+	// TentTop and TentBottom cover the top and bottom pads with
+	// solder mask (i.e. no mask opening is made).
+	TentTop    bool `json:"tentTop,omitempty"`
+	TentBottom bool `json:"tentBottom,omitempty"`
+	// PasteOverprint is the amount by which the top paste opening of
+	// a pin-in-paste instance (see PadstackRef.PinInPaste) extends
+	// beyond the top pad, so that enough paste is printed to fill the
+	// hole around the pin when it is reflowed.
+	PasteOverprint float64 `json:"pasteOverprint,omitempty"`
+	// Paste adds paste openings, grown by PasteOverprint (which may be
+	// negative), to the pads of a surface mount padstack.
+	Paste bool `json:"paste,omitempty"`
+}
+
+// Padstack returns a padstack for the via with identical round pads
+// on every copper layer, tented on both sides.
+func (v Via) Padstack() *Padstack {
+	pad := RoundPad(v.Pad)
+	return &Padstack{Drill: v.Drill, Top: pad, Inner: pad, Bottom: pad, TentTop: true, TentBottom: true}
+}
+
+// AspectRatio returns the ratio of the board thickness to the drill
+// diameter of the padstack, or 0 if it has no drill.
+func (ps *Padstack) AspectRatio(boardThickness float64) float64 {
+	if ps.Drill <= 0 {
+		return 0
+	}
+	return boardThickness / ps.Drill
+}
+
+// Check verifies that the padstack can be fabricated in a board of
+// the given thickness: its aspect ratio must not exceed maxAspect
+// (DefaultMaxAspectRatio if 0) and each of its pads must be larger
+// than the drill.
+func (ps *Padstack) Check(boardThickness, maxAspect float64) error {
+	if maxAspect <= 0 {
+		maxAspect = DefaultMaxAspectRatio
+	}
+	if ar := ps.AspectRatio(boardThickness); ar > maxAspect {
+		return fmt.Errorf("padstack %q: aspect ratio %v exceeds %v", ps.Name, fmtFloat(ar), fmtFloat(maxAspect))
+	}
+	if ps.Drill <= 0 {
+		return nil
+	}
+	for _, side := range []struct {
+		name string
+		pad  PadShape
+	}{{"top", ps.Top}, {"inner", ps.Inner}, {"bottom", ps.Bottom}} {
+		if side.pad.Width > 0 && math.Min(side.pad.Width, side.pad.height()) <= ps.Drill {
+			return fmt.Errorf("padstack %q: %v pad is not larger than the %vmm drill", ps.Name, side.name, fmtFloat(ps.Drill))
+		}
+	}
+	return nil
+}
+
+// PadstackRef represents an instance of a padstack in a design.
+type PadstackRef struct {
+	Padstack *Padstack
+	Center   Pt
+	// Rotation is the rotation of the pads in degrees.
+	Rotation float64
+	// Part identifies the part (e.g. a reference designator or a
+	// footprint name) the pad belongs to, Number is the pad's number
+	// within its part (e.g. "1" or "A3"), and Net is the name of the
+	// net it connects to. They are optional and only used by PadMap
+	// and (via NetTag) CheckShorts.
+	Part   string
+	Number string
+	Net    string
+	// PinInPaste adds a top paste opening (see
+	// Padstack.PasteOverprint) to a through-hole instance, so that its
+	// part can be soldered by reflow (pin-in-paste) assembly.
+	PinInPaste bool
+}
+
+// PlacePadstack adds an instance of the padstack to the design.
+// Its pads, hole, and mask openings are generated (see DeriveOpenings)
+// when the design is written.
+func (g *Gerber) PlacePadstack(ps *Padstack, center Pt, rotation float64) *PadstackRef {
+	ref := &PadstackRef{Padstack: ps, Center: center, Rotation: rotation}
+	g.padstackRefs = append(g.padstackRefs, ref)
+	return ref
+}
+
+// PadstackRefs returns the padstack instances of the design.
+func (g *Gerber) PadstackRefs() []*PadstackRef {
+	return g.padstackRefs
+}
+
+// Padstacks returns the distinct padstacks used in the design,
+// in the order they were first placed.
+func (g *Gerber) Padstacks() []*Padstack {
+	var result []*Padstack
+	seen := map[*Padstack]bool{}
+	for _, ref := range g.padstackRefs {
+		if !seen[ref.Padstack] {
+			seen[ref.Padstack] = true
+			result = append(result, ref.Padstack)
+		}
+	}
+	return result
+}
+
+// ReplacePadstack replaces every instance of old with ps and returns
+// the number of instances replaced.
+func (g *Gerber) ReplacePadstack(old, ps *Padstack) int {
+	var n int
+	for _, ref := range g.padstackRefs {
+		if ref.Padstack == old {
+			ref.Padstack = ps
+			n++
+		}
+	}
+	return n
+}
+
+// placePadstacks adds the (derived) primitives of the design's
+// padstack instances, declaring the mask openings of untented pads.
+func (g *Gerber) placePadstacks() {
+	add := func(layer *Layer, tags []string, p Primitive) {
+		layer.AddDerived(p)
+		layer.Tag(p, tags...)
+	}
+	for _, ref := range g.padstackRefs {
+		ps := ref.Padstack
+		var tags []string
+		if ps.Name != "" {
+			tags = []string{"padstack:" + ps.Name}
+		}
+		if ref.Part != "" {
+			tags = append(tags, PartTag(ref.Part))
+		}
+		if ps.Drill > 0 {
+			holeTags := tags
+			if ps.NonPlated {
+				holeTags = append(holeTags[:len(holeTags):len(holeTags)], NonPlatedTag)
+			}
+			add(g.firstLayerOfType(DrillLayer), holeTags, Circle(ref.Center, ps.Drill))
+		}
+		if ref.Net != "" {
+			tags = append(tags, NetTag(ref.Net))
+		}
+		smdPaste := ps.Paste && ps.Drill <= 0
+		if p := ps.Top.primitive(ref.Center, ref.Rotation); p != nil {
+			layer := g.firstLayerOfType(TopCopperLayer)
+			add(layer, tags, p)
+			var o Openings
+			if !ps.TentTop {
+				o.Mask, o.MaskExpansion = true, ps.MaskExpansion
+			}
+			if (ref.PinInPaste && ps.Drill > 0) || smdPaste {
+				o.Paste, o.PasteExpansion = true, ps.PasteOverprint
+			}
+			if o != (Openings{}) {
+				layer.SetOpenings(p, o)
+			}
+		}
+		if p := ps.Bottom.primitive(ref.Center, ref.Rotation); p != nil {
+			layer := g.firstLayerOfType(BottomCopperLayer)
+			add(layer, tags, p)
+			var o Openings
+			if !ps.TentBottom {
+				o.Mask, o.MaskExpansion = true, ps.MaskExpansion
+			}
+			if smdPaste {
+				o.Paste, o.PasteExpansion = true, ps.PasteOverprint
+			}
+			if o != (Openings{}) {
+				layer.SetOpenings(p, o)
+			}
+		}
+		if ps.Drill > 0 {
+			for _, layer := range g.layersOfType(InnerCopperLayer) {
+				if p := ps.Inner.primitive(ref.Center, ref.Rotation); p != nil {
+					add(layer, tags, p)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package gerber
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestGerber_PlacePadstack(t *testing.T) {
+	g := New("test")
+	inner := g.LayerN(2)
+	via := Via{Drill: 0.3, Pad: 0.6}.Padstack()
+	th := &Padstack{Name: "th", Drill: 1, Top: RectPad(2, 2), Bottom: RoundPad(2), MaskExpansion: 0.1}
+	g.PlacePadstack(via, Pt{1, 1}, 0)
+	g.PlacePadstack(via, Pt{2, 1}, 0)
+	g.PlacePadstack(th, Pt{5, 5}, 0)
+	if err := g.DeriveOpenings(); err != nil {
+		t.Fatal(err)
+	}
+
+	count := func(typ LayerType) int {
+		var n int
+		for _, layer := range g.layersOfType(typ) {
+			n += len(layer.Primitives)
+		}
+		return n
+	}
+	tests := []struct {
+		typ  LayerType
+		want int
+	}{
+		{DrillLayer, 3},
+		{TopCopperLayer, 3},
+		{BottomCopperLayer, 3},
+		{InnerCopperLayer, 2},
+		{TopSolderMaskLayer, 1},
+		{BottomSolderMaskLayer, 1},
+	}
+	for _, tt := range tests {
+		if got := count(tt.typ); got != tt.want {
+			t.Errorf("%v primitives = %v, want %v", tt.typ, got, tt.want)
+		}
+	}
+	if got := len(inner.Select("padstack:th")); got != 0 {
+		t.Errorf("inner th pads = %v, want 0", got)
+	}
+	mask := g.layersOfType(TopSolderMaskLayer)[0].MBB()
+	if got, want := mask.Max[0]-mask.Min[0], 2.2; math.Abs(got-want) > 1e-9 {
+		t.Errorf("mask opening width = %v, want %v", got, want)
+	}
+
+	// Edits to a padstack apply to all instances.
+	via.Drill = 0.25
+	if err := g.DeriveOpenings(); err != nil {
+		t.Fatal(err)
+	}
+	if got := g.Estimate(); got.Holes != 3 || math.Abs(got.MinDrill-0.25) > 1e-9 {
+		t.Errorf("after edit: %v holes, min drill %v, want 3 holes, min drill 0.25", got.Holes, got.MinDrill)
+	}
+	if got := g.ReplacePadstack(via, th); got != 2 {
+		t.Errorf("ReplacePadstack = %v, want 2", got)
+	}
+	if got := len(g.Padstacks()); got != 1 {
+		t.Errorf("Padstacks = %v, want 1", got)
+	}
+}
+
+func TestFootprint_PinInPaste(t *testing.T) {
+	th := &Padstack{Drill: 1, Top: RoundPad(1.8), Bottom: RoundPad(1.8), PasteOverprint: 0.3}
+	smd := &Padstack{Top: RectPad(1, 1)}
+	tests := []struct {
+		name       string
+		pinInPaste bool
+		wantPaste  int
+	}{
+		{name: "wave soldered"},
+		{name: "pin in paste", pinInPaste: true, wantPaste: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &Footprint{
+				Name:       "J1",
+				Pads:       []PadstackRef{{Padstack: th}, {Padstack: smd, Center: Pt{5, 0}}},
+				PinInPaste: tt.pinInPaste,
+			}
+			g := New("test")
+			if err := f.Place(g, Pt{10, 10}, 0); err != nil {
+				t.Fatal(err)
+			}
+			if err := g.DeriveOpenings(); err != nil {
+				t.Fatal(err)
+			}
+			var paste []Primitive
+			for _, layer := range g.layersOfType(TopSolderPasteLayer) {
+				paste = append(paste, layer.Primitives...)
+			}
+			if len(paste) != tt.wantPaste {
+				t.Fatalf("paste primitives = %v, want %v", len(paste), tt.wantPaste)
+			}
+			if tt.wantPaste > 0 {
+				want := MBB{Min: Pt{8.8, 8.8}, Max: Pt{11.2, 11.2}}
+				if got := paste[0].MBB(); !mbbNear(got, want, 1e-9) {
+					t.Errorf("paste MBB = %v, want %v", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestGerber_PadstackJSON(t *testing.T) {
+	g := New("test")
+	ps := &Padstack{Name: "p", Drill: 0.8, Top: RoundPad(1.6), Bottom: RoundPad(1.6)}
+	g.PlacePadstack(ps, Pt{1, 1}, 0)
+	g.PlacePadstack(ps, Pt{3, 1}, 90).PinInPaste = true
+	data, err := json.Marshal(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Gerber
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	refs := got.PadstackRefs()
+	if len(refs) != 2 || refs[0].Padstack != refs[1].Padstack || refs[1].Rotation != 90 || !refs[1].PinInPaste || *refs[0].Padstack != *ps {
+		t.Errorf("PadstackRefs = %+v, want 2 refs sharing %+v", refs, *ps)
+	}
+}
+
+func TestPadstack_Check(t *testing.T) {
+	tests := []struct {
+		name    string
+		ps      *Padstack
+		wantErr bool
+	}{
+		{name: "ok", ps: &Padstack{Drill: 0.3, Top: RoundPad(0.6)}},
+		{name: "aspect ratio", ps: &Padstack{Drill: 0.15, Top: RoundPad(0.6)}, wantErr: true},
+		{name: "pad too small", ps: &Padstack{Drill: 0.3, Top: RectPad(1, 0.3)}, wantErr: true},
+		{name: "smd", ps: &Padstack{Top: RectPad(1, 0.3)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.ps.Check(1.6, 0); (err != nil) != tt.wantErr {
+				t.Errorf("Check = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPadShape_primitive(t *testing.T) {
+	tests := []struct {
+		name     string
+		s        PadShape
+		rotation float64
+		want     MBB
+	}{
+		{name: "round", s: RoundPad(1), want: MBB{Min: Pt{-0.5, -0.5}, Max: Pt{0.5, 0.5}}},
+		{name: "rect", s: RectPad(2, 1), want: MBB{Min: Pt{-1, -0.5}, Max: Pt{1, 0.5}}},
+		{name: "rotated rect", s: RectPad(2, 1), rotation: 90, want: MBB{Min: Pt{-0.5, -1}, Max: Pt{0.5, 1}}},
+		{name: "oblong", s: PadShape{Shape: CircleShape, Width: 1, Height: 3}, want: MBB{Min: Pt{-0.5, -1.5}, Max: Pt{0.5, 1.5}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.s.primitive(Pt{}, tt.rotation).MBB(); !mbbNear(got, tt.want, 1e-9) {
+				t.Errorf("MBB = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if got := (PadShape{}).primitive(Pt{}, 0); got != nil {
+		t.Errorf("empty pad = %v, want nil", got)
+	}
+}
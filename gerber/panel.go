@@ -0,0 +1,422 @@
+package gerber
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Separation is the method by which the boards of a panel are separated
+// after fabrication (see Panelizer).
+type Separation int
+
+const (
+	// MouseBites separates the boards by routed gaps, bridged by tabs
+	// perforated with rows of small non-plated holes.
+	MouseBites Separation = iota
+	// VScore separates abutting (rectangular) boards by V-grooves cut
+	// along straight lines across the whole panel.
+	VScore
+)
+
+// VScoreTag is the tag of the V-score lines that Panelize adds to the
+// outline layer of a panel.
+const VScoreTag = "v-score"
+
+// Panelizer represents the options used by Panelize.
+// All dimensions are in millimeters.
+type Panelizer struct {
+	// NX and NY are the number of columns and rows of boards.
+	NX, NY int
+	// Separation is the method used to separate the boards.
+	Separation Separation
+	// Spacing is the width of the routed gaps between adjacent boards
+	// (and between the boards and the rails) with MouseBites. It must
+	// be 0 with VScore, whose boards abut.
+	Spacing float64
+	// RailWidth is the width of the rails added along the bottom and
+	// top of the panel (for handling during assembly), or 0 for none.
+	RailWidth float64
+	// Fiducial is the diameter of the fiducial marks added to the rails
+	// (two on the bottom rail and one on the top, so that the panel's
+	// orientation is unambiguous), or 0 for none. Their solder mask
+	// openings are twice as large.
+	Fiducial float64
+	// ToolingHole is the diameter of the non-plated tooling holes added
+	// at the ends of the rails, or 0 for none.
+	ToolingHole float64
+	// Tabs is the number of tabs bridging each gap between a pair of
+	// boards (or between a board and a rail) with MouseBites, each
+	// TabWidth wide and perforated along both of its edges by holes of
+	// diameter BiteHole, BitePitch apart.
+	Tabs                          int
+	TabWidth, BiteHole, BitePitch float64
+	// OutlineWidth is the width of the lines added to the outline layer.
+	OutlineWidth float64
+}
+
+// validate checks the options.
+func (p Panelizer) validate() error {
+	if p.NX < 1 || p.NY < 1 {
+		return fmt.Errorf("invalid panel of %vx%v boards", p.NX, p.NY)
+	}
+	if p.RailWidth < 0 || p.Fiducial < 0 || p.ToolingHole < 0 || p.OutlineWidth <= 0 {
+		return errors.New("invalid panel dimensions")
+	}
+	if p.RailWidth == 0 && (p.Fiducial > 0 || p.ToolingHole > 0) {
+		return errors.New("fiducials and tooling holes require rails")
+	}
+	if p.RailWidth > 0 && math.Max(p.Fiducial, p.ToolingHole) >= p.RailWidth {
+		return errors.New("fiducials and tooling holes must fit within the rails")
+	}
+	switch p.Separation {
+	case MouseBites:
+		if p.Spacing <= 0 {
+			return errors.New("mouse bites require a positive spacing")
+		}
+		if p.Tabs < 1 || p.TabWidth <= 0 || p.BiteHole <= 0 || p.BitePitch <= p.BiteHole || p.BiteHole > p.TabWidth {
+			return errors.New("invalid mouse bite tabs")
+		}
+	case VScore:
+		if p.Spacing != 0 {
+			return errors.New("V-scored boards cannot be spaced apart")
+		}
+	default:
+		return fmt.Errorf("unknown separation %v", p.Separation)
+	}
+	return nil
+}
+
+// Panelize returns a new design (with the given filename prefix and the
+// output settings of g) holding an NX by NY panel of copies of the
+// complete design g, whose board is bounded by its outline layer. Each
+// layer of the panel repeats the corresponding layer of g (as a step
+// and repeat block, see StepRepeatT), with the first board in its
+// original position; the panel's origin is its lower left corner.
+//
+// With MouseBites, the boards (and rails) are outlined individually and
+// joined by tabs, which break their outlines and add rows of holes
+// (tagged with NonPlatedTag) to the drill layer; tabs should fall on
+// straight (LineT) edges of the outline. With VScore, the outline layer
+// holds the outline of the whole panel and the V-score lines (tagged
+// with VScoreTag) between the boards and the rails. Fiducials (tagged
+// with FiducialTag) are added to the outer copper layers and tooling
+// holes (tagged with NonPlatedTag and ToolingHoleTag) to the drill
+// layer.
+//
+// The export pipeline of g (see DeriveOpenings) is run first, so that
+// the panel includes the primitives it derives.
+func (p Panelizer) Panelize(g *Gerber, filenamePrefix string) (*Gerber, error) {
+	return p.PanelizeContext(context.Background(), g, filenamePrefix)
+}
+
+// PanelizeContext is like Panelize but stops early (returning
+// ctx.Err()) if ctx is canceled while the panel is being built, e.g.
+// while the tabs of a large panel cut its outlines.
+func (p Panelizer) PanelizeContext(ctx context.Context, g *Gerber, filenamePrefix string) (*Gerber, error) {
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	if err := g.DeriveOpenings(); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var outline *Layer
+	if layers := g.layersOfType(OutlineLayer); len(layers) > 0 && !layers[0].IsEmpty() {
+		outline = layers[0]
+	} else {
+		return nil, errors.New("panelizing requires a board outline")
+	}
+	board := outlineExtents(outline.Primitives)
+	size := Pt{board.Max[0] - board.Min[0], board.Max[1] - board.Min[1]}
+	pitch := Pt{size[0] + p.Spacing, size[1] + p.Spacing}
+
+	panel := g.panelSettings(filenamePrefix)
+	panel.array = true
+	for _, layer := range g.Layers {
+		pl := panel.makeLayer(layer.Type, layer.N)
+		pl.Grid, pl.Format = layer.Grid, layer.Format
+		if layer != outline && !layer.IsEmpty() {
+			pl.StepRepeat(p.NX, p.NY, pitch[0], pitch[1], layer.Primitives...)
+		}
+	}
+
+	// The boards span cells, and the rails (if any) lie below and above
+	// them, separated by the spacing.
+	cells := MBB{Min: board.Min, Max: Pt{board.Min[0] + float64(p.NX-1)*pitch[0] + size[0], board.Min[1] + float64(p.NY-1)*pitch[1] + size[1]}}
+	var rails []MBB
+	if p.RailWidth > 0 {
+		bottom := cells.Min[1] - p.Spacing
+		top := cells.Max[1] + p.Spacing
+		rails = []MBB{
+			{Min: Pt{cells.Min[0], bottom - p.RailWidth}, Max: Pt{cells.Max[0], bottom}},
+			{Min: Pt{cells.Min[0], top}, Max: Pt{cells.Max[0], top + p.RailWidth}},
+		}
+	}
+	frame := cells
+	for i := range rails {
+		frame.Join(&rails[i])
+	}
+
+	pOutline := panel.firstLayerOfType(OutlineLayer)
+	drill := func() *Layer { return panel.firstLayerOfType(DrillLayer) }
+	if p.Separation == VScore {
+		pOutline.Add(OutlinePath(RoundedRect(frame, 0), p.OutlineWidth)...)
+		var scores []Primitive
+		for i := 1; i < p.NX; i++ {
+			x := board.Min[0] + float64(i)*pitch[0]
+			scores = append(scores, Line(x, frame.Min[1], x, frame.Max[1], CircleShape, p.OutlineWidth))
+		}
+		for j := 0; j <= p.NY; j++ {
+			if (j == 0 || j == p.NY) && len(rails) == 0 {
+				continue
+			}
+			y := board.Min[1] + float64(j)*pitch[1]
+			scores = append(scores, Line(frame.Min[0], y, frame.Max[0], y, CircleShape, p.OutlineWidth))
+		}
+		pOutline.AddTagged([]string{VScoreTag}, scores...)
+	} else {
+		var lines []Primitive
+		for j := 0; j < p.NY; j++ {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			for i := 0; i < p.NX; i++ {
+				cell, err := Group(outline.Primitives).Transform(Translate(float64(i)*pitch[0], float64(j)*pitch[1]))
+				if err != nil {
+					return nil, err
+				}
+				lines = append(lines, cell...)
+			}
+		}
+		for _, r := range rails {
+			lines = append(lines, OutlinePath(RoundedRect(r, 0), p.OutlineWidth)...)
+		}
+		// The tabs bridge the gaps between columns, between rows, and
+		// between the outer rows and the rails.
+		var tabs []panelTab
+		for k := 0; k < p.Tabs; k++ {
+			along := (float64(k) + 0.5) / float64(p.Tabs)
+			for j := 0; j < p.NY; j++ {
+				y := board.Min[1] + float64(j)*pitch[1] + along*size[1]
+				for i := 1; i < p.NX; i++ {
+					a := board.Min[0] + float64(i-1)*pitch[0] + size[0]
+					tabs = append(tabs, panelTab{vertical: true, a: a, b: a + p.Spacing, at: y})
+				}
+			}
+			for i := 0; i < p.NX; i++ {
+				x := board.Min[0] + float64(i)*pitch[0] + along*size[0]
+				for j := 1; j < p.NY; j++ {
+					a := board.Min[1] + float64(j-1)*pitch[1] + size[1]
+					tabs = append(tabs, panelTab{a: a, b: a + p.Spacing, at: x})
+				}
+				if len(rails) > 0 {
+					tabs = append(tabs,
+						panelTab{a: rails[0].Max[1], b: cells.Min[1], at: x},
+						panelTab{a: cells.Max[1], b: rails[1].Min[1], at: x})
+				}
+			}
+		}
+		var holes []Primitive
+		for _, tab := range tabs {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			lines = tab.cut(lines, p.TabWidth)
+			lines = append(lines, tab.sides(p.TabWidth, p.OutlineWidth)...)
+			holes = append(holes, tab.bites(p.TabWidth, p.BiteHole, p.BitePitch)...)
+		}
+		pOutline.Add(lines...)
+		drill().AddTagged([]string{NonPlatedTag}, holes...)
+	}
+
+	// Tooling holes are centered half a rail width from the ends of
+	// the rails, and fiducials one and a half rail widths.
+	for k, r := range rails {
+		y := 0.5 * (r.Min[1] + r.Max[1])
+		left, right := r.Min[0]+0.5*p.RailWidth, r.Max[0]-0.5*p.RailWidth
+		if p.ToolingHole > 0 {
+			drill().AddTagged([]string{NonPlatedTag, ToolingHoleTag}, Circle(Pt{left, y}, p.ToolingHole), Circle(Pt{right, y}, p.ToolingHole))
+		}
+		if p.Fiducial == 0 {
+			continue
+		}
+		fiducials := []Pt{{left + p.RailWidth, y}}
+		if k == 0 {
+			fiducials = append(fiducials, Pt{right - p.RailWidth, y})
+		}
+		panel.addFiducials(g, fiducials, p.Fiducial)
+	}
+
+	panel.origin = Pt{frame.Min[0] - 0.5*p.OutlineWidth, frame.Min[1] - 0.5*p.OutlineWidth}
+	return panel, nil
+}
+
+// panelSettings returns a new, empty design with the output settings
+// (but none of the contents) of g.
+func (g *Gerber) panelSettings(filenamePrefix string) *Gerber {
+	panel := New(filenamePrefix)
+	panel.units = g.units
+	panel.format = g.format
+	panel.defaultApertureSize = g.defaultApertureSize
+	panel.x2 = g.x2
+	panel.naming = g.naming
+	panel.numbering = g.numbering
+	panel.apertureTolerance = g.apertureTolerance
+	panel.arcTolerance = g.arcTolerance
+	panel.nativeArcs = g.nativeArcs
+	panel.validateOutput = g.validateOutput
+	panel.autoFormat = g.autoFormat
+	panel.grid = g.grid
+	panel.manifest = g.manifest
+	panel.revision = g.revision
+	panel.stackup = g.stackup
+	panel.parallelWrites = g.parallelWrites
+	panel.outputFormat = g.outputFormat
+	panel.deterministic = g.deterministic
+	panel.drillOrder = g.drillOrder
+	panel.splitDrills = g.splitDrills
+	panel.excellonDrills = g.excellonDrills
+	panel.progress = g.progress
+	panel.gerbvProject = g.gerbvProject
+	return panel
+}
+
+// addFiducials adds fiducial marks of diameter d at the points to the
+// top copper layer of the panel, and to its bottom copper layer if the
+// board has one, with openings in the corresponding solder mask layers.
+func (g *Gerber) addFiducials(board *Gerber, pts []Pt, d float64) {
+	sides := [][2]LayerType{{TopCopperLayer, TopSolderMaskLayer}}
+	if len(board.layersOfType(BottomCopperLayer)) > 0 {
+		sides = append(sides, [2]LayerType{BottomCopperLayer, BottomSolderMaskLayer})
+	}
+	for _, side := range sides {
+		copper := g.firstLayerOfType(side[0])
+		masks := g.layersOfType(side[1])
+		for _, pt := range pts {
+			copper.AddTagged([]string{FiducialTag}, Circle(pt, d))
+			for _, mask := range masks {
+				mask.AddTagged([]string{FiducialTag}, Circle(pt, 2*d))
+			}
+		}
+	}
+}
+
+// outlineExtents returns the extents of the centerlines of the outline
+// primitives (i.e. of the board they outline).
+func outlineExtents(primitives []Primitive) MBB {
+	var mbb MBB
+	for i, p := range primitives {
+		var v MBB
+		switch p := p.(type) {
+		case *LineT:
+			v = MBB{Min: p.P1, Max: p.P1}
+			v.Join(&MBB{Min: p.P2, Max: p.P2})
+		case *ArcT:
+			v = arcExtents(p.Center, p.XScale*p.Radius, p.YScale*p.Radius, p.StartAngle, p.EndAngle)
+		default:
+			v = p.MBB()
+		}
+		if i == 0 {
+			mbb = v
+			continue
+		}
+		mbb.Join(&v)
+	}
+	return mbb
+}
+
+// panelTab represents a tab bridging the gap between the parallel
+// edges at a and b (with a < b) across the gap, centered at at along
+// the edges. The edges are vertical (at X = a and b) if vertical is set
+// and horizontal (at Y = a and b) otherwise.
+type panelTab struct {
+	vertical bool
+	a, b, at float64
+}
+
+// pt returns the point with coordinate u across the gap and v along it.
+func (t panelTab) pt(u, v float64) Pt {
+	if t.vertical {
+		return Pt{u, v}
+	}
+	return Pt{v, u}
+}
+
+// cut returns the outline lines with the parts along the edges of the
+// tab (of the given width) removed.
+func (t panelTab) cut(lines []Primitive, width float64) []Primitive {
+	window := MBB{Min: t.pt(t.a-validationEps, t.at-0.5*width), Max: t.pt(t.b+validationEps, t.at+0.5*width)}
+	var result []Primitive
+	for _, p := range lines {
+		l, ok := p.(*LineT)
+		if !ok {
+			result = append(result, p)
+			continue
+		}
+		for _, piece := range cutLine(l, window) {
+			result = append(result, piece)
+		}
+	}
+	return result
+}
+
+// sides returns the outline lines across the gap at the sides of the
+// tab (of the given width).
+func (t panelTab) sides(width, lineWidth float64) []Primitive {
+	var result []Primitive
+	for _, v := range []float64{t.at - 0.5*width, t.at + 0.5*width} {
+		p1, p2 := t.pt(t.a, v), t.pt(t.b, v)
+		result = append(result, Line(p1[0], p1[1], p2[0], p2[1], CircleShape, lineWidth))
+	}
+	return result
+}
+
+// bites returns the holes, pitch apart, centered along both edges of the
+// tab (of the given width).
+func (t panelTab) bites(width, hole, pitch float64) []Primitive {
+	var result []Primitive
+	for _, u := range []float64{t.a, t.b} {
+		result = append(result, MouseBiteHoles(t.pt(u, t.at-0.5*width), t.pt(u, t.at+0.5*width), hole, pitch)...)
+	}
+	return result
+}
+
+// cutLine returns the parts of the line outside the window, omitting
+// any that are too short to matter.
+func cutLine(l *LineT, window MBB) []*LineT {
+	// Clip the line's parameter range [0,1] to the window (see the
+	// Liang-Barsky algorithm).
+	d := Pt{l.P2[0] - l.P1[0], l.P2[1] - l.P1[1]}
+	u0, u1 := 0.0, 1.0
+	for k := 0; k < 2; k++ {
+		if math.Abs(d[k]) < validationEps {
+			if l.P1[k] < window.Min[k] || l.P1[k] > window.Max[k] {
+				return []*LineT{l}
+			}
+			continue
+		}
+		ta, tb := (window.Min[k]-l.P1[k])/d[k], (window.Max[k]-l.P1[k])/d[k]
+		if ta > tb {
+			ta, tb = tb, ta
+		}
+		u0, u1 = math.Max(u0, ta), math.Min(u1, tb)
+	}
+	if u0 >= u1 {
+		return []*LineT{l}
+	}
+	at := func(u float64) Pt { return Pt{l.P1[0] + u*d[0], l.P1[1] + u*d[1]} }
+	length := math.Hypot(d[0], d[1])
+	var result []*LineT
+	for _, r := range [][2]float64{{0, u0}, {u1, 1}} {
+		if (r[1]-r[0])*length < validationEps {
+			continue
+		}
+		p1, p2 := at(r[0]), at(r[1])
+		result = append(result, Line(p1[0], p1[1], p2[0], p2[1], l.Shape, l.Thickness))
+	}
+	return result
+}
@@ -0,0 +1,155 @@
+package gerber
+
+import (
+	"io"
+	"math"
+	"strings"
+	"testing"
+)
+
+// panelBoard returns a 20x10mm board with a pad and a hole.
+func panelBoard() *Gerber {
+	g := New("board", WithOutputValidation(true))
+	g.TopCopper().Add(Circle(Pt{5, 5}, 2))
+	g.BottomCopper().Add(Circle(Pt{5, 5}, 2))
+	g.TopSolderMask().Add(Circle(Pt{5, 5}, 2.2))
+	g.Drill().Add(Circle(Pt{5, 5}, 1))
+	g.Outline().Add(OutlinePath(RoundedRect(MBB{Max: Pt{20, 10}}, 1), 0.1)...)
+	return g
+}
+
+// endpointKey returns a map key for a line endpoint.
+func endpointKey(pt Pt) [2]int64 {
+	return [2]int64{int64(math.Round(pt[0] * 1e4)), int64(math.Round(pt[1] * 1e4))}
+}
+
+func TestPanelizer_MouseBites(t *testing.T) {
+	p := Panelizer{
+		NX: 2, NY: 2, Spacing: 2, RailWidth: 5, Fiducial: 1, ToolingHole: 2,
+		Tabs: 1, TabWidth: 3, BiteHole: 0.5, BitePitch: 0.8, OutlineWidth: 0.1,
+	}
+	panel, err := p.Panelize(panelBoard(), "panel")
+	if err != nil {
+		t.Fatalf("Panelize: %v", err)
+	}
+
+	top := panel.firstLayerOfType(TopCopperLayer)
+	if top.Filename != "panel.gtl" || len(top.Primitives) != 4 {
+		t.Fatalf("top copper %v has %v primitives, want panel.gtl with 4", top.Filename, len(top.Primitives))
+	}
+	if sr, ok := top.Primitives[0].(*StepRepeatT); !ok || sr.NX != 2 || sr.NY != 2 || sr.ColumnStep != (Pt{22, 0}) || sr.RowStep != (Pt{0, 12}) {
+		t.Errorf("top copper cells = %v, want a 2x2 array 22mm by 12mm apart", top.Primitives[0])
+	}
+	if got := len(top.Select(FiducialTag)); got != 3 {
+		t.Errorf("top copper has %v fiducials, want 3", got)
+	}
+	if got := len(panel.firstLayerOfType(BottomCopperLayer).Select(FiducialTag)); got != 3 {
+		t.Errorf("bottom copper has %v fiducials, want 3", got)
+	}
+	if got := len(panel.firstLayerOfType(TopSolderMaskLayer).Select(FiducialTag)); got != 3 {
+		t.Errorf("top solder mask has %v fiducial openings, want 3", got)
+	}
+
+	// 8 tabs, each with 2 rows of 4 holes, and 4 tooling holes.
+	if got := len(panel.firstLayerOfType(DrillLayer).Select(NonPlatedTag)); got != 68 {
+		t.Errorf("drill layer has %v non-plated holes, want 68", got)
+	}
+
+	// The outline's straight lines must form closed contours, so every
+	// endpoint must be shared by an even number of them.
+	outline := panel.firstLayerOfType(OutlineLayer)
+	ends := map[[2]int64]int{}
+	for _, p := range outline.Primitives {
+		switch p := p.(type) {
+		case *LineT:
+			ends[endpointKey(p.P1)]++
+			ends[endpointKey(p.P2)]++
+		case *ArcT:
+			for _, angle := range []float64{p.StartAngle, p.EndAngle} {
+				ends[endpointKey(Pt{p.Center[0] + p.Radius*math.Cos(angle), p.Center[1] + p.Radius*math.Sin(angle)})]++
+			}
+		}
+	}
+	for pt, n := range ends {
+		if n%2 != 0 {
+			t.Errorf("outline endpoint %v is shared by %v primitives", pt, n)
+		}
+	}
+	if want := (MBB{Min: Pt{-0.05, -7.05}, Max: Pt{42.05, 29.05}}); !mbbNear(outline.MBB(), want, 1e-6) {
+		t.Errorf("outline MBB = %v, want %v", outline.MBB(), want)
+	}
+
+	files := map[string]*memFile{}
+	err = panel.Write(func(filename string) (io.WriteCloser, error) {
+		f := &memFile{}
+		files[filename] = f
+		return f, nil
+	})
+	if err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if out := files["panel.gtl"].String(); !strings.Contains(out, "%SRX2Y2I22.00000J12.00000*%\n") || !strings.Contains(out, "X5050000Y12050000D02*") {
+		t.Errorf("panel.gtl = %q, want a step and repeat block from the panel's lower left corner", out)
+	}
+}
+
+func TestPanelizer_VScore(t *testing.T) {
+	g := New("board")
+	g.Outline().Add(OutlinePath(RoundedRect(MBB{Max: Pt{20, 10}}, 0), 0.1)...)
+	p := Panelizer{NX: 3, NY: 1, Separation: VScore, RailWidth: 5, OutlineWidth: 0.1}
+	panel, err := p.Panelize(g, "panel")
+	if err != nil {
+		t.Fatalf("Panelize: %v", err)
+	}
+	outline := panel.firstLayerOfType(OutlineLayer)
+	scores := outline.Select(VScoreTag)
+	if len(outline.Primitives) != 8 || len(scores) != 4 {
+		t.Fatalf("outline has %v primitives and %v V-score lines, want 8 and 4", len(outline.Primitives), len(scores))
+	}
+	want := []*LineT{
+		Line(20, -5, 20, 15, CircleShape, 0.1),
+		Line(40, -5, 40, 15, CircleShape, 0.1),
+		Line(0, 0, 60, 0, CircleShape, 0.1),
+		Line(0, 10, 60, 10, CircleShape, 0.1),
+	}
+	for i, s := range scores {
+		if l := s.(*LineT); l.P1 != want[i].P1 || l.P2 != want[i].P2 {
+			t.Errorf("V-score %v = %v, want %v", i, l, want[i])
+		}
+	}
+	if len(panel.layersOfType(DrillLayer)) != 0 {
+		t.Errorf("Panelize added an unused drill layer")
+	}
+}
+
+func TestPanelizer_Errors(t *testing.T) {
+	valid := Panelizer{NX: 2, NY: 2, Spacing: 2, Tabs: 1, TabWidth: 3, BiteHole: 0.5, BitePitch: 0.8, OutlineWidth: 0.1}
+	tests := []struct {
+		name    string
+		g       *Gerber
+		p       func(p *Panelizer)
+		wantErr string
+	}{
+		{name: "no outline", g: New("board"), wantErr: "requires a board outline"},
+		{name: "no boards", p: func(p *Panelizer) { p.NX = 0 }, wantErr: "invalid panel of 0x2 boards"},
+		{name: "no spacing", p: func(p *Panelizer) { p.Spacing = 0 }, wantErr: "positive spacing"},
+		{name: "overlapping bites", p: func(p *Panelizer) { p.BitePitch = 0.4 }, wantErr: "invalid mouse bite tabs"},
+		{name: "spaced V-score", p: func(p *Panelizer) { p.Separation = VScore }, wantErr: "cannot be spaced apart"},
+		{name: "no rails", p: func(p *Panelizer) { p.Fiducial = 1 }, wantErr: "require rails"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := valid
+			if tt.p != nil {
+				tt.p(&p)
+			}
+			g := tt.g
+			if g == nil {
+				g = panelBoard()
+			}
+			if _, err := p.Panelize(g, "panel"); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Panelize = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
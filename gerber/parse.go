@@ -0,0 +1,615 @@
+package gerber
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// parsedAperture represents a standard aperture of a parsed file,
+// with its size in mm, or a macro aperture with its parameters.
+type parsedAperture struct {
+	shape  byte // 'C', 'R', or 'O'
+	w, h   float64
+	macro  *ApertureMacro
+	params []float64
+}
+
+// parser holds the graphics state while parsing a Gerber file.
+type parser struct {
+	units     Units
+	format    *CoordinateFormat
+	apertures map[int]parsedAperture
+	current   *parsedAperture
+	// macros holds the aperture macros defined so far, by name.
+	macros map[string]*ApertureMacro
+	// interp is 1 (linear), 2 (clockwise), or 3 (counterclockwise).
+	interp     int
+	multiQuad  bool
+	clear      bool
+	pos        Pt
+	d          int
+	inRegion   bool
+	contour    []Pt
+	primitives []Primitive
+	// attrs is the current object attribute dictionary (see
+	// Attributes), and primAttrs the attributes of each primitive.
+	attrs     Attributes
+	primAttrs map[Primitive]Attributes
+	// sr is the open step and repeat block, if any.
+	sr *parsedStepRepeat
+	// ctx is checked every ctxCheckInterval commands.
+	ctx context.Context
+}
+
+// parsedStepRepeat is a step and repeat block of a parsed file, with
+// its steps in mm and the index of its first primitive.
+type parsedStepRepeat struct {
+	nx, ny int
+	dx, dy float64
+	start  int
+}
+
+// Parse reads a Gerber (RS-274X) file, such as one written by this
+// package or by other CAM tools, into a new layer that does not belong
+// to a design (see ParseDesign to read a set of files into a design).
+// Flashes and draws of standard circle, rectangle, and obround
+// apertures become circles and lines, circular interpolation (in
+// multi-quadrant mode) becomes arcs, regions become polygons, and
+// clear (LPC) objects are wrapped in ClearT. Coordinates are converted
+// to millimeters. Step and repeat blocks are expanded into copies of
+// their primitives, and a file whose blocks would expand to more than a
+// few million primitives is an error. Object attributes (%TO...*%)
+// become the attributes of the primitives (see Attributes). Flashes of
+// aperture macros become FlashT primitives. Block apertures are not
+// supported. Excellon drill files (those whose header starts with M48)
+// are read with ParseExcellon.
+func Parse(r io.Reader) (*Layer, error) {
+	return ParseContext(context.Background(), r)
+}
+
+// ParseContext is like Parse but stops early (returning ctx.Err()) if
+// ctx is canceled while the file's commands are being read.
+func ParseContext(ctx context.Context, r io.Reader) (*Layer, error) {
+	layer, _, err := parse(ctx, r)
+	return layer, err
+}
+
+func parse(ctx context.Context, r io.Reader) (*Layer, *parser, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	if isExcellon(data) {
+		layer, err := ParseExcellon(bytes.NewReader(data))
+		return layer, &parser{}, err
+	}
+	p := &parser{apertures: map[int]parsedAperture{}, macros: map[string]*ApertureMacro{}, interp: 1, d: 2, ctx: ctx}
+	if err := p.run(data); err != nil {
+		return nil, nil, err
+	}
+	if err := p.endStepRepeat(); err != nil {
+		return nil, nil, err
+	}
+	layer := &Layer{apertureMap: map[string]int{"default": -1}}
+	layer.Add(p.primitives...)
+	layer.attrs = p.primAttrs
+	return layer, p, nil
+}
+
+// run splits data into extended (%...%) and word commands.
+func (p *parser) run(data []byte) error {
+	line := 1
+	for n := 0; len(data) > 0; n++ {
+		if n%ctxCheckInterval == 0 {
+			if err := p.ctx.Err(); err != nil {
+				return err
+			}
+		}
+		if c := data[0]; c == ' ' || c == '\t' || c == '\r' || c == '\n' {
+			if c == '\n' {
+				line++
+			}
+			data = data[1:]
+			continue
+		}
+		if data[0] == '%' {
+			end := bytes.IndexByte(data[1:], '%')
+			if end < 0 {
+				return fmt.Errorf("line %v: unterminated extended command", line)
+			}
+			if block := string(data[1 : end+1]); strings.HasPrefix(strings.TrimSpace(block), "AM") {
+				m, err := ParseApertureMacro(block)
+				if err != nil {
+					return fmt.Errorf("line %v: %v", line, err)
+				}
+				p.macros[m.Name] = m
+				line += bytes.Count(data[:end+2], []byte("\n"))
+				data = data[end+2:]
+				continue
+			}
+			for _, cmd := range strings.Split(string(data[1:end+1]), "*") {
+				if cmd = strings.TrimSpace(cmd); cmd != "" {
+					if err := p.extended(cmd); err != nil {
+						return fmt.Errorf("line %v: %v", line, err)
+					}
+				}
+			}
+			line += bytes.Count(data[:end+2], []byte("\n"))
+			data = data[end+2:]
+			continue
+		}
+		end := bytes.IndexByte(data, '*')
+		if end < 0 {
+			if len(bytes.TrimSpace(data)) > 0 {
+				return fmt.Errorf("line %v: unterminated command", line)
+			}
+			break
+		}
+		cmd := strings.Join(strings.Fields(string(data[:end])), "")
+		if strings.HasPrefix(cmd, "G04") {
+			cmd = "G04" // comments may contain any characters
+		}
+		if cmd != "" {
+			if err := p.word(cmd); err != nil {
+				return fmt.Errorf("line %v: %v", line, err)
+			}
+		}
+		line += bytes.Count(data[:end+1], []byte("\n"))
+		data = data[end+1:]
+	}
+	return nil
+}
+
+func (p *parser) extended(cmd string) error {
+	switch {
+	case strings.HasPrefix(cmd, "FS"):
+		var xi, xd, yi, yd int
+		if _, err := fmt.Sscanf(cmd, "FSLAX%1d%1dY%1d%1d", &xi, &xd, &yi, &yd); err != nil || xi != yi || xd != yd {
+			return fmt.Errorf("unsupported format %q", cmd)
+		}
+		p.format = &CoordinateFormat{Integer: xi, Decimal: xd}
+	case cmd == "MOMM":
+		p.units = UnitsMM
+	case cmd == "MOIN":
+		p.units = UnitsInch
+	case cmd == "LPD":
+		p.clear = false
+	case cmd == "LPC":
+		p.clear = true
+	case strings.HasPrefix(cmd, "ADD"):
+		return p.defineAperture(cmd)
+	case cmd == "SR":
+		return p.endStepRepeat()
+	case strings.HasPrefix(cmd, "SR"):
+		sr := &parsedStepRepeat{}
+		if _, err := fmt.Sscanf(cmd, "SRX%dY%dI%gJ%g", &sr.nx, &sr.ny, &sr.dx, &sr.dy); err != nil || sr.nx < 1 || sr.ny < 1 {
+			return fmt.Errorf("invalid step and repeat %q", cmd)
+		}
+		if sr.nx > maxStepRepeatCells/sr.ny {
+			return fmt.Errorf("step and repeat %q has more than %v cells", cmd, maxStepRepeatCells)
+		}
+		if err := p.endStepRepeat(); err != nil {
+			return err
+		}
+		sr.start = len(p.primitives)
+		sr.dx, sr.dy = p.mm(sr.dx), p.mm(sr.dy)
+		p.sr = sr
+	case strings.HasPrefix(cmd, "AB"):
+		return fmt.Errorf("unsupported command %q", cmd)
+	case strings.HasPrefix(cmd, "TO"):
+		fields := strings.SplitN(cmd[2:], ",", 2)
+		if len(fields) != 2 {
+			return fmt.Errorf("invalid attribute %q", cmd)
+		}
+		p.setAttribute(fields[0], unescapeAttribute(fields[1]), true)
+	case cmd == "TD":
+		p.attrs = nil
+	case strings.HasPrefix(cmd, "TD"):
+		p.setAttribute(cmd[2:], "", false)
+	}
+	// Other attributes (TF, TA) and settings are ignored.
+	return nil
+}
+
+// Limits of the step and repeat blocks that Parse expands, so that a
+// malformed file cannot exhaust memory: the number of cells of a block,
+// and the number of primitives of a layer once its blocks are expanded.
+const (
+	maxStepRepeatCells      = 1 << 20
+	maxStepRepeatPrimitives = 1 << 22
+)
+
+// endStepRepeat ends the open step and repeat block (if any), adding
+// the copies of its primitives.
+func (p *parser) endStepRepeat() error {
+	sr := p.sr
+	p.sr = nil
+	if sr == nil {
+		return nil
+	}
+	cell := p.primitives[sr.start:]
+	if copies := sr.nx*sr.ny - 1; len(cell) > 0 && copies > (maxStepRepeatPrimitives-len(p.primitives))/len(cell) {
+		return fmt.Errorf("step and repeat of %vx%v cells of %v primitives expands to more than %v primitives", sr.nx, sr.ny, len(cell), maxStepRepeatPrimitives)
+	}
+	for j := 0; j < sr.ny; j++ {
+		for i := 0; i < sr.nx; i++ {
+			if i == 0 && j == 0 {
+				continue
+			}
+			for _, prim := range cell {
+				moved, err := TransformPrimitive(prim, Translate(float64(i)*sr.dx, float64(j)*sr.dy))
+				if err != nil {
+					return err
+				}
+				p.primitives = append(p.primitives, moved)
+				if attrs, ok := p.primAttrs[prim]; ok {
+					p.primAttrs[moved] = attrs
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// setAttribute sets (or, if !ok, deletes) an object attribute. The
+// dictionary is copied, as it may be shared by parsed primitives.
+func (p *parser) setAttribute(name, value string, ok bool) {
+	attrs := Attributes{}
+	for k, v := range p.attrs {
+		attrs[k] = v
+	}
+	if ok {
+		attrs[name] = value
+	} else {
+		delete(attrs, name)
+	}
+	p.attrs = attrs
+}
+
+// add adds a parsed primitive, with the current object attributes.
+func (p *parser) add(prim Primitive) {
+	if p.clear {
+		prim = Clear(prim)
+	}
+	p.primitives = append(p.primitives, prim)
+	if len(p.attrs) > 0 {
+		if p.primAttrs == nil {
+			p.primAttrs = map[Primitive]Attributes{}
+		}
+		p.primAttrs[prim] = p.attrs
+	}
+}
+
+func (p *parser) mm(v float64) float64 {
+	if p.units == UnitsInch {
+		return Inch(v)
+	}
+	return v
+}
+
+func (p *parser) defineAperture(cmd string) error {
+	fields := strings.SplitN(cmd[3:], ",", 2)
+	i := strings.IndexFunc(fields[0], func(r rune) bool { return r < '0' || r > '9' })
+	if i > 0 && len(fields[0]) > i+1 {
+		return p.defineMacroAperture(fields, i)
+	}
+	if len(fields) != 2 || i <= 0 || len(fields[0]) != i+1 || !strings.Contains("CRO", fields[0][i:]) {
+		return fmt.Errorf("unsupported aperture %q", cmd)
+	}
+	code, _ := strconv.Atoi(fields[0][:i])
+	var sizes []float64
+	for _, m := range strings.Split(fields[1], "X") {
+		v, err := strconv.ParseFloat(m, 64)
+		if err != nil {
+			return fmt.Errorf("invalid aperture %q", cmd)
+		}
+		sizes = append(sizes, p.mm(v))
+	}
+	a := parsedAperture{shape: fields[0][i], w: sizes[0], h: sizes[0]}
+	if a.shape != 'C' && len(sizes) > 1 {
+		a.h = sizes[1]
+	}
+	p.apertures[code] = a
+	return nil
+}
+
+// defineMacroAperture defines the aperture instantiating a macro from
+// the fields of its definition, whose D-code has i digits. The macro is
+// converted to millimeters.
+func (p *parser) defineMacroAperture(fields []string, i int) error {
+	code, _ := strconv.Atoi(fields[0][:i])
+	m, ok := p.macros[fields[0][i:]]
+	if !ok {
+		return fmt.Errorf("aperture D%v uses undefined macro %q", code, fields[0][i:])
+	}
+	if p.units == UnitsInch {
+		m = m.transformed(m.Name, mmPerInch, false, 0)
+	}
+	a := parsedAperture{macro: m}
+	if len(fields) == 2 {
+		for _, s := range strings.Split(fields[1], "X") {
+			v, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return fmt.Errorf("invalid aperture parameter %q", s)
+			}
+			a.params = append(a.params, v)
+		}
+	}
+	p.apertures[code] = a
+	return nil
+}
+
+func (p *parser) word(cmd string) error {
+	for strings.HasPrefix(cmd, "G") {
+		i := 1
+		for i < len(cmd) && cmd[i] >= '0' && cmd[i] <= '9' {
+			i++
+		}
+		g, err := strconv.Atoi(cmd[1:i])
+		if err != nil {
+			return fmt.Errorf("invalid command %q", cmd)
+		}
+		cmd = cmd[i:]
+		switch g {
+		case 1, 2, 3:
+			p.interp = g
+		case 4:
+			return nil
+		case 36:
+			p.inRegion, p.contour = true, nil
+		case 37:
+			p.endContour()
+			p.inRegion = false
+		case 70:
+			p.units = UnitsInch
+		case 71:
+			p.units = UnitsMM
+		case 74:
+			p.multiQuad = false
+		case 75:
+			p.multiQuad = true
+		case 54, 90:
+		default:
+			return fmt.Errorf("unsupported command G%v", g)
+		}
+	}
+	switch {
+	case cmd == "":
+		return nil
+	case strings.HasPrefix(cmd, "M"):
+		return nil // M02 (end of file)
+	case cmd[0] == 'D':
+		code, err := strconv.Atoi(cmd[1:])
+		if err != nil {
+			return fmt.Errorf("invalid command %q", cmd)
+		}
+		if code < 10 {
+			p.d = code
+			return p.operate(p.pos, Pt{})
+		}
+		a, ok := p.apertures[code]
+		if !ok {
+			return fmt.Errorf("undefined aperture D%v", code)
+		}
+		p.current = &a
+		return nil
+	}
+	return p.coordinates(cmd)
+}
+
+// coordinates parses an "X...Y...I...J...Dnn" operation.
+func (p *parser) coordinates(cmd string) error {
+	if p.format == nil {
+		return fmt.Errorf("coordinates %q before the format specification", cmd)
+	}
+	scale := math.Pow(10, float64(p.format.Decimal))
+	pt, offset := p.pos, Pt{}
+	for s := cmd; len(s) > 0; {
+		key := s[0]
+		j := 1
+		for j < len(s) && (s[j] == '-' || s[j] == '+' || (s[j] >= '0' && s[j] <= '9')) {
+			j++
+		}
+		v, err := strconv.ParseInt(s[1:j], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid operation %q", cmd)
+		}
+		f := p.mm(float64(v) / scale)
+		switch key {
+		case 'X':
+			pt[0] = f
+		case 'Y':
+			pt[1] = f
+		case 'I':
+			offset[0] = f
+		case 'J':
+			offset[1] = f
+		case 'D':
+			p.d = int(v)
+		default:
+			return fmt.Errorf("unsupported operation %q", cmd)
+		}
+		s = s[j:]
+	}
+	return p.operate(pt, offset)
+}
+
+// operate performs the current D01, D02, or D03 operation to pt.
+func (p *parser) operate(pt, offset Pt) error {
+	from := p.pos
+	p.pos = pt
+	if p.inRegion {
+		switch p.d {
+		case 1:
+			if p.contour == nil {
+				p.contour = []Pt{from}
+			}
+			if p.interp == 1 {
+				p.contour = append(p.contour, pt)
+				return nil
+			}
+			arc, err := p.arc(from, pt, offset, 0)
+			if err != nil {
+				return err
+			}
+			pts := arcPoints(arc)
+			if p.interp == 2 {
+				pts = reversed(pts)
+			}
+			p.contour = append(p.contour, pts[1:]...)
+		case 2:
+			p.endContour()
+		default:
+			return fmt.Errorf("D%02d in a region", p.d)
+		}
+		return nil
+	}
+
+	var prim Primitive
+	switch p.d {
+	case 2:
+		return nil
+	case 1, 3:
+		if p.current == nil {
+			return fmt.Errorf("D%02d without an aperture", p.d)
+		}
+		a := *p.current
+		switch {
+		case a.macro != nil && p.d == 1 && from != pt:
+			return fmt.Errorf("draws with aperture macros are not supported")
+		case a.macro != nil:
+			prim = Flash(pt, a.macro, a.params...)
+		case p.d == 1 && p.interp != 1:
+			if a.shape != 'C' {
+				return fmt.Errorf("arcs require a circle aperture")
+			}
+			arc, err := p.arc(from, pt, offset, a.w)
+			if err != nil {
+				return err
+			}
+			prim = arc
+		case p.d == 3 || from == pt:
+			prim = flash(a, pt)
+		case a.shape == 'C' || a.shape == 'O':
+			prim = Line(from[0], from[1], pt[0], pt[1], CircleShape, math.Min(a.w, a.h))
+		case a.w == a.h:
+			prim = Line(from[0], from[1], pt[0], pt[1], RectShape, a.w)
+		default:
+			return fmt.Errorf("draws with rectangular %vx%v apertures are not supported", fmtFloat(a.w), fmtFloat(a.h))
+		}
+	default:
+		return fmt.Errorf("unsupported operation D%02d", p.d)
+	}
+	p.add(prim)
+	return nil
+}
+
+// flash returns the primitive exposing aperture a at pt.
+func flash(a parsedAperture, pt Pt) Primitive {
+	switch {
+	case a.shape == 'C' || (a.shape == 'O' && a.w == a.h):
+		return Circle(pt, a.w)
+	case a.shape == 'R' && a.w == a.h:
+		return Line(pt[0], pt[1], pt[0], pt[1], RectShape, a.w)
+	case a.shape == 'R':
+		hw, hh := 0.5*a.w, 0.5*a.h
+		return Polygon(pt, true, []Pt{{-hw, -hh}, {hw, -hh}, {hw, hh}, {-hw, hh}}, 0)
+	}
+	return PadShape{Width: a.w, Height: a.h}.primitive(pt, 0)
+}
+
+// arc returns the arc from p1 to p2 about p1+offset, in the current
+// (clockwise or counterclockwise) direction. ArcT is always
+// counterclockwise, so clockwise arcs swap their ends.
+func (p *parser) arc(p1, p2, offset Pt, thickness float64) (*ArcT, error) {
+	if !p.multiQuad {
+		return nil, fmt.Errorf("single quadrant arcs (G74) are not supported")
+	}
+	center := Pt{p1[0] + offset[0], p1[1] + offset[1]}
+	start := math.Atan2(p1[1]-center[1], p1[0]-center[0])
+	end := math.Atan2(p2[1]-center[1], p2[0]-center[0])
+	if p.interp == 2 {
+		start, end = end, start
+	}
+	if end <= start {
+		end += 2 * math.Pi
+	}
+	return Arc(center, Distance(p1, center), CircleShape, 1, 1, start*180/math.Pi, end*180/math.Pi, thickness), nil
+}
+
+func (p *parser) endContour() {
+	c := p.contour
+	p.contour = nil
+	if len(c) > 1 && c[0] == c[len(c)-1] {
+		c = c[:len(c)-1]
+	}
+	if len(c) < 3 {
+		return
+	}
+	p.add(Polygon(Pt{}, true, c, 0))
+}
+
+// ParseDesign reads a set of Gerber and Excellon files (see Parse) into
+// a new design, determining the type of each layer from its filename
+// in one of the built-in conventions (see ProtelNames, OSHParkNames,
+// and KiCadNames). The design's filename prefix and convention are
+// those of the first file, and its units and coordinate format are
+// those of the first Gerber file that specifies them. Layers keep the
+// filenames they were read from, and their primitives the tags (such
+// as NonPlatedTag) of the parsed layers.
+func ParseDesign(filenames ...string) (*Gerber, error) {
+	return ParseDesignContext(context.Background(), filenames...)
+}
+
+// ParseDesignContext is like ParseDesign but stops early (returning
+// ctx.Err()) if ctx is canceled while the files are being read.
+func ParseDesignContext(ctx context.Context, filenames ...string) (*Gerber, error) {
+	if len(filenames) == 0 {
+		return nil, fmt.Errorf("no files to parse")
+	}
+	var g *Gerber
+	formatSet := false
+	for _, filename := range filenames {
+		t, n, prefix, fc, ok := layerTypeOf(filename)
+		if !ok {
+			return nil, fmt.Errorf("%v: unknown layer type", filename)
+		}
+		if g == nil {
+			g = New(prefix, WithFilenameConvention(fc))
+		}
+		f, err := os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+		parsed, p, err := parse(ctx, bufio.NewReader(f))
+		f.Close()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, fmt.Errorf("%v: %v", filename, err)
+		}
+		if !formatSet && p.format != nil {
+			g.units, g.format, formatSet = p.units, p.format, true
+		}
+		layer := g.makeLayer(t, n)
+		layer.Filename = filename
+		layer.Add(parsed.Primitives...)
+		for _, prim := range parsed.Primitives {
+			layer.setAttributes(prim, parsed.Attributes(prim))
+			layer.Tag(prim, parsed.Tags(prim)...)
+		}
+	}
+	return g, nil
+}
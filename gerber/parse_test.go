@@ -0,0 +1,161 @@
+package gerber
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParse_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		p    Primitive
+		want string // type of the parsed primitive
+	}{
+		{name: "circle", p: Circle(Pt{1, 2}, 0.5), want: "*gerber.CircleT"},
+		{name: "round line", p: Line(0, 0, 3, 4, CircleShape, 0.2), want: "*gerber.LineT"},
+		{name: "square line", p: Line(-1, 0, 1, 0, RectShape, 0.3), want: "*gerber.LineT"},
+		{name: "polygon", p: Polygon(Pt{1, 1}, true, []Pt{{0, 0}, {2, 0}, {2, 1}, {0, 1}}, 0), want: "*gerber.PolygonT"},
+		{name: "clear circle", p: Clear(Circle(Pt{1, 1}, 1)), want: "*gerber.ClearT"},
+		{name: "inches", opts: []Option{WithUnits(UnitsInch)}, p: Circle(Pt{25.4, 12.7}, 1), want: "*gerber.CircleT"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			top := New("test", tt.opts...).TopCopper()
+			top.Add(tt.p)
+			var buf bytes.Buffer
+			if err := top.WriteGerber(&buf); err != nil {
+				t.Fatal(err)
+			}
+			layer, err := Parse(&buf)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if len(layer.Primitives) != 1 {
+				t.Fatalf("Parse = %v primitives, want 1", len(layer.Primitives))
+			}
+			got := layer.Primitives[0]
+			if typ := fmt.Sprintf("%T", got); typ != tt.want {
+				t.Errorf("Parse type = %v, want %v", typ, tt.want)
+			}
+			if !mbbNear(got.MBB(), tt.p.MBB(), 1e-3) {
+				t.Errorf("Parse MBB = %v, want %v", got.MBB(), tt.p.MBB())
+			}
+		})
+	}
+}
+
+func TestParse_Arcs(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want MBB
+	}{
+		{
+			name: "counterclockwise quarter",
+			data: "G03X0Y2000000I-2000000J0D01*\n",
+			want: MBB{Min: Pt{-0.05, -0.05}, Max: Pt{2.05, 2.05}},
+		},
+		{
+			name: "clockwise three quarters",
+			data: "G02X0Y2000000I-2000000J0D01*\n",
+			want: MBB{Min: Pt{-2.05, -2.05}, Max: Pt{2.05, 2.05}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := "%FSLAX36Y36*%\n%MOMM*%\n%ADD10C,0.1*%\nG75*\nD10*\nX2000000Y0D02*\n" + tt.data + "M02*\n"
+			layer, err := Parse(strings.NewReader(data))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if len(layer.Primitives) != 1 {
+				t.Fatalf("Parse = %v primitives, want 1", len(layer.Primitives))
+			}
+			if _, ok := layer.Primitives[0].(*ArcT); !ok {
+				t.Fatalf("Parse = %T, want *ArcT", layer.Primitives[0])
+			}
+			if got := layer.MBB(); !mbbNear(got, tt.want, 1e-3) {
+				t.Errorf("MBB = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "undefined macro", data: "%FSLAX36Y36*%\n%MOMM*%\n%ADD11BOX,1*%\n"},
+		{name: "macro variable", data: "%FSLAX36Y36*%\n%MOMM*%\n%AMBOX*$2=$1x2*21,1,$2,1,0,0,0*%\n"},
+		{name: "undefined aperture", data: "%FSLAX36Y36*%\n%MOMM*%\nD11*\n"},
+		{name: "no format", data: "%MOMM*%\n%ADD11C,1*%\nD11*\nX0Y0D03*\n"},
+		{name: "flash without aperture", data: "%FSLAX36Y36*%\nX0Y0D03*\n"},
+		{name: "unterminated", data: "%FSLAX36Y36*\n"},
+		{name: "too many cells", data: "%FSLAX36Y36*%\n%MOMM*%\n%SRX1000000Y1000000I1J1*%\n"},
+		{name: "too many copies", data: "%FSLAX36Y36*%\n%MOMM*%\n%ADD11C,1*%\n%SRX1000Y1000I1J1*%\nD11*\nX0Y0D03*\nX0Y1000000D03*\nX0Y2000000D03*\nX0Y3000000D03*\nX0Y4000000D03*\n%SR*%\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(strings.NewReader(tt.data)); err == nil {
+				t.Errorf("Parse = nil, want error")
+			}
+		})
+	}
+}
+
+func TestParseDesign(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-gerber")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	g := New(filepath.Join(dir, "board"), WithUnits(UnitsInch))
+	g.TopCopper().Add(Circle(Pt{1, 1}, 1), Line(0, 0, 2, 0, CircleShape, 0.2))
+	g.LayerN(2).Add(Circle(Pt{1, 1}, 1))
+	g.Outline().Add(Line(0, 0, 2, 0, CircleShape, 0.1))
+	if err := g.WriteToDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	var filenames []string
+	for _, layer := range g.Layers {
+		filenames = append(filenames, layer.Filename)
+	}
+	got, err := ParseDesign(filenames...)
+	if err != nil {
+		t.Fatalf("ParseDesign: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := ParseDesignContext(ctx, filenames...); err != context.Canceled {
+		t.Errorf("ParseDesignContext = %v, want %v", err, context.Canceled)
+	}
+	if got.units != UnitsInch {
+		t.Errorf("units = %v, want %v", got.units, UnitsInch)
+	}
+	if len(got.Layers) != len(g.Layers) {
+		t.Fatalf("ParseDesign = %v layers, want %v", len(got.Layers), len(g.Layers))
+	}
+	for i, layer := range got.Layers {
+		want := g.Layers[i]
+		if layer.Type != want.Type || layer.N != want.N || layer.Filename != want.Filename {
+			t.Errorf("layer %v = %v %v %q, want %v %v %q", i, layer.Type, layer.N, layer.Filename, want.Type, want.N, want.Filename)
+		}
+		if len(layer.Primitives) != len(want.Primitives) {
+			t.Errorf("layer %v has %v primitives, want %v", i, len(layer.Primitives), len(want.Primitives))
+		}
+	}
+
+	if _, err := ParseDesign(filepath.Join(dir, "board.txt")); err == nil {
+		t.Errorf("ParseDesign(board.txt) = nil, want error")
+	}
+}
@@ -0,0 +1,202 @@
+package gerber
+
+import (
+	"errors"
+	"fmt"
+)
+
+// PastePattern represents a standard subdivision of the solder paste
+// opening of a (typically large, thermal) pad. Subdividing the paste
+// reduces voiding and prevents the part from floating on the solder.
+type PastePattern int
+
+const (
+	// PasteGrid is a grid of Rows x Cols rectangular windows.
+	PasteGrid PastePattern = iota
+	// PasteBorder is a single frame of the given Width around the
+	// edge of the pad, leaving its center free of paste.
+	PasteBorder
+	// PasteCrosshatch is a lattice of horizontal and vertical stripes
+	// of the given Width and Pitch.
+	PasteCrosshatch
+)
+
+func (p PastePattern) String() string {
+	switch p {
+	case PasteGrid:
+		return "grid"
+	case PasteBorder:
+		return "border"
+	case PasteCrosshatch:
+		return "crosshatch"
+	}
+	return fmt.Sprintf("PastePattern(%d)", int(p))
+}
+
+// PasteOpts represents the options used by PasteWindows.
+// All dimensions are in millimeters.
+type PasteOpts struct {
+	Pattern PastePattern
+	// Rows and Cols are the number of PasteGrid windows (default 2).
+	Rows, Cols int
+	// Web is the width of the stencil between PasteGrid windows
+	// (default 0.2).
+	Web float64
+	// Width is the width of the PasteBorder frame or of the
+	// PasteCrosshatch stripes (default 0.4), and Pitch the spacing of
+	// the PasteCrosshatch stripes (default 1).
+	Width, Pitch float64
+	// Inset shrinks the pattern from the edges of the pad.
+	Inset float64
+}
+
+func (o PasteOpts) withDefaults() PasteOpts {
+	if o.Rows <= 0 {
+		o.Rows = 2
+	}
+	if o.Cols <= 0 {
+		o.Cols = 2
+	}
+	if o.Web <= 0 {
+		o.Web = 0.2
+	}
+	if o.Width <= 0 {
+		o.Width = 0.4
+	}
+	if o.Pitch <= 0 {
+		o.Pitch = 1
+	}
+	return o
+}
+
+// PasteWindows returns the paste windows of pad for the selected
+// pattern, along with the resulting paste coverage of the pad
+// (see PasteCoverage). Windows that would extend beyond the pad
+// (e.g. the corners of a grid on a round pad) are omitted.
+func PasteWindows(pad Primitive, opts PasteOpts) ([]Primitive, float64, error) {
+	o := opts.withDefaults()
+	mbb := pad.MBB()
+	r := MBB{Min: Pt{mbb.Min[0] + o.Inset, mbb.Min[1] + o.Inset}, Max: Pt{mbb.Max[0] - o.Inset, mbb.Max[1] - o.Inset}}
+	w, h := r.Max[0]-r.Min[0], r.Max[1]-r.Min[1]
+	if w <= 0 || h <= 0 {
+		return nil, 0, errors.New("paste inset is larger than the pad")
+	}
+	rect := func(r MBB) []Pt {
+		return []Pt{r.Min, {r.Max[0], r.Min[1]}, r.Max, {r.Min[0], r.Max[1]}}
+	}
+	// fits reports whether r lies within the pad, allowing its
+	// corners to touch the pad's edges.
+	fits := func(r MBB) bool {
+		const eps = 1e-6
+		for _, pt := range rect(MBB{Min: Pt{r.Min[0] + eps, r.Min[1] + eps}, Max: Pt{r.Max[0] - eps, r.Max[1] - eps}}) {
+			if !PrimitiveContains(pad, pt) {
+				return false
+			}
+		}
+		return true
+	}
+
+	var windows []Primitive
+	switch o.Pattern {
+	case PasteGrid:
+		cw := (w - float64(o.Cols-1)*o.Web) / float64(o.Cols)
+		ch := (h - float64(o.Rows-1)*o.Web) / float64(o.Rows)
+		if cw <= 0 || ch <= 0 {
+			return nil, 0, errors.New("paste webs are wider than the pad")
+		}
+		for j := 0; j < o.Rows; j++ {
+			for i := 0; i < o.Cols; i++ {
+				ll := Pt{r.Min[0] + float64(i)*(cw+o.Web), r.Min[1] + float64(j)*(ch+o.Web)}
+				window := MBB{Min: ll, Max: Pt{ll[0] + cw, ll[1] + ch}}
+				if fits(window) {
+					windows = append(windows, Polygon(Pt{}, true, rect(window), 0))
+				}
+			}
+		}
+	case PasteBorder:
+		outer := rect(r)
+		if !fits(r) {
+			return nil, 0, errors.New("paste border does not fit within the pad")
+		}
+		if 2*o.Width >= w || 2*o.Width >= h {
+			windows = append(windows, Polygon(Pt{}, true, outer, 0))
+			break
+		}
+		inner := rect(MBB{Min: Pt{r.Min[0] + o.Width, r.Min[1] + o.Width}, Max: Pt{r.Max[0] - o.Width, r.Max[1] - o.Width}})
+		windows = append(windows, Polygon(Pt{}, true, cutHoles(outer, [][]Pt{inner}), 0))
+	case PasteCrosshatch:
+		half := 0.5 * o.Width
+		inset := MBB{Min: Pt{r.Min[0] + half, r.Min[1] + half}, Max: Pt{r.Max[0] - half, r.Max[1] - half}}
+		inside := func(pt Pt) bool {
+			return inset.ContainsPoint(&pt) && fits(MBB{Min: Pt{pt[0] - half, pt[1] - half}, Max: Pt{pt[0] + half, pt[1] + half}})
+		}
+		for _, p := range hatch(inset, inside, o.Width, o.Pitch) {
+			l := p.(*LineT)
+			windows = append(windows, Line(l.P1[0], l.P1[1], l.P2[0], l.P2[1], RectShape, o.Width))
+		}
+	default:
+		return nil, 0, fmt.Errorf("unknown paste pattern %v", o.Pattern)
+	}
+	if len(windows) == 0 {
+		return nil, 0, fmt.Errorf("no %v paste windows fit within the pad", o.Pattern)
+	}
+	return windows, PasteCoverage(pad, windows), nil
+}
+
+// coverageSamples is the number of samples (in each of X and Y) used
+// to estimate paste coverage.
+const coverageSamples = 200
+
+// PasteCoverage returns the percentage (0-100) of the area of pad
+// that is covered by the paste windows, estimated by sampling.
+func PasteCoverage(pad Primitive, windows []Primitive) float64 {
+	mbb := pad.MBB()
+	dx := (mbb.Max[0] - mbb.Min[0]) / coverageSamples
+	dy := (mbb.Max[1] - mbb.Min[1]) / coverageSamples
+	var inPad, covered int
+	for j := 0; j < coverageSamples; j++ {
+		for i := 0; i < coverageSamples; i++ {
+			pt := Pt{mbb.Min[0] + (float64(i)+0.5)*dx, mbb.Min[1] + (float64(j)+0.5)*dy}
+			if !PrimitiveContains(pad, pt) {
+				continue
+			}
+			inPad++
+			for _, w := range windows {
+				if PrimitiveContains(w, pt) {
+					covered++
+					break
+				}
+			}
+		}
+	}
+	if inPad == 0 {
+		return 0
+	}
+	return 100 * float64(covered) / float64(inPad)
+}
+
+// AddPasteWindows adds the paste windows of pad (a primitive in this
+// top or bottom copper layer) to the paste layer on the same side,
+// which is added to the design if necessary, and returns the paste
+// coverage. The pad should not also declare a paste opening
+// (see Openings).
+func (l *Layer) AddPasteWindows(pad Primitive, opts PasteOpts) (float64, error) {
+	if l.g == nil {
+		return 0, errors.New("layer does not belong to a design")
+	}
+	var pasteType LayerType
+	switch l.Type {
+	case TopCopperLayer:
+		pasteType = TopSolderPasteLayer
+	case BottomCopperLayer:
+		pasteType = BottomSolderPasteLayer
+	default:
+		return 0, fmt.Errorf("paste windows require a top or bottom copper layer, not %v", l.Type)
+	}
+	windows, coverage, err := PasteWindows(pad, opts)
+	if err != nil {
+		return 0, err
+	}
+	l.g.firstLayerOfType(pasteType).Add(windows...)
+	return coverage, nil
+}
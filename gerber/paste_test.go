@@ -0,0 +1,62 @@
+package gerber
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPasteWindows(t *testing.T) {
+	square := Polygon(Pt{}, true, RoundedRect(MBB{Max: Pt{4, 4}}, 0), 0)
+	tests := []struct {
+		name         string
+		pad          Primitive
+		opts         PasteOpts
+		wantWindows  int
+		wantCoverage float64
+		tol          float64
+	}{
+		{name: "grid", pad: square, opts: PasteOpts{Pattern: PasteGrid}, wantWindows: 4, wantCoverage: 90.25, tol: 0.5},
+		{name: "grid on round pad", pad: Circle(Pt{2, 2}, 4), opts: PasteOpts{Pattern: PasteGrid, Rows: 3, Cols: 3}, wantWindows: 1, wantCoverage: 100 * 1.2 * 1.2 / (4 * math.Pi), tol: 0.5},
+		{name: "border", pad: square, opts: PasteOpts{Pattern: PasteBorder, Width: 0.5}, wantWindows: 1, wantCoverage: 43.75, tol: 0.5},
+		{name: "crosshatch", pad: square, opts: PasteOpts{Pattern: PasteCrosshatch}, wantWindows: 8, wantCoverage: 64, tol: 1.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			windows, coverage, err := PasteWindows(tt.pad, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := len(windows); got != tt.wantWindows {
+				t.Errorf("windows = %v, want %v", got, tt.wantWindows)
+			}
+			if math.Abs(coverage-tt.wantCoverage) > tt.tol {
+				t.Errorf("coverage = %v, want %v", coverage, tt.wantCoverage)
+			}
+		})
+	}
+
+	if _, _, err := PasteWindows(square, PasteOpts{Inset: 3}); err == nil {
+		t.Error("PasteWindows with oversized inset: want error")
+	}
+}
+
+func TestLayer_AddPasteWindows(t *testing.T) {
+	g := New("test")
+	pad := Polygon(Pt{}, true, RoundedRect(MBB{Max: Pt{4, 4}}, 0), 0)
+	bottom := g.BottomCopper()
+	bottom.Add(pad)
+	coverage, err := bottom.AddPasteWindows(pad, PasteOpts{Pattern: PasteGrid, Rows: 3, Cols: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	paste := g.layersOfType(BottomSolderPasteLayer)
+	if len(paste) != 1 || len(paste[0].Primitives) != 9 {
+		t.Fatalf("bottom paste = %v, want 9 windows", paste)
+	}
+	if coverage <= 0 || coverage >= 100 {
+		t.Errorf("coverage = %v, want between 0 and 100", coverage)
+	}
+	if _, err := g.Drill().AddPasteWindows(pad, PasteOpts{}); err == nil {
+		t.Error("AddPasteWindows on drill layer: want error")
+	}
+}
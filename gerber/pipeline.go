@@ -0,0 +1,124 @@
+package gerber
+
+import "fmt"
+
+// Pass is one step of the export pipeline run by DeriveOpenings before
+// a design is written. Passes that add primitives should add them with
+// Layer.AddDerived, so that they are replaced (rather than duplicated)
+// when the pipeline runs again. The pipeline is not serialized.
+type Pass struct {
+	Name string
+	Run  func(g *Gerber) error
+}
+
+// The built-in passes, in their default order.
+var (
+	// PadstackPass adds the pads and holes of the design's padstack
+	// instances (see PlacePadstack).
+	PadstackPass = Pass{Name: "padstacks", Run: func(g *Gerber) error {
+		g.placePadstacks()
+		return nil
+	}}
+	// TestPadPass adds bottom side test pads to the nets of the
+	// design's copper (found from its connectivity), if enabled (see
+	// WithTestPads). It runs after PadstackPass, so that pads count as
+	// copper of their nets, and before OpeningsPass, which opens the
+	// solder mask over the test pads.
+	TestPadPass = Pass{Name: "test-pads", Run: func(g *Gerber) error {
+		if g.testPads == nil {
+			return nil
+		}
+		return g.addTestPads(g.testPads)
+	}}
+	// OpeningsPass adds the solder mask and paste openings declared
+	// with Openings.
+	OpeningsPass = Pass{Name: "openings", Run: (*Gerber).deriveOpenings}
+	// SilkscreenClipPass clips the silkscreen against the solder mask
+	// openings, if enabled (see WithSilkscreenClipping).
+	SilkscreenClipPass = Pass{Name: "silkscreen-clip", Run: func(g *Gerber) error {
+		if g.silkscreenMargin > 0 {
+			g.clipSilkscreen(g.silkscreenMargin)
+		}
+		return nil
+	}}
+	// RevisionPass prints the design's revision on its silkscreen, if
+	// enabled (see WithRevisionStamp). It runs before DrillChartPass,
+	// so that the drill chart avoids the stamp.
+	RevisionPass = Pass{Name: "revision", Run: func(g *Gerber) error {
+		if g.revisionFont == "" {
+			return nil
+		}
+		return g.addRevisionStamp(g.revisionFont, g.revisionAt)
+	}}
+	// DrillChartPass prints the design's drill counts (see DrillCounts)
+	// in an unused corner of the top silkscreen, if enabled (see
+	// WithDrillChart).
+	DrillChartPass = Pass{Name: "drill-chart", Run: func(g *Gerber) error {
+		if g.drillChartFont == "" {
+			return nil
+		}
+		return g.addDrillChart(g.drillChartFont)
+	}}
+)
+
+// DefaultPipeline returns the built-in passes in their default order.
+func DefaultPipeline() []Pass {
+	return []Pass{PadstackPass, TestPadPass, OpeningsPass, SilkscreenClipPass, RevisionPass, DrillChartPass}
+}
+
+// WithPass appends a pass to the design's export pipeline.
+func WithPass(p Pass) Option {
+	return func(g *Gerber) {
+		g.pipeline = append(g.Pipeline(), p)
+	}
+}
+
+// Pipeline returns (a copy of) the design's export pipeline.
+func (g *Gerber) Pipeline() []Pass {
+	if g.pipeline == nil {
+		return DefaultPipeline()
+	}
+	return append([]Pass(nil), g.pipeline...)
+}
+
+// SetPipeline replaces the design's export pipeline, e.g. to reorder
+// or omit built-in passes. With no passes, nothing is derived.
+func (g *Gerber) SetPipeline(passes ...Pass) {
+	g.pipeline = append([]Pass{}, passes...)
+}
+
+// InsertPass inserts a pass into the design's export pipeline after
+// the named pass, or first if after is "".
+func (g *Gerber) InsertPass(after string, p Pass) error {
+	passes := g.Pipeline()
+	i := 0
+	if after != "" {
+		i = passIndex(passes, after) + 1
+		if i == 0 {
+			return fmt.Errorf("unknown pass %q", after)
+		}
+	}
+	g.pipeline = append(passes[:i], append([]Pass{p}, passes[i:]...)...)
+	return nil
+}
+
+// RemovePass removes the named pass from the design's export pipeline
+// and reports whether it was found.
+func (g *Gerber) RemovePass(name string) bool {
+	passes := g.Pipeline()
+	i := passIndex(passes, name)
+	if i < 0 {
+		return false
+	}
+	g.pipeline = append(passes[:i], passes[i+1:]...)
+	return true
+}
+
+func passIndex(passes []Pass, name string) int {
+	for i, p := range passes {
+		if p.Name == name {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,91 @@
+package gerber
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGerber_Pipeline(t *testing.T) {
+	var order []string
+	record := func(name string) Pass {
+		return Pass{Name: name, Run: func(g *Gerber) error {
+			order = append(order, name)
+			return nil
+		}}
+	}
+	marker := Pass{Name: "marker", Run: func(g *Gerber) error {
+		g.firstLayerOfType(TopSilkscreenLayer).AddDerived(Circle(Pt{1, 1}, 1))
+		return nil
+	}}
+
+	g := New("test", WithPass(record("last")))
+	g.firstLayerOfType(TopCopperLayer).AddWithOpenings(Openings{Mask: true}, Circle(Pt{5, 5}, 1))
+	if err := g.InsertPass("", record("first")); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.InsertPass("openings", record("after-openings")); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.InsertPass("missing", marker); err == nil {
+		t.Error("InsertPass after a missing pass = nil error, want error")
+	}
+	var names []string
+	for _, p := range g.Pipeline() {
+		names = append(names, p.Name)
+	}
+	want := []string{"first", "padstacks", "test-pads", "openings", "after-openings", "silkscreen-clip", "revision", "drill-chart", "last"}
+	if len(names) != len(want) {
+		t.Fatalf("Pipeline = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("Pipeline[%v] = %v, want %v", i, names[i], want[i])
+		}
+	}
+
+	if err := g.DeriveOpenings(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(order), 3; got != want {
+		t.Errorf("passes run = %v, want %v", order, []string{"first", "after-openings", "last"})
+	}
+	if got := len(g.layersOfType(TopSolderMaskLayer)); got != 1 {
+		t.Errorf("mask layers = %v, want 1", got)
+	}
+
+	// Derived primitives of custom passes are replaced on each run.
+	if err := g.InsertPass("last", marker); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := g.DeriveOpenings(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	silk := g.layersOfType(TopSilkscreenLayer)
+	if len(silk) != 1 || len(silk[0].Primitives) != 1 {
+		t.Errorf("silkscreen after two runs = %v, want one primitive", silk)
+	}
+
+	// Removing the openings pass removes the derived mask opening.
+	if !g.RemovePass("openings") {
+		t.Fatal("RemovePass(openings) = false, want true")
+	}
+	if g.RemovePass("openings") {
+		t.Error("RemovePass(openings) again = true, want false")
+	}
+	if err := g.DeriveOpenings(); err != nil {
+		t.Fatal(err)
+	}
+	if mask := g.layersOfType(TopSolderMaskLayer); len(mask[0].Primitives) != 0 {
+		t.Errorf("mask primitives = %v, want none", len(mask[0].Primitives))
+	}
+}
+
+func TestGerber_DeriveOpenings_PassError(t *testing.T) {
+	g := New("test")
+	g.SetPipeline(Pass{Name: "broken", Run: func(g *Gerber) error { return errors.New("oops") }})
+	if err := g.DeriveOpenings(); err == nil || err.Error() != "broken pass: oops" {
+		t.Errorf("DeriveOpenings = %v, want broken pass: oops", err)
+	}
+}
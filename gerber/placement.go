@@ -0,0 +1,175 @@
+package gerber
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// The helpers below solve simple placement constraints, so that
+// scripts can lay out rows and rings of parts (e.g. connector pins or
+// LEDs) declaratively. All dimensions are in millimeters.
+
+// Placement represents the position and counterclockwise rotation (in
+// degrees) at which to place a group or footprint (see Group.Place and
+// Footprint.Place).
+type Placement struct {
+	At      Pt
+	Degrees float64
+}
+
+// DistributeLine returns n placements evenly spaced from p1 to p2
+// (inclusive), rotated to follow the direction of the line.
+func DistributeLine(p1, p2 Pt, n int) []Placement {
+	degrees := math.Atan2(p2[1]-p1[1], p2[0]-p1[0]) * 180 / math.Pi
+	var result []Placement
+	for i := 0; i < n; i++ {
+		t := 0.0
+		if n > 1 {
+			t = float64(i) / float64(n-1)
+		}
+		at := Pt{p1[0] + t*(p2[0]-p1[0]), p1[1] + t*(p2[1]-p1[1])}
+		result = append(result, Placement{At: at, Degrees: degrees})
+	}
+	return result
+}
+
+// DistributeArc returns n placements evenly spaced on the circle of
+// the given radius about center, counterclockwise from startDeg to
+// endDeg (inclusive), each rotated to its angle so that parts face
+// outward. If the arc is a full circle, the last placement does not
+// coincide with the first.
+func DistributeArc(center Pt, radius, startDeg, endDeg float64, n int) []Placement {
+	sweep := endDeg - startDeg
+	step := 0.0
+	switch {
+	case math.Abs(math.Abs(sweep)-360) < 1e-9:
+		step = sweep / float64(n)
+	case n > 1:
+		step = sweep / float64(n-1)
+	}
+	var result []Placement
+	for i := 0; i < n; i++ {
+		angle := startDeg + float64(i)*step
+		result = append(result, Placement{At: PolarFrom(center, radius, angle), Degrees: angle})
+	}
+	return result
+}
+
+// Edge identifies a side (or center line) of a minimum bounding box.
+type Edge int
+
+// Edges used by Align.
+const (
+	EdgeLeft Edge = iota
+	EdgeRight
+	EdgeBottom
+	EdgeTop
+	// EdgeCenterX and EdgeCenterY are the vertical and horizontal
+	// center lines.
+	EdgeCenterX
+	EdgeCenterY
+)
+
+// coordinate returns the position of the edge of mbb, and whether it
+// is an X (rather than a Y) coordinate.
+func (e Edge) coordinate(mbb MBB) (float64, bool) {
+	switch e {
+	case EdgeLeft:
+		return mbb.Min[0], true
+	case EdgeRight:
+		return mbb.Max[0], true
+	case EdgeBottom:
+		return mbb.Min[1], false
+	case EdgeTop:
+		return mbb.Max[1], false
+	case EdgeCenterX:
+		return 0.5 * (mbb.Min[0] + mbb.Max[0]), true
+	}
+	return 0.5 * (mbb.Min[1] + mbb.Max[1]), false
+}
+
+// Align returns copies of the groups moved (in X for the left, right,
+// and vertical center edges, or in Y otherwise) so that the given edge
+// of each group lines up with that of the first group.
+func Align(edge Edge, groups ...Group) ([]Group, error) {
+	if len(groups) == 0 {
+		return nil, nil
+	}
+	target, _ := edge.coordinate(groups[0].MBB())
+	var result []Group
+	for _, g := range groups {
+		v, isX := edge.coordinate(g.MBB())
+		d := Translate(0, target-v)
+		if isX {
+			d = Translate(target-v, 0)
+		}
+		moved, err := g.Transform(d)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, moved)
+	}
+	return result, nil
+}
+
+// keepApartIterations limits the passes made by KeepApart.
+const keepApartIterations = 100
+
+// KeepApart returns copies of the groups moved apart (by as little as
+// possible along one axis per pair, sharing the move equally) until the
+// minimum bounding boxes of every pair are separated by at least
+// distance. It returns an error if the groups cannot be separated
+// within a fixed number of passes.
+func KeepApart(distance float64, groups ...Group) ([]Group, error) {
+	if distance < 0 {
+		return nil, errors.New("negative distance")
+	}
+	mbbs := make([]MBB, len(groups))
+	offsets := make([]Pt, len(groups))
+	for i, g := range groups {
+		mbbs[i] = g.MBB()
+	}
+	box := func(i int) MBB {
+		o := offsets[i]
+		return MBB{Min: Pt{mbbs[i].Min[0] + o[0], mbbs[i].Min[1] + o[1]}, Max: Pt{mbbs[i].Max[0] + o[0], mbbs[i].Max[1] + o[1]}}
+	}
+	settled := false
+	for pass := 0; pass < keepApartIterations && !settled; pass++ {
+		settled = true
+		for i := range groups {
+			for j := i + 1; j < len(groups); j++ {
+				a, b := box(i), box(j)
+				// Overlap (including the required distance) in each axis.
+				dx := math.Min(a.Max[0], b.Max[0]) - math.Max(a.Min[0], b.Min[0]) + distance
+				dy := math.Min(a.Max[1], b.Max[1]) - math.Max(a.Min[1], b.Min[1]) + distance
+				if dx <= validationEps || dy <= validationEps {
+					continue
+				}
+				settled = false
+				axis, d := 0, dx
+				if dy < dx {
+					axis, d = 1, dy
+				}
+				sign := 1.0
+				if a.Min[axis]+a.Max[axis] > b.Min[axis]+b.Max[axis] {
+					sign = -1
+				}
+				offsets[i][axis] -= sign * 0.5 * d
+				offsets[j][axis] += sign * 0.5 * d
+			}
+		}
+	}
+	if !settled {
+		return nil, fmt.Errorf("could not keep %v groups %vmm apart", len(groups), fmtFloat(distance))
+	}
+	var result []Group
+	for i, g := range groups {
+		moved, err := g.Transform(Translate(offsets[i][0], offsets[i][1]))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, moved)
+	}
+	return result, nil
+}
@@ -0,0 +1,96 @@
+package gerber
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDistributeLine(t *testing.T) {
+	got := DistributeLine(Pt{0, 0}, Pt{0, 10}, 3)
+	want := []Placement{{At: Pt{0, 0}, Degrees: 90}, {At: Pt{0, 5}, Degrees: 90}, {At: Pt{0, 10}, Degrees: 90}}
+	if len(got) != len(want) {
+		t.Fatalf("DistributeLine = %v, want %v", got, want)
+	}
+	for i := range got {
+		if Distance(got[i].At, want[i].At) > 1e-9 || got[i].Degrees != want[i].Degrees {
+			t.Errorf("DistributeLine[%v] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestDistributeArc(t *testing.T) {
+	tests := []struct {
+		name       string
+		start, end float64
+		n          int
+		want       []float64 // degrees
+	}{
+		{name: "full circle", start: 0, end: 360, n: 4, want: []float64{0, 90, 180, 270}},
+		{name: "half circle", start: 0, end: 180, n: 3, want: []float64{0, 90, 180}},
+		{name: "single", start: 45, end: 90, n: 1, want: []float64{45}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DistributeArc(Pt{1, 1}, 2, tt.start, tt.end, tt.n)
+			if len(got) != len(tt.want) {
+				t.Fatalf("DistributeArc = %v, want %v placements", got, len(tt.want))
+			}
+			for i, p := range got {
+				want := PolarFrom(Pt{1, 1}, 2, tt.want[i])
+				if math.Abs(p.Degrees-tt.want[i]) > 1e-9 || Distance(p.At, want) > 1e-9 {
+					t.Errorf("DistributeArc[%v] = %v, want %v at %v degrees", i, p, want, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestAlign(t *testing.T) {
+	a := Group{Circle(Pt{0, 0}, 2)}
+	b := Group{Circle(Pt{5, 3}, 4)}
+	tests := []struct {
+		edge Edge
+		want MBB // of b
+	}{
+		{EdgeLeft, MBB{Min: Pt{-1, 1}, Max: Pt{3, 5}}},
+		{EdgeTop, MBB{Min: Pt{3, -3}, Max: Pt{7, 1}}},
+		{EdgeCenterY, MBB{Min: Pt{3, -2}, Max: Pt{7, 2}}},
+	}
+	for _, tt := range tests {
+		got, err := Align(tt.edge, a, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mbb := got[1].MBB(); !mbbNear(mbb, tt.want, 1e-9) {
+			t.Errorf("Align(%v) MBB = %v, want %v", tt.edge, mbb, tt.want)
+		}
+		if mbb := got[0].MBB(); !mbbNear(mbb, a.MBB(), 1e-9) {
+			t.Errorf("Align(%v) moved the first group to %v", tt.edge, mbb)
+		}
+	}
+}
+
+func TestKeepApart(t *testing.T) {
+	groups := []Group{
+		{Circle(Pt{0, 0}, 2)},
+		{Circle(Pt{1, 0.5}, 2)},
+		{Circle(Pt{2, 0}, 2)},
+	}
+	got, err := KeepApart(0.5, groups...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range got {
+		for j := i + 1; j < len(got); j++ {
+			a, b := got[i].MBB(), got[j].MBB()
+			gx := math.Max(a.Min[0], b.Min[0]) - math.Min(a.Max[0], b.Max[0])
+			gy := math.Max(a.Min[1], b.Min[1]) - math.Min(a.Max[1], b.Max[1])
+			if math.Max(gx, gy) < 0.5-1e-6 {
+				t.Errorf("groups %v and %v are %v apart, want 0.5", i, j, math.Max(gx, gy))
+			}
+		}
+	}
+	if _, err := KeepApart(-1, groups...); err == nil {
+		t.Error("KeepApart(-1) = nil, want error")
+	}
+}
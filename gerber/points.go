@@ -0,0 +1,66 @@
+package gerber
+
+import "math"
+
+// Pt is an alias for vec2.T, so methods cannot be added to it.
+// The following helpers cover the trigonometry that is otherwise
+// repeated throughout generator programs (e.g. when building coils).
+// As elsewhere in this package, angles are in degrees, measured
+// counterclockwise from the positive X axis.
+
+// Radians converts degrees to radians.
+func Radians(degrees float64) float64 { return degrees * math.Pi / 180 }
+
+// Degrees converts radians to degrees.
+func Degrees(radians float64) float64 { return radians * 180 / math.Pi }
+
+// NormalizeAngle returns the equivalent angle in the range [0,360).
+func NormalizeAngle(degrees float64) float64 {
+	degrees = math.Mod(degrees, 360)
+	if degrees < 0 {
+		degrees += 360
+	}
+	return degrees
+}
+
+// PolarPt returns the point at distance r from the origin in the
+// direction theta (in degrees).
+func PolarPt(r, theta float64) Pt {
+	s, c := math.Sincos(Radians(theta))
+	return Pt{r * c, r * s}
+}
+
+// PolarFrom returns the point at distance r from center in the
+// direction theta (in degrees).
+func PolarFrom(center Pt, r, theta float64) Pt {
+	p := PolarPt(r, theta)
+	return Pt{center[0] + p[0], center[1] + p[1]}
+}
+
+// Offset returns pt moved by (dx,dy).
+func Offset(pt Pt, dx, dy float64) Pt {
+	return Pt{pt[0] + dx, pt[1] + dy}
+}
+
+// Midpoint returns the point halfway between a and b.
+func Midpoint(a, b Pt) Pt {
+	return Pt{0.5 * (a[0] + b[0]), 0.5 * (a[1] + b[1])}
+}
+
+// Distance returns the distance between a and b.
+func Distance(a, b Pt) float64 {
+	return math.Hypot(b[0]-a[0], b[1]-a[1])
+}
+
+// AngleTo returns the direction (in degrees, in the range (-180,180])
+// from a to b.
+func AngleTo(a, b Pt) float64 {
+	return Degrees(math.Atan2(b[1]-a[1], b[0]-a[0]))
+}
+
+// RotatePt returns pt rotated counterclockwise by degrees about center.
+func RotatePt(pt, center Pt, degrees float64) Pt {
+	s, c := math.Sincos(Radians(degrees))
+	dx, dy := pt[0]-center[0], pt[1]-center[1]
+	return Pt{center[0] + c*dx - s*dy, center[1] + s*dx + c*dy}
+}
@@ -0,0 +1,45 @@
+package gerber
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPointHelpers(t *testing.T) {
+	const eps = 1e-12
+	near := func(a, b Pt) bool {
+		return math.Abs(a[0]-b[0]) < eps && math.Abs(a[1]-b[1]) < eps
+	}
+
+	tests := []struct {
+		name      string
+		got, want Pt
+	}{
+		{name: "PolarPt 0", got: PolarPt(2, 0), want: Pt{2, 0}},
+		{name: "PolarPt 90", got: PolarPt(2, 90), want: Pt{0, 2}},
+		{name: "PolarFrom", got: PolarFrom(Pt{1, 1}, 1, 180), want: Pt{0, 1}},
+		{name: "Offset", got: Offset(Pt{1, 2}, 3, -4), want: Pt{4, -2}},
+		{name: "Midpoint", got: Midpoint(Pt{0, 0}, Pt{2, 4}), want: Pt{1, 2}},
+		{name: "RotatePt", got: RotatePt(Pt{2, 1}, Pt{1, 1}, 90), want: Pt{1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !near(tt.got, tt.want) {
+				t.Errorf("got %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+
+	if got, want := Distance(Pt{0, 0}, Pt{3, 4}), 5.0; got != want {
+		t.Errorf("Distance = %v, want %v", got, want)
+	}
+	if got, want := AngleTo(Pt{1, 1}, Pt{0, 1}), 180.0; got != want {
+		t.Errorf("AngleTo = %v, want %v", got, want)
+	}
+	if got, want := NormalizeAngle(-90), 270.0; got != want {
+		t.Errorf("NormalizeAngle(-90) = %v, want %v", got, want)
+	}
+	if got, want := Degrees(Radians(45)), 45.0; math.Abs(got-want) > eps {
+		t.Errorf("Degrees(Radians(45)) = %v, want %v", got, want)
+	}
+}
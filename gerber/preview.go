@@ -0,0 +1,96 @@
+package gerber
+
+import (
+	"errors"
+	"fmt"
+	"image"
+)
+
+// Side represents a side of the board.
+type Side int
+
+const (
+	// SideTop is the top (component) side of the board.
+	SideTop Side = iota
+	// SideBottom is the bottom side of the board, viewed from below
+	// (i.e. mirrored left to right).
+	SideBottom
+)
+
+func (s Side) String() string {
+	switch s {
+	case SideTop:
+		return "top"
+	case SideBottom:
+		return "bottom"
+	}
+	return fmt.Sprintf("Side(%d)", int(s))
+}
+
+// PreviewOpts represents the options used by Preview.
+type PreviewOpts struct {
+	// Width and Height are the maximum size of the image in pixels
+	// (default 800). The board is scaled to fit, preserving its
+	// aspect ratio.
+	Width, Height int
+}
+
+// Previewer renders a preview of one side of a design. See
+// RegisterPreviewer.
+type Previewer func(g *Gerber, side Side, opts PreviewOpts) (image.Image, error)
+
+var previewer Previewer
+
+// RegisterPreviewer registers the renderer used by Preview. It is
+// called from the init function of the render package, so
+//
+//	import _ "github.com/gmlewis/go-gerber/gerber/render"
+//
+// makes Preview available without this package depending on it.
+func RegisterPreviewer(p Previewer) {
+	previewer = p
+}
+
+// LayerRenderer rasterizes a layer at dpi pixels per inch. See
+// RegisterLayerRenderer.
+type LayerRenderer func(l *Layer, dpi int) (*image.RGBA, error)
+
+var layerRenderer LayerRenderer
+
+// RegisterLayerRenderer registers the rasterizer used by Layer.Render.
+// Like RegisterPreviewer, it is called by the render package.
+func RegisterLayerRenderer(r LayerRenderer) {
+	layerRenderer = r
+}
+
+// Render rasterizes the layer (as its Gerber file would be plotted) at
+// dpi pixels per inch into an image just large enough for it, e.g. for
+// golden-image regression tests or thumbnails. A LayerRenderer must be
+// registered (see RegisterLayerRenderer).
+func (l *Layer) Render(dpi int) (*image.RGBA, error) {
+	if layerRenderer == nil {
+		return nil, errors.New("no layer renderer registered (import github.com/gmlewis/go-gerber/gerber/render)")
+	}
+	return layerRenderer(l, dpi)
+}
+
+// Preview renders a composite preview image of one side of the board
+// (as the fab would see its Gerber files), so that services can serve
+// board previews without running an external viewer. A Previewer must
+// be registered (see RegisterPreviewer). opts may be nil.
+func (g *Gerber) Preview(side Side, opts *PreviewOpts) (image.Image, error) {
+	if previewer == nil {
+		return nil, errors.New("no previewer registered (import github.com/gmlewis/go-gerber/gerber/render)")
+	}
+	var o PreviewOpts
+	if opts != nil {
+		o = *opts
+	}
+	if o.Width <= 0 {
+		o.Width = 800
+	}
+	if o.Height <= 0 {
+		o.Height = 800
+	}
+	return previewer(g, side, o)
+}
@@ -0,0 +1,19 @@
+package gerber
+
+import "testing"
+
+func TestGerber_Preview_NoPreviewer(t *testing.T) {
+	g := New("test")
+	g.TopCopper().Add(Circle(Pt{1, 1}, 1))
+	if _, err := g.Preview(SideTop, nil); err == nil {
+		t.Error("Preview without a registered previewer = nil error, want error")
+	}
+}
+
+func TestLayer_Render_NoRenderer(t *testing.T) {
+	layer := New("test").TopCopper()
+	layer.Add(Circle(Pt{1, 1}, 1))
+	if _, err := layer.Render(300); err == nil {
+		t.Error("Render without a registered renderer = nil error, want error")
+	}
+}
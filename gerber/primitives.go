@@ -6,6 +6,8 @@ import (
 	"math"
 
 	"github.com/gmlewis/go3d/float64/vec2"
+
+	"github.com/gmlewis/go-gerber/gerber/internal/polyclip"
 )
 
 const (
@@ -21,13 +23,28 @@ const (
 	RectShape Shape = "R"
 	// CircleShape uses circles for the aperture.
 	CircleShape Shape = "C"
+	// ObroundShape uses obrounds (rectangles with semicircular ends
+	// on their shorter sides) for the aperture.
+	ObroundShape Shape = "O"
 )
 
 // Primitive is a Gerber primitive.
+//
+// Primitives may optionally implement Transformer, Expander, and
+// Container. See RegisterPrimitive for information on third-party
+// primitives, and GerberWriter for writing them.
 type Primitive interface {
+	// WriteGerber writes the primitive to the Gerber file using the
+	// aperture with the provided index, which it must select before
+	// drawing or flashing (see GerberWriter.SelectAperture).
 	WriteGerber(w io.Writer, apertureIndex int) error
+	// Aperture returns the aperture used by the primitive, or nil if
+	// the primitive uses the default aperture (e.g. for regions).
+	// Primitives with the same aperture share its definition.
 	Aperture() *Aperture
-	// MBB returns the minimum bounding box in millimeters.
+	// MBB returns the minimum bounding box in millimeters. It is called
+	// often (e.g. by Layer.MBB and the design rule checks), so
+	// primitives with many points may cache it.
 	MBB() MBB
 }
 
@@ -36,20 +53,40 @@ type Primitive interface {
 type Aperture struct {
 	Shape Shape
 	Size  float64
+	// Height, if positive, is the size in Y of a rectangle or obround
+	// aperture, whose Size is then its width.
+	Height float64 `json:",omitempty"`
+	// Macro, if not nil, is the aperture macro (instantiated with
+	// Params) that defines the aperture instead of Shape and Size.
+	Macro  *ApertureMacro `json:",omitempty"`
+	Params []float64      `json:",omitempty"`
 }
 
 func (a *Aperture) MBB() MBB { return MBB{} }
 
 // WriteGerber writes the aperture to the Gerber file.
 func (a *Aperture) WriteGerber(w io.Writer, apertureIndex int) error {
+	gw := toWriter(w)
+	if a.Macro != nil {
+		fmt.Fprintf(w, "%%ADD%v%v%v*%%\n", apertureIndex, a.Macro.Name, fmtParams(a.Params))
+		return nil
+	}
 	if a.Shape == CircleShape {
-		fmt.Fprintf(w, "%%ADD%vC,%0.5f*%%\n", apertureIndex, a.Size)
+		fmt.Fprintf(w, "%%ADD%vC,%v*%%\n", apertureIndex, gw.size(a.Size))
 		return nil
 	}
-	fmt.Fprintf(w, "%%ADD%vR,%0.5fX%0.5f*%%\n", apertureIndex, a.Size, a.Size)
+	fmt.Fprintf(w, "%%ADD%v%v,%vX%v*%%\n", apertureIndex, a.Shape, gw.size(a.Size), gw.size(a.height()))
 	return nil
 }
 
+// height returns the size in Y of the aperture.
+func (a *Aperture) height() float64 {
+	if a.Height > 0 && a.Shape != CircleShape {
+		return a.Height
+	}
+	return a.Size
+}
+
 // Aperture is defined to implement the Primitive interface.
 func (a *Aperture) Aperture() *Aperture {
 	return a
@@ -60,9 +97,20 @@ func (a *Aperture) ID() string {
 	if a == nil {
 		return "default"
 	}
+	if a.Macro != nil {
+		return "M" + a.Macro.Name + fmtParams(a.Params)
+	}
+	if h := a.height(); h != a.Size {
+		return fmt.Sprintf("%v%0.5fx%0.5f", a.Shape, sf*a.Size, sf*h)
+	}
 	return fmt.Sprintf("%v%0.5f", a.Shape, sf*a.Size)
 }
 
+// Transform returns the aperture itself since apertures have no position.
+func (a *Aperture) Transform(t Transform) Primitive {
+	return a
+}
+
 // Pt represents a 2D Point.
 type Pt = vec2.T
 
@@ -85,7 +133,11 @@ type ArcT struct {
 	StartAngle float64
 	EndAngle   float64
 	Thickness  float64
-	mbb        *MBB // cached minimum bounding box
+	// Tolerance is the maximum chord error (in millimeters) allowed when
+	// the arc is flattened into line segments. If zero, the design's
+	// tolerance (see WithArcTolerance) is used when writing.
+	Tolerance float64
+	mbb       *MBB // cached minimum bounding box
 }
 
 // Arc returns an arc primitive.
@@ -114,11 +166,17 @@ func Arc(
 
 // WriteGerber writes the primitive to the Gerber file.
 func (a *ArcT) WriteGerber(w io.Writer, apertureIndex int) error {
-	delta := a.EndAngle - a.StartAngle
-	length := delta * a.Radius
-	// Resolution of segments is 0.1mm
-	segments := int(0.5+length*10.0) + 1
-	delta /= float64(segments)
+	gw := toWriter(w)
+	if gw.nativeArcs && a.XScale == a.YScale {
+		r := a.Radius * a.XScale
+		start, end := PolarFrom(a.Center, r, a.StartAngle*180/math.Pi), PolarFrom(a.Center, r, a.EndAngle*180/math.Pi)
+		gw.aperture(apertureIndex)
+		gw.move(start[0], start[1])
+		gw.arc(start, end, a.Center, false)
+		return nil
+	}
+	segments := a.segments(gw.arcTolerance)
+	delta := (a.EndAngle - a.StartAngle) / float64(segments)
 
 	angle := float64(a.StartAngle)
 	for i := 0; i < segments; i++ {
@@ -131,11 +189,37 @@ func (a *ArcT) WriteGerber(w io.Writer, apertureIndex int) error {
 		y2 := a.Center[1] + a.YScale*math.Sin(angle)*a.Radius
 
 		line := Line(x1, y1, x2, y2, a.Shape, a.Thickness)
-		line.WriteGerber(w, apertureIndex)
+		line.WriteGerber(gw, apertureIndex)
 	}
 	return nil
 }
 
+// segments returns the number of line segments used to flatten the arc.
+// The arc's own Tolerance takes precedence over tol. If neither is set,
+// the arc is divided into segments of roughly 0.1mm.
+func (a *ArcT) segments(tol float64) int {
+	delta := a.EndAngle - a.StartAngle
+	if a.Tolerance > 0 {
+		tol = a.Tolerance
+	}
+	if tol <= 0 {
+		length := delta * a.Radius
+		// Resolution of segments is 0.1mm
+		return int(0.5+length*10.0) + 1
+	}
+	r := a.Radius * math.Max(a.XScale, a.YScale)
+	if tol >= r {
+		return 1
+	}
+	// The maximum chord error of a segment spanning angle theta
+	// is r*(1-cos(theta/2)).
+	theta := 2 * math.Acos(1-tol/r)
+	if n := int(math.Ceil(math.Abs(delta) / theta)); n > 1 {
+		return n
+	}
+	return 1
+}
+
 // Aperture returns the primitive's desired aperture.
 func (a *ArcT) Aperture() *Aperture {
 	return &Aperture{
@@ -148,12 +232,48 @@ func (a *ArcT) MBB() MBB {
 	if a.mbb != nil {
 		return *a.mbb
 	}
+	// The true extents of the arc (rather than of its segments), grown
+	// by half the thickness of its round or square aperture.
+	mbb := grow(arcExtents(a.Center, a.XScale*a.Radius, a.YScale*a.Radius, a.StartAngle, a.EndAngle), 0.5*a.Thickness)
+	a.mbb = &mbb
+	return mbb
+}
 
-	delta := a.EndAngle - a.StartAngle
-	length := delta * a.Radius
-	// Resolution of segments is 0.1mm
-	segments := int(0.5+length*10.0) + 1
-	delta /= float64(segments)
+func (a *ArcT) invalidateBounds() { a.mbb = nil }
+
+// Transform returns a transformed copy of the arc.
+// Only the uniform scale of t is applied to the radius and thickness.
+func (a *ArcT) Transform(t Transform) Primitive {
+	s, theta := t.scaleFactor(), t.rotation()
+	start, end := a.StartAngle+theta, a.EndAngle+theta
+	if t.det() < 0 {
+		start, end = theta-a.EndAngle, theta-a.StartAngle
+	}
+	return &ArcT{
+		Center:     t.Apply(a.Center),
+		Radius:     s * a.Radius,
+		Shape:      a.Shape,
+		XScale:     a.XScale,
+		YScale:     a.YScale,
+		StartAngle: start,
+		EndAngle:   end,
+		Thickness:  s * a.Thickness,
+		Tolerance:  s * a.Tolerance,
+	}
+}
+
+// Expand returns a copy of the arc with its thickness grown by 2*delta.
+func (a *ArcT) Expand(delta float64) Primitive {
+	ea := *a
+	ea.Thickness = expandSize(a.Thickness, delta)
+	ea.mbb = nil
+	return &ea
+}
+
+// Contains reports whether pt lies on the arc.
+func (a *ArcT) Contains(pt Pt) bool {
+	segments := a.segments(0)
+	delta := (a.EndAngle - a.StartAngle) / float64(segments)
 
 	angle := float64(a.StartAngle)
 	for i := 0; i < segments; i++ {
@@ -165,16 +285,11 @@ func (a *ArcT) MBB() MBB {
 		x2 := a.Center[0] + a.XScale*math.Cos(angle)*a.Radius
 		y2 := a.Center[1] + a.YScale*math.Sin(angle)*a.Radius
 
-		line := Line(x1, y1, x2, y2, a.Shape, a.Thickness)
-		mbb := line.MBB()
-		if a.mbb == nil {
-			a.mbb = &mbb
-		} else {
-			a.mbb.Join(&mbb)
+		if Line(x1, y1, x2, y2, a.Shape, a.Thickness).Contains(pt) {
+			return true
 		}
 	}
-
-	return *a.mbb
+	return false
 }
 
 // CircleT represents a circle and satisfies the Primitive interface.
@@ -195,9 +310,10 @@ func Circle(center Pt, thickness float64) *CircleT {
 
 // WriteGerber writes the primitive to the Gerber file.
 func (c *CircleT) WriteGerber(w io.Writer, apertureIndex int) error {
-	fmt.Fprintf(w, "G54D%d*\n", apertureIndex)
-	fmt.Fprintf(w, "X%06dY%06dD02*\n", int(0.5+sf*(c.pt[0])), int(0.5+sf*(c.pt[1])))
-	fmt.Fprintf(w, "X%06dY%06dD01*\n", int(0.5+sf*(c.pt[0])), int(0.5+sf*(c.pt[1])))
+	gw := toWriter(w)
+	gw.aperture(apertureIndex)
+	gw.move(c.pt[0], c.pt[1])
+	gw.draw(c.pt[0], c.pt[1])
 	return nil
 }
 
@@ -220,6 +336,21 @@ func (c *CircleT) MBB() MBB {
 	return *c.mbb
 }
 
+// Transform returns a transformed copy of the circle.
+func (c *CircleT) Transform(t Transform) Primitive {
+	return Circle(t.Apply(c.pt), t.scaleFactor()*c.thickness)
+}
+
+// Expand returns a copy of the circle with its diameter grown by 2*delta.
+func (c *CircleT) Expand(delta float64) Primitive {
+	return Circle(c.pt, expandSize(c.thickness, delta))
+}
+
+// Contains reports whether pt lies within the circle.
+func (c *CircleT) Contains(pt Pt) bool {
+	return math.Hypot(pt[0]-c.pt[0], pt[1]-c.pt[1]) <= 0.5*c.thickness
+}
+
 // LineT represents a line and satisfies the Primitive interface.
 type LineT struct {
 	P1, P2    Pt
@@ -243,9 +374,10 @@ func Line(x1, y1, x2, y2 float64, shape Shape, thickness float64) *LineT {
 
 // WriteGerber writes the primitive to the Gerber file.
 func (l *LineT) WriteGerber(w io.Writer, apertureIndex int) error {
-	fmt.Fprintf(w, "G54D%d*\n", apertureIndex)
-	fmt.Fprintf(w, "X%06dY%06dD02*\n", int(0.5+sf*(l.P1[0])), int(0.5+sf*(l.P1[1])))
-	fmt.Fprintf(w, "X%06dY%06dD01*\n", int(0.5+sf*(l.P2[0])), int(0.5+sf*(l.P2[1])))
+	gw := toWriter(w)
+	gw.aperture(apertureIndex)
+	gw.move(l.P1[0], l.P1[1])
+	gw.draw(l.P2[0], l.P2[1])
 	return nil
 }
 
@@ -270,6 +402,39 @@ func (l *LineT) MBB() MBB {
 	return *l.mbb
 }
 
+func (l *LineT) invalidateBounds() { l.mbb = nil }
+
+// Transform returns a transformed copy of the line.
+func (l *LineT) Transform(t Transform) Primitive {
+	p1, p2 := t.Apply(l.P1), t.Apply(l.P2)
+	return Line(p1[0], p1[1], p2[0], p2[1], l.Shape, t.scaleFactor()*l.Thickness)
+}
+
+// Expand returns a copy of the line with its thickness grown by 2*delta.
+// Rectangular lines grow by delta at each end as well.
+func (l *LineT) Expand(delta float64) Primitive {
+	return Line(l.P1[0], l.P1[1], l.P2[0], l.P2[1], l.Shape, expandSize(l.Thickness, delta))
+}
+
+// Contains reports whether pt lies within the stroked line.
+func (l *LineT) Contains(pt Pt) bool {
+	r := 0.5 * l.Thickness
+	if l.Shape == CircleShape {
+		d, _ := segmentDistance(pt, l.P1, l.P2)
+		return d <= r
+	}
+	// Rectangular apertures extend the stroke by r beyond each endpoint.
+	dx, dy := l.P2[0]-l.P1[0], l.P2[1]-l.P1[1]
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		return math.Abs(pt[0]-l.P1[0]) <= r && math.Abs(pt[1]-l.P1[1]) <= r
+	}
+	ux, uy := dx/length, dy/length
+	px, py := pt[0]-l.P1[0], pt[1]-l.P1[1]
+	along, across := px*ux+py*uy, -px*uy+py*ux
+	return along >= -r && along <= length+r && math.Abs(across) <= r
+}
+
 // PolygonT represents a polygon and satisfies the Primitive interface.
 type PolygonT struct {
 	Offset Pt
@@ -288,16 +453,18 @@ func Polygon(offset Pt, filled bool, points []Pt, thickness float64) *PolygonT {
 
 // WriteGerber writes the primitive to the Gerber file.
 func (p *PolygonT) WriteGerber(w io.Writer, apertureIndex int) error {
-	io.WriteString(w, "G54D11*\n")
+	gw := toWriter(w)
+	gw.aperture(apertureIndex)
 	io.WriteString(w, "G36*\n")
 	for i, pt := range p.Points {
 		if i == 0 {
-			fmt.Fprintf(w, "X%06dY%06dD02*\n", int(0.5+sf*(pt[0]+p.Offset[0])), int(0.5+sf*(pt[1]+p.Offset[1])))
+			gw.move(pt[0]+p.Offset[0], pt[1]+p.Offset[1])
 			continue
 		}
-		fmt.Fprintf(w, "X%06dY%06dD01*\n", int(0.5+sf*(pt[0]+p.Offset[0])), int(0.5+sf*(pt[1]+p.Offset[1])))
+		gw.draw(pt[0]+p.Offset[0], pt[1]+p.Offset[1])
 	}
-	fmt.Fprintf(w, "X%06dY%06dD02*\n", int(0.5+sf*(p.Points[0][0]+p.Offset[0])), int(0.5+sf*(p.Points[0][1]+p.Offset[1])))
+	// Contours must be closed with a draw back to their start point.
+	gw.draw(p.Points[0][0]+p.Offset[0], p.Points[0][1]+p.Offset[1])
 	io.WriteString(w, "G37*\n")
 	return nil
 }
@@ -323,3 +490,29 @@ func (p *PolygonT) MBB() MBB {
 
 	return *p.mbb
 }
+
+func (p *PolygonT) invalidateBounds() { p.mbb = nil }
+
+// Transform returns a transformed copy of the polygon (with a zero offset).
+func (p *PolygonT) Transform(t Transform) Primitive {
+	pts := make([]Pt, 0, len(p.Points))
+	for _, pt := range p.Points {
+		pts = append(pts, t.Apply(Pt{pt[0] + p.Offset[0], pt[1] + p.Offset[1]}))
+	}
+	return Polygon(Pt{0, 0}, true, pts, 0)
+}
+
+// Expand returns a copy of the polygon (with a zero offset) whose edges
+// are moved outward by delta (or inward, if delta is negative).
+func (p *PolygonT) Expand(delta float64) Primitive {
+	pts := make([]Pt, 0, len(p.Points))
+	for _, pt := range p.Points {
+		pts = append(pts, Pt{pt[0] + p.Offset[0], pt[1] + p.Offset[1]})
+	}
+	return Polygon(Pt{0, 0}, true, polyclip.OffsetPolygon(pts, delta), 0)
+}
+
+// Contains reports whether pt lies within the polygon.
+func (p *PolygonT) Contains(pt Pt) bool {
+	return pointInPolygon(Pt{pt[0] - p.Offset[0], pt[1] - p.Offset[1]}, p.Points)
+}
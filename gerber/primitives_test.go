@@ -78,6 +78,21 @@ func TestArcT_MBB(t *testing.T) {
 			p:    Arc(Pt{10, 20}, 10, CircleShape, 1, 1, 270, 360, 2),
 			want: MBB{Min: Pt{9, 9}, Max: Pt{21, 21}},
 		},
+		{
+			name: "arc crossing the Y axis",
+			p:    Arc(Pt{0, 0}, 100, CircleShape, 1, 1, 45, 135, 2),
+			want: MBB{Min: Pt{-71.711, 69.711}, Max: Pt{71.711, 101}},
+		},
+		{
+			name: "elliptical arc crossing the X axis",
+			p:    Arc(Pt{0, 0}, 100, RectShape, 2, 1, -30, 30, 0),
+			want: MBB{Min: Pt{173.205, -50}, Max: Pt{200, 50}},
+		},
+		{
+			name: "arc crossing three axes",
+			p:    Arc(Pt{0, 0}, 100, CircleShape, 1, 1, 315, 585, 0),
+			want: MBB{Min: Pt{-100, -70.711}, Max: Pt{100, 100}},
+		},
 	}
 
 	for _, tt := range tests {
@@ -99,6 +114,47 @@ func TestArcT_MBB(t *testing.T) {
 	}
 }
 
+func TestArcT_segments(t *testing.T) {
+	tests := []struct {
+		name string
+		p    *ArcT
+		tol  float64
+		want int
+	}{
+		{
+			name: "default 0.1mm resolution",
+			p:    Arc(Pt{0, 0}, 10, CircleShape, 1, 1, 0, 360, 0),
+			want: 629,
+		},
+		{
+			name: "design tolerance",
+			p:    Arc(Pt{0, 0}, 10, CircleShape, 1, 1, 0, 360, 0),
+			tol:  0.01,
+			want: 71,
+		},
+		{
+			name: "arc tolerance takes precedence",
+			p:    &ArcT{Radius: 10, XScale: 1, YScale: 1, EndAngle: 2 * math.Pi, Tolerance: 0.01},
+			tol:  1,
+			want: 71,
+		},
+		{
+			name: "tolerance larger than radius",
+			p:    Arc(Pt{0, 0}, 1, CircleShape, 1, 1, 0, 90, 0),
+			tol:  2,
+			want: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.p.segments(tt.tol); got != tt.want {
+				t.Errorf("segments(%v) = %v, want %v", tt.tol, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestCircleT_Primitive(t *testing.T) {
 	var p Primitive = &CircleT{}
 	if p == nil {
@@ -0,0 +1,78 @@
+package gerber
+
+import (
+	"context"
+	"sync"
+)
+
+// ProgressFunc is called as the primitives of a design are written,
+// with the number of primitives written so far and the total (see
+// WithProgress).
+type ProgressFunc func(done, total int)
+
+// WithProgress sets a function that Write (and WriteGerber, WriteZip,
+// etc.) calls to report its progress through the primitives of all the
+// layers: once with done == 0 before anything is written, about every
+// thousand primitives, and with done == total once the last layer is
+// written. Writing a single layer (see Layer.WriteGerber) reports the
+// progress through that layer. The calls are never concurrent (even
+// with WithParallelWrites) and done never decreases, so fn may update
+// a progress bar (or cancel the context of WriteContext) directly. As
+// with export passes, fn is not saved by SaveJSON.
+func WithProgress(fn ProgressFunc) Option {
+	return func(g *Gerber) {
+		g.progress = fn
+	}
+}
+
+// progress tracks the primitives written by a write of one or more
+// layers, for the design's ProgressFunc.
+type progress struct {
+	fn ProgressFunc
+
+	mu          sync.Mutex // serializes the calls of fn
+	done, total int
+}
+
+// progressKey is the context key of the progress of a write.
+type progressKey struct{}
+
+// withProgress returns ctx with a new progress through the primitives
+// of layers, reported to fn (with done == 0), or ctx itself if fn is nil.
+func withProgress(ctx context.Context, fn ProgressFunc, layers []*Layer) context.Context {
+	if fn == nil {
+		return ctx
+	}
+	pr := &progress{fn: fn}
+	for _, layer := range layers {
+		pr.total += len(layer.Primitives)
+	}
+	fn(0, pr.total)
+	return context.WithValue(ctx, progressKey{}, pr)
+}
+
+// layerProgress returns the progress of the write of the layer: that of
+// the write of its design in ctx, if any, or a new one through the
+// layer (nil if its design has no ProgressFunc).
+func (l *Layer) layerProgress(ctx context.Context) *progress {
+	if pr, ok := ctx.Value(progressKey{}).(*progress); ok {
+		return pr
+	}
+	if l.g == nil || l.g.progress == nil {
+		return nil
+	}
+	pr := &progress{fn: l.g.progress, total: len(l.Primitives)}
+	pr.fn(0, pr.total)
+	return pr
+}
+
+// add reports that n more primitives have been written.
+func (pr *progress) add(n int) {
+	if pr == nil || n <= 0 {
+		return
+	}
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	pr.done += n
+	pr.fn(pr.done, pr.total)
+}
@@ -0,0 +1,685 @@
+package gerber
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+func init() {
+	registerPrimitive("qrCode", func() Primitive { return &QRCodeT{} })
+}
+
+// QRLevel is the error correction level of a QR code: the fraction of
+// its codewords that may be damaged (e.g. by a via or a scratch) while
+// it remains readable.
+type QRLevel int
+
+const (
+	// QRLevelM restores about 15% of the codewords (the default).
+	QRLevelM QRLevel = iota
+	// QRLevelL restores about 7% of the codewords.
+	QRLevelL
+	// QRLevelQ restores about 25% of the codewords.
+	QRLevelQ
+	// QRLevelH restores about 30% of the codewords.
+	QRLevelH
+)
+
+// qrQuietZone is the width (in modules) of the light margin that must
+// surround a QR code.
+const qrQuietZone = 4
+
+// QRCodeT represents a QR code (such as a serial number or a URL) and
+// satisfies the Primitive interface. Its Content is encoded in byte
+// mode in the smallest version (21x21 to 177x177 modules) that holds
+// it, and its dark modules are flashed as squares or (if Region is set)
+// written as a single region.
+//
+// The code is drawn on whichever layer it is added to: in copper, in
+// silkscreen, or as openings in a solder mask layer. Scanners expect
+// dark modules on a light background, so Invert should be set where
+// the drawn objects are lighter than their surroundings (such as white
+// silkscreen on a dark solder mask, or mask openings exposing a copper
+// fill). Otherwise, the quiet zone (a margin of four modules around the
+// code) must be kept clear of other artwork.
+type QRCodeT struct {
+	// Center is the center of the code and Size the length of its
+	// sides (excluding the quiet zone), in millimeters.
+	Center Pt
+	Size   float64
+	// Content is the text or data encoded by the code.
+	Content string
+	Level   QRLevel `json:",omitempty"`
+	// Rotation rotates the code counterclockwise by degrees about its
+	// center, after mirroring it about its horizontal axis if Mirror is
+	// set (e.g. to be read from the bottom side of the board).
+	Rotation float64 `json:",omitempty"`
+	Mirror   bool    `json:",omitempty"`
+	// Region writes the dark modules as a single region rather than
+	// flashing each one.
+	Region bool `json:",omitempty"`
+	// Invert draws the light modules and the quiet zone rather than
+	// the dark modules.
+	Invert bool `json:",omitempty"`
+
+	// modules caches the matrix of the content and level in key.
+	modules [][]bool
+	key     string
+}
+
+// QRCode returns a QR code of the given size (in millimeters) centered
+// on (x, y) that encodes content, with the default (M) level of error
+// correction.
+func QRCode(x, y, size float64, content string) *QRCodeT {
+	return &QRCodeT{Center: Pt{x, y}, Size: size, Content: content}
+}
+
+// Modules returns the matrix of the code's modules (true for dark),
+// indexed by row (from the top) and column, excluding the quiet zone.
+// It returns an error if the content is too long for any version of
+// QR code at the code's level of error correction.
+func (q *QRCodeT) Modules() ([][]bool, error) {
+	key := fmt.Sprintf("%v:%v", q.Level, q.Content)
+	if q.modules == nil || q.key != key {
+		m, err := encodeQR([]byte(q.Content), q.Level)
+		if err != nil {
+			return nil, err
+		}
+		q.modules, q.key = m, key
+	}
+	return q.modules, nil
+}
+
+// cells returns the drawn cells (dark modules, or light modules and the
+// quiet zone if Invert is set) as rows of booleans, along with the
+// number of modules of the margin around the matrix that they include.
+func (q *QRCodeT) cells() ([][]bool, int, error) {
+	modules, err := q.Modules()
+	if err != nil {
+		return nil, 0, err
+	}
+	if !q.Invert {
+		return modules, 0, nil
+	}
+	n := len(modules) + 2*qrQuietZone
+	cells := make([][]bool, n)
+	for r := range cells {
+		cells[r] = make([]bool, n)
+		for c := range cells[r] {
+			mr, mc := r-qrQuietZone, c-qrQuietZone
+			inside := mr >= 0 && mr < len(modules) && mc >= 0 && mc < len(modules)
+			cells[r][c] = !inside || !modules[mr][mc]
+		}
+	}
+	return cells, qrQuietZone, nil
+}
+
+// moduleSize returns the side of a module in millimeters.
+func (q *QRCodeT) moduleSize() float64 {
+	modules, err := q.Modules()
+	if err != nil || len(modules) == 0 {
+		return 0
+	}
+	return q.Size / float64(len(modules))
+}
+
+// point returns the position of the point at column u and row v of the
+// matrix (in modules, from its top left corner), where margin is the
+// number of modules of quiet zone included before the matrix.
+func (q *QRCodeT) point(u, v float64, margin int) Pt {
+	m := q.moduleSize()
+	local := Pt{(u-float64(margin))*m - 0.5*q.Size, 0.5*q.Size - (v-float64(margin))*m}
+	if q.Mirror {
+		local[1] = -local[1]
+	}
+	local = RotatePt(local, Pt{}, q.Rotation)
+	return Pt{q.Center[0] + local[0], q.Center[1] + local[1]}
+}
+
+// WriteGerber writes the primitive to the Gerber file.
+func (q *QRCodeT) WriteGerber(w io.Writer, apertureIndex int) error {
+	if !(q.Size > 0) {
+		return fmt.Errorf("QR code of size %v", fmtFloat(q.Size))
+	}
+	cells, margin, err := q.cells()
+	if err != nil {
+		return err
+	}
+	gw := toWriter(w)
+	gw.aperture(apertureIndex)
+	if !q.Region {
+		for r, row := range cells {
+			for c, dark := range row {
+				if dark {
+					pt := q.point(float64(c)+0.5, float64(r)+0.5, margin)
+					gw.flash(pt[0], pt[1])
+				}
+			}
+		}
+		return nil
+	}
+	// Each horizontal run of cells is a contour of the region.
+	io.WriteString(gw, "G36*\n")
+	for r, row := range cells {
+		for c := 0; c < len(row); c++ {
+			if !row[c] {
+				continue
+			}
+			end := c
+			for end < len(row) && row[end] {
+				end++
+			}
+			corners := []Pt{
+				q.point(float64(c), float64(r+1), margin),
+				q.point(float64(end), float64(r+1), margin),
+				q.point(float64(end), float64(r), margin),
+				q.point(float64(c), float64(r), margin),
+			}
+			gw.move(corners[0][0], corners[0][1])
+			for _, pt := range append(corners[1:], corners[0]) {
+				gw.draw(pt[0], pt[1])
+			}
+			c = end
+		}
+	}
+	io.WriteString(gw, "G37*\n")
+	return nil
+}
+
+// Aperture returns the square aperture flashed for each module, or nil
+// if the code is written as a region (or cannot be encoded).
+func (q *QRCodeT) Aperture() *Aperture {
+	m := q.moduleSize()
+	if q.Region || !(m > 0) {
+		return nil
+	}
+	return Pad(Pt{}, RectShape, m, m, q.Rotation).Aperture()
+}
+
+// MBB returns the minimum bounding box in millimeters, including the
+// quiet zone if Invert is set.
+func (q *QRCodeT) MBB() MBB {
+	cells, margin, err := q.cells()
+	if err != nil || q.moduleSize() == 0 {
+		return MBB{Min: q.Center, Max: q.Center}
+	}
+	n := float64(len(cells))
+	var mbb MBB
+	for i, uv := range []Pt{{0, 0}, {n, 0}, {n, n}, {0, n}} {
+		pt := q.point(uv[0], uv[1], margin)
+		v := &MBB{Min: pt, Max: pt}
+		if i == 0 {
+			mbb = *v
+			continue
+		}
+		mbb.Join(v)
+	}
+	return mbb
+}
+
+// Contains reports whether pt lies within a drawn module of the code.
+func (q *QRCodeT) Contains(pt Pt) bool {
+	cells, margin, err := q.cells()
+	m := q.moduleSize()
+	if err != nil || m == 0 {
+		return false
+	}
+	local := RotatePt(Pt{pt[0] - q.Center[0], pt[1] - q.Center[1]}, Pt{}, -q.Rotation)
+	if q.Mirror {
+		local[1] = -local[1]
+	}
+	c := int(math.Floor((local[0]+0.5*q.Size)/m)) + margin
+	r := int(math.Floor((0.5*q.Size-local[1])/m)) + margin
+	return r >= 0 && r < len(cells) && c >= 0 && c < len(cells) && cells[r][c]
+}
+
+// Transform returns a transformed copy of the code.
+func (q *QRCodeT) Transform(t Transform) Primitive {
+	nq := *q
+	nq.Center = t.Apply(q.Center)
+	nq.Size = t.scaleFactor() * q.Size
+	nq.Rotation = NormalizeAngle(q.Rotation + Degrees(t.rotation()))
+	if t.det() < 0 {
+		nq.Rotation = NormalizeAngle(Degrees(t.rotation()) - q.Rotation)
+		nq.Mirror = !q.Mirror
+	}
+	return &nq
+}
+
+func (q *QRCodeT) String() string {
+	return fmt.Sprintf("QRCode(%v, %v, %q)", fmtPt(q.Center), fmtFloat(q.Size), q.Content)
+}
+
+// The error correction codewords per block and the number of blocks of
+// each version (1 to 40), indexed by QRLevel.
+var (
+	qrECCodewords = [4][41]int{
+		QRLevelM: {0, 10, 16, 26, 18, 24, 16, 18, 22, 22, 26, 30, 22, 22, 24, 24, 28, 28, 26, 26, 26, 26, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28},
+		QRLevelL: {0, 7, 10, 15, 20, 26, 18, 20, 24, 30, 18, 20, 24, 26, 30, 22, 24, 28, 30, 28, 28, 28, 28, 30, 30, 26, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+		QRLevelQ: {0, 13, 22, 18, 26, 18, 24, 18, 22, 20, 24, 28, 26, 24, 20, 30, 24, 28, 28, 26, 30, 28, 30, 30, 30, 30, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+		QRLevelH: {0, 17, 28, 22, 16, 22, 28, 26, 26, 24, 28, 24, 28, 22, 24, 24, 30, 28, 28, 26, 28, 30, 24, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	}
+	qrBlocks = [4][41]int{
+		QRLevelM: {0, 1, 1, 1, 2, 2, 4, 4, 4, 5, 5, 5, 8, 9, 9, 10, 10, 11, 13, 14, 16, 17, 17, 18, 20, 21, 23, 25, 26, 28, 29, 31, 33, 35, 37, 38, 40, 43, 45, 47, 49},
+		QRLevelL: {0, 1, 1, 1, 1, 1, 2, 2, 2, 2, 4, 4, 4, 4, 4, 6, 6, 6, 6, 7, 8, 8, 9, 9, 10, 12, 12, 12, 13, 14, 15, 16, 17, 18, 19, 19, 20, 21, 22, 24, 25},
+		QRLevelQ: {0, 1, 1, 2, 2, 4, 4, 6, 6, 8, 8, 8, 10, 12, 16, 12, 17, 16, 18, 21, 20, 23, 23, 25, 27, 29, 34, 34, 35, 38, 40, 43, 45, 48, 51, 53, 56, 59, 62, 65, 68},
+		QRLevelH: {0, 1, 1, 2, 4, 4, 4, 5, 6, 8, 8, 11, 11, 16, 16, 18, 16, 19, 21, 25, 25, 25, 34, 30, 32, 35, 37, 40, 42, 45, 48, 51, 54, 57, 60, 63, 66, 70, 74, 77, 81},
+	}
+	// qrFormatLevel is the level's two bits of the format information.
+	qrFormatLevel = [4]int{QRLevelM: 0, QRLevelL: 1, QRLevelQ: 3, QRLevelH: 2}
+)
+
+// qrRawModules returns the number of modules of a version that hold
+// codewords (rather than function patterns).
+func qrRawModules(version int) int {
+	n := (16*version+128)*version + 64
+	if version >= 2 {
+		align := version/7 + 2
+		n -= (25*align-10)*align - 55
+		if version >= 7 {
+			n -= 36
+		}
+	}
+	return n
+}
+
+// qrDataCodewords returns the number of data codewords of a version.
+func qrDataCodewords(version int, level QRLevel) int {
+	return qrRawModules(version)/8 - qrECCodewords[level][version]*qrBlocks[level][version]
+}
+
+// encodeQR returns the module matrix of the smallest QR code that
+// encodes data in byte mode.
+func encodeQR(data []byte, level QRLevel) ([][]bool, error) {
+	if level < QRLevelM || level > QRLevelH {
+		return nil, fmt.Errorf("invalid QR code level %v", level)
+	}
+	version := 1
+	for ; version <= 40; version++ {
+		countBits := 8
+		if version >= 10 {
+			countBits = 16
+		}
+		if 4+countBits+8*len(data) <= 8*qrDataCodewords(version, level) {
+			break
+		}
+	}
+	if version > 40 {
+		return nil, errors.New("QR code content is too long")
+	}
+
+	// The segment: the byte mode indicator, the count, and the data.
+	var bits qrBits
+	bits.append(4, 4)
+	if version >= 10 {
+		bits.append(len(data), 16)
+	} else {
+		bits.append(len(data), 8)
+	}
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+	capacity := 8 * qrDataCodewords(version, level)
+	bits.append(0, min(4, capacity-len(bits))) // terminator
+	bits.append(0, (8-len(bits)%8)%8)
+	for pad := 0xEC; len(bits) < capacity; pad ^= 0xEC ^ 0x11 {
+		bits.append(pad, 8)
+	}
+	codewords := make([]byte, len(bits)/8)
+	for i, bit := range bits {
+		if bit {
+			codewords[i/8] |= 0x80 >> uint(i%8)
+		}
+	}
+
+	qr := newQRMatrix(version)
+	qr.drawCodewords(qrInterleave(codewords, version, level))
+	best, bestPenalty := -1, 0
+	for mask := 0; mask < 8; mask++ {
+		qr.applyMask(mask)
+		qr.drawFormat(level, mask)
+		if p := qr.penalty(); best < 0 || p < bestPenalty {
+			best, bestPenalty = mask, p
+		}
+		qr.applyMask(mask) // masks are their own inverse
+	}
+	qr.applyMask(best)
+	qr.drawFormat(level, best)
+	return qr.modules, nil
+}
+
+// qrBits is a bit stream, most significant bit first.
+type qrBits []bool
+
+// append appends the n low bits of v.
+func (b *qrBits) append(v, n int) {
+	for i := n - 1; i >= 0; i-- {
+		*b = append(*b, v>>uint(i)&1 != 0)
+	}
+}
+
+// qrInterleave splits the data codewords into blocks, appends their
+// error correction codewords, and interleaves the blocks.
+func qrInterleave(data []byte, version int, level QRLevel) []byte {
+	numBlocks, ecLen := qrBlocks[level][version], qrECCodewords[level][version]
+	raw := qrRawModules(version) / 8
+	numShort := numBlocks - raw%numBlocks
+	shortLen := raw / numBlocks
+	divisor := rsDivisor(ecLen)
+	var blocks [][]byte
+	for i, k := 0, 0; i < numBlocks; i++ {
+		n := shortLen - ecLen
+		if i >= numShort {
+			n++
+		}
+		block := append([]byte{}, data[k:k+n]...)
+		k += n
+		ec := rsRemainder(block, divisor)
+		if i < numShort {
+			block = append(block, 0) // aligns the short blocks' codewords
+		}
+		blocks = append(blocks, append(block, ec...))
+	}
+	var result []byte
+	for i := 0; i <= shortLen; i++ {
+		for j, block := range blocks {
+			if i != shortLen-ecLen || j >= numShort {
+				result = append(result, block[i])
+			}
+		}
+	}
+	return result
+}
+
+// gfMul returns the product of x and y in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1.
+func gfMul(x, y byte) byte {
+	var z int
+	for i := 7; i >= 0; i-- {
+		z = z<<1 ^ (z>>7)*0x11D
+		z ^= int(y>>uint(i)&1) * int(x)
+	}
+	return byte(z)
+}
+
+// rsDivisor returns the coefficients (highest first, excluding the
+// leading 1) of the Reed-Solomon generator polynomial of the degree.
+func rsDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = gfMul(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMul(root, 2)
+	}
+	return result
+}
+
+// rsRemainder returns the Reed-Solomon error correction codewords of data.
+func rsRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, d := range divisor {
+			result[i] ^= gfMul(d, factor)
+		}
+	}
+	return result
+}
+
+// qrMatrix is the module matrix of a QR code under construction, with
+// the modules of its function patterns (which are not masked) marked.
+type qrMatrix struct {
+	size     int
+	modules  [][]bool
+	function [][]bool
+}
+
+// newQRMatrix returns the matrix of a version with its function
+// patterns drawn (and its format information reserved).
+func newQRMatrix(version int) *qrMatrix {
+	size := 4*version + 17
+	qr := &qrMatrix{size: size}
+	for i := 0; i < size; i++ {
+		qr.modules = append(qr.modules, make([]bool, size))
+		qr.function = append(qr.function, make([]bool, size))
+	}
+	for i := 0; i < size; i++ {
+		qr.set(6, i, i%2 == 0) // timing patterns
+		qr.set(i, 6, i%2 == 0)
+	}
+	for _, c := range [][2]int{{3, 3}, {size - 4, 3}, {3, size - 4}} {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				x, y := c[0]+dx, c[1]+dy
+				if d := maxAbs(dx, dy); x >= 0 && x < size && y >= 0 && y < size {
+					qr.set(x, y, d != 2 && d != 4)
+				}
+			}
+		}
+	}
+	if version >= 2 {
+		align := version/7 + 2
+		step := (version*8 + align*3 + 5) / (align*4 - 4) * 2
+		pos := []int{6}
+		for p := size - 7; len(pos) < align; p -= step {
+			pos = append([]int{6}, append([]int{p}, pos[1:]...)...)
+		}
+		for i, x := range pos {
+			for j, y := range pos {
+				if i == 0 && j == 0 || i == 0 && j == align-1 || i == align-1 && j == 0 {
+					continue // the finder patterns
+				}
+				for dy := -2; dy <= 2; dy++ {
+					for dx := -2; dx <= 2; dx++ {
+						qr.set(x+dx, y+dy, maxAbs(dx, dy) != 1)
+					}
+				}
+			}
+		}
+	}
+	qr.drawFormat(0, 0)
+	if version >= 7 {
+		rem := version
+		for i := 0; i < 12; i++ {
+			rem = rem<<1 ^ (rem>>11)*0x1F25
+		}
+		bits := version<<12 | rem
+		for i := 0; i < 18; i++ {
+			a, b := size-11+i%3, i/3
+			qr.set(a, b, bits>>uint(i)&1 != 0)
+			qr.set(b, a, bits>>uint(i)&1 != 0)
+		}
+	}
+	return qr
+}
+
+func maxAbs(a, b int) int {
+	if a < 0 {
+		a = -a
+	}
+	if b < 0 {
+		b = -b
+	}
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// set sets the function module at column x and row y.
+func (qr *qrMatrix) set(x, y int, dark bool) {
+	qr.modules[y][x] = dark
+	qr.function[y][x] = true
+}
+
+// qrFormatBits returns the 15 bits of the format information.
+func qrFormatBits(level QRLevel, mask int) int {
+	data := qrFormatLevel[level]<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	return (data<<10 | rem) ^ 0x5412
+}
+
+// drawFormat draws both copies of the format information.
+func (qr *qrMatrix) drawFormat(level QRLevel, mask int) {
+	bits := qrFormatBits(level, mask)
+	bit := func(i int) bool { return bits>>uint(i)&1 != 0 }
+	for i := 0; i <= 5; i++ {
+		qr.set(8, i, bit(i))
+	}
+	qr.set(8, 7, bit(6))
+	qr.set(8, 8, bit(7))
+	qr.set(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		qr.set(14-i, 8, bit(i))
+	}
+	for i := 0; i < 8; i++ {
+		qr.set(qr.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		qr.set(8, qr.size-15+i, bit(i))
+	}
+	qr.set(8, qr.size-8, true) // the dark module
+}
+
+// drawCodewords places the codewords in the zigzag order of the
+// specification, two columns at a time from the bottom right.
+func (qr *qrMatrix) drawCodewords(data []byte) {
+	i := 0
+	for right := qr.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5 // skips the vertical timing pattern
+		}
+		for vert := 0; vert < qr.size; vert++ {
+			for j := 0; j < 2; j++ {
+				x, y := right-j, vert
+				if (right+1)&2 == 0 {
+					y = qr.size - 1 - vert // upward
+				}
+				if !qr.function[y][x] && i < 8*len(data) {
+					qr.modules[y][x] = data[i/8]>>uint(7-i%8)&1 != 0
+					i++
+				}
+			}
+		}
+	}
+}
+
+// applyMask inverts the data modules selected by the mask pattern.
+func (qr *qrMatrix) applyMask(mask int) {
+	for y := 0; y < qr.size; y++ {
+		for x := 0; x < qr.size; x++ {
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert && !qr.function[y][x] {
+				qr.modules[y][x] = !qr.modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty returns the penalty score of the matrix, by which the mask
+// that is easiest to scan is chosen: for runs of five or more modules
+// of the same color, 2x2 blocks of the same color, patterns resembling
+// the finder patterns, and an imbalance of dark and light modules.
+func (qr *qrMatrix) penalty() int {
+	n := qr.size
+	at := func(x, y int, transpose bool) bool {
+		if transpose {
+			return qr.modules[x][y]
+		}
+		return qr.modules[y][x]
+	}
+	finder := []bool{true, false, true, true, true, false, true}
+	var result, dark int
+	for _, transpose := range []bool{false, true} {
+		for y := 0; y < n; y++ {
+			run := 1
+			for x := 1; x <= n; x++ {
+				if x < n && at(x, y, transpose) == at(x-1, y, transpose) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					result += run - 2
+				}
+				run = 1
+			}
+			for x := 0; x+7 <= n; x++ {
+				match := true
+				for k, v := range finder {
+					if at(x+k, y, transpose) != v {
+						match = false
+						break
+					}
+				}
+				if match && (qrLight(qr, x-4, x, y, transpose) || qrLight(qr, x+7, x+11, y, transpose)) {
+					result += 40
+				}
+			}
+		}
+	}
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			if qr.modules[y][x] {
+				dark++
+			}
+			if x+1 < n && y+1 < n {
+				c := qr.modules[y][x]
+				if qr.modules[y][x+1] == c && qr.modules[y+1][x] == c && qr.modules[y+1][x+1] == c {
+					result += 3
+				}
+			}
+		}
+	}
+	// 10 points for each 5% that the dark modules deviate from half.
+	total := n * n
+	deviation := 20*dark - 10*total
+	if deviation < 0 {
+		deviation = -deviation
+	}
+	return result + 10*((deviation+total-1)/total-1)
+}
+
+// qrLight reports whether the modules from a to b (exclusive) of a row
+// (or column, if transpose is set) are light, counting modules outside
+// the matrix (in the quiet zone) as light.
+func qrLight(qr *qrMatrix, a, b, y int, transpose bool) bool {
+	for x := a; x < b; x++ {
+		if x < 0 || x >= qr.size {
+			continue
+		}
+		if transpose && qr.modules[x][y] || !transpose && qr.modules[y][x] {
+			return false
+		}
+	}
+	return true
+}
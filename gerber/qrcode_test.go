@@ -0,0 +1,276 @@
+package gerber
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestQRCodeTables(t *testing.T) {
+	tests := []struct {
+		version int
+		level   QRLevel
+		want    int
+	}{
+		{version: 1, level: QRLevelL, want: 19},
+		{version: 1, level: QRLevelM, want: 16},
+		{version: 1, level: QRLevelQ, want: 13},
+		{version: 1, level: QRLevelH, want: 9},
+		{version: 7, level: QRLevelM, want: 124},
+		{version: 10, level: QRLevelQ, want: 154},
+		{version: 40, level: QRLevelL, want: 2956},
+		{version: 40, level: QRLevelH, want: 1276},
+	}
+	for _, tt := range tests {
+		if got := qrDataCodewords(tt.version, tt.level); got != tt.want {
+			t.Errorf("qrDataCodewords(%v, %v) = %v, want %v", tt.version, tt.level, got, tt.want)
+		}
+	}
+
+	if got, want := qrFormatBits(QRLevelL, 0), 0x77C4; got != want {
+		t.Errorf("qrFormatBits(L, 0) = %#x, want %#x", got, want)
+	}
+	if got, want := qrFormatBits(QRLevelH, 7), 0x083B; got != want {
+		t.Errorf("qrFormatBits(H, 7) = %#x, want %#x", got, want)
+	}
+
+	// The "HELLO WORLD" example (version 1-M) of the specification.
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	if got := rsRemainder(data, rsDivisor(10)); !bytes.Equal(got, want) {
+		t.Errorf("rsRemainder = %v, want %v", got, want)
+	}
+}
+
+// decodeQR reads the byte mode content of a QR code matrix, checking
+// its format information and error correction codewords.
+func decodeQR(t *testing.T, modules [][]bool, level QRLevel) string {
+	t.Helper()
+	size := len(modules)
+	version := (size - 17) / 4
+	var format int
+	for i := 14; i >= 0; i-- {
+		// The second copy of the format information.
+		var dark bool
+		if i < 8 {
+			dark = modules[8][size-1-i]
+		} else {
+			dark = modules[size-15+i][8]
+		}
+		format <<= 1
+		if dark {
+			format |= 1
+		}
+	}
+	mask := -1
+	for m := 0; m < 8; m++ {
+		if qrFormatBits(level, m) == format {
+			mask = m
+		}
+	}
+	if mask < 0 {
+		t.Fatalf("format bits %#x do not match level %v", format, level)
+	}
+
+	qr := newQRMatrix(version)
+	for y := range modules {
+		copy(qr.modules[y], modules[y])
+	}
+	qr.applyMask(mask)
+	var bits qrBits
+	for right := size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < size; vert++ {
+			for j := 0; j < 2; j++ {
+				x, y := right-j, vert
+				if (right+1)&2 == 0 {
+					y = size - 1 - vert
+				}
+				if !qr.function[y][x] {
+					bits = append(bits, qr.modules[y][x])
+				}
+			}
+		}
+	}
+	codewords := make([]byte, qrRawModules(version)/8)
+	for i := range codewords {
+		for k := 0; k < 8; k++ {
+			if bits[8*i+k] {
+				codewords[i] |= 0x80 >> uint(k)
+			}
+		}
+	}
+
+	// Deinterleave the blocks and check their error correction.
+	numBlocks, ecLen := qrBlocks[level][version], qrECCodewords[level][version]
+	numShort := numBlocks - len(codewords)%numBlocks
+	shortLen := len(codewords) / numBlocks
+	blocks := make([][]byte, numBlocks)
+	k := 0
+	for i := 0; i <= shortLen; i++ {
+		for j := range blocks {
+			if i == shortLen-ecLen && j < numShort {
+				continue
+			}
+			blocks[j] = append(blocks[j], codewords[k])
+			k++
+		}
+	}
+	var data []byte
+	for i, b := range blocks {
+		n := len(b) - ecLen
+		if got := rsRemainder(b[:n], rsDivisor(ecLen)); !bytes.Equal(got, b[n:]) {
+			t.Fatalf("block %v: error correction %v, want %v", i, b[n:], got)
+		}
+		data = append(data, b[:n]...)
+	}
+
+	read := func(pos, n int) int {
+		var v int
+		for i := 0; i < n; i++ {
+			v = v<<1 | int(data[(pos+i)/8]>>uint(7-(pos+i)%8)&1)
+		}
+		return v
+	}
+	if mode := read(0, 4); mode != 4 {
+		t.Fatalf("mode %v, want byte mode", mode)
+	}
+	countBits := 8
+	if version >= 10 {
+		countBits = 16
+	}
+	n := read(4, countBits)
+	var content []byte
+	for i := 0; i < n; i++ {
+		content = append(content, byte(read(4+countBits+8*i, 8)))
+	}
+	return string(content)
+}
+
+func TestQRCode_Modules(t *testing.T) {
+	tests := []struct {
+		content string
+		level   QRLevel
+		want    int
+	}{
+		{content: "SN 0001", want: 21},
+		{content: "https://github.com/gmlewis/go-gerber", level: QRLevelL, want: 29},
+		{content: "https://github.com/gmlewis/go-gerber", level: QRLevelH, want: 37},
+		{content: strings.Repeat("0123456789", 20), level: QRLevelQ, want: 65},
+		{content: strings.Repeat("x", 1000), want: 121},
+	}
+	for _, tt := range tests {
+		t.Run(tt.content[:4], func(t *testing.T) {
+			q := QRCode(0, 0, 10, tt.content)
+			q.Level = tt.level
+			modules, err := q.Modules()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(modules) != tt.want {
+				t.Errorf("size = %v, want %v", len(modules), tt.want)
+			}
+			if got := decodeQR(t, modules, tt.level); got != tt.content {
+				t.Errorf("decoded %q, want %q", got, tt.content)
+			}
+		})
+	}
+
+	if _, err := QRCode(0, 0, 10, strings.Repeat("x", 3000)).Modules(); err == nil {
+		t.Error("Modules: no error for content too long")
+	}
+}
+
+func TestQRCode(t *testing.T) {
+	const eps = 1e-9
+	q := QRCode(10, 20, 21*0.5, "SN 0001")
+	modules, err := q.Modules()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q.MBB(), (MBB{Min: Pt{4.75, 14.75}, Max: Pt{15.25, 25.25}}); !mbbNear(got, want, eps) {
+		t.Errorf("MBB = %v, want %v", got, want)
+	}
+	// The top left module is part of a finder pattern.
+	if !q.Contains(Pt{5, 25}) || q.Contains(Pt{5.5, 24.5}) {
+		t.Error("Contains does not match the finder pattern")
+	}
+	if got, want := q.Aperture(), (&Aperture{Shape: RectShape, Size: 0.5, Height: 0.5}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Aperture = %#v, want %#v", got, want)
+	}
+
+	var dark int
+	for _, row := range modules {
+		for _, v := range row {
+			if v {
+				dark++
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := q.WriteGerber(&buf, 10); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(buf.String(), "D03*"); got != dark {
+		t.Errorf("flashes = %v, want %v", got, dark)
+	}
+
+	q.Region = true
+	buf.Reset()
+	if err := q.WriteGerber(&buf, 10); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); strings.Count(got, "G36*") != 1 || strings.Contains(got, "D03*") || q.Aperture() != nil {
+		t.Errorf("region = %v", got)
+	}
+
+	inverted := *q
+	inverted.Invert = true
+	if got, want := inverted.MBB(), (MBB{Min: Pt{2.75, 12.75}, Max: Pt{17.25, 27.25}}); !mbbNear(got, want, eps) {
+		t.Errorf("inverted MBB = %v, want %v", got, want)
+	}
+	if inverted.Contains(Pt{5, 25}) || !inverted.Contains(Pt{3, 27}) {
+		t.Error("inverted Contains does not match the finder pattern and quiet zone")
+	}
+}
+
+func TestQRCode_Transform(t *testing.T) {
+	q := QRCode(1, 2, 21*0.5, "SN 0001")
+	for _, xf := range []Transform{Rotate(30).Then(Translate(5, -3)), MirrorX().Then(Rotate(90)), Scale(2, 2)} {
+		tq := q.Transform(xf).(*QRCodeT)
+		for y := -4.0; y <= 8; y += 0.37 {
+			for x := -4.0; x <= 6; x += 0.41 {
+				pt := Pt{x, y}
+				if got, want := tq.Contains(xf.Apply(pt)), q.Contains(pt); got != want {
+					t.Fatalf("%v: Contains(%v) = %v, want %v", xf, xf.Apply(pt), got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestQRCode_JSON(t *testing.T) {
+	g := New("test")
+	top := g.TopSilkscreen()
+	top.Add(&QRCodeT{Center: Pt{1, 2}, Size: 8, Content: "rev B", Level: QRLevelH, Invert: true})
+	data, err := json.Marshal(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := &Gerber{}
+	if err := json.Unmarshal(data, got); err != nil {
+		t.Fatal(err)
+	}
+	q, ok := got.Layers[0].Primitives[0].(*QRCodeT)
+	if !ok || q.Content != "rev B" || q.Level != QRLevelH || !q.Invert {
+		t.Fatalf("got primitive %#v", got.Layers[0].Primitives[0])
+	}
+	var buf bytes.Buffer
+	if err := got.Layers[0].WriteGerber(&buf); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,170 @@
+package gerber
+
+import (
+	"io"
+	"math"
+
+	"github.com/gmlewis/go-gerber/gerber/internal/polyclip"
+)
+
+func init() {
+	registerPrimitive("region", func() Primitive { return &RegionT{} })
+}
+
+// Segment represents a segment of a region outline: a straight line
+// or (if Arc is set) a circular arc about Center, ending at To.
+type Segment struct {
+	To        Pt   `json:"to"`
+	Arc       bool `json:"arc,omitempty"`
+	Center    Pt   `json:"center,omitempty"`
+	Clockwise bool `json:"clockwise,omitempty"`
+}
+
+// LineTo returns a straight segment ending at to.
+func LineTo(to Pt) Segment {
+	return Segment{To: to}
+}
+
+// ArcTo returns a circular arc segment about center ending at to,
+// running clockwise or counterclockwise from the end of the previous
+// segment. If to is that point, the arc is a full circle.
+func ArcTo(to, center Pt, clockwise bool) Segment {
+	return Segment{To: to, Arc: true, Center: center, Clockwise: clockwise}
+}
+
+// RegionT represents a filled region whose outline mixes straight and
+// circular arc segments, and satisfies the Primitive interface. The
+// outline is closed back to Start. Arcs are written as G02/G03
+// circular interpolation if enabled (see WithNativeArcs) and are
+// otherwise flattened like ArcT.
+type RegionT struct {
+	Start    Pt        `json:"start"`
+	Segments []Segment `json:"segments"`
+	mbb      *MBB      // cached minimum bounding box
+}
+
+// Region returns a region primitive.
+// All dimensions are in millimeters.
+func Region(start Pt, segments ...Segment) *RegionT {
+	return &RegionT{Start: start, Segments: segments}
+}
+
+// WriteGerber writes the primitive to the Gerber file.
+func (r *RegionT) WriteGerber(w io.Writer, apertureIndex int) error {
+	gw := toWriter(w)
+	if !gw.nativeArcs {
+		return Polygon(Pt{}, true, r.Points(gw.arcTolerance), 0).WriteGerber(gw, apertureIndex)
+	}
+	gw.aperture(apertureIndex)
+	io.WriteString(gw, "G36*\n")
+	gw.move(r.Start[0], r.Start[1])
+	from := r.Start
+	for _, s := range r.Segments {
+		if s.Arc {
+			gw.arc(from, s.To, s.Center, s.Clockwise)
+		} else {
+			gw.draw(s.To[0], s.To[1])
+		}
+		from = s.To
+	}
+	if from != r.Start {
+		gw.draw(r.Start[0], r.Start[1])
+	}
+	io.WriteString(gw, "G37*\n")
+	return nil
+}
+
+// Points returns the outline of the region with its arcs flattened
+// into line segments of at most tol millimeters of chord error (see
+// WithArcTolerance; 0 for the default).
+func (r *RegionT) Points(tol float64) []Pt {
+	pts := []Pt{r.Start}
+	from := r.Start
+	for _, s := range r.Segments {
+		if !s.Arc {
+			pts = append(pts, s.To)
+			from = s.To
+			continue
+		}
+		radius, start, end := s.angles(from)
+		a := &ArcT{Center: s.Center, Radius: radius, XScale: 1, YScale: 1, StartAngle: math.Min(start, end), EndAngle: math.Max(start, end)}
+		n := a.segments(tol)
+		for i := 1; i <= n; i++ {
+			angle := start + (end-start)*float64(i)/float64(n)
+			pts = append(pts, Pt{s.Center[0] + radius*math.Cos(angle), s.Center[1] + radius*math.Sin(angle)})
+		}
+		from = s.To
+	}
+	if len(pts) > 1 && Distance(pts[len(pts)-1], r.Start) < 1e-9 {
+		pts = pts[:len(pts)-1]
+	}
+	return pts
+}
+
+// angles returns the radius of the arc segment from from and its start
+// and end angles (in radians), in the direction of the arc.
+func (s Segment) angles(from Pt) (radius, start, end float64) {
+	radius = Distance(from, s.Center)
+	start = math.Atan2(from[1]-s.Center[1], from[0]-s.Center[0])
+	end = math.Atan2(s.To[1]-s.Center[1], s.To[0]-s.Center[0])
+	if s.Clockwise {
+		if end >= start {
+			end -= 2 * math.Pi
+		}
+	} else if end <= start {
+		end += 2 * math.Pi
+	}
+	return radius, start, end
+}
+
+// Aperture returns nil for RegionT because it uses the default aperture.
+func (r *RegionT) Aperture() *Aperture {
+	return nil
+}
+
+// MBB returns the minimum bounding box in millimeters.
+func (r *RegionT) MBB() MBB {
+	if r.mbb != nil {
+		return *r.mbb
+	}
+	mbb := MBB{Min: r.Start, Max: r.Start}
+	from := r.Start
+	for _, s := range r.Segments {
+		v := MBB{Min: s.To, Max: s.To}
+		if s.Arc {
+			radius, start, end := s.angles(from)
+			v = arcExtents(s.Center, radius, radius, math.Min(start, end), math.Max(start, end))
+		}
+		mbb.Join(&v)
+		from = s.To
+	}
+	r.mbb = &mbb
+	return mbb
+}
+
+func (r *RegionT) invalidateBounds() { r.mbb = nil }
+
+// Transform returns a transformed copy of the region. Mirroring
+// transforms reverse the direction of its arcs.
+func (r *RegionT) Transform(t Transform) Primitive {
+	result := Region(t.Apply(r.Start))
+	for _, s := range r.Segments {
+		ts := Segment{To: t.Apply(s.To), Arc: s.Arc}
+		if s.Arc {
+			ts.Center, ts.Clockwise = t.Apply(s.Center), s.Clockwise != (t.det() < 0)
+		}
+		result.Segments = append(result.Segments, ts)
+	}
+	return result
+}
+
+// Expand returns a polygon approximating the region with its edges
+// moved outward by delta (or inward, if delta is negative).
+func (r *RegionT) Expand(delta float64) Primitive {
+	return Polygon(Pt{}, true, polyclip.OffsetPolygon(r.Points(0), delta), 0)
+}
+
+// Contains reports whether pt lies within the region.
+func (r *RegionT) Contains(pt Pt) bool {
+	return pointInPolygon(pt, r.Points(0))
+}
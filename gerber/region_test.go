@@ -0,0 +1,167 @@
+package gerber
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/gmlewis/go-gerber/gerber/internal/polyclip"
+)
+
+func TestRegionT_Primitive(t *testing.T) {
+	var p Primitive = &RegionT{}
+	if p == nil {
+		// In actuality, this test won't compile if it isn't a Primitive.
+		t.Errorf("RegionT does not implement the Primitive interface")
+	}
+}
+
+// slot returns a 4x2mm region with semicircular ends centered on the
+// origin.
+func slot(clockwise bool) *RegionT {
+	if clockwise {
+		return Region(Pt{-1, -1}, ArcTo(Pt{-1, 1}, Pt{-1, 0}, true), LineTo(Pt{1, 1}), ArcTo(Pt{1, -1}, Pt{1, 0}, true))
+	}
+	return Region(Pt{-1, -1}, LineTo(Pt{1, -1}), ArcTo(Pt{1, 1}, Pt{1, 0}, false), LineTo(Pt{-1, 1}), ArcTo(Pt{-1, -1}, Pt{-1, 0}, false))
+}
+
+func TestRegionT_Geometry(t *testing.T) {
+	for _, clockwise := range []bool{false, true} {
+		r := slot(clockwise)
+		if want := (MBB{Min: Pt{-2, -1}, Max: Pt{2, 1}}); !mbbNear(r.MBB(), want, 1e-9) {
+			t.Errorf("clockwise=%v: MBB = %v, want %v", clockwise, r.MBB(), want)
+		}
+		if got, want := math.Abs(polyclip.SignedArea(r.Points(0))), 4+math.Pi; math.Abs(got-want) > 0.01 {
+			t.Errorf("clockwise=%v: area = %v, want %v", clockwise, got, want)
+		}
+		if !r.Contains(Pt{1.9, 0}) || r.Contains(Pt{1.9, 0.9}) {
+			t.Errorf("clockwise=%v: Contains does not follow the arcs", clockwise)
+		}
+	}
+}
+
+func TestRegionT_WriteGerber(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "flattened",
+			notWant: []string{"G02*", "G03*", "I"},
+			want:    []string{"G36*", "G37*"},
+		},
+		{
+			name: "native",
+			opts: []Option{WithNativeArcs(true)},
+			want: []string{"G36*\n", "G75*\nG03*\nX2000000Y1000000I000000J1000000D01*\nG01*\n", "G37*\n"},
+		},
+		{
+			name: "mirrored",
+			opts: []Option{WithNativeArcs(true), WithExportTransform(MirrorY())},
+			want: []string{"G75*\nG02*\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New("test", tt.opts...)
+			layer := g.TopCopper()
+			layer.Add(slot(false).Transform(Translate(1, 0)))
+			var buf bytes.Buffer
+			if err := layer.WriteGerber(&buf); err != nil {
+				t.Fatal(err)
+			}
+			got := buf.String()
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("WriteGerber = %q, want %q", got, want)
+				}
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(got, s) {
+					t.Errorf("WriteGerber = %q, should not contain %q", got, s)
+				}
+			}
+
+			parsed, err := Parse(strings.NewReader(got))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := MBB{Min: Pt{-1, -1}, Max: Pt{3, 1}}
+			if tt.name == "mirrored" {
+				want = MBB{Min: Pt{-3, -1}, Max: Pt{1, 1}}
+			}
+			if !mbbNear(parsed.MBB(), want, 0.01) {
+				t.Errorf("parsed MBB = %v, want %v", parsed.MBB(), want)
+			}
+		})
+	}
+}
+
+func TestArcT_WriteGerber_NativeArcs(t *testing.T) {
+	g := New("test", WithNativeArcs(true))
+	layer := g.TopCopper()
+	layer.Add(Arc(Pt{1, 1}, 2, CircleShape, 1, 1, 0, 90, 0.2))
+	var buf bytes.Buffer
+	if err := layer.WriteGerber(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if want := "X3000000Y1000000D02*\nG75*\nG03*\nX1000000Y3000000I-2000000J000000D01*\n"; !strings.Contains(buf.String(), want) {
+		t.Errorf("WriteGerber = %q, want %q", buf.String(), want)
+	}
+	parsed, err := Parse(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed.Primitives) != 1 {
+		t.Fatalf("parsed %v primitives, want 1", len(parsed.Primitives))
+	}
+	arc, ok := parsed.Primitives[0].(*ArcT)
+	if !ok {
+		t.Fatalf("parsed %T, want *ArcT", parsed.Primitives[0])
+	}
+	if math.Abs(arc.Radius-2) > 1e-3 || math.Abs(arc.EndAngle-arc.StartAngle-0.5*math.Pi) > 1e-3 {
+		t.Errorf("parsed arc = %+v, want radius 2 and 90 degrees", arc)
+	}
+
+	s, err := layer.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Draws != 1 || math.Abs(s.DrawLength-math.Pi) > 1e-3 {
+		t.Errorf("Stats = %v draws of %v, want 1 of %v", s.Draws, s.DrawLength, math.Pi)
+	}
+}
+
+func TestRegionT_Transform(t *testing.T) {
+	r := slot(false).Transform(MirrorY().Then(Translate(1, 0))).(*RegionT)
+	if want := (MBB{Min: Pt{-1, -1}, Max: Pt{3, 1}}); !mbbNear(r.MBB(), want, 1e-3) {
+		t.Errorf("MBB = %v, want %v", r.MBB(), want)
+	}
+	if !r.Segments[1].Arc || !r.Segments[1].Clockwise {
+		t.Errorf("mirrored arc = %+v, want clockwise", r.Segments[1])
+	}
+}
+
+func TestRegionT_JSON(t *testing.T) {
+	g := New("test")
+	g.TopCopper().Add(slot(false))
+	data, err := json.Marshal(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Gerber
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	r, ok := got.Layers[0].Primitives[0].(*RegionT)
+	if !ok {
+		t.Fatalf("unmarshaled %T, want *RegionT", got.Layers[0].Primitives[0])
+	}
+	if want := slot(false); len(r.Segments) != len(want.Segments) || r.Segments[1] != want.Segments[1] {
+		t.Errorf("unmarshaled %+v, want %+v", r, want)
+	}
+}
@@ -0,0 +1,48 @@
+package gerber
+
+import "sort"
+
+// EvenOddRegions returns filled regions (offset by offset) for a set of
+// non-crossing, possibly nested contours with even-odd fill semantics:
+// a contour nested within an odd number of others is a hole, and one
+// nested within an even number is an island inside a hole. This is how
+// complex artwork such as logos with counters (e.g. the inside of an
+// "O") must be emitted: the regions are ordered from the outermost
+// inward, with holes as clear-polarity (LPC) regions and islands as
+// dark (LPD) regions, so each clears or restores what the previous
+// level drew.
+//
+// Clear regions also clear any earlier primitives of the layer that
+// they overlap, so the returned group should be added after the
+// artwork it is drawn over.
+func EvenOddRegions(offset Pt, contours ...[]Pt) Group {
+	depths := make([]int, len(contours))
+	for i, c := range contours {
+		if len(c) == 0 {
+			continue
+		}
+		for j, other := range contours {
+			if i != j && len(other) > 2 && pointInPolygon(c[0], other) {
+				depths[i]++
+			}
+		}
+	}
+	order := make([]int, len(contours))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return depths[order[a]] < depths[order[b]] })
+
+	var result Group
+	for _, i := range order {
+		if len(contours[i]) < 3 {
+			continue
+		}
+		var p Primitive = Polygon(offset, true, contours[i], 0)
+		if depths[i]%2 == 1 {
+			p = Clear(p)
+		}
+		result = append(result, p)
+	}
+	return result
+}
@@ -0,0 +1,53 @@
+package gerber
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func square(x, y, size float64) []Pt {
+	return []Pt{{x, y}, {x + size, y}, {x + size, y + size}, {x, y + size}}
+}
+
+func TestEvenOddRegions(t *testing.T) {
+	// Given innermost first to check the ordering.
+	island := square(4, 4, 2)
+	hole := square(2, 2, 6)
+	outer := square(0, 0, 10)
+	other := square(20, 0, 1)
+	got := EvenOddRegions(Pt{}, island, hole, other, outer)
+
+	want := []struct {
+		clear  bool
+		points []Pt
+	}{
+		{false, other},
+		{false, outer},
+		{true, hole},
+		{false, island},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("EvenOddRegions = %v regions, want %v", len(got), len(want))
+	}
+	for i, p := range got {
+		c, clear := p.(*ClearT)
+		if clear {
+			p = c.Primitive
+		}
+		poly := p.(*PolygonT)
+		if clear != want[i].clear || poly.Points[0] != want[i].points[0] {
+			t.Errorf("region %v = %v (clear %v), want %v (clear %v)", i, poly.Points, clear, want[i].points, want[i].clear)
+		}
+	}
+
+	layer := New("test").TopSilkscreen()
+	layer.Add(got...)
+	var buf bytes.Buffer
+	if err := layer.WriteGerber(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(buf.String(), "%LPC*%"); n != 1 {
+		t.Errorf("WriteGerber has %v clear regions, want 1", n)
+	}
+}
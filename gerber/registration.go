@@ -0,0 +1,86 @@
+package gerber
+
+import (
+	"fmt"
+	"math"
+)
+
+// NonPlatedTag is the tag of drill holes that are not plated (e.g.
+// mounting holes), which CheckDrillRegistration does not require to
+// be covered by pads.
+const NonPlatedTag = "npth"
+
+// registrationSamples is the number of points around each hole that
+// CheckDrillRegistration requires to be covered.
+const registrationSamples = 32
+
+// CheckDrillRegistration derives the design's pads (see
+// DeriveOpenings) and checks that every plated hole of its drill
+// layers is covered, on each of its copper layers, by copper extending
+// at least minRing millimeters (the minimum annular ring) beyond the
+// edge of the hole. This catches generators whose drill and copper
+// coordinates disagree. Holes tagged with NonPlatedTag are skipped.
+// Each issue offers a Fix that enlarges (or adds) the hole's pad.
+func (g *Gerber) CheckDrillRegistration(minRing float64) (Issues, error) {
+	if err := g.DeriveOpenings(); err != nil {
+		return nil, err
+	}
+	var copper []*Layer
+	for _, layer := range g.Layers {
+		if layer.Type.IsCopper() {
+			copper = append(copper, layer)
+		}
+	}
+	var issues Issues
+	for _, drill := range g.layersOfType(DrillLayer) {
+		for i, p := range drill.Primitives {
+			hole, ok := p.(*CircleT)
+			if !ok || drill.HasTag(p, NonPlatedTag) {
+				continue
+			}
+			// Allow pads that are exactly large enough.
+			r := 0.5*hole.thickness + minRing - validationEps
+			for _, layer := range copper {
+				if !ringCovered(layer, hole.pt, r) {
+					fix := annularRingFix(layer, hole, minRing)
+					issues = append(issues, Issue{
+						Severity:  SeverityError,
+						Layer:     drill,
+						Primitive: p,
+						Message: fmt.Sprintf("hole #%v at %v (%vmm) lacks a %vmm annular ring on %v",
+							i, fmtPt(hole.pt), fmtFloat(hole.thickness), fmtFloat(minRing), layer.Filename),
+						Fix: fix,
+					})
+				}
+			}
+		}
+	}
+	return issues, nil
+}
+
+// ringCovered reports whether the layer's primitives cover the circle
+// of radius r around center (sampled at registrationSamples points).
+func ringCovered(l *Layer, center Pt, r float64) bool {
+	area := MBB{Min: Pt{center[0] - r, center[1] - r}, Max: Pt{center[0] + r, center[1] + r}}
+	var candidates []Primitive
+	for _, p := range l.Primitives {
+		if mbb := p.MBB(); mbb.Intersects(&area) {
+			candidates = append(candidates, p)
+		}
+	}
+	for i := 0; i < registrationSamples; i++ {
+		angle := 2 * math.Pi * float64(i) / registrationSamples
+		pt := Pt{center[0] + r*math.Cos(angle), center[1] + r*math.Sin(angle)}
+		covered := false
+		for _, p := range candidates {
+			if PrimitiveContains(p, pt) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,108 @@
+package gerber
+
+import "testing"
+
+func TestGerber_CheckDrillRegistration(t *testing.T) {
+	g := New("test")
+	top, bottom := g.TopCopper(), g.BottomCopper()
+	drill := g.Drill()
+	// A via with a 0.15mm ring on both sides.
+	Via{Center: Pt{1, 1}, Drill: 0.3, Pad: 0.6}.AddTo(drill, top, bottom)
+	// A hole whose bottom pad is offset by 0.2mm.
+	drill.Add(Circle(Pt{3, 1}, 0.3))
+	top.Add(Circle(Pt{3, 1}, 0.6))
+	bottom.Add(Circle(Pt{3.2, 1}, 0.6))
+	// A hole whose top pad is a trace passing over it.
+	drill.Add(Circle(Pt{5, 1}, 0.3))
+	top.Add(Line(4, 1, 6, 1, CircleShape, 0.7))
+	bottom.Add(Circle(Pt{5, 1}, 0.7))
+	// A mounting hole without pads.
+	drill.AddTagged([]string{NonPlatedTag}, Circle(Pt{7, 1}, 3))
+	// A padstack, whose pads are derived when checked.
+	g.PlacePadstack(&Padstack{Drill: 0.4, Top: RoundPad(0.8), Bottom: RoundPad(0.8)}, Pt{9, 1}, 0)
+
+	tests := []struct {
+		minRing float64
+		want    []string
+	}{
+		{
+			minRing: 0.1,
+			want:    []string{"error: test.drl: hole #1 at (3,1) (0.3mm) lacks a 0.1mm annular ring on test.gbl"},
+		},
+		{
+			minRing: 0.15,
+			want:    []string{"error: test.drl: hole #1 at (3,1) (0.3mm) lacks a 0.15mm annular ring on test.gbl"},
+		},
+		{
+			minRing: 0.18,
+			want: []string{
+				"error: test.drl: hole #0 at (1,1) (0.3mm) lacks a 0.18mm annular ring on test.gtl",
+				"error: test.drl: hole #0 at (1,1) (0.3mm) lacks a 0.18mm annular ring on test.gbl",
+				"error: test.drl: hole #1 at (3,1) (0.3mm) lacks a 0.18mm annular ring on test.gtl",
+				"error: test.drl: hole #1 at (3,1) (0.3mm) lacks a 0.18mm annular ring on test.gbl",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(fmtFloat(tt.minRing), func(t *testing.T) {
+			issues, err := g.CheckDrillRegistration(tt.minRing)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(issues) != len(tt.want) {
+				t.Fatalf("CheckDrillRegistration = %v, want %v", issues, tt.want)
+			}
+			for i, issue := range issues {
+				if got := issue.String(); got != tt.want[i] {
+					t.Errorf("issue[%v] = %q, want %q", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestGerber_ApplyFixes(t *testing.T) {
+	g := New("test")
+	top, bottom := g.TopCopper(), g.BottomCopper()
+	drill := g.Drill()
+	// A hole with a small top pad and a bottom pad offset by 0.2mm.
+	drill.Add(Circle(Pt{3, 1}, 0.3))
+	top.AddTagged([]string{NetTag("GND")}, Circle(Pt{3, 1}, 0.4))
+	bottom.Add(Circle(Pt{3.2, 1}, 0.6))
+
+	issues, err := g.CheckDrillRegistration(0.15)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issues = append(issues, Issue{Severity: SeverityWarning, Message: "no fix"})
+	if got, want := issues[0].Fix.Description, "enlarge the pad of the hole at (3,1) on test.gtl to 0.6mm"; got != want {
+		t.Errorf("Fix.Description = %q, want %q", got, want)
+	}
+	// Applying the fixes twice changes nothing the second time.
+	issues = append(issues, issues[0])
+	changes, unfixed, err := g.ApplyFixes(issues)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`test.gtl: changed #0 circle{"center":[3,1],"thickness":0.4} to #0 circle{"center":[3,1],"thickness":0.6}`,
+		`test.gbl: added #1 circle{"center":[3,1],"thickness":0.6}`,
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("ApplyFixes changes = %v, want %v", changes, want)
+	}
+	for i, c := range changes {
+		if got := c.String(); got != want[i] {
+			t.Errorf("change[%v] = %q, want %q", i, got, want[i])
+		}
+	}
+	if len(unfixed) != 1 || unfixed[0].Message != "no fix" {
+		t.Errorf("ApplyFixes unfixed = %v, want the issue without a fix", unfixed)
+	}
+	if !top.HasTag(top.Primitives[0], NetTag("GND")) {
+		t.Errorf("enlarged pad lost its tags")
+	}
+	if issues, err := g.CheckDrillRegistration(0.15); err != nil || len(issues) != 0 {
+		t.Errorf("CheckDrillRegistration after ApplyFixes = %v, %v, want no issues", issues, err)
+	}
+}
@@ -0,0 +1,93 @@
+package gerber
+
+import "fmt"
+
+// Third-party primitives:
+//
+// Any type that implements the Primitive interface can be added to a
+// Layer and written with the rest of the design. Its WriteGerber method
+// receives the writer for the layer together with the index of the
+// aperture returned by its Aperture method (or the default aperture
+// when Aperture returns nil). NewGerberWriter wraps that writer so that
+// the primitive's coordinates are formatted with the layer's settings.
+//
+// Primitives may also implement the optional Transformer, Expander, and
+// Container interfaces so that they participate in transformations,
+// derived solder mask and paste openings, and hit-testing,
+// and may be registered with RegisterPrimitive so that designs containing
+// them can be serialized and deserialized.
+
+// Transformer is implemented by primitives that can be transformed.
+type Transformer interface {
+	// Transform returns a transformed copy of the primitive.
+	// The original primitive is not modified.
+	Transform(t Transform) Primitive
+}
+
+// Expander is implemented by primitives whose geometry can be grown
+// or shrunk (e.g. to derive solder mask or paste openings).
+type Expander interface {
+	// Expand returns a copy of the primitive grown by delta millimeters
+	// on each side (or shrunk, if delta is negative).
+	// The original primitive is not modified.
+	Expand(delta float64) Primitive
+}
+
+// Container is implemented by primitives that support hit-testing.
+type Container interface {
+	// Contains reports whether the point (in millimeters) lies
+	// within the primitive's exposed area.
+	Contains(pt Pt) bool
+}
+
+// RegisterPrimitive registers a primitive type under the provided name
+// so that it can be serialized (see Gerber.MarshalJSON) and deserialized.
+// newFunc must return a pointer to a new zero value of the primitive,
+// which must support encoding/json.
+//
+// RegisterPrimitive is typically called from an init function and
+// panics if name is already registered.
+func RegisterPrimitive(name string, newFunc func() Primitive) {
+	if _, ok := primitiveTypes[name]; ok {
+		panic(fmt.Sprintf("gerber: RegisterPrimitive called twice for %q", name))
+	}
+	registerPrimitive(name, newFunc)
+}
+
+// TransformPrimitive returns a transformed copy of p, or an error if
+// p does not implement Transformer.
+func TransformPrimitive(p Primitive, t Transform) (Primitive, error) {
+	tp, ok := p.(Transformer)
+	if !ok {
+		return nil, fmt.Errorf("primitive %T does not support transforms", p)
+	}
+	return tp.Transform(t), nil
+}
+
+// ExpandPrimitive returns a copy of p grown by delta millimeters on each
+// side, or an error if p does not implement Expander.
+func ExpandPrimitive(p Primitive, delta float64) (Primitive, error) {
+	ep, ok := p.(Expander)
+	if !ok {
+		return nil, fmt.Errorf("primitive %T does not support expansion", p)
+	}
+	return ep.Expand(delta), nil
+}
+
+// PrimitiveContains reports whether p contains pt. If p does
+// not implement Container, its MBB is used instead.
+func PrimitiveContains(p Primitive, pt Pt) bool {
+	if c, ok := p.(Container); ok {
+		return c.Contains(pt)
+	}
+	mbb := p.MBB()
+	return mbb.ContainsPoint(&pt)
+}
+
+// PrimitiveDistance returns the clearance (in mm) between a and b, or 0
+// if they touch or overlap, as used by the design's connectivity and
+// spacing checks (see Layer.Islands). Primitives of unknown types are
+// approximated by their MBBs.
+func PrimitiveDistance(a, b Primitive) float64 {
+	return outlineOf(a).distance(outlineOf(b))
+}
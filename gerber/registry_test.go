@@ -0,0 +1,136 @@
+package gerber
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"testing"
+)
+
+type testDot struct {
+	Center Pt
+}
+
+func (d *testDot) WriteGerber(w io.Writer, apertureIndex int) error {
+	return Circle(d.Center, 0.1).WriteGerber(w, apertureIndex)
+}
+func (d *testDot) Aperture() *Aperture { return &Aperture{Shape: CircleShape, Size: 0.1} }
+func (d *testDot) MBB() MBB            { return Circle(d.Center, 0.1).MBB() }
+
+func TestRegisterPrimitive(t *testing.T) {
+	RegisterPrimitive("testDot", func() Primitive { return &testDot{} })
+
+	g := New("test")
+	g.TopCopper().Add(&testDot{Center: Pt{1, 2}})
+	data, err := json.Marshal(g)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	got := &Gerber{}
+	if err := json.Unmarshal(data, got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if d, ok := got.Layers[0].Primitives[0].(*testDot); !ok || d.Center != (Pt{1, 2}) {
+		t.Errorf("got primitive %#v, want testDot at (1,2)", got.Layers[0].Primitives[0])
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("RegisterPrimitive did not panic on duplicate name")
+		}
+	}()
+	RegisterPrimitive("testDot", func() Primitive { return &testDot{} })
+}
+
+func TestTransformPrimitive(t *testing.T) {
+	const eps = 1e-9
+	xf := Translate(10, 0).Then(Rotate(90))
+	tests := []struct {
+		name string
+		p    Primitive
+		want MBB
+	}{
+		{
+			name: "circle",
+			p:    Circle(Pt{0, 0}, 2),
+			want: MBB{Min: Pt{-1, 9}, Max: Pt{1, 11}},
+		},
+		{
+			name: "line",
+			p:    Line(0, 0, 2, 0, CircleShape, 2),
+			want: MBB{Min: Pt{-1, 9}, Max: Pt{1, 13}},
+		},
+		{
+			name: "polygon",
+			p:    Polygon(Pt{1, 1}, true, []Pt{{0, 0}, {1, 0}, {1, 1}}, 0),
+			want: MBB{Min: Pt{-2, 11}, Max: Pt{-1, 12}},
+		},
+		{
+			name: "arc",
+			p:    Arc(Pt{0, 0}, 10, CircleShape, 1, 1, 0, 90, 2),
+			want: MBB{Min: Pt{-11, 9}, Max: Pt{1, 21}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := TransformPrimitive(tt.p, xf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := p.MBB()
+			if math.Abs(got.Min[0]-tt.want.Min[0]) > eps || math.Abs(got.Min[1]-tt.want.Min[1]) > eps ||
+				math.Abs(got.Max[0]-tt.want.Max[0]) > eps || math.Abs(got.Max[1]-tt.want.Max[1]) > eps {
+				t.Errorf("MBB = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := TransformPrimitive(&testDot{}, xf); err == nil {
+		t.Errorf("TransformPrimitive(testDot) = nil error, want error")
+	}
+}
+
+func TestPrimitiveContains(t *testing.T) {
+	tests := []struct {
+		name string
+		p    Primitive
+		pt   Pt
+		want bool
+	}{
+		{name: "circle inside", p: Circle(Pt{0, 0}, 2), pt: Pt{0.5, 0.5}, want: true},
+		{name: "circle outside", p: Circle(Pt{0, 0}, 2), pt: Pt{0.8, 0.8}},
+		{name: "round line end", p: Line(0, 0, 2, 0, CircleShape, 2), pt: Pt{-0.8, 0.8}},
+		{name: "rect line end", p: Line(0, 0, 2, 0, RectShape, 2), pt: Pt{-0.8, 0.8}, want: true},
+		{name: "polygon inside", p: Polygon(Pt{0, 0}, true, []Pt{{0, 0}, {2, 0}, {2, 2}}, 0), pt: Pt{1.5, 0.5}, want: true},
+		{name: "polygon outside", p: Polygon(Pt{0, 0}, true, []Pt{{0, 0}, {2, 0}, {2, 2}}, 0), pt: Pt{0.5, 1.5}},
+		{name: "arc", p: Arc(Pt{0, 0}, 10, CircleShape, 1, 1, 0, 90, 2), pt: Pt{0, 10.5}, want: true},
+		{name: "arc gap", p: Arc(Pt{0, 0}, 10, CircleShape, 1, 1, 0, 90, 2), pt: Pt{-10, 0}},
+		{name: "MBB fallback", p: &testDot{Center: Pt{1, 1}}, pt: Pt{1.04, 1.04}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PrimitiveContains(tt.p, tt.pt); got != tt.want {
+				t.Errorf("PrimitiveContains = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrimitiveDistance(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Primitive
+		want float64
+	}{
+		{"circles", Circle(Pt{0, 0}, 1), Circle(Pt{3, 0}, 2), 1.5},
+		{"overlapping", Circle(Pt{0, 0}, 2), Line(0, 0, 5, 0, CircleShape, 0.2), 0},
+		{"line", Line(0, 0, 5, 0, CircleShape, 0.2), Line(0, 1, 5, 1, CircleShape, 0.4), 0.7},
+	}
+	for _, tt := range tests {
+		if got := PrimitiveDistance(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%v: PrimitiveDistance = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+
+	"github.com/gmlewis/go-gerber/gerber"
+)
+
+// object represents one graphical object of a layer, with the polarity
+// it is to be applied with: a round stroke (a dot if it has one point)
+// of the given width through its points, or the filled contours of a
+// region.
+type object struct {
+	dark     bool
+	pts      []gerber.Pt
+	width    float64
+	contours [][]gerber.Pt
+}
+
+// mbb returns the extents (in mm) of the object.
+func (o *object) mbb() gerber.MBB {
+	var pts []gerber.Pt
+	for _, c := range o.contours {
+		pts = append(pts, c...)
+	}
+	r := 0.5 * o.width
+	for _, pt := range o.pts {
+		pts = append(pts, gerber.Pt{pt[0] - r, pt[1] - r}, gerber.Pt{pt[0] + r, pt[1] + r})
+	}
+	mbb := gerber.MBB{Min: pts[0], Max: pts[0]}
+	for _, pt := range pts[1:] {
+		v := gerber.MBB{Min: pt, Max: pt}
+		mbb.Join(&v)
+	}
+	return mbb
+}
+
+// arcSegments is the number of segments of a full circle when arcs are
+// flattened.
+const arcSegments = 90
+
+// parse reads an RS-274X layer with gerber.Parse and returns its
+// objects, in order.
+func parse(data []byte) ([]*object, error) {
+	layer, err := gerber.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	var objects []*object
+	for _, p := range layer.Primitives {
+		if objects, err = appendObjects(objects, p, gerber.Pt{}, true); err != nil {
+			return nil, err
+		}
+	}
+	return objects, nil
+}
+
+// appendObjects appends the objects of a primitive returned by
+// gerber.Parse (or of a macro's image), moved by offset, to objects.
+// The clear primitives of a macro's image erase whatever was drawn
+// beneath them, not just the macro's own image.
+func appendObjects(objects []*object, p gerber.Primitive, offset gerber.Pt, dark bool) ([]*object, error) {
+	move := func(pts ...gerber.Pt) []gerber.Pt {
+		moved := make([]gerber.Pt, len(pts))
+		for i, pt := range pts {
+			moved[i] = gerber.Pt{pt[0] + offset[0], pt[1] + offset[1]}
+		}
+		return moved
+	}
+	switch v := p.(type) {
+	case *gerber.ClearT:
+		return appendObjects(objects, v.Primitive, offset, !dark)
+	case *gerber.CircleT:
+		mbb := v.MBB()
+		center := gerber.Pt{0.5 * (mbb.Min[0] + mbb.Max[0]), 0.5 * (mbb.Min[1] + mbb.Max[1])}
+		return append(objects, &object{dark: dark, pts: move(center), width: mbb.Max[0] - mbb.Min[0]}), nil
+	case *gerber.LineT:
+		if v.Shape == gerber.RectShape {
+			return append(objects, &object{dark: dark, contours: [][]gerber.Pt{move(gerber.ConvexHull(v)...)}}), nil
+		}
+		return append(objects, &object{dark: dark, pts: move(v.P1, v.P2), width: v.Thickness}), nil
+	case *gerber.ArcT:
+		n := int(math.Ceil(arcSegments * (v.EndAngle - v.StartAngle) / (2 * math.Pi)))
+		if n < 1 {
+			n = 1
+		}
+		pts := make([]gerber.Pt, n+1)
+		for i := range pts {
+			a := v.StartAngle + (v.EndAngle-v.StartAngle)*float64(i)/float64(n)
+			pts[i] = gerber.Pt{v.Center[0] + v.XScale*v.Radius*math.Cos(a), v.Center[1] + v.YScale*v.Radius*math.Sin(a)}
+		}
+		return append(objects, &object{dark: dark, pts: move(pts...), width: v.Thickness}), nil
+	case *gerber.PolygonT:
+		pts := make([]gerber.Pt, len(v.Points))
+		for i, pt := range v.Points {
+			pts[i] = gerber.Pt{v.Offset[0] + pt[0], v.Offset[1] + pt[1]}
+		}
+		return append(objects, &object{dark: dark, contours: [][]gerber.Pt{move(pts...)}}), nil
+	case *gerber.PadT:
+		return appendPad(objects, v, offset, dark), nil
+	case *gerber.FlashT:
+		image, err := v.Macro.Image(v.Params...)
+		if err != nil {
+			return nil, err
+		}
+		center := move(v.Center)[0]
+		for _, ip := range image {
+			if objects, err = appendObjects(objects, ip, center, dark); err != nil {
+				return nil, err
+			}
+		}
+		return objects, nil
+	}
+	return nil, fmt.Errorf("unsupported primitive %T", p)
+}
+
+// appendPad appends the objects of a rectangle, rounded rectangle, or
+// obround pad: its rectangle, less its rounded corners, and a dot at
+// the center of each corner.
+func appendPad(objects []*object, p *gerber.PadT, offset gerber.Pt, dark bool) []*object {
+	hw, hh := 0.5*p.Width, 0.5*p.Height
+	r := math.Min(p.Radius, math.Min(hw, hh))
+	if p.Shape != gerber.RectShape {
+		r = math.Min(hw, hh)
+	}
+	at := func(x, y float64) gerber.Pt {
+		pt := gerber.RotatePt(gerber.Pt{x, y}, gerber.Pt{}, p.Rotation)
+		return gerber.Pt{offset[0] + p.Center[0] + pt[0], offset[1] + p.Center[1] + pt[1]}
+	}
+	rect := func(w, h float64) []gerber.Pt {
+		return []gerber.Pt{at(-w, -h), at(w, -h), at(w, h), at(-w, h)}
+	}
+	o := &object{dark: dark, contours: [][]gerber.Pt{rect(hw, hh-r), rect(hw-r, hh)}}
+	if r <= 0 {
+		o.contours = o.contours[:1]
+	}
+	objects = append(objects, o)
+	if r > 0 {
+		for _, c := range [][2]float64{{-1, -1}, {1, -1}, {1, 1}, {-1, 1}} {
+			objects = append(objects, &object{dark: dark, pts: []gerber.Pt{at(c[0]*(hw-r), c[1]*(hh-r))}, width: 2 * r})
+		}
+	}
+	return objects
+}
@@ -0,0 +1,390 @@
+// Package render rasterizes Gerber layers into a composite preview
+// image, applying the same polarity rules as CAM tools: within a layer,
+// dark primitives add to the image and clear (LPC) primitives erase
+// whatever was drawn before them; a negative layer is inverted over the
+// extents of the composition; and layers are then blended, bottom to
+// top, using each layer's color and alpha.
+package render
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"github.com/fogleman/gg"
+	"github.com/gmlewis/go-gerber/gerber"
+)
+
+// Style represents how a layer is blended into the composition.
+type Style struct {
+	Color color.Color
+	// Alpha is the opacity (0-1) of the layer's dark areas.
+	Alpha float64
+	// Negative inverts the layer, so that its dark areas show through
+	// and the rest of the composition's extents is filled (as is
+	// conventional for solder mask openings).
+	Negative bool
+}
+
+// Layer represents one Gerber layer of a composition.
+type Layer struct {
+	Name string
+	// Data is the RS-274X source of the layer.
+	Data  []byte
+	Style Style
+}
+
+// Composition represents a stack of layers, in blend order (the first
+// layer is drawn first and is covered by the later ones).
+type Composition struct {
+	// Background is the color of the image behind all layers
+	// (transparent if nil).
+	Background color.Color
+	Layers     []*Layer
+	// Mirror flips the image left to right (as when viewing the bottom
+	// of the board).
+	Mirror bool
+}
+
+// Render rasterizes the composition into a width x height image.
+// The extents of all layers are scaled (preserving the aspect ratio)
+// to fit, and centered in, the image.
+func (c *Composition) Render(width, height int) (*image.RGBA, error) {
+	return c.RenderContext(context.Background(), width, height)
+}
+
+// RenderContext is like Render but stops early (returning ctx.Err()) if
+// ctx is canceled while the layers are being rasterized.
+func (c *Composition) RenderContext(ctx context.Context, width, height int) (*image.RGBA, error) {
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("invalid image size %vx%v", width, height)
+	}
+	layers, mbb, err := c.parse()
+	if err != nil {
+		return nil, err
+	}
+	return c.render(ctx, layers, mbb, width, height)
+}
+
+// RenderDPI rasterizes the composition at dpi pixels per inch into an
+// image just large enough for the extents of all layers.
+func (c *Composition) RenderDPI(dpi int) (*image.RGBA, error) {
+	return c.RenderDPIContext(context.Background(), dpi)
+}
+
+// RenderDPIContext is like RenderDPI but stops early (returning
+// ctx.Err()) if ctx is canceled while the layers are being rasterized.
+func (c *Composition) RenderDPIContext(ctx context.Context, dpi int) (*image.RGBA, error) {
+	if dpi <= 0 {
+		return nil, fmt.Errorf("invalid resolution %v dpi", dpi)
+	}
+	layers, mbb, err := c.parse()
+	if err != nil {
+		return nil, err
+	}
+	width, height := 1, 1
+	if mbb != nil {
+		scale := float64(dpi) / 25.4
+		width = int(math.Max(1, math.Ceil(scale*(mbb.Max[0]-mbb.Min[0])-1e-9)))
+		height = int(math.Max(1, math.Ceil(scale*(mbb.Max[1]-mbb.Min[1])-1e-9)))
+	}
+	return c.render(ctx, layers, mbb, width, height)
+}
+
+// parse returns the objects of each layer and their extents (nil if
+// there are none).
+func (c *Composition) parse() ([][]*object, *gerber.MBB, error) {
+	layers := make([][]*object, len(c.Layers))
+	var mbb *gerber.MBB
+	for i, layer := range c.Layers {
+		objects, err := parse(layer.Data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("layer %v: %v", layer.Name, err)
+		}
+		layers[i] = objects
+		for _, o := range objects {
+			v := o.mbb()
+			if mbb == nil {
+				mbb = &v
+			} else {
+				mbb.Join(&v)
+			}
+		}
+	}
+	return layers, mbb, nil
+}
+
+func (c *Composition) render(ctx context.Context, layers [][]*object, mbb *gerber.MBB, width, height int) (*image.RGBA, error) {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	if c.Background != nil {
+		draw.Draw(img, img.Bounds(), image.NewUniform(c.Background), image.Point{}, draw.Src)
+	}
+	if mbb == nil {
+		return img, nil
+	}
+	v := newView(*mbb, width, height)
+	v.mirror = c.Mirror
+	for i, layer := range c.Layers {
+		mask, err := v.rasterize(ctx, layers[i])
+		if err != nil {
+			return nil, err
+		}
+		if layer.Style.Negative {
+			v.invert(mask)
+		}
+		draw.DrawMask(img, img.Bounds(), image.NewUniform(layer.Style.blend()), image.Point{}, mask, image.Point{}, draw.Over)
+	}
+	return img, nil
+}
+
+// blend returns the style's color with its alpha applied.
+func (s Style) blend() color.Color {
+	if s.Color == nil {
+		return color.Transparent
+	}
+	c := color.NRGBAModel.Convert(s.Color).(color.NRGBA)
+	c.A = uint8(math.Round(float64(c.A) * math.Max(0, math.Min(1, s.Alpha))))
+	return c
+}
+
+// ctxCheckInterval is the number of objects rasterized between checks
+// for cancellation.
+const ctxCheckInterval = 1000
+
+// view maps millimeters to pixels (with Y increasing upward).
+type view struct {
+	mbb           gerber.MBB
+	scale         float64
+	width, height int
+	dx, dy        float64
+	mirror        bool
+}
+
+func newView(mbb gerber.MBB, width, height int) *view {
+	w, h := mbb.Max[0]-mbb.Min[0], mbb.Max[1]-mbb.Min[1]
+	scale := math.Min(float64(width)/math.Max(w, 1e-9), float64(height)/math.Max(h, 1e-9))
+	return &view{
+		mbb:    mbb,
+		scale:  scale,
+		width:  width,
+		height: height,
+		dx:     0.5 * (float64(width) - scale*w),
+		dy:     0.5 * (float64(height) - scale*h),
+	}
+}
+
+func (v *view) xy(pt gerber.Pt) (float64, float64) {
+	x := v.dx + v.scale*(pt[0]-v.mbb.Min[0])
+	if v.mirror {
+		x = float64(v.width) - x
+	}
+	return x, float64(v.height) - v.dy - v.scale*(pt[1]-v.mbb.Min[1])
+}
+
+// rasterize returns the coverage of a layer's objects. Consecutive
+// objects of the same polarity are drawn together, then either added to
+// (dark) or erased from (clear) the coverage, so that a clear object
+// affects only the objects that precede it.
+func (v *view) rasterize(ctx context.Context, objects []*object) (*image.Alpha, error) {
+	mask := image.NewAlpha(image.Rect(0, 0, v.width, v.height))
+	for start := 0; start < len(objects); {
+		end := start + 1
+		for end < len(objects) && objects[end].dark == objects[start].dark {
+			end++
+		}
+		dc := gg.NewContext(v.width, v.height)
+		dc.SetColor(color.White)
+		for i, o := range objects[start:end] {
+			if (start+i)%ctxCheckInterval == 0 {
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+			}
+			v.draw(dc, o)
+		}
+		run := dc.AsMask()
+		if objects[start].dark {
+			draw.DrawMask(mask, mask.Bounds(), image.Opaque, image.Point{}, run, image.Point{}, draw.Over)
+		} else {
+			for i, a := range run.Pix {
+				mask.Pix[i] = uint8(uint(mask.Pix[i]) * (255 - uint(a)) / 255)
+			}
+		}
+		start = end
+	}
+	return mask, nil
+}
+
+func (v *view) draw(dc *gg.Context, o *object) {
+	fill := func(pts []gerber.Pt) {
+		for _, pt := range pts {
+			dc.LineTo(v.xy(pt))
+		}
+		dc.ClosePath()
+		dc.Fill()
+	}
+	if len(o.contours) > 0 {
+		for _, c := range o.contours {
+			fill(c)
+		}
+		return
+	}
+	if len(o.pts) == 1 {
+		x, y := v.xy(o.pts[0])
+		dc.DrawCircle(x, y, 0.5*o.width*v.scale)
+		dc.Fill()
+		return
+	}
+	dc.SetLineCapRound()
+	dc.SetLineJoinRound()
+	dc.SetLineWidth(o.width * v.scale)
+	for _, pt := range o.pts {
+		dc.LineTo(v.xy(pt))
+	}
+	dc.Stroke()
+}
+
+// invert inverts the mask within the extents of the composition.
+func (v *view) invert(mask *image.Alpha) {
+	x0, y0 := v.xy(v.mbb.Min)
+	x1, y1 := v.xy(v.mbb.Max)
+	// image.Rect swaps the coordinates of mirrored views as necessary.
+	r := image.Rect(int(math.Round(x0)), int(math.Round(y0)), int(math.Round(x1)), int(math.Round(y1))).Intersect(mask.Bounds())
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			i := mask.PixOffset(x, y)
+			mask.Pix[i] = 255 - mask.Pix[i]
+		}
+	}
+}
+
+// DefaultStyle returns the conventional preview style of a layer type,
+// that of its SVG previews (see gerber.DefaultSVGStyle). Solder mask
+// and plane layers are negative, showing the board through their
+// openings.
+func DefaultStyle(t gerber.LayerType) Style {
+	s := gerber.DefaultSVGStyle(t)
+	return Style{Color: svgColor(s.Color), Alpha: s.Opacity, Negative: s.Negative}
+}
+
+// svgColors are the named SVG colors of the default styles.
+var svgColors = map[string]color.Color{
+	"black":  color.Black,
+	"white":  color.White,
+	"yellow": color.NRGBA{255, 255, 0, 255},
+}
+
+// svgColor returns the color of an SVG color name or "#rrggbb" value
+// (gray if it is neither).
+func svgColor(s string) color.Color {
+	if c, ok := svgColors[s]; ok {
+		return c
+	}
+	var r, g, b uint8
+	if _, err := fmt.Sscanf(s, "#%02x%02x%02x", &r, &g, &b); err != nil {
+		return color.NRGBA{128, 128, 128, 255}
+	}
+	return color.NRGBA{r, g, b, 255}
+}
+
+// sideOrder is the order in which FromSide stacks the layer types
+// visible from each side of the board.
+var sideOrder = map[gerber.Side][]gerber.LayerType{
+	gerber.SideTop: {
+		gerber.TopCopperLayer,
+		gerber.TopSolderMaskLayer,
+		gerber.TopSolderPasteLayer,
+		gerber.TopSilkscreenLayer,
+		gerber.OutlineLayer,
+		gerber.DrillLayer,
+	},
+	gerber.SideBottom: {
+		gerber.BottomCopperLayer,
+		gerber.BottomSolderMaskLayer,
+		gerber.BottomSolderPasteLayer,
+		gerber.BottomSilkscreenLayer,
+		gerber.OutlineLayer,
+		gerber.DrillLayer,
+	},
+}
+
+// FromGerber derives the design's openings (see Gerber.DeriveOpenings),
+// writes each of its non-empty layers, and returns their composition as
+// seen from the top of the board (stacked as by
+// gerber.StackedLayerTypes), using DefaultStyle.
+func FromGerber(g *gerber.Gerber) (*Composition, error) {
+	return compose(g, gerber.StackedLayerTypes())
+}
+
+// FromSide is like FromGerber but only includes the layers visible
+// from one side of the board (and its outline and drills), mirrored
+// for the bottom side.
+func FromSide(g *gerber.Gerber, side gerber.Side) (*Composition, error) {
+	order, ok := sideOrder[side]
+	if !ok {
+		return nil, fmt.Errorf("unknown side %v", side)
+	}
+	c, err := compose(g, order)
+	if err != nil {
+		return nil, err
+	}
+	c.Mirror = side == gerber.SideBottom
+	return c, nil
+}
+
+func compose(g *gerber.Gerber, order []gerber.LayerType) (*Composition, error) {
+	if g == nil {
+		return nil, errors.New("nil design")
+	}
+	if err := g.DeriveOpenings(); err != nil {
+		return nil, err
+	}
+	c := &Composition{Background: color.NRGBA{20, 20, 20, 255}}
+	for _, t := range order {
+		for _, layer := range g.Layers {
+			if layer.Type != t || layer.IsEmpty() {
+				continue
+			}
+			var buf bytes.Buffer
+			if err := layer.WriteGerber(&buf); err != nil {
+				return nil, err
+			}
+			c.Layers = append(c.Layers, &Layer{Name: layer.Filename, Data: buf.Bytes(), Style: DefaultStyle(t)})
+		}
+	}
+	return c, nil
+}
+
+// RenderLayer rasterizes a single layer (in its DefaultStyle, on a
+// transparent background) at dpi pixels per inch. See
+// Composition.RenderDPI.
+func RenderLayer(l *gerber.Layer, dpi int) (*image.RGBA, error) {
+	if l == nil {
+		return nil, errors.New("nil layer")
+	}
+	var buf bytes.Buffer
+	if err := l.WriteGerber(&buf); err != nil {
+		return nil, err
+	}
+	c := &Composition{Layers: []*Layer{{Name: l.Filename, Data: buf.Bytes(), Style: DefaultStyle(l.Type)}}}
+	return c.RenderDPI(dpi)
+}
+
+func init() {
+	gerber.RegisterPreviewer(preview)
+	gerber.RegisterLayerRenderer(RenderLayer)
+}
+
+// preview implements gerber.Previewer.
+func preview(g *gerber.Gerber, side gerber.Side, opts gerber.PreviewOpts) (image.Image, error) {
+	c, err := FromSide(g, side)
+	if err != nil {
+		return nil, err
+	}
+	return c.Render(opts.Width, opts.Height)
+}
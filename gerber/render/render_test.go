@@ -0,0 +1,276 @@
+package render
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"strconv"
+	"testing"
+
+	"github.com/gmlewis/go-gerber/gerber"
+)
+
+const header = "%FSLAX36Y36*%\n%MOMM*%\n%LPD*%\n%ADD10C,0.1*%\n%ADD11R,2X2*%\n"
+
+// square returns a G36 region of the given extents (in mm).
+func square(x0, y0, x1, y1 int) string {
+	pt := func(x, y int) string {
+		return "X" + strconv.Itoa(x*1000000) + "Y" + strconv.Itoa(y*1000000)
+	}
+	return "G36*\n" + pt(x0, y0) + "D02*\n" + pt(x1, y0) + "D01*\n" + pt(x1, y1) + "D01*\n" +
+		pt(x0, y1) + "D01*\n" + pt(x0, y0) + "D01*\nG37*\n"
+}
+
+var (
+	white = color.NRGBA{255, 255, 255, 255}
+	black = color.NRGBA{0, 0, 0, 255}
+)
+
+func TestComposition_Render(t *testing.T) {
+	// The board is 10x10mm, rendered at 10 pixels per mm; pixel (x, y)
+	// samples the point (x/10, 10-y/10) mm.
+	tests := []struct {
+		name   string
+		layers []*Layer
+		// want maps pixels to their expected colors.
+		want map[[2]int]color.NRGBA
+	}{
+		{
+			name: "clear erases earlier dark",
+			layers: []*Layer{{
+				Name:  "copper",
+				Data:  []byte(header + square(0, 0, 10, 10) + "%LPC*%\n" + square(3, 3, 7, 7) + "%LPD*%\nM02*\n"),
+				Style: Style{Color: color.White, Alpha: 1},
+			}},
+			want: map[[2]int]color.NRGBA{{10, 10}: white, {50, 50}: black},
+		},
+		{
+			name: "dark after clear is drawn",
+			layers: []*Layer{{
+				Name: "copper",
+				Data: []byte(header + square(0, 0, 10, 10) + "%LPC*%\n" + square(3, 3, 7, 7) +
+					"%LPD*%\nG54D11*\nX5000000Y5000000D03*\nM02*\n"),
+				Style: Style{Color: color.White, Alpha: 1},
+			}},
+			want: map[[2]int]color.NRGBA{{10, 10}: white, {35, 50}: black, {50, 50}: white},
+		},
+		{
+			name: "negative layer",
+			layers: []*Layer{
+				{Name: "extents", Data: []byte(header + "G54D10*\nX0Y0D03*\nX10000000Y10000000D03*\nM02*\n")},
+				{
+					Name:  "mask",
+					Data:  []byte(header + square(3, 3, 7, 7) + "M02*\n"),
+					Style: Style{Color: color.White, Alpha: 1, Negative: true},
+				},
+			},
+			want: map[[2]int]color.NRGBA{{20, 20}: white, {50, 50}: black},
+		},
+		{
+			name: "step and repeat",
+			layers: []*Layer{
+				{Name: "extents", Data: []byte(header + "G54D10*\nX0Y0D03*\nX10000000Y10000000D03*\nM02*\n")},
+				{
+					Name:  "copper",
+					Data:  []byte(header + "%SRX3Y1I4.0J0*%\n" + square(0, 0, 2, 2) + "%SR*%\nM02*\n"),
+					Style: Style{Color: color.White, Alpha: 1},
+				},
+			},
+			want: map[[2]int]color.NRGBA{{10, 90}: white, {30, 90}: black, {50, 90}: white, {90, 90}: white, {90, 50}: black},
+		},
+		{
+			name: "macro flash",
+			layers: []*Layer{
+				{Name: "extents", Data: []byte(header + "G54D10*\nX0Y0D03*\nX10000000Y10000000D03*\nM02*\n")},
+				{
+					Name:  "copper",
+					Data:  []byte(header + "%AMRING*\n1,1,8,0,0*\n1,0,4,0,0*\n%\n%ADD12RING*%\nG54D12*\nX5000000Y5000000D03*\nM02*\n"),
+					Style: Style{Color: color.White, Alpha: 1},
+				},
+			},
+			want: map[[2]int]color.NRGBA{{15, 50}: white, {50, 50}: black, {50, 5}: black},
+		},
+		{
+			name: "alpha blend order",
+			layers: []*Layer{
+				{Name: "bottom", Data: []byte(header + square(0, 0, 10, 10) + "M02*\n"), Style: Style{Color: color.NRGBA{255, 0, 0, 255}, Alpha: 1}},
+				{Name: "top", Data: []byte(header + square(0, 0, 5, 10) + "M02*\n"), Style: Style{Color: color.NRGBA{0, 0, 255, 255}, Alpha: 0.5}},
+			},
+			want: map[[2]int]color.NRGBA{{25, 50}: {127, 0, 128, 255}, {75, 50}: {255, 0, 0, 255}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Composition{Background: color.Black, Layers: tt.layers}
+			img, err := c.Render(100, 100)
+			if err != nil {
+				t.Fatalf("Render: %v", err)
+			}
+			for pt, want := range tt.want {
+				got := color.NRGBAModel.Convert(img.At(pt[0], pt[1])).(color.NRGBA)
+				if !near(got, want) {
+					t.Errorf("pixel %v = %v, want %v", pt, got, want)
+				}
+			}
+		})
+	}
+}
+
+func near(a, b color.NRGBA) bool {
+	d := func(x, y uint8) bool { return int(x)+2 >= int(y) && int(y)+2 >= int(x) }
+	return d(a.R, b.R) && d(a.G, b.G) && d(a.B, b.B) && d(a.A, b.A)
+}
+
+func TestComposition_Render_Errors(t *testing.T) {
+	c := &Composition{Layers: []*Layer{{Name: "bad", Data: []byte(header + "G54D99*\n")}}}
+	if _, err := c.Render(10, 10); err == nil {
+		t.Error("Render with undefined aperture = nil error, want error")
+	}
+	if _, err := (&Composition{}).Render(0, 10); err == nil {
+		t.Error("Render(0, 10) = nil error, want error")
+	}
+}
+
+func TestComposition_RenderContext_Canceled(t *testing.T) {
+	g := gerber.New("test")
+	g.TopCopper().Add(gerber.Circle(gerber.Pt{1, 1}, 1))
+	c, err := FromGerber(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.RenderContext(ctx, 10, 10); err != context.Canceled {
+		t.Errorf("RenderContext = %v, want %v", err, context.Canceled)
+	}
+	if _, err := c.RenderDPIContext(ctx, 100); err != context.Canceled {
+		t.Errorf("RenderDPIContext = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestFromGerber(t *testing.T) {
+	g := gerber.New("test")
+	g.TopSilkscreen().Add(gerber.Circle(gerber.Pt{5, 5}, 1))
+	g.TopCopper().Add(gerber.Circle(gerber.Pt{5, 5}, 2))
+	g.BottomCopper()
+	g.Outline().Add(gerber.Polygon(gerber.Pt{}, false, []gerber.Pt{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}}, 0.1))
+
+	c, err := FromGerber(g)
+	if err != nil {
+		t.Fatalf("FromGerber: %v", err)
+	}
+	var got []string
+	for _, layer := range c.Layers {
+		got = append(got, layer.Name)
+	}
+	want := []string{"test.gtl", "test.gto", "test.gko"}
+	if len(got) != len(want) {
+		t.Fatalf("layers = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("layers[%v] = %v, want %v", i, got[i], want[i])
+		}
+	}
+	if _, err := c.Render(50, 50); err != nil {
+		t.Errorf("Render: %v", err)
+	}
+}
+
+func TestDefaultStyle(t *testing.T) {
+	tests := []struct {
+		t    gerber.LayerType
+		want Style
+	}{
+		{gerber.TopCopperLayer, Style{Color: color.NRGBA{184, 115, 51, 255}, Alpha: 1}},
+		{gerber.InnerPlaneLayer, Style{Color: color.NRGBA{184, 115, 51, 255}, Alpha: 1, Negative: true}},
+		{gerber.BottomSolderMaskLayer, Style{Color: color.NRGBA{0, 100, 0, 255}, Alpha: 0.6, Negative: true}},
+		{gerber.TopSilkscreenLayer, Style{Color: color.White, Alpha: 1}},
+		{gerber.OutlineLayer, Style{Color: color.NRGBA{255, 255, 0, 255}, Alpha: 1}},
+		{gerber.TopCoverlayLayer, Style{Color: color.NRGBA{128, 128, 255, 255}, Alpha: 0.5}},
+	}
+	for _, tt := range tests {
+		if got := DefaultStyle(tt.t); got != tt.want {
+			t.Errorf("DefaultStyle(%v) = %+v, want %+v", tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestGerber_Preview(t *testing.T) {
+	g := gerber.New("test")
+	g.TopCopper().Add(gerber.Circle(gerber.Pt{2, 5}, 2))
+	g.BottomCopper().Add(gerber.Circle(gerber.Pt{8, 5}, 2))
+	g.Outline().Add(gerber.OutlinePath(gerber.RoundedRect(gerber.MBB{Max: gerber.Pt{10, 10}}, 0), 0.1)...)
+
+	copper := color.NRGBAModel.Convert(DefaultStyle(gerber.TopCopperLayer).blend()).(color.NRGBA)
+	tests := []struct {
+		side gerber.Side
+		// pad is the pixel at the center of the side's pad, and other
+		// the pixel at the center of the other side's pad.
+		pad, other [2]int
+	}{
+		{side: gerber.SideTop, pad: [2]int{20, 50}, other: [2]int{80, 50}},
+		// The bottom is viewed from below, mirrored left to right.
+		{side: gerber.SideBottom, pad: [2]int{20, 50}, other: [2]int{80, 50}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.side.String(), func(t *testing.T) {
+			img, err := g.Preview(tt.side, &gerber.PreviewOpts{Width: 100, Height: 100})
+			if err != nil {
+				t.Fatalf("Preview: %v", err)
+			}
+			if got, want := img.Bounds().Dx(), 100; got != want {
+				t.Errorf("width = %v, want %v", got, want)
+			}
+			if got := color.NRGBAModel.Convert(img.At(tt.pad[0], tt.pad[1])).(color.NRGBA); !near(got, copper) {
+				t.Errorf("pad pixel = %v, want %v", got, copper)
+			}
+			if got := color.NRGBAModel.Convert(img.At(tt.other[0], tt.other[1])).(color.NRGBA); near(got, copper) {
+				t.Errorf("other side's pad pixel = %v, want not %v", got, copper)
+			}
+		})
+	}
+}
+
+func TestLayer_Render(t *testing.T) {
+	layer := gerber.New("test").TopCopper()
+	layer.Add(gerber.Polygon(gerber.Pt{}, true, []gerber.Pt{{0, 0}, {25.4, 0}, {25.4, 12.7}, {0, 12.7}}, 0))
+	img, err := layer.Render(100)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if got, want := img.Bounds().Size(), (image.Point{100, 50}); got != want {
+		t.Errorf("size = %v, want %v", got, want)
+	}
+	copper := color.NRGBAModel.Convert(DefaultStyle(gerber.TopCopperLayer).blend()).(color.NRGBA)
+	if got := color.NRGBAModel.Convert(img.At(50, 25)).(color.NRGBA); !near(got, copper) {
+		t.Errorf("center pixel = %v, want %v", got, copper)
+	}
+	if _, err := layer.Render(0); err == nil {
+		t.Error("Render(0) = nil error, want error")
+	}
+}
+
+func TestLayer_Render_NativeArcs(t *testing.T) {
+	layer := gerber.New("test", gerber.WithNativeArcs(true)).TopCopper()
+	// A half circle, from (9,5) to (1,5) through (5,9); the image spans
+	// 0.5-9.5mm by 4.5-9.5mm at 10 pixels per mm.
+	layer.Add(gerber.Arc(gerber.Pt{5, 5}, 4, gerber.CircleShape, 1, 1, 0, 180, 1))
+	img, err := layer.Render(254)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if got, want := img.Bounds().Size(), (image.Point{90, 50}); got != want {
+		t.Errorf("size = %v, want %v", got, want)
+	}
+	copper := color.NRGBAModel.Convert(DefaultStyle(gerber.TopCopperLayer).blend()).(color.NRGBA)
+	for _, pt := range [][2]int{{45, 5}, {85, 45}, {5, 45}, {17, 17}} {
+		if got := color.NRGBAModel.Convert(img.At(pt[0], pt[1])).(color.NRGBA); !near(got, copper) {
+			t.Errorf("arc pixel %v = %v, want %v", pt, got, copper)
+		}
+	}
+	if got := color.NRGBAModel.Convert(img.At(45, 45)).(color.NRGBA); near(got, copper) {
+		t.Errorf("center pixel = %v, want not %v", got, copper)
+	}
+}
@@ -0,0 +1,159 @@
+package gerber
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"text/tabwriter"
+)
+
+// Report summarizes a design for checking it against the capabilities
+// of a fab and estimating its price (see also Estimate and
+// JobStats.Tier). It may be marshaled as JSON. All dimensions are in
+// millimeters.
+type Report struct {
+	// Name is the design's FilenamePrefix.
+	Name string `json:"name"`
+	// Width and Height are the size of the board outline (or of the
+	// whole design if it has no outline), and Area the area (in mm²)
+	// of its bounding box.
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+	Area   float64 `json:"area"`
+	// Layers is the number of non-empty layers, and CopperLayers the
+	// number of non-empty copper layers.
+	Layers       int `json:"layers"`
+	CopperLayers int `json:"copperLayers"`
+	// Copper reports the copper of each non-empty copper layer and
+	// each plane layer, in layer order.
+	Copper []*CopperReport `json:"copper"`
+	// Drills counts the round holes of each size (see DrillCounts),
+	// and Holes the holes and slots.
+	Drills []DrillCount `json:"drills"`
+	Holes  int          `json:"holes"`
+	// MinTrace, MinSpace, and MinDrill are the narrowest trace, the
+	// smallest clearance between unconnected copper, and the smallest
+	// hole (see JobStats), or 0 if there are none.
+	MinTrace float64 `json:"minTrace"`
+	MinSpace float64 `json:"minSpace"`
+	MinDrill float64 `json:"minDrill"`
+	// Primitives is the total number of primitives in the design.
+	Primitives int `json:"primitives"`
+}
+
+// CopperReport reports the copper of one layer.
+type CopperReport struct {
+	Filename string `json:"filename"`
+	// Layer is the type of the layer (e.g. "TopCopper").
+	Layer string `json:"layer"`
+	// Area is the area (in mm²) of the copper within the board's
+	// bounding box, estimated by sampling the layer on a fine grid,
+	// and Coverage its fraction of the board's area.
+	Area     float64 `json:"area"`
+	Coverage float64 `json:"coverage"`
+}
+
+// reportSamples is the maximum number of points at which each layer
+// is sampled to estimate its copper area, and reportPitch the
+// smallest distance (in mm) between them.
+const (
+	reportSamples = 250000
+	reportPitch   = 0.05
+)
+
+// Report analyzes the design (see Estimate) and returns its report.
+func (g *Gerber) Report() *Report {
+	s := g.Estimate()
+	r := &Report{
+		Name:         g.FilenamePrefix,
+		Width:        s.Width,
+		Height:       s.Height,
+		Area:         s.Area,
+		CopperLayers: s.CopperLayers,
+		Drills:       g.DrillCounts(),
+		Holes:        s.Holes,
+		MinTrace:     s.MinTrace,
+		MinSpace:     s.MinSpace,
+		MinDrill:     s.MinDrill,
+		Primitives:   s.Primitives,
+	}
+	board := g.boardMBB()
+	for _, layer := range g.Layers {
+		if !layer.IsEmpty() {
+			r.Layers++
+		}
+		if !layer.Type.IsNegative() && (!layer.Type.IsCopper() || layer.IsEmpty()) {
+			continue
+		}
+		c := &CopperReport{Filename: layer.Filename, Layer: layer.Type.String(), Area: layer.copperArea(board)}
+		if r.Area > 0 {
+			c.Coverage = c.Area / r.Area
+		}
+		r.Copper = append(r.Copper, c)
+	}
+	return r
+}
+
+// boardMBB returns the MBB of the board outline, or of the whole
+// design if it has no outline.
+func (g *Gerber) boardMBB() MBB {
+	for _, layer := range g.Layers {
+		if layer.Type == OutlineLayer && !layer.IsEmpty() {
+			return layer.MBB()
+		}
+	}
+	return g.MBB()
+}
+
+// copperArea estimates the area (in mm²) of the copper of the layer
+// within mbb by sampling it on a grid.
+func (l *Layer) copperArea(mbb MBB) float64 {
+	w, h := mbb.Max[0]-mbb.Min[0], mbb.Max[1]-mbb.Min[1]
+	if w <= 0 || h <= 0 {
+		return 0
+	}
+	pitch := math.Max(reportPitch, math.Sqrt(w*h/reportSamples))
+	nx, ny := int(math.Ceil(w/pitch)), int(math.Ceil(h/pitch))
+	dx, dy := w/float64(nx), h/float64(ny)
+	var n int
+	for j := 0; j < ny; j++ {
+		for i := 0; i < nx; i++ {
+			if l.copperAt(Pt{mbb.Min[0] + (float64(i)+0.5)*dx, mbb.Min[1] + (float64(j)+0.5)*dy}) {
+				n++
+			}
+		}
+	}
+	return float64(n) * dx * dy
+}
+
+// copperAt reports whether the layer has copper at pt: whether the
+// last primitive containing pt is dark (or, on a plane layer, whether
+// none is).
+func (l *Layer) copperAt(pt Pt) bool {
+	dark := false
+	if ps := l.At(pt); len(ps) > 0 {
+		_, clear := ps[len(ps)-1].(*ClearT)
+		dark = !clear
+	}
+	return dark != l.Type.IsNegative()
+}
+
+// WriteText writes the report as human-readable text.
+func (r *Report) WriteText(w io.Writer) error {
+	fmt.Fprintf(w, "%v: %vx%vmm (%vmm²), %v layers (%v copper), %v primitives\n",
+		r.Name, fmtFloat(r.Width), fmtFloat(r.Height), fmtFloat(r.Area), r.Layers, r.CopperLayers, r.Primitives)
+	fmt.Fprintf(w, "min trace %vmm, min space %vmm, min drill %vmm, %v holes\n\n",
+		fmtFloat(r.MinTrace), fmtFloat(r.MinSpace), fmtFloat(r.MinDrill), r.Holes)
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "Copper\tLayer\tArea (mm²)\tCoverage")
+	for _, c := range r.Copper {
+		fmt.Fprintf(tw, "%v\t%v\t%.2f\t%.1f%%\n", c.Filename, c.Layer, c.Area, 100*c.Coverage)
+	}
+	if len(r.Drills) > 0 {
+		fmt.Fprintln(tw, "\nDrill\tPlated\tCount\t")
+		for _, d := range r.Drills {
+			fmt.Fprintf(tw, "%vmm\t%v\t%v\t\n", fmtFloat(d.Diameter), d.Plated, d.Count)
+		}
+	}
+	return tw.Flush()
+}
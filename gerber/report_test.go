@@ -0,0 +1,64 @@
+package gerber
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestGerber_Report(t *testing.T) {
+	g := New("board")
+	g.Outline().Add(OutlinePath([]Pt{{0, 0}, {20, 0}, {20, 10}, {0, 10}, {0, 0}}, 0.1)...)
+	top := g.TopCopper()
+	top.Add(
+		Polygon(Pt{}, true, []Pt{{2, 2}, {12, 2}, {12, 7}, {2, 7}}, 0),      // 50mm²
+		Clear(Polygon(Pt{}, true, []Pt{{4, 4}, {6, 4}, {6, 6}, {4, 6}}, 0)), // -4mm²
+		Line(14, 5, 18, 5, CircleShape, 0.2),
+	)
+	g.BottomCopper()
+	plane := g.PlaneN(2)
+	plane.Add(Circle(Pt{10, 5}, 2))
+	drill := g.Drill()
+	drill.Add(Circle(Pt{1, 1}, 0.3), Circle(Pt{19, 1}, 0.3), Circle(Pt{19, 9}, 1))
+	drill.Tag(drill.Primitives[2], NonPlatedTag)
+
+	r := g.Report()
+	if math.Abs(r.Width-20.1) > 1e-9 || math.Abs(r.Height-10.1) > 1e-9 || r.Layers != 4 || r.CopperLayers != 1 || r.Holes != 3 || r.MinTrace != 0.2 || r.MinDrill != 0.3 {
+		t.Errorf("Report = %+v", r)
+	}
+	if len(r.Copper) != 2 {
+		t.Fatalf("Copper = %v, want the top copper and the plane", r.Copper)
+	}
+	wantTop := 46 + 4*0.2 + math.Pi*0.01
+	if c := r.Copper[0]; c.Filename != "board.gtl" || math.Abs(c.Area-wantTop) > 0.5 || math.Abs(c.Coverage-c.Area/r.Area) > 1e-9 {
+		t.Errorf("top copper = %+v, want %vmm²", c, wantTop)
+	}
+	if c, want := r.Copper[1], r.Area-math.Pi; c.Layer != "InnerPlane" || math.Abs(c.Area-want) > 0.5 {
+		t.Errorf("plane = %+v, want %vmm²", c, want)
+	}
+	if len(r.Drills) != 2 || r.Drills[0] != (DrillCount{Diameter: 0.3, Plated: true, Count: 2}) {
+		t.Errorf("Drills = %v", r.Drills)
+	}
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"name":"board"`, `"copperLayers":1`, `"filename":"board.gtl","layer":"TopCopper"`, `{"diameter":1,"plated":false,"count":1}`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("JSON missing %v:\n%s", want, data)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := r.WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"board: 20.1x10.1mm (203.01mm²), 4 layers (1 copper)", "board.gtl", "0.3mm"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("WriteText missing %q:\n%v", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package gerber
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"io"
+)
+
+// revisionPts is the size of the revision stamp text.
+const revisionPts = 6
+
+// RevisionText returns the text of the revision stamp (e.g. "REV B"),
+// or "" if the design has no revision (see WithRevision).
+func (g *Gerber) RevisionText() string {
+	if g.revision == "" {
+		return ""
+	}
+	return "REV " + g.revision
+}
+
+// projectGUID returns a GUID that is derived from the design's name,
+// so that every release of the design has the same project ID.
+func (g *Gerber) projectGUID() string {
+	h := sha1.Sum([]byte(g.FilenamePrefix))
+	h[6] = h[6]&0x0f | 0x50 // version 5 (name based, SHA-1)
+	h[8] = h[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", h[0:4], h[4:6], h[6:8], h[8:10], h[10:16])
+}
+
+// writeProjectID writes the X2 .ProjectId file attribute that marks
+// the layer with the design's name and revision.
+func (l *Layer) writeProjectID(w io.Writer) {
+	fmt.Fprintf(w, "%%TF.ProjectId,%v,%v,%v*%%\n", escapeAttribute(l.g.FilenamePrefix), l.g.projectGUID(), escapeAttribute(l.g.revision))
+}
+
+// addRevisionStamp adds the revision stamp centered on at to the top
+// silkscreen and, mirrored so that it reads correctly from below, to
+// the bottom silkscreen if the design has one.
+func (g *Gerber) addRevisionStamp(fontName string, at Pt) error {
+	message := g.RevisionText()
+	if message == "" {
+		return nil
+	}
+	top := Text(at[0], at[1], 1, message, fontName, revisionPts, &Center)
+	if err := top.Err(); err != nil {
+		return err
+	}
+	g.firstLayerOfType(TopSilkscreenLayer).AddDerived(top)
+	for _, layer := range g.layersOfType(BottomSilkscreenLayer) {
+		layer.AddDerived(Text(at[0], at[1], -1, message, fontName, revisionPts, &Center))
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package gerber
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestWithRevision(t *testing.T) {
+	g := New("my,board", WithRevision("B*2"), WithRevisionStamp("freeserif", Pt{10, 5}), WithManifest(true), WithOutputValidation(true))
+	g.TopCopper().Add(Circle(Pt{5, 5}, 2))
+	g.BottomSilkscreen().Add(Circle(Pt{20, 5}, 1))
+	if got, want := g.RevisionText(), "REV B*2"; got != want {
+		t.Errorf("RevisionText = %q, want %q", got, want)
+	}
+	files := map[string]*memFile{}
+	err := g.Write(func(filename string) (io.WriteCloser, error) {
+		f := &memFile{}
+		files[filename] = f
+		return f, nil
+	})
+	if err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	wantID := "%TF.ProjectId,my\\u002Cboard," + g.projectGUID() + ",B\\u002A2*%"
+	for _, layer := range g.Layers {
+		if got := files[layer.Filename].String(); !strings.Contains(got, wantID) {
+			t.Errorf("%v does not contain %v:\n%v", layer.Filename, wantID, got)
+		}
+	}
+	if guid := g.projectGUID(); len(guid) != 36 || guid[14] != '5' || guid != New("my,board").projectGUID() {
+		t.Errorf("projectGUID = %v, want a stable version 5 GUID", guid)
+	}
+
+	// Write derives the stamps into copies of the layers.
+	if err := g.DeriveOpenings(); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		layer  LayerType
+		xScale float64
+	}{
+		{TopSilkscreenLayer, 1},
+		{BottomSilkscreenLayer, -1},
+	} {
+		layer := g.firstLayerOfType(tt.layer)
+		var stamps []*TextT
+		for _, p := range layer.Primitives {
+			if text, ok := p.(*TextT); ok && layer.IsDerived(p) {
+				stamps = append(stamps, text)
+			}
+		}
+		if len(stamps) != 1 {
+			t.Fatalf("%v: %v derived texts, want 1", layer.Filename, len(stamps))
+		}
+		if stamps[0].message != "REV B*2" || stamps[0].xScale != tt.xScale {
+			t.Errorf("%v: stamp %q with xScale %v, want %q with %v", layer.Filename, stamps[0].message, stamps[0].xScale, "REV B*2", tt.xScale)
+		}
+		if mbb := stamps[0].MBB(); !mbb.ContainsPoint(&Pt{10, 5}) {
+			t.Errorf("%v: stamp MBB %v, want it centered on (10,5)", layer.Filename, mbb)
+		}
+	}
+
+	jsonFile, textFile := g.ManifestFilenames()
+	var m Manifest
+	if err := json.Unmarshal(files[jsonFile].Bytes(), &m); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if m.Revision != "B*2" {
+		t.Errorf("manifest revision = %q, want B*2", m.Revision)
+	}
+	if got := files[textFile].String(); !strings.Contains(got, "my,board revision B*2") {
+		t.Errorf("text manifest does not name the revision:\n%v", got)
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(g); err != nil {
+		t.Fatal(err)
+	}
+	var ng Gerber
+	if err := json.Unmarshal(buf.Bytes(), &ng); err != nil {
+		t.Fatal(err)
+	}
+	if ng.revision != g.revision || ng.revisionFont != g.revisionFont || ng.revisionAt != g.revisionAt {
+		t.Errorf("round trip revision = %q, %q, %v, want %q, %q, %v", ng.revision, ng.revisionFont, ng.revisionAt, g.revision, g.revisionFont, g.revisionAt)
+	}
+}
+
+func TestWithRevision_Unset(t *testing.T) {
+	g := New("board", WithRevisionStamp("freeserif", Pt{10, 5}))
+	g.TopCopper().Add(Circle(Pt{5, 5}, 2))
+	if err := g.DeriveOpenings(); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(g.layersOfType(TopSilkscreenLayer)); got != 0 {
+		t.Errorf("silkscreen layers = %v, want 0 without a revision", got)
+	}
+	var buf bytes.Buffer
+	if err := g.Layers[0].WriteGerber(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "ProjectId") {
+		t.Errorf("WriteGerber wrote a .ProjectId without a revision:\n%v", buf.String())
+	}
+}
@@ -0,0 +1,115 @@
+package gerber
+
+import (
+	"errors"
+	"fmt"
+)
+
+// RogowskiOpts represents the options used by RogowskiCoil.
+// All dimensions are in millimeters and angles are in degrees.
+type RogowskiOpts struct {
+	// Turns is the number of turns of the winding.
+	Turns int
+	// InnerRadius and OuterRadius are the radii of the via
+	// centers at the inside and outside of the winding.
+	InnerRadius float64
+	OuterRadius float64
+	// TraceWidth is the width of the winding traces.
+	TraceWidth float64
+	// ViaDrill and ViaPad are the drill and pad diameters of the vias.
+	ViaDrill float64
+	ViaPad   float64
+	// GapAngle is the angular opening left for the leads. Default is
+	// the angle of one turn.
+	GapAngle float64
+	// Return generates a return trace along the mean radius of the
+	// winding, from its end back to its start, which cancels the
+	// pickup of the coil's loop area.
+	Return bool
+}
+
+// CoilLayout represents a winding spanning two copper layers,
+// as generated by RogowskiCoil.
+type CoilLayout struct {
+	// Top and Bottom are the traces on the top and bottom copper layers.
+	Top, Bottom Group
+	// Return is the return trace (see RogowskiOpts.Return). Since it
+	// crosses the winding, it must be placed on a separate copper
+	// layer (e.g. an inner layer).
+	Return Group
+	// Vias connect the layers at the ends of each trace.
+	Vias []Via
+	// Start and End are the lead connection points of the coil (on
+	// every layer). If Return is set, End is the return trace's end.
+	Start, End Pt
+}
+
+// AddTo adds the coil to the provided layers. ret may be nil if the
+// coil has no return trace.
+func (c *CoilLayout) AddTo(top, bottom, ret, drill *Layer) {
+	top.Add(c.Top...)
+	bottom.Add(c.Bottom...)
+	copper := []*Layer{top, bottom}
+	if ret != nil {
+		ret.Add(c.Return...)
+		copper = append(copper, ret)
+	}
+	for _, v := range c.Vias {
+		v.AddTo(drill, copper...)
+	}
+}
+
+// RogowskiCoil returns a toroidal (Rogowski-style) winding centered on
+// center. Each turn runs outward on the top layer, through a via,
+// and back inward on the bottom layer to the next via, so that the
+// winding encircles a conductor passing through the center.
+func RogowskiCoil(center Pt, opts RogowskiOpts) (*CoilLayout, error) {
+	o := opts
+	if o.Turns < 1 || o.InnerRadius <= 0 || o.OuterRadius <= o.InnerRadius {
+		return nil, errors.New("invalid turns or radii")
+	}
+	if o.TraceWidth <= 0 || o.ViaDrill <= 0 || o.ViaPad <= o.ViaDrill {
+		return nil, errors.New("invalid trace width or via size")
+	}
+	pitch := 360 / float64(o.Turns+1)
+	if o.GapAngle > 0 {
+		pitch = (360 - o.GapAngle) / float64(o.Turns)
+	}
+	// Adjacent inner vias are a full pitch apart (each bottom trace
+	// ends at the via where the next top trace starts), and the outer
+	// vias, half a pitch around from them, are further apart still.
+	if spacing := o.InnerRadius * Radians(pitch); spacing < o.ViaPad+o.TraceWidth {
+		return nil, fmt.Errorf("%v turns do not fit at inner radius %v", o.Turns, o.InnerRadius)
+	}
+
+	at := func(r, angle float64) Pt { return PolarFrom(center, r, angle) }
+	via := func(pt Pt) Via { return Via{Center: pt, Drill: o.ViaDrill, Pad: o.ViaPad} }
+	line := func(p1, p2 Pt) Primitive {
+		return Line(p1[0], p1[1], p2[0], p2[1], CircleShape, o.TraceWidth)
+	}
+
+	c := &CoilLayout{Start: at(o.InnerRadius, 0)}
+	c.Vias = append(c.Vias, via(c.Start))
+	for i := 0; i < o.Turns; i++ {
+		a := float64(i) * pitch
+		inner, outer, next := at(o.InnerRadius, a), at(o.OuterRadius, a+0.5*pitch), at(o.InnerRadius, a+pitch)
+		c.Top = append(c.Top, line(inner, outer))
+		c.Bottom = append(c.Bottom, line(outer, next))
+		c.Vias = append(c.Vias, via(outer), via(next))
+	}
+	c.End = at(o.InnerRadius, float64(o.Turns)*pitch)
+
+	if o.Return {
+		mean, end := 0.5*(o.InnerRadius+o.OuterRadius), float64(o.Turns)*pitch
+		// The return trace ends (at its own via) just short of the start via.
+		stop := Degrees((o.ViaPad + o.TraceWidth) / o.InnerRadius)
+		c.Return = Group{
+			line(c.End, at(mean, end)),
+			Arc(center, mean, CircleShape, 1, 1, -stop, end, o.TraceWidth),
+			line(at(mean, -stop), at(o.InnerRadius, -stop)),
+		}
+		c.End = at(o.InnerRadius, -stop)
+		c.Vias = append(c.Vias, via(c.End))
+	}
+	return c, nil
+}
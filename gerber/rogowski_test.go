@@ -0,0 +1,74 @@
+package gerber
+
+import "testing"
+
+func TestRogowskiCoil(t *testing.T) {
+	opts := RogowskiOpts{
+		Turns:       20,
+		InnerRadius: 10,
+		OuterRadius: 15,
+		TraceWidth:  0.2,
+		ViaDrill:    0.3,
+		ViaPad:      0.6,
+	}
+
+	tests := []struct {
+		name     string
+		ret      bool
+		wantVias int
+	}{
+		{name: "without return", wantVias: 41},
+		{name: "with return", ret: true, wantVias: 42},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := opts
+			o.Return = tt.ret
+			c, err := RogowskiCoil(Pt{0, 0}, o)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := len(c.Top), o.Turns; got != want {
+				t.Errorf("len(Top) = %v, want %v", got, want)
+			}
+			if got, want := len(c.Bottom), o.Turns; got != want {
+				t.Errorf("len(Bottom) = %v, want %v", got, want)
+			}
+			if got := len(c.Vias); got != tt.wantVias {
+				t.Errorf("len(Vias) = %v, want %v", got, tt.wantVias)
+			}
+			if got := len(c.Return) > 0; got != tt.ret {
+				t.Errorf("has return = %v, want %v", got, tt.ret)
+			}
+
+			// The winding must form a single electrical path: each layer's
+			// traces must not touch each other, and the vias must not touch.
+			for _, traces := range []Group{c.Top, c.Bottom} {
+				for i := 1; i < len(traces); i++ {
+					if outlineOf(traces[i-1]).overlaps(outlineOf(traces[i])) {
+						t.Errorf("traces #%v and #%v touch", i-1, i)
+					}
+				}
+			}
+			for i, v := range c.Vias {
+				for _, w := range c.Vias[i+1:] {
+					if Distance(v.Center, w.Center) < v.Pad {
+						t.Errorf("vias at %v and %v overlap", v.Center, w.Center)
+					}
+				}
+			}
+
+			g := New("test")
+			top, bottom, inner, drill := g.TopCopper(), g.BottomCopper(), g.LayerN(2), g.Drill()
+			c.AddTo(top, bottom, inner, drill)
+			if got := len(drill.Primitives); got != tt.wantVias {
+				t.Errorf("drill holes = %v, want %v", got, tt.wantVias)
+			}
+		})
+	}
+
+	if _, err := RogowskiCoil(Pt{0, 0}, RogowskiOpts{Turns: 200, InnerRadius: 5, OuterRadius: 10, TraceWidth: 0.2, ViaDrill: 0.3, ViaPad: 0.6}); err == nil {
+		t.Error("RogowskiCoil with too many turns succeeded, want error")
+	}
+}
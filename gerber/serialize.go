@@ -0,0 +1,451 @@
+package gerber
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// primitiveTypes maps the serialized type name of a primitive
+// to a function returning a new zero value of that primitive.
+var primitiveTypes = map[string]func() Primitive{}
+
+// primitiveNames maps a primitive's concrete type to its serialized name.
+var primitiveNames = map[reflect.Type]string{}
+
+func registerPrimitive(name string, newFunc func() Primitive) {
+	primitiveTypes[name] = newFunc
+	primitiveNames[reflect.TypeOf(newFunc())] = name
+}
+
+func init() {
+	registerPrimitive("aperture", func() Primitive { return &Aperture{} })
+	registerPrimitive("arc", func() Primitive { return &ArcT{} })
+	registerPrimitive("circle", func() Primitive { return &CircleT{} })
+	registerPrimitive("line", func() Primitive { return &LineT{} })
+	registerPrimitive("polygon", func() Primitive { return &PolygonT{} })
+	registerPrimitive("text", func() Primitive { return &TextT{} })
+}
+
+// primitiveJSON is the type-tagged envelope used to serialize a primitive.
+type primitiveJSON struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+	Tags []string        `json:"tags,omitempty"`
+	// Attributes are the primitive's custom attributes.
+	Attributes Attributes `json:"attributes,omitempty"`
+	// Openings and Derived record the openings declared for (or
+	// derived from) the primitive; see Gerber.DeriveOpenings.
+	Openings *Openings `json:"openings,omitempty"`
+	Derived  bool      `json:"derived,omitempty"`
+}
+
+func marshalPrimitive(p Primitive) (*primitiveJSON, error) {
+	name, ok := primitiveNames[reflect.TypeOf(p)]
+	if !ok {
+		return nil, fmt.Errorf("unable to serialize unknown primitive type %T", p)
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return &primitiveJSON{Type: name, Data: data}, nil
+}
+
+func unmarshalPrimitive(pj *primitiveJSON) (Primitive, error) {
+	newFunc, ok := primitiveTypes[pj.Type]
+	if !ok {
+		return nil, fmt.Errorf("unable to deserialize unknown primitive type %q", pj.Type)
+	}
+	p := newFunc()
+	if err := json.Unmarshal(pj.Data, p); err != nil {
+		return nil, fmt.Errorf("primitive %q: %v", pj.Type, err)
+	}
+	return p, nil
+}
+
+type layerJSON struct {
+	Filename   string            `json:"filename"`
+	Type       LayerType         `json:"type"`
+	N          int               `json:"n,omitempty"`
+	Grid       float64           `json:"grid,omitempty"`
+	Format     *CoordinateFormat `json:"format,omitempty"`
+	Primitives []*primitiveJSON  `json:"primitives"`
+}
+
+// padstackRefJSON refers to its padstack by index (in
+// gerberJSON.Padstacks) so that instances continue to share it.
+type padstackRefJSON struct {
+	Padstack   int     `json:"padstack"`
+	Center     Pt      `json:"center"`
+	Rotation   float64 `json:"rotation,omitempty"`
+	Part       string  `json:"part,omitempty"`
+	Number     string  `json:"number,omitempty"`
+	Net        string  `json:"net,omitempty"`
+	PinInPaste bool    `json:"pinInPaste,omitempty"`
+}
+
+type gerberJSON struct {
+	FilenamePrefix      string             `json:"filenamePrefix"`
+	Units               Units              `json:"units"`
+	Format              *CoordinateFormat  `json:"format,omitempty"`
+	Origin              Pt                 `json:"origin"`
+	ExportTransform     *Transform         `json:"exportTransform,omitempty"`
+	DefaultApertureSize float64            `json:"defaultApertureSize"`
+	X2                  bool               `json:"x2,omitempty"`
+	Array               bool               `json:"array,omitempty"`
+	ApertureNumbering   ApertureNumbering  `json:"apertureNumbering"`
+	ApertureTolerance   float64            `json:"apertureTolerance,omitempty"`
+	ArcTolerance        float64            `json:"arcTolerance,omitempty"`
+	NativeArcs          bool               `json:"nativeArcs,omitempty"`
+	ValidateOutput      bool               `json:"validateOutput,omitempty"`
+	Keepouts            []Keepout          `json:"keepouts,omitempty"`
+	SilkscreenMargin    float64            `json:"silkscreenMargin,omitempty"`
+	DrillChartFont      string             `json:"drillChartFont,omitempty"`
+	AutoFormat          bool               `json:"autoFormat,omitempty"`
+	Grid                float64            `json:"grid,omitempty"`
+	Manifest            bool               `json:"manifest,omitempty"`
+	GerbvProject        bool               `json:"gerbvProject,omitempty"`
+	Revision            string             `json:"revision,omitempty"`
+	RevisionFont        string             `json:"revisionFont,omitempty"`
+	RevisionAt          Pt                 `json:"revisionAt,omitempty"`
+	TestPads            *TestPadOpts       `json:"testPads,omitempty"`
+	Stackup             *Stackup           `json:"stackup,omitempty"`
+	ParallelWrites      int                `json:"parallelWrites,omitempty"`
+	OutputFormat        OutputFormat       `json:"outputFormat,omitempty"`
+	AutoPaste           bool               `json:"autoPaste,omitempty"`
+	PasteShrink         float64            `json:"pasteShrink,omitempty"`
+	Deterministic       bool               `json:"deterministic,omitempty"`
+	DrillOrder          DrillOrder         `json:"drillOrder,omitempty"`
+	SplitDrills         bool               `json:"splitDrills,omitempty"`
+	ExcellonDrills      bool               `json:"excellonDrills,omitempty"`
+	MaskPolicy          *MaskPolicy        `json:"maskPolicy,omitempty"`
+	Padstacks           []*Padstack        `json:"padstacks,omitempty"`
+	PadstackRefs        []*padstackRefJSON `json:"padstackRefs,omitempty"`
+	Components          []*Component       `json:"components,omitempty"`
+	Layers              []*layerJSON       `json:"layers"`
+}
+
+// MarshalJSON implements json.Marshaler for the full design: its
+// settings, layers, and (type-tagged) primitives.
+// Note that the FilenameConvention is not serialized; the
+// filenames of the layers are preserved instead.
+func (g *Gerber) MarshalJSON() ([]byte, error) {
+	gj := &gerberJSON{
+		FilenamePrefix:      g.FilenamePrefix,
+		Units:               g.units,
+		Format:              g.format,
+		Origin:              g.origin,
+		ExportTransform:     g.exportXform,
+		DefaultApertureSize: g.defaultApertureSize,
+		X2:                  g.x2,
+		Array:               g.array,
+		ApertureNumbering:   g.numbering,
+		ApertureTolerance:   g.apertureTolerance,
+		ArcTolerance:        g.arcTolerance,
+		NativeArcs:          g.nativeArcs,
+		ValidateOutput:      g.validateOutput,
+		Keepouts:            g.keepouts,
+		SilkscreenMargin:    g.silkscreenMargin,
+		DrillChartFont:      g.drillChartFont,
+		AutoFormat:          g.autoFormat,
+		Grid:                g.grid,
+		Manifest:            g.manifest,
+		GerbvProject:        g.gerbvProject,
+		Revision:            g.revision,
+		RevisionFont:        g.revisionFont,
+		RevisionAt:          g.revisionAt,
+		TestPads:            g.testPads,
+		Stackup:             g.stackup,
+		ParallelWrites:      g.parallelWrites,
+		OutputFormat:        g.outputFormat,
+		AutoPaste:           g.autoPaste,
+		PasteShrink:         g.pasteShrink,
+		Deterministic:       g.deterministic,
+		DrillOrder:          g.drillOrder,
+		SplitDrills:         g.splitDrills,
+		ExcellonDrills:      g.excellonDrills,
+		MaskPolicy:          g.maskPolicy,
+		Padstacks:           g.Padstacks(),
+		Components:          g.components,
+	}
+	index := map[*Padstack]int{}
+	for i, ps := range gj.Padstacks {
+		index[ps] = i
+	}
+	for _, ref := range g.padstackRefs {
+		gj.PadstackRefs = append(gj.PadstackRefs, &padstackRefJSON{
+			Padstack:   index[ref.Padstack],
+			Center:     ref.Center,
+			Rotation:   ref.Rotation,
+			Part:       ref.Part,
+			Number:     ref.Number,
+			Net:        ref.Net,
+			PinInPaste: ref.PinInPaste,
+		})
+	}
+	for _, layer := range g.Layers {
+		lj := &layerJSON{Filename: layer.Filename, Type: layer.Type, N: layer.N, Grid: layer.Grid, Format: layer.Format}
+		for _, p := range layer.Primitives {
+			pj, err := marshalPrimitive(p)
+			if err != nil {
+				return nil, fmt.Errorf("layer %v: %v", layer.Filename, err)
+			}
+			pj.Tags = layer.Tags(p)
+			pj.Attributes = layer.Attributes(p)
+			if o, ok := layer.Openings(p); ok {
+				pj.Openings = &o
+			}
+			pj.Derived = layer.IsDerived(p)
+			lj.Primitives = append(lj.Primitives, pj)
+		}
+		gj.Layers = append(gj.Layers, lj)
+	}
+	return json.Marshal(gj)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, replacing the design
+// with the one represented by data.
+func (g *Gerber) UnmarshalJSON(data []byte) error {
+	var gj gerberJSON
+	if err := json.Unmarshal(data, &gj); err != nil {
+		return err
+	}
+
+	ng := New(gj.FilenamePrefix)
+	ng.units = gj.Units
+	ng.format = gj.Format
+	ng.origin = gj.Origin
+	ng.exportXform = gj.ExportTransform
+	ng.defaultApertureSize = gj.DefaultApertureSize
+	ng.x2 = gj.X2
+	ng.array = gj.Array
+	ng.numbering = gj.ApertureNumbering
+	ng.apertureTolerance = gj.ApertureTolerance
+	ng.arcTolerance = gj.ArcTolerance
+	ng.nativeArcs = gj.NativeArcs
+	ng.validateOutput = gj.ValidateOutput
+	ng.keepouts = gj.Keepouts
+	ng.silkscreenMargin = gj.SilkscreenMargin
+	ng.drillChartFont = gj.DrillChartFont
+	ng.autoFormat = gj.AutoFormat
+	ng.grid = gj.Grid
+	ng.manifest = gj.Manifest
+	ng.gerbvProject = gj.GerbvProject
+	ng.revision = gj.Revision
+	ng.revisionFont = gj.RevisionFont
+	ng.revisionAt = gj.RevisionAt
+	ng.testPads = gj.TestPads
+	ng.stackup = gj.Stackup
+	ng.parallelWrites = gj.ParallelWrites
+	ng.outputFormat = gj.OutputFormat
+	ng.autoPaste = gj.AutoPaste
+	ng.pasteShrink = gj.PasteShrink
+	ng.deterministic = gj.Deterministic
+	ng.drillOrder = gj.DrillOrder
+	ng.splitDrills = gj.SplitDrills
+	ng.excellonDrills = gj.ExcellonDrills
+	ng.maskPolicy = gj.MaskPolicy
+	ng.components = gj.Components
+	for _, rj := range gj.PadstackRefs {
+		if rj.Padstack < 0 || rj.Padstack >= len(gj.Padstacks) {
+			return fmt.Errorf("padstack reference to unknown padstack %v", rj.Padstack)
+		}
+		ref := ng.PlacePadstack(gj.Padstacks[rj.Padstack], rj.Center, rj.Rotation)
+		ref.Part, ref.Number, ref.Net = rj.Part, rj.Number, rj.Net
+		ref.PinInPaste = rj.PinInPaste
+	}
+	for _, lj := range gj.Layers {
+		layer := ng.makeLayer(lj.Type, lj.N)
+		layer.Filename = lj.Filename
+		layer.Grid = lj.Grid
+		layer.Format = lj.Format
+		for _, pj := range lj.Primitives {
+			p, err := unmarshalPrimitive(pj)
+			if err != nil {
+				return fmt.Errorf("layer %v: %v", lj.Filename, err)
+			}
+			layer.AddTagged(pj.Tags, p)
+			layer.setAttributes(p, pj.Attributes)
+			if pj.Openings != nil {
+				layer.SetOpenings(p, *pj.Openings)
+			}
+			if pj.Derived {
+				if layer.derived == nil {
+					layer.derived = map[Primitive]bool{}
+				}
+				layer.derived[p] = true
+			}
+		}
+	}
+
+	g.FilenamePrefix = ng.FilenamePrefix
+	g.Layers = ng.Layers
+	g.mbb = nil
+	g.units = ng.units
+	g.format = ng.format
+	g.origin = ng.origin
+	g.exportXform = ng.exportXform
+	g.defaultApertureSize = ng.defaultApertureSize
+	g.x2 = ng.x2
+	g.array = ng.array
+	g.numbering = ng.numbering
+	g.apertureTolerance = ng.apertureTolerance
+	g.arcTolerance = ng.arcTolerance
+	g.nativeArcs = ng.nativeArcs
+	g.validateOutput = ng.validateOutput
+	g.keepouts = ng.keepouts
+	g.padstackRefs = ng.padstackRefs
+	g.components = ng.components
+	g.silkscreenMargin = ng.silkscreenMargin
+	g.drillChartFont = ng.drillChartFont
+	g.autoFormat = ng.autoFormat
+	g.grid = ng.grid
+	g.manifest = ng.manifest
+	g.gerbvProject = ng.gerbvProject
+	g.revision = ng.revision
+	g.revisionFont = ng.revisionFont
+	g.revisionAt = ng.revisionAt
+	g.testPads = ng.testPads
+	g.stackup = ng.stackup
+	g.parallelWrites = ng.parallelWrites
+	g.outputFormat = ng.outputFormat
+	g.autoPaste = ng.autoPaste
+	g.pasteShrink = ng.pasteShrink
+	g.deterministic = ng.deterministic
+	g.drillOrder = ng.drillOrder
+	g.splitDrills = ng.splitDrills
+	g.excellonDrills = ng.excellonDrills
+	g.maskPolicy = ng.maskPolicy
+	g.naming = ng.naming
+	for _, layer := range g.Layers {
+		layer.g = g
+	}
+	return nil
+}
+
+// GobEncode implements gob.GobEncoder. Because primitives are stored
+// as interfaces, the design is encoded using its JSON representation.
+func (g *Gerber) GobEncode() ([]byte, error) {
+	return g.MarshalJSON()
+}
+
+// GobDecode implements gob.GobDecoder.
+func (g *Gerber) GobDecode(data []byte) error {
+	return g.UnmarshalJSON(data)
+}
+
+// SaveJSON writes the design (see MarshalJSON) to w as indented JSON,
+// to be edited by external scripts (JSON is also valid YAML 1.2) and
+// reloaded with LoadJSON to continue generating or exporting it. Export
+// passes added to the pipeline (see InsertPass) and write hooks (see
+// Layer.OnHeader) are code, and must be added again after loading.
+func (g *Gerber) SaveJSON(w io.Writer) error {
+	data, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// LoadJSON reads a design written by SaveJSON (or json.Marshal).
+func LoadJSON(r io.Reader) (*Gerber, error) {
+	g := &Gerber{}
+	if err := json.NewDecoder(r).Decode(g); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+type circleJSON struct {
+	Center    Pt      `json:"center"`
+	Thickness float64 `json:"thickness"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (c *CircleT) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&circleJSON{Center: c.pt, Thickness: c.thickness})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (c *CircleT) UnmarshalJSON(data []byte) error {
+	var cj circleJSON
+	if err := json.Unmarshal(data, &cj); err != nil {
+		return err
+	}
+	*c = CircleT{pt: cj.Center, thickness: cj.Thickness}
+	return nil
+}
+
+type textJSON struct {
+	X        float64   `json:"x"`
+	Y        float64   `json:"y"`
+	XScale   float64   `json:"xScale"`
+	Opts     *TextOpts `json:"opts,omitempty"`
+	Message  string    `json:"message"`
+	FontName string    `json:"fontName"`
+	Pts      float64   `json:"pts"`
+}
+
+// MarshalJSON implements json.Marshaler.
+// The rendered glyphs are not serialized; they are regenerated on demand.
+func (t *TextT) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&textJSON{
+		X:        t.x,
+		Y:        t.y,
+		XScale:   t.xScale,
+		Opts:     t.opts,
+		Message:  t.message,
+		FontName: t.fontName,
+		Pts:      t.pts,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (t *TextT) UnmarshalJSON(data []byte) error {
+	var tj textJSON
+	if err := json.Unmarshal(data, &tj); err != nil {
+		return err
+	}
+	*t = *Text(tj.X, tj.Y, tj.XScale, tj.Message, tj.FontName, tj.Pts, tj.Opts)
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t LayerType) MarshalText() ([]byte, error) {
+	if _, ok := layerTypes[t]; !ok {
+		return nil, fmt.Errorf("unknown layer type %d", int(t))
+	}
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *LayerType) UnmarshalText(text []byte) error {
+	for k, info := range layerTypes {
+		if info.name == string(text) {
+			*t = k
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown layer type %q", text)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (u Units) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (u *Units) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "MM":
+		*u = UnitsMM
+	case "IN":
+		*u = UnitsInch
+	default:
+		return fmt.Errorf("unknown units %q", text)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package gerber
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"testing"
+)
+
+func testDesign() *Gerber {
+	g := New("test", WithUnits(UnitsInch), WithOrigin(Pt{1, 1}))
+	top := g.TopCopper()
+	top.Add(
+		Circle(Pt{1, 2}, 0.5),
+		Line(0, 0, 3.3, 1.1, RectShape, 0.2),
+		Polygon(Pt{1, 1}, true, []Pt{{0, 0}, {1, 0}, {1, 1}}, 0),
+		Arc(Pt{0, 0}, 5, CircleShape, 1, 1, 0, 90, 0.1),
+	)
+	g.LayerN(2).Add(Circle(Pt{5, 5}, 1))
+	g.TopSilkscreen().Add(Text(0, 0, 1, "Hi", "freeserif", 12, &Center))
+	g.Drill().Add(Circle(Pt{1, 2}, 0.3))
+	return g
+}
+
+func layerOutputs(t *testing.T, g *Gerber) map[string]string {
+	t.Helper()
+	files := map[string]string{}
+	for _, layer := range g.Layers {
+		var buf bytes.Buffer
+		if err := layer.WriteGerber(&buf); err != nil {
+			t.Fatalf("WriteGerber(%v): %v", layer.Filename, err)
+		}
+		files[layer.Filename] = buf.String()
+	}
+	return files
+}
+
+func compareOutputs(t *testing.T, got, want *Gerber) {
+	t.Helper()
+	gotFiles, wantFiles := layerOutputs(t, got), layerOutputs(t, want)
+	if len(gotFiles) != len(wantFiles) {
+		t.Fatalf("got %v layers, want %v", len(gotFiles), len(wantFiles))
+	}
+	for name, want := range wantFiles {
+		if got := gotFiles[name]; got != want {
+			t.Errorf("layer %v =\n%v\nwant:\n%v", name, got, want)
+		}
+	}
+}
+
+func TestGerber_JSON(t *testing.T) {
+	want := testDesign()
+	data, err := json.Marshal(want)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	got := &Gerber{}
+	if err := json.Unmarshal(data, got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	compareOutputs(t, got, want)
+}
+
+func TestGerber_SaveJSON(t *testing.T) {
+	want := testDesign()
+	var buf bytes.Buffer
+	if err := want.SaveJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadJSON(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compareOutputs(t, got, want)
+
+	// An external script edits the saved design.
+	buf.Reset()
+	if err := want.SaveJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	layer := doc["layers"].([]interface{})[0].(map[string]interface{})
+	circle := layer["primitives"].([]interface{})[0].(map[string]interface{})
+	circle["data"].(map[string]interface{})["thickness"] = 0.75
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = LoadJSON(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := got.Layers[0].Primitives[0].MBB(), (MBB{Min: Pt{0.625, 1.625}, Max: Pt{1.375, 2.375}}); !mbbNear(got, want, 1e-9) {
+		t.Errorf("edited circle MBB = %v, want %v", got, want)
+	}
+
+	if _, err := LoadJSON(bytes.NewReader([]byte(`{"layers": [{"primitives": [{"type": "bogus"}]}]}`))); err == nil {
+		t.Error("LoadJSON of an unknown primitive type succeeded")
+	}
+}
+
+func TestGerber_Gob(t *testing.T) {
+	want := testDesign()
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(want); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	got := &Gerber{}
+	if err := gob.NewDecoder(&buf).Decode(got); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	compareOutputs(t, got, want)
+}
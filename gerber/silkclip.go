@@ -0,0 +1,144 @@
+package gerber
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/gmlewis/go-gerber/gerber/internal/polyclip"
+)
+
+func init() {
+	registerPrimitive("clear", func() Primitive { return &ClearT{} })
+}
+
+// ClearT wraps a primitive so that it is written with clear polarity
+// (%LPC*%), erasing whatever was previously drawn beneath it in the
+// layer (e.g. to knock text out of a copper pour). It satisfies the
+// Primitive interface. The polarity of primitives that write clear
+// objects of their own (such as the counters of text, or nested ClearT
+// primitives) is inverted, so that they are knocked out exactly.
+type ClearT struct {
+	Primitive Primitive
+}
+
+// Clear returns a clear-polarity copy of p.
+func Clear(p Primitive) *ClearT {
+	return &ClearT{Primitive: p}
+}
+
+// WriteGerber writes the primitive to the Gerber file.
+func (c *ClearT) WriteGerber(w io.Writer, apertureIndex int) error {
+	gw := toWriter(w)
+	gw.clear = !gw.clear
+	gw.polarity(true)
+	err := c.Primitive.WriteGerber(gw, apertureIndex)
+	gw.clear = !gw.clear
+	if err != nil {
+		return err
+	}
+	gw.polarity(true)
+	return nil
+}
+
+// Aperture returns the aperture of the wrapped primitive.
+func (c *ClearT) Aperture() *Aperture {
+	return c.Primitive.Aperture()
+}
+
+// MBB returns the minimum bounding box of the wrapped primitive.
+func (c *ClearT) MBB() MBB {
+	return c.Primitive.MBB()
+}
+
+func (c *ClearT) invalidateBounds() { invalidateBounds(c.Primitive) }
+
+// Transform returns a transformed copy of the primitive. If the wrapped
+// primitive does not implement Transformer, it is returned unchanged.
+func (c *ClearT) Transform(t Transform) Primitive {
+	p, err := TransformPrimitive(c.Primitive, t)
+	if err != nil {
+		return c
+	}
+	return Clear(p)
+}
+
+// Expand returns a copy of the primitive with the wrapped primitive
+// grown by delta on each side. If the wrapped primitive does not
+// implement Expander, it is returned unchanged.
+func (c *ClearT) Expand(delta float64) Primitive {
+	p, err := ExpandPrimitive(c.Primitive, delta)
+	if err != nil {
+		return c
+	}
+	return Clear(p)
+}
+
+func (c *ClearT) String() string {
+	return fmt.Sprintf("Clear(%v)", c.Primitive)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (c *ClearT) MarshalJSON() ([]byte, error) {
+	pj, err := marshalPrimitive(c.Primitive)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(pj)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (c *ClearT) UnmarshalJSON(data []byte) error {
+	var pj primitiveJSON
+	if err := json.Unmarshal(data, &pj); err != nil {
+		return err
+	}
+	p, err := unmarshalPrimitive(&pj)
+	if err != nil {
+		return err
+	}
+	c.Primitive = p
+	return nil
+}
+
+// clipSilkscreen adds (derived) clear-polarity copies of the solder
+// mask openings, grown by margin, to the end of the silkscreen layer
+// on the same side so that silkscreen never prints on exposed copper.
+// Mask primitives that cannot be expanded are clipped by their MBB.
+func (g *Gerber) clipSilkscreen(margin float64) {
+	sides := []struct{ mask, silk LayerType }{
+		{TopSolderMaskLayer, TopSilkscreenLayer},
+		{BottomSolderMaskLayer, BottomSilkscreenLayer},
+	}
+	for _, side := range sides {
+		var clears []Primitive
+		attrs := map[Primitive]Attributes{}
+		for _, mask := range g.layersOfType(side.mask) {
+			for _, p := range mask.Primitives {
+				if _, ok := p.(*ClearT); ok {
+					continue
+				}
+				ep, err := ExpandPrimitive(p, margin)
+				if err != nil {
+					mbb := p.MBB()
+					ep = Polygon(Pt{}, true, polyclip.OffsetPolygon([]Pt{mbb.Min, {mbb.Max[0], mbb.Min[1]}, mbb.Max, {mbb.Min[0], mbb.Max[1]}}, margin), 0)
+				}
+				c := Clear(ep)
+				attrs[c] = mask.Attributes(p)
+				clears = append(clears, c)
+			}
+		}
+		if len(clears) == 0 {
+			continue
+		}
+		for _, silk := range g.layersOfType(side.silk) {
+			if silk.IsEmpty() {
+				continue
+			}
+			silk.AddDerived(clears...)
+			for _, c := range clears {
+				silk.setAttributes(c, attrs[c])
+			}
+		}
+	}
+}
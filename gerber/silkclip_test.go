@@ -0,0 +1,101 @@
+package gerber
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestWithSilkscreenClipping(t *testing.T) {
+	g := New("test", WithSilkscreenClipping(0.1))
+	g.TopCopper().AddWithOpenings(Openings{Mask: true, MaskExpansion: 0.05}, Circle(Pt{5, 5}, 1))
+	silk := g.TopSilkscreen()
+	silk.Add(Line(0, 5, 10, 5, CircleShape, 0.15))
+	g.BottomSilkscreen().Add(Line(0, 5, 10, 5, CircleShape, 0.15))
+
+	for i := 0; i < 2; i++ { // derivation is repeatable
+		if err := g.DeriveOpenings(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := len(silk.Primitives), 2; got != want {
+		t.Fatalf("top silkscreen primitives = %v, want %v", got, want)
+	}
+	c, ok := silk.Primitives[1].(*ClearT)
+	if !ok || !silk.IsDerived(c) {
+		t.Fatalf("top silkscreen primitive #1 = %v, want derived Clear", silk.Primitives[1])
+	}
+	if got, want := c.MBB(), (MBB{Min: Pt{4.35, 4.35}, Max: Pt{5.65, 5.65}}); !mbbNear(got, want, 1e-9) {
+		t.Errorf("clear MBB = %v, want %v", got, want)
+	}
+	if got := len(g.layersOfType(BottomSilkscreenLayer)[0].Primitives); got != 1 {
+		t.Errorf("bottom silkscreen primitives = %v, want 1", got)
+	}
+
+	var buf bytes.Buffer
+	if err := silk.WriteGerber(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if i, j := strings.Index(out, "D01*"), strings.Index(out, "%LPC*%\n"); i < 0 || j < i {
+		t.Errorf("WriteGerber = %v, want clear polarity after the silkscreen", out)
+	}
+
+	data, err := json.Marshal(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Gerber
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if c, ok := got.layersOfType(TopSilkscreenLayer)[0].Primitives[1].(*ClearT); !ok || c.Primitive.MBB() != silk.Primitives[1].MBB() {
+		t.Errorf("unmarshaled = %v, want %v", got.layersOfType(TopSilkscreenLayer)[0].Primitives[1], silk.Primitives[1])
+	}
+}
+
+func TestClearT_Polarity(t *testing.T) {
+	polarities := func(p Primitive) string {
+		var buf bytes.Buffer
+		if err := p.WriteGerber(&buf, 10); err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, line := range strings.Split(buf.String(), "\n") {
+			switch line {
+			case "%LPD*%", "%LPC*%":
+				got = append(got, line[3:4])
+			case "G36*":
+				got = append(got, "region")
+			}
+		}
+		return strings.Join(got, " ")
+	}
+
+	o := Text(0, 0, 1, "o", "freeserif", 72, nil)
+	tests := []struct {
+		name string
+		p    Primitive
+		want string
+	}{
+		{name: "text", p: o, want: "region C region D"},
+		{name: "clear text", p: Clear(o), want: "C region D region C D"},
+		{name: "clear clear", p: Clear(Clear(Polygon(Pt{}, true, []Pt{{0, 0}, {1, 0}, {1, 1}}, 0))), want: "C D region C D"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := polarities(tt.p); got != tt.want {
+				t.Errorf("polarities = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	grown, err := ExpandPrimitive(Clear(Circle(Pt{1, 1}, 1)), 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, ok := grown.(*ClearT); !ok || c.MBB() != (MBB{Min: Pt{0, 0}, Max: Pt{2, 2}}) {
+		t.Errorf("ExpandPrimitive = %v, want Clear(Circle((1,1), 2))", grown)
+	}
+}
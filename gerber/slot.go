@@ -0,0 +1,41 @@
+package gerber
+
+// Slot returns a routed slot of the given width (the diameter of the
+// tool) between the centers p1 and p2, for a drill layer. As on Gerber
+// drill layers, slots are lines with round caps; Excellon files route
+// them with G85 (see WriteExcellon).
+func Slot(p1, p2 Pt, width float64) *LineT {
+	return Line(p1[0], p1[1], p2[0], p2[1], CircleShape, width)
+}
+
+// AddSlot adds a slot (see Slot) to the design's first drill layer
+// (adding one if necessary), tagged with NonPlatedTag unless plated. A
+// plated slot with a pad wider than the slot (such as the slot of a
+// connector's mounting pin) also gets an obround pad of that width on
+// every copper layer. It returns the slot.
+func (g *Gerber) AddSlot(p1, p2 Pt, width, pad float64, plated bool) *LineT {
+	slot := Slot(p1, p2, width)
+	if !plated {
+		g.firstLayerOfType(DrillLayer).AddTagged([]string{NonPlatedTag}, slot)
+		return slot
+	}
+	g.firstLayerOfType(DrillLayer).Add(slot)
+	if pad > width {
+		for _, layer := range g.Layers {
+			if layer.Type.IsCopper() {
+				layer.Add(Line(p1[0], p1[1], p2[0], p2[1], CircleShape, pad))
+			}
+		}
+	}
+	return slot
+}
+
+// AddMilledSlot adds the outline (drawn with lines and arcs of the
+// given width, see SlotOutline) of an internal cutout of width
+// slotWidth between the centers p1 and p2 to the design's first outline
+// layer (adding one if necessary), for slots too long or wide to route
+// as holes. Cutouts of other shapes may be added to the outline layer
+// with RoundedRectOutline, CircularOutline, or OutlinePath.
+func (g *Gerber) AddMilledSlot(p1, p2 Pt, slotWidth, width float64) {
+	g.firstLayerOfType(OutlineLayer).Add(SlotOutline(p1, p2, slotWidth, width)...)
+}
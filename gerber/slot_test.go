@@ -0,0 +1,79 @@
+package gerber
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestGerber_AddSlot(t *testing.T) {
+	g := New("board")
+	top, bottom := g.TopCopper(), g.BottomCopper()
+	plated := g.AddSlot(Pt{1, 1}, Pt{3, 1}, 0.8, 1.6, true)
+	npth := g.AddSlot(Pt{5, 1}, Pt{5, 3}, 1, 2, false)
+	g.AddMilledSlot(Pt{10, 0}, Pt{20, 0}, 3, 0.1)
+
+	drill := g.layersOfType(DrillLayer)[0]
+	if got := len(drill.Primitives); got != 2 {
+		t.Fatalf("drill layer has %v primitives, want 2", got)
+	}
+	if drill.HasTag(plated, NonPlatedTag) || !drill.HasTag(npth, NonPlatedTag) {
+		t.Error("slots are not tagged by plating")
+	}
+	for _, layer := range []*Layer{top, bottom} {
+		if len(layer.Primitives) != 1 {
+			t.Fatalf("%v has %v primitives, want the plated slot's pad", layer.Type, len(layer.Primitives))
+		}
+		if got, want := layer.Primitives[0].MBB(), (MBB{Min: Pt{0.2, 0.2}, Max: Pt{3.8, 1.8}}); !mbbNear(got, want, 1e-12) {
+			t.Errorf("%v pad MBB = %v, want %v", layer.Type, got, want)
+		}
+	}
+	if got, want := g.layersOfType(OutlineLayer)[0].MBB(), (MBB{Min: Pt{8.45, -1.55}, Max: Pt{21.55, 1.55}}); !mbbNear(got, want, 1e-9) {
+		t.Errorf("milled slot MBB = %v, want %v", got, want)
+	}
+}
+
+func TestGerber_WriteExcellon(t *testing.T) {
+	g := New("board")
+	g.Drill().Add(Circle(Pt{1, 2}, 0.8), Circle(Pt{-1.5, 2}, 0.3))
+	g.AddSlot(Pt{1, 1}, Pt{3, 1}, 0.8, 0, true)
+	g.AddSlot(Pt{5, 1}, Pt{5, 3}, 1, 0, false)
+
+	var buf bytes.Buffer
+	if err := g.WriteExcellon(&buf, true); err != nil {
+		t.Fatal(err)
+	}
+	want := `M48
+; board PTH drill file
+; FORMAT={-:-/ absolute / metric / decimal}
+FMAT,2
+METRIC
+T1C0.3
+T2C0.8
+%
+G90
+G05
+T1
+X-1.5Y2.0
+T2
+X1.0Y2.0
+X1.0Y1.0G85X3.0Y1.0
+T0
+M30
+`
+	if got := buf.String(); got != want {
+		t.Errorf("WriteExcellon(plated) =\n%v\nwant:\n%v", got, want)
+	}
+
+	buf.Reset()
+	if err := g.WriteExcellon(&buf, false); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); !bytes.Contains([]byte(got), []byte("T1C1\n%\nG90\nG05\nT1\nX5.0Y1.0G85X5.0Y3.0\n")) {
+		t.Errorf("WriteExcellon(non-plated) =\n%v", got)
+	}
+
+	g.Drill().Add(Pad(Pt{}, RectShape, 1, 1, 0))
+	if err := g.WriteExcellon(&buf, true); err == nil {
+		t.Error("WriteExcellon of a rectangular pad succeeded")
+	}
+}
@@ -0,0 +1,173 @@
+package gerber
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Snapshot is a record of the primitives in each layer of a design at
+// a point in time, suitable for comparison with Diff.
+type Snapshot struct {
+	Layers []*LayerSnapshot
+}
+
+// LayerSnapshot records the primitives of a single layer.
+type LayerSnapshot struct {
+	Filename   string
+	Type       LayerType
+	N          int
+	Primitives []*PrimitiveSnapshot
+}
+
+// PrimitiveSnapshot records a single primitive.
+type PrimitiveSnapshot struct {
+	// Index is the index of the primitive within its layer.
+	Index int
+	// Type is the registered type name of the primitive (see RegisterPrimitive).
+	Type string
+	// Data is the serialized (JSON) representation of the primitive.
+	Data string
+}
+
+func (p *PrimitiveSnapshot) String() string {
+	return fmt.Sprintf("#%v %v%v", p.Index, p.Type, p.Data)
+}
+
+// Snapshot returns a snapshot of the design. All primitives must be
+// serializable (see RegisterPrimitive).
+func (g *Gerber) Snapshot() (*Snapshot, error) {
+	s := &Snapshot{}
+	for _, layer := range g.Layers {
+		ls := &LayerSnapshot{Filename: layer.Filename, Type: layer.Type, N: layer.N}
+		for i, p := range layer.Primitives {
+			pj, err := marshalPrimitive(p)
+			if err != nil {
+				return nil, fmt.Errorf("layer %v: %v", layer.Filename, err)
+			}
+			ls.Primitives = append(ls.Primitives, &PrimitiveSnapshot{Index: i, Type: pj.Type, Data: string(pj.Data)})
+		}
+		s.Layers = append(s.Layers, ls)
+	}
+	return s, nil
+}
+
+// ChangeKind represents the kind of a Change.
+type ChangeKind int
+
+const (
+	// Added indicates a primitive that is only in the second snapshot.
+	Added ChangeKind = iota
+	// Removed indicates a primitive that is only in the first snapshot.
+	Removed
+	// Changed indicates a primitive of the same type and index whose data differs.
+	Changed
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	}
+	return "changed"
+}
+
+// Change represents a single difference between two snapshots.
+type Change struct {
+	// Layer is the filename of the layer containing the change.
+	Layer string
+	Kind  ChangeKind
+	// Before is the primitive in the first snapshot (nil if Added).
+	Before *PrimitiveSnapshot
+	// After is the primitive in the second snapshot (nil if Removed).
+	After *PrimitiveSnapshot
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("%v: added %v", c.Layer, c.After)
+	case Removed:
+		return fmt.Sprintf("%v: removed %v", c.Layer, c.Before)
+	}
+	return fmt.Sprintf("%v: changed %v to %v", c.Layer, c.Before, c.After)
+}
+
+// Diff returns the differences between snapshots a and b, per layer
+// (matched by filename). Primitives are compared as multisets, so
+// reordering primitives within a layer does not produce changes.
+// A removed and an added primitive with the same index and type are
+// reported together as a single Changed entry.
+func Diff(a, b *Snapshot) []Change {
+	var changes []Change
+	bLayers := map[string]*LayerSnapshot{}
+	for _, l := range b.Layers {
+		bLayers[l.Filename] = l
+	}
+	seen := map[string]bool{}
+	for _, la := range a.Layers {
+		seen[la.Filename] = true
+		changes = append(changes, diffLayer(la.Filename, la.Primitives, primitivesOf(bLayers[la.Filename]))...)
+	}
+	for _, lb := range b.Layers {
+		if !seen[lb.Filename] {
+			changes = append(changes, diffLayer(lb.Filename, nil, lb.Primitives)...)
+		}
+	}
+	return changes
+}
+
+func primitivesOf(l *LayerSnapshot) []*PrimitiveSnapshot {
+	if l == nil {
+		return nil
+	}
+	return l.Primitives
+}
+
+func diffLayer(layer string, a, b []*PrimitiveSnapshot) []Change {
+	key := func(p *PrimitiveSnapshot) string { return p.Type + p.Data }
+	counts := map[string]int{}
+	for _, p := range b {
+		counts[key(p)]++
+	}
+	var removed []*PrimitiveSnapshot
+	for _, p := range a {
+		if counts[key(p)] > 0 {
+			counts[key(p)]--
+			continue
+		}
+		removed = append(removed, p)
+	}
+	counts = map[string]int{}
+	for _, p := range a {
+		counts[key(p)]++
+	}
+	added := map[int]*PrimitiveSnapshot{}
+	for _, p := range b {
+		if counts[key(p)] > 0 {
+			counts[key(p)]--
+			continue
+		}
+		added[p.Index] = p
+	}
+
+	var changes []Change
+	for _, p := range removed {
+		if q, ok := added[p.Index]; ok && q.Type == p.Type {
+			changes = append(changes, Change{Layer: layer, Kind: Changed, Before: p, After: q})
+			delete(added, p.Index)
+			continue
+		}
+		changes = append(changes, Change{Layer: layer, Kind: Removed, Before: p})
+	}
+	var indices []int
+	for i := range added {
+		indices = append(indices, i)
+	}
+	sort.Ints(indices)
+	for _, i := range indices {
+		changes = append(changes, Change{Layer: layer, Kind: Added, After: added[i]})
+	}
+	return changes
+}
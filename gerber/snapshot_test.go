@@ -0,0 +1,47 @@
+package gerber
+
+import (
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	g := New("test")
+	top := g.TopCopper()
+	top.Add(Circle(Pt{1, 1}, 1), Circle(Pt{2, 2}, 1), Line(0, 0, 1, 1, CircleShape, 0.1))
+	before, err := g.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Change, remove, and add primitives.
+	top.Primitives = []Primitive{
+		Circle(Pt{1, 1}, 0.5),
+		Circle(Pt{2, 2}, 1),
+		Circle(Pt{3, 3}, 1),
+	}
+	g.Drill().Add(Circle(Pt{1, 1}, 0.3))
+	after, err := g.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		`test.gtl: changed #0 circle{"center":[1,1],"thickness":1} to #0 circle{"center":[1,1],"thickness":0.5}`,
+		`test.gtl: removed #2 line{"P1":[0,0],"P2":[1,1],"Shape":"C","Thickness":0.1}`,
+		`test.gtl: added #2 circle{"center":[3,3],"thickness":1}`,
+		`test.drl: added #0 circle{"center":[1,1],"thickness":0.3}`,
+	}
+	changes := Diff(before, after)
+	if len(changes) != len(want) {
+		t.Fatalf("Diff = %v, want %v", changes, want)
+	}
+	for i, c := range changes {
+		if got := c.String(); got != want[i] {
+			t.Errorf("change[%v] = %v, want %v", i, got, want[i])
+		}
+	}
+
+	if changes := Diff(after, after); len(changes) != 0 {
+		t.Errorf("Diff(after, after) = %v, want none", changes)
+	}
+}
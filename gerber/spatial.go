@@ -0,0 +1,276 @@
+package gerber
+
+import (
+	"math"
+	"sort"
+)
+
+// spatialIndex buckets primitives by their minimum bounding boxes in a
+// uniform grid, so that generators placing many features (such as
+// AddThieving and AddStitchingVias) can quickly find the obstacles near
+// each site, and layers can answer spatial queries (see Layer.Query)
+// without visiting every primitive.
+type spatialIndex struct {
+	cell  float64
+	cells map[[2]int][]int
+	items []indexItem
+	// bounds is the range of cells holding items.
+	bounds [4]int
+}
+
+// indexItem is an obstacle that sites must stay clearance millimeters
+// away from.
+type indexItem struct {
+	p         Primitive
+	clearance float64
+	mbb       MBB // the MBB of p, grown by clearance
+}
+
+// spatialIndexCell is the default cell size (in mm) of a spatialIndex.
+const spatialIndexCell = 5
+
+func newSpatialIndex(cell float64) *spatialIndex {
+	if cell <= 0 {
+		cell = spatialIndexCell
+	}
+	return &spatialIndex{cell: cell, cells: map[[2]int][]int{}}
+}
+
+// cellRange returns the range of cells covered by mbb.
+func (s *spatialIndex) cellRange(mbb MBB) (x0, y0, x1, y1 int) {
+	return int(math.Floor(mbb.Min[0] / s.cell)), int(math.Floor(mbb.Min[1] / s.cell)),
+		int(math.Floor(mbb.Max[0] / s.cell)), int(math.Floor(mbb.Max[1] / s.cell))
+}
+
+// insert adds an obstacle to the index.
+func (s *spatialIndex) insert(p Primitive, clearance float64) {
+	mbb := p.MBB()
+	mbb = MBB{
+		Min: Pt{mbb.Min[0] - clearance, mbb.Min[1] - clearance},
+		Max: Pt{mbb.Max[0] + clearance, mbb.Max[1] + clearance},
+	}
+	n := len(s.items)
+	s.items = append(s.items, indexItem{p: p, clearance: clearance, mbb: mbb})
+	x0, y0, x1, y1 := s.cellRange(mbb)
+	if n == 0 {
+		s.bounds = [4]int{x0, y0, x1, y1}
+	} else {
+		s.bounds = [4]int{min(s.bounds[0], x0), min(s.bounds[1], y0), max(s.bounds[2], x1), max(s.bounds[3], y1)}
+	}
+	for x := x0; x <= x1; x++ {
+		for y := y0; y <= y1; y++ {
+			key := [2]int{x, y}
+			s.cells[key] = append(s.cells[key], n)
+		}
+	}
+}
+
+// query returns the indices (in insertion order) of the items whose
+// MBBs intersect mbb.
+func (s *spatialIndex) query(mbb MBB) []int {
+	if len(s.items) == 0 {
+		return nil
+	}
+	x0, y0, x1, y1 := s.cellRange(mbb)
+	x0, y0 = max(x0, s.bounds[0]), max(y0, s.bounds[1])
+	x1, y1 = min(x1, s.bounds[2]), min(y1, s.bounds[3])
+	var result []int
+	seen := map[int]bool{}
+	for x := x0; x <= x1; x++ {
+		for y := y0; y <= y1; y++ {
+			for _, i := range s.cells[[2]int{x, y}] {
+				if seen[i] {
+					continue
+				}
+				seen[i] = true
+				if s.items[i].mbb.Intersects(&mbb) {
+					result = append(result, i)
+				}
+			}
+		}
+	}
+	sort.Ints(result)
+	return result
+}
+
+// nearest returns the index of the item nearest to pt, as measured by
+// dist (which must be no less than the distance from pt to the item's
+// MBB), and its distance. Ties go to the earliest item. It returns -1
+// if the index is empty.
+func (s *spatialIndex) nearest(pt Pt, dist func(i int) float64) (int, float64) {
+	best, bestDist := -1, math.Inf(1)
+	if len(s.items) == 0 {
+		return best, bestDist
+	}
+	cx, cy := int(math.Floor(pt[0]/s.cell)), int(math.Floor(pt[1]/s.cell))
+	// Rings of cells closer than r0 to the center cell are empty.
+	r0 := max(0, max(max(s.bounds[0]-cx, cx-s.bounds[2]), max(s.bounds[1]-cy, cy-s.bounds[3])))
+	r1 := max(max(cx-s.bounds[0], s.bounds[2]-cx), max(cy-s.bounds[1], s.bounds[3]-cy))
+	seen := map[int]bool{}
+	visit := func(x, y int) {
+		if x < s.bounds[0] || x > s.bounds[2] || y < s.bounds[1] || y > s.bounds[3] {
+			return
+		}
+		for _, i := range s.cells[[2]int{x, y}] {
+			if seen[i] {
+				continue
+			}
+			seen[i] = true
+			if mbbDistance(s.items[i].mbb, pt) > bestDist {
+				continue
+			}
+			if d := dist(i); d < bestDist || (d == bestDist && i < best) {
+				best, bestDist = i, d
+			}
+		}
+	}
+	for r := r0; r <= r1; r++ {
+		// pt lies in the center cell, so the items of ring r (and
+		// beyond) are at least r-1 cells from it.
+		if float64(r-1)*s.cell > bestDist {
+			break
+		}
+		for x := cx - r; x <= cx+r; x++ {
+			visit(x, cy-r)
+			if r > 0 {
+				visit(x, cy+r)
+			}
+		}
+		for y := cy - r + 1; y < cy+r; y++ {
+			visit(cx-r, y)
+			visit(cx+r, y)
+		}
+	}
+	return best, bestDist
+}
+
+// mbbDistance returns the distance from pt to mbb, or 0 if mbb
+// contains pt.
+func mbbDistance(mbb MBB, pt Pt) float64 {
+	dx := math.Max(0, math.Max(mbb.Min[0]-pt[0], pt[0]-mbb.Max[0]))
+	dy := math.Max(0, math.Max(mbb.Min[1]-pt[1], pt[1]-mbb.Max[1]))
+	return math.Hypot(dx, dy)
+}
+
+// blocked reports whether a circle of diameter d centered at pt comes
+// within the clearance of any obstacle in the index.
+func (s *spatialIndex) blocked(pt Pt, d float64) bool {
+	r := 0.5 * d
+	for _, i := range s.query(MBB{Min: Pt{pt[0] - r, pt[1] - r}, Max: Pt{pt[0] + r, pt[1] + r}}) {
+		item := s.items[i]
+		site := outlineOf(Circle(pt, d+2*item.clearance))
+		if outlineOf(item.p).overlaps(site) {
+			return true
+		}
+	}
+	return false
+}
+
+// spatial returns the spatial index of the layer's primitives (whose
+// items are in the order of Primitives), building it if the layer has
+// changed since it was last built. Add extends the index in place.
+func (l *Layer) spatial() *spatialIndex {
+	if l.indexCurrent() {
+		return l.index
+	}
+	l.index = newSpatialIndex(layerIndexCell(l.Primitives))
+	for _, p := range l.Primitives {
+		l.index.insert(p, 0)
+	}
+	l.indexed = l.Primitives
+	return l.index
+}
+
+// indexCurrent reports whether the layer's spatial index was built from
+// its current Primitives slice, rather than from one that has since been
+// replaced (e.g. by assigning Primitives directly).
+func (l *Layer) indexCurrent() bool {
+	if l.index == nil || len(l.indexed) != len(l.Primitives) {
+		return false
+	}
+	return len(l.Primitives) == 0 || &l.indexed[0] == &l.Primitives[0]
+}
+
+// layerIndexCell returns the cell size of a spatial index of the
+// primitives: the larger of the typical (median) primitive and the cell
+// that would hold one primitive if they were spread evenly, so that
+// neither many primitives share a cell nor one spans many cells.
+func layerIndexCell(primitives []Primitive) float64 {
+	if len(primitives) == 0 {
+		return spatialIndexCell
+	}
+	sizes := make([]float64, len(primitives))
+	var all MBB
+	for i, p := range primitives {
+		mbb := p.MBB()
+		sizes[i] = math.Max(mbb.Max[0]-mbb.Min[0], mbb.Max[1]-mbb.Min[1])
+		if i == 0 {
+			all = mbb
+		} else {
+			all.Join(&mbb)
+		}
+	}
+	sort.Float64s(sizes)
+	area := (all.Max[0] - all.Min[0]) * (all.Max[1] - all.Min[1])
+	cell := math.Max(sizes[len(sizes)/2], math.Sqrt(area/float64(len(primitives))))
+	if cell <= 0 || math.IsInf(cell, 0) || math.IsNaN(cell) {
+		return spatialIndexCell
+	}
+	return cell
+}
+
+// Query returns the primitives of the layer whose MBBs intersect mbb,
+// in layer order. The layer keeps a spatial index of its primitives,
+// so that queries of large layers only visit the primitives near mbb.
+// The index is extended by Add and rebuilt by the editing methods
+// (such as Remove) or when Primitives is assigned a new slice; see
+// InvalidateBounds if primitives are modified in place.
+func (l *Layer) Query(mbb MBB) []Primitive {
+	s := l.spatial()
+	var result []Primitive
+	for _, i := range s.query(mbb) {
+		result = append(result, l.Primitives[i])
+	}
+	return result
+}
+
+// At returns the primitives of the layer that contain pt (see
+// PrimitiveContains), in layer order.
+func (l *Layer) At(pt Pt) []Primitive {
+	var result []Primitive
+	for _, p := range l.Query(MBB{Min: pt, Max: pt}) {
+		if PrimitiveContains(p, pt) {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// Nearest returns the primitive of the layer nearest to pt and its
+// distance (0 if it contains pt, see PrimitiveDistance), or nil if the
+// layer is empty. Ties go to the earliest primitive.
+func (l *Layer) Nearest(pt Pt) (Primitive, float64) {
+	s := l.spatial()
+	target := outlineOf(Circle(pt, 0))
+	i, d := s.nearest(pt, func(i int) float64 {
+		return outlineOf(l.Primitives[i]).distance(target)
+	})
+	if i < 0 {
+		return nil, 0
+	}
+	return l.Primitives[i], d
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,106 @@
+package gerber
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func randomLayer(r *rand.Rand, n int) *Layer {
+	g := New("test")
+	layer := g.TopCopper()
+	for i := 0; i < n; i++ {
+		x, y := 100*r.Float64(), 50*r.Float64()
+		switch i % 3 {
+		case 0:
+			layer.Add(Circle(Pt{x, y}, 0.1+r.Float64()))
+		case 1:
+			layer.Add(Line(x, y, x+5*r.Float64(), y+5*r.Float64(), CircleShape, 0.2))
+		default:
+			layer.Add(Pad(Pt{x, y}, RectShape, 2, 1, 30))
+		}
+	}
+	return layer
+}
+
+func TestLayer_Query(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	layer := randomLayer(r, 500)
+	for k := 0; k < 100; k++ {
+		x, y := -10+120*r.Float64(), -10+70*r.Float64()
+		mbb := MBB{Min: Pt{x, y}, Max: Pt{x + 10*r.Float64(), y + 10*r.Float64()}}
+		var want []Primitive
+		for _, p := range layer.Primitives {
+			if pm := p.MBB(); pm.Intersects(&mbb) {
+				want = append(want, p)
+			}
+		}
+		if got := layer.Query(mbb); !reflect.DeepEqual(got, want) {
+			t.Fatalf("Query(%v) = %v primitives, want %v", mbb, len(got), len(want))
+		}
+		if k == 50 {
+			// Primitives added after the index is built are found.
+			layer.Add(Circle(Pt{200, 200}, 1))
+			if got := layer.Query(MBB{Min: Pt{199, 199}, Max: Pt{201, 201}}); len(got) != 1 {
+				t.Fatalf("Query after Add = %v", got)
+			}
+		}
+	}
+
+	layer.Remove(func(p Primitive) bool { _, ok := p.(*CircleT); return ok })
+	for _, p := range layer.Query(layer.MBB()) {
+		if _, ok := p.(*CircleT); ok {
+			t.Fatal("Query after Remove returned a removed primitive")
+		}
+	}
+	if got := New("empty").TopCopper().Query(MBB{Max: Pt{1, 1}}); got != nil {
+		t.Errorf("Query of an empty layer = %v", got)
+	}
+
+	// Assigning Primitives a new slice of the same length rebuilds the
+	// index.
+	moved := make([]Primitive, len(layer.Primitives))
+	for i := range moved {
+		moved[i] = Circle(Pt{300, 300}, 1)
+	}
+	layer.Primitives = moved
+	if got := layer.Query(MBB{Min: Pt{299, 299}, Max: Pt{301, 301}}); len(got) != len(moved) {
+		t.Errorf("Query after assigning Primitives = %v primitives, want %v", len(got), len(moved))
+	}
+}
+
+func TestLayer_At(t *testing.T) {
+	layer := New("test").TopCopper()
+	a, b := Circle(Pt{0, 0}, 2), Pad(Pt{1, 0}, RectShape, 1, 1, 0)
+	layer.Add(a, b, Line(5, 5, 10, 5, CircleShape, 0.5))
+	if got, want := layer.At(Pt{0.75, 0}), []Primitive{a, b}; !reflect.DeepEqual(got, want) {
+		t.Errorf("At = %v, want %v", got, want)
+	}
+	// The corner of a's MBB is outside the circle.
+	if got := layer.At(Pt{-0.95, 0.95}); got != nil {
+		t.Errorf("At(corner) = %v, want none", got)
+	}
+}
+
+func TestLayer_Nearest(t *testing.T) {
+	if p, _ := New("empty").TopCopper().Nearest(Pt{}); p != nil {
+		t.Errorf("Nearest of an empty layer = %v", p)
+	}
+	r := rand.New(rand.NewSource(2))
+	layer := randomLayer(r, 300)
+	for k := 0; k < 100; k++ {
+		pt := Pt{-50 + 200*r.Float64(), -50 + 150*r.Float64()}
+		var want Primitive
+		wantDist := math.Inf(1)
+		for _, p := range layer.Primitives {
+			if d := PrimitiveDistance(p, Circle(pt, 0)); d < wantDist {
+				want, wantDist = p, d
+			}
+		}
+		got, d := layer.Nearest(pt)
+		if got != want || math.Abs(d-wantDist) > 1e-12 {
+			t.Fatalf("Nearest(%v) = %v, %v, want %v, %v", pt, got, d, want, wantDist)
+		}
+	}
+}
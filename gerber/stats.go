@@ -0,0 +1,228 @@
+package gerber
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+// ApertureStats reports the usage of one aperture in a layer.
+type ApertureStats struct {
+	// Code is the aperture's D-code and Aperture the aperture itself.
+	Code     int
+	Aperture *Aperture
+	// Default reports whether this is the layer's default aperture.
+	Default bool
+	// Flashes is the number of zero-length exposures (e.g. circles),
+	// and Draws the number of (non-zero length) interpolations.
+	Flashes int
+	Draws   int
+	// DrawLength is the total length (in mm) of the draws.
+	DrawLength float64
+}
+
+// LayerStats reports statistics about the Gerber output of a layer,
+// useful for sanity-checking a design before ordering.
+type LayerStats struct {
+	Layer *Layer
+	// Apertures reports the usage of each aperture, in D-code order.
+	Apertures []*ApertureStats
+	// Flashes, Draws, and DrawLength are the totals over all apertures.
+	Flashes    int
+	Draws      int
+	DrawLength float64
+	// Regions is the number of filled regions (G36/G37).
+	Regions int
+	// MergedApertures is the number of apertures that were merged into
+	// a similar one (see WithApertureTolerance).
+	MergedApertures int
+	// Smallest is the smallest aperture used by any flash or draw
+	// (nil if there are none).
+	Smallest *Aperture
+	// Extents is the MBB (in mm) of the coordinates as written, after
+	// the design's origin and export transform are applied. It does
+	// not include the size of the apertures.
+	Extents MBB
+}
+
+// Stats writes the layer (without X2 attributes or hooks) and returns
+// statistics about its output.
+func (l *Layer) Stats() (*LayerStats, error) {
+	defaultCode, codes, err := l.apertureCodes()
+	if err != nil {
+		return nil, fmt.Errorf("layer %v: %v", l.Filename, err)
+	}
+	defaultSize := defaultApertureSize
+	if l.g != nil {
+		defaultSize = l.g.defaultApertureSize
+	}
+	s := &LayerStats{Layer: l, MergedApertures: l.mergedApertures}
+	byCode := map[int]*ApertureStats{}
+	if defaultSize > 0 {
+		byCode[defaultCode] = &ApertureStats{Code: defaultCode, Aperture: &Aperture{Shape: CircleShape, Size: defaultSize}, Default: true}
+	}
+	for i, a := range l.Apertures {
+		byCode[codes[i]] = &ApertureStats{Code: codes[i], Aperture: a}
+	}
+
+	var buf bytes.Buffer
+	gw := l.newWriter(&buf)
+	if defaultSize <= 0 {
+		gw.omitted = defaultCode
+	}
+	gw.dcode = l.dcodes(defaultCode, codes)
+	gw.stepRepeat = true // write every cell of step and repeat arrays
+	for i, p := range l.Primitives {
+		code := gw.dcode(p.Aperture())
+		if err := p.WriteGerber(gw, code); err != nil {
+			return nil, fmt.Errorf("layer %v: primitive #%v: %v", l.Filename, i, err)
+		}
+	}
+
+	var current *ApertureStats
+	var inRegion, started, clockwise bool
+	var last Pt
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		line := scanner.Text()
+		var x, y, i, j int64
+		var d int
+		switch {
+		case line == "G02*" || line == "G03*":
+			clockwise = line == "G02*"
+			continue
+		case strings.HasPrefix(line, "G54D"):
+			if _, err := fmt.Sscanf(line, "G54D%d*", &d); err == nil {
+				current = byCode[d]
+			}
+			continue
+		case line == "G36*":
+			inRegion = true
+			continue
+		case line == "G37*":
+			inRegion = false
+			s.Regions++
+			continue
+		}
+		isArc := false
+		if _, err := fmt.Sscanf(line, "X%dY%dI%dJ%dD%d*", &x, &y, &i, &j, &d); err == nil {
+			isArc = true
+		} else if _, err := fmt.Sscanf(line, "X%dY%dD%d*", &x, &y, &d); err != nil {
+			continue
+		}
+		pt := Pt{float64(x) / gw.scale, float64(y) / gw.scale}
+		if !started {
+			s.Extents = MBB{Min: pt, Max: pt}
+			started = true
+		} else {
+			v := MBB{Min: pt, Max: pt}
+			s.Extents.Join(&v)
+		}
+		prev := last
+		last = pt
+		if inRegion || current == nil || (d != 1 && d != 3) {
+			continue
+		}
+		if isArc {
+			center := Pt{prev[0] + float64(i)/gw.scale, prev[1] + float64(j)/gw.scale}
+			current.Draws++
+			current.DrawLength += arcLength(prev, pt, center, clockwise)
+			continue
+		}
+		if length := Distance(prev, pt); d == 3 || length == 0 {
+			current.Flashes++
+		} else {
+			current.Draws++
+			current.DrawLength += length
+		}
+	}
+
+	for _, code := range sortedCodes(byCode) {
+		as := byCode[code]
+		s.Apertures = append(s.Apertures, as)
+		s.Flashes += as.Flashes
+		s.Draws += as.Draws
+		s.DrawLength += as.DrawLength
+		if as.Flashes+as.Draws > 0 && as.Aperture.Macro == nil && (s.Smallest == nil || as.Aperture.Size < s.Smallest.Size) {
+			s.Smallest = as.Aperture
+		}
+	}
+	return s, nil
+}
+
+func sortedCodes(byCode map[int]*ApertureStats) []int {
+	var codes []int
+	for code := range byCode {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	return codes
+}
+
+// Stats derives the design's openings (see DeriveOpenings) and returns
+// the statistics of each of its layers, in layer order.
+func (g *Gerber) Stats() ([]*LayerStats, error) {
+	if err := g.DeriveOpenings(); err != nil {
+		return nil, err
+	}
+	var result []*LayerStats
+	for _, layer := range g.Layers {
+		s, err := layer.Stats()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, s)
+	}
+	return result, nil
+}
+
+// WriteStats writes a summary of the design and of each of its layers,
+// with their statistics (see Stats), to w.
+func (g *Gerber) WriteStats(w io.Writer) error {
+	stats, err := g.Stats()
+	if err != nil {
+		return err
+	}
+	ew := &errWriter{w: w}
+	fmt.Fprintln(ew, g)
+	for _, s := range stats {
+		fmt.Fprintf(ew, "  %v\n    %v\n", s.Layer, s)
+	}
+	return ew.err
+}
+
+func (s *LayerStats) String() string {
+	smallest := "none"
+	if s.Smallest != nil {
+		smallest = s.Smallest.String()
+	}
+	extents := "empty"
+	if s.Flashes+s.Draws+s.Regions > 0 {
+		extents = fmtMBB(s.Extents)
+	}
+	merged := ""
+	if s.MergedApertures > 0 {
+		merged = fmt.Sprintf(", %v apertures merged", s.MergedApertures)
+	}
+	return fmt.Sprintf("%v: %v flashes, %v draws (%vmm), %v regions, smallest %v, extents %v%v",
+		s.Layer.Filename, s.Flashes, s.Draws, fmtFloat(s.DrawLength), s.Regions, smallest, extents, merged)
+}
+
+// arcLength returns the length of the circular arc from p1 to p2 about
+// center (a full circle if p1 is p2).
+func arcLength(p1, p2, center Pt, clockwise bool) float64 {
+	a1 := math.Atan2(p1[1]-center[1], p1[0]-center[0])
+	a2 := math.Atan2(p2[1]-center[1], p2[0]-center[0])
+	sweep := a2 - a1
+	if clockwise {
+		sweep = -sweep
+	}
+	if sweep <= 1e-9 {
+		sweep += 2 * math.Pi
+	}
+	return sweep * Distance(p1, center)
+}
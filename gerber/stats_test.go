@@ -0,0 +1,84 @@
+package gerber
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestLayer_Stats(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{name: "mm"},
+		{name: "inch", opts: []Option{WithUnits(UnitsInch)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New("test", tt.opts...)
+			layer := g.TopCopper()
+			layer.Add(
+				Circle(Pt{1, 1}, 0.5),
+				Circle(Pt{2, 1}, 0.5),
+				Line(0, 0, 3, 4, CircleShape, 0.2),
+				Line(-1, 0, -1, 2, RectShape, 0.3),
+				Polygon(Pt{}, true, []Pt{{5, 5}, {6, 5}, {6, 6}}, 0),
+			)
+			s, err := layer.Stats()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s.Flashes != 2 || s.Draws != 2 || s.Regions != 1 {
+				t.Errorf("Stats = %v, want 2 flashes, 2 draws, 1 region", s)
+			}
+			if math.Abs(s.DrawLength-7) > 1e-3 {
+				t.Errorf("DrawLength = %v, want 7", s.DrawLength)
+			}
+			if s.Smallest == nil || s.Smallest.Size != 0.2 {
+				t.Errorf("Smallest = %v, want 0.2mm", s.Smallest)
+			}
+			if want := (MBB{Min: Pt{-1, 0}, Max: Pt{6, 6}}); !mbbNear(s.Extents, want, 1e-3) {
+				t.Errorf("Extents = %v, want %v", s.Extents, want)
+			}
+			if got, want := len(s.Apertures), 4; got != want {
+				t.Errorf("Apertures = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestGerber_Stats(t *testing.T) {
+	g := New("test", WithOrigin(Pt{10, 10}))
+	g.TopCopper().AddWithOpenings(Openings{Mask: true}, Circle(Pt{11, 11}, 1))
+	stats, err := g.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(stats), 2; got != want {
+		t.Fatalf("Stats = %v layers, want %v", got, want)
+	}
+	if got, want := stats[1].Extents, (MBB{Min: Pt{1, 1}, Max: Pt{1, 1}}); !mbbNear(got, want, 1e-9) || stats[1].Flashes != 1 {
+		t.Errorf("mask Stats = %v, want 1 flash at (1,1)", stats[1])
+	}
+}
+
+func TestGerber_WriteStats(t *testing.T) {
+	g := New("test")
+	g.TopCopper().Add(Circle(Pt{1, 1}, 1), Line(0, 0, 3, 4, CircleShape, 0.2))
+	var buf strings.Builder
+	if err := g.WriteStats(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := `Gerber "test": 1 layers, 2 primitives, MBB (-0.1,-0.1)-(3.1,4.1)
+  TopCopper layer "test.gtl": 2 primitives, 2 apertures, MBB (-0.1,-0.1)-(3.1,4.1)
+    test.gtl: 1 flashes, 1 draws (5mm), 0 regions, smallest Aperture(C, 0.2), extents (0,0)-(3,4)
+`
+	if got := buf.String(); got != want {
+		t.Errorf("WriteStats =\n%v\nwant:\n%v", got, want)
+	}
+
+	if err := g.WriteStats(&failingWriter{n: 10}); err != errDiskFull {
+		t.Errorf("WriteStats to a failing writer = %v, want %v", err, errDiskFull)
+	}
+}
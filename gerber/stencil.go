@@ -0,0 +1,267 @@
+package gerber
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/gmlewis/go-gerber/gerber/internal/polyclip"
+)
+
+// StencilOpts represents the options used by WriteStencilSVG. All
+// dimensions are in millimeters.
+type StencilOpts struct {
+	// Kerf is the width of the laser's cut. Apertures are cut half the
+	// kerf inside their outlines, so that they come out at size.
+	Kerf float64
+	// CornerRadius is the minimum radius of the corners of apertures,
+	// which helps the paste release from the stencil.
+	CornerRadius float64
+	// Border is the distance from the board's outline (or the design's
+	// bounding box if it has none) to the outline of the stencil sheet,
+	// which is cut if positive.
+	Border float64
+	// Registration is the diameter of the registration holes cut at
+	// three corners of the board (halfway into the border), for pins
+	// that align the stencil with its fixture. They are not cut if
+	// zero.
+	Registration float64
+}
+
+// The stroke colors of the cuts of stencils: most laser cutters map
+// colors to operations, so that the sheet's outline can be cut last.
+const (
+	stencilCutColor   = "red"
+	stencilSheetColor = "blue"
+)
+
+// WriteStencilSVG derives the design's pads (see DeriveOpenings) and
+// writes the solder paste layer of the given side as an SVG image for
+// laser cutting a (e.g. mylar) stencil. Each aperture is a single
+// closed path (in stencilCutColor), compensated for the laser's kerf
+// and with rounded corners, followed by the registration holes and the
+// sheet's outline (in stencilSheetColor). The bottom side is mirrored
+// left to right, as its stencil is used with the board flipped over.
+// Dimensions are in millimeters, with the design's output origin and
+// export transform applied.
+func (g *Gerber) WriteStencilSVG(w io.Writer, side Side, opts StencilOpts) error {
+	if opts.Kerf < 0 || opts.CornerRadius < 0 || opts.Border < 0 || opts.Registration < 0 {
+		return errors.New("invalid stencil kerf, corner radius, border, or registration")
+	}
+	if opts.Registration > 0 && opts.Registration >= opts.Border {
+		return errors.New("stencil registration holes require a larger border")
+	}
+	if err := g.DeriveOpenings(); err != nil {
+		return err
+	}
+	t := TopSolderPasteLayer
+	if side == SideBottom {
+		t = BottomSolderPasteLayer
+	}
+	layers := g.layersOfType(t)
+	if len(layers) == 0 {
+		return fmt.Errorf("design has no %v solder paste layer", side)
+	}
+
+	sw := &stencilWriter{g: g, mirror: side == SideBottom}
+	// Whether mapping to the stencil reverses the direction of arcs.
+	o, x, y := sw.pt(Pt{}), sw.pt(Pt{1, 0}), sw.pt(Pt{0, 1})
+	sw.reversed = (x[0]-o[0])*(y[1]-o[1])-(x[1]-o[1])*(y[0]-o[0]) < 0
+
+	var cuts strings.Builder
+	for _, layer := range layers {
+		for _, p := range layer.Primitives {
+			for _, s := range stencilShapes(p) {
+				path, err := sw.aperture(s, opts)
+				if err != nil {
+					return fmt.Errorf("%v: %v", layer.Filename, err)
+				}
+				cuts.WriteString(path)
+			}
+		}
+	}
+
+	board := g.MBB()
+	if outlines := g.layersOfType(OutlineLayer); len(outlines) > 0 {
+		board = outlines[0].MBB()
+		for _, layer := range outlines[1:] {
+			mbb := layer.MBB()
+			board.Join(&mbb)
+		}
+	}
+	if opts.Registration > 0 {
+		h := 0.5 * opts.Border
+		for _, c := range []Pt{
+			{board.Min[0] - h, board.Min[1] - h},
+			{board.Max[0] + h, board.Min[1] - h},
+			{board.Min[0] - h, board.Max[1] + h},
+		} {
+			cuts.WriteString(sw.circle(c, 0.5*opts.Registration))
+		}
+	}
+	sheet := MBB{Min: Pt{board.Min[0] - opts.Border, board.Min[1] - opts.Border}, Max: Pt{board.Max[0] + opts.Border, board.Max[1] + opts.Border}}
+	corners := []Pt{sheet.Min, {sheet.Max[0], sheet.Min[1]}, sheet.Max, {sheet.Min[0], sheet.Max[1]}}
+	var view MBB
+	for i, c := range corners {
+		c = sw.pt(c)
+		corners[i] = c
+		if i == 0 {
+			view = MBB{Min: c, Max: c}
+			continue
+		}
+		view.Join(&MBB{Min: c, Max: c})
+	}
+
+	var buf bytes.Buffer
+	width, height := view.Max[0]-view.Min[0], view.Max[1]-view.Min[1]
+	fmt.Fprintf(&buf, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%vmm\" height=\"%vmm\" viewBox=\"%v %v %v %v\">\n",
+		svgFloat(width), svgFloat(height), svgFloat(view.Min[0]), svgFloat(-view.Max[1]), svgFloat(width), svgFloat(height))
+	// SVG's Y axis points down.
+	buf.WriteString("<g transform=\"scale(1,-1)\" fill=\"none\" stroke-width=\"0.01\">\n")
+	fmt.Fprintf(&buf, "<g stroke=\"%v\">\n%v</g>\n", stencilCutColor, cuts.String())
+	if opts.Border > 0 {
+		var coords []string
+		for _, c := range corners {
+			coords = append(coords, svgFloat(c[0])+","+svgFloat(c[1]))
+		}
+		fmt.Fprintf(&buf, "<polygon points=\"%v\" stroke=\"%v\"/>\n", strings.Join(coords, " "), stencilSheetColor)
+	}
+	buf.WriteString("</g>\n</svg>\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// stencilShape is an aperture's shape: its core (a point, segment, or
+// counterclockwise polygon) grown by r.
+type stencilShape struct {
+	core []Pt
+	r    float64
+}
+
+// stencilShapes returns the shapes of a paste primitive.
+func stencilShapes(p Primitive) []stencilShape {
+	if pad, ok := p.(*PadT); ok {
+		r := pad.radius()
+		a, b := 0.5*pad.Width-r, 0.5*pad.Height-r
+		var core []Pt
+		switch {
+		case a < validationEps && b < validationEps:
+			core = []Pt{{}}
+		case a < validationEps:
+			core = []Pt{{0, -b}, {0, b}}
+		case b < validationEps:
+			core = []Pt{{-a, 0}, {a, 0}}
+		default:
+			core = []Pt{{-a, -b}, {a, -b}, {a, b}, {-a, b}}
+		}
+		for i, pt := range core {
+			core[i] = RotatePt(pt, Pt{}, pad.Rotation)
+			core[i] = Pt{core[i][0] + pad.Center[0], core[i][1] + pad.Center[1]}
+		}
+		return []stencilShape{{core: core, r: r}}
+	}
+	o := outlineOf(p)
+	var shapes []stencilShape
+	for _, s := range o.strokes {
+		if Distance(s.p1, s.p2) < validationEps {
+			shapes = append(shapes, stencilShape{core: []Pt{s.p1}, r: s.r})
+			continue
+		}
+		shapes = append(shapes, stencilShape{core: []Pt{s.p1, s.p2}, r: s.r})
+	}
+	for _, poly := range o.polys {
+		poly = polyclip.OffsetPolygon(poly, 0)
+		if len(poly) < 3 {
+			continue
+		}
+		if polyclip.SignedArea(poly) < 0 {
+			poly = reversed(poly)
+		}
+		shapes = append(shapes, stencilShape{core: poly})
+	}
+	return shapes
+}
+
+// stencilWriter writes the cuts of a stencil in its coordinates.
+type stencilWriter struct {
+	g        *Gerber
+	mirror   bool
+	reversed bool
+}
+
+func (sw *stencilWriter) pt(pt Pt) Pt {
+	pt = sw.g.exportPt(pt)
+	if sw.mirror {
+		pt[0] = -pt[0]
+	}
+	return pt
+}
+
+func (sw *stencilWriter) circle(c Pt, r float64) string {
+	c = sw.pt(c)
+	return fmt.Sprintf("<circle cx=\"%v\" cy=\"%v\" r=\"%v\"/>\n", svgFloat(c[0]), svgFloat(c[1]), svgFloat(r))
+}
+
+// aperture returns the cut of the shape shrunk by half the kerf, with
+// corners of at least the corner radius.
+func (sw *stencilWriter) aperture(s stencilShape, opts StencilOpts) (string, error) {
+	r := s.r - 0.5*opts.Kerf
+	core := s.core
+	if len(core) > 2 && r < opts.CornerRadius {
+		// Shrink the polygon so that rounding its corners keeps its size.
+		area := polyclip.SignedArea(core)
+		core = polyclip.OffsetPolygon(core, r-opts.CornerRadius)
+		r = opts.CornerRadius
+		if a := polyclip.SignedArea(core); a*area <= 0 || len(core) < 3 {
+			return "", fmt.Errorf("aperture at %v is too small for the kerf and corner radius", fmtPt(s.core[0]))
+		}
+	}
+	if r <= 0 && len(core) <= 2 {
+		return "", fmt.Errorf("aperture at %v is too small for the kerf", fmtPt(s.core[0]))
+	}
+	if len(core) == 1 {
+		return sw.circle(core[0], r), nil
+	}
+
+	// The core's edges moved outward by r, joined by arcs at its convex
+	// corners and at their intersections at its concave corners.
+	sweep := 1
+	if sw.reversed {
+		sweep = 0
+	}
+	normal := func(p1, p2 Pt) Pt {
+		dx, dy := p2[0]-p1[0], p2[1]-p1[1]
+		l := math.Hypot(dx, dy)
+		return Pt{dy / l, -dx / l}
+	}
+	at := func(pt, n Pt, d float64) string {
+		pt = sw.pt(Pt{pt[0] + d*n[0], pt[1] + d*n[1]})
+		return svgFloat(pt[0]) + " " + svgFloat(pt[1])
+	}
+	var d strings.Builder
+	n := len(core)
+	for i, pt := range core {
+		prev, next := core[(i+n-1)%n], core[(i+1)%n]
+		n1, n2 := normal(prev, pt), normal(pt, next)
+		cross := n1[0]*n2[1] - n1[1]*n2[0]
+		dot := n1[0]*n2[0] + n1[1]*n2[1]
+		cmd := "L"
+		if i == 0 {
+			cmd = "M"
+		}
+		if r <= 0 {
+			fmt.Fprintf(&d, "%v%v ", cmd, at(pt, Pt{}, 0))
+			continue
+		}
+		if cross < -validationEps && 1+dot > 1e-6 {
+			// A concave corner: the miter of the moved edges.
+			fmt.Fprintf(&d, "%v%v ", cmd, at(pt, Pt{(n1[0] + n2[0]) / (1 + dot), (n1[1] + n2[1]) / (1 + dot)}, r))
+			continue
+		}
+		fmt.Fprintf(&d, "%v%v A%v %v 0 0 %v %v ", cmd, at(pt, n1, r), svgFloat(r), svgFloat(r), sweep, at(pt, n2, r))
+	}
+	return fmt.Sprintf("<path d=\"%vZ\"/>\n", d.String()), nil
+}
@@ -0,0 +1,75 @@
+package gerber
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestGerber_WriteStencilSVG(t *testing.T) {
+	g := New("test")
+	g.Outline().Add(Polygon(Pt{}, false, []Pt{{0, 0}, {20, 0}, {20, 10}, {0, 10}}, 0))
+	g.TopSolderPaste().Add(Pad(Pt{5, 5}, RectShape, 2, 1, 0), Circle(Pt{10, 5}, 1), Line(14, 5, 16, 5, CircleShape, 0.5))
+
+	var buf bytes.Buffer
+	if err := g.WriteStencilSVG(&buf, SideTop, StencilOpts{Kerf: 0.1, CornerRadius: 0.1, Border: 4, Registration: 2}); err != nil {
+		t.Fatal(err)
+	}
+	want := `<svg xmlns="http://www.w3.org/2000/svg" width="28mm" height="18mm" viewBox="-4 -14 28 18">
+<g transform="scale(1,-1)" fill="none" stroke-width="0.01">
+<g stroke="red">
+<path d="M4.05 4.65 A0.1 0.1 0 0 1 4.15 4.55 L5.85 4.55 A0.1 0.1 0 0 1 5.95 4.65 L5.95 5.35 A0.1 0.1 0 0 1 5.85 5.45 L4.15 5.45 A0.1 0.1 0 0 1 4.05 5.35 Z"/>
+<circle cx="10" cy="5" r="0.45"/>
+<path d="M14 5.2 A0.2 0.2 0 0 1 14 4.8 L16 4.8 A0.2 0.2 0 0 1 16 5.2 Z"/>
+<circle cx="-2" cy="-2" r="1"/>
+<circle cx="22" cy="-2" r="1"/>
+<circle cx="-2" cy="12" r="1"/>
+</g>
+<polygon points="-4,-4 24,-4 24,14 -4,14" stroke="blue"/>
+</g>
+</svg>
+`
+	if got := buf.String(); got != want {
+		t.Errorf("WriteStencilSVG =\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestGerber_WriteStencilSVG_Bottom(t *testing.T) {
+	g := New("test")
+	g.BottomSolderPaste().Add(Polygon(Pt{}, true, []Pt{{1, 1}, {3, 1}, {3, 2}, {1, 2}}, 0))
+
+	var buf bytes.Buffer
+	if err := g.WriteStencilSVG(&buf, SideBottom, StencilOpts{Kerf: 0.2}); err != nil {
+		t.Fatal(err)
+	}
+	// Mirrored, with sharp corners and no sheet outline.
+	want := `<path d="M-1.1 1.1 L-2.9 1.1 L-2.9 1.9 L-1.1 1.9 Z"/>`
+	if got := buf.String(); !strings.Contains(got, want) || strings.Contains(got, "polygon") {
+		t.Errorf("WriteStencilSVG =\n%v\nwant %v", got, want)
+	}
+}
+
+func TestGerber_WriteStencilSVG_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		opts StencilOpts
+		want string
+	}{
+		{"kerf", StencilOpts{Kerf: -1}, "invalid stencil kerf"},
+		{"border", StencilOpts{Registration: 2, Border: 1}, "require a larger border"},
+		{"too small", StencilOpts{Kerf: 0.5}, "aperture at (1,1) is too small for the kerf"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New("test")
+			g.TopSolderPaste().Add(Circle(Pt{1, 1}, 0.4))
+			var buf bytes.Buffer
+			if err := g.WriteStencilSVG(&buf, SideTop, tt.opts); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("WriteStencilSVG = %v, want error containing %q", err, tt.want)
+			}
+		})
+	}
+	if err := New("test").WriteStencilSVG(&bytes.Buffer{}, SideTop, StencilOpts{}); err == nil {
+		t.Error("WriteStencilSVG(no paste layer) = nil, want error")
+	}
+}
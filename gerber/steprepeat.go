@@ -0,0 +1,240 @@
+package gerber
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+)
+
+func init() {
+	registerPrimitive("stepRepeat", func() Primitive { return &StepRepeatT{} })
+}
+
+// StepRepeatT represents an array of identical cells: its primitives
+// repeated NX times along ColumnStep and NY times along RowStep. When
+// the steps are horizontal and vertical in the output (as for a panel
+// of boards, or an array of pads), it is written as a single Gerber
+// step and repeat (%SR*%) block, which is far smaller than the
+// repeated primitives; otherwise (or when nested in another array) the
+// copies are written out. It satisfies the Primitive interface.
+type StepRepeatT struct {
+	NX, NY int
+	// ColumnStep and RowStep are the offsets (in millimeters) between
+	// adjacent columns and rows of cells.
+	ColumnStep, RowStep Pt
+	Primitives          []Primitive
+	mbb                 *MBB // cached minimum bounding box
+}
+
+// StepRepeat returns an nx by ny array of copies of the primitives,
+// with columns dx and rows dy millimeters apart.
+func StepRepeat(nx, ny int, dx, dy float64, primitives ...Primitive) *StepRepeatT {
+	return &StepRepeatT{NX: nx, NY: ny, ColumnStep: Pt{dx, 0}, RowStep: Pt{0, dy}, Primitives: primitives}
+}
+
+// StepRepeat adds an nx by ny array of copies of the primitives (see
+// StepRepeat) to the layer and returns it.
+func (l *Layer) StepRepeat(nx, ny int, dx, dy float64, primitives ...Primitive) *StepRepeatT {
+	sr := StepRepeat(nx, ny, dx, dy, primitives...)
+	l.Add(sr)
+	return sr
+}
+
+// children returns the primitives of a cell.
+func (s *StepRepeatT) children() []Primitive {
+	return s.Primitives
+}
+
+// offset returns the offset of the cell in column i and row j.
+func (s *StepRepeatT) offset(i, j int) Pt {
+	return Pt{float64(i)*s.ColumnStep[0] + float64(j)*s.RowStep[0], float64(i)*s.ColumnStep[1] + float64(j)*s.RowStep[1]}
+}
+
+// WriteGerber writes the primitive to the Gerber file. The cell's
+// primitives are written with their own apertures.
+func (s *StepRepeatT) WriteGerber(w io.Writer, apertureIndex int) error {
+	gw := toWriter(w)
+	if s.NX < 1 || s.NY < 1 {
+		return fmt.Errorf("invalid step and repeat of %vx%v cells", s.NX, s.NY)
+	}
+	// The output steps must be along the X and Y axes. Cells are
+	// written from the first one, so that the steps are positive.
+	linear := Identity()
+	if gw.xform != nil {
+		linear = *gw.xform
+		linear.C, linear.F = 0, 0
+	}
+	size, count := [2]float64{}, [2]int{1, 1}
+	var first Pt
+	ok := true
+	for k, step := range [2]Pt{s.ColumnStep, s.RowStep} {
+		n := [2]int{s.NX, s.NY}[k]
+		if n == 1 {
+			continue
+		}
+		out := linear.Apply(step)
+		axis := 0
+		if math.Abs(out[0]) < validationEps {
+			axis = 1
+		} else if math.Abs(out[1]) >= validationEps {
+			ok = false
+		}
+		if !ok || count[axis] != 1 {
+			ok = false
+			break
+		}
+		size[axis], count[axis] = math.Abs(out[axis]), n
+		if out[axis] < 0 {
+			first = Pt{first[0] + float64(n-1)*step[0], first[1] + float64(n-1)*step[1]}
+		}
+	}
+	if !ok || gw.stepRepeat {
+		for j := 0; j < s.NY; j++ {
+			for i := 0; i < s.NX; i++ {
+				if err := s.writeCell(gw, apertureIndex, s.offset(i, j)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	fmt.Fprintf(gw, "%%SRX%vY%vI%vJ%v*%%\n", count[0], count[1], gw.size(size[0]), gw.size(size[1]))
+	gw.stepRepeat = true
+	err := s.writeCell(gw, apertureIndex, first)
+	gw.stepRepeat = false
+	if err != nil {
+		return err
+	}
+	io.WriteString(gw, "%SR*%\n")
+	return nil
+}
+
+// writeCell writes the cell's primitives moved by offset.
+func (s *StepRepeatT) writeCell(gw *writer, apertureIndex int, offset Pt) error {
+	for _, p := range s.Primitives {
+		if offset != (Pt{}) {
+			moved, err := TransformPrimitive(p, Translate(offset[0], offset[1]))
+			if err != nil {
+				return err
+			}
+			p = moved
+		}
+		code := apertureIndex
+		if gw.dcode != nil {
+			code = gw.dcode(p.Aperture())
+		}
+		if err := p.WriteGerber(gw, code); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Aperture returns nil for StepRepeatT because its primitives use
+// their own apertures.
+func (s *StepRepeatT) Aperture() *Aperture {
+	return nil
+}
+
+// MBB returns the minimum bounding box in millimeters.
+func (s *StepRepeatT) MBB() MBB {
+	if s.mbb != nil {
+		return *s.mbb
+	}
+	mbb := Group(s.Primitives).MBB()
+	result := mbb
+	for _, o := range []Pt{s.offset(s.NX-1, 0), s.offset(0, s.NY-1), s.offset(s.NX-1, s.NY-1)} {
+		v := MBB{Min: Pt{mbb.Min[0] + o[0], mbb.Min[1] + o[1]}, Max: Pt{mbb.Max[0] + o[0], mbb.Max[1] + o[1]}}
+		result.Join(&v)
+	}
+	s.mbb = &result
+	return result
+}
+
+func (s *StepRepeatT) invalidateBounds() { s.mbb = nil }
+
+// Transform returns a transformed copy of the array. If any of its
+// primitives does not implement Transformer, it is returned unchanged.
+func (s *StepRepeatT) Transform(t Transform) Primitive {
+	cell, err := Group(s.Primitives).Transform(t)
+	if err != nil {
+		return s
+	}
+	linear := t
+	linear.C, linear.F = 0, 0
+	return &StepRepeatT{NX: s.NX, NY: s.NY, ColumnStep: linear.Apply(s.ColumnStep), RowStep: linear.Apply(s.RowStep), Primitives: cell}
+}
+
+// Expand returns a copy of the array with each of its primitives grown
+// by delta on each side. If any of its primitives does not implement
+// Expander, it is returned unchanged.
+func (s *StepRepeatT) Expand(delta float64) Primitive {
+	cell := make([]Primitive, 0, len(s.Primitives))
+	for _, p := range s.Primitives {
+		ep, err := ExpandPrimitive(p, delta)
+		if err != nil {
+			return s
+		}
+		cell = append(cell, ep)
+	}
+	return &StepRepeatT{NX: s.NX, NY: s.NY, ColumnStep: s.ColumnStep, RowStep: s.RowStep, Primitives: cell}
+}
+
+// Contains reports whether pt lies within any primitive of any cell.
+func (s *StepRepeatT) Contains(pt Pt) bool {
+	for j := 0; j < s.NY; j++ {
+		for i := 0; i < s.NX; i++ {
+			o := s.offset(i, j)
+			for _, p := range s.Primitives {
+				if PrimitiveContains(p, Pt{pt[0] - o[0], pt[1] - o[1]}) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func (s *StepRepeatT) String() string {
+	return fmt.Sprintf("StepRepeat(%vx%v, %v, %v, %v primitives)", s.NX, s.NY, fmtPt(s.ColumnStep), fmtPt(s.RowStep), len(s.Primitives))
+}
+
+// stepRepeatJSON is the serialized form of a StepRepeatT.
+type stepRepeatJSON struct {
+	NX         int              `json:"nx"`
+	NY         int              `json:"ny"`
+	ColumnStep Pt               `json:"columnStep"`
+	RowStep    Pt               `json:"rowStep"`
+	Primitives []*primitiveJSON `json:"primitives"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (s *StepRepeatT) MarshalJSON() ([]byte, error) {
+	sj := stepRepeatJSON{NX: s.NX, NY: s.NY, ColumnStep: s.ColumnStep, RowStep: s.RowStep}
+	for _, p := range s.Primitives {
+		pj, err := marshalPrimitive(p)
+		if err != nil {
+			return nil, err
+		}
+		sj.Primitives = append(sj.Primitives, pj)
+	}
+	return json.Marshal(sj)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (s *StepRepeatT) UnmarshalJSON(data []byte) error {
+	var sj stepRepeatJSON
+	if err := json.Unmarshal(data, &sj); err != nil {
+		return err
+	}
+	*s = StepRepeatT{NX: sj.NX, NY: sj.NY, ColumnStep: sj.ColumnStep, RowStep: sj.RowStep}
+	for _, pj := range sj.Primitives {
+		p, err := unmarshalPrimitive(pj)
+		if err != nil {
+			return err
+		}
+		s.Primitives = append(s.Primitives, p)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package gerber
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestStepRepeatT_Primitive(t *testing.T) {
+	var p Primitive = &StepRepeatT{}
+	if p == nil {
+		// In actuality, this test won't compile if it isn't a Primitive.
+		t.Errorf("StepRepeatT does not implement the Primitive interface")
+	}
+}
+
+func TestLayer_StepRepeat(t *testing.T) {
+	cell := []Primitive{Circle(Pt{1, 1}, 1), Line(0, 0, 2, 0, RectShape, 0.5)}
+	want := MBB{Min: Pt{-0.25, -0.25}, Max: Pt{22.25, 6.5}}
+	tests := []struct {
+		name   string
+		opts   []Option
+		wantSR string
+		want   MBB
+	}{
+		{name: "native", wantSR: "%SRX3Y2I10.00000J5.00000*%", want: want},
+		{name: "inch", opts: []Option{WithUnits(UnitsInch)}, wantSR: "%SRX3Y2I0.393701J0.196850*%", want: want},
+		{
+			name:   "mirrored",
+			opts:   []Option{WithExportTransform(MirrorY())},
+			wantSR: "%SRX3Y2I10.00000J5.00000*%",
+			want:   MBB{Min: Pt{-22.25, -0.25}, Max: Pt{0.25, 6.5}},
+		},
+		{
+			name:   "rotated",
+			opts:   []Option{WithExportTransform(Rotate(90))},
+			wantSR: "%SRX2Y3I5.00000J10.00000*%",
+			want:   MBB{Min: Pt{-6.5, -0.25}, Max: Pt{0.25, 22.25}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New("test", append(tt.opts, WithOutputValidation(true))...)
+			top := g.TopCopper()
+			sr := top.StepRepeat(3, 2, 10, 5, cell...)
+			if !mbbNear(sr.MBB(), want, 1e-9) {
+				t.Errorf("MBB = %v, want %v", sr.MBB(), want)
+			}
+			if len(top.Apertures) != 2 {
+				t.Errorf("layer has %v apertures, want 2", len(top.Apertures))
+			}
+			var buf bytes.Buffer
+			if err := top.WriteGerber(&buf); err != nil {
+				t.Fatal(err)
+			}
+			out := buf.String()
+			if !strings.Contains(out, tt.wantSR+"\n") || strings.Count(out, "G54D") != 2 {
+				t.Errorf("WriteGerber = %q, want one cell in a %v block", out, tt.wantSR)
+			}
+			parsed, err := Parse(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := len(parsed.Primitives); got != 12 {
+				t.Errorf("parsed %v primitives, want 12", got)
+			}
+			if !mbbNear(parsed.MBB(), tt.want, 1e-4) {
+				t.Errorf("parsed MBB = %v, want %v", parsed.MBB(), tt.want)
+			}
+		})
+	}
+
+	// Steps that are not along the output axes are written as copies.
+	g := New("test", WithExportTransform(Rotate(30)), WithOutputValidation(true))
+	top := g.TopCopper()
+	top.StepRepeat(3, 2, 10, 5, cell...)
+	var buf bytes.Buffer
+	if err := top.WriteGerber(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "%SR") {
+		t.Errorf("WriteGerber = %q, want no step and repeat block", buf.String())
+	}
+	parsed, err := Parse(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(parsed.Primitives); got != 12 {
+		t.Errorf("parsed %v primitives, want 12", got)
+	}
+}
+
+func TestStepRepeatT_Geometry(t *testing.T) {
+	sr := StepRepeat(2, 1, 5, 0, Circle(Pt{0, 0}, 2))
+	for _, tt := range []struct {
+		pt   Pt
+		want bool
+	}{{Pt{0.5, 0}, true}, {Pt{5.5, 0}, true}, {Pt{2.5, 0}, false}} {
+		if got := sr.Contains(tt.pt); got != tt.want {
+			t.Errorf("Contains(%v) = %v, want %v", tt.pt, got, tt.want)
+		}
+	}
+	grown := sr.Expand(0.5).(*StepRepeatT)
+	if want := (MBB{Min: Pt{-1.5, -1.5}, Max: Pt{6.5, 1.5}}); !mbbNear(grown.MBB(), want, 1e-9) {
+		t.Errorf("expanded MBB = %v, want %v", grown.MBB(), want)
+	}
+	moved := sr.Transform(Rotate(90)).(*StepRepeatT)
+	if want := (MBB{Min: Pt{-1, -1}, Max: Pt{1, 6}}); !mbbNear(moved.MBB(), want, 1e-9) {
+		t.Errorf("rotated MBB = %v, want %v", moved.MBB(), want)
+	}
+
+	// Nested arrays are written with the inner array's copies.
+	g := New("test", WithOutputValidation(true))
+	top := g.TopCopper()
+	top.StepRepeat(2, 2, 20, 20, sr)
+	var buf bytes.Buffer
+	if err := top.WriteGerber(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(buf.String(), "%SRX"); got != 1 {
+		t.Errorf("WriteGerber wrote %v step and repeat blocks, want 1", got)
+	}
+
+	data, err := json.Marshal(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ng Gerber
+	if err := json.Unmarshal(data, &ng); err != nil {
+		t.Fatal(err)
+	}
+	got, ok := ng.Layers[0].Primitives[0].(*StepRepeatT)
+	if !ok || got.NX != 2 || len(got.Primitives) != 1 || got.MBB() != top.Primitives[0].MBB() {
+		t.Errorf("unmarshaled %v, want %v", ng.Layers[0].Primitives[0], top.Primitives[0])
+	}
+}
+
+func TestLayer_Stats_StepRepeat(t *testing.T) {
+	g := New("test")
+	top := g.TopCopper()
+	top.StepRepeat(3, 2, 10, 5, Circle(Pt{1, 1}, 1))
+	s, err := top.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Flashes != 6 {
+		t.Errorf("Flashes = %v, want 6", s.Flashes)
+	}
+}
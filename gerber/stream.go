@@ -0,0 +1,203 @@
+package gerber
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// StreamWriter writes the Gerber file of a layer incrementally (see
+// Layer.NewStreamWriter), for generated designs (such as spiral coils
+// or fractal antennas) with too many primitives to hold in memory.
+type StreamWriter struct {
+	l       *Layer
+	ew      *errWriter
+	gw      *writer
+	checker *complianceChecker
+	n       ApertureNumbering
+	// codes are the D-codes of the apertures defined so far, by ID,
+	// and next is the next D-code to try.
+	codes  map[string]int
+	next   int
+	macros map[string]*ApertureMacro
+	// holes are the plated holes used to infer the X2 functions of
+	// apertures, and function is the function in effect (if any).
+	holes    []Node
+	function string
+	count    int
+	err      error
+}
+
+// ErrStreamClosed is returned when writing to a closed StreamWriter.
+var ErrStreamClosed = errors.New("stream writer is closed")
+
+// NewStreamWriter writes the header of the layer's Gerber file to w
+// (as WriteGerber does) and returns a StreamWriter that writes the
+// primitives of the file: the layer's own primitives, then those
+// passed to Write. Streamed primitives are written immediately and are
+// not added to the layer, so memory usage does not grow with their
+// number. Apertures are defined as they are first used rather than in
+// the header (so the Sorted option of ApertureNumbering is ignored).
+//
+// Since streamed primitives are not part of the layer, the design's
+// MBB, checks, and other exports do not include them; in particular,
+// WithAutoFormat cannot fit the coordinate format to them.
+func (l *Layer) NewStreamWriter(w io.Writer) (*StreamWriter, error) {
+	s := &StreamWriter{l: l, ew: &errWriter{w: w}, codes: map[string]int{}, macros: map[string]*ApertureMacro{}}
+	s.gw = l.newWriter(s.ew)
+	if l.g != nil {
+		s.n = l.g.numbering
+		if l.g.validateOutput {
+			s.checker = newComplianceChecker(s.gw.Writer)
+			s.gw.Writer = s.checker
+		}
+		if l.g.x2 {
+			s.holes = l.platedHoles()
+		}
+	}
+	defaultCode := s.n.defaultCode()
+	if defaultCode < minDCode {
+		return nil, fmt.Errorf("layer %v: invalid default aperture D-code %v (must be >= %v)", l.Filename, defaultCode, minDCode)
+	}
+	s.next = s.n.Start
+	if s.next == 0 {
+		s.next = 12
+	}
+	if s.next < minDCode {
+		return nil, fmt.Errorf("layer %v: invalid starting aperture D-code %v (must be >= %v)", l.Filename, s.next, minDCode)
+	}
+	s.codes[(*Aperture)(nil).ID()] = defaultCode
+	if err := l.writeHeader(s.gw, defaultCode); err != nil {
+		return nil, fmt.Errorf("layer %v: %v", l.Filename, err)
+	}
+	if s.ew.err != nil {
+		return nil, fmt.Errorf("layer %v: %v", l.Filename, s.ew.err)
+	}
+	if s.checker != nil && s.checker.err != nil {
+		return nil, fmt.Errorf("layer %v: header: %v", l.Filename, s.checker.err)
+	}
+	s.gw.dcode = func(a *Aperture) int { return s.codes[a.ID()] }
+	if err := s.Write(l.Primitives...); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Write writes primitives to the layer's file, defining any apertures
+// they use that are not yet defined. The first error is sticky: it is
+// returned by every later Write and by Close.
+func (s *StreamWriter) Write(primitives ...Primitive) error {
+	if s.err != nil {
+		return s.err
+	}
+	l := s.l
+	for _, p := range primitives {
+		if err := s.define(p); err != nil {
+			s.err = fmt.Errorf("layer %v: primitive #%v: %v", l.Filename, s.count, err)
+			return s.err
+		}
+		attrs := l.objectAttributes(p)
+		if err := writeAttributes(s.gw, attrs); err != nil {
+			s.err = fmt.Errorf("layer %v: primitive #%v: %v", l.Filename, s.count, err)
+			return s.err
+		}
+		if err := p.WriteGerber(s.gw, s.codes[p.Aperture().ID()]); err != nil {
+			s.err = fmt.Errorf("layer %v: %v", l.Filename, err)
+			return s.err
+		}
+		if len(attrs) > 0 {
+			io.WriteString(s.gw, "%TD*%\n")
+		}
+		if s.ew.err != nil {
+			s.err = fmt.Errorf("layer %v: %v", l.Filename, s.ew.err)
+			return s.err
+		}
+		if s.gw.err != nil {
+			s.err = s.gw.layerErr(l, s.count, p)
+			return s.err
+		}
+		if s.checker != nil && s.checker.err != nil {
+			s.err = fmt.Errorf("layer %v: primitive #%v (%v): %v", l.Filename, s.count, primitiveTypeName(p), s.checker.err)
+			return s.err
+		}
+		s.count++
+	}
+	return nil
+}
+
+// define writes the definitions of the apertures (and their macros)
+// used by a primitive (and by the children of a compound primitive)
+// that are not yet defined.
+func (s *StreamWriter) define(p Primitive) error {
+	if c, ok := p.(compound); ok {
+		for _, child := range c.children() {
+			if err := s.define(child); err != nil {
+				return err
+			}
+		}
+	}
+	a := p.Aperture()
+	if a == nil {
+		return nil
+	}
+	id := a.ID()
+	if _, ok := s.codes[id]; ok {
+		return nil
+	}
+	if m := a.Macro; m != nil {
+		d, ok := s.macros[m.Name]
+		switch {
+		case !ok:
+			s.macros[m.Name] = m
+			if err := m.writeGerber(s.gw); err != nil {
+				return err
+			}
+		case !reflect.DeepEqual(d.Primitives, m.Primitives):
+			return fmt.Errorf("two different aperture macros are named %v", m.Name)
+		}
+	}
+	if s.l.g != nil && s.l.g.x2 {
+		// The function of an aperture is that of its first primitive.
+		if f := s.l.aperFunction(p, s.holes); f != s.function {
+			if f == "" {
+				io.WriteString(s.gw, "%TD.AperFunction*%\n")
+			} else {
+				fmt.Fprintf(s.gw, "%%TA.AperFunction,%v*%%\n", f)
+			}
+			s.function = f
+		}
+	}
+	for s.n.reserved(s.next) {
+		s.next++
+	}
+	s.codes[id] = s.next
+	s.next++
+	return a.WriteGerber(s.gw, s.codes[id])
+}
+
+// Close writes the end of the layer's file (running its footer hooks).
+// It does not close the underlying writer.
+func (s *StreamWriter) Close() error {
+	if s.err != nil {
+		return s.err
+	}
+	l := s.l
+	if s.function != "" {
+		io.WriteString(s.gw, "%TD.AperFunction*%\n")
+	}
+	s.err = ErrStreamClosed
+	if err := l.runHooks(s.gw, l.footerHooks); err != nil {
+		return fmt.Errorf("layer %v: %v", l.Filename, err)
+	}
+	io.WriteString(s.gw, "M02*\n")
+	if s.ew.err != nil {
+		return fmt.Errorf("layer %v: %v", l.Filename, s.ew.err)
+	}
+	if s.checker != nil {
+		if err := s.checker.Close(); err != nil {
+			return fmt.Errorf("layer %v: %v", l.Filename, err)
+		}
+	}
+	return nil
+}
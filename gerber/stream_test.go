@@ -0,0 +1,99 @@
+package gerber
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestLayer_NewStreamWriter(t *testing.T) {
+	g := New("test", WithOutputValidation(true))
+	top := g.TopCopper()
+	top.Add(Circle(Pt{0, 0}, 1))
+
+	var buf bytes.Buffer
+	s, err := top.NewStreamWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A spiral of short segments, alternating between two widths.
+	const n = 1000
+	prev := Pt{}
+	for i := 1; i <= n; i++ {
+		a := 0.1 * float64(i)
+		pt := Pt{a * math.Cos(a), a * math.Sin(a)}
+		width := 0.2
+		if i%2 == 0 {
+			width = 0.3
+		}
+		if err := s.Write(Line(prev[0], prev[1], pt[0], pt[1], CircleShape, width)); err != nil {
+			t.Fatalf("Write #%v: %v", i, err)
+		}
+		prev = pt
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Write(Circle(Pt{}, 1)); err != ErrStreamClosed {
+		t.Errorf("Write after Close = %v, want %v", err, ErrStreamClosed)
+	}
+	if len(top.Primitives) != 1 {
+		t.Errorf("layer primitives = %v, want 1", len(top.Primitives))
+	}
+
+	got := buf.String()
+	for _, want := range []string{
+		"%LPD*%\n%ADD11C,0.00100*%\n%ADD12C,1.00000*%\nG54D12*\n",
+		"%ADD13C,0.20000*%\nG54D13*\n",
+		"%ADD14C,0.30000*%\nG54D14*\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("stream missing %q", want)
+		}
+	}
+	if c := strings.Count(got, "%ADD"); c != 4 {
+		t.Errorf("aperture definitions = %v, want 4", c)
+	}
+	parsed, err := Parse(strings.NewReader(got))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed.Primitives) != n+1 {
+		t.Errorf("parsed primitives = %v, want %v", len(parsed.Primitives), n+1)
+	}
+}
+
+func TestStreamWriter_StickyError(t *testing.T) {
+	g := New("test")
+	layer := g.TopCopper()
+	var buf bytes.Buffer
+	s, err := layer.NewStreamWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Write(Line(0, 0, 1e9, 0, CircleShape, 0.1))
+	if err == nil {
+		t.Fatal("Write(out of range) = nil, want error")
+	}
+	if got := s.Write(Circle(Pt{}, 1)); got != err {
+		t.Errorf("Write = %v, want %v", got, err)
+	}
+	if got := s.Close(); got != err {
+		t.Errorf("Close = %v, want %v", got, err)
+	}
+}
+
+func TestStreamWriter_WriteError(t *testing.T) {
+	layer := New("test").TopCopper()
+	s, err := layer.NewStreamWriter(&failingWriter{n: 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100 && err == nil; i++ {
+		err = s.Write(Circle(Pt{float64(i), 0}, 1))
+	}
+	if err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Errorf("Write = %v, want disk full", err)
+	}
+}
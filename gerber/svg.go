@@ -0,0 +1,268 @@
+package gerber
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+// SVGStyle represents how a layer is drawn by WriteSVG and
+// WriteSVGPreview.
+type SVGStyle struct {
+	// Color is an SVG color (e.g. "#b87333" or "white").
+	Color string
+	// Opacity is the opacity of the layer, from 0 to 1.
+	Opacity float64
+	// Negative draws the layer as a film covering the board, with its
+	// primitives as openings (e.g. for solder mask).
+	Negative bool
+}
+
+// DefaultSVGStyle returns the style used for layers of type t unless
+// overridden by SVGOpts.
+func DefaultSVGStyle(t LayerType) SVGStyle {
+	if info, ok := layerTypes[t]; ok {
+		return info.svg
+	}
+	return otherSVGStyle
+}
+
+// SVGOpts represents the options used by WriteSVGPreview.
+type SVGOpts struct {
+	// Background is the color behind the board (default "#202020").
+	Background string
+	// Styles overrides the default style (see DefaultSVGStyle) of
+	// layer types.
+	Styles map[LayerType]SVGStyle
+	// Exclude lists layer types that are not drawn.
+	Exclude []LayerType
+}
+
+// WriteSVG writes an SVG image of the layer (in its default style,
+// see DefaultSVGStyle) to w. The layer is drawn as its Gerber file
+// would be plotted: its Gerber output is read back (see Parse), so
+// every primitive (including text) is drawn, with the design's output
+// origin and export transform applied. Dimensions are in millimeters.
+func (l *Layer) WriteSVG(w io.Writer) error {
+	s := &svgWriter{}
+	if err := s.addLayer(l, DefaultSVGStyle(l.Type)); err != nil {
+		return err
+	}
+	return s.write(w, "")
+}
+
+// WriteSVGPreview writes an SVG image of the whole board (its layers
+// are stacked as seen from the top of the board and drawn as WriteSVG
+// does) to w, so that generated boards can be checked visually (e.g.
+// in CI) or embedded in documentation. opts may be nil.
+func (g *Gerber) WriteSVGPreview(w io.Writer, opts *SVGOpts) error {
+	var o SVGOpts
+	if opts != nil {
+		o = *opts
+	}
+	if o.Background == "" {
+		o.Background = "#202020"
+	}
+	if err := g.DeriveOpenings(); err != nil {
+		return err
+	}
+	excluded := map[LayerType]bool{}
+	for _, t := range o.Exclude {
+		excluded[t] = true
+	}
+	var layers []*Layer
+	for _, layer := range g.Layers {
+		if !excluded[layer.Type] {
+			layers = append(layers, layer)
+		}
+	}
+	sort.SliceStable(layers, func(i, j int) bool {
+		return layerTypes[layers[i].Type].stack < layerTypes[layers[j].Type].stack
+	})
+
+	s := &svgWriter{}
+	for _, layer := range layers {
+		style, ok := o.Styles[layer.Type]
+		if !ok {
+			style = DefaultSVGStyle(layer.Type)
+		}
+		if err := s.addLayer(layer, style); err != nil {
+			return err
+		}
+	}
+	return s.write(w, o.Background)
+}
+
+// svgLayer holds the (output coordinate) primitives of a layer.
+type svgLayer struct {
+	style      SVGStyle
+	primitives []Primitive
+}
+
+// svgWriter collects the layers of an SVG image.
+type svgWriter struct {
+	layers []*svgLayer
+	mbb    MBB
+	masks  int
+}
+
+func (s *svgWriter) addLayer(l *Layer, style SVGStyle) error {
+	var buf bytes.Buffer
+	if err := l.WriteGerber(&buf); err != nil {
+		return err
+	}
+	parsed, err := Parse(&buf)
+	if err != nil {
+		return fmt.Errorf("layer %v: %v", l.Filename, err)
+	}
+	if parsed.IsEmpty() {
+		return nil
+	}
+	mbb := parsed.MBB()
+	if len(s.layers) == 0 {
+		s.mbb = mbb
+	} else {
+		s.mbb.Join(&mbb)
+	}
+	s.layers = append(s.layers, &svgLayer{style: style, primitives: parsed.Primitives})
+	return nil
+}
+
+func (s *svgWriter) write(w io.Writer, background string) error {
+	mbb := s.mbb
+	width, height := mbb.Max[0]-mbb.Min[0], mbb.Max[1]-mbb.Min[1]
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%vmm\" height=\"%vmm\" viewBox=\"%v %v %v %v\">\n",
+		svgFloat(width), svgFloat(height), svgFloat(mbb.Min[0]), svgFloat(-mbb.Max[1]), svgFloat(width), svgFloat(height))
+	if background != "" {
+		fmt.Fprintf(&buf, "<rect x=\"%v\" y=\"%v\" width=\"%v\" height=\"%v\" fill=\"%v\"/>\n",
+			svgFloat(mbb.Min[0]), svgFloat(-mbb.Max[1]), svgFloat(width), svgFloat(height), background)
+	}
+	// SVG's Y axis points down.
+	buf.WriteString("<g transform=\"scale(1,-1)\">\n")
+	for _, layer := range s.layers {
+		buf.WriteString(s.layer(layer))
+	}
+	buf.WriteString("</g>\n</svg>\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// layer returns the SVG group of the layer. Clear primitives (and the
+// primitives of negative layers) mask out everything drawn before them.
+func (s *svgWriter) layer(l *svgLayer) string {
+	color := l.style.Color
+	var content strings.Builder
+	var pending []Primitive
+	clear := l.style.Negative
+	if clear {
+		content.WriteString(s.rect(color))
+	}
+	flush := func() {
+		if len(pending) == 0 {
+			return
+		}
+		if !clear {
+			for _, p := range pending {
+				content.WriteString(svgElement(p, color))
+			}
+		} else {
+			s.masks++
+			id := fmt.Sprintf("clear%v", s.masks)
+			var mask strings.Builder
+			fmt.Fprintf(&mask, "<mask id=\"%v\" maskUnits=\"userSpaceOnUse\">\n%v", id, s.rect("white"))
+			for _, p := range pending {
+				mask.WriteString(svgElement(p, "black"))
+			}
+			mask.WriteString("</mask>\n")
+			wrapped := fmt.Sprintf("%v<g mask=\"url(#%v)\">\n%v</g>\n", mask.String(), id, content.String())
+			content.Reset()
+			content.WriteString(wrapped)
+		}
+		pending = nil
+	}
+	for _, p := range l.primitives {
+		isClear := l.style.Negative
+		if c, ok := p.(*ClearT); ok {
+			p, isClear = c.Primitive, !isClear
+		}
+		if isClear != clear {
+			flush()
+			clear = isClear
+		}
+		pending = append(pending, p)
+	}
+	flush()
+	return fmt.Sprintf("<g opacity=\"%v\">\n%v</g>\n", svgFloat(l.style.Opacity), content.String())
+}
+
+// rect returns a rectangle of the given color covering the image.
+func (s *svgWriter) rect(color string) string {
+	mbb := s.mbb
+	return fmt.Sprintf("<rect x=\"%v\" y=\"%v\" width=\"%v\" height=\"%v\" fill=\"%v\"/>\n",
+		svgFloat(mbb.Min[0]), svgFloat(mbb.Min[1]), svgFloat(mbb.Max[0]-mbb.Min[0]), svgFloat(mbb.Max[1]-mbb.Min[1]), color)
+}
+
+// svgElement returns the SVG element of a primitive returned by Parse.
+func svgElement(p Primitive, color string) string {
+	switch v := p.(type) {
+	case *CircleT:
+		return fmt.Sprintf("<circle cx=\"%v\" cy=\"%v\" r=\"%v\" fill=\"%v\"/>\n",
+			svgFloat(v.pt[0]), svgFloat(v.pt[1]), svgFloat(0.5*v.thickness), color)
+	case *LineT:
+		if v.Shape == RectShape {
+			// A square aperture swept along the line.
+			h := 0.5 * v.Thickness
+			var pts []Pt
+			for _, c := range []Pt{v.P1, v.P2} {
+				pts = append(pts, Pt{c[0] - h, c[1] - h}, Pt{c[0] + h, c[1] - h}, Pt{c[0] + h, c[1] + h}, Pt{c[0] - h, c[1] + h})
+			}
+			return svgPolygon(Pt{}, convexHull(pts), color)
+		}
+		return fmt.Sprintf("<line x1=\"%v\" y1=\"%v\" x2=\"%v\" y2=\"%v\" stroke=\"%v\" stroke-width=\"%v\" stroke-linecap=\"round\"/>\n",
+			svgFloat(v.P1[0]), svgFloat(v.P1[1]), svgFloat(v.P2[0]), svgFloat(v.P2[1]), color, svgFloat(v.Thickness))
+	case *ArcT:
+		if v.EndAngle-v.StartAngle >= 2*math.Pi-1e-9 {
+			return fmt.Sprintf("<circle cx=\"%v\" cy=\"%v\" r=\"%v\" fill=\"none\" stroke=\"%v\" stroke-width=\"%v\"/>\n",
+				svgFloat(v.Center[0]), svgFloat(v.Center[1]), svgFloat(v.Radius), color, svgFloat(v.Thickness))
+		}
+		p1 := PolarFrom(v.Center, v.Radius, v.StartAngle*180/math.Pi)
+		p2 := PolarFrom(v.Center, v.Radius, v.EndAngle*180/math.Pi)
+		large := 0
+		if v.EndAngle-v.StartAngle > math.Pi {
+			large = 1
+		}
+		return fmt.Sprintf("<path d=\"M%v %v A%v %v 0 %v 1 %v %v\" fill=\"none\" stroke=\"%v\" stroke-width=\"%v\" stroke-linecap=\"round\"/>\n",
+			svgFloat(p1[0]), svgFloat(p1[1]), svgFloat(v.Radius), svgFloat(v.Radius), large, svgFloat(p2[0]), svgFloat(p2[1]), color, svgFloat(v.Thickness))
+	case *PolygonT:
+		return svgPolygon(v.Offset, v.Points, color)
+	case *FlashT:
+		// The exposed parts of the macro's image. Its clear primitives
+		// are not drawn.
+		image, _ := v.Macro.Image(v.Params...)
+		var s string
+		for _, p := range image {
+			if p, err := TransformPrimitive(p, Translate(v.Center[0], v.Center[1])); err == nil {
+				s += svgElement(p, color)
+			}
+		}
+		return s
+	}
+	return ""
+}
+
+func svgPolygon(offset Pt, pts []Pt, color string) string {
+	var coords []string
+	for _, pt := range pts {
+		coords = append(coords, svgFloat(pt[0]+offset[0])+","+svgFloat(pt[1]+offset[1]))
+	}
+	return fmt.Sprintf("<polygon points=\"%v\" fill=\"%v\"/>\n", strings.Join(coords, " "), color)
+}
+
+// svgFloat formats v (in mm) to the nearest micrometer.
+func svgFloat(v float64) string {
+	return fmtFloat(math.Round(v*1e3) / 1e3)
+}
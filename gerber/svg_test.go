@@ -0,0 +1,71 @@
+package gerber
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestLayer_WriteSVG(t *testing.T) {
+	top := New("test").TopCopper()
+	top.Add(
+		Circle(Pt{1, 1}, 1),
+		Line(0, 0, 2, 0, CircleShape, 0.2),
+		Line(0, 0, 0, 2, RectShape, 0.2),
+		Polygon(Pt{}, true, []Pt{{0, 0}, {1, 0}, {1, 1}}, 0),
+		Clear(Circle(Pt{1, 1}, 0.5)),
+	)
+	var buf bytes.Buffer
+	if err := top.WriteSVG(&buf); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	for _, want := range []string{
+		`<svg xmlns="http://www.w3.org/2000/svg" width="2.2mm" height="2.2mm" viewBox="-0.1 -2.1 2.2 2.2">`,
+		`<circle cx="1" cy="1" r="0.5" fill="#b87333"/>`,
+		`<line x1="0" y1="0" x2="2" y2="0" stroke="#b87333" stroke-width="0.2" stroke-linecap="round"/>`,
+		`<polygon points="-0.1,-0.1 0.1,-0.1 0.1,2.1 -0.1,2.1" fill="#b87333"/>`,
+		`<polygon points="0,0 1,0 1,1" fill="#b87333"/>`,
+		`<mask id="clear1" maskUnits="userSpaceOnUse">`,
+		`<circle cx="1" cy="1" r="0.25" fill="black"/>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("WriteSVG =\n%v\nwant %v", got, want)
+		}
+	}
+}
+
+func TestGerber_WriteSVGPreview(t *testing.T) {
+	g := New("test")
+	g.TopCopper().AddWithOpenings(Openings{Mask: true}, Circle(Pt{5, 5}, 2))
+	g.Outline().Add(OutlinePath(RoundedRect(MBB{Max: Pt{10, 10}}, 0), 0.1)...)
+	g.TopSilkscreen().Add(Line(1, 1, 3, 1, CircleShape, 0.15))
+
+	var buf bytes.Buffer
+	opts := &SVGOpts{
+		Styles:  map[LayerType]SVGStyle{TopCopperLayer: {Color: "gold", Opacity: 0.5}},
+		Exclude: []LayerType{TopSilkscreenLayer},
+	}
+	if err := g.WriteSVGPreview(&buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	for _, want := range []string{
+		`fill="#202020"`,
+		`<g opacity="0.5">`,
+		`fill="gold"`,
+		`fill="#006400"`,
+		`stroke="yellow"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("WriteSVGPreview =\n%v\nwant %v", got, want)
+		}
+	}
+	if strings.Contains(got, `stroke="white"`) {
+		t.Errorf("WriteSVGPreview includes the excluded silkscreen:\n%v", got)
+	}
+	// Layers are stacked as seen from the top: copper, mask, outline.
+	if i, j, k := strings.Index(got, "gold"), strings.Index(got, "#006400"), strings.Index(got, "yellow"); i > j || j > k {
+		t.Errorf("WriteSVGPreview layer order = %v, %v, %v; want increasing", i, j, k)
+	}
+}
@@ -0,0 +1,121 @@
+package gerber
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Params represents one combination of parameter values of a sweep.
+type Params map[string]float64
+
+// Generator generates the design for a combination of parameters.
+type Generator func(p Params) (*Gerber, error)
+
+// Variant represents the result of generating one combination of
+// parameters of a sweep.
+type Variant struct {
+	// Name identifies the variant (e.g. "turns=10_width=0.2") and is
+	// the name of its output directory.
+	Name   string
+	Params Params
+	// Stats are the metrics of the generated design (nil on error).
+	Stats *JobStats
+	// Err is the error (if any) generating or writing the design.
+	Err error
+}
+
+// SweepSummary is the name of the CSV file summarizing a sweep.
+const SweepSummary = "summary.csv"
+
+// Sweep runs gen for every combination of the values in grid (which
+// maps parameter names to their values), writing each variant's design
+// to its own subdirectory of dir (see Gerber.WriteToDir) and a summary
+// of all variants (their parameters, metrics, and errors) to
+// SweepSummary in dir. Variants are generated in order of their
+// parameter values, with the last parameter name (alphabetically)
+// varying fastest. A variant that fails is recorded (see Variant.Err)
+// rather than stopping the sweep; the returned error reports problems
+// writing the summary.
+func Sweep(dir string, grid map[string][]float64, gen Generator) ([]*Variant, error) {
+	var names []string
+	for name := range grid {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var variants []*Variant
+	var walk func(i int, p Params)
+	walk = func(i int, p Params) {
+		if i == len(names) {
+			params := Params{}
+			var parts []string
+			for _, name := range names {
+				params[name] = p[name]
+				parts = append(parts, name+"="+fmtFloat(p[name]))
+			}
+			variants = append(variants, &Variant{Name: strings.Join(parts, "_"), Params: params})
+			return
+		}
+		for _, v := range grid[names[i]] {
+			p[names[i]] = v
+			walk(i+1, p)
+		}
+	}
+	walk(0, Params{})
+
+	for _, v := range variants {
+		g, err := gen(v.Params)
+		if err != nil {
+			v.Err = err
+			continue
+		}
+		if err := g.WriteToDir(filepath.Join(dir, v.Name)); err != nil {
+			v.Err = err
+			continue
+		}
+		v.Stats = g.Estimate()
+	}
+
+	if err := writeSweepSummary(filepath.Join(dir, SweepSummary), names, variants); err != nil {
+		return variants, err
+	}
+	return variants, nil
+}
+
+func writeSweepSummary(filename string, names []string, variants []*Variant) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	header := append([]string{"variant"}, names...)
+	header = append(header, "board_width", "board_height", "board_area", "copper_layers", "min_trace", "min_space", "min_drill", "holes", "error")
+	w.Write(header)
+	for _, v := range variants {
+		row := []string{v.Name}
+		for _, name := range names {
+			row = append(row, fmtFloat(v.Params[name]))
+		}
+		if s := v.Stats; s != nil {
+			row = append(row, fmtFloat(s.Width), fmtFloat(s.Height), fmtFloat(s.Area), strconv.Itoa(s.CopperLayers),
+				fmtFloat(s.MinTrace), fmtFloat(s.MinSpace), fmtFloat(s.MinDrill), strconv.Itoa(s.Holes), "")
+		} else {
+			row = append(row, "", "", "", "", "", "", "", "", fmt.Sprint(v.Err))
+		}
+		w.Write(row)
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,65 @@
+package gerber
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSweep(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sweep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	grid := map[string][]float64{
+		"width": {0.2, 0.3},
+		"turns": {1, 2, 3},
+	}
+	gen := func(p Params) (*Gerber, error) {
+		if p["turns"] == 3 && p["width"] == 0.3 {
+			return nil, errors.New("too wide")
+		}
+		g := New("coil")
+		g.TopCopper().Add(Line(0, 0, 10*p["turns"], 0, CircleShape, p["width"]))
+		return g, nil
+	}
+	variants, err := Sweep(dir, grid, gen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(variants), 6; got != want {
+		t.Fatalf("variants = %v, want %v", got, want)
+	}
+	if got, want := variants[1].Name, "turns=1_width=0.3"; got != want {
+		t.Errorf("variants[1].Name = %q, want %q", got, want)
+	}
+	if v := variants[5]; v.Err == nil || v.Stats != nil {
+		t.Errorf("variants[5] = %+v, want error", v)
+	}
+	if s := variants[4].Stats; s == nil || s.MinTrace != 0.2 {
+		t.Errorf("variants[4].Stats = %v, want min trace 0.2", s)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "turns=2_width=0.2", "coil.gtl")); err != nil {
+		t.Errorf("variant output: %v", err)
+	}
+
+	buf, err := ioutil.ReadFile(filepath.Join(dir, SweepSummary))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(buf)), "\n")
+	if got, want := len(lines), 7; got != want {
+		t.Fatalf("summary lines = %v, want %v", got, want)
+	}
+	if got, want := lines[0], "variant,turns,width,board_width,board_height,board_area,copper_layers,min_trace,min_space,min_drill,holes,error"; got != want {
+		t.Errorf("summary header = %q, want %q", got, want)
+	}
+	if !strings.HasSuffix(lines[6], ",too wide") {
+		t.Errorf("summary = %q, want error", lines[6])
+	}
+}
@@ -0,0 +1,75 @@
+package gerber
+
+// Tags allow generators to label primitives (e.g. "coil-turn-7" or
+// "fiducial") and find them again later for transforms or checks.
+// Tags are stored per layer and keyed by primitive identity, so tagged
+// primitives must be comparable (e.g. pointers, as are all primitives
+// provided by this package).
+
+// LayerPrimitive identifies a primitive within a design.
+type LayerPrimitive struct {
+	Layer     *Layer
+	Primitive Primitive
+}
+
+// AddTagged adds primitives to a layer (like Add) and tags them all
+// with the provided tags.
+func (l *Layer) AddTagged(tags []string, primitives ...Primitive) {
+	l.Add(primitives...)
+	for _, p := range primitives {
+		l.Tag(p, tags...)
+	}
+}
+
+// Tag adds tags to a primitive in the layer.
+func (l *Layer) Tag(p Primitive, tags ...string) {
+	if len(tags) == 0 {
+		return
+	}
+	if l.tags == nil {
+		l.tags = map[Primitive][]string{}
+	}
+	for _, tag := range tags {
+		if !l.HasTag(p, tag) {
+			l.tags[p] = append(l.tags[p], tag)
+		}
+	}
+}
+
+// Tags returns the tags of a primitive in the layer.
+func (l *Layer) Tags(p Primitive) []string {
+	return l.tags[p]
+}
+
+// HasTag reports whether the primitive has the provided tag.
+func (l *Layer) HasTag(p Primitive, tag string) bool {
+	for _, t := range l.tags[p] {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Select returns all primitives in the layer with the provided tag,
+// in the order in which they appear in the layer.
+func (l *Layer) Select(tag string) []Primitive {
+	var result []Primitive
+	for _, p := range l.Primitives {
+		if l.HasTag(p, tag) {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// Select returns all primitives in the design with the provided tag.
+func (g *Gerber) Select(tag string) []LayerPrimitive {
+	var result []LayerPrimitive
+	for _, layer := range g.Layers {
+		for _, p := range layer.Select(tag) {
+			result = append(result, LayerPrimitive{Layer: layer, Primitive: p})
+		}
+	}
+	return result
+}
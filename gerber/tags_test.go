@@ -0,0 +1,51 @@
+package gerber
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestTags(t *testing.T) {
+	g := New("test")
+	top := g.TopCopper()
+	f1, f2 := Circle(Pt{1, 1}, 1), Circle(Pt{9, 9}, 1)
+	trace := Line(1, 1, 9, 9, CircleShape, 0.2)
+	top.AddTagged([]string{"fiducial"}, f1, f2)
+	top.Add(trace)
+	top.Tag(trace, "net:gnd", "net:gnd")
+
+	if got, want := top.Select("fiducial"), []Primitive{f1, f2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Select(fiducial) = %v, want %v", got, want)
+	}
+	if got, want := top.Tags(trace), []string{"net:gnd"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Tags(trace) = %v, want %v", got, want)
+	}
+	if got := g.Select("net:gnd"); len(got) != 1 || got[0].Layer != top || got[0].Primitive != trace {
+		t.Errorf("Gerber.Select(net:gnd) = %v, want [{top trace}]", got)
+	}
+
+	// Replacements inherit tags; removed primitives lose them.
+	top.Replace(func(p Primitive) ([]Primitive, bool) {
+		if p != f2 {
+			return nil, false
+		}
+		return []Primitive{Circle(Pt{8, 8}, 1)}, true
+	})
+	if got := top.Select("fiducial"); len(got) != 2 || got[0] != f1 || got[1] == f2 {
+		t.Errorf("Select(fiducial) after Replace = %v", got)
+	}
+
+	// Tags survive serialization.
+	data, err := json.Marshal(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ng := &Gerber{}
+	if err := json.Unmarshal(data, ng); err != nil {
+		t.Fatal(err)
+	}
+	if got := ng.Layers[0].Select("fiducial"); len(got) != 2 {
+		t.Errorf("Select(fiducial) after JSON round trip = %v, want 2 primitives", got)
+	}
+}
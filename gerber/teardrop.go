@@ -0,0 +1,89 @@
+package gerber
+
+// TeardropTag is the tag of the teardrops added by ApplyTeardrops.
+const TeardropTag = "teardrop"
+
+// ApplyTeardrops adds a teardrop (a filled polygon, see
+// Trace.Teardrops) to the copper layers of the design wherever a
+// straight trace (a round line) ends within a pad or via (a circle,
+// pad, or flash), reinforcing the junction against drilling
+// misregistration and acid traps. The teardrops are tagged with
+// TeardropTag. It returns the number of teardrops added; ends that
+// already have one (tagged with TeardropTag) are skipped, so it may be
+// called again after more traces are added. Use TeardropPass to add
+// teardrops to the pads that the export pipeline derives (such as those
+// of padstacks).
+func (g *Gerber) ApplyTeardrops(opts TeardropOpts) int {
+	return g.addTeardrops(opts, false)
+}
+
+// TeardropPass returns an export pipeline pass (see Pass) that adds
+// teardrops (see ApplyTeardrops) as derived primitives. Insert it after
+// PadstackPass so that padstack pads get teardrops too, e.g.
+//
+//	g.InsertPass(PadstackPass.Name, TeardropPass(TeardropOpts{}))
+func TeardropPass(opts TeardropOpts) Pass {
+	return Pass{Name: "teardrops", Run: func(g *Gerber) error {
+		g.addTeardrops(opts, true)
+		return nil
+	}}
+}
+
+// addTeardrops adds the teardrops of ApplyTeardrops (as derived
+// primitives if derived is set) and returns their number.
+func (g *Gerber) addTeardrops(opts TeardropOpts, derived bool) int {
+	opts = opts.withDefaults()
+	var count int
+	for _, layer := range g.Layers {
+		if !layer.Type.IsCopper() {
+			continue
+		}
+		// Teardrops are identified by their vertices, so that each end
+		// gets one (even within overlapping pads).
+		existing := map[[4]Pt]bool{}
+		for _, p := range layer.Primitives {
+			if td, ok := p.(*PolygonT); ok && len(td.Points) == 4 && layer.HasTag(p, TeardropTag) {
+				existing[teardropKey(td)] = true
+			}
+		}
+		var added []Primitive
+		for _, p := range layer.Primitives {
+			line, ok := p.(*LineT)
+			if !ok || line.Shape != CircleShape || line.P1 == line.P2 {
+				continue
+			}
+			for _, end := range [][2]Pt{{line.P1, line.P2}, {line.P2, line.P1}} {
+				for _, pad := range layer.At(end[0]) {
+					switch pad.(type) {
+					case *CircleT, *PadT, *FlashT:
+					default:
+						continue
+					}
+					td := teardrop(pad, end[0], end[1], line.Thickness, opts)
+					if td == nil || existing[teardropKey(td)] {
+						continue
+					}
+					existing[teardropKey(td)] = true
+					added = append(added, td)
+				}
+			}
+		}
+		if len(added) == 0 {
+			continue
+		}
+		if derived {
+			layer.AddDerived(added...)
+		} else {
+			layer.Add(added...)
+		}
+		for _, p := range added {
+			layer.Tag(p, TeardropTag)
+		}
+		count += len(added)
+	}
+	return count
+}
+
+func teardropKey(td *PolygonT) [4]Pt {
+	return [4]Pt{td.Points[0], td.Points[1], td.Points[2], td.Points[3]}
+}
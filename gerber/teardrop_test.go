@@ -0,0 +1,54 @@
+package gerber
+
+import "testing"
+
+func TestGerber_ApplyTeardrops(t *testing.T) {
+	g := New("board")
+	top := g.TopCopper()
+	pad, via := Pad(Pt{0, 0}, RectShape, 2, 3, 0), Circle(Pt{10, 0}, 2)
+	top.Add(pad, via, Line(0, 0, 10, 0, CircleShape, 0.2))
+	// A trace as wide as the teardrop and one that ends in no pad.
+	top.Add(Line(10, 0, 10, 10, CircleShape, 1.9), Line(20, 0, 30, 0, CircleShape, 0.2))
+
+	if got := g.ApplyTeardrops(TeardropOpts{}); got != 2 {
+		t.Fatalf("ApplyTeardrops = %v, want 2", got)
+	}
+	td := top.Primitives[len(top.Primitives)-2]
+	if !top.HasTag(td, TeardropTag) {
+		t.Fatal("teardrop is not tagged")
+	}
+	// The pad's smaller dimension (2) sizes the teardrop.
+	if got, want := td.MBB(), (MBB{Min: Pt{0, -0.9}, Max: Pt{2, 0.9}}); !mbbNear(got, want, 1e-9) {
+		t.Errorf("pad teardrop MBB = %v, want %v", got, want)
+	}
+	if got := g.ApplyTeardrops(TeardropOpts{}); got != 0 {
+		t.Errorf("second ApplyTeardrops = %v, want 0", got)
+	}
+}
+
+func TestTeardropPass(t *testing.T) {
+	g := New("board")
+	top := g.TopCopper()
+	g.PlacePadstack(&Padstack{Drill: 1, Top: RoundPad(2)}, Pt{0, 0}, 0)
+	top.Add(Line(0, 0, 10, 0, CircleShape, 0.2))
+	if err := g.InsertPass(PadstackPass.Name, TeardropPass(TeardropOpts{})); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := g.DeriveOpenings(); err != nil {
+			t.Fatal(err)
+		}
+		var n int
+		for _, p := range top.Primitives {
+			if top.HasTag(p, TeardropTag) {
+				if !top.IsDerived(p) {
+					t.Error("teardrop is not derived")
+				}
+				n++
+			}
+		}
+		if n != 1 {
+			t.Errorf("after %v runs, top copper has %v teardrops, want 1", i+1, n)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package gerber
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// TestPadTag is the tag of the test pads added by TestPadPass.
+const TestPadTag = "test-pad"
+
+// TestPadOpts represents the options of the bottom side test pads
+// added by TestPadPass (see WithTestPads). All dimensions are in
+// millimeters.
+type TestPadOpts struct {
+	SiteClearances
+	// Nets are the nets that each get a test pad.
+	Nets []string
+	// Pitch is the grid pitch of the test fixture's probes (e.g. 2.54),
+	// aligned to the design's origin. Test pads are only placed on the
+	// grid.
+	Pitch float64
+	// Pad is the diameter of each test pad.
+	Pad float64
+	// Clearance is the clearance from the bottom copper of other nets
+	// and from the other test pads, and HoleClearance the clearance
+	// from the design's holes.
+	Clearance     float64
+	HoleClearance float64
+}
+
+// addTestPads adds a test pad, with a solder mask opening, for each of
+// the nets to the bottom copper layer, on the first grid site (from
+// the top left) that lies on the net's bottom copper and keeps clear
+// of everything else.
+func (g *Gerber) addTestPads(opts *TestPadOpts) error {
+	if opts.Pitch <= 0 || opts.Pad <= 0 || opts.Pad > opts.Pitch {
+		return errors.New("invalid test pad pitch or size")
+	}
+	bottoms := g.layersOfType(BottomCopperLayer)
+	if len(bottoms) == 0 {
+		return errors.New("test pads require a bottom copper layer")
+	}
+	bottom := bottoms[0]
+	islands := bottom.Islands(IslandOpts{})
+	index := g.siteIndex(opts.SiteClearances, bottom)
+	for _, drill := range g.layersOfType(DrillLayer) {
+		for _, p := range drill.Primitives {
+			index.insert(p, opts.HoleClearance)
+		}
+	}
+
+	for _, net := range opts.Nets {
+		// The net's copper, whose islands may contain untagged traces,
+		// and the copper of everything else.
+		var copper []Primitive
+		obstacles := newSpatialIndex(0)
+		for _, island := range islands {
+			onNet := false
+			for _, n := range bottom.netsOf(island.Primitives) {
+				if n == net {
+					onNet = true
+				}
+			}
+			for _, p := range island.Primitives {
+				if onNet {
+					copper = append(copper, p)
+				} else {
+					obstacles.insert(p, opts.Clearance)
+				}
+			}
+		}
+		if len(copper) == 0 {
+			return fmt.Errorf("net %v has no bottom copper for a test pad", net)
+		}
+		pt, ok := testPadSite(copper, g.origin, opts.Pitch, func(pt Pt) bool {
+			return index.blocked(pt, opts.Pad) || obstacles.blocked(pt, opts.Pad)
+		})
+		if !ok {
+			return fmt.Errorf("no room for a test pad on net %v", net)
+		}
+		pad := Circle(pt, opts.Pad)
+		bottom.AddDerived(pad)
+		bottom.Tag(pad, TestPadTag, NetTag(net))
+		bottom.SetOpenings(pad, Openings{Mask: true})
+		index.insert(pad, opts.Clearance)
+	}
+	return nil
+}
+
+// testPadSite returns the first site of the grid of the given pitch
+// aligned to origin, from the top left, that lies on the copper and is
+// not blocked.
+func testPadSite(copper []Primitive, origin Pt, pitch float64, blocked func(pt Pt) bool) (Pt, bool) {
+	mbb := copper[0].MBB()
+	for _, p := range copper[1:] {
+		v := p.MBB()
+		mbb.Join(&v)
+	}
+	for j := math.Floor((mbb.Max[1] - origin[1]) / pitch); j >= math.Ceil((mbb.Min[1]-origin[1])/pitch); j-- {
+		for i := math.Ceil((mbb.Min[0] - origin[0]) / pitch); i <= math.Floor((mbb.Max[0]-origin[0])/pitch); i++ {
+			pt := Pt{origin[0] + i*pitch, origin[1] + j*pitch}
+			site := outlineOf(Circle(pt, 0))
+			on := false
+			for _, p := range copper {
+				if v := p.MBB(); v.ContainsPoint(&pt) && outlineOf(p).overlaps(site) {
+					on = true
+					break
+				}
+			}
+			if on && !blocked(pt) {
+				return pt, true
+			}
+		}
+	}
+	return Pt{}, false
+}
+
+// TestPoint describes one test pad of a design (see WithTestPads), for
+// drilling the probe holes of a bed-of-nails test fixture.
+type TestPoint struct {
+	Net string `json:"net"`
+	// X and Y are the pad's center, after the design's origin and
+	// export transform are applied (i.e. as written), and Pad its
+	// diameter, in millimeters.
+	X   float64 `json:"x"`
+	Y   float64 `json:"y"`
+	Pad float64 `json:"pad"`
+}
+
+// TestPoints derives the design's test pads (see DeriveOpenings) and
+// returns them, sorted by net.
+func (g *Gerber) TestPoints() ([]*TestPoint, error) {
+	if err := g.DeriveOpenings(); err != nil {
+		return nil, err
+	}
+	var result []*TestPoint
+	for _, layer := range g.layersOfType(BottomCopperLayer) {
+		for _, p := range layer.Select(TestPadTag) {
+			pad, ok := p.(*CircleT)
+			if !ok {
+				continue
+			}
+			var net string
+			if nets := layer.netsOf([]Primitive{p}); len(nets) > 0 {
+				net = nets[0]
+			}
+			center := g.exportPt(pad.pt)
+			result = append(result, &TestPoint{Net: net, X: center[0], Y: center[1], Pad: pad.thickness})
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Net < result[j].Net })
+	return result, nil
+}
+
+// WriteFixtureDrillMap writes the design's test points (see
+// TestPoints) as CSV.
+func (g *Gerber) WriteFixtureDrillMap(w io.Writer) error {
+	points, err := g.TestPoints()
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"net", "x", "y", "pad"})
+	for _, tp := range points {
+		cw.Write([]string{tp.Net, fmtFloat(tp.X), fmtFloat(tp.Y), fmtFloat(tp.Pad)})
+	}
+	cw.Flush()
+	return cw.Error()
+}
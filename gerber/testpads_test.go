@@ -0,0 +1,67 @@
+package gerber
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWithTestPads(t *testing.T) {
+	opts := &TestPadOpts{Nets: []string{"GND", "VCC"}, Pitch: 2.54, Pad: 1, Clearance: 0.3, HoleClearance: 0.5}
+	g := New("test", WithOrigin(Pt{10, 10}), WithTestPads(opts))
+	tht := &Padstack{Name: "tht", Drill: 1, Top: RoundPad(1.7), Bottom: RoundPad(1.7)}
+	g.PlacePadstack(tht, Pt{10, 10}, 0).Net = "GND"
+	bottom := g.BottomCopper()
+	// An untagged trace connected to the GND pad, and a VCC pad.
+	bottom.Add(Line(10, 10, 20, 10, CircleShape, 1))
+	bottom.AddTagged([]string{NetTag("VCC")}, Circle(Pt{12.54, 12.54}, 2))
+
+	var buf bytes.Buffer
+	if err := g.WriteFixtureDrillMap(&buf); err != nil {
+		t.Fatalf("WriteFixtureDrillMap: %v", err)
+	}
+	// The GND pad's own site is too close to its hole.
+	want := "net,x,y,pad\nGND,2.54,0,1\nVCC,2.54,2.54,1\n"
+	if got := buf.String(); got != want {
+		t.Errorf("WriteFixtureDrillMap =\n%v\nwant\n%v", got, want)
+	}
+	pads := bottom.Select(TestPadTag)
+	if len(pads) != 2 || !bottom.IsDerived(pads[0]) {
+		t.Fatalf("test pads = %v, want 2 derived pads", pads)
+	}
+	if got := len(g.firstLayerOfType(BottomSolderMaskLayer).Primitives); got != 3 {
+		t.Errorf("bottom mask openings = %v, want 3 (the pad and 2 test pads)", got)
+	}
+
+	// Running the pipeline again replaces the test pads.
+	if err := g.DeriveOpenings(); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(bottom.Select(TestPadTag)); got != 2 {
+		t.Errorf("test pads after a second run = %v, want 2", got)
+	}
+}
+
+func TestWithTestPads_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		opts TestPadOpts
+		want string
+	}{
+		{"pitch", TestPadOpts{Nets: []string{"GND"}, Pad: 1}, "invalid test pad pitch"},
+		{"no copper", TestPadOpts{Nets: []string{"VCC"}, Pitch: 2.54, Pad: 1}, "net VCC has no bottom copper"},
+		{"no room", TestPadOpts{Nets: []string{"GND"}, Pitch: 2.54, Pad: 1, HoleClearance: 1}, "no room for a test pad on net GND"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			g := New("test", WithTestPads(&opts))
+			g.BottomCopper().AddTagged([]string{NetTag("GND")}, Circle(Pt{0, 0}, 2))
+			g.Drill().Add(Circle(Pt{0, 0}, 0.5))
+			err := g.DeriveOpenings()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("DeriveOpenings = %v, want error containing %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package testutil
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/gmlewis/go-gerber/gerber"
+)
+
+// maxDifferences is the number of differences reported by
+// CompareGeometry.
+const maxDifferences = 10
+
+// CompareGeometry compares two Gerber files semantically: both must
+// flash and draw the same shapes, in the same polarity, with
+// coordinates and sizes (in mm) that differ by up to tol. Unlike
+// CompareGerber, it ignores aperture numbering, the order of
+// statements and objects, the direction of lines and the start point
+// of regions, and the units and coordinate format of the files. It
+// returns an error listing the objects of each file that are missing
+// from the other.
+func CompareGeometry(got, want []byte, tol float64) error {
+	gl, err := gerber.Parse(bytes.NewReader(got))
+	if err != nil {
+		return fmt.Errorf("got: %v", err)
+	}
+	wl, err := gerber.Parse(bytes.NewReader(want))
+	if err != nil {
+		return fmt.Errorf("want: %v", err)
+	}
+	return compareFeatures(featuresOf(gl.Primitives), featuresOf(wl.Primitives), tol)
+}
+
+// CompareLayers writes two layers and compares their output (see
+// CompareGeometry). The layers are compared as written, so their
+// designs' origins and export transforms apply.
+func CompareLayers(got, want *gerber.Layer, tol float64) error {
+	var gb, wb bytes.Buffer
+	if err := got.WriteGerber(&gb); err != nil {
+		return err
+	}
+	if err := want.WriteGerber(&wb); err != nil {
+		return err
+	}
+	return CompareGeometry(gb.Bytes(), wb.Bytes(), tol)
+}
+
+// AssertSameGeometry reports an error if the layers differ (see
+// CompareLayers).
+func AssertSameGeometry(t testing.TB, got, want *gerber.Layer, tol float64) {
+	t.Helper()
+	if err := CompareLayers(got, want, tol); err != nil {
+		t.Errorf("%v: %v", got.Filename, err)
+	}
+}
+
+// feature is a parsed object in a canonical form: its kind (including
+// its polarity and anything else that must match exactly) and the
+// values that may differ by up to the tolerance. Objects with more
+// than one equivalent form (e.g. a line drawn in either direction)
+// list each of them in forms.
+type feature struct {
+	kind  string
+	forms [][]float64
+	desc  string
+}
+
+func featuresOf(primitives []gerber.Primitive) []*feature {
+	var result []*feature
+	for _, p := range primitives {
+		result = append(result, featureOf(p))
+	}
+	return result
+}
+
+func featureOf(p gerber.Primitive) *feature {
+	switch p := p.(type) {
+	case *gerber.ClearT:
+		f := featureOf(p.Primitive)
+		f.kind = "clear " + f.kind
+		f.desc = "clear " + f.desc
+		return f
+	case *gerber.CircleT:
+		mbb := p.MBB()
+		d := mbb.Max[0] - mbb.Min[0]
+		c := gerber.Pt{0.5 * (mbb.Min[0] + mbb.Max[0]), 0.5 * (mbb.Min[1] + mbb.Max[1])}
+		return &feature{
+			kind:  "circle",
+			forms: [][]float64{{d, c[0], c[1]}},
+			desc:  fmt.Sprintf("circle %v at %v", d, c),
+		}
+	case *gerber.LineT:
+		return &feature{
+			kind: "line " + string(p.Shape),
+			forms: [][]float64{
+				{p.Thickness, p.P1[0], p.P1[1], p.P2[0], p.P2[1]},
+				{p.Thickness, p.P2[0], p.P2[1], p.P1[0], p.P1[1]},
+			},
+			desc: fmt.Sprintf("line %v %v from %v to %v", p.Shape, p.Thickness, p.P1, p.P2),
+		}
+	case *gerber.ArcT:
+		// Parsed arcs are counterclockwise, so their ends are
+		// canonical; compare the ends rather than the angles, which
+		// may differ by whole turns.
+		p1 := gerber.PolarFrom(p.Center, p.Radius, gerber.Degrees(p.StartAngle))
+		p2 := gerber.PolarFrom(p.Center, p.Radius, gerber.Degrees(p.EndAngle))
+		return &feature{
+			kind:  "arc",
+			forms: [][]float64{{p.Thickness, p.Center[0], p.Center[1], p1[0], p1[1], p2[0], p2[1]}},
+			desc:  fmt.Sprintf("arc %v about %v from %v to %v", p.Thickness, p.Center, p1, p2),
+		}
+	case *gerber.PolygonT:
+		var pts []gerber.Pt
+		for _, pt := range p.Points {
+			pts = append(pts, gerber.Pt{p.Offset[0] + pt[0], p.Offset[1] + pt[1]})
+		}
+		f := &feature{kind: fmt.Sprintf("polygon %v", len(pts)), desc: fmt.Sprintf("polygon %v", pts)}
+		// Any vertex may start the contour.
+		for i := range pts {
+			var form []float64
+			for j := range pts {
+				pt := pts[(i+j)%len(pts)]
+				form = append(form, pt[0], pt[1])
+			}
+			f.forms = append(f.forms, form)
+		}
+		return f
+	case *gerber.FlashT:
+		return &feature{
+			kind:  fmt.Sprintf("flash %v %v", p.Macro.Name, len(p.Params)),
+			forms: [][]float64{append([]float64{p.Center[0], p.Center[1]}, p.Params...)},
+			desc:  fmt.Sprintf("flash %v%v at %v", p.Macro.Name, p.Params, p.Center),
+		}
+	}
+	mbb := p.MBB()
+	return &feature{
+		kind:  fmt.Sprintf("%T", p),
+		forms: [][]float64{{mbb.Min[0], mbb.Min[1], mbb.Max[0], mbb.Max[1]}},
+		desc:  fmt.Sprintf("%T %v", p, mbb),
+	}
+}
+
+// matches reports whether the features are the same within tol.
+func (f *feature) matches(other *feature, tol float64) bool {
+	if f.kind != other.kind {
+		return false
+	}
+	want := other.forms[0]
+	for _, form := range f.forms {
+		if len(form) != len(want) {
+			continue
+		}
+		same := true
+		for i := range form {
+			if math.Abs(form[i]-want[i]) > tol {
+				same = false
+				break
+			}
+		}
+		if same {
+			return true
+		}
+	}
+	return false
+}
+
+// compareFeatures matches each feature of got with a distinct feature
+// of want, and returns an error describing those left over.
+func compareFeatures(got, want []*feature, tol float64) error {
+	matched := make([]bool, len(want))
+	var unexpected []*feature
+	for _, g := range got {
+		found := false
+		for i, w := range want {
+			if !matched[i] && g.matches(w, tol) {
+				matched[i], found = true, true
+				break
+			}
+		}
+		if !found {
+			unexpected = append(unexpected, g)
+		}
+	}
+	var diffs []string
+	for i, w := range want {
+		if !matched[i] {
+			diffs = append(diffs, "missing "+w.desc)
+		}
+	}
+	for _, g := range unexpected {
+		diffs = append(diffs, "unexpected "+g.desc)
+	}
+	if len(diffs) == 0 {
+		return nil
+	}
+	if len(diffs) > maxDifferences {
+		diffs = append(diffs[:maxDifferences], fmt.Sprintf("... and %v more", len(diffs)-maxDifferences))
+	}
+	return fmt.Errorf("geometry differs (within %vmm):\n  %v", tol, strings.Join(diffs, "\n  "))
+}
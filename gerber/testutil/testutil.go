@@ -0,0 +1,229 @@
+// Package testutil provides helpers for writing regression tests of
+// board generators: golden-file comparison of Gerber output at a
+// geometric tolerance (line by line, or semantically), and MBB and
+// aperture table assertions.
+package testutil
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gmlewis/go-gerber/gerber"
+)
+
+// update causes Golden to (re)write its golden files instead of
+// comparing against them (e.g. "go test -update-golden").
+var update = flag.Bool("update-golden", false, "update golden Gerber files")
+
+// AssertMBB reports an error if got and want differ by more than tol
+// (in mm) in any coordinate.
+func AssertMBB(t testing.TB, got, want gerber.MBB, tol float64) {
+	t.Helper()
+	for i := 0; i < 2; i++ {
+		if math.Abs(got.Min[i]-want.Min[i]) > tol || math.Abs(got.Max[i]-want.Max[i]) > tol {
+			t.Errorf("MBB = %v, want %v (within %v)", got, want, tol)
+			return
+		}
+	}
+}
+
+// AssertApertures reports an error if the aperture table of the layer
+// does not match want (in order), comparing sizes to within tol (in mm).
+func AssertApertures(t testing.TB, layer *gerber.Layer, want []gerber.Aperture, tol float64) {
+	t.Helper()
+	got := layer.Apertures
+	if len(got) != len(want) {
+		t.Errorf("%v: %v apertures, want %v", layer.Filename, len(got), len(want))
+		return
+	}
+	for i, a := range got {
+		if a.Shape != want[i].Shape || math.Abs(a.Size-want[i].Size) > tol {
+			t.Errorf("%v: aperture #%v = %v, want %v", layer.Filename, i, a, &want[i])
+		}
+	}
+}
+
+// Golden writes every layer of the design and compares it (see
+// CompareGerber) to the file of the same base name in dir. When the
+// test binary is run with -update-golden, the golden files are
+// written instead.
+func Golden(t testing.TB, g *gerber.Gerber, dir string, tol float64) {
+	t.Helper()
+	golden(t, g, dir, tol, CompareGerber)
+}
+
+// GoldenGeometry is like Golden but compares the layers semantically
+// (see CompareGeometry), so that the golden files need not be updated
+// when only the aperture numbering or the order of the output changes.
+func GoldenGeometry(t testing.TB, g *gerber.Gerber, dir string, tol float64) {
+	t.Helper()
+	golden(t, g, dir, tol, CompareGeometry)
+}
+
+func golden(t testing.TB, g *gerber.Gerber, dir string, tol float64, compare func(got, want []byte, tol float64) error) {
+	t.Helper()
+	files := map[string]*bytes.Buffer{}
+	err := g.Write(func(filename string) (io.WriteCloser, error) {
+		buf := &bytes.Buffer{}
+		files[filepath.Base(filename)] = buf
+		return nopCloser{buf}, nil
+	})
+	if err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if *update {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range names {
+			if err := ioutil.WriteFile(filepath.Join(dir, name), files[name].Bytes(), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return
+	}
+	for _, name := range names {
+		want, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Errorf("%v (run with -update-golden to create it)", err)
+			continue
+		}
+		if err := compare(files[name].Bytes(), want, tol); err != nil {
+			t.Errorf("%v: %v", name, err)
+		}
+	}
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+// CompareGerber compares two Gerber files line by line, allowing
+// coordinates and aperture sizes to differ by up to tol (in mm). Both
+// files must use the same coordinate format and units. It returns an
+// error describing the first difference.
+func CompareGerber(got, want []byte, tol float64) error {
+	gotLines, wantLines := lines(got), lines(want)
+	decimal, mmPerUnit := 6, 1.0 // the defaults: %FSLAX36Y36*% and %MOMM*%
+	for i := 0; i < len(gotLines) || i < len(wantLines); i++ {
+		if i >= len(gotLines) {
+			return fmt.Errorf("line %v: missing %q", i+1, wantLines[i])
+		}
+		if i >= len(wantLines) {
+			return fmt.Errorf("line %v: unexpected %q", i+1, gotLines[i])
+		}
+		g, w := gotLines[i], wantLines[i]
+		if g == w {
+			var integer int
+			if _, err := fmt.Sscanf(g, "%%FSLAX%1d%1d", &integer, &decimal); err == nil {
+				continue
+			}
+			switch g {
+			case "%MOMM*%":
+				mmPerUnit = 1
+			case "%MOIN*%":
+				mmPerUnit = 25.4
+			}
+			continue
+		}
+		// Integer coordinates are in units of 10^-decimal.
+		coordTol := tol / mmPerUnit * math.Pow(10, float64(decimal))
+		if same, ok := compareCoords(g, w, coordTol); ok {
+			if !same {
+				return fmt.Errorf("line %v: got %q, want %q (within %vmm)", i+1, g, w, tol)
+			}
+			continue
+		}
+		if same, ok := compareApertures(g, w, tol/mmPerUnit); ok && same {
+			continue
+		}
+		return fmt.Errorf("line %v: got %q, want %q", i+1, g, w)
+	}
+	return nil
+}
+
+func lines(data []byte) []string {
+	var result []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		result = append(result, strings.TrimSpace(scanner.Text()))
+	}
+	return result
+}
+
+// compareCoords compares two "X...Y...D.." operations whose integer
+// coordinates may differ by up to tol. ok is false if either line is
+// not such an operation.
+func compareCoords(got, want string, tol float64) (same, ok bool) {
+	var gx, gy, wx, wy int64
+	var gd, wd int
+	if _, err := fmt.Sscanf(got, "X%dY%dD%d*", &gx, &gy, &gd); err != nil {
+		return false, false
+	}
+	if _, err := fmt.Sscanf(want, "X%dY%dD%d*", &wx, &wy, &wd); err != nil {
+		return false, false
+	}
+	return gd == wd && math.Abs(float64(gx-wx)) <= tol && math.Abs(float64(gy-wy)) <= tol, true
+}
+
+// compareApertures compares two "%ADDnnS,size...*%" aperture definitions
+// whose sizes (in file units) may differ by up to tol. ok is false if
+// either line is not an aperture definition.
+func compareApertures(got, want string, tol float64) (same, ok bool) {
+	gid, gsizes, ok := parseAperture(got)
+	if !ok {
+		return false, false
+	}
+	wid, wsizes, ok := parseAperture(want)
+	if !ok {
+		return false, false
+	}
+	if gid != wid || len(gsizes) != len(wsizes) {
+		return false, true
+	}
+	for i := range gsizes {
+		if math.Abs(gsizes[i]-wsizes[i]) > tol {
+			return false, true
+		}
+	}
+	return true, true
+}
+
+// parseAperture returns the D-code and template (e.g. "12C") and the
+// modifiers of an aperture definition.
+func parseAperture(s string) (id string, sizes []float64, ok bool) {
+	if !strings.HasPrefix(s, "%ADD") || !strings.HasSuffix(s, "*%") {
+		return "", nil, false
+	}
+	fields := strings.SplitN(s[len("%ADD"):len(s)-len("*%")], ",", 2)
+	if len(fields) != 2 {
+		return "", nil, false
+	}
+	for _, m := range strings.Split(fields[1], "X") {
+		v, err := strconv.ParseFloat(m, 64)
+		if err != nil {
+			return "", nil, false
+		}
+		sizes = append(sizes, v)
+	}
+	return fields[0], sizes, true
+}
@@ -0,0 +1,100 @@
+package testutil
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gmlewis/go-gerber/gerber"
+)
+
+func TestCompareGerber(t *testing.T) {
+	want := "%FSLAX36Y36*%\n%MOMM*%\n%ADD12C,0.50000*%\nX1000000Y2000000D02*\nX1000000Y2000000D01*\nM02*\n"
+	tests := []struct {
+		name    string
+		got     string
+		tol     float64
+		wantErr bool
+	}{
+		{name: "identical", got: want},
+		{name: "coordinate within tolerance", got: "%FSLAX36Y36*%\n%MOMM*%\n%ADD12C,0.50000*%\nX1000001Y2000000D02*\nX1000000Y2000000D01*\nM02*\n", tol: 1e-5},
+		{name: "coordinate beyond tolerance", got: "%FSLAX36Y36*%\n%MOMM*%\n%ADD12C,0.50000*%\nX1000100Y2000000D02*\nX1000000Y2000000D01*\nM02*\n", tol: 1e-5, wantErr: true},
+		{name: "aperture within tolerance", got: "%FSLAX36Y36*%\n%MOMM*%\n%ADD12C,0.50001*%\nX1000000Y2000000D02*\nX1000000Y2000000D01*\nM02*\n", tol: 1e-4},
+		{name: "aperture beyond tolerance", got: "%FSLAX36Y36*%\n%MOMM*%\n%ADD12C,0.60000*%\nX1000000Y2000000D02*\nX1000000Y2000000D01*\nM02*\n", tol: 1e-4, wantErr: true},
+		{name: "different operation", got: "%FSLAX36Y36*%\n%MOMM*%\n%ADD12C,0.50000*%\nX1000000Y2000000D02*\nX1000000Y2000000D02*\nM02*\n", tol: 1, wantErr: true},
+		{name: "missing line", got: "%FSLAX36Y36*%\n%MOMM*%\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CompareGerber([]byte(tt.got), []byte(want), tt.tol)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CompareGerber = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGolden(t *testing.T) {
+	dir, err := ioutil.TempDir("", "golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	design := func(x float64) *gerber.Gerber {
+		g := gerber.New("coil")
+		g.TopCopper().Add(gerber.Line(0, 0, x, 0, gerber.CircleShape, 0.2))
+		g.Outline().Add(gerber.OutlinePath(gerber.RoundedRect(gerber.MBB{Max: gerber.Pt{10, 10}}, 0), 0.1)...)
+		return g
+	}
+	*update = true
+	Golden(t, design(5), dir, 0)
+	*update = false
+	Golden(t, design(5.000001), dir, 1e-5)
+	GoldenGeometry(t, design(5.000001), dir, 1e-5)
+
+	g := design(5)
+	AssertMBB(t, g.Layers[0].MBB(), gerber.MBB{Min: gerber.Pt{-0.1, -0.1}, Max: gerber.Pt{5.1, 0.1}}, 1e-9)
+	AssertApertures(t, g.Layers[0], []gerber.Aperture{{Shape: gerber.CircleShape, Size: 0.2}}, 1e-9)
+}
+
+func TestCompareGeometry(t *testing.T) {
+	want := "%FSLAX36Y36*%\n%MOMM*%\n%ADD10C,0.50000*%\n%ADD11C,0.20000*%\nD10*\nX1000000Y2000000D03*\nD11*\nX0Y0D02*\nX5000000Y0D01*\n%LPC*%\nD10*\nX3000000Y0D03*\n%LPD*%\nG36*\nX0Y0D02*\nX1000000Y0D01*\nX1000000Y1000000D01*\nX0Y0D01*\nG37*\nM02*\n"
+	tests := []struct {
+		name    string
+		got     string
+		tol     float64
+		wantErr bool
+	}{
+		{name: "identical", got: want},
+		{name: "renumbered and reordered",
+			got: "%FSLAX25Y25*%\n%MOIN*%\n%ADD12C,0.00787*%\n%ADD13C,0.01969*%\nG36*\nX3937Y0D02*\nX3937Y3937D01*\nX0Y0D01*\nX3937Y0D01*\nG37*\nD12*\nX19685Y0D02*\nX0Y0D01*\n%LPC*%\nD13*\nX11811Y0D03*\n%LPD*%\nD13*\nX3937Y7874D03*\nM02*\n",
+			tol: 1e-3},
+		{name: "moved", got: strings.Replace(want, "X1000000Y2000000D03", "X1000000Y2100000D03", 1), tol: 1e-3, wantErr: true},
+		{name: "polarity", got: strings.Replace(want, "%LPC*%\n", "", 1), tol: 1e-3, wantErr: true},
+		{name: "missing object", got: strings.Replace(want, "X1000000Y2000000D03*\n", "", 1), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CompareGeometry([]byte(tt.got), []byte(want), tt.tol)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CompareGeometry = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCompareLayers(t *testing.T) {
+	a := gerber.New("a").TopCopper()
+	a.Add(gerber.Circle(gerber.Pt{1, 1}, 0.5), gerber.Arc(gerber.Pt{0, 0}, 2, gerber.CircleShape, 1, 1, 0, 90, 0.2))
+	b := gerber.New("b").TopCopper()
+	b.Add(gerber.Arc(gerber.Pt{0, 0}, 2, gerber.CircleShape, 1, 1, 0, 90, 0.2), gerber.Circle(gerber.Pt{1, 1.00001}, 0.5))
+	AssertSameGeometry(t, a, b, 1e-4)
+
+	b.Add(gerber.Line(0, 0, 1, 0, gerber.CircleShape, 0.2))
+	err := CompareLayers(a, b, 1e-4)
+	if err == nil || !strings.Contains(err.Error(), "missing line C 0.2 from [0 0] to [1 0]") {
+		t.Errorf("CompareLayers = %v, want a missing line", err)
+	}
+}
@@ -1,9 +1,10 @@
 package gerber
 
 import (
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"math"
 
 	"github.com/gmlewis/go-fonts/fonts"
 )
@@ -44,11 +45,18 @@ type TextT struct {
 	fontName string
 	pts      float64
 	Render   *fonts.Render
+	err      error // deferred error from construction or rendering
 }
 
-func verifyOrSubstituteFont(fontName string) string {
+// ErrNoFonts is reported when a text primitive is created but no fonts
+// have been registered (e.g. by importing a go-fonts font package).
+var ErrNoFonts = errors.New("no fonts available")
+
+func verifyOrSubstituteFont(fontName string) (string, error) {
+	fontsMu.RLock()
+	defer fontsMu.RUnlock()
 	if len(fonts.Fonts) == 0 {
-		log.Fatal("No fonts available")
+		return "", ErrNoFonts
 	}
 
 	if _, ok := fonts.Fonts[fontName]; !ok {
@@ -56,22 +64,30 @@ func verifyOrSubstituteFont(fontName string) string {
 		for name = range fonts.Fonts {
 			break
 		}
-		log.Printf("Could not find font %q: using %q instead", fontName, name)
+		logger.Printf("Could not find font %q: using %q instead", fontName, name)
 		fontName = name
 	}
-	return fontName
+	return fontName, nil
 }
 
 // TextBox returns a text primitive where the text fills the MBB and aligns
 // the text according to TextOpts.
 // All dimensions are in millimeters.
 // xScale is 1.0 for top silkscreen and -1.0 for bottom silkscreen.
+//
+// Any error (such as no available fonts) is deferred and reported
+// by Err and WriteGerber.
 func TextBox(mbb MBB, xScale float64, message, fontName string, opts *TextOpts) *TextT {
-	fontName = verifyOrSubstituteFont(fontName)
+	fontName, err := verifyOrSubstituteFont(fontName)
+	if err != nil {
+		return &TextT{xScale: xScale, opts: opts, message: message, err: err}
+	}
 
+	fontsMu.RLock()
 	x, y, pts, err := fonts.FillBox(mbb, xScale, 1.0, message, fontName, opts)
+	fontsMu.RUnlock()
 	if err != nil {
-		log.Fatalf("fonts.FillBox: %v", err)
+		return &TextT{xScale: xScale, opts: opts, message: message, fontName: fontName, err: fmt.Errorf("fonts.FillBox: %v", err)}
 	}
 	return &TextT{
 		x:        x,
@@ -87,8 +103,11 @@ func TextBox(mbb MBB, xScale float64, message, fontName string, opts *TextOpts)
 // Text returns a text primitive.
 // All dimensions are in millimeters.
 // xScale is 1.0 for top silkscreen and -1.0 for bottom silkscreen.
+//
+// Any error (such as no available fonts) is deferred and reported
+// by Err and WriteGerber.
 func Text(x, y, xScale float64, message, fontName string, pts float64, opts *TextOpts) *TextT {
-	fontName = verifyOrSubstituteFont(fontName)
+	fontName, err := verifyOrSubstituteFont(fontName)
 
 	return &TextT{
 		x:        x,
@@ -98,24 +117,42 @@ func Text(x, y, xScale float64, message, fontName string, pts float64, opts *Tex
 		message:  message,
 		fontName: fontName,
 		pts:      pts,
+		err:      err,
+	}
+}
+
+// Err returns any error encountered while creating or rendering the text.
+func (t *TextT) Err() error {
+	if t.err != nil {
+		return t.err
 	}
+	return t.renderText()
 }
 
 func (t *TextT) renderText() error {
+	if t.err != nil {
+		return t.err
+	}
 	if t.Render == nil {
 		yScale := t.pts * mmPerPt
 		xScale := t.xScale * yScale
-		var err error
-		if t.Render, err = fonts.Text(t.x, t.y, xScale, yScale, t.message, t.fontName, t.opts); err != nil {
+		fontsMu.RLock()
+		render, err := fonts.Text(t.x, t.y, xScale, yScale, t.message, t.fontName, t.opts)
+		fontsMu.RUnlock()
+		if t.Render = render; err != nil {
+			t.err = err
 			return err
 		}
 	}
 	return nil
 }
 
+// MBB returns the minimum bounding box of the text in millimeters.
+// If the text could not be rendered, an empty MBB is returned and
+// the error is available from Err.
 func (t *TextT) MBB() MBB {
 	if err := t.renderText(); err != nil {
-		log.Fatal(err)
+		return MBB{}
 	}
 	return t.Render.MBB
 }
@@ -123,7 +160,7 @@ func (t *TextT) MBB() MBB {
 // Width returns the width of the text in millimeters.
 func (t *TextT) Width() float64 {
 	if err := t.renderText(); err != nil {
-		log.Fatal(err)
+		return 0
 	}
 	width := t.Render.MBB.Max[0] - t.Render.MBB.Min[0]
 	return width
@@ -132,7 +169,7 @@ func (t *TextT) Width() float64 {
 // Height returns the height of the text in millimeters
 func (t *TextT) Height() float64 {
 	if err := t.renderText(); err != nil {
-		log.Fatal(err)
+		return 0
 	}
 	height := t.Render.MBB.Max[1] - t.Render.MBB.Min[1]
 	return height
@@ -144,31 +181,29 @@ func (t *TextT) WriteGerber(w io.Writer, apertureIndex int) error {
 		return err
 	}
 
+	gw := toWriter(w)
 	currentDark := true
 	for _, poly := range t.Render.Polygons {
-		if poly.Dark && !currentDark {
-			io.WriteString(w, "%LPD*%\n")
-			currentDark = true
-		} else if !poly.Dark && currentDark {
-			io.WriteString(w, "%LPC*%\n")
-			currentDark = false
+		if poly.Dark != currentDark {
+			gw.polarity(poly.Dark)
+			currentDark = poly.Dark
 		}
 
-		io.WriteString(w, "G54D11*\n")
+		gw.aperture(apertureIndex)
 		io.WriteString(w, "G36*\n")
 		for i, pt := range poly.Pts {
 			if i == 0 {
-				fmt.Fprintf(w, "X%06dY%06dD02*\n", int(0.5+sf*pt[0]), int(0.5+sf*pt[1]))
+				gw.move(pt[0], pt[1])
 				continue
 			}
-			fmt.Fprintf(w, "X%06dY%06dD01*\n", int(0.5+sf*pt[0]), int(0.5+sf*pt[1]))
+			gw.draw(pt[0], pt[1])
 		}
-		fmt.Fprintf(w, "X%06dY%06dD02*\n", int(0.5+sf*poly.Pts[0][0]), int(0.5+sf*poly.Pts[0][1]))
+		gw.move(poly.Pts[0][0], poly.Pts[0][1])
 		io.WriteString(w, "G37*\n")
 	}
 
 	if !currentDark {
-		io.WriteString(w, "%LPD*%\n")
+		gw.polarity(true)
 	}
 	return nil
 }
@@ -177,3 +212,55 @@ func (t *TextT) WriteGerber(w io.Writer, apertureIndex int) error {
 func (t *TextT) Aperture() *Aperture {
 	return nil
 }
+
+// Transform returns a transformed copy of the text.
+// Mirroring transforms (e.g. MirrorY) reverse the text's xScale
+// so that mirrored text reads correctly from the other side.
+func (t *TextT) Transform(xf Transform) Primitive {
+	pos := xf.Apply(Pt{t.x, t.y})
+	opts := &TextOpts{}
+	if t.opts != nil {
+		*opts = *t.opts
+	}
+	xScale := t.xScale
+	if xf.det() < 0 {
+		// Express the mirror as MirrorY followed by a rotation, noting
+		// that MirrorY reverses the direction of the original rotation.
+		xScale = -xScale
+		opts.Rotate = math.Atan2(-xf.D, -xf.A) - opts.Rotate
+	} else {
+		opts.Rotate += xf.rotation()
+	}
+	pts := xf.scaleFactor() * t.pts
+	nt := Text(pos[0], pos[1], xScale, t.message, t.fontName, pts, opts)
+	if t.err != nil {
+		nt.err = t.err
+		return nt
+	}
+	// The fonts package rotates text about the origin of its first
+	// glyph, not about its anchor point, so move the copy to put that
+	// origin where xf puts the original's.
+	if t.renderText() == nil && nt.renderText() == nil && len(t.Render.Info) > 0 {
+		want := xf.Apply(Pt{t.Render.Info[0].X, t.Render.Info[0].Y})
+		got := nt.Render.Info[0]
+		nt = Text(pos[0]+want[0]-got.X, pos[1]+want[1]-got.Y, xScale, t.message, t.fontName, pts, opts)
+	}
+	return nt
+}
+
+// Contains reports whether pt lies within the dark area of the text.
+func (t *TextT) Contains(pt Pt) bool {
+	if err := t.renderText(); err != nil {
+		return false
+	}
+	if !t.Render.MBB.ContainsPoint(&pt) {
+		return false
+	}
+	var inside bool
+	for _, poly := range t.Render.Polygons {
+		if pointInPolygon(pt, poly.Pts) {
+			inside = poly.Dark
+		}
+	}
+	return inside
+}
@@ -224,3 +224,34 @@ func TestText(t *testing.T) {
 		})
 	}
 }
+
+func TestTextT_Transform(t *testing.T) {
+	const eps = 1e-6
+	orig := Text(10, 20, 1, "012", "freeserif", 72, nil)
+	want := orig.MBB()
+	want.Min[0], want.Max[0] = -want.Max[0], -want.Min[0]
+
+	got := orig.Transform(MirrorY()).MBB()
+	if math.Abs(got.Min[0]-want.Min[0]) > eps || math.Abs(got.Min[1]-want.Min[1]) > eps ||
+		math.Abs(got.Max[0]-want.Max[0]) > eps || math.Abs(got.Max[1]-want.Max[1]) > eps {
+		t.Errorf("MBB = %v, want %v", got, want)
+	}
+
+	// Rotations and mirrors move the text's glyphs rigidly, whatever
+	// its anchor.
+	centered := Text(10, 20, 1, "012", "freeserif", 72, &Center)
+	mbb := centered.MBB()
+	for _, tt := range []struct {
+		name string
+		xf   Transform
+		want MBB
+	}{
+		{"MirrorX", MirrorX(), MBB{Min: Pt{mbb.Min[0], -mbb.Max[1]}, Max: Pt{mbb.Max[0], -mbb.Min[1]}}},
+		{"Rotate(180)", Rotate(180), MBB{Min: Pt{-mbb.Max[0], -mbb.Max[1]}, Max: Pt{-mbb.Min[0], -mbb.Min[1]}}},
+		{"Rotate(90)", Rotate(90), MBB{Min: Pt{-mbb.Max[1], mbb.Min[0]}, Max: Pt{-mbb.Min[1], mbb.Max[0]}}},
+	} {
+		if got := centered.Transform(tt.xf).MBB(); !mbbNear(got, tt.want, eps) {
+			t.Errorf("%v: MBB = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
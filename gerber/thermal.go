@@ -0,0 +1,61 @@
+package gerber
+
+import "math"
+
+// Thermal relief defaults (see PourOpts).
+const (
+	defaultSpokeWidth = 0.3 // mm
+	defaultSpokeCount = 4
+)
+
+// pourVoids returns the closed polygons of the voids of a pour around
+// its obstacles and connections.
+func pourVoids(o PourOpts) [][]Pt {
+	var result [][]Pt
+	for _, p := range o.Obstacles {
+		result = append(result, grownOutline(outlineOf(p), o.Clearance)...)
+	}
+	for _, p := range o.Connections {
+		result = append(result, grownOutline(outlineOf(p), o.thermalGap())...)
+	}
+	return result
+}
+
+// thermalGap returns the clearance of the pour's connections.
+func (o PourOpts) thermalGap() float64 {
+	if o.ThermalGap > 0 {
+		return o.ThermalGap
+	}
+	return o.Clearance
+}
+
+// thermalSpokes returns the thermal relief spokes joining a pour to
+// its connections.
+func thermalSpokes(o PourOpts) []Primitive {
+	gap := o.thermalGap()
+	spokeWidth, spokeCount := o.SpokeWidth, o.SpokeCount
+	if spokeWidth <= 0 {
+		spokeWidth = defaultSpokeWidth
+	}
+	if spokeCount <= 0 {
+		spokeCount = defaultSpokeCount
+	}
+
+	var result []Primitive
+	for _, p := range o.Connections {
+		mbb := p.MBB()
+		center := Pt{0.5 * (mbb.Min[0] + mbb.Max[0]), 0.5 * (mbb.Min[1] + mbb.Max[1])}
+		hw, hh := 0.5*(mbb.Max[0]-mbb.Min[0]), 0.5*(mbb.Max[1]-mbb.Min[1])
+		for i := 0; i < spokeCount; i++ {
+			angle := o.SpokeAngle + 360*float64(i)/float64(spokeCount)
+			// Spokes run from the center of the pad across the gap,
+			// overlapping the pour by a spoke width.
+			rad := angle * math.Pi / 180
+			cos, sin := math.Abs(math.Cos(rad)), math.Abs(math.Sin(rad))
+			reach := math.Min(hw/math.Max(cos, 1e-9), hh/math.Max(sin, 1e-9))
+			end := PolarFrom(center, reach+gap+spokeWidth, angle)
+			result = append(result, Line(center[0], center[1], end[0], end[1], CircleShape, spokeWidth))
+		}
+	}
+	return result
+}
@@ -0,0 +1,296 @@
+package gerber
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/gmlewis/go-gerber/gerber/internal/polyclip"
+)
+
+// FiducialTag, ThievingTag, and StitchingViaTag are the tags of
+// fiducial marks (which AddThieving and AddStitchingVias keep clear of)
+// and of the features added by AddThieving and AddStitchingVias.
+const (
+	FiducialTag     = "fiducial"
+	ThievingTag     = "thieving"
+	StitchingViaTag = "stitching-via"
+)
+
+// SiteClearances represents the clearances (in millimeters) kept by
+// AddThieving and AddStitchingVias, in addition to avoiding the
+// keepouts of the layers they add to (see AddKeepout).
+type SiteClearances struct {
+	// Edge is the clearance from the board outline.
+	Edge float64
+	// Fiducial is the clearance from fiducial marks (primitives on any
+	// layer tagged with FiducialTag).
+	Fiducial float64
+}
+
+// ThievingOpts represents the options used by AddThieving.
+// All dimensions are in millimeters.
+type ThievingOpts struct {
+	SiteClearances
+	// Pitch is the center-to-center spacing of the dots.
+	Pitch float64
+	// Size is the diameter (or, with RectShape, the side) of each dot.
+	Size  float64
+	Shape Shape
+	// Clearance is the clearance from the layer's other copper.
+	Clearance float64
+}
+
+// AddThieving fills the closed polygon region of this copper layer with
+// a grid of copper thieving dots (balancing the copper density for
+// plating), tagged with ThievingTag. Dots that would come within the
+// clearances of the layer's existing copper, the board outline, or the
+// design's fiducials, or that would touch the layer's keepouts, are
+// omitted. It returns the dots added.
+func (l *Layer) AddThieving(region []Pt, opts ThievingOpts) ([]Primitive, error) {
+	if l.g == nil {
+		return nil, errors.New("layer does not belong to a design")
+	}
+	if !l.Type.IsCopper() {
+		return nil, fmt.Errorf("thieving requires a copper layer, not %v", l.Type)
+	}
+	if opts.Pitch <= 0 || opts.Size <= 0 || opts.Size >= opts.Pitch {
+		return nil, errors.New("invalid thieving pitch or size")
+	}
+	// The circle enclosing each dot.
+	d := opts.Size
+	if opts.Shape == RectShape {
+		d *= math.Sqrt2
+	}
+	index := l.g.siteIndex(opts.SiteClearances, l)
+	for _, p := range l.Primitives {
+		index.insert(p, opts.Clearance)
+	}
+	var dots []Primitive
+	for _, pt := range gridSites(region, opts.Pitch, 0.5*d) {
+		if index.blocked(pt, d) {
+			continue
+		}
+		if opts.Shape == RectShape {
+			h := 0.5 * opts.Size
+			dots = append(dots, Polygon(pt, true, []Pt{{-h, -h}, {h, -h}, {h, h}, {-h, h}}, 0))
+		} else {
+			dots = append(dots, Circle(pt, opts.Size))
+		}
+	}
+	l.AddTagged([]string{ThievingTag}, dots...)
+	return dots, nil
+}
+
+// StitchingOpts represents the options used by AddStitchingVias and
+// AddViaFence.
+// All dimensions are in millimeters.
+type StitchingOpts struct {
+	SiteClearances
+	// Pitch is the center-to-center spacing of the vias (along the
+	// path, for AddViaFence).
+	Pitch float64
+	// Drill and Pad are the drill and pad diameters of each via.
+	Drill float64
+	Pad   float64
+	// HoleClearance is the clearance from the design's other holes.
+	HoleClearance float64
+	// CopperClearance, if positive, is the clearance from the existing
+	// copper of the stitched layers other than that of Net (primitives
+	// tagged with its NetTag, such as the ground pour being stitched),
+	// e.g. to keep the vias away from signal traces.
+	CopperClearance float64
+	// Net, if set, is the net of the vias, whose pads (and holes) are
+	// tagged with its NetTag.
+	Net string
+}
+
+// ViaFenceTag is the tag of the vias added by AddViaFence, in
+// addition to StitchingViaTag.
+const ViaFenceTag = "via-fence"
+
+// AddStitchingVias fills the closed polygon region with a grid of vias
+// (tagged with StitchingViaTag) stitching together the given copper
+// layers (e.g. ground pours). Vias that would come within the
+// clearances of the design's other holes, the board outline, its
+// fiducials, or (see StitchingOpts.CopperClearance) the layers' other
+// copper, or that would touch the keepouts of any of the layers, are
+// omitted. The design's drill layer is added if necessary. It returns
+// the vias added.
+func (g *Gerber) AddStitchingVias(region []Pt, opts StitchingOpts, copper ...*Layer) ([]Via, error) {
+	if opts.Pitch <= 0 || opts.Drill <= 0 || opts.Pad < opts.Drill || opts.Pad >= opts.Pitch {
+		return nil, errors.New("invalid via pitch or size")
+	}
+	return g.addVias(gridSites(region, opts.Pitch, 0.5*opts.Pad), opts, []string{StitchingViaTag}, copper)
+}
+
+// AddViaFence places a fence of vias (tagged with StitchingViaTag and
+// ViaFenceTag) stitching together the given copper layers along the
+// path, opts.Pitch apart, or, if offset is positive, a row of vias on
+// each side of the path at that distance from it (e.g. to shield an RF
+// trace or a coplanar waveguide). The vias of an open path are centered
+// along it; a closed path (whose last point is its first) is fenced all
+// the way around at a pitch of at least opts.Pitch. Vias are omitted as
+// by AddStitchingVias, and where they would overlap each other (such as
+// on the inside of sharp corners). It returns the vias added.
+func (g *Gerber) AddViaFence(path []Pt, offset float64, opts StitchingOpts, copper ...*Layer) ([]Via, error) {
+	if opts.Pitch <= 0 || opts.Drill <= 0 || opts.Pad < opts.Drill || opts.Pad >= opts.Pitch {
+		return nil, errors.New("invalid via pitch or size")
+	}
+	if len(path) < 2 {
+		return nil, errors.New("via fence path needs at least two points")
+	}
+	return g.addVias(fenceSites(path, opts.Pitch, offset), opts, []string{StitchingViaTag, ViaFenceTag}, copper)
+}
+
+// addVias adds the vias of AddStitchingVias and AddViaFence at the
+// sites that are clear of the obstacles, tagged with tags.
+func (g *Gerber) addVias(sites []Pt, opts StitchingOpts, tags []string, copper []*Layer) ([]Via, error) {
+	for _, layer := range copper {
+		if !layer.Type.IsCopper() {
+			return nil, fmt.Errorf("stitching vias require copper layers, not %v", layer.Type)
+		}
+	}
+	index := g.siteIndex(opts.SiteClearances, copper...)
+	for _, drill := range g.layersOfType(DrillLayer) {
+		for _, p := range drill.Primitives {
+			index.insert(p, opts.HoleClearance)
+		}
+	}
+	if opts.Net != "" {
+		tags = append(tags, NetTag(opts.Net))
+	}
+	if opts.CopperClearance > 0 {
+		for _, layer := range copper {
+			for _, p := range layer.Primitives {
+				if opts.Net == "" || !layer.HasTag(p, NetTag(opts.Net)) {
+					index.insert(p, opts.CopperClearance)
+				}
+			}
+		}
+	}
+	var vias []Via
+	for _, pt := range sites {
+		if !index.blocked(pt, opts.Pad) {
+			vias = append(vias, Via{Center: pt, Drill: opts.Drill, Pad: opts.Pad})
+			index.insert(Circle(pt, opts.Pad), 0)
+		}
+	}
+	drill := g.firstLayerOfType(DrillLayer)
+	for _, v := range vias {
+		drill.AddTagged(tags, Circle(v.Center, v.Drill))
+		for _, layer := range copper {
+			layer.AddTagged(tags, Circle(v.Center, v.Pad))
+		}
+	}
+	return vias, nil
+}
+
+// fenceSites returns the sites of a via fence of the given pitch along
+// the path or, if offset is positive, along each side of it.
+func fenceSites(path []Pt, pitch, offset float64) []Pt {
+	var pts []Pt
+	for _, pt := range path {
+		if len(pts) == 0 || pt != pts[len(pts)-1] {
+			pts = append(pts, pt)
+		}
+	}
+	closed := len(pts) > 2 && pts[0] == pts[len(pts)-1]
+	if closed {
+		pts = pts[:len(pts)-1]
+	}
+	if len(pts) < 2 {
+		return nil
+	}
+	rows := [][]Pt{pts}
+	if offset > 0 {
+		rows = [][]Pt{polyclip.OffsetPath(pts, offset, closed), polyclip.OffsetPath(pts, -offset, closed)}
+	}
+	var sites []Pt
+	for _, row := range rows {
+		sites = append(sites, pointsAlong(row, pitch, closed)...)
+	}
+	return sites
+}
+
+// pointsAlong returns the points spaced pitch apart along the open path
+// (centered along it), or around the closed path at a pitch of at
+// least pitch.
+func pointsAlong(pts []Pt, pitch float64, closed bool) []Pt {
+	if closed {
+		pts = append(pts[:len(pts):len(pts)], pts[0])
+	}
+	length := PathLength(pts)
+	count := math.Floor(length/pitch + 1e-9)
+	var dists []float64
+	if closed {
+		step := length / math.Max(count, 1)
+		for k := 0.0; k < math.Max(count, 1); k++ {
+			dists = append(dists, k*step)
+		}
+	} else {
+		start := 0.5 * (length - count*pitch)
+		for k := 0.0; k <= count; k++ {
+			dists = append(dists, start+k*pitch)
+		}
+	}
+	var result []Pt
+	walked := 0.0
+	for i := 1; i < len(pts) && len(dists) > 0; i++ {
+		l := Distance(pts[i-1], pts[i])
+		for len(dists) > 0 && dists[0] <= walked+l+1e-9 {
+			t := 0.0
+			if l > 0 {
+				t = math.Min((dists[0]-walked)/l, 1)
+			}
+			result = append(result, Pt{pts[i-1][0] + t*(pts[i][0]-pts[i-1][0]), pts[i-1][1] + t*(pts[i][1]-pts[i-1][1])})
+			dists = dists[1:]
+		}
+		walked += l
+	}
+	return result
+}
+
+// siteIndex returns a spatial index of the obstacles shared by
+// AddThieving, AddStitchingVias, and AddViaFence: the board outline, the design's
+// fiducials, and the keepouts of the given layers.
+func (g *Gerber) siteIndex(c SiteClearances, layers ...*Layer) *spatialIndex {
+	index := newSpatialIndex(0)
+	for _, outline := range g.layersOfType(OutlineLayer) {
+		for _, p := range outline.Primitives {
+			index.insert(p, c.Edge)
+		}
+	}
+	for _, layer := range g.Layers {
+		for _, p := range layer.Select(FiducialTag) {
+			index.insert(p, c.Fiducial)
+		}
+	}
+	for _, layer := range layers {
+		for _, k := range layer.keepouts() {
+			index.insert(Polygon(Pt{}, true, k.Region, 0), 0)
+		}
+	}
+	return index
+}
+
+// gridSites returns the points of a square grid of the given pitch
+// (aligned to the origin, so that adjacent regions line up) at which a
+// circle of radius r lies within the closed polygon region.
+func gridSites(region []Pt, pitch, r float64) []Pt {
+	if len(region) < 3 {
+		return nil
+	}
+	inner := polyclip.OffsetPolygon(region, -r)
+	mbb := Polygon(Pt{}, true, region, 0).MBB()
+	var sites []Pt
+	for j := math.Ceil(mbb.Min[1] / pitch); j <= math.Floor(mbb.Max[1]/pitch); j++ {
+		for i := math.Ceil(mbb.Min[0] / pitch); i <= math.Floor(mbb.Max[0]/pitch); i++ {
+			pt := Pt{i * pitch, j * pitch}
+			if pointInPolygon(pt, region) && pointInPolygon(pt, inner) {
+				sites = append(sites, pt)
+			}
+		}
+	}
+	return sites
+}
@@ -0,0 +1,186 @@
+package gerber
+
+import (
+	"math"
+	"testing"
+)
+
+// siteBoard returns a 20x20mm board with a fiducial at (5,5), a
+// keepout over its right 5mm, and a pad at (15,15) on the top copper.
+func siteBoard() *Gerber {
+	g := New("test")
+	g.Outline().Add(OutlinePath(RoundedRect(MBB{Max: Pt{20, 20}}, 0), 0.1)...)
+	top := g.firstLayerOfType(TopCopperLayer)
+	top.AddTagged([]string{FiducialTag}, Circle(Pt{5, 5}, 1))
+	top.Add(Circle(Pt{10, 15}, 2))
+	g.AddKeepout(Keepout{Region: RoundedRect(MBB{Min: Pt{15, 0}, Max: Pt{20, 20}}, 0)})
+	return g
+}
+
+// checkSites reports sites that violate the clearances of siteBoard.
+func checkSites(t *testing.T, sites []Pt, r float64, c SiteClearances, padClearance float64) {
+	t.Helper()
+	for _, pt := range sites {
+		switch {
+		case pt[0]-r < c.Edge || pt[1]-r < c.Edge || pt[0]+r > 20-c.Edge || pt[1]+r > 20-c.Edge:
+			t.Errorf("site %v is within %vmm of the edge", pt, c.Edge)
+		case Distance(pt, Pt{5, 5}) < 0.5+r+c.Fiducial:
+			t.Errorf("site %v is within %vmm of the fiducial", pt, c.Fiducial)
+		case pt[0]+r >= 15:
+			t.Errorf("site %v touches the keepout", pt)
+		case Distance(pt, Pt{10, 15}) < 1+r+padClearance:
+			t.Errorf("site %v is within %vmm of the pad", pt, padClearance)
+		}
+	}
+}
+
+func TestLayer_AddThieving(t *testing.T) {
+	g := siteBoard()
+	top := g.firstLayerOfType(TopCopperLayer)
+	c := SiteClearances{Edge: 1, Fiducial: 2}
+	region := RoundedRect(MBB{Max: Pt{20, 20}}, 0)
+	dots, err := top.AddThieving(region, ThievingOpts{SiteClearances: c, Pitch: 1, Size: 0.5, Clearance: 0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dots) == 0 {
+		t.Fatal("AddThieving added no dots")
+	}
+	var sites []Pt
+	for _, p := range dots {
+		sites = append(sites, p.(*CircleT).pt)
+	}
+	checkSites(t, sites, 0.25, c, 0.5)
+	if got := len(top.Select(ThievingTag)); got != len(dots) {
+		t.Errorf("Select(ThievingTag) = %v primitives, want %v", got, len(dots))
+	}
+	// (2,2) is clear of everything, while (5,6) is too close to the
+	// fiducial.
+	found := map[Pt]bool{}
+	for _, pt := range sites {
+		found[pt] = true
+	}
+	if !found[Pt{2, 2}] || found[Pt{5, 6}] {
+		t.Errorf("thieving dots at (2,2) = %v, (5,6) = %v, want true, false", found[Pt{2, 2}], found[Pt{5, 6}])
+	}
+
+	if _, err := g.Outline().AddThieving(region, ThievingOpts{Pitch: 1, Size: 0.5}); err == nil {
+		t.Error("AddThieving on the outline layer = nil error, want error")
+	}
+	if _, err := top.AddThieving(region, ThievingOpts{Pitch: 1, Size: 1}); err == nil {
+		t.Error("AddThieving with touching dots = nil error, want error")
+	}
+}
+
+func TestGerber_AddStitchingVias(t *testing.T) {
+	g := siteBoard()
+	g.Drill().Add(Circle(Pt{10, 15}, 1))
+	top, bottom := g.firstLayerOfType(TopCopperLayer), g.BottomCopper()
+	c := SiteClearances{Edge: 0.5, Fiducial: 1}
+	region := RoundedRect(MBB{Max: Pt{20, 20}}, 0)
+	vias, err := g.AddStitchingVias(region, StitchingOpts{SiteClearances: c, Pitch: 2, Drill: 0.3, Pad: 0.6, HoleClearance: 1}, top, bottom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vias) == 0 {
+		t.Fatal("AddStitchingVias added no vias")
+	}
+	var sites []Pt
+	for _, v := range vias {
+		sites = append(sites, v.Center)
+	}
+	// The hole (0.5mm radius) plus its 1mm clearance reaches as far as
+	// the pad (1mm radius) plus 0.5mm.
+	checkSites(t, sites, 0.3, c, 0.5)
+	for _, layer := range []*Layer{top, bottom, g.layersOfType(DrillLayer)[0]} {
+		if got := len(layer.Select(StitchingViaTag)); got != len(vias) {
+			t.Errorf("%v: Select(StitchingViaTag) = %v primitives, want %v", layer.Filename, got, len(vias))
+		}
+	}
+
+	if _, err := g.AddStitchingVias(region, StitchingOpts{Pitch: 2, Drill: 0.3, Pad: 0.6}, g.TopSilkscreen()); err == nil {
+		t.Error("AddStitchingVias on a silkscreen layer = nil error, want error")
+	}
+}
+
+func TestGerber_AddStitchingVias_CopperClearance(t *testing.T) {
+	g := New("test")
+	top := g.TopCopper()
+	top.AddTagged([]string{NetTag("gnd")}, Polygon(Pt{}, true, RoundedRect(MBB{Max: Pt{10, 10}}, 0), 0))
+	top.AddTagged([]string{NetTag("sig")}, Line(5, 0, 5, 10, CircleShape, 0.5))
+	region := RoundedRect(MBB{Max: Pt{10, 10}}, 0)
+	opts := StitchingOpts{Pitch: 1, Drill: 0.3, Pad: 0.6, CopperClearance: 0.3, Net: "gnd"}
+	vias, err := g.AddStitchingVias(region, opts, top)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The grid has 9x9 sites inside the region, and the trace blocks
+	// the column at x=5.
+	if len(vias) != 8*9 {
+		t.Errorf("got %v vias, want %v", len(vias), 8*9)
+	}
+	for _, v := range vias {
+		if math.Abs(v.Center[0]-5) < 0.25+0.3+0.3 {
+			t.Errorf("via at %v is within 0.3mm of the trace", v.Center)
+		}
+	}
+	if got := len(top.Select(NetTag("gnd"))); got != 1+len(vias) {
+		t.Errorf("Select(gnd) = %v primitives, want %v", got, 1+len(vias))
+	}
+}
+
+func TestGerber_AddViaFence(t *testing.T) {
+	g := siteBoard()
+	top, bottom := g.firstLayerOfType(TopCopperLayer), g.BottomCopper()
+	top.AddTagged([]string{NetTag("rf")}, Line(2, 10, 12, 10, CircleShape, 0.5))
+	opts := StitchingOpts{Pitch: 1, Drill: 0.3, Pad: 0.6, CopperClearance: 0.2, Net: "gnd"}
+	vias, err := g.AddViaFence([]Pt{{2, 10}, {12, 10}}, 1, opts, top, bottom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vias) != 22 {
+		t.Fatalf("got %v vias, want 22", len(vias))
+	}
+	for i, v := range vias {
+		want := Pt{float64(2 + i%11), 11}
+		if i >= 11 {
+			want[1] = 9
+		}
+		if Distance(v.Center, want) > 1e-9 {
+			t.Errorf("via #%v at %v, want %v", i, v.Center, want)
+		}
+	}
+	for _, layer := range []*Layer{top, bottom, g.layersOfType(DrillLayer)[0]} {
+		if got := len(layer.Select(ViaFenceTag)); got != len(vias) {
+			t.Errorf("%v: Select(ViaFenceTag) = %v primitives, want %v", layer.Filename, got, len(vias))
+		}
+	}
+
+	// A closed path 40mm around is fenced at a pitch of 40/11mm.
+	g = New("test")
+	square := []Pt{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}}
+	vias, err = g.AddViaFence(square, 0, StitchingOpts{Pitch: 3.5, Drill: 0.3, Pad: 0.6}, g.TopCopper())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vias) != 11 || vias[0].Center != (Pt{}) {
+		t.Errorf("got %v vias from %v, want 11 from (0,0)", len(vias), vias[0].Center)
+	}
+	// Sharp corners with an offset leave no overlapping vias inside.
+	g = New("test")
+	vias, err = g.AddViaFence([]Pt{{0, 0}, {10, 0}, {0, 1}}, 1, StitchingOpts{Pitch: 1, Drill: 0.3, Pad: 0.6}, g.TopCopper())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, a := range vias {
+		for _, b := range vias[i+1:] {
+			if Distance(a.Center, b.Center) < 0.6 {
+				t.Errorf("vias at %v and %v overlap", a.Center, b.Center)
+			}
+		}
+	}
+
+	if _, err := g.AddViaFence([]Pt{{0, 0}}, 0, opts, g.TopCopper()); err == nil {
+		t.Error("AddViaFence of a single point = nil error, want error")
+	}
+}
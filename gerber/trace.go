@@ -0,0 +1,213 @@
+package gerber
+
+import "math"
+
+// Trace builds a copper trace from a chain of straight and circular
+// arc segments, drawn with round apertures so that consecutive
+// segments join smoothly. Its methods return the trace so that calls
+// can be chained:
+//
+//	NewTrace(Pt{0, 0}, 0.25).LineTo(Pt{5, 0}).ArcTo(Pt{7, 2}, Pt{5, 2}, false).LineTo(Pt{7, 6}).AddTo(top)
+//
+// All dimensions are in millimeters.
+type Trace struct {
+	width    float64
+	pos      Pt
+	segments []traceSegment
+	// pads are the pads whose entries get teardrops (see Teardrops).
+	pads     []Primitive
+	teardrop TeardropOpts
+}
+
+// traceSegment is a segment of a trace: a line from from to to, or
+// (if arc is set) an arc about center.
+type traceSegment struct {
+	from, to  Pt
+	arc       bool
+	center    Pt
+	clockwise bool
+	width     float64
+}
+
+// NewTrace returns a trace of the given width that starts at start.
+func NewTrace(start Pt, width float64) *Trace {
+	return &Trace{width: width, pos: start}
+}
+
+// Pos returns the current end of the trace.
+func (t *Trace) Pos() Pt {
+	return t.pos
+}
+
+// Width sets the width of the segments that follow (e.g. to neck down
+// between the pins of a part).
+func (t *Trace) Width(width float64) *Trace {
+	t.width = width
+	return t
+}
+
+// MoveTo starts a new run of the trace at pt without drawing.
+func (t *Trace) MoveTo(pt Pt) *Trace {
+	t.pos = pt
+	return t
+}
+
+// LineTo draws a straight segment to pt. A segment that continues the
+// previous one in the same direction (with the same width) extends it.
+func (t *Trace) LineTo(pt Pt) *Trace {
+	if pt == t.pos {
+		return t
+	}
+	if n := len(t.segments); n > 0 {
+		last := &t.segments[n-1]
+		if !last.arc && last.to == t.pos && last.width == t.width && collinear(last.from, last.to, pt) {
+			last.to, t.pos = pt, pt
+			return t
+		}
+	}
+	t.segments = append(t.segments, traceSegment{from: t.pos, to: pt, width: t.width})
+	t.pos = pt
+	return t
+}
+
+// collinear reports whether b lies between a and c on the same line.
+func collinear(a, b, c Pt) bool {
+	ab, bc := Pt{b[0] - a[0], b[1] - a[1]}, Pt{c[0] - b[0], c[1] - b[1]}
+	cross := ab[0]*bc[1] - ab[1]*bc[0]
+	return math.Abs(cross) <= 1e-9*Distance(a, b)*Distance(b, c) && ab[0]*bc[0]+ab[1]*bc[1] > 0
+}
+
+// ArcTo draws a circular arc about center, clockwise or
+// counterclockwise, ending in the direction of to (which is moved onto
+// the arc's circle if necessary). If to is the current end of the
+// trace, the arc is a full circle.
+func (t *Trace) ArcTo(to, center Pt, clockwise bool) *Trace {
+	r := Distance(center, t.pos)
+	end := PolarFrom(center, r, AngleTo(center, to))
+	if to == t.pos {
+		end = t.pos
+	}
+	t.segments = append(t.segments, traceSegment{from: t.pos, to: end, arc: true, center: center, clockwise: clockwise, width: t.width})
+	t.pos = end
+	return t
+}
+
+// TeardropOpts represents the shape of the teardrops added by
+// Trace.Teardrops and Gerber.ApplyTeardrops, as fractions of the size
+// of the pad (its smaller dimension). Fields that are zero take their
+// defaults.
+type TeardropOpts struct {
+	// Length is the distance that the teardrop extends beyond the edge
+	// of the pad (default 0.5).
+	Length float64
+	// Width is the width of the teardrop where it meets the pad
+	// (default 0.9).
+	Width float64
+}
+
+func (o TeardropOpts) withDefaults() TeardropOpts {
+	if o.Length <= 0 {
+		o.Length = 0.5
+	}
+	if o.Width <= 0 {
+		o.Width = 0.9
+	}
+	return o
+}
+
+// Teardrops adds a teardrop wherever a run of the trace starts or ends
+// with a straight segment within one of the pads, reinforcing the
+// junction against drilling misregistration and acid traps. A segment
+// too short to hold the teardrop gets none.
+func (t *Trace) Teardrops(opts TeardropOpts, pads ...Primitive) *Trace {
+	t.teardrop = opts.withDefaults()
+	t.pads = append(t.pads, pads...)
+	return t
+}
+
+// Primitives returns the lines, arcs, and teardrops of the trace.
+func (t *Trace) Primitives() []Primitive {
+	var result []Primitive
+	for i, s := range t.segments {
+		if s.arc {
+			result = append(result, s.arcPrimitive())
+			continue
+		}
+		result = append(result, Line(s.from[0], s.from[1], s.to[0], s.to[1], CircleShape, s.width))
+		if i == 0 || t.segments[i-1].to != s.from {
+			result = append(result, t.teardrops(s.from, s.to, s.width)...)
+		}
+		if i == len(t.segments)-1 || t.segments[i+1].from != s.to {
+			result = append(result, t.teardrops(s.to, s.from, s.width)...)
+		}
+	}
+	return result
+}
+
+// arcPrimitive returns the arc of an arc segment.
+func (s traceSegment) arcPrimitive() *ArcT {
+	r := Distance(s.center, s.from)
+	start, end := AngleTo(s.center, s.from), AngleTo(s.center, s.to)
+	if s.clockwise {
+		start, end = end, start
+	}
+	if end <= start {
+		end += 360
+	}
+	return Arc(s.center, r, CircleShape, 1, 1, start, end, s.width)
+}
+
+// teardrops returns the teardrops joining the end at of a straight
+// segment (running toward toward) to the pads that contain it.
+func (t *Trace) teardrops(at, toward Pt, width float64) []Primitive {
+	var result []Primitive
+	for _, pad := range t.pads {
+		if !PrimitiveContains(pad, at) {
+			continue
+		}
+		if p := teardrop(pad, at, toward, width, t.teardrop); p != nil {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// teardrop returns the teardrop joining the end at (within pad) of a
+// straight segment of the given width running toward toward, or nil if
+// the segment is too short to hold it or as wide as the teardrop.
+func teardrop(pad Primitive, at, toward Pt, width float64, o TeardropOpts) *PolygonT {
+	length := Distance(at, toward)
+	if length == 0 {
+		return nil
+	}
+	dir := Pt{(toward[0] - at[0]) / length, (toward[1] - at[1]) / length}
+	normal := Pt{-dir[1], dir[0]}
+	mbb := pad.MBB()
+	size := math.Min(mbb.Max[0]-mbb.Min[0], mbb.Max[1]-mbb.Min[1])
+	if o.Width*size <= width {
+		return nil // the trace is as wide as the teardrop
+	}
+	// The base of the teardrop lies across the pad's center.
+	c := Midpoint(mbb.Min, mbb.Max)
+	along := (c[0]-at[0])*dir[0] + (c[1]-at[1])*dir[1]
+	base := Pt{at[0] + along*dir[0], at[1] + along*dir[1]}
+	reach := 0.5*size + o.Length*size
+	if along+reach > length {
+		return nil
+	}
+	tip := Pt{base[0] + reach*dir[0], base[1] + reach*dir[1]}
+	hb, ht := 0.5*o.Width*size, 0.5*width
+	return Polygon(Pt{}, true, []Pt{
+		{base[0] + hb*normal[0], base[1] + hb*normal[1]},
+		{tip[0] + ht*normal[0], tip[1] + ht*normal[1]},
+		{tip[0] - ht*normal[0], tip[1] - ht*normal[1]},
+		{base[0] - hb*normal[0], base[1] - hb*normal[1]},
+	}, 0)
+}
+
+// AddTo adds the primitives of the trace to the layer and returns them.
+func (t *Trace) AddTo(layer *Layer) []Primitive {
+	primitives := t.Primitives()
+	layer.Add(primitives...)
+	return primitives
+}
@@ -0,0 +1,55 @@
+package gerber
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTrace(t *testing.T) {
+	tr := NewTrace(Pt{0, 0}, 0.25).LineTo(Pt{2, 0}).LineTo(Pt{5, 0}).ArcTo(Pt{7, 2}, Pt{5, 2}, false).
+		LineTo(Pt{7, 6}).MoveTo(Pt{10, 0}).Width(0.5).ArcTo(Pt{8, 0}, Pt{9, 0}, true)
+	got := tr.Primitives()
+	if len(got) != 4 {
+		t.Fatalf("Primitives = %v, want 4", got)
+	}
+	if l, ok := got[0].(*LineT); !ok || l.P1 != (Pt{0, 0}) || l.P2 != (Pt{5, 0}) || l.Shape != CircleShape || l.Thickness != 0.25 {
+		t.Errorf("Primitives[0] = %#v, want the merged line (0,0)-(5,0)", got[0])
+	}
+	if a, ok := got[1].(*ArcT); !ok || a.Radius != 2 || math.Abs(a.StartAngle+0.5*math.Pi) > 1e-9 || math.Abs(a.EndAngle) > 1e-9 {
+		t.Errorf("Primitives[1] = %#v, want a counterclockwise quarter arc", got[1])
+	}
+	// Clockwise from 0° to 180° is counterclockwise from 180° to 360°.
+	if a, ok := got[3].(*ArcT); !ok || a.Thickness != 0.5 || math.Abs(a.StartAngle-math.Pi) > 1e-9 || math.Abs(a.EndAngle-2*math.Pi) > 1e-9 {
+		t.Errorf("Primitives[3] = %#v, want a clockwise half arc", got[3])
+	}
+	if got, want := tr.Pos(), (Pt{8, 0}); Distance(got, want) > 1e-9 {
+		t.Errorf("Pos = %v, want %v", got, want)
+	}
+}
+
+func TestTrace_Teardrops(t *testing.T) {
+	pad1, pad2 := Circle(Pt{0, 0}, 2), Circle(Pt{10, 0}, 2)
+	tr := NewTrace(Pt{0, 0}, 0.2).LineTo(Pt{10, 0}).Teardrops(TeardropOpts{}, pad1, pad2)
+	got := tr.Primitives()
+	if len(got) != 3 {
+		t.Fatalf("Primitives = %v, want a line and 2 teardrops", got)
+	}
+	want := []Pt{{0, 0.9}, {2, 0.1}, {2, -0.1}, {0, -0.9}}
+	p, ok := got[1].(*PolygonT)
+	if !ok || len(p.Points) != len(want) {
+		t.Fatalf("Primitives[1] = %#v, want teardrop %v", got[1], want)
+	}
+	for i, pt := range p.Points {
+		if Distance(pt, want[i]) > 1e-9 {
+			t.Errorf("teardrop point %v = %v, want %v", i, pt, want[i])
+		}
+	}
+	if mbb := got[2].MBB(); !mbbNear(mbb, MBB{Min: Pt{8, -0.9}, Max: Pt{10, 0.9}}, 1e-9) {
+		t.Errorf("second teardrop MBB = %v", mbb)
+	}
+
+	// Too short a segment gets no teardrop.
+	if got := NewTrace(Pt{0, 0}, 0.2).LineTo(Pt{1.5, 0}).Teardrops(TeardropOpts{}, pad1).Primitives(); len(got) != 1 {
+		t.Errorf("short trace Primitives = %v, want only the line", got)
+	}
+}
@@ -0,0 +1,95 @@
+package gerber
+
+import "math"
+
+// Transform represents a 2D affine transformation that maps
+// a point (x,y) to (A*x + B*y + C, D*x + E*y + F).
+//
+// Note that the zero value is not the identity transformation;
+// use Identity instead.
+//
+// Repeated structures may be built once about their own origin and
+// placed with Group.Transform (or Group.Place), which returns
+// transformed copies of the primitives.
+type Transform struct {
+	A, B, C float64
+	D, E, F float64
+}
+
+// Identity returns the identity transformation.
+func Identity() Transform {
+	return Transform{A: 1, E: 1}
+}
+
+// Translate returns a transformation that translates by (dx,dy).
+func Translate(dx, dy float64) Transform {
+	return Transform{A: 1, C: dx, E: 1, F: dy}
+}
+
+// Rotate returns a transformation that rotates counterclockwise
+// about the origin by the angle specified in degrees.
+func Rotate(degrees float64) Transform {
+	s, c := math.Sincos(math.Pi * degrees / 180.0)
+	// Snap to exact values for multiples of 90 degrees.
+	s, c = snapUnit(s), snapUnit(c)
+	return Transform{A: c, B: -s, D: s, E: c}
+}
+
+// RotateAbout returns a transformation that rotates counterclockwise
+// about the point p by the angle specified in degrees.
+func RotateAbout(p Pt, degrees float64) Transform {
+	return Translate(-p[0], -p[1]).Then(Rotate(degrees)).Then(Translate(p[0], p[1]))
+}
+
+// Scale returns a transformation that scales by (sx,sy) about the origin.
+func Scale(sx, sy float64) Transform {
+	return Transform{A: sx, E: sy}
+}
+
+// MirrorX returns a transformation that mirrors about the X axis (y = 0).
+func MirrorX() Transform {
+	return Scale(1, -1)
+}
+
+// MirrorY returns a transformation that mirrors about the Y axis (x = 0).
+func MirrorY() Transform {
+	return Scale(-1, 1)
+}
+
+// Then returns the transformation that applies t followed by u.
+func (t Transform) Then(u Transform) Transform {
+	return Transform{
+		A: u.A*t.A + u.B*t.D,
+		B: u.A*t.B + u.B*t.E,
+		C: u.A*t.C + u.B*t.F + u.C,
+		D: u.D*t.A + u.E*t.D,
+		E: u.D*t.B + u.E*t.E,
+		F: u.D*t.C + u.E*t.F + u.F,
+	}
+}
+
+// Apply returns the transformed point.
+func (t Transform) Apply(p Pt) Pt {
+	return Pt{
+		t.A*p[0] + t.B*p[1] + t.C,
+		t.D*p[0] + t.E*p[1] + t.F,
+	}
+}
+
+// IsIdentity reports whether t is the identity transformation.
+func (t Transform) IsIdentity() bool {
+	return t == Identity()
+}
+
+func snapUnit(v float64) float64 {
+	const eps = 1e-15
+	switch {
+	case math.Abs(v) < eps:
+		return 0
+	case math.Abs(v-1) < eps:
+		return 1
+	case math.Abs(v+1) < eps:
+		return -1
+	}
+	return v
+}
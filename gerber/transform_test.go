@@ -0,0 +1,87 @@
+package gerber
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestTransform_Apply(t *testing.T) {
+	const eps = 1e-12
+	tests := []struct {
+		name string
+		t    Transform
+		p    Pt
+		want Pt
+	}{
+		{name: "identity", t: Identity(), p: Pt{1, 2}, want: Pt{1, 2}},
+		{name: "translate", t: Translate(10, 20), p: Pt{1, 2}, want: Pt{11, 22}},
+		{name: "rotate 90", t: Rotate(90), p: Pt{1, 0}, want: Pt{0, 1}},
+		{name: "rotate 45", t: Rotate(45), p: Pt{1, 0}, want: Pt{math.Sqrt2 / 2, math.Sqrt2 / 2}},
+		{name: "rotate about", t: RotateAbout(Pt{1, 1}, 180), p: Pt{2, 1}, want: Pt{0, 1}},
+		{name: "scale", t: Scale(2, 3), p: Pt{1, 1}, want: Pt{2, 3}},
+		{name: "mirror X", t: MirrorX(), p: Pt{1, 2}, want: Pt{1, -2}},
+		{name: "mirror Y", t: MirrorY(), p: Pt{1, 2}, want: Pt{-1, 2}},
+		{name: "translate then rotate", t: Translate(1, 0).Then(Rotate(90)), p: Pt{1, 0}, want: Pt{0, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.t.Apply(tt.p)
+			if math.Abs(got[0]-tt.want[0]) > eps || math.Abs(got[1]-tt.want[1]) > eps {
+				t.Errorf("Apply(%v) = %v, want %v", tt.p, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGerber_SetExportTransform(t *testing.T) {
+	g := New("test")
+	top := g.TopCopper()
+	top.Add(Line(-1, -1, 1, 1, CircleShape, 0.1))
+	g.SetOriginLowerLeft()
+	g.SetExportTransform(Rotate(90))
+
+	var buf bytes.Buffer
+	if err := top.WriteGerber(&buf); err != nil {
+		t.Fatal(err)
+	}
+	// Origin shifts (-1.05,-1.05) to (0,0), then the 90 degree rotation maps (x,y) to (-y,x).
+	for _, want := range []string{"X-50000Y050000D02*", "X-2050000Y2050000D01*"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("missing %q in output:\n%v", want, buf.String())
+		}
+	}
+}
+
+func TestLayer_AddMirrored(t *testing.T) {
+	g := New("test")
+	top, bottom := g.TopSilkscreen(), g.BottomSilkscreen()
+	label := Text(2, 3, 1, "Hi", "freeserif", 12, &BottomLeft)
+	primitives := []Primitive{Line(1, 1, 4, 2, CircleShape, 0.2), label}
+	top.Add(primitives...)
+	if err := bottom.AddMirrored(primitives...); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(bottom.Primitives); got != 2 {
+		t.Fatalf("bottom primitives = %v, want 2", got)
+	}
+	for i, p := range top.Primitives {
+		want := p.MBB()
+		want = MBB{Min: Pt{want.Min[0], -want.Max[1]}, Max: Pt{want.Max[0], -want.Min[1]}}
+		if got := bottom.Primitives[i].MBB(); !mbbNear(got, want, 1e-9) {
+			t.Errorf("mirrored %v MBB = %v, want %v", p, got, want)
+		}
+	}
+	if text := bottom.Primitives[1].(*TextT); text.xScale != -1 {
+		t.Errorf("mirrored text xScale = %v, want -1", text.xScale)
+	}
+
+	if err := bottom.AddMirrored(Circle(Pt{1, 1}, 1), &testDot{}); err == nil {
+		t.Error("AddMirrored of a primitive without Transform = nil error, want error")
+	}
+	if got := len(bottom.Primitives); got != 2 {
+		t.Errorf("bottom primitives after an error = %v, want 2", got)
+	}
+}
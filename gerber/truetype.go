@@ -0,0 +1,187 @@
+package gerber
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/gmlewis/go-fonts/fonts"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// RegisterFont parses a TrueType or OpenType font (e.g. the contents
+// of a .ttf or .otf file, or a font embedded in a Go package such as
+// golang.org/x/image/font/gofont/goregular) and registers it under
+// name, so that Text and TextBox can render it just like the fonts
+// provided by the go-fonts packages. The glyph outlines are converted
+// into Gerber regions, with the holes of glyphs (such as "o") cleared.
+// Glyphs outside of the Basic Multilingual Plane are not registered.
+// It is safe to call while other goroutines create or render text with
+// this package, but not while fonts.Fonts is accessed directly.
+func RegisterFont(name string, data []byte) error {
+	if name == "" {
+		return errors.New("missing font name")
+	}
+	f, err := sfnt.Parse(data)
+	if err != nil {
+		return fmt.Errorf("font %q: %v", name, err)
+	}
+	converted, err := convertFont(name, f)
+	if err != nil {
+		return fmt.Errorf("font %q: %v", name, err)
+	}
+	fontsMu.Lock()
+	fonts.Fonts[name] = converted
+	fontsMu.Unlock()
+	return nil
+}
+
+// fontsMu protects fonts.Fonts against RegisterFont while text is
+// being created or rendered.
+var fontsMu sync.RWMutex
+
+// convertFont converts the font into the go-fonts representation, in
+// font units.
+func convertFont(name string, f *sfnt.Font) (*fonts.Font, error) {
+	var b sfnt.Buffer
+	upem := float64(f.UnitsPerEm())
+	ppem := fixed.I(int(f.UnitsPerEm()))
+	metrics, err := f.Metrics(&b, ppem, font.HintingNone)
+	if err != nil {
+		return nil, err
+	}
+	result := &fonts.Font{
+		ID:               name,
+		UnitsPerEm:       upem,
+		Ascent:           fixedFloat(metrics.Ascent),
+		Descent:          -fixedFloat(metrics.Descent),
+		HorizAdvX:        0.5 * upem,
+		MissingHorizAdvX: 0.5 * upem,
+		Glyphs:           map[rune]*fonts.Glyph{},
+	}
+	if x, err := f.GlyphIndex(&b, ' '); err == nil && x != 0 {
+		if adv, err := f.GlyphAdvance(&b, x, ppem, font.HintingNone); err == nil {
+			result.HorizAdvX, result.MissingHorizAdvX = fixedFloat(adv), fixedFloat(adv)
+		}
+	}
+	for r := rune(0x21); r <= 0xffff; r++ {
+		x, err := f.GlyphIndex(&b, r)
+		if err != nil || x == 0 {
+			continue
+		}
+		segments, err := f.LoadGlyph(&b, x, ppem, nil)
+		if err != nil {
+			return nil, fmt.Errorf("glyph %+q: %v", r, err)
+		}
+		adv, err := f.GlyphAdvance(&b, x, ppem, font.HintingNone)
+		if err != nil {
+			return nil, fmt.Errorf("glyph %+q: %v", r, err)
+		}
+		if g := convertGlyph(segments); g != nil {
+			g.Unicode, g.HorizAdvX = r, fixedFloat(adv)
+			result.Glyphs[r] = g
+		}
+	}
+	if len(result.Glyphs) == 0 {
+		return nil, errors.New("no glyphs")
+	}
+	return result, nil
+}
+
+func fixedFloat(v fixed.Int26_6) float64 {
+	return float64(v) / 64
+}
+
+// glyphContour is one closed contour of a glyph, as go-fonts path
+// steps (in font units, with Y up) and flattened points.
+type glyphContour struct {
+	steps []*fonts.PathStep
+	pts   []Pt
+	depth int
+}
+
+// convertGlyph returns the glyph with its contours ordered by nesting
+// depth, dark (outside) contours first, or nil if it has no contours.
+func convertGlyph(segments []sfnt.Segment) *fonts.Glyph {
+	pt := func(p fixed.Point26_6) Pt {
+		return Pt{fixedFloat(p.X), -fixedFloat(p.Y)}
+	}
+	var contours []*glyphContour
+	var c *glyphContour
+	var last Pt
+	for _, s := range segments {
+		switch s.Op {
+		case sfnt.SegmentOpMoveTo:
+			last = pt(s.Args[0])
+			c = &glyphContour{steps: []*fonts.PathStep{{C: 'M', P: []float64{last[0], last[1]}}}, pts: []Pt{last}}
+			contours = append(contours, c)
+		case sfnt.SegmentOpLineTo:
+			last = pt(s.Args[0])
+			c.steps = append(c.steps, &fonts.PathStep{C: 'L', P: []float64{last[0], last[1]}})
+			c.pts = append(c.pts, last)
+		case sfnt.SegmentOpQuadTo:
+			// Raise the quadratic Bézier curve to a cubic one.
+			q, end := pt(s.Args[0]), pt(s.Args[1])
+			c1 := Pt{last[0] + 2.0/3*(q[0]-last[0]), last[1] + 2.0/3*(q[1]-last[1])}
+			c2 := Pt{end[0] + 2.0/3*(q[0]-end[0]), end[1] + 2.0/3*(q[1]-end[1])}
+			c.addCubic(last, c1, c2, end)
+			last = end
+		case sfnt.SegmentOpCubeTo:
+			end := pt(s.Args[2])
+			c.addCubic(last, pt(s.Args[0]), pt(s.Args[1]), end)
+			last = end
+		}
+	}
+	if len(contours) == 0 {
+		return nil
+	}
+
+	for i, c := range contours {
+		for j, other := range contours {
+			if i != j && pointInPolygon(c.pts[0], other.pts) {
+				c.depth++
+			}
+		}
+	}
+	sort.SliceStable(contours, func(i, j int) bool { return contours[i].depth < contours[j].depth })
+
+	g := &fonts.Glyph{}
+	for i, c := range contours {
+		g.PathSteps = append(append(g.PathSteps, c.steps...), &fonts.PathStep{C: 'Z'})
+		if c.depth%2 == 0 {
+			g.GerberLP += "d"
+		} else {
+			g.GerberLP += "c"
+		}
+		for j, p := range c.pts {
+			v := MBB{Min: p, Max: p}
+			if i == 0 && j == 0 {
+				g.MBB = v
+			} else {
+				g.MBB.Join(&v)
+			}
+		}
+	}
+	return g
+}
+
+// glyphCurveSteps is the number of points at which curves are sampled
+// to find the nesting and bounds of contours.
+const glyphCurveSteps = 8
+
+// addCubic adds the cubic Bézier curve from p0 to p3 to the contour.
+func (c *glyphContour) addCubic(p0, p1, p2, p3 Pt) {
+	c.steps = append(c.steps, &fonts.PathStep{C: 'C', P: []float64{p1[0], p1[1], p2[0], p2[1], p3[0], p3[1]}})
+	for i := 1; i <= glyphCurveSteps; i++ {
+		t := float64(i) / glyphCurveSteps
+		u := 1 - t
+		a, b, cc, d := u*u*u, 3*u*u*t, 3*u*t*t, t*t*t
+		c.pts = append(c.pts, Pt{
+			a*p0[0] + b*p1[0] + cc*p2[0] + d*p3[0],
+			a*p0[1] + b*p1[1] + cc*p2[1] + d*p3[1],
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package gerber
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+func TestRegisterFont(t *testing.T) {
+	if err := RegisterFont("goregular", goregular.TTF); err != nil {
+		t.Fatal(err)
+	}
+
+	o := Text(0, 0, 1, "o", "goregular", 72, nil)
+	if err := o.Err(); err != nil {
+		t.Fatal(err)
+	}
+	var dark, clear int
+	for _, poly := range o.Render.Polygons {
+		if poly.Dark {
+			dark++
+		} else {
+			clear++
+		}
+	}
+	if dark != 1 || clear != 1 {
+		t.Errorf("o = %v dark and %v clear polygons, want 1 and 1", dark, clear)
+	}
+	mbb := o.MBB()
+	c := Pt{0.5 * (mbb.Min[0] + mbb.Max[0]), 0.5 * (mbb.Min[1] + mbb.Max[1])}
+	if !o.Contains(Pt{mbb.Min[0] + 0.2, c[1]}) {
+		t.Error("o does not contain its left stroke")
+	}
+	if o.Contains(c) {
+		t.Errorf("o contains its center %v", c)
+	}
+
+	// At 72pt (one inch per em), a capital H is about 0.73em tall.
+	h := Text(0, 0, 1, "H", "goregular", 72, nil)
+	if got := h.Height(); math.Abs(got-0.729*25.4) > 0.2 {
+		t.Errorf("H height = %v, want about %v", got, 0.729*25.4)
+	}
+	opts := TopLeft
+	opts.Rotate = 0.5 * math.Pi
+	rotated := Text(0, 0, 1, "HHH", "goregular", 72, &opts)
+	if mbb := rotated.MBB(); mbb.Max[1]-mbb.Min[1] < mbb.Max[0]-mbb.Min[0] {
+		t.Errorf("rotated MBB = %v, want taller than wide", mbb)
+	}
+
+	var buf bytes.Buffer
+	if err := o.WriteGerber(&buf, 10); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); !strings.Contains(got, "%LPC*%") || strings.Count(got, "G36*") != 2 {
+		t.Errorf("WriteGerber = %q, want two regions, one clear", got)
+	}
+
+	if err := RegisterFont("bad", []byte("not a font")); err == nil {
+		t.Error("RegisterFont with bad data = nil error, want error")
+	}
+}
+
+func TestRegisterFont_Concurrent(t *testing.T) {
+	if err := RegisterFont("goregular", goregular.TTF); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error)
+	go func() {
+		done <- RegisterFont("goregular2", goregular.TTF)
+	}()
+	for i := 0; i < 10; i++ {
+		if err := Text(0, 0, 1, "H", "goregular", 12, nil).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
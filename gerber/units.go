@@ -0,0 +1,39 @@
+package gerber
+
+// All primitive constructors take dimensions in millimeters.
+// The following helpers make the units of a dimension explicit at the
+// call site so that mil- or inch-based values aren't silently
+// interpreted as millimeters, e.g.:
+//
+//   Line(0, 0, Mil(500), 0, CircleShape, Mil(8))
+
+const (
+	mmPerInch = 25.4
+	mmPerMil  = mmPerInch / 1000.0
+)
+
+// MM returns v millimeters in millimeters (i.e. v).
+// It is provided for symmetry with Mil and Inch.
+func MM(v float64) float64 { return v }
+
+// Mil returns v mils (thousandths of an inch) in millimeters.
+func Mil(v float64) float64 { return v * mmPerMil }
+
+// Inch returns v inches in millimeters.
+func Inch(v float64) float64 { return v * mmPerInch }
+
+// ToMil converts v millimeters to mils.
+func ToMil(v float64) float64 { return v / mmPerMil }
+
+// ToInch converts v millimeters to inches.
+func ToInch(v float64) float64 { return v / mmPerInch }
+
+// PointMil returns a point from coordinates specified in mils.
+func PointMil(x, y float64) Pt {
+	return Pt{Mil(x), Mil(y)}
+}
+
+// PointInch returns a point from coordinates specified in inches.
+func PointInch(x, y float64) Pt {
+	return Pt{Inch(x), Inch(y)}
+}
@@ -0,0 +1,31 @@
+package gerber
+
+import (
+	"math"
+	"testing"
+)
+
+func TestUnits(t *testing.T) {
+	const eps = 1e-12
+	tests := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{name: "MM", got: MM(1.5), want: 1.5},
+		{name: "Mil", got: Mil(8), want: 0.2032},
+		{name: "Inch", got: Inch(2), want: 50.8},
+		{name: "ToMil", got: ToMil(0.2032), want: 8},
+		{name: "ToInch", got: ToInch(50.8), want: 2},
+		{name: "PointMil", got: PointMil(100, 200)[1], want: 5.08},
+		{name: "PointInch", got: PointInch(1, 2)[0], want: 25.4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if math.Abs(tt.got-tt.want) > eps {
+				t.Errorf("got %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package gerber
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"strings"
+)
+
+// Severity represents the severity of a validation Issue.
+type Severity int
+
+const (
+	// SeverityWarning indicates a likely (but not necessarily fatal) problem.
+	SeverityWarning Severity = iota
+	// SeverityError indicates a problem that will produce a broken fab package.
+	SeverityError
+)
+
+func (s Severity) String() string {
+	if s == SeverityError {
+		return "error"
+	}
+	return "warning"
+}
+
+// Issue represents a single problem found by Validate.
+type Issue struct {
+	Severity Severity
+	// Layer is the layer containing the problem (nil for design-wide issues).
+	Layer *Layer
+	// Primitive is the offending primitive (nil if not applicable).
+	Primitive Primitive
+	// Message describes the problem.
+	Message string
+	// Fix is the automatic correction offered for the problem, if any
+	// (see Issues.Fix).
+	Fix *Fix
+}
+
+func (i Issue) String() string {
+	if i.Layer == nil {
+		return fmt.Sprintf("%v: %v", i.Severity, i.Message)
+	}
+	return fmt.Sprintf("%v: %v: %v", i.Severity, i.Layer.Filename, i.Message)
+}
+
+// Issues is a list of validation issues.
+type Issues []Issue
+
+// Err returns an error summarizing all issues with SeverityError,
+// or nil if there are none.
+func (is Issues) Err() error {
+	var msgs []string
+	for _, i := range is {
+		if i.Severity == SeverityError {
+			msgs = append(msgs, i.String())
+		}
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(msgs, "\n"))
+}
+
+// validationEps is the tolerance (in mm) used when checking that
+// primitives lie within the board outline.
+const validationEps = 1e-6
+
+// Validate checks the design for common problems before it is written:
+// empty layers, a missing outline, primitives outside the outline,
+// zero-size apertures, misplaced mask windows, NaN coordinates,
+// coordinates that do not fit in the coordinate format (which writing
+// rejects, see CoordinateError), and primitives smaller than the output
+// resolution (one unit of the format's last decimal digit, or the
+// output grid), which are written as points.
+// See CheckOutline to also check that the outline is closed.
+func (g *Gerber) Validate() Issues {
+	issues, _ := g.ValidateContext(context.Background())
+	return issues
+}
+
+// ValidateContext is like Validate but stops early if ctx is canceled,
+// returning the issues found so far and ctx.Err().
+func (g *Gerber) ValidateContext(ctx context.Context) (Issues, error) {
+	var issues Issues
+	add := func(sev Severity, layer *Layer, p Primitive, format string, args ...interface{}) {
+		issues = append(issues, Issue{Severity: sev, Layer: layer, Primitive: p, Message: fmt.Sprintf(format, args...)})
+	}
+
+	var outline *MBB
+	for _, layer := range g.Layers {
+		if layer.Type == OutlineLayer && !layer.IsEmpty() {
+			mbb := layer.MBB()
+			outline = &mbb
+			break
+		}
+	}
+	if outline == nil {
+		add(SeverityError, nil, nil, "missing board outline")
+	}
+
+	for _, layer := range g.Layers {
+		if layer.IsEmpty() {
+			add(SeverityWarning, layer, nil, "layer has no primitives")
+			continue
+		}
+		gw := layer.newWriter(ioutil.Discard)
+		resolution := math.Max(1, float64(gw.step)) / gw.scale
+		for i, p := range layer.Primitives {
+			if i%ctxCheckInterval == 0 {
+				if err := ctx.Err(); err != nil {
+					return issues, err
+				}
+			}
+			if a := p.Aperture(); a != nil && a.Macro == nil && !(a.Size > 0) {
+				add(SeverityError, layer, p, "primitive #%v (%T) has zero-size aperture", i, p)
+			}
+			if _, ok := p.(*MaskWindowT); ok && layer.Type != TopSolderMaskLayer && layer.Type != BottomSolderMaskLayer {
+				add(SeverityWarning, layer, p, "primitive #%v is a mask window on a %v layer", i, layer.Type)
+			}
+			mbb := p.MBB()
+			if hasNaN(mbb) {
+				add(SeverityError, layer, p, "primitive #%v (%T) has NaN or infinite coordinates", i, p)
+				continue
+			}
+			if !gw.fits(mbb) {
+				add(SeverityError, layer, p, "primitive #%v (%T) at %v does not fit in the %v.%v coordinate format (see WithAutoFormat)", i, p, mbb, gw.format.Integer, gw.format.Decimal)
+			} else if mbb.Max[0]-mbb.Min[0] < resolution && mbb.Max[1]-mbb.Min[1] < resolution {
+				add(SeverityWarning, layer, p, "primitive #%v (%T) at %v is smaller than the output resolution of %vmm", i, p, mbb, fmtFloat(resolution))
+			}
+			if outline != nil && layer.Type != OutlineLayer && !contains(outline, &mbb, validationEps) {
+				add(SeverityError, layer, p, "primitive #%v (%T) at %v lies outside the board outline %v", i, p, mbb, *outline)
+			}
+		}
+	}
+	return issues, nil
+}
+
+// fits reports whether the corners of mbb fit in the writer's
+// coordinate format, once its origin and export transform are applied.
+func (w *writer) fits(mbb MBB) bool {
+	for _, pt := range []Pt{mbb.Min, mbb.Max, {mbb.Min[0], mbb.Max[1]}, {mbb.Max[0], mbb.Min[1]}} {
+		pt = w.out(pt)
+		for _, v := range pt {
+			if math.Abs(math.Round(w.scale*v)) > float64(w.maxCoord) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func hasNaN(mbb MBB) bool {
+	for _, v := range []float64{mbb.Min[0], mbb.Min[1], mbb.Max[0], mbb.Max[1]} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// contains reports whether outer contains inner within the tolerance eps.
+func contains(outer, inner *MBB, eps float64) bool {
+	return inner.Min[0] >= outer.Min[0]-eps && inner.Min[1] >= outer.Min[1]-eps &&
+		inner.Max[0] <= outer.Max[0]+eps && inner.Max[1] <= outer.Max[1]+eps
+}
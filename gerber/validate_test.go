@@ -0,0 +1,93 @@
+package gerber
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGerber_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		g       func() *Gerber
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "valid design",
+			g: func() *Gerber {
+				g := New("test")
+				g.TopCopper().Add(Circle(Pt{5, 5}, 1))
+				g.Outline().Add(Line(0, 0, 10, 10, CircleShape, 0.1))
+				return g
+			},
+		},
+		{
+			name: "missing outline and empty layer",
+			g: func() *Gerber {
+				g := New("test")
+				g.TopCopper().Add(Circle(Pt{5, 5}, 1))
+				g.TopSilkscreen()
+				return g
+			},
+			want: []string{
+				"error: missing board outline",
+				"warning: test.gto: layer has no primitives",
+			},
+			wantErr: true,
+		},
+		{
+			name: "outside outline, zero-size aperture, and NaN",
+			g: func() *Gerber {
+				g := New("test")
+				g.TopCopper().Add(
+					Circle(Pt{20, 5}, 1),
+					Line(1, 1, 2, 2, CircleShape, 0),
+					Circle(Pt{math.NaN(), 5}, 1),
+				)
+				g.Outline().Add(Line(0, 0, 10, 10, CircleShape, 0.1))
+				return g
+			},
+			want: []string{
+				"error: test.gtl: primitive #0 (*gerber.CircleT) at {[19.5 4.5] [20.5 5.5]} lies outside the board outline {[-0.05 -0.05] [10.05 10.05]}",
+				"error: test.gtl: primitive #1 (*gerber.LineT) has zero-size aperture",
+				"error: test.gtl: primitive #2 (*gerber.CircleT) has NaN or infinite coordinates",
+			},
+			wantErr: true,
+		},
+		{
+			name: "coordinate overflow and sub-resolution geometry",
+			g: func() *Gerber {
+				g := New("test", WithCoordinateFormat(2, 4))
+				g.TopCopper().Add(
+					Circle(Pt{5, 5}, 0.00005),
+					Circle(Pt{5, 105}, 1),
+				)
+				g.Outline().Add(Line(0, 0, 10, 10, CircleShape, 0.1))
+				return g
+			},
+			want: []string{
+				"warning: test.gtl: primitive #0 (*gerber.CircleT) at {[4.999975 4.999975] [5.000025 5.000025]} is smaller than the output resolution of 0.0001mm",
+				"error: test.gtl: primitive #1 (*gerber.CircleT) at {[4.5 104.5] [5.5 105.5]} does not fit in the 2.4 coordinate format (see WithAutoFormat)",
+				"error: test.gtl: primitive #1 (*gerber.CircleT) at {[4.5 104.5] [5.5 105.5]} lies outside the board outline {[-0.05 -0.05] [10.05 10.05]}",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := tt.g().Validate()
+			if len(issues) != len(tt.want) {
+				t.Fatalf("Validate = %v, want %v", issues, tt.want)
+			}
+			for i, issue := range issues {
+				if got := issue.String(); got != tt.want[i] {
+					t.Errorf("issue[%v] = %q, want %q", i, got, tt.want[i])
+				}
+			}
+			if err := issues.Err(); (err != nil) != tt.wantErr {
+				t.Errorf("Err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
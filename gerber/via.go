@@ -0,0 +1,187 @@
+package gerber
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Via represents a plated through-hole connecting copper layers.
+// All dimensions are in millimeters.
+type Via struct {
+	Center Pt
+	// Drill is the diameter of the hole.
+	Drill float64
+	// Pad is the diameter of the copper pad on each layer.
+	Pad float64
+}
+
+// AddTo adds the via's hole to the drill layer (if not nil) and its
+// pad to each of the copper layers, tagged with ViaTag.
+func (v Via) AddTo(drill *Layer, copper ...*Layer) {
+	if drill != nil {
+		drill.AddTagged([]string{ViaTag}, Circle(v.Center, v.Drill))
+	}
+	for _, layer := range copper {
+		layer.AddTagged([]string{ViaTag}, Circle(v.Center, v.Pad))
+	}
+}
+
+// ViaCovering determines how the solder mask covers a via.
+type ViaCovering int
+
+const (
+	// ViaTented vias are covered by the solder mask.
+	ViaTented ViaCovering = iota
+	// ViaUntented vias have solder mask openings on the opposite side
+	// (on both sides for Gerber.Via).
+	ViaUntented
+	// ViaPlugged vias are filled (to prevent solder wicking) and tented.
+	// They are tagged with PluggedViaTag so that fabrication notes can
+	// identify them.
+	ViaPlugged
+)
+
+// Via adds a via at (x,y) to the design in one step: its hole to the
+// first drill layer and its pad to every copper layer (adding a drill
+// layer, and top and bottom copper layers if the design has no copper,
+// as necessary). Untented vias get solder mask openings on both sides
+// (see Openings) and plugged vias are tagged with PluggedViaTag.
+func (g *Gerber) Via(x, y, drill, pad float64, covering ViaCovering) Via {
+	v := Via{Center: Pt{x, y}, Drill: drill, Pad: pad}
+	var copper []*Layer
+	for _, layer := range g.Layers {
+		if layer.Type.IsCopper() {
+			copper = append(copper, layer)
+		}
+	}
+	if len(copper) == 0 {
+		copper = []*Layer{g.firstLayerOfType(TopCopperLayer), g.firstLayerOfType(BottomCopperLayer)}
+	}
+	tags := []string{ViaTag}
+	if covering == ViaPlugged {
+		tags = append(tags, PluggedViaTag)
+	}
+	g.firstLayerOfType(DrillLayer).AddTagged(tags, Circle(v.Center, drill))
+	for _, layer := range copper {
+		p := Circle(v.Center, pad)
+		outer := layer.Type == TopCopperLayer || layer.Type == BottomCopperLayer
+		if covering == ViaUntented && outer {
+			layer.AddWithOpenings(Openings{Mask: true}, p)
+		} else {
+			layer.Add(p)
+		}
+		layer.Tag(p, ViaTag)
+	}
+	return v
+}
+
+// ViaTag is the tag of the holes and pads of the vias added by
+// Via.AddTo, Gerber.Via, and AddThermalVias, which Gerber X2 output
+// uses to identify them (see WithX2). ThermalViaTag and PluggedViaTag
+// are the tags of the drill holes of vias added by AddThermalVias (and
+// Gerber.Via, for plugged vias).
+const (
+	ViaTag        = "via"
+	ThermalViaTag = "thermal-via"
+	PluggedViaTag = "plugged-via"
+)
+
+// ThermalViaOpts represents the options used by AddThermalVias.
+// All dimensions are in millimeters.
+type ThermalViaOpts struct {
+	// Pitch is the center-to-center spacing of the via array.
+	Pitch float64
+	// Drill and Pad are the drill and pad diameters of each via.
+	Drill float64
+	Pad   float64
+	// Margin is the minimum distance from a via pad to the edge of the
+	// thermal pad.
+	Margin float64
+	// Covering determines how the solder mask covers the vias.
+	Covering ViaCovering
+}
+
+// AddThermalVias fills the area of pad (a primitive in this top or
+// bottom copper layer, such as an exposed pad) with an array of vias
+// that connect it to the copper layer on the opposite side, for
+// thermal management of power parts. Vias are not placed in the
+// layer's keepouts. The design's drill and opposite copper layers are
+// added if necessary. It returns the vias added.
+func (l *Layer) AddThermalVias(pad Primitive, opts ThermalViaOpts) ([]Via, error) {
+	if l.g == nil {
+		return nil, errors.New("layer does not belong to a design")
+	}
+	if opts.Pitch <= 0 || opts.Drill <= 0 || opts.Pad < opts.Drill {
+		return nil, errors.New("invalid via pitch or size")
+	}
+	var oppositeType, maskType LayerType
+	switch l.Type {
+	case TopCopperLayer:
+		oppositeType, maskType = BottomCopperLayer, BottomSolderMaskLayer
+	case BottomCopperLayer:
+		oppositeType, maskType = TopCopperLayer, TopSolderMaskLayer
+	default:
+		return nil, fmt.Errorf("thermal vias require a top or bottom copper layer, not %v", l.Type)
+	}
+
+	// fits reports whether a via pad (plus margin) at pt lies within the pad.
+	r := 0.5*opts.Pad + opts.Margin
+	fits := func(pt Pt) bool {
+		if !PrimitiveContains(pad, pt) {
+			return false
+		}
+		for i := 0; i < 16; i++ {
+			if !PrimitiveContains(pad, PolarFrom(pt, r, 22.5*float64(i))) {
+				return false
+			}
+		}
+		return true
+	}
+
+	// Center the array within the pad's MBB.
+	mbb := pad.MBB()
+	nx := int(math.Floor((mbb.Max[0]-mbb.Min[0]-2*r)/opts.Pitch)) + 1
+	ny := int(math.Floor((mbb.Max[1]-mbb.Min[1]-2*r)/opts.Pitch)) + 1
+	x0 := 0.5*(mbb.Min[0]+mbb.Max[0]) - 0.5*float64(nx-1)*opts.Pitch
+	y0 := 0.5*(mbb.Min[1]+mbb.Max[1]) - 0.5*float64(ny-1)*opts.Pitch
+	var vias []Via
+	for j := 0; j < ny; j++ {
+		for i := 0; i < nx; i++ {
+			pt := Pt{x0 + float64(i)*opts.Pitch, y0 + float64(j)*opts.Pitch}
+			if fits(pt) && !l.inKeepout(Circle(pt, opts.Pad)) {
+				vias = append(vias, Via{Center: pt, Drill: opts.Drill, Pad: opts.Pad})
+			}
+		}
+	}
+	if len(vias) == 0 {
+		return nil, errors.New("no thermal vias fit within the pad")
+	}
+
+	g := l.g
+	drill := g.firstLayerOfType(DrillLayer)
+	opposite := g.firstLayerOfType(oppositeType)
+	tags := []string{ViaTag, ThermalViaTag}
+	if opts.Covering == ViaPlugged {
+		tags = append(tags, PluggedViaTag)
+	}
+	for _, v := range vias {
+		hole := Circle(v.Center, v.Drill)
+		drill.AddTagged(tags, hole)
+		l.AddTagged([]string{ViaTag}, Circle(v.Center, v.Pad))
+		opposite.AddTagged([]string{ViaTag}, Circle(v.Center, v.Pad))
+		if opts.Covering == ViaUntented {
+			g.firstLayerOfType(maskType).Add(Circle(v.Center, v.Pad))
+		}
+	}
+	return vias, nil
+}
+
+// firstLayerOfType returns the first layer of the design with the
+// given type, adding one if necessary.
+func (g *Gerber) firstLayerOfType(t LayerType) *Layer {
+	if layers := g.layersOfType(t); len(layers) > 0 {
+		return layers[0]
+	}
+	return g.makeLayer(t, 0)
+}
@@ -0,0 +1,133 @@
+package gerber
+
+import "testing"
+
+func TestLayer_AddThermalVias(t *testing.T) {
+	epad := Polygon(Pt{0, 0}, true, []Pt{{0, 0}, {4, 0}, {4, 4}, {0, 4}}, 0)
+
+	tests := []struct {
+		name        string
+		opts        ThermalViaOpts
+		wantVias    int
+		wantMask    int
+		wantPlugged int
+	}{
+		{
+			name:     "tented 3x3",
+			opts:     ThermalViaOpts{Pitch: 1.2, Drill: 0.3, Pad: 0.6, Margin: 0.2},
+			wantVias: 9,
+		},
+		{
+			name:     "untented 2x2",
+			opts:     ThermalViaOpts{Pitch: 2, Drill: 0.3, Pad: 0.6, Margin: 0.2, Covering: ViaUntented},
+			wantVias: 4,
+			wantMask: 4,
+		},
+		{
+			name:        "plugged",
+			opts:        ThermalViaOpts{Pitch: 2, Drill: 0.3, Pad: 0.6, Covering: ViaPlugged},
+			wantVias:    4,
+			wantPlugged: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New("test")
+			top := g.TopCopper()
+			top.Add(epad)
+			vias, err := top.AddThermalVias(epad, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := len(vias); got != tt.wantVias {
+				t.Errorf("len(vias) = %v, want %v", got, tt.wantVias)
+			}
+			for _, v := range vias {
+				if !epad.Contains(v.Center) {
+					t.Errorf("via at %v lies outside the pad", v.Center)
+				}
+			}
+			layerLen := func(lt LayerType) int {
+				if layers := g.layersOfType(lt); len(layers) > 0 {
+					return len(layers[0].Primitives)
+				}
+				return 0
+			}
+			if got := layerLen(BottomCopperLayer); got != tt.wantVias {
+				t.Errorf("bottom copper pads = %v, want %v", got, tt.wantVias)
+			}
+			if got := layerLen(BottomSolderMaskLayer); got != tt.wantMask {
+				t.Errorf("bottom mask openings = %v, want %v", got, tt.wantMask)
+			}
+			if got := len(g.Select(ThermalViaTag)); got != tt.wantVias {
+				t.Errorf("thermal via holes = %v, want %v", got, tt.wantVias)
+			}
+			if got := len(g.Select(PluggedViaTag)); got != tt.wantPlugged {
+				t.Errorf("plugged via holes = %v, want %v", got, tt.wantPlugged)
+			}
+		})
+	}
+
+	g := New("test")
+	if _, err := g.TopSilkscreen().AddThermalVias(epad, ThermalViaOpts{Pitch: 1, Drill: 0.3, Pad: 0.6}); err == nil {
+		t.Error("AddThermalVias on silkscreen succeeded, want error")
+	}
+}
+
+func TestGerber_Via(t *testing.T) {
+	tests := []struct {
+		name        string
+		covering    ViaCovering
+		wantMask    int
+		wantPlugged int
+	}{
+		{name: "tented"},
+		{name: "untented", covering: ViaUntented, wantMask: 1},
+		{name: "plugged", covering: ViaPlugged, wantPlugged: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New("test")
+			top, inner, bottom := g.TopCopper(), g.LayerN(2), g.BottomCopper()
+			v := g.Via(1, 2, 0.3, 0.6, tt.covering)
+			if want := (Via{Center: Pt{1, 2}, Drill: 0.3, Pad: 0.6}); v != want {
+				t.Errorf("Via = %+v, want %+v", v, want)
+			}
+			for _, layer := range []*Layer{top, inner, bottom} {
+				if len(layer.Primitives) != 1 {
+					t.Errorf("%v pads = %v, want 1", layer.Type, len(layer.Primitives))
+				}
+			}
+			drill := g.firstLayerOfType(DrillLayer)
+			if len(drill.Primitives) != 1 {
+				t.Fatalf("holes = %v, want 1", len(drill.Primitives))
+			}
+			if got := len(drill.Select(PluggedViaTag)); got != tt.wantPlugged {
+				t.Errorf("plugged holes = %v, want %v", got, tt.wantPlugged)
+			}
+			if err := g.DeriveOpenings(); err != nil {
+				t.Fatal(err)
+			}
+			for _, mt := range []LayerType{TopSolderMaskLayer, BottomSolderMaskLayer} {
+				var got int
+				for _, mask := range g.layersOfType(mt) {
+					got += len(mask.Primitives)
+				}
+				if got != tt.wantMask {
+					t.Errorf("%v openings = %v, want %v", mt, got, tt.wantMask)
+				}
+			}
+		})
+	}
+}
+
+func TestGerber_Via_NoCopper(t *testing.T) {
+	g := New("test")
+	g.Via(0, 0, 0.3, 0.6, ViaTented)
+	for _, lt := range []LayerType{TopCopperLayer, BottomCopperLayer, DrillLayer} {
+		if layers := g.layersOfType(lt); len(layers) != 1 || len(layers[0].Primitives) != 1 {
+			t.Errorf("%v layers = %v, want 1 with the via", lt, layers)
+		}
+	}
+}
@@ -2,13 +2,14 @@
 package viewer
 
 import (
+	"bytes"
 	"fmt"
 	"image"
 	"image/color"
 	"log"
 	"math"
-	"regexp"
-	"strconv"
+	"sort"
+	"strings"
 	"sync"
 
 	"fyne.io/fyne"
@@ -20,10 +21,6 @@ import (
 	"github.com/gmlewis/go-gerber/gerber"
 )
 
-var (
-	layerRE = regexp.MustCompile(`\.gl(\d+)$`)
-)
-
 type viewController struct {
 	g         *gerber.Gerber
 	mbb       gerber.MBB
@@ -40,86 +37,160 @@ type viewController struct {
 	xOffset int
 	yOffset int
 
-	indexDrill            int
-	indexTopSilkscreen    int
-	indexTopSolderMask    int
-	indexTop              int
-	indexLayerN           map[int]int
-	indexBottom           int
-	indexBottomSilkscreen int
-	indexBottomSolderMask int
-	indexOutline          int
+	indexDrill             int
+	indexTopSilkscreen     int
+	indexTopSolderMask     int
+	indexTopSolderPaste    int
+	indexTop               int
+	indexLayerN            map[int]int
+	indexBottom            int
+	indexBottomSilkscreen  int
+	indexBottomSolderMask  int
+	indexBottomSolderPaste int
+	indexOutline           int
+	// indexOther holds the indices of layers of other types
+	// (e.g. flex PCB layers), which are drawn below the design.
+	indexOther []int
 
 	maxN int
 
+	// tagPrefix, if colorByTag is set, selects the tags used to color
+	// primitives (see ColorByTag); tagColors maps each tag to its color.
+	colorByTag bool
+	tagPrefix  string
+	tagColors  map[string]color.Color
+
+	// written caches the primitives that the primitives without their
+	// own drawing code are written as (see writtenPrimitives).
+	written map[gerber.Primitive][]gerber.Primitive
+
 	// mu protects Refresh from being hit multiple times concurrently.
 	mu sync.Mutex
 }
 
-func initController(g *gerber.Gerber, app fyne.App, allLayersOn bool) *viewController {
+// Option configures the viewer.
+type Option func(*viewController)
+
+// ColorByTag colors each primitive by its first tag that starts with
+// prefix (e.g. "net:" to color primitives by net) instead of by layer,
+// which makes it obvious when generated traces end up on the wrong net.
+// Primitives without a matching tag use their layer's color.
+func ColorByTag(prefix string) Option {
+	return func(vc *viewController) {
+		vc.colorByTag = true
+		vc.tagPrefix = prefix
+	}
+}
+
+func initController(g *gerber.Gerber, app fyne.App, allLayersOn bool, opts ...Option) *viewController {
 	mbb := g.MBB()
 	vc := &viewController{
-		g:                     g,
-		app:                   app,
-		mbb:                   mbb,
-		center:                gerber.Pt{0.5 * (mbb.Max[0] + mbb.Min[0]), 0.5 * (mbb.Max[1] + mbb.Min[1])},
-		drawLayer:             make([]bool, len(g.Layers)),
-		indexDrill:            -1,
-		indexTopSilkscreen:    -1,
-		indexTopSolderMask:    -1,
-		indexTop:              -1,
-		indexLayerN:           map[int]int{},
-		indexBottom:           -1,
-		indexBottomSilkscreen: -1,
-		indexBottomSolderMask: -1,
-		indexOutline:          -1,
+		g:                      g,
+		app:                    app,
+		mbb:                    mbb,
+		center:                 gerber.Pt{0.5 * (mbb.Max[0] + mbb.Min[0]), 0.5 * (mbb.Max[1] + mbb.Min[1])},
+		drawLayer:              make([]bool, len(g.Layers)),
+		indexDrill:             -1,
+		indexTopSilkscreen:     -1,
+		indexTopSolderMask:     -1,
+		indexTopSolderPaste:    -1,
+		indexTop:               -1,
+		indexLayerN:            map[int]int{},
+		indexBottom:            -1,
+		indexBottomSilkscreen:  -1,
+		indexBottomSolderMask:  -1,
+		indexBottomSolderPaste: -1,
+		indexOutline:           -1,
 	}
 
 	for i, layer := range g.Layers {
-
-		if m := layerRE.FindStringSubmatch(layer.Filename); len(m) == 2 {
-			n, err := strconv.Atoi(m[1])
-			if err != nil || n < 2 {
-				log.Fatalf("error parsing layer suffix %v", layer.Filename)
-			}
-			vc.indexLayerN[n] = i
-			if n > vc.maxN {
-				vc.maxN = n
+		if layer.Type == gerber.InnerCopperLayer {
+			n := layer.N
+			if n >= 2 {
+				vc.indexLayerN[n] = i
+				if n > vc.maxN {
+					vc.maxN = n
+				}
+				vc.drawLayer[i] = allLayersOn
+				continue
 			}
-			vc.drawLayer[i] = allLayersOn
-			continue
+			log.Printf("invalid inner layer number %v for %v: drawing it with the other layers", n, layer.Filename)
 		}
 
 		vc.drawLayer[i] = true
-		switch layer.Filename[len(layer.Filename)-4:] {
-		case ".gtl":
+		switch layer.Type {
+		case gerber.TopCopperLayer:
 			vc.indexTop = i
-		case ".gts":
+		case gerber.TopSolderMaskLayer:
 			vc.indexTopSolderMask = i
-		case ".gto":
+		case gerber.TopSilkscreenLayer:
 			vc.indexTopSilkscreen = i
-		case ".gbl":
+		case gerber.BottomCopperLayer:
 			vc.indexBottom = i
-		case ".gbs":
+		case gerber.BottomSolderMaskLayer:
 			vc.indexBottomSolderMask = i
-		case ".gbo":
+		case gerber.TopSolderPasteLayer:
+			vc.indexTopSolderPaste = i
+		case gerber.BottomSolderPasteLayer:
+			vc.indexBottomSolderPaste = i
+		case gerber.BottomSilkscreenLayer:
 			vc.indexBottomSilkscreen = i
-		case ".drl":
+		case gerber.DrillLayer:
 			vc.indexDrill = i
-		case ".gko":
+		case gerber.OutlineLayer:
 			vc.indexOutline = i
 		default:
-			log.Fatalf("Unknown Gerber layer: %v", layer.Filename)
+			vc.indexOther = append(vc.indexOther, i)
 		}
 	}
 
+	for _, opt := range opts {
+		opt(vc)
+	}
+	if vc.colorByTag {
+		vc.assignTagColors()
+	}
 	return vc
 }
 
-func Gerber(g *gerber.Gerber, allLayersOn bool) {
+// assignTagColors assigns a color to each distinct matching tag,
+// in sorted order so that colors are stable between runs.
+func (vc *viewController) assignTagColors() {
+	seen := map[string]bool{}
+	var tags []string
+	for _, layer := range vc.g.Layers {
+		for _, p := range layer.Primitives {
+			if tag, ok := vc.matchingTag(layer, p); ok && !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	sort.Strings(tags)
+	vc.tagColors = map[string]color.Color{}
+	for i, tag := range tags {
+		vc.tagColors[tag] = tagColors[i%len(tagColors)]
+	}
+}
+
+func (vc *viewController) matchingTag(layer *gerber.Layer, p gerber.Primitive) (string, bool) {
+	for _, tag := range layer.Tags(p) {
+		if strings.HasPrefix(tag, vc.tagPrefix) {
+			return tag, true
+		}
+	}
+	return "", false
+}
+
+// Gerber displays the design in a window. Opts may be used
+// to configure the viewer (e.g. ColorByTag).
+func Gerber(g *gerber.Gerber, allLayersOn bool, opts ...Option) {
+	if err := g.DeriveOpenings(); err != nil {
+		log.Printf("unable to derive openings: %v", err)
+	}
 	a := app.New()
 
-	vc := initController(g, a, allLayersOn)
+	vc := initController(g, a, allLayersOn, opts...)
 	vc.scaleToFit(800, 800)
 	vc.img = image.NewRGBA(image.Rect(0, 0, 800, 800))
 	c := canvas.NewRaster(vc.imageFunc)
@@ -140,6 +211,7 @@ func Gerber(g *gerber.Gerber, allLayersOn bool) {
 	scroller := widget.NewScrollContainer(layers)
 	addCheck(vc.indexDrill, "Drill")
 	addCheck(vc.indexTopSilkscreen, "Top Silkscreen")
+	addCheck(vc.indexTopSolderPaste, "Top Solder Paste")
 	addCheck(vc.indexTopSolderMask, "Top Solder Mask")
 	addCheck(vc.indexTop, "Top")
 	for i := 2; i <= vc.maxN; i++ {
@@ -147,8 +219,12 @@ func Gerber(g *gerber.Gerber, allLayersOn bool) {
 	}
 	addCheck(vc.indexBottom, "Bottom")
 	addCheck(vc.indexBottomSolderMask, "Bottom Solder Mask")
+	addCheck(vc.indexBottomSolderPaste, "Bottom Solder Paste")
 	addCheck(vc.indexBottomSilkscreen, "Bottom Silkscreen")
 	addCheck(vc.indexOutline, "Outline")
+	for _, i := range vc.indexOther {
+		addCheck(i, g.Layers[i].Type.String())
+	}
 	quit := widget.NewHBox(
 		layout.NewSpacer(),
 		widget.NewButton("Quit", func() { a.Quit() }),
@@ -168,6 +244,20 @@ func Gerber(g *gerber.Gerber, allLayersOn bool) {
 	w.ShowAndRun()
 }
 
+// SavePNG renders the design (with all layers on) to a PNG image of the
+// given size without opening a window, which is useful for producing
+// diagnostic images (e.g. with ColorByTag).
+func SavePNG(g *gerber.Gerber, filename string, width, height int, opts ...Option) error {
+	if err := g.DeriveOpenings(); err != nil {
+		return err
+	}
+	vc := initController(g, nil, true, opts...)
+	vc.scaleToFit(width, height)
+	vc.img = image.NewRGBA(image.Rect(0, 0, width, height))
+	vc.Refresh()
+	return gg.SavePNG(filename, vc.img)
+}
+
 func (vc *viewController) OnTypedRune(key rune) {
 	log.Printf("rune=%+q", key)
 	switch key {
@@ -274,23 +364,22 @@ func (vc *viewController) Refresh() {
 	dc := gg.NewContextForImage(vc.img)
 	dc.SetRGB(0, 0, 0)
 	dc.Clear()
-	renderLayer := func(index int, color color.Color) {
+	renderLayer := func(index int, layerColor color.Color) {
 		if index < 0 || !vc.drawLayer[index] {
 			return
 		}
-		r, g, b, a := color.RGBA()
-		fr, fg, fb, fa := float64(r)*cs, float64(g)*cs, float64(b)*cs, float64(a)*cs
+		var fr, fg, fb, fa float64
+		setColor := func(c color.Color) {
+			r, g, b, a := c.RGBA()
+			fr, fg, fb, fa = float64(r)*cs, float64(g)*cs, float64(b)*cs, float64(a)*cs
+		}
 		foreground := func(ctx *gg.Context) {
 			ctx.SetRGBA(fr, fg, fb, fa)
 		}
+		setColor(layerColor)
 		foreground(dc)
-		layer := vc.g.Layers[index]
-		for _, p := range layer.Primitives {
-			mbb := p.MBB()
-			if !bbox.Intersects(&mbb) {
-				continue
-			}
-			// Render this primitive.
+		var draw func(p gerber.Primitive)
+		draw = func(p gerber.Primitive) {
 			switch v := p.(type) {
 			case *gerber.ArcT:
 				dc.SetLineWidth(v.Thickness * vc.scale)
@@ -320,6 +409,7 @@ func (vc *viewController) Refresh() {
 				}
 				dc.Stroke()
 			case *gerber.CircleT:
+				mbb := v.MBB()
 				x, y, r := 0.5*(mbb.Min[0]+mbb.Max[0]), 0.5*(mbb.Min[1]+mbb.Max[1]), 0.5*(mbb.Max[0]-mbb.Min[0])
 				dc.DrawCircle(xf(x), yf(y), r*vc.scale)
 				dc.Fill()
@@ -334,6 +424,10 @@ func (vc *viewController) Refresh() {
 				dc.DrawLine(xf(v.P1[0]), yf(v.P1[1]), xf(v.P2[0]), yf(v.P2[1]))
 				dc.Stroke()
 			case *gerber.TextT:
+				if err := v.Err(); err != nil {
+					log.Printf("unable to render text: %v", err)
+					return
+				}
 				// Render text into new context, the copy foreground pixels only.
 				bnds := vc.img.Bounds()
 				nc := gg.NewContext(bnds.Max.X, bnds.Max.Y)
@@ -353,6 +447,7 @@ func (vc *viewController) Refresh() {
 					}
 					nc.Fill()
 				}
+				mbb := v.MBB()
 				llx, lly := int(xf(mbb.Min[0])), int(yf(mbb.Max[1]))
 				urx, ury := int(0.5+xf(mbb.Max[0])), int(0.5+yf(mbb.Min[1]))
 				// log.Printf("ll=(%v,%v), ur=(%v,%v)", llx, lly, urx, ury)
@@ -378,14 +473,87 @@ func (vc *viewController) Refresh() {
 					}
 				}
 				dc.Fill()
+			case *gerber.MaskWindowT:
+				for i, pt := range v.Region {
+					if i == 0 {
+						dc.MoveTo(xf(pt[0]), yf(pt[1]))
+					} else {
+						dc.LineTo(xf(pt[0]), yf(pt[1]))
+					}
+				}
+				dc.Fill()
+			case *gerber.RegionT:
+				for i, pt := range v.Points(0) {
+					if i == 0 {
+						dc.MoveTo(xf(pt[0]), yf(pt[1]))
+					} else {
+						dc.LineTo(xf(pt[0]), yf(pt[1]))
+					}
+				}
+				dc.Fill()
+			case *gerber.PadT:
+				r := v.Radius
+				if v.Shape != gerber.RectShape {
+					r = 0.5 * math.Min(v.Width, v.Height)
+				}
+				r = math.Min(r, 0.5*math.Min(v.Width, v.Height))
+				x, y := xf(v.Center[0]), yf(v.Center[1])
+				w, h := v.Width*vc.scale, v.Height*vc.scale
+				dc.Push()
+				dc.RotateAbout(gg.Radians(-v.Rotation), x, y) // the image's y axis points down
+				dc.DrawRoundedRectangle(x-0.5*w, y-0.5*h, w, h, r*vc.scale)
+				dc.Fill()
+				dc.Pop()
+			case *gerber.FlashT:
+				parts, err := v.Macro.Image(v.Params...)
+				if err != nil {
+					log.Printf("unable to render flash: %v", err)
+					return
+				}
+				for _, part := range parts {
+					if tp, err := gerber.TransformPrimitive(part, gerber.Translate(v.Center[0], v.Center[1])); err == nil {
+						draw(tp)
+					}
+				}
+			case *gerber.ClearT:
+				// Clear polarity (e.g. silkscreen clipping) is not rendered.
 			default:
-				log.Printf("%T not yet supported", v)
+				// Other primitives are drawn as the primitives they are
+				// written as.
+				parts, err := vc.writtenPrimitives(p)
+				if err != nil {
+					log.Printf("unable to render %T: %v", v, err)
+					return
+				}
+				for _, part := range parts {
+					draw(part)
+				}
 			}
 		}
+		layer := vc.g.Layers[index]
+		for _, p := range layer.Primitives {
+			mbb := p.MBB()
+			if !bbox.Intersects(&mbb) {
+				continue
+			}
+			if vc.colorByTag {
+				if tag, ok := vc.matchingTag(layer, p); ok {
+					setColor(vc.tagColors[tag])
+				} else {
+					setColor(layerColor)
+				}
+				foreground(dc)
+			}
+			draw(p)
+		}
 	}
 	// Draw layers from bottom up
+	for _, i := range vc.indexOther {
+		renderLayer(i, color.RGBA{R: 100, G: 100, B: 100, A: 255})
+	}
 	renderLayer(vc.indexOutline, color.RGBA{R: 0, G: 255, B: 0, A: 255})
 	renderLayer(vc.indexBottomSilkscreen, color.RGBA{R: 250, G: 50, B: 250, A: 255})
+	renderLayer(vc.indexBottomSolderPaste, color.RGBA{R: 150, G: 150, B: 150, A: 255})
 	renderLayer(vc.indexBottomSolderMask, color.RGBA{R: 250, G: 50, B: 50, A: 255})
 	renderLayer(vc.indexBottom, color.RGBA{R: 50, G: 50, B: 250, A: 255})
 	for i := vc.maxN; i >= 2; i-- {
@@ -393,11 +561,35 @@ func (vc *viewController) Refresh() {
 	}
 	renderLayer(vc.indexTop, color.RGBA{R: 250, G: 50, B: 250, A: 255})
 	renderLayer(vc.indexTopSolderMask, color.RGBA{R: 0, G: 150, B: 200, A: 255})
+	renderLayer(vc.indexTopSolderPaste, color.RGBA{R: 150, G: 150, B: 150, A: 255})
 	renderLayer(vc.indexTopSilkscreen, color.RGBA{R: 250, G: 150, B: 0, A: 255})
 	renderLayer(vc.indexDrill, color.RGBA{R: 200, G: 200, B: 200, A: 255})
 	vc.img = dc.Image().(*image.RGBA)
 }
 
+// writtenPrimitives returns the primitives (flashes, draws, and
+// regions) that p is written as, read back by gerber.Parse.
+func (vc *viewController) writtenPrimitives(p gerber.Primitive) ([]gerber.Primitive, error) {
+	if parts, ok := vc.written[p]; ok {
+		return parts, nil
+	}
+	layer := gerber.New("").TopCopper()
+	layer.Add(p)
+	var buf bytes.Buffer
+	if err := layer.WriteGerber(&buf); err != nil {
+		return nil, err
+	}
+	parsed, err := gerber.Parse(&buf)
+	if err != nil {
+		return nil, err
+	}
+	if vc.written == nil {
+		vc.written = map[gerber.Primitive][]gerber.Primitive{}
+	}
+	vc.written[p] = parsed.Primitives
+	return parsed.Primitives, nil
+}
+
 func (vc *viewController) imageFunc(w, h int) image.Image {
 	if vc.lastW != w || vc.lastH != h {
 		vc.mu.Lock()
@@ -407,6 +599,20 @@ func (vc *viewController) imageFunc(w, h int) image.Image {
 	return vc.img
 }
 
+// tagColors are the (bright) colors assigned to tags by ColorByTag.
+var tagColors = []color.Color{
+	color.RGBA{R: 0xe6, G: 0x19, B: 0x4b, A: 255},
+	color.RGBA{R: 0x3c, G: 0xb4, B: 0x4b, A: 255},
+	color.RGBA{R: 0xff, G: 0xe1, B: 0x19, A: 255},
+	color.RGBA{R: 0x43, G: 0x63, B: 0xd8, A: 255},
+	color.RGBA{R: 0xf5, G: 0x82, B: 0x31, A: 255},
+	color.RGBA{R: 0x91, G: 0x1e, B: 0xb4, A: 255},
+	color.RGBA{R: 0x42, G: 0xd4, B: 0xf4, A: 255},
+	color.RGBA{R: 0xf0, G: 0x32, B: 0xe6, A: 255},
+	color.RGBA{R: 0xbf, G: 0xef, B: 0x45, A: 255},
+	color.RGBA{R: 0xfa, G: 0xbe, B: 0xd4, A: 255},
+}
+
 var colors = []color.Color{
 	color.RGBA{R: 0, G: 0, B: 0x84, A: 255},
 	color.RGBA{R: 0x84, G: 0, B: 0, A: 255},
@@ -1,6 +1,10 @@
 package viewer
 
 import (
+	"bytes"
+	"image"
+	"log"
+	"os"
 	"testing"
 
 	"github.com/gmlewis/go-gerber/gerber"
@@ -107,3 +111,67 @@ func TestMBB(t *testing.T) {
 		})
 	}
 }
+
+func TestColorByTag(t *testing.T) {
+	g := gerber.New("test")
+	top := g.TopCopper()
+	gnd := gerber.Line(0, 0, 1, 1, gerber.CircleShape, 0.1)
+	vcc := gerber.Line(0, 1, 1, 0, gerber.CircleShape, 0.1)
+	other := gerber.Circle(gerber.Pt{0, 0}, 1)
+	top.AddTagged([]string{"fiducial", "net:vcc"}, vcc)
+	top.AddTagged([]string{"net:gnd"}, gnd)
+	top.AddTagged([]string{"fiducial"}, other)
+
+	vc := initController(g, nil, true, ColorByTag("net:"))
+	if got, want := len(vc.tagColors), 2; got != want {
+		t.Fatalf("len(tagColors) = %v, want %v", got, want)
+	}
+	// Colors are assigned in sorted tag order.
+	if got, want := vc.tagColors["net:gnd"], tagColors[0]; got != want {
+		t.Errorf("net:gnd color = %v, want %v", got, want)
+	}
+	if got, want := vc.tagColors["net:vcc"], tagColors[1]; got != want {
+		t.Errorf("net:vcc color = %v, want %v", got, want)
+	}
+	if tag, ok := vc.matchingTag(top, other); ok {
+		t.Errorf("matchingTag(other) = %q, want none", tag)
+	}
+}
+
+func TestRefresh_Primitives(t *testing.T) {
+	g := gerber.New("test")
+	top := g.TopCopper()
+	pad := gerber.RoundRectPad(gerber.Pt{1, 1}, 1.5, 1, 0.2, 30)
+	region := gerber.Region(gerber.Pt{3, 0}, gerber.LineTo(gerber.Pt{5, 0}), gerber.LineTo(gerber.Pt{5, 2}), gerber.LineTo(gerber.Pt{3, 2}))
+	qr := gerber.QRCode(7, 1, 2, "go-gerber")
+	hatch := gerber.HatchedRegion([]gerber.Pt{{9, 0}, {11, 0}, {11, 2}, {9, 2}}, 0.2, 0.5, 45, true)
+	curve := gerber.QuadraticBezier([]gerber.Pt{{0, 4}, {1, 6}, {2, 4}}, 0.2)
+	array := gerber.StepRepeat(2, 2, 1, 1, gerber.Circle(gerber.Pt{4, 4}, 0.5))
+	tie := gerber.NetTie("a", gerber.Pt{7, 4}, "b", gerber.Pt{9, 4}, 0.8, 0.4)
+	top.Add(pad, region, qr, hatch, curve, array, tie)
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+	vc := initController(g, nil, true)
+	vc.scaleToFit(241, 121)
+	vc.img = image.NewRGBA(image.Rect(0, 0, 241, 121))
+	vc.Refresh()
+	if bytes.Contains(logs.Bytes(), []byte("render")) {
+		t.Errorf("Refresh logged:\n%s", logs.Bytes())
+	}
+
+	bbox := vc.MBB()
+	xf, yf := vc.xf(bbox), vc.yf(bbox)
+	for _, pt := range []gerber.Pt{pad.Center, {4, 1}, {4, 4}, {5, 5}, {8, 4}} {
+		r, g, b, _ := vc.img.At(int(xf(pt[0])), int(yf(pt[1]))).RGBA()
+		if r == 0 && g == 0 && b == 0 {
+			t.Errorf("nothing drawn at %v", pt)
+		}
+	}
+	for _, p := range []gerber.Primitive{qr, hatch, curve, array, tie} {
+		if parts, ok := vc.written[p]; !ok || len(parts) == 0 {
+			t.Errorf("%T was drawn as %v", p, parts)
+		}
+	}
+}
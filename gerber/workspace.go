@@ -0,0 +1,286 @@
+package gerber
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+)
+
+// Footprint is a reusable part layout: the primitives to add to each
+// type of layer and the padstacks to place, relative to its origin.
+type Footprint struct {
+	Name string
+	// Package names the footprint's package (e.g. "0603") when Name is
+	// a reference designator (see Named).
+	Package string
+	// Side is the side of the board the part is mounted on (see
+	// Flipped).
+	Side   Side
+	Layers map[LayerType]Group
+	Pads   []PadstackRef
+	// PinInPaste makes every through-hole pad of the placed footprint
+	// a pin-in-paste instance (see PadstackRef.PinInPaste).
+	PinInPaste bool
+	// Anchors names points of the footprint (e.g. "center" or
+	// "mounting-hole") relative to its origin. See Anchor.
+	Anchors Anchors
+}
+
+// Place adds the footprint to the design, rotated counterclockwise by
+// degrees about its origin and then moved to at. Layers are added to
+// the design as necessary. Placed pads keep their numbers and nets,
+// and belong to the part named after the footprint unless they already
+// name one. The placed primitives are tagged with the PartTag of the
+// footprint's name, if any, and a named footprint is added to the
+// design's components (see AddComponent), centered on its pads.
+func (f *Footprint) Place(g *Gerber, at Pt, degrees float64) error {
+	var types []LayerType
+	for t := range f.Layers {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	var tags []string
+	if f.Name != "" {
+		tags = []string{PartTag(f.Name)}
+	}
+	for _, t := range types {
+		placed, err := f.Layers[t].Place(at, degrees)
+		if err != nil {
+			return fmt.Errorf("footprint %q: %v", f.Name, err)
+		}
+		g.firstLayerOfType(t).AddTagged(tags, placed...)
+	}
+	for _, pad := range f.Pads {
+		center := RotatePt(pad.Center, Pt{}, degrees)
+		ref := g.PlacePadstack(pad.Padstack, Pt{center[0] + at[0], center[1] + at[1]}, pad.Rotation+degrees)
+		ref.Part, ref.Number, ref.Net = pad.Part, pad.Number, pad.Net
+		ref.PinInPaste = pad.PinInPaste || (f.PinInPaste && pad.Padstack.Drill > 0)
+		if ref.Part == "" {
+			ref.Part = f.Name
+		}
+	}
+	if f.Name != "" {
+		g.AddComponent(&Component{Designator: f.Name, Package: f.Package, Center: f.centroid(at, degrees), Rotation: degrees, Side: f.Side})
+	}
+	return nil
+}
+
+// centroid returns the center of the pads of the footprint placed at
+// at (or at, if it has none).
+func (f *Footprint) centroid(at Pt, degrees float64) Pt {
+	if len(f.Pads) == 0 {
+		return at
+	}
+	mbb := MBB{Min: f.Pads[0].Center, Max: f.Pads[0].Center}
+	for _, pad := range f.Pads[1:] {
+		mbb.Join(&MBB{Min: pad.Center, Max: pad.Center})
+	}
+	c := RotatePt(Pt{0.5 * (mbb.Min[0] + mbb.Max[0]), 0.5 * (mbb.Min[1] + mbb.Max[1])}, Pt{}, degrees)
+	return Pt{c[0] + at[0], c[1] + at[1]}
+}
+
+// NetClass represents the routing rules shared by a class of nets
+// (e.g. power or signal). All dimensions are in millimeters.
+type NetClass struct {
+	Name       string
+	TraceWidth float64
+	Clearance  float64
+	// Via is the padstack of the class's vias (nil for the default).
+	Via *Padstack
+}
+
+// Trace returns the lines (of the class's trace width) connecting pts.
+func (nc NetClass) Trace(pts ...Pt) []Primitive {
+	var result []Primitive
+	for i := 1; i < len(pts); i++ {
+		result = append(result, Line(pts[i-1][0], pts[i-1][1], pts[i][0], pts[i][1], CircleShape, nc.TraceWidth))
+	}
+	return result
+}
+
+// Workspace manages a family of designs (e.g. coil variants generated
+// by one program, or the boards of a multi-board project) that share
+// footprint libraries, net classes, and DRC profiles, checks the
+// connectors that mate between them (see AddMate), and exports them
+// together.
+type Workspace struct {
+	// Designs are the designs of the workspace, in the order they were added.
+	Designs []*Gerber
+
+	footprints  map[string]*Footprint
+	netClasses  map[string]NetClass
+	drcProfiles map[string]FabLimits
+	mates       []Mate
+}
+
+// NewWorkspace returns a new, empty workspace.
+func NewWorkspace() *Workspace {
+	return &Workspace{
+		footprints:  map[string]*Footprint{},
+		netClasses:  map[string]NetClass{},
+		drcProfiles: map[string]FabLimits{},
+	}
+}
+
+// New returns a new design (see New) added to the workspace.
+func (w *Workspace) New(filenamePrefix string, opts ...Option) *Gerber {
+	g := New(filenamePrefix, opts...)
+	w.Designs = append(w.Designs, g)
+	return g
+}
+
+// AddFootprint adds a footprint to the workspace's library,
+// replacing any footprint with the same name.
+func (w *Workspace) AddFootprint(f *Footprint) {
+	w.footprints[f.Name] = f
+}
+
+// Footprint returns the named footprint of the library.
+func (w *Workspace) Footprint(name string) (*Footprint, bool) {
+	f, ok := w.footprints[name]
+	return f, ok
+}
+
+// PlaceFootprint places the named footprint of the library in g
+// (see Footprint.Place).
+func (w *Workspace) PlaceFootprint(g *Gerber, name string, at Pt, degrees float64) error {
+	f, ok := w.footprints[name]
+	if !ok {
+		return fmt.Errorf("unknown footprint %q", name)
+	}
+	return f.Place(g, at, degrees)
+}
+
+// AddNetClass adds a net class to the workspace, replacing any net
+// class with the same name.
+func (w *Workspace) AddNetClass(nc NetClass) {
+	w.netClasses[nc.Name] = nc
+}
+
+// NetClass returns the named net class.
+func (w *Workspace) NetClass(name string) (NetClass, bool) {
+	nc, ok := w.netClasses[name]
+	return nc, ok
+}
+
+// AddDRCProfile adds a named set of design rules to the workspace,
+// such as the Standard limits of a Fab.
+func (w *Workspace) AddDRCProfile(name string, limits FabLimits) {
+	w.drcProfiles[name] = limits
+}
+
+// Check checks every design against the named DRC profile (see
+// Gerber.Estimate) and returns the violations of each design that has
+// any, keyed by its FilenamePrefix.
+func (w *Workspace) Check(profile string) (map[string][]string, error) {
+	limits, ok := w.drcProfiles[profile]
+	if !ok {
+		return nil, fmt.Errorf("unknown DRC profile %q", profile)
+	}
+	result := map[string][]string{}
+	for _, g := range w.Designs {
+		if reasons := limits.check(g.Estimate()); len(reasons) > 0 {
+			result[g.FilenamePrefix] = reasons
+		}
+	}
+	return result, nil
+}
+
+// Mate declares that a connector (a placed part, such as a footprint
+// from the workspace's library) of one design mates pin for pin, by
+// pad number, with a connector of another: e.g. a header and its
+// socket on stacked boards.
+type Mate struct {
+	A, B         *Gerber
+	PartA, PartB string
+	// Offset is the position of B's origin in A's coordinates when
+	// the boards are assembled, and Tolerance the maximum misalignment
+	// (in mm) of mating pins (default 0.05).
+	Offset    Pt
+	Tolerance float64
+}
+
+// AddMate adds a pair of mating connectors to the workspace, checked
+// by CheckMates.
+func (w *Workspace) AddMate(m Mate) {
+	w.mates = append(w.mates, m)
+}
+
+// CheckMates checks that the pinouts and positions of each pair of
+// mating connectors (see AddMate) agree: every pin has a mate, aligned
+// with it when the boards are assembled and on the same net (see
+// PadstackRef.Net).
+func (w *Workspace) CheckMates() Issues {
+	var issues Issues
+	for _, m := range w.mates {
+		errorf := func(format string, args ...interface{}) {
+			issues = append(issues, Issue{
+				Severity: SeverityError,
+				Message: fmt.Sprintf("%v %v mates with %v %v: ", m.A.FilenamePrefix, m.PartA, m.B.FilenamePrefix, m.PartB) +
+					fmt.Sprintf(format, args...),
+			})
+		}
+		pinsA, pinsB := partPins(m.A, m.PartA), partPins(m.B, m.PartB)
+		if len(pinsA) == 0 || len(pinsB) == 0 {
+			errorf("connector has no pins")
+			continue
+		}
+		tolerance := m.Tolerance
+		if tolerance <= 0 {
+			tolerance = 0.05
+		}
+		for _, number := range pinNumbers(pinsA) {
+			a := pinsA[number]
+			b, ok := pinsB[number]
+			if !ok {
+				errorf("pin %v of %v has no mate", a.Number, m.PartA)
+				continue
+			}
+			at := Pt{b.Center[0] + m.Offset[0], b.Center[1] + m.Offset[1]}
+			if d := Distance(a.Center, at); d > tolerance {
+				errorf("pin %v at %v is %vmm from its mate at %v", a.Number, fmtPt(a.Center), fmtFloat(d), fmtPt(at))
+			}
+			if a.Net != b.Net {
+				errorf("pin %v connects net %q to net %q", a.Number, a.Net, b.Net)
+			}
+		}
+		for _, number := range pinNumbers(pinsB) {
+			if _, ok := pinsA[number]; !ok {
+				errorf("pin %v of %v has no mate", number, m.PartB)
+			}
+		}
+	}
+	return issues
+}
+
+// partPins returns the pads of the design's part, by number.
+func partPins(g *Gerber, part string) map[string]*PadstackRef {
+	pins := map[string]*PadstackRef{}
+	for _, ref := range g.padstackRefs {
+		if ref.Part == part {
+			pins[ref.Number] = ref
+		}
+	}
+	return pins
+}
+
+// pinNumbers returns the sorted numbers (see padNumberLess) of the pins.
+func pinNumbers(pins map[string]*PadstackRef) []string {
+	var numbers []string
+	for number := range pins {
+		numbers = append(numbers, number)
+	}
+	sort.Slice(numbers, func(i, j int) bool { return padNumberLess(numbers[i], numbers[j]) })
+	return numbers
+}
+
+// Export writes every design to its own subdirectory of dir (named
+// using the base name of its FilenamePrefix; see Gerber.WriteToDir).
+func (w *Workspace) Export(dir string) error {
+	for _, g := range w.Designs {
+		if err := g.WriteToDir(filepath.Join(dir, filepath.Base(g.FilenamePrefix))); err != nil {
+			return fmt.Errorf("design %v: %v", g.FilenamePrefix, err)
+		}
+	}
+	return nil
+}
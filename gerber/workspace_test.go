@@ -0,0 +1,113 @@
+package gerber
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWorkspace(t *testing.T) {
+	w := NewWorkspace()
+	w.AddFootprint(&Footprint{
+		Name: "test-point",
+		Layers: map[LayerType]Group{
+			TopSilkscreenLayer: {Line(-1, 0, 1, 0, CircleShape, 0.15)},
+		},
+		Pads: []PadstackRef{{Padstack: Via{Drill: 0.3, Pad: 0.6}.Padstack(), Center: Pt{1, 0}}},
+	})
+	w.AddNetClass(NetClass{Name: "signal", TraceWidth: 0.1, Clearance: 0.1})
+	w.AddDRCProfile("oshpark", OSHPark.Standard)
+
+	signal, ok := w.NetClass("signal")
+	if !ok {
+		t.Fatal("NetClass(signal) not found")
+	}
+	for i, prefix := range []string{"coil-a", "coil-b"} {
+		g := w.New(prefix)
+		g.Outline().Add(OutlinePath(RoundedRect(MBB{Max: Pt{10, 10}}, 0), 0.1)...)
+		if err := w.PlaceFootprint(g, "test-point", Pt{5, 5}, 90); err != nil {
+			t.Fatal(err)
+		}
+		if i == 1 {
+			g.TopCopper().Add(signal.Trace(Pt{1, 1}, Pt{4, 1}, Pt{4, 4})...)
+		}
+	}
+	if err := w.PlaceFootprint(w.Designs[0], "missing", Pt{}, 0); err == nil {
+		t.Error("PlaceFootprint(missing): want error")
+	}
+	if got, want := w.Designs[0].PadstackRefs()[0].Center, (Pt{5, 6}); !mbbNear(MBB{Min: got, Max: got}, MBB{Min: want, Max: want}, 1e-9) {
+		t.Errorf("pad center = %v, want %v", got, want)
+	}
+
+	violations, err := w.Check("oshpark")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(violations) != 1 || len(violations["coil-b"]) != 1 {
+		t.Errorf("Check = %v, want a min trace violation for coil-b", violations)
+	}
+	if _, err := w.Check("missing"); err == nil {
+		t.Error("Check(missing): want error")
+	}
+
+	dir, err := ioutil.TempDir("", "workspace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := w.Export(dir); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"coil-a/coil-a.gto", "coil-b/coil-b.gtl", "coil-b/coil-b.drl"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("Export: %v", err)
+		}
+	}
+}
+
+func TestWorkspace_CheckMates(t *testing.T) {
+	w := NewWorkspace()
+	tht := Via{Drill: 0.9, Pad: 1.6}.Padstack()
+	w.AddFootprint(&Footprint{
+		Name: "header",
+		Pads: []PadstackRef{{Padstack: tht, Number: "1", Net: "gnd"}, {Padstack: tht, Center: Pt{2.54, 0}, Number: "2", Net: "vcc"}},
+	})
+	main, daughter := w.New("main"), w.New("daughter")
+	if err := w.PlaceFootprint(main, "header", Pt{10, 10}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.PlaceFootprint(daughter, "header", Pt{5, 5}, 0); err != nil {
+		t.Fatal(err)
+	}
+	w.AddMate(Mate{A: main, PartA: "header", B: daughter, PartB: "header", Offset: Pt{5, 5}})
+	if issues := w.CheckMates(); len(issues) != 0 {
+		t.Errorf("CheckMates = %v, want no issues", issues)
+	}
+
+	// Move a pin, swap a net, and add a pin without a mate.
+	pins := daughter.PadstackRefs()
+	pins[1].Center[0] += 0.1
+	pins[0].Net = "vbat"
+	daughter.PlacePadstack(tht, Pt{10.08, 5}, 0).Part = "header"
+	daughter.PadstackRefs()[2].Number = "3"
+	var got []string
+	for _, i := range w.CheckMates() {
+		got = append(got, i.String())
+	}
+	want := []string{
+		`error: main header mates with daughter header: pin 1 connects net "gnd" to net "vbat"`,
+		`error: main header mates with daughter header: pin 2 at (12.54,10) is 0.1mm from its mate at (12.64,10)`,
+		`error: main header mates with daughter header: pin 3 of header has no mate`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CheckMates =\n%q\nwant\n%q", got, want)
+	}
+
+	w.AddMate(Mate{A: main, PartA: "J1", B: daughter, PartB: "header"})
+	if issues := w.CheckMates(); len(issues) != 4 || !strings.Contains(issues[3].Message, "connector has no pins") {
+		t.Errorf("CheckMates(missing part) = %v, want a missing connector", issues)
+	}
+}
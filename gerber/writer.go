@@ -0,0 +1,332 @@
+package gerber
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// writer wraps an io.Writer and formats coordinates and sizes
+// (specified in millimeters) according to the units, coordinate format,
+// and origin of the design being written.
+type writer struct {
+	io.Writer
+	units  Units
+	format CoordinateFormat
+	origin Pt
+	xform  *Transform // optional export transform (applied after origin)
+	scale  float64    // converts millimeters to output integer coordinates
+	// arcTolerance is the chord error used to flatten arcs (0 for the default).
+	arcTolerance float64
+	// nativeArcs writes circular arcs with G02/G03 (see WithNativeArcs).
+	nativeArcs bool
+	// dcode returns the D-code of an aperture of the layer being
+	// written (if any), for compound primitives (such as StepRepeatT).
+	dcode func(a *Aperture) int
+	// stepRepeat is set while writing a step and repeat block, which
+	// cannot be nested, so that nested arrays are written as copies.
+	stepRepeat bool
+	// clear is set while writing the primitive wrapped by a ClearT,
+	// inverting the polarity of everything it writes (see polarity).
+	clear bool
+	// maxCoord is the largest output integer coordinate that fits in
+	// the format, and err records the first coordinate that did not.
+	maxCoord int64
+	err      error
+	// omitted is the D-code of the default aperture when it is not
+	// defined (see WithDefaultApertureSize), and is never selected.
+	omitted int
+	// step is the output grid (see WithGrid) in output integer
+	// coordinates (0 or 1 for none).
+	step int64
+	// last and lastXY cache the last point formatted by xy (if cached
+	// is set), which consecutive operations (such as the move and draw
+	// of a circle, or the segments of a path) often repeat, and line is
+	// the scratch buffer of operations.
+	cached bool
+	last   Pt
+	lastXY string
+	line   []byte
+}
+
+// newWriter returns a writer for the provided design. g may be nil,
+// in which case the default (millimeter) settings are used.
+func newWriter(w io.Writer, g *Gerber) *writer {
+	gw := &writer{Writer: w, format: DefaultFormatMM}
+	if g != nil {
+		gw.units = g.units
+		gw.format = g.Format()
+		if g.autoFormat {
+			gw.format = g.fittedFormat(gw.format)
+		}
+		gw.origin = g.origin
+		gw.xform = g.exportXform
+		gw.arcTolerance = g.arcTolerance
+		gw.nativeArcs = g.nativeArcs
+	}
+	gw.setFormat(gw.format)
+	return gw
+}
+
+// setFormat sets the coordinate format, which must precede setGrid.
+func (w *writer) setFormat(f CoordinateFormat) {
+	w.format = f
+	w.cached = false
+	w.scale = math.Pow(10, float64(f.Decimal))
+	w.maxCoord = int64(math.Pow(10, float64(f.Integer+f.Decimal))) - 1
+	if w.units == UnitsInch {
+		w.scale /= mmPerInch
+	}
+}
+
+// errWriter wraps an io.Writer, recording its first error, after which
+// writes are skipped. The writers of layers are built upon it so that
+// I/O errors (such as a full disk or a closed pipe) are reported
+// rather than producing silently truncated files.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (e *errWriter) Write(p []byte) (int, error) {
+	if e.err != nil {
+		return 0, e.err
+	}
+	n, err := e.w.Write(p)
+	e.err = err
+	return n, err
+}
+
+// toWriter returns w if it is already a *writer (or the writer of a
+// GerberWriter); otherwise it wraps w using the default settings. This
+// allows primitives to be written directly to any io.Writer.
+func toWriter(w io.Writer) *writer {
+	switch gw := w.(type) {
+	case *writer:
+		return gw
+	case *GerberWriter:
+		return gw.w
+	}
+	return newWriter(w, nil)
+}
+
+// header writes the format and units statements.
+func (w *writer) header() {
+	fmt.Fprintf(w, "%%FSLAX%v%vY%v%v*%%\n", w.format.Integer, w.format.Decimal, w.format.Integer, w.format.Decimal)
+	fmt.Fprintf(w, "%%MO%v*%%\n", w.units)
+}
+
+// xy returns the formatted X and Y coordinates of the point (in mm).
+func (w *writer) xy(x, y float64) string {
+	pt := Pt{x, y}
+	if w.cached && w.last == pt {
+		return w.lastXY
+	}
+	p := w.out(pt)
+	b := append(w.line[:0], 'X')
+	b = appendCoord(b, w.coord(p[0]))
+	b = append(b, 'Y')
+	b = appendCoord(b, w.coord(p[1]))
+	w.line = b
+	w.cached, w.last, w.lastXY = true, pt, string(b)
+	return w.lastXY
+}
+
+// appendCoord appends an output integer coordinate to b, zero padded
+// to six characters (including its sign) like the %06d verb.
+func appendCoord(b []byte, c int64) []byte {
+	var digits [20]byte
+	d := strconv.AppendInt(digits[:0], c, 10)
+	width := 6
+	if c < 0 {
+		b = append(b, '-')
+		d, width = d[1:], 5
+	}
+	for i := len(d); i < width; i++ {
+		b = append(b, '0')
+	}
+	return append(b, d...)
+}
+
+// op writes an operation (such as "D01*") at the point (in mm).
+func (w *writer) op(x, y float64, code string) {
+	xy := w.xy(x, y)
+	w.line = append(append(append(w.line[:0], xy...), code...), '\n')
+	w.Write(w.line)
+}
+
+// out returns the output position (in mm) of the point, after the
+// origin and export transform are applied.
+func (w *writer) out(pt Pt) Pt {
+	pt = Pt{pt[0] - w.origin[0], pt[1] - w.origin[1]}
+	if w.xform != nil {
+		pt = w.xform.Apply(pt)
+	}
+	return pt
+}
+
+// setGrid sets the output grid (in mm), which is rounded to a whole
+// number of output integer coordinates.
+func (w *writer) setGrid(grid float64) {
+	w.step = 0
+	w.cached = false
+	if grid > 0 {
+		w.step = int64(math.Max(1, math.Round(w.scale*grid)))
+	}
+}
+
+// coord converts a value in mm to an output integer coordinate,
+// rounding half away from zero (to the output grid, if any). Values
+// that are NaN or infinite or that do not fit in the coordinate format
+// are recorded in w.err (as a *CoordinateError).
+func (w *writer) coord(v float64) int64 {
+	// This also rejects values too large to convert to an int64.
+	if !(math.Abs(w.scale*v) < float64(w.maxCoord)+1) {
+		w.badCoord(v)
+		return 0
+	}
+	c := int64(math.Round(w.scale * v))
+	if w.step > 1 {
+		c = int64(math.Round(float64(w.scale*v)/float64(w.step))) * w.step
+	}
+	if c > w.maxCoord || c < -w.maxCoord {
+		w.badCoord(v)
+	}
+	return c
+}
+
+// badCoord records the first coordinate that cannot be written.
+func (w *writer) badCoord(v float64) {
+	if w.err == nil {
+		w.err = &CoordinateError{Value: v, Format: w.format}
+	}
+}
+
+// CoordinateError is the error writing a coordinate that is NaN or
+// infinite, or that does not fit in the coordinate format and would
+// otherwise overflow its integer digits, producing an invalid file.
+type CoordinateError struct {
+	// Layer is the layer being written and Primitive (if any) the
+	// offending primitive, the Index-th of the layer.
+	Layer     *Layer
+	Primitive Primitive
+	Index     int
+	// Value is the offending coordinate, in mm, and Format the
+	// coordinate format of the layer.
+	Value  float64
+	Format CoordinateFormat
+}
+
+func (e *CoordinateError) Error() string {
+	msg := fmt.Sprintf("coordinate %vmm does not fit in the %v.%v coordinate format (see WithAutoFormat)", fmtFloat(e.Value), e.Format.Integer, e.Format.Decimal)
+	if math.IsNaN(e.Value) || math.IsInf(e.Value, 0) {
+		msg = fmt.Sprintf("invalid coordinate %v", e.Value)
+	}
+	switch {
+	case e.Layer == nil:
+		return msg
+	case e.Primitive == nil:
+		return fmt.Sprintf("layer %v: %v", e.Layer.Filename, msg)
+	}
+	return fmt.Sprintf("layer %v: primitive #%v (%v): %v", e.Layer.Filename, e.Index, primitiveTypeName(e.Primitive), msg)
+}
+
+// layerErr returns the error recorded by the writer of layer l, if
+// any, with the primitive being written (the i-th of the layer, if p
+// is not nil).
+func (w *writer) layerErr(l *Layer, i int, p Primitive) error {
+	if ce, ok := w.err.(*CoordinateError); ok {
+		if ce.Layer == nil {
+			ce.Layer, ce.Primitive, ce.Index = l, p, i
+		}
+		return ce
+	}
+	if w.err != nil {
+		return fmt.Errorf("layer %v: %v", l.Filename, w.err)
+	}
+	return nil
+}
+
+// maxIntegerDigits is the largest number of integer digits allowed
+// by the Gerber specification.
+const maxIntegerDigits = 6
+
+// fittedFormat returns f with (up to maxIntegerDigits) integer digits
+// added as needed to fit all of the design's output coordinates.
+func (g *Gerber) fittedFormat(f CoordinateFormat) CoordinateFormat {
+	var max float64
+	for _, layer := range g.Layers {
+		for _, p := range layer.Primitives {
+			mbb := p.MBB()
+			for _, pt := range []Pt{mbb.Min, mbb.Max, {mbb.Min[0], mbb.Max[1]}, {mbb.Max[0], mbb.Min[1]}} {
+				pt = g.exportPt(pt)
+				max = math.Max(max, math.Max(math.Abs(pt[0]), math.Abs(pt[1])))
+			}
+		}
+	}
+	if g.units == UnitsInch {
+		max = ToInch(max)
+	}
+	for f.Integer < maxIntegerDigits && max >= math.Pow(10, float64(f.Integer)) {
+		f.Integer++
+	}
+	return f
+}
+
+// move writes a D02 (move) operation.
+func (w *writer) move(x, y float64) {
+	w.op(x, y, "D02*")
+}
+
+// draw writes a D01 (interpolate) operation.
+func (w *writer) draw(x, y float64) {
+	w.op(x, y, "D01*")
+}
+
+// flash writes a D03 (flash) operation.
+func (w *writer) flash(x, y float64) {
+	w.op(x, y, "D03*")
+}
+
+// arc writes a multi-quadrant circular interpolation (G02 or G03)
+// from the current point from to the point to about center. Mirroring
+// export transforms reverse its direction.
+func (w *writer) arc(from, to, center Pt, clockwise bool) {
+	if w.xform != nil && w.xform.det() < 0 {
+		clockwise = !clockwise
+	}
+	mode := "G03"
+	if clockwise {
+		mode = "G02"
+	}
+	c, f := w.out(center), w.out(from)
+	fmt.Fprintf(w, "G75*\n%v*\n%vI%06dJ%06dD01*\nG01*\n", mode, w.xy(to[0], to[1]), w.coord(c[0]-f[0]), w.coord(c[1]-f[1]))
+}
+
+// polarity writes an LPD (dark) or LPC (clear) statement for objects
+// that are dark or clear within the primitive being written; within a
+// ClearT, the polarity is inverted.
+func (w *writer) polarity(dark bool) {
+	if dark != w.clear {
+		io.WriteString(w, "%LPD*%\n")
+	} else {
+		io.WriteString(w, "%LPC*%\n")
+	}
+}
+
+// aperture writes a G54 (select aperture) statement.
+func (w *writer) aperture(apertureIndex int) {
+	if w.omitted != 0 && apertureIndex == w.omitted {
+		return
+	}
+	fmt.Fprintf(w, "G54D%d*\n", apertureIndex)
+}
+
+// size returns the formatted size (in mm) in output units.
+func (w *writer) size(v float64) string {
+	if w.units == UnitsInch {
+		return fmt.Sprintf("%0.6f", ToInch(v))
+	}
+	return fmt.Sprintf("%0.5f", v)
+}
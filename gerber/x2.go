@@ -0,0 +1,211 @@
+package gerber
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// AperFunctionAttribute is the name of the attribute that sets the X2
+// function of a primitive's aperture (e.g. "SMDPad,CuDef"), overriding
+// the function inferred from its layer, tags, and openings. It is
+// written as an aperture attribute (%TA) rather than an object
+// attribute.
+const AperFunctionAttribute = ".AperFunction"
+
+// writeX2 writes the Gerber X2 file attributes for the layer.
+func (l *Layer) writeX2(w io.Writer) {
+	io.WriteString(w, "%TF.GenerationSoftware,gmlewis,go-gerber*%\n")
+	if l.g.array {
+		io.WriteString(w, "%TF.Part,Array*%\n")
+	} else {
+		io.WriteString(w, "%TF.Part,Single*%\n")
+	}
+	if ff := l.fileFunction(); ff != "" {
+		fmt.Fprintf(w, "%%TF.FileFunction,%v*%%\n", ff)
+	}
+	if l.Type.IsNegative() {
+		io.WriteString(w, "%TF.FilePolarity,Negative*%\n")
+	} else {
+		io.WriteString(w, "%TF.FilePolarity,Positive*%\n")
+	}
+}
+
+// fileFunction returns the X2 .FileFunction attribute value for the layer.
+func (l *Layer) fileFunction() string {
+	n := l.g.numCopperLayers()
+	switch l.Type {
+	case BottomCopperLayer:
+		return fmt.Sprintf("Copper,L%v,Bot", n)
+	case DrillLayer:
+		if l.drillKind == nonPlatedDrill {
+			return fmt.Sprintf("NonPlated,1,%v,NPTH", n)
+		}
+		return fmt.Sprintf("Plated,1,%v,PTH", n)
+	}
+	info, _ := l.typeInfo()
+	return info.fileFunction
+}
+
+// writeApertures writes the layer's aperture definitions given their
+// D-codes (see apertureCodes), preceded by their X2 .AperFunction
+// attributes if enabled.
+func (l *Layer) writeApertures(w io.Writer, codes []int) {
+	var funcs []string
+	if l.g != nil && l.g.x2 {
+		funcs = l.aperFunctions()
+	}
+	var current string
+	for _, i := range sortedIndices(codes) {
+		if funcs != nil && funcs[i] != current {
+			if funcs[i] == "" {
+				io.WriteString(w, "%TD.AperFunction*%\n")
+			} else {
+				fmt.Fprintf(w, "%%TA.AperFunction,%v*%%\n", funcs[i])
+			}
+			current = funcs[i]
+		}
+		l.Apertures[i].WriteGerber(w, codes[i])
+	}
+	if current != "" {
+		io.WriteString(w, "%TD.AperFunction*%\n")
+	}
+}
+
+// aperFunctions returns the X2 .AperFunction attribute value of each
+// of the layer's apertures (by index): the function of the primitives
+// that use it (see aperFunction), or "" if they disagree.
+func (l *Layer) aperFunctions() []string {
+	holes := l.platedHoles()
+	funcs := make([]string, len(l.Apertures))
+	seen := make([]bool, len(l.Apertures))
+	conflict := make([]bool, len(l.Apertures))
+	for _, p := range l.Primitives {
+		a := p.Aperture()
+		if a == nil {
+			continue
+		}
+		i, ok := l.apertureMap[a.ID()]
+		if !ok || i < 0 || conflict[i] {
+			continue
+		}
+		f := l.aperFunction(p, holes)
+		switch {
+		case !seen[i]:
+			funcs[i], seen[i] = f, true
+		case f != funcs[i]:
+			funcs[i], conflict[i] = "", true
+		}
+	}
+	return funcs
+}
+
+// platedHoles returns the plated holes of the design's drill layers if
+// the layer is a copper layer, for aperFunction.
+func (l *Layer) platedHoles() []Node {
+	if !l.Type.IsCopper() {
+		return nil
+	}
+	var holes []Node
+	for _, layer := range l.g.layersOfType(DrillLayer) {
+		for _, p := range layer.Primitives {
+			if !layer.HasTag(p, NonPlatedTag) {
+				holes = append(holes, Node{Layer: layer, Primitive: p})
+			}
+		}
+	}
+	return holes
+}
+
+// aperFunction returns the X2 function of a primitive's aperture: its
+// AperFunctionAttribute, or the function inferred from the layer and,
+// for copper pads, from the via and plated holes (of the design's
+// drill layers) at their centers and their solder mask openings.
+func (l *Layer) aperFunction(p Primitive, holes []Node) string {
+	if f, ok := l.attrs[p][AperFunctionAttribute]; ok {
+		return f
+	}
+	switch {
+	case l.Type == DrillLayer:
+		switch {
+		case l.HasTag(p, NonPlatedTag):
+			return "MechanicalDrill"
+		case l.HasTag(p, ViaTag):
+			return "ViaDrill"
+		case l.HasTag(p, CastellationTag):
+			return "CastellatedDrill"
+		}
+		return "ComponentDrill"
+	case l.Type == OutlineLayer:
+		return "Profile"
+	case !l.Type.IsCopper():
+		return ""
+	}
+	switch p.(type) {
+	case *CircleT, *PadT, *FlashT:
+	default:
+		return "Conductor"
+	}
+	if l.HasTag(p, ViaTag) {
+		return "ViaPad"
+	}
+	if l.HasTag(p, CastellationTag) {
+		return "CastellatedPad"
+	}
+	mbb := p.MBB()
+	center := Pt{0.5 * (mbb.Min[0] + mbb.Max[0]), 0.5 * (mbb.Min[1] + mbb.Max[1])}
+	for _, h := range holes {
+		if hm := h.Primitive.MBB(); hm.ContainsPoint(&center) {
+			if h.Layer.HasTag(h.Primitive, ViaTag) {
+				return "ViaPad"
+			}
+			return "ComponentPad"
+		}
+	}
+	if o, ok := l.Openings(p); ok && o.Mask {
+		return "SMDPad,CuDef"
+	}
+	return "Conductor"
+}
+
+// objectAttributes returns the X2 object attributes of a primitive:
+// its attributes (except AperFunctionAttribute) and, if X2 is enabled,
+// the .N (net) and .C (component) attributes of the NetTag and PartTag
+// of copper and drill primitives, unless set explicitly.
+func (l *Layer) objectAttributes(p Primitive) Attributes {
+	attrs := l.attrs[p]
+	_, aper := attrs[AperFunctionAttribute]
+	tagged := l.g != nil && l.g.x2 && (l.Type.IsCopper() || l.Type == DrillLayer)
+	if !aper && !tagged {
+		return attrs
+	}
+	result := Attributes{}
+	if tagged {
+		for _, tag := range l.Tags(p) {
+			switch {
+			case strings.HasPrefix(tag, NetTagPrefix) && result[".N"] == "":
+				result[".N"] = strings.TrimPrefix(tag, NetTagPrefix)
+			case strings.HasPrefix(tag, PartTagPrefix) && result[".C"] == "":
+				result[".C"] = strings.TrimPrefix(tag, PartTagPrefix)
+			}
+		}
+	}
+	for k, v := range attrs {
+		if k != AperFunctionAttribute {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+// numCopperLayers returns the number of copper layers (including plane
+// layers) in the design.
+func (g *Gerber) numCopperLayers() int {
+	var n int
+	for _, layer := range g.Layers {
+		if layer.Type.IsCopper() || layer.Type == InnerPlaneLayer {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,78 @@
+package gerber
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestLayer_WriteGerber_X2Attributes(t *testing.T) {
+	g := New("test", WithX2(true))
+	top, drill := g.TopCopper(), g.Drill()
+	tht := &Padstack{Drill: 1, Top: RoundPad(1.8), Bottom: RoundPad(1.8)}
+	ref := g.PlacePadstack(tht, Pt{0, 0}, 0)
+	ref.Part, ref.Net = "J1", "gnd"
+	ref = g.PlacePadstack(&Padstack{Top: RoundPad(1.2)}, Pt{10, 0}, 0)
+	ref.Part, ref.Net = "U1", "vcc"
+	g.Via(5, 5, 0.3, 0.6, ViaTented)
+	top.AddTagged([]string{NetTag("gnd")}, Line(0, 0, 5, 5, CircleShape, 0.25))
+	top.AddWithAttributes(Attributes{AperFunctionAttribute: "HeatsinkPad", "kind": "test"}, Pad(Pt{20, 0}, RectShape, 3, 3, 0))
+	// The top pad of tht uses the aperture of this trace's end cap.
+	top.Add(Line(0, -5, 0, -10, CircleShape, 1.8))
+	if err := g.DeriveOpenings(); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := top.WriteGerber(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"%TF.Part,Single*%\n",
+		"%TA.AperFunction,ViaPad*%\n%ADD12C,0.60000*%\n",
+		"%TA.AperFunction,Conductor*%\n%ADD13C,0.25000*%\n",
+		"%TA.AperFunction,HeatsinkPad*%\n%ADD14R,3.00000X3.00000*%\n",
+		// Conflicting functions.
+		"%TD.AperFunction*%\n%ADD15C,1.80000*%\n",
+		"%TA.AperFunction,SMDPad,CuDef*%\n%ADD16C,1.20000*%\n%TD.AperFunction*%\n",
+		"%TO.N,gnd*%\nG54D13*\n",
+		"%TOkind,test*%\nG54D14*\n",
+		"%TO.C,J1*%\n%TO.N,gnd*%\nG54D15*\n",
+		"%TO.C,U1*%\n%TO.N,vcc*%\nG54D16*\n",
+	} {
+		if got := buf.String(); !strings.Contains(got, want) {
+			t.Errorf("top copper =\n%v\nwant %q", got, want)
+		}
+	}
+
+	buf.Reset()
+	if err := drill.WriteGerber(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"%TA.AperFunction,ViaDrill*%\n%ADD12C,0.30000*%\n%TA.AperFunction,ComponentDrill*%\n%ADD13C,1.00000*%\n%TD.AperFunction*%\n",
+		"%TO.C,J1*%\nG54D13*\n",
+	} {
+		if got := buf.String(); !strings.Contains(got, want) {
+			t.Errorf("drill =\n%v\nwant %q", got, want)
+		}
+	}
+}
+
+func TestPanelizer_X2Part(t *testing.T) {
+	g := New("board", WithX2(true))
+	g.TopCopper().Add(Circle(Pt{5, 5}, 2))
+	g.Outline().Add(OutlinePath(RoundedRect(MBB{Max: Pt{20, 10}}, 0), 0.1)...)
+	p := Panelizer{NX: 2, NY: 1, Separation: VScore, OutlineWidth: 0.1}
+	panel, err := p.Panelize(g, "panel")
+	if err != nil {
+		t.Fatalf("Panelize: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := panel.firstLayerOfType(TopCopperLayer).WriteGerber(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "%TF.Part,Array*%\n"; !strings.Contains(got, want) {
+		t.Errorf("panel top copper =\n%v\nwant %q", got, want)
+	}
+}
@@ -11,6 +11,7 @@ require (
 	github.com/spf13/afero v1.2.2 // indirect
 	github.com/srwiley/oksvg v0.0.0-20190414003808-c520f0a6c5cc // indirect
 	github.com/stretchr/objx v0.2.0 // indirect
+	golang.org/x/image v0.0.0-20190523035834-f03afa92d3ff
 )
 
 go 1.13